@@ -2,6 +2,8 @@ package mode
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/zk/txpool"
@@ -10,7 +12,9 @@ import (
 )
 
 var (
-	mode string // Mode of the ACL
+	mode   string // Mode of the ACL
+	at     string // Time to switch to the mode at
+	cancel bool   // Cancel the mode change scheduled at the given time
 )
 
 var Command = cli.Command{
@@ -24,6 +28,16 @@ var Command = cli.Command{
 			Usage:       "Mode of the ACL (allowlist, blocklist or disabled)",
 			Destination: &mode,
 		},
+		&cli.StringFlag{
+			Name:        "at",
+			Usage:       "Schedule the mode change for the given time in RFC3339 format (e.g. 2025-01-02T15:04:05Z) instead of applying it now",
+			Destination: &at,
+		},
+		&cli.BoolFlag{
+			Name:        "cancel",
+			Usage:       "Cancel the mode change scheduled with --at",
+			Destination: &cancel,
+		},
 	},
 }
 
@@ -32,10 +46,20 @@ func run(cliCtx *cli.Context) error {
 		return errors.New("data directory is not set")
 	}
 
-	if mode == "" {
+	if mode == "" && !cancel {
 		return errors.New("mode is not set")
 	}
 
+	var activatesAt time.Time
+	if at != "" {
+		var err error
+		if activatesAt, err = time.Parse(time.RFC3339, at); err != nil {
+			return fmt.Errorf("invalid at: %w", err)
+		}
+	} else if cancel {
+		return errors.New("at is not set")
+	}
+
 	dataDir := cliCtx.String(utils.DataDirFlag.Name)
 
 	log.Info("Setting mode ", "mode - ", mode, "dataDir - ", dataDir)
@@ -46,9 +70,22 @@ func run(cliCtx *cli.Context) error {
 		return err
 	}
 
-	if err := txpool.SetMode(cliCtx.Context, aclDB, mode); err != nil {
-		log.Error("Failed to set acl mode", "err", err)
-		return err
+	switch {
+	case cancel:
+		if err := txpool.CancelScheduledMode(cliCtx.Context, aclDB, activatesAt); err != nil {
+			log.Error("Failed to cancel scheduled acl mode", "err", err)
+			return err
+		}
+	case at != "":
+		if err := txpool.ScheduleMode(cliCtx.Context, aclDB, mode, activatesAt); err != nil {
+			log.Error("Failed to schedule acl mode", "err", err)
+			return err
+		}
+	default:
+		if err := txpool.SetMode(cliCtx.Context, aclDB, mode); err != nil {
+			log.Error("Failed to set acl mode", "err", err)
+			return err
+		}
 	}
 
 	return nil
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -32,6 +33,9 @@ var (
 
 	address string
 	policy  string
+
+	notBefore string
+	notAfter  string
 )
 
 var UpdateCommand = cli.Command{
@@ -110,6 +114,16 @@ var AddCommand = cli.Command{
 			Destination: &aclType,
 			Required:    true,
 		},
+		&cli.StringFlag{
+			Name:        "not-before",
+			Usage:       "Time the policy starts to apply from, in RFC3339 format (e.g. 2025-01-02T15:04:05Z)",
+			Destination: &notBefore,
+		},
+		&cli.StringFlag{
+			Name:        "not-after",
+			Usage:       "Time the policy stops applying after, in RFC3339 format (e.g. 2025-01-02T15:04:05Z)",
+			Destination: &notAfter,
+		},
 	},
 }
 
@@ -136,12 +150,18 @@ func addRun(cliCtx *cli.Context) error {
 		return err
 	}
 
-	if err := txpool.AddPolicy(cliCtx.Context, aclDB, aclType, addr, policy); err != nil {
+	window, err := parsePolicyWindow(notBefore, notAfter)
+	if err != nil {
+		log.Error("Failed to parse policy window", "err", err)
+		return err
+	}
+
+	if err := txpool.AddPolicyWithWindow(cliCtx.Context, aclDB, aclType, addr, policy, window); err != nil {
 		log.Error("Failed to add policy", "err", err)
 		return err
 	}
 
-	log.Info("Policy added", "address", address, "policy", policy)
+	log.Info("Policy added", "address", address, "policy", policy, "window", window.String())
 
 	return nil
 }
@@ -265,6 +285,27 @@ func readCSV(filePath string) ([]common.Address, [][]txpool.Policy, error) {
 	return addresses, policies, nil
 }
 
+// parsePolicyWindow parses the RFC3339 bounds of a policy window, an empty bound leaves the window open on that side
+func parsePolicyWindow(notBefore, notAfter string) (txpool.PolicyWindow, error) {
+	var (
+		window txpool.PolicyWindow
+		err    error
+	)
+
+	if notBefore != "" {
+		if window.NotBefore, err = time.Parse(time.RFC3339, notBefore); err != nil {
+			return window, fmt.Errorf("invalid not-before: %w", err)
+		}
+	}
+	if notAfter != "" {
+		if window.NotAfter, err = time.Parse(time.RFC3339, notAfter); err != nil {
+			return window, fmt.Errorf("invalid not-after: %w", err)
+		}
+	}
+
+	return window, nil
+}
+
 func splitPolicies(s string) []string {
 	substrings := strings.Split(strings.TrimSpace(s), ",")
 	result := make([]string, 0, len(substrings))
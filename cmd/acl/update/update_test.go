@@ -103,3 +103,17 @@ func TestUpdatePolicies(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePolicyWindow(t *testing.T) {
+	window, err := parsePolicyWindow("", "2025-01-02T15:04:05Z")
+	require.NoError(t, err)
+	require.True(t, window.NotBefore.IsZero())
+	require.Equal(t, time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC), window.NotAfter)
+
+	window, err = parsePolicyWindow("", "")
+	require.NoError(t, err)
+	require.True(t, window.IsZero())
+
+	_, err = parsePolicyWindow("2025-01-02", "")
+	require.ErrorContains(t, err, "invalid not-before")
+}
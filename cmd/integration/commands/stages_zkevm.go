@@ -129,6 +129,9 @@ func newSyncZk(ctx context.Context, db kv.RwDB) (consensus.Engine, *vm.Config, *
 			nil,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
 		)
 	} else {
 		stages = stages2.NewDefaultZkStages(
@@ -100,5 +100,9 @@ type HttpCfg struct {
 	DataStreamWriteTimeout            time.Duration
 	DataStreamInactivityTimeout       time.Duration
 	DataStreamInactivityCheckInterval time.Duration
+	DataStreamTLSCert                 string
+	DataStreamTLSKey                  string
+	DataStreamTLSClientCA             string
+	DataStreamTLSAllowedFingerprints  []string
 	L2RpcUrl                          string
 }
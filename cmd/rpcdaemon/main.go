@@ -13,6 +13,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/turbo/jsonrpc"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 	"github.com/spf13/cobra"
 
 	_ "github.com/ledgerwatch/erigon/core/snaptype"        //hack
@@ -42,7 +43,15 @@ func main() {
 		gasTracker.Start()
 		defer gasTracker.Stop()
 
-		apiList := jsonrpc.APIList(db, backend, txPool, nil, mining, ff, stateCache, blockReader, agg, cfg, engine, &ethConfig, nil, logger, nil, gasTracker)
+		// like the node, only rpc nodes with a sequencer to ask answer eth_gasPrice from the gas price of the sequencer
+		var l2GasTracker *jsonrpc.RecurringL2GasPriceTracker
+		if !sequencer.IsSequencer() && ethConfig.L2RpcUrl != "" {
+			l2GasTracker = jsonrpc.NewRecurringL2GasPriceTracker(func() string { return ethConfig.L2RpcUrl }, ethConfig.L2GasPriceCheckFrequency)
+			l2GasTracker.Start()
+			defer l2GasTracker.Stop()
+		}
+
+		apiList := jsonrpc.APIList(db, backend, txPool, nil, mining, ff, stateCache, blockReader, agg, cfg, engine, &ethConfig, nil, logger, nil, gasTracker, nil, nil, l2GasTracker, nil)
 		rpc.PreAllocateRPCMetricLabels(apiList)
 		if err := cli.StartRpcServer(ctx, cfg, apiList, logger); err != nil {
 			logger.Error(err.Error())
@@ -425,6 +425,11 @@ var (
 		Usage: "Use TLS connection to L2 datastreamer endpoint",
 		Value: false,
 	}
+	L2DataStreamerFileFlag = cli.StringFlag{
+		Name:  "zkevm.l2-datastreamer-file",
+		Usage: "Sync from a datastream archive created by 'datastream export' instead of the L2 datastreamer endpoint",
+		Value: "",
+	}
 	L2DataStreamerTimeout = cli.StringFlag{
 		Name:  "zkevm.l2-datastreamer-timeout",
 		Usage: "The time to wait for data to arrive from the stream before reporting an error (0s doesn't check)",
@@ -465,6 +470,11 @@ var (
 		Usage: "The port used for the L1 cache",
 		Value: 6969,
 	}
+	L1CacheMethodTTLsFlag = cli.StringFlag{
+		Name:  "zkevm.l1-cache-method-ttls",
+		Usage: "Comma separated method=duration list replacing how long the L1 cache keeps responses that may still change, e.g. eth_blockNumber=2s,eth_getLogs=0s. A duration of 0 stops them from being cached",
+		Value: "",
+	}
 	AddressSequencerFlag = cli.StringFlag{
 		Name:  "zkevm.address-sequencer",
 		Usage: "Sequencer address",
@@ -551,6 +561,21 @@ var (
 		Usage: "Regenerate the SMT in memory (requires a lot of RAM for most chains)",
 		Value: false,
 	}
+	SmtPruneMode = cli.StringFlag{
+		Name:  "zkevm.smt-prune-mode",
+		Usage: "SMT pruning mode: 'full' deletes the nodes each write replaces so only the latest state root is whole, 'pruned' keeps the state roots of the last zkevm.smt-prune-retain-batches batches whole and deletes the nodes no longer reachable from them",
+		Value: "full",
+	}
+	SmtPruneRetainBatches = cli.Uint64Flag{
+		Name:  "zkevm.smt-prune-retain-batches",
+		Usage: "The number of latest batches whose state roots are kept reachable when the SMT is pruned",
+		Value: 128,
+	}
+	SmtPruneStep = cli.IntFlag{
+		Name:  "zkevm.smt-prune-step",
+		Usage: "The number of SMT nodes the pruner visits per stage loop",
+		Value: 100_000,
+	}
 	SequencerBlockSealTime = cli.StringFlag{
 		Name:  "zkevm.sequencer-block-seal-time",
 		Usage: "Block seal time. Defaults to 6s",
@@ -601,6 +626,11 @@ var (
 		Usage: "Reuse the L1 info index for resequencing",
 		Value: true,
 	}
+	SequencerResequenceDryRun = cli.BoolFlag{
+		Name:  "zkevm.sequencer-resequence-dry-run",
+		Usage: "Resequence the unseen batches stored in data stream into a scratch copy of the chain data and write a report comparing them with the original batches, the data stream and chain data are left untouched. The copy leaves out the history indices but needs about as much free disk space in the datadir as the chain data, and takes as long to write",
+		Value: false,
+	}
 	SequencerDecodedTxCacheSize = cli.IntFlag{
 		Name:  "zkevm.sequencer-decoded-tx-cache-size",
 		Usage: "Sequencer decoded transaction cache size",
@@ -611,6 +641,16 @@ var (
 		Usage: "Sequencer decoded transaction cache time-to-live",
 		Value: 600 * time.Second,
 	}
+	SequencerTxOrdering = cli.StringFlag{
+		Name:  "zkevm.sequencer-tx-ordering",
+		Usage: "Order in which the sequencer takes pending transactions from the pool: priority-fee, fifo, sender-fair or counter-aware",
+		Value: "priority-fee",
+	}
+	SequencerMaxTxsPerSender = cli.IntFlag{
+		Name:  "zkevm.sequencer-max-txs-per-sender",
+		Usage: "Maximum number of transactions from a single sender taken from the pool for each batch, 0 for no limit",
+		Value: 0,
+	}
 	ExecutorUrls = cli.StringFlag{
 		Name:  "zkevm.executor-urls",
 		Usage: "A comma separated list of grpc addresses that host executors",
@@ -653,6 +693,26 @@ var (
 		Usage: "The maximum number of concurrent requests to the executor",
 		Value: 1,
 	}
+	ExecutorWeights = cli.StringFlag{
+		Name:  "zkevm.executor-weights",
+		Usage: "A comma separated list of routing weights for the executors, in the same order as zkevm.executor-urls. Executors without a weight get a weight of 1",
+		Value: "",
+	}
+	ExecutorConcurrencyLimits = cli.StringFlag{
+		Name:  "zkevm.executor-concurrency-limits",
+		Usage: "A comma separated list of in-flight request limits for the executors, in the same order as zkevm.executor-urls. Limits must be at least 1, executors without a limit use zkevm.executor-max-concurrent-requests",
+		Value: "",
+	}
+	ExecutorCircuitBreakerFailures = cli.IntFlag{
+		Name:  "zkevm.executor-circuit-breaker-failures",
+		Usage: "The number of consecutive failed requests after which an executor is taken out of rotation, 0 disables the circuit breaker",
+		Value: 0,
+	}
+	ExecutorCircuitBreakerCooldown = cli.DurationFlag{
+		Name:  "zkevm.executor-circuit-breaker-cooldown",
+		Usage: "How long an executor is taken out of rotation for before it is tried again",
+		Value: 30 * time.Second,
+	}
 	RpcRateLimitsFlag = cli.IntFlag{
 		Name:  "zkevm.rpc-ratelimit",
 		Usage: "RPC rate limit in requests per second.",
@@ -663,6 +723,11 @@ var (
 		Usage: "The maximum number of concurrent requests to the executor for getBatchWitness.",
 		Value: 1,
 	}
+	RpcGetBatchStateProofConcurrencyLimitFlag = cli.IntFlag{
+		Name:  "zkevm.rpc-get-batch-state-proof-concurrency-limit",
+		Usage: "The maximum number of concurrent zkevm_getBatchStateProof requests, each one may unwind the SMT in memory. Use 0 for no limit.",
+		Value: 1,
+	}
 	DataStreamPort = cli.UintFlag{
 		Name:  "zkevm.data-stream-port",
 		Usage: "Define the port used for the zkevm data stream",
@@ -688,11 +753,36 @@ var (
 		Usage: "Define the inactivity check interval timeout when interacting with a data stream server",
 		Value: 5 * time.Minute,
 	}
+	DataStreamTLSCert = cli.StringFlag{
+		Name:  "zkevm.data-stream-tls-cert",
+		Usage: "Path to the PEM certificate used to serve the data stream over TLS, TLS is enabled when set",
+		Value: "",
+	}
+	DataStreamTLSKey = cli.StringFlag{
+		Name:  "zkevm.data-stream-tls-key",
+		Usage: "Path to the PEM private key for the data stream TLS certificate",
+		Value: "",
+	}
+	DataStreamTLSClientCA = cli.StringFlag{
+		Name:  "zkevm.data-stream-tls-client-ca",
+		Usage: "Path to a PEM bundle of CAs used to verify data stream client certificates, enables mutual TLS when set",
+		Value: "",
+	}
+	DataStreamTLSAllowedFingerprints = cli.StringFlag{
+		Name:  "zkevm.data-stream-tls-allowed-fingerprints",
+		Usage: "Comma separated list of SHA-256 fingerprints of client certificates allowed to connect to the data stream",
+		Value: "",
+	}
 	Limbo = cli.BoolFlag{
 		Name:  "zkevm.limbo",
 		Usage: "Enable limbo processing on batches that failed verification",
 		Value: false,
 	}
+	AdminToken = cli.StringFlag{
+		Name:  "zkevm.admin-token",
+		Usage: "Bearer token required by the operator admin methods, the limbo and sequencer failover ones, all are disabled when empty",
+		Value: "",
+	}
 	AllowFreeTransactions = cli.BoolFlag{
 		Name:  "zkevm.allow-free-transactions",
 		Usage: "Allow the sequencer to proceed transactions with 0 gas price",
@@ -754,6 +844,16 @@ var (
 		Usage: "The frequency at which to check the L1 for the latest gas price",
 		Value: 0,
 	}
+	L2GasPriceCheckFrequency = cli.DurationFlag{
+		Name:  "zkevm.l2-gas-price-check-frequency",
+		Usage: "The frequency at which RPC nodes poll the sequencer for its gas price. 0 fetches it when eth_gasPrice is called, caching it for 3 seconds",
+		Value: 3 * time.Second,
+	}
+	RpcReceiptEffectiveGasPriceDetails = cli.BoolFlag{
+		Name:  "zkevm.rpc-receipt-effective-gas-price-details",
+		Usage: "Add an effectiveGasPriceDetails field to transaction receipts explaining how the effective gas price was derived",
+		Value: false,
+	}
 	GasPriceHistoryCount = cli.Uint64Flag{
 		Name:  "zkevm.gas-price-history-count",
 		Usage: "The number of historical gas prices to keep",
@@ -774,6 +874,26 @@ var (
 		Usage: "The URL of the pool manager. If set, eth_sendRawTransaction will be redirected there.",
 		Value: "",
 	}
+	TxForwardingQueue = cli.BoolFlag{
+		Name:  "zkevm.tx-forwarding-queue",
+		Usage: "Keep the transactions forwarded to the sequencer in a persistent queue, retrying and rebroadcasting them until they are mined or rejected. Ignored when zkevm.pool-manager-url is set",
+		Value: false,
+	}
+	TxForwardingQueueSize = cli.IntFlag{
+		Name:  "zkevm.tx-forwarding-queue-size",
+		Usage: "The maximum number of transactions kept in the forwarding queue, once full transactions are forwarded without retries",
+		Value: 10000,
+	}
+	TxForwardingRetryInterval = cli.DurationFlag{
+		Name:  "zkevm.tx-forwarding-retry-interval",
+		Usage: "The delay before a queued transaction is retried or checked for inclusion, it doubles on every attempt",
+		Value: 5 * time.Second,
+	}
+	TxForwardingMaxRetryInterval = cli.DurationFlag{
+		Name:  "zkevm.tx-forwarding-max-retry-interval",
+		Usage: "The maximum delay between two attempts for a queued transaction",
+		Value: 2 * time.Minute,
+	}
 	TxPoolRejectSmartContractDeployments = cli.BoolFlag{
 		Name:  "zkevm.reject-smart-contract-deployments",
 		Usage: "Reject smart contract deployments",
@@ -791,9 +911,24 @@ var (
 	}
 	DAUrl = cli.StringFlag{
 		Name:  "zkevm.da-url",
-		Usage: "The URL of the data availability service",
+		Usage: "Comma separated URLs of the data availability backends, tried in order: http(s):// for a DAC member, file:///dir for a local directory or objstore+http(s):// for an object store",
 		Value: "",
 	}
+	DAQuorumUrls = cli.StringFlag{
+		Name:  "zkevm.da-quorum-urls",
+		Usage: "Comma separated URLs of DAC members queried together, tried after zkevm.da-url",
+		Value: "",
+	}
+	DAQuorumThreshold = cli.IntFlag{
+		Name:  "zkevm.da-quorum-threshold",
+		Usage: "Number of DAC members from zkevm.da-quorum-urls that must return data matching the batch hash",
+		Value: 1,
+	}
+	DACacheSize = cli.IntFlag{
+		Name:  "zkevm.da-cache-size",
+		Usage: "Number of batches whose off chain data is cached in memory",
+		Value: 256,
+	}
 	VirtualCountersSmtReduction = cli.Float64Flag{
 		Name:  "zkevm.virtual-counters-smt-reduction",
 		Usage: "The multiplier to reduce the SMT depth by when calculating virtual counters",
@@ -859,6 +994,31 @@ var (
 		Usage: "Crash on reorg instead of attempting to recover",
 		Value: false,
 	}
+	SequencerRole = cli.StringFlag{
+		Name:  "zkevm.sequencer-role",
+		Usage: "Role of the node: rpc, sequencer or standby. A standby follows the lease holder until it is promoted with admin_promoteSequencer, a sequencer takes the lease when it is free and otherwise follows too. Defaults to sequencer when CDK_ERIGON_SEQUENCER=1 and rpc otherwise",
+		Value: "",
+	}
+	SequencerLeaseFile = cli.StringFlag{
+		Name:  "zkevm.sequencer-lease-file",
+		Usage: "Lease file shared by the sequencer and its standbys, only the node holding the lease produces blocks. Required for the standby role",
+		Value: "",
+	}
+	SequencerLeaseTTL = cli.DurationFlag{
+		Name:  "zkevm.sequencer-lease-ttl",
+		Usage: "How long the sequencer lease lasts without being renewed, it is renewed every third of it",
+		Value: 30 * time.Second,
+	}
+	SequencerLeaseRpcUrl = cli.StringFlag{
+		Name:  "zkevm.sequencer-lease-rpc-url",
+		Usage: "Rpc url the other nodes sharing the lease reach this node on while it holds the lease. They use zkevm.l2-sequencer-rpc-url when it is not set",
+		Value: "",
+	}
+	SequencerLeaseDataStreamUrl = cli.StringFlag{
+		Name:  "zkevm.sequencer-lease-datastream-url",
+		Usage: "Datastream url the other nodes sharing the lease follow this node on while it holds the lease. They use zkevm.l2-datastreamer-url when it is not set",
+		Value: "",
+	}
 	ShadowSequencer = cli.BoolFlag{
 		Name:  "zkevm.shadow-sequencer",
 		Usage: "Shadow the main sequencer when run in sequencer mode. Used for local testing",
@@ -903,6 +1063,16 @@ var (
 		Usage: "When enabled a RPC node can use the L2 to build the InfoTree.",
 		Value: false,
 	}
+	BridgeIndexerEnabled = cli.BoolFlag{
+		Name:  "zkevm.bridge-indexer",
+		Usage: "Index the deposits and claims of the L2 bridge contract and serve them with their merkle proofs over the zkevm_ RPC namespace. Requires zkevm.address-l2-bridge.",
+		Value: false,
+	}
+	AddressL2BridgeFlag = cli.StringFlag{
+		Name:  "zkevm.address-l2-bridge",
+		Usage: "Address of the bridge contract on the L2, used by the bridge indexer",
+		Value: "",
+	}
 	ACLPrintHistory = cli.IntFlag{
 		Name:  "acl.print-history",
 		Usage: "Number of entries to print from the ACL history on node start up",
@@ -153,6 +153,17 @@ func (c Counters) OverflownAsString() string {
 	return res
 }
 
+// OverflownNames returns the names of all counters that have gone below zero remaining
+func (c Counters) OverflownNames() []CounterName {
+	var res []CounterName
+	for k, v := range c {
+		if v != nil && v.remaining < 0 {
+			res = append(res, CounterKeyNames[k])
+		}
+	}
+	return res
+}
+
 func (c *Counters) GetArithmetics() *Counter {
 	return (*c)[A]
 }
@@ -186,10 +197,12 @@ func (c *Counters) GetPoseidonPaddings() *Counter {
 }
 
 func (cc Counters) Clone() Counters {
-	var clonedCounters Counters = Counters{}
+	clonedCounters := make(Counters, len(cc))
 
 	for k, v := range cc {
-		clonedCounters[k] = v.Clone()
+		if v != nil {
+			clonedCounters[k] = v.Clone()
+		}
 	}
 
 	return clonedCounters
@@ -268,17 +281,12 @@ func NewCounterCollector(smtLevels int, forkId uint16) *CounterCollector {
 }
 
 func (cc *CounterCollector) Clone() *CounterCollector {
-	var clonedCounters Counters = Counters{}
-
-	for k, v := range cc.counters {
-		clonedCounters[k] = v.Clone()
-	}
-
 	return &CounterCollector{
-		counters:    clonedCounters,
+		counters:    cc.counters.Clone(),
 		smtLevels:   cc.smtLevels,
 		isDeploy:    cc.isDeploy,
 		transaction: cc.transaction, // no need to make deep clone of a transaction
+		forkId:      cc.forkId,
 	}
 }
 
@@ -37,6 +37,11 @@ func TestGetTotalSteps(t *testing.T) {
 			forkID:   uint16(zk_consts.ForkId13Durian),
 			expected: forkId11TotalSteps - stepDeduction,
 		},
+		{
+			name:     "ForkID14",
+			forkID:   uint16(zk_consts.ForkId14TypedTxs),
+			expected: forkId11TotalSteps - stepDeduction,
+		},
 	}
 
 	for _, tt := range tests {
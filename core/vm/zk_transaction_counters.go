@@ -70,6 +70,21 @@ func (tc *TransactionCounter) CombineCountersInto(combined *Counters) *Counters
 	return combined
 }
 
+// UsageRatio returns the highest fraction of a single counter's limit used by the transaction across its rlp,
+// execution and processing counters
+func (tc *TransactionCounter) UsageRatio() float64 {
+	ratio := 0.0
+	for k := range tc.rlpCounters.counters {
+		limit := tc.executionCounters.counters[k].initialAmount
+		if limit <= 0 {
+			continue
+		}
+		used := tc.rlpCounters.counters[k].used + tc.executionCounters.counters[k].used + tc.processingCounters.counters[k].used
+		ratio = math.Max(ratio, float64(used)/float64(limit))
+	}
+	return ratio
+}
+
 func (tc *TransactionCounter) Clone() *TransactionCounter {
 	var l2DataCacheCopy []byte
 	if tc.l2DataCache != nil {
@@ -83,13 +98,19 @@ func (tc *TransactionCounter) Clone() *TransactionCounter {
 		executionCounters:  tc.executionCounters.Clone(),
 		processingCounters: tc.processingCounters.Clone(),
 		smtLevels:          tc.smtLevels,
+		forkId:             tc.forkId,
 		l2DataCache:        l2DataCacheCopy,
 	}
 }
 
 func (tc *TransactionCounter) GetL2DataCache() ([]byte, error) {
 	if tc.l2DataCache == nil {
-		data, err := tx.TransactionToL2Data(tc.transaction, 8, tx.MaxEffectivePercentage)
+		// the legacy encoding is the same from fork 8 onwards, typed transactions need their own fork
+		forkId := tc.forkId
+		if forkId < uint16(chain.ForkID8Elderberry) {
+			forkId = uint16(chain.ForkID8Elderberry)
+		}
+		data, err := tx.TransactionToL2Data(tc.transaction, forkId, tx.MaxEffectivePercentage)
 		if err != nil {
 			return data, err
 		}
@@ -161,6 +182,23 @@ func (tc *TransactionCounter) CalculateRlp() error {
 	collector.getLenBytes(chainIdLength)
 	collector.getLenBytes(nonceLength)
 
+	// typed transactions carry a fee cap and an access list on top of the legacy fields, the access list is hashed
+	// into the tx hash and its own hash is part of the l2 tx hash
+	if txType := tc.transaction.Type(); txType != types.LegacyTxType {
+		if txType == types.DynamicFeeTxType {
+			feeCapHex := tc.transaction.GetFeeCap().Hex()
+			hexutil.Remove0xPrefixIfExists(&feeCapHex)
+			hexutil.AddLeadingZeroToHexValueForByteCompletion(&feeCapHex)
+			collector.Deduct(S, 6)
+			collector.getLenBytes(len(feeCapHex) / 2)
+		}
+		accessList := tc.transaction.GetAccessList()
+		accessListLen := len(accessList)*20 + accessList.StorageKeys()*32
+		collector.getLenBytes(3)
+		collector.multiCall(collector.addHashTx, accessListLen>>5)
+		collector.addL2HashTx()
+	}
+
 	collector.divArith()
 	collector.multiCall(collector.addHashTx, 9+(txDataLen>>5)) //txDataLen>>5 equals to int(math.Floor(float64(txDataLen)/32))
 	collector.multiCall(collector.addL2HashTx, 8+(txDataLen>>5))
@@ -168,7 +206,7 @@ func (tc *TransactionCounter) CalculateRlp() error {
 	collector.SHLarith()
 
 	v, r, s := tc.transaction.RawSignatureValues()
-	v = tx.GetDecodedV(tc.transaction, v)
+	v = tx.GetBatchL2DataV(tc.transaction, v, uint64(tc.forkId))
 	if err := collector.ecRecover(v, r, s, false); err != nil {
 		return err
 	}
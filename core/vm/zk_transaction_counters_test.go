@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+func TestCalculateRlpTypedTx(t *testing.T) {
+	to := libcommon.HexToAddress("0x1275fbb540c8efc58b812ba83b0d0b8b9917ae98")
+	commonTx := types.CommonTx{
+		Nonce: 1,
+		Gas:   100000,
+		To:    &to,
+		Value: uint256.NewInt(1),
+		Data:  []byte{1, 2, 3},
+		V:     *uint256.NewInt(2036),
+		R:     *uint256.NewInt(7),
+		S:     *uint256.NewInt(8),
+	}
+	legacy := &types.LegacyTx{CommonTx: commonTx, GasPrice: uint256.NewInt(1000000000)}
+
+	typedCommonTx := commonTx
+	typedCommonTx.V = *uint256.NewInt(1)
+	dynamicFee := &types.DynamicFeeTransaction{
+		CommonTx: typedCommonTx,
+		ChainID:  uint256.NewInt(1000),
+		Tip:      uint256.NewInt(1),
+		FeeCap:   uint256.NewInt(1000000000),
+		AccessList: types2.AccessList{
+			{Address: to, StorageKeys: []libcommon.Hash{libcommon.HexToHash("0x01")}},
+		},
+	}
+
+	steps := func(tx types.Transaction, forkId chain.ForkId) (int, []byte) {
+		tc := NewTransactionCounter(tx, 32, uint16(forkId), 0.6, false)
+		require.NoError(t, tc.CalculateRlp())
+		l2Data, err := tc.GetL2DataCache()
+		require.NoError(t, err)
+		return tc.rlpCounters.Counters()[S].Used(), l2Data
+	}
+
+	legacySteps, _ := steps(legacy, chain.ForkId14TypedTxs)
+	typedSteps, l2Data := steps(dynamicFee, chain.ForkId14TypedTxs)
+	require.Equal(t, byte(types.DynamicFeeTxType), l2Data[0])
+	require.Greater(t, typedSteps, legacySteps)
+
+	// before the fork the typed transaction is counted as the legacy data it is encoded as
+	_, l2Data = steps(dynamicFee, chain.ForkId13Durian)
+	require.NotEqual(t, byte(types.DynamicFeeTxType), l2Data[0])
+}
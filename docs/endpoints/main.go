@@ -30,6 +30,7 @@ type keyValue struct {
 func main() {
 	apiInterfaces := []keyValue{
 		{"admin", (*jsonrpc.AdminAPI)(nil)},
+		{"admin", (*jsonrpc.ZkEvmAdminAPI)(nil)},
 		{"bor", (*jsonrpc.BorAPI)(nil)},
 		{"debug", (*jsonrpc.PrivateDebugAPI)(nil)},
 		{"engine", (*engineapi.EngineAPI)(nil)},
@@ -43,6 +44,7 @@ func main() {
 		{"txpool", (*jsonrpc.TxPoolAPI)(nil)},
 		{"web3", (*jsonrpc.Web3API)(nil)},
 		{"zkevm", (*jsonrpc.ZkEvmAPI)(nil)},
+		{"zkevm", (*jsonrpc.ZkEvmLimboAPI)(nil)},
 	}
 
 	endpointGroups := []keyValue{}
@@ -59,6 +61,12 @@ func main() {
 			apiEndpoints = append(apiEndpoints, endpointName)
 		}
 
+		// several interfaces may be served on the same namespace
+		if last := len(endpointGroups) - 1; last >= 0 && endpointGroups[last].Key == apiPrefix {
+			apiEndpoints = append(endpointGroups[last].Value.([]string), apiEndpoints...)
+			endpointGroups = endpointGroups[:last]
+		}
+
 		sort.Slice(apiEndpoints, func(i, j int) bool {
 			return apiEndpoints[i] < apiEndpoints[j]
 		})
@@ -14,6 +14,7 @@ const (
 	ForkID11
 	ForkID12Banana
 	ForkId13Durian
+	ForkId14TypedTxs
 
 	// ImpossibleForkId is a fork ID that is greater than any possible fork ID
 	// Nothing should be added after this line
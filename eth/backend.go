@@ -131,6 +131,7 @@ import (
 	stages2 "github.com/ledgerwatch/erigon/turbo/stages"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/erigon/zk/contracts"
+	"github.com/ledgerwatch/erigon/zk/da"
 	"github.com/ledgerwatch/erigon/zk/datastream/client"
 	"github.com/ledgerwatch/erigon/zk/datastream/server"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
@@ -139,6 +140,7 @@ import (
 	"github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
 	zkStages "github.com/ledgerwatch/erigon/zk/stages"
 	"github.com/ledgerwatch/erigon/zk/syncer"
+	"github.com/ledgerwatch/erigon/zk/txforwarder"
 	txpool2 "github.com/ledgerwatch/erigon/zk/txpool"
 	"github.com/ledgerwatch/erigon/zk/txpool/txpooluitl"
 	"github.com/ledgerwatch/erigon/zk/utils"
@@ -231,6 +233,10 @@ type Ethereum struct {
 	l1Syncer        *syncer.L1Syncer
 	etherManClients []*etherman.Client
 	l1Cache         *l1_cache.L1Cache
+	legacyExecutors []*legacy_executor_verifier.Executor
+	failover        *sequencer.Failover
+	// followStages builds the stages a node sharing the sequencer lease follows the lease holder with
+	followStages func(ctx context.Context, leader sequencer.Leader) ([]*stagedsync.Stage, error)
 
 	preStartTasks *PreStartTasks
 
@@ -243,6 +249,8 @@ type Ethereum struct {
 	polygonSyncService polygonsync.Service
 	stopNode           func() error
 	gasTracker         *jsonrpc.RecurringL1GasPriceTracker
+	txForwarder        *txforwarder.Forwarder
+	l2GasTracker       *jsonrpc.RecurringL2GasPriceTracker
 }
 
 func splitAddrIntoHostAndPort(addr string) (host string, port int, err error) {
@@ -979,6 +987,41 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 	}
 
 	if backend.config.Zk != nil {
+		// a sequencer sharing a lease file only produces blocks while it holds the lease and follows the holder
+		// otherwise, a standby only holds it once promoted.  Both switch role in place, see runFailoverStageLoop.
+		if backend.config.SequencerLeaseFile != "" && backend.config.SequencerRole != sequencer.RoleRpc {
+			hostname, _ := os.Hostname()
+			backend.failover = sequencer.NewFailover(sequencer.FailoverConfig{
+				Role:      backend.config.SequencerRole,
+				LeaseFile: backend.config.SequencerLeaseFile,
+				LeaseTTL:  backend.config.SequencerLeaseTTL,
+				Holder: sequencer.Leader{
+					Holder:        fmt.Sprintf("%s:%s", hostname, stack.Config().Dirs.DataDir),
+					RpcUrl:        backend.config.SequencerLeaseRpcUrl,
+					DataStreamUrl: backend.config.SequencerLeaseDataStreamUrl,
+				},
+				SequencerRpcUrl:        backend.config.L2RpcUrl,
+				SequencerDataStreamUrl: backend.config.L2DataStreamerUrl,
+				LocalBlock: func(ctx context.Context) (progress uint64, err error) {
+					err = backend.chainDB.View(ctx, func(tx kv.Tx) error {
+						progress, err = stages.GetStageProgress(tx, stages.Finish)
+						return err
+					})
+					return progress, err
+				},
+			})
+			sequencing, err := backend.failover.Resume()
+			if err != nil {
+				return nil, err
+			}
+			if sequencing {
+				sequencer.SetRole(sequencer.RoleSequencer)
+			} else {
+				sequencer.SetRole(sequencer.RoleRpc)
+			}
+			go backend.failover.Run(ctx)
+		}
+
 		// setup the gas tracker and start it
 		backend.gasTracker = jsonrpc.NewRecurringL1GasPriceTracker(
 			backend.config.AllowFreeTransactions,
@@ -990,6 +1033,26 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 			backend.config.GasPriceHistoryCount,
 		)
 
+		// rpc nodes answer eth_gasPrice from the gas price of the sequencer, a node sharing the sequencer lease asks
+		// whichever node holds it while it is following
+		if !sequencer.IsSequencer() || backend.failover != nil {
+			backend.l2GasTracker = jsonrpc.NewRecurringL2GasPriceTracker(backend.sequencerRpcUrl, backend.config.L2GasPriceCheckFrequency)
+		}
+
+		// rpc nodes can keep the transactions they forward to the sequencer until they are mined, a node sharing the
+		// sequencer lease forwards to whichever node holds it while it is following
+		if (!sequencer.IsSequencer() || backend.failover != nil) && backend.config.TxForwardingQueue && backend.config.PoolManagerUrl == "" {
+			backend.txForwarder, err = txforwarder.New(ctx, stack.Config().Dirs.DataDir, txforwarder.Config{
+				SequencerRpcUrl:  backend.sequencerRpcUrl,
+				MaxSize:          backend.config.TxForwardingQueueSize,
+				RetryInterval:    backend.config.TxForwardingRetryInterval,
+				MaxRetryInterval: backend.config.TxForwardingMaxRetryInterval,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// zkevm: create a data stream server if we have the appropriate config for one.  This will be started on the call to Init
 		// alongside the http server
 		httpCfg := stack.Config().Http
@@ -1001,11 +1064,24 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 				Outputs:     nil,
 			}
 
+			tlsCfg := &server.TLSConfig{
+				CertFile:            httpCfg.DataStreamTLSCert,
+				KeyFile:             httpCfg.DataStreamTLSKey,
+				ClientCAFile:        httpCfg.DataStreamTLSClientCA,
+				AllowedFingerprints: httpCfg.DataStreamTLSAllowedFingerprints,
+			}
+
 			// todo [zkevm] read the stream version from config and figure out what system id is used for
 			backend.streamServer, err = dataStreamServerFactory.CreateStreamServer(uint16(httpCfg.DataStreamPort), 1, datastreamer.StreamType(1), file, httpCfg.DataStreamWriteTimeout, httpCfg.DataStreamInactivityTimeout, httpCfg.DataStreamInactivityCheckInterval, logConfig)
 			if err != nil {
 				return nil, err
 			}
+			// with TLS enabled the TLS listener takes the port and hands authenticated clients to the stream server
+			if tlsCfg.Enabled() {
+				if backend.streamServer, err = dataStreamServerFactory.CreateTLSStreamServer(backend.streamServer, uint16(httpCfg.DataStreamPort), tlsCfg); err != nil {
+					return nil, err
+				}
+			}
 
 			// recovery here now, if the stream got into a bad state we want to be able to delete the file and have
 			// the stream re-populated from scratch.  So we check the stream for the latest header and if it is
@@ -1028,12 +1104,20 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 		l1Urls := strings.Split(cfg.L1RpcUrl, ",")
 
 		if cfg.Zk.L1CacheEnabled {
-			l1Cache, err := l1_cache.NewL1Cache(ctx, path.Join(stack.DataDir(), "l1cache"), cfg.Zk.L1CachePort)
+			l1Cache, err := l1_cache.NewL1Cache(ctx, l1_cache.Config{
+				DbPath:     path.Join(stack.DataDir(), "l1cache"),
+				Port:       cfg.Zk.L1CachePort,
+				ChainID:    cfg.L2ChainId,
+				Upstreams:  l1Urls,
+				MethodTTLs: cfg.Zk.L1CacheMethodTTLs,
+			})
 			if err != nil {
 				return nil, err
 			}
 			backend.l1Cache = l1Cache
 
+			// each client keeps its own provider, the cache only falls back to the others when it fails. The cache keys
+			// have always been namespaced by the L2 chain id, changing it would discard existing caches
 			var cacheL1Urls []string
 			for _, l1Url := range l1Urls {
 				encoded := url.QueryEscape(l1Url)
@@ -1048,7 +1132,9 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 			backend.etherManClients[i] = newEtherMan(cfg, chainConfig.ChainName, url)
 		}
 
-		isSequencer := sequencer.IsSequencer()
+		// a node sharing the sequencer lease builds the sequencer stages even while it follows, to switch to them in
+		// place once it is promoted
+		isSequencer := sequencer.IsSequencer() || backend.failover != nil
 
 		// if the L1 block sync is set we're in recovery so can't run as a sequencer
 		if cfg.IsL1Recovery() {
@@ -1149,16 +1235,21 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 			var legacyExecutors []*legacy_executor_verifier.Executor = make([]*legacy_executor_verifier.Executor, 0, len(cfg.ExecutorUrls))
 			if len(cfg.ExecutorUrls) > 0 && cfg.ExecutorUrls[0] != "" {
 				levCfg := legacy_executor_verifier.Config{
-					GrpcUrls:              cfg.ExecutorUrls,
-					Timeout:               cfg.ExecutorRequestTimeout,
-					MaxConcurrentRequests: cfg.ExecutorMaxConcurrentRequests,
-					OutputLocation:        cfg.ExecutorPayloadOutput,
+					GrpcUrls:               cfg.ExecutorUrls,
+					Timeout:                cfg.ExecutorRequestTimeout,
+					MaxConcurrentRequests:  cfg.ExecutorMaxConcurrentRequests,
+					OutputLocation:         cfg.ExecutorPayloadOutput,
+					Weights:                cfg.ExecutorWeights,
+					InFlightLimits:         cfg.ExecutorConcurrencyLimits,
+					CircuitBreakerFailures: cfg.ExecutorCircuitBreakerFailures,
+					CircuitBreakerCooldown: cfg.ExecutorCircuitBreakerCooldown,
 				}
 				executors := legacy_executor_verifier.NewExecutors(levCfg)
 				for _, e := range executors {
 					legacyExecutors = append(legacyExecutors, e)
 				}
 			}
+			backend.legacyExecutors = legacyExecutors
 
 			verifier := legacy_executor_verifier.NewLegacyExecutorVerifier(
 				*cfg.Zk,
@@ -1177,6 +1268,20 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 			// we switch context from being an RPC node to a sequencer
 			backend.txPool2.ForceUpdateLatestBlock(executionProgress)
 
+			var daBackend da.DABackend
+			daClient, err := da.NewClientFromConfig(da.Config{
+				URLs:            cfg.DAUrls,
+				QuorumURLs:      cfg.DAQuorumUrls,
+				QuorumThreshold: cfg.DAQuorumThreshold,
+				CacheSize:       cfg.DACacheSize,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if daClient != nil {
+				daBackend = daClient
+			}
+
 			l1BlockSyncer := syncer.NewL1Syncer(
 				ctx,
 				ethermanClients,
@@ -1208,11 +1313,44 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 				backend.txPool2DB,
 				verifier,
 				l1InfoTreeUpdater,
+				backend.gasTracker,
+				daBackend,
+				backend.failover,
 				hook,
 			)
 
 			backend.syncUnwindOrder = zkStages.ZkSequencerUnwindOrder
 
+			if backend.failover != nil {
+				backend.followStages = func(ctx context.Context, leader sequencer.Leader) ([]*stagedsync.Stage, error) {
+					var latestForkId uint64
+					if err := backend.chainDB.View(ctx, func(tx kv.Tx) (err error) {
+						latestForkId, err = stages.GetStageProgress(tx, stages.ForkId)
+						return err
+					}); err != nil {
+						return nil, err
+					}
+					streamClient := client.NewClient(ctx, leader.DataStreamUrl, cfg.L2DataStreamerUseTLS, cfg.L2DataStreamerTimeout, uint16(latestForkId), cfg.L2DataStreamerMaxEntryChan)
+
+					return stages2.NewDefaultZkStages(
+						ctx,
+						backend.chainDB,
+						config,
+						backend.sentriesClient,
+						backend.notifications,
+						backend.downloaderClient,
+						allSnapshots,
+						backend.agg,
+						backend.forkValidator,
+						backend.engine,
+						seqVerSyncer,
+						streamClient,
+						dataStreamServer,
+						l1InfoTreeUpdater,
+					), nil
+				}
+			}
+
 		} else {
 			/*
 			 if we are syncing from for the RPC, we do the normal ZK sync loop
@@ -1228,7 +1366,12 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 			if err != nil {
 				return nil, err
 			}
-			streamClient := initDataStreamClient(ctx, cfg.Zk, uint16(latestForkId))
+			var streamClient zkStages.DatastreamClient
+			if cfg.Zk.L2DataStreamerFile != "" {
+				streamClient = client.NewFileClient(ctx, cfg.Zk.L2DataStreamerFile, cfg.L2DataStreamerMaxEntryChan)
+			} else {
+				streamClient = initDataStreamClient(ctx, cfg.Zk, uint16(latestForkId))
+			}
 
 			backend.syncStages = stages2.NewDefaultZkStages(
 				backend.sentryCtx,
@@ -1249,7 +1392,7 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 
 			backend.syncUnwindOrder = zkStages.ZkUnwindOrder
 		}
-		// TODO: SEQ: prune order
+		backend.syncPruneOrder = zkStages.ZkPruneOrder
 
 	} else {
 		backend.syncStages = stages2.NewDefaultStages(backend.sentryCtx, backend.chainDB, snapDb, stack.Config().P2P, config, backend.sentriesClient, backend.notifications, backend.downloaderClient, blockReader, blockRetire, backend.agg, backend.silkworm, backend.forkValidator, heimdallClient, recents, signatures, logger)
@@ -1368,7 +1511,7 @@ func (s *Ethereum) Init(stack *node.Node, config *ethconfig.Config, chainConfig
 	if s.streamServer != nil {
 		dataStreamServer = dataStreamServerFactory.CreateDataStreamServer(s.streamServer, config.Zk.L2ChainId)
 	}
-	s.apiList = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, s.txPool2, miningRpcClient, ff, stateCache, blockReader, s.agg, &httpRpcCfg, s.engine, config, s.l1Syncer, s.logger, dataStreamServer, s.gasTracker)
+	s.apiList = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, s.txPool2, miningRpcClient, ff, stateCache, blockReader, s.agg, &httpRpcCfg, s.engine, config, s.l1Syncer, s.logger, dataStreamServer, s.gasTracker, s.legacyExecutors, s.txForwarder, s.l2GasTracker, s.failover)
 
 	if config.SilkwormRpcDaemon && httpRpcCfg.Enabled {
 		interface_log_settings := silkworm.RpcInterfaceLogSettings{
@@ -1952,7 +2095,11 @@ func (s *Ethereum) Start() error {
 		if s.config.DebugNoSync {
 			return nil
 		}
-		go stages2.StageLoop(s.sentryCtx, s.chainDB, s.stagedSync, s.sentriesClient.Hd, s.waitForStageLoopStop, s.config.Sync.LoopThrottle, s.logger, s.blockReader, hook, s.config.ForcePartialCommit)
+		if s.failover != nil {
+			go s.runFailoverStageLoop(hook)
+		} else {
+			go stages2.StageLoop(s.sentryCtx, s.chainDB, s.stagedSync, s.sentriesClient.Hd, s.waitForStageLoopStop, s.config.Sync.LoopThrottle, s.logger, s.blockReader, hook, s.config.ForcePartialCommit)
+		}
 	}
 
 	stages := diagnostics.InitStagesFromList(nodeStages)
@@ -1963,6 +2110,12 @@ func (s *Ethereum) Start() error {
 	}
 
 	s.gasTracker.Start()
+	if s.l2GasTracker != nil {
+		s.l2GasTracker.Start()
+	}
+	if s.txForwarder != nil {
+		s.txForwarder.Start()
+	}
 
 	// if s.silkwormRPCDaemonService != nil {
 	// 	if err := s.silkwormRPCDaemonService.Start(); err != nil {
@@ -1978,6 +2131,45 @@ func (s *Ethereum) Start() error {
 	return nil
 }
 
+// sequencerRpcUrl returns the url of the sequencer this node forwards to, the holder of the sequencer lease when the
+// node shares one.  It is empty while the node is sequencing itself.
+func (s *Ethereum) sequencerRpcUrl() string {
+	if s.failover == nil {
+		return s.config.L2RpcUrl
+	}
+	if s.failover.Sequencing() {
+		return ""
+	}
+	return s.failover.SequencerRpcUrl()
+}
+
+// runFailoverStageLoop runs the sequencer stages while the node holds the sequencer lease and the rpc stages following
+// the lease holder's datastream otherwise.  The running stage loop is stopped and the other one started whenever the
+// node is promoted, steps down, loses the lease or the lease changes hands, so the node switches role in place.
+func (s *Ethereum) runFailoverStageLoop(hook *stages2.Hook) {
+	defer close(s.waitForStageLoopStop)
+
+	s.failover.Switch(s.sentryCtx, func(ctx context.Context) {
+		sequencer.SetRole(sequencer.RoleSequencer)
+		s.logger.Info("Starting the sequencer stage loop")
+		s.runStageLoop(ctx, s.stagedSync, hook)
+	}, func(ctx context.Context, leader sequencer.Leader) {
+		sequencer.SetRole(sequencer.RoleRpc)
+		s.logger.Info("Starting the stage loop following the sequencer", "holder", leader.Holder, "datastream", leader.DataStreamUrl)
+		followStages, err := s.followStages(ctx, leader)
+		if err != nil {
+			s.logger.Error("Failed to set up the stages following the sequencer", "err", err)
+			<-ctx.Done()
+			return
+		}
+		s.runStageLoop(ctx, stagedsync.New(s.config.Sync, followStages, zkStages.ZkUnwindOrder, zkStages.ZkPruneOrder, s.logger), hook)
+	})
+}
+
+func (s *Ethereum) runStageLoop(ctx context.Context, sync *stagedsync.Sync, hook *stages2.Hook) {
+	stages2.StageLoop(ctx, s.chainDB, sync, s.sentriesClient.Hd, make(chan struct{}), s.config.Sync.LoopThrottle, s.logger, s.blockReader, hook, s.config.ForcePartialCommit)
+}
+
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
@@ -2020,7 +2212,16 @@ func (s *Ethereum) Stop() error {
 	}
 	s.chainDB.Close()
 
+	if stopper, ok := s.streamServer.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
 	s.gasTracker.Stop()
+	if s.l2GasTracker != nil {
+		s.l2GasTracker.Stop()
+	}
+	if s.txForwarder != nil {
+		s.txForwarder.Stop()
+	}
 
 	if s.silkwormRPCDaemonService != nil {
 		if err := s.silkwormRPCDaemonService.Stop(); err != nil {
@@ -6,6 +6,8 @@ import (
 	"github.com/c2h5oh/datasize"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 )
 
 type Zk struct {
@@ -14,6 +16,7 @@ type Zk struct {
 	L2DataStreamerUrl                      string
 	L2DataStreamerMaxEntryChan             uint64
 	L2DataStreamerUseTLS                   bool
+	L2DataStreamerFile                     string
 	L2DataStreamerTimeout                  time.Duration
 	L2ShortCircuitToVerifiedBatch          bool
 	L1SyncStartBlock                       uint64
@@ -36,8 +39,10 @@ type Zk struct {
 	L1FinalizedBlockRequirement            uint64
 	L1CacheEnabled                         bool
 	L1CachePort                            uint
+	L1CacheMethodTTLs                      map[string]time.Duration
 	RpcRateLimits                          int
 	RpcGetBatchWitnessConcurrencyLimit     int
+	RpcGetBatchStateProofConcurrencyLimit  int
 	SequencerBlockSealTime                 time.Duration
 	SequencerEmptyBlockSealTime            time.Duration
 	SequencerBatchSealTime                 time.Duration
@@ -48,8 +53,11 @@ type Zk struct {
 	SequencerResequence                    bool
 	SequencerResequenceStrict              bool
 	SequencerResequenceReuseL1InfoIndex    bool
+	SequencerResequenceDryRun              bool
 	SequencerDecodedTxCacheSize            int
 	SequencerDecodedTxCacheTTL             time.Duration
+	SequencerTxOrdering                    string
+	SequencerMaxTxsPerSender               int
 	ExecutorUrls                           []string
 	ExecutorStrictMode                     bool
 	ExecutorRequestTimeout                 time.Duration
@@ -58,7 +66,12 @@ type Zk struct {
 	WitnessMemdbSize                       datasize.ByteSize
 	WitnessUnwindLimit                     uint64
 	ExecutorMaxConcurrentRequests          int
+	ExecutorWeights                        []int
+	ExecutorConcurrencyLimits              []int
+	ExecutorCircuitBreakerFailures         int
+	ExecutorCircuitBreakerCooldown         time.Duration
 	Limbo                                  bool
+	AdminToken                             string
 	AllowFreeTransactions                  bool
 	AllowPreEIP155Transactions             bool
 	EffectiveGasPriceForEthTransfer        uint8
@@ -70,7 +83,12 @@ type Zk struct {
 	GasPriceFactor                         float64
 	GasPriceCheckFrequency                 time.Duration
 	GasPriceHistoryCount                   uint64
-	DAUrl                                  string
+	L2GasPriceCheckFrequency               time.Duration
+	RpcReceiptEffectiveGasPriceDetails     bool
+	DAUrls                                 []string
+	DAQuorumUrls                           []string
+	DAQuorumThreshold                      int
+	DACacheSize                            int
 	DataStreamHost                         string
 	DataStreamPort                         uint
 	DataStreamWriteTimeout                 time.Duration
@@ -78,6 +96,11 @@ type Zk struct {
 	DataStreamInactivityCheckInterval      time.Duration
 	PanicOnReorg                           bool
 	ShadowSequencer                        bool
+	SequencerRole                          sequencer.Role
+	SequencerLeaseFile                     string
+	SequencerLeaseTTL                      time.Duration
+	SequencerLeaseRpcUrl                   string
+	SequencerLeaseDataStreamUrl            string
 
 	RebuildTreeAfter         uint64
 	IncrementTreeAlways      bool
@@ -100,6 +123,15 @@ type Zk struct {
 	VirtualCountersSmtReduction float64
 	ExecutorPayloadOutput       string
 
+	TxForwardingQueue            bool
+	TxForwardingQueueSize        int
+	TxForwardingRetryInterval    time.Duration
+	TxForwardingMaxRetryInterval time.Duration
+
+	SmtPruneMode          string
+	SmtPruneRetainBatches uint64
+	SmtPruneStep          int
+
 	TxPoolRejectSmartContractDeployments bool
 
 	InitialBatchCfgFile            string
@@ -123,6 +155,8 @@ type Zk struct {
 	BadTxPurge                     bool
 	L2InfoTreeUpdatesBatchSize     uint64
 	L2InfoTreeUpdatesEnabled       bool
+	BridgeIndexerEnabled           bool
+	AddressL2Bridge                common.Address
 }
 
 var DefaultZkConfig = &Zk{}
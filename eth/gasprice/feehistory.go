@@ -112,7 +112,7 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		baseFee.SetFromBig(bf.block.BaseFee())
 	}
 	for i, tx := range bf.block.Transactions() {
-		reward := tx.GetEffectiveGasTip(baseFee)
+		reward := oracle.effectiveGasTip(tx, bf.blockNumber, baseFee)
 		sorter[i] = txGasAndReward{gasUsed: bf.receipts[i].GasUsed, reward: reward.ToBig()}
 	}
 	sort.Sort(sorter)
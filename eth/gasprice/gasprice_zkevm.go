@@ -0,0 +1,37 @@
+package gasprice
+
+import (
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// EffectiveGasPriceBackend is implemented by zkevm oracle backends.  From the dragonfruit fork the sequencer only charges
+// a percentage of the gas price a transaction is signed with, the fee history reports what users actually paid.  The
+// suggested gas price keeps sampling the signed price, as the sequencer checks the signed price against its own before
+// applying the percentage.
+type EffectiveGasPriceBackend interface {
+	GetEffectiveGasPricePercentage(txHash libcommon.Hash) (uint8, error)
+}
+
+// effectiveGasTip returns the tip the transaction paid, scaled by its effective gas price percentage when the backend
+// knows it
+func (oracle *Oracle) effectiveGasTip(tx types.Transaction, blockNum uint64, baseFee *uint256.Int) *uint256.Int {
+	tip := tx.GetEffectiveGasTip(baseFee)
+
+	backend, ok := oracle.backend.(EffectiveGasPriceBackend)
+	if !ok || !oracle.backend.ChainConfig().IsForkID5Dragonfruit(blockNum) {
+		return tip
+	}
+
+	percentage, err := backend.GetEffectiveGasPricePercentage(tx.Hash())
+	if err != nil {
+		log.Warn("gasprice.go: could not read the effective gas price percentage", "hash", tx.Hash(), "err", err)
+		return tip
+	}
+
+	return core.CalculateEffectiveGas(tip.Clone(), percentage)
+}
@@ -0,0 +1,116 @@
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/gasprice/gaspricecfg"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// zkTestBackend serves in memory blocks with one transaction each, priced 1G, 2G, ... and charges every transaction
+// the same effective gas price percentage
+type zkTestBackend struct {
+	cfg        *chain.Config
+	blocks     []*types.Block
+	percentage uint8
+}
+
+func newZkTestBackend(blockCount int, percentage uint8, dragonfruitBlock *big.Int) *zkTestBackend {
+	b := &zkTestBackend{cfg: &chain.Config{ChainID: big.NewInt(1), ForkID5DragonfruitBlock: dragonfruitBlock}, percentage: percentage}
+	for i := 0; i <= blockCount; i++ {
+		var txs []types.Transaction
+		if i > 0 {
+			txs = append(txs, types.NewTransaction(uint64(i), libcommon.Address{2}, uint256.NewInt(1), 21000, uint256.NewInt(uint64(i)*params.GWei), nil))
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), Coinbase: libcommon.Address{1}, GasLimit: 30_000_000, GasUsed: 21000}
+		b.blocks = append(b.blocks, types.NewBlock(header, txs, nil, nil, nil))
+	}
+	return b
+}
+
+func (b *zkTestBackend) block(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber {
+		return b.blocks[len(b.blocks)-1]
+	}
+	if int(number) >= len(b.blocks) {
+		return nil
+	}
+	return b.blocks[number]
+}
+
+func (b *zkTestBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if block := b.block(number); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *zkTestBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.block(number), nil
+}
+
+func (b *zkTestBackend) ChainConfig() *chain.Config {
+	return b.cfg
+}
+
+func (b *zkTestBackend) GetReceipts(ctx context.Context, block *types.Block) (types.Receipts, error) {
+	receipts := make(types.Receipts, len(block.Transactions()))
+	for i := range receipts {
+		receipts[i] = &types.Receipt{GasUsed: 21000}
+	}
+	return receipts, nil
+}
+
+func (b *zkTestBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	return nil, nil
+}
+
+func (b *zkTestBackend) GetEffectiveGasPricePercentage(txHash libcommon.Hash) (uint8, error) {
+	return b.percentage, nil
+}
+
+type testCache struct {
+	hash  libcommon.Hash
+	price *big.Int
+}
+
+func (c *testCache) GetLatest() (libcommon.Hash, *big.Int) { return c.hash, c.price }
+
+func (c *testCache) SetLatest(hash libcommon.Hash, price *big.Int) { c.hash, c.price = hash, price }
+
+func TestSuggestTipCapSignedGasPrice(t *testing.T) {
+	config := gaspricecfg.Config{
+		Blocks:     2,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	}
+
+	// the sampled gas prices are 10G, 9G, 8G, 7G, 6G and 5G, the 60th percentile is 8G
+	oracle := NewOracle(newZkTestBackend(10, 255, nil), config, &testCache{})
+	got, err := oracle.SuggestTipCap(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(8*params.GWei), got)
+
+	// from dragonfruit the sequencer only charged (63+1)/256 of it, the suggestion is still made from the signed price
+	oracle = NewOracle(newZkTestBackend(10, 63, big.NewInt(0)), config, &testCache{})
+	got, err = oracle.SuggestTipCap(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(8*params.GWei), got)
+}
+
+func TestFeeHistoryEffectiveGasPrice(t *testing.T) {
+	oracle := NewOracle(newZkTestBackend(10, 127, big.NewInt(0)), gaspricecfg.Config{MaxBlockHistory: 10}, &testCache{})
+
+	_, reward, _, _, err := oracle.FeeHistory(context.Background(), 2, rpc.LatestBlockNumber, []float64{50})
+	require.NoError(t, err)
+	require.Equal(t, [][]*big.Int{{big.NewInt(params.GWei * 9 / 2)}, {big.NewInt(params.GWei * 5)}}, reward)
+}
@@ -39,6 +39,7 @@ var SyncStagesZk = map[string]SyncStage{
 	"highest_seen_batch_no":          HighestSeenBatchNumber,
 	"data_stream":                    DataStream,
 	"witness":                        Witness,
+	"bridge_indexer":                 BridgeIndexer,
 	"fork_id":                        ForkId,
 	"sequence_executor_verify":       SequenceExecutorVerify,
 }
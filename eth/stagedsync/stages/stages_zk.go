@@ -32,4 +32,5 @@ var (
 	SequenceExecutorVerify SyncStage = "SequenceExecutorVerify"
 	L1BlockSync            SyncStage = "L1BlockSync"
 	Witness                SyncStage = "Witness"
+	BridgeIndexer          SyncStage = "BridgeIndexer"
 )
@@ -0,0 +1,370 @@
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("zkCounterTracer", newZkCounterTracer)
+}
+
+const (
+	zkCounterFormatJson      = "json"
+	zkCounterFormatCollapsed = "collapsed"
+
+	zkCounterSelectorFallback    = "fallback"
+	zkCounterSelectorConstructor = "constructor"
+	zkCounterSelectorPrecompile  = "precompile"
+)
+
+// known zkEVM precompile names keyed by their address, used to label precompile frames
+var zkPrecompileNames = map[libcommon.Address]string{
+	libcommon.BytesToAddress([]byte{1}): "ecrecover",
+	libcommon.BytesToAddress([]byte{2}): "sha256",
+	libcommon.BytesToAddress([]byte{3}): "ripemd160",
+	libcommon.BytesToAddress([]byte{4}): "identity",
+	libcommon.BytesToAddress([]byte{5}): "modexp",
+	libcommon.BytesToAddress([]byte{6}): "ecAdd",
+	libcommon.BytesToAddress([]byte{7}): "ecMul",
+	libcommon.BytesToAddress([]byte{8}): "ecPairing",
+	libcommon.BytesToAddress([]byte{9}): "blake2f",
+}
+
+// zkCounterTotals holds the used amount for every counter type, keyed by the counter name
+type zkCounterTotals map[vm.CounterName]int
+
+func (t zkCounterTotals) add(delta []int) {
+	for k, v := range delta {
+		if v == 0 {
+			continue
+		}
+		t[vm.CounterKeyNames[k]] += v
+	}
+}
+
+type zkCounterFrame struct {
+	Type       string                     `json:"type"`
+	Address    libcommon.Address          `json:"address"`
+	Selector   string                     `json:"selector"`
+	Precompile string                     `json:"precompile,omitempty"`
+	Self       zkCounterTotals            `json:"self"`
+	Total      zkCounterTotals            `json:"total"`
+	Opcodes    map[string]zkCounterTotals `json:"opcodes,omitempty"`
+	Calls      []*zkCounterFrame          `json:"calls,omitempty"`
+}
+
+func newZkCounterFrame(typ vm.OpCode, address libcommon.Address, input []byte, create, precompile bool) *zkCounterFrame {
+	f := &zkCounterFrame{
+		Type:    typ.String(),
+		Address: address,
+		Self:    zkCounterTotals{},
+		Total:   zkCounterTotals{},
+		Opcodes: map[string]zkCounterTotals{},
+	}
+	switch {
+	case precompile:
+		f.Selector = zkCounterSelectorPrecompile
+		if name, ok := zkPrecompileNames[address]; ok {
+			f.Precompile = name
+		} else {
+			f.Precompile = address.Hex()
+		}
+	case create:
+		f.Selector = zkCounterSelectorConstructor
+	case len(input) >= 4:
+		f.Selector = bytesToHex(input[:4])
+	default:
+		f.Selector = zkCounterSelectorFallback
+	}
+	return f
+}
+
+// label is the name used for the frame in the collapsed stack output
+func (f *zkCounterFrame) label() string {
+	if f.Precompile != "" {
+		return "precompile:" + f.Precompile
+	}
+	return f.Address.Hex() + ":" + f.Selector
+}
+
+type zkCounterTracerConfig struct {
+	Format  string `json:"format"`  // json (default) or collapsed
+	Counter string `json:"counter"` // counter to use for collapsed output, steps (S) by default
+}
+
+// zkCounterResult is the aggregated counter profile returned in json format
+type zkCounterResult struct {
+	SmtLevels   int                                   `json:"smtLevels"`
+	Total       zkCounterTotals                       `json:"total"`
+	Contracts   map[libcommon.Address]zkCounterTotals `json:"contracts"`
+	Selectors   map[string]zkCounterTotals            `json:"selectors"`
+	Opcodes     map[string]zkCounterTotals            `json:"opcodes"`
+	Precompiles map[string]zkCounterTotals            `json:"precompiles"`
+	CallTree    *zkCounterFrame                       `json:"callTree"`
+}
+
+// zkCounterTracer folds the deductions made against the zk execution counters into
+// a call tree, attributing every deduction to the frame and opcode that caused it.
+// The resulting profile can be returned either as json or in the collapsed stack
+// format consumed by flamegraph tooling.
+//
+// Example:
+//
+//	> debug.traceTransactionCounters("0x...", {tracer: "zkCounterTracer", tracerConfig: {format: "collapsed", counter: "K"}})
+type zkCounterTracer struct {
+	noopTracer
+	config    zkCounterTracerConfig
+	collector *vm.CounterCollector
+	callstack []*zkCounterFrame
+	root      *zkCounterFrame
+	last      []int
+	// pendingOp is the opcode that the next counter delta is attributed to, empty
+	// when the delta belongs to the frame itself (e.g. precompile execution)
+	pendingOp    string
+	pendingFrame *zkCounterFrame
+	callOps      []string // opcode in the parent frame that entered each nested frame
+	interrupt    uint32   // Atomic flag to signal execution interruption
+	reason       error    // Textual reason for the interruption
+}
+
+// newZkCounterTracer returns a native go tracer which aggregates the zk counters
+// used by a transaction per call frame, contract, function selector, opcode and precompile.
+func newZkCounterTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	config := zkCounterTracerConfig{
+		Format:  zkCounterFormatJson,
+		Counter: string(vm.CounterKeyNames[vm.S]),
+	}
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	if config.Format != zkCounterFormatJson && config.Format != zkCounterFormatCollapsed {
+		return nil, fmt.Errorf("unknown output format %q", config.Format)
+	}
+	if counterKeyByName(config.Counter) < 0 {
+		return nil, fmt.Errorf("unknown counter %q", config.Counter)
+	}
+	if ctx == nil || ctx.CounterCollector == nil {
+		return nil, fmt.Errorf("zkCounterTracer requires a counter collector")
+	}
+	return &zkCounterTracer{
+		config:    config,
+		collector: ctx.CounterCollector,
+	}, nil
+}
+
+func counterKeyByName(name string) int {
+	for k, v := range vm.CounterKeyNames {
+		if string(v) == name {
+			return k
+		}
+	}
+	return -1
+}
+
+// flush attributes everything deducted since the last snapshot to the pending frame and opcode
+func (t *zkCounterTracer) flush() {
+	current := t.collector.Counters().UsedAsArray()
+	if t.last == nil {
+		t.last = current
+		return
+	}
+	delta := make([]int, len(current))
+	changed := false
+	for k := range current {
+		delta[k] = current[k] - t.last[k]
+		if delta[k] != 0 {
+			changed = true
+		}
+	}
+	t.last = current
+	if !changed || t.pendingFrame == nil {
+		return
+	}
+	t.pendingFrame.Self.add(delta)
+	if t.pendingOp != "" {
+		getZkCounterTotals(t.pendingFrame.Opcodes, t.pendingOp).add(delta)
+	}
+}
+
+func (t *zkCounterTracer) push(f *zkCounterFrame) {
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, f)
+	}
+	t.callstack = append(t.callstack, f)
+	t.pendingFrame = f
+	t.pendingOp = ""
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *zkCounterTracer) CaptureStart(env *vm.EVM, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.flush()
+	t.root = newZkCounterFrame(typ, to, input, create, precompile)
+	t.push(t.root)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+// The counters for an opcode are deducted when it executes, after CaptureState has
+// been called, so the delta seen here belongs to the previous opcode.
+func (t *zkCounterTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.callstack) == 0 {
+		return
+	}
+	t.flush()
+	t.pendingFrame = t.callstack[len(t.callstack)-1]
+	t.pendingOp = op.String()
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *zkCounterTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.callstack) == 0 {
+		return
+	}
+	t.flush()
+	t.callOps = append(t.callOps, t.pendingOp)
+	t.push(newZkCounterFrame(typ, to, input, create, precompile))
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *zkCounterTracer) CaptureExit(output []byte, usedGas uint64, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.callstack) <= 1 {
+		return
+	}
+	t.flush()
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	// anything deducted after returning belongs to the call opcode in the parent frame
+	t.pendingFrame = t.callstack[len(t.callstack)-1]
+	t.pendingOp = t.callOps[len(t.callOps)-1]
+	t.callOps = t.callOps[:len(t.callOps)-1]
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *zkCounterTracer) CaptureEnd(output []byte, usedGas uint64, err error) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	t.flush()
+	t.pendingFrame = nil
+	t.pendingOp = ""
+}
+
+// GetResult returns the aggregated counter profile, either as a json object or as
+// a json string holding the collapsed stack lines.
+func (t *zkCounterTracer) GetResult() (json.RawMessage, error) {
+	if t.root == nil {
+		return json.RawMessage(`{}`), t.reason
+	}
+	result := &zkCounterResult{
+		SmtLevels:   t.collector.GetSmtLevels(),
+		Total:       zkCounterTotals{},
+		Contracts:   map[libcommon.Address]zkCounterTotals{},
+		Selectors:   map[string]zkCounterTotals{},
+		Opcodes:     map[string]zkCounterTotals{},
+		Precompiles: map[string]zkCounterTotals{},
+		CallTree:    t.root,
+	}
+	aggregateZkCounterFrame(t.root, result)
+
+	var res []byte
+	var err error
+	if t.config.Format == zkCounterFormatCollapsed {
+		key := vm.CounterName(t.config.Counter)
+		var lines []string
+		collapseZkCounterFrame(t.root, "", key, &lines)
+		sort.Strings(lines)
+		res, err = json.Marshal(strings.Join(lines, "\n"))
+	} else {
+		res, err = json.Marshal(result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *zkCounterTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// aggregateZkCounterFrame fills in the inclusive totals of the frame and its children
+// and adds the frame's own usage to the flat views of the result
+func aggregateZkCounterFrame(f *zkCounterFrame, result *zkCounterResult) {
+	mergeZkCounterTotals(f.Total, f.Self)
+	for _, child := range f.Calls {
+		aggregateZkCounterFrame(child, result)
+		mergeZkCounterTotals(f.Total, child.Total)
+	}
+
+	mergeZkCounterTotals(result.Total, f.Self)
+	if f.Precompile != "" {
+		mergeZkCounterTotals(getZkCounterTotals(result.Precompiles, f.Precompile), f.Self)
+		return
+	}
+	contract, ok := result.Contracts[f.Address]
+	if !ok {
+		contract = zkCounterTotals{}
+		result.Contracts[f.Address] = contract
+	}
+	mergeZkCounterTotals(contract, f.Self)
+	mergeZkCounterTotals(getZkCounterTotals(result.Selectors, f.Address.Hex()+":"+f.Selector), f.Self)
+	for op, totals := range f.Opcodes {
+		mergeZkCounterTotals(getZkCounterTotals(result.Opcodes, op), totals)
+	}
+}
+
+// collapseZkCounterFrame writes one "frame;frame;OPCODE value" line for every opcode
+// of every frame that used the requested counter
+func collapseZkCounterFrame(f *zkCounterFrame, prefix string, key vm.CounterName, lines *[]string) {
+	stack := f.label()
+	if prefix != "" {
+		stack = prefix + ";" + stack
+	}
+	attributed := 0
+	for op, totals := range f.Opcodes {
+		if totals[key] == 0 {
+			continue
+		}
+		attributed += totals[key]
+		*lines = append(*lines, fmt.Sprintf("%s;%s %d", stack, op, totals[key]))
+	}
+	if rest := f.Self[key] - attributed; rest != 0 {
+		*lines = append(*lines, fmt.Sprintf("%s %d", stack, rest))
+	}
+	for _, child := range f.Calls {
+		collapseZkCounterFrame(child, stack, key, lines)
+	}
+}
+
+func getZkCounterTotals(m map[string]zkCounterTotals, key string) zkCounterTotals {
+	totals, ok := m[key]
+	if !ok {
+		totals = zkCounterTotals{}
+		m[key] = totals
+	}
+	return totals
+}
+
+func mergeZkCounterTotals(dst, src zkCounterTotals) {
+	for k, v := range src {
+		dst[k] += v
+	}
+}
@@ -0,0 +1,106 @@
+package native
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func runZkCounterTrace(t *testing.T, cfg string) json.RawMessage {
+	t.Helper()
+	cc := vm.NewUnlimitedCounterCollector()
+	tracer, err := newZkCounterTracer(&tracers.Context{CounterCollector: cc}, json.RawMessage(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract := libcommon.HexToAddress("0x1000")
+	callee := libcommon.HexToAddress("0x2000")
+	ecrecover := libcommon.BytesToAddress([]byte{1})
+
+	tracer.CaptureStart(nil, libcommon.Address{}, contract, false, false, []byte{0xaa, 0xbb, 0xcc, 0xdd}, 0, nil, nil)
+	tracer.CaptureState(0, vm.ADD, 0, 0, nil, nil, 1, nil)
+	cc.Deduct(vm.S, 10)
+	tracer.CaptureState(1, vm.CALL, 0, 0, nil, nil, 1, nil)
+	cc.Deduct(vm.S, 5)
+	tracer.CaptureEnter(vm.CALL, contract, callee, false, false, []byte{0x01, 0x02, 0x03, 0x04, 0x05}, 0, nil, nil)
+	tracer.CaptureState(0, vm.KECCAK256, 0, 0, nil, nil, 2, nil)
+	cc.Deduct(vm.S, 7)
+	cc.Deduct(vm.K, 2)
+	tracer.CaptureState(1, vm.STATICCALL, 0, 0, nil, nil, 2, nil)
+	tracer.CaptureEnter(vm.STATICCALL, callee, ecrecover, true, false, nil, 0, nil, nil)
+	cc.Deduct(vm.A, 3)
+	tracer.CaptureExit(nil, 0, nil)
+	tracer.CaptureExit(nil, 0, nil)
+	cc.Deduct(vm.S, 2)
+	tracer.CaptureState(2, vm.STOP, 0, 0, nil, nil, 1, nil)
+	cc.Deduct(vm.S, 1)
+	tracer.CaptureEnd(nil, 0, nil)
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestZkCounterTracerJson(t *testing.T) {
+	var result zkCounterResult
+	if err := json.Unmarshal(runZkCounterTrace(t, `{}`), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Total["S"] != 25 || result.Total["K"] != 2 || result.Total["A"] != 3 {
+		t.Fatalf("unexpected totals %v", result.Total)
+	}
+	if got := result.Opcodes["CALL"]["S"]; got != 7 {
+		t.Fatalf("expected 7 steps on CALL, got %d", got)
+	}
+	if got := result.Opcodes["KECCAK256"]["K"]; got != 2 {
+		t.Fatalf("expected 2 keccaks on KECCAK256, got %d", got)
+	}
+	if got := result.Precompiles["ecrecover"]["A"]; got != 3 {
+		t.Fatalf("expected 3 arith on ecrecover, got %d", got)
+	}
+	if got := result.Selectors[libcommon.HexToAddress("0x2000").Hex()+":0x01020304"]["S"]; got != 7 {
+		t.Fatalf("expected 7 steps on callee selector, got %d", got)
+	}
+	if got := result.Contracts[libcommon.HexToAddress("0x1000")]["S"]; got != 18 {
+		t.Fatalf("expected 18 steps on caller contract, got %d", got)
+	}
+	if got := result.CallTree.Total["S"]; got != 25 {
+		t.Fatalf("expected 25 inclusive steps on root frame, got %d", got)
+	}
+}
+
+func TestZkCounterTracerCollapsed(t *testing.T) {
+	var collapsed string
+	if err := json.Unmarshal(runZkCounterTrace(t, `{"format":"collapsed","counter":"A"}`), &collapsed); err != nil {
+		t.Fatal(err)
+	}
+
+	root := libcommon.HexToAddress("0x1000").Hex() + ":0xaabbccdd"
+	callee := libcommon.HexToAddress("0x2000").Hex() + ":0x01020304"
+	expected := root + ";" + callee + ";precompile:ecrecover 3"
+	if strings.TrimSpace(collapsed) != expected {
+		t.Fatalf("expected %q, got %q", expected, collapsed)
+	}
+}
+
+func TestZkCounterTracerConfig(t *testing.T) {
+	cc := vm.NewUnlimitedCounterCollector()
+	if _, err := newZkCounterTracer(&tracers.Context{CounterCollector: cc}, json.RawMessage(`{"format":"svg"}`)); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if _, err := newZkCounterTracer(&tracers.Context{CounterCollector: cc}, json.RawMessage(`{"counter":"X"}`)); err == nil {
+		t.Fatal("expected error for unknown counter")
+	}
+	if _, err := newZkCounterTracer(&tracers.Context{}, nil); err == nil {
+		t.Fatal("expected error without a counter collector")
+	}
+}
@@ -36,6 +36,7 @@ type Context struct {
 	Txn               types.Transaction
 	CumulativeGasUsed *uint64
 	BlockNum          uint64
+	CounterCollector  *vm.CounterCollector // zkEVM execution counters for the transaction, nil if not counting
 }
 
 // Tracer interface extends vm.EVMLogger and additionally
@@ -153,6 +153,8 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 	defer clean()
 	newSnCh, newSnClean := s.events.AddNewSnapshotSubscription()
 	defer newSnClean()
+	batchCh, batchClean := s.events.AddBatchEventSubscription()
+	defer batchClean()
 	s.logger.Info("new subscription to newHeaders established")
 	defer func() {
 		if err != nil {
@@ -183,6 +185,15 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 			if err = subscribeServer.Send(&remote.SubscribeReply{Type: remote.Event_NEW_SNAPSHOT}); err != nil {
 				return err
 			}
+		case batchEvents := <-batchCh:
+			for _, batchEvent := range batchEvents {
+				if err = subscribeServer.Send(&remote.SubscribeReply{
+					Type: shards.EventZkBatch,
+					Data: batchEvent.Marshall(),
+				}); err != nil {
+					return err
+				}
+			}
 		}
 	}
 }
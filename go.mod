@@ -11,6 +11,9 @@ require (
 
 replace github.com/ledgerwatch/erigon-lib => ./erigon-lib
 
+// patched to serve the stream on a listener provided by the caller, see zkevm-data-streamer/README.md
+replace github.com/0xPolygonHermez/zkevm-data-streamer => ./zkevm-data-streamer
+
 require (
 	gfx.cafe/util/go/generic v0.0.0-20230721185457-c559e86c829c
 	github.com/0xPolygonHermez/zkevm-data-streamer v0.2.8
@@ -91,6 +94,7 @@ require (
 	github.com/prysmaticlabs/gohashtree v0.0.3-alpha.0.20230502123415-aafd8b3ca202
 	github.com/quasilyte/go-ruleguard/dsl v0.3.22
 	github.com/rs/cors v1.11.0
+	github.com/shirou/gopsutil/v3 v3.24.3
 	github.com/spf13/afero v1.10.0
 	github.com/spf13/cobra v1.8.0
 	github.com/spf13/pflag v1.0.5
@@ -278,7 +282,6 @@ require (
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
 	github.com/rs/dnscache v0.0.0-20211102005908-e0241e321417 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	github.com/shirou/gopsutil/v3 v3.24.3 // indirect
 	github.com/shoenig/go-m1cpu v0.1.6 // indirect
 	github.com/shopspring/decimal v1.2.0 // indirect
 	github.com/showwin/speedtest-go v1.7.7 // indirect
@@ -113,6 +113,9 @@ type clientConn struct {
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
+	if wc, ok := conn.(*websocketCodec); ok && wc.authorization != "" {
+		ctx = ContextWithAuthorization(ctx, wc.authorization)
+	}
 	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, 50, false /* traceRequests */, c.logger, 0)
 	return &clientConn{conn, handler}
 }
@@ -193,6 +196,19 @@ func ClientFromContext(ctx context.Context, logger log.Logger) (*Client, bool) {
 	return client, ok
 }
 
+type authorizationContextKey struct{}
+
+// ContextWithAuthorization returns a copy of ctx carrying the Authorization header of the request.
+func ContextWithAuthorization(ctx context.Context, auth string) context.Context {
+	return context.WithValue(ctx, authorizationContextKey{}, auth)
+}
+
+// AuthorizationFromContext returns the Authorization header of the request, or an empty string if none was sent.
+func AuthorizationFromContext(ctx context.Context) string {
+	auth, _ := ctx.Value(authorizationContextKey{}).(string)
+	return auth
+}
+
 func newClient(initctx context.Context, connect reconnectFunc, logger log.Logger) (*Client, error) {
 	conn, err := connect(initctx)
 	if err != nil {
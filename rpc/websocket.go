@@ -64,6 +64,8 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 			return
 		}
 		codec := NewWebsocketCodec(conn)
+		// the headers are only sent with the upgrade request so the Authorization header is kept for the whole connection
+		codec.(*websocketCodec).authorization = r.Header.Get("Authorization")
 		s.ServeCodec(codec, 0)
 	})
 }
@@ -242,7 +244,8 @@ func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
 
 type websocketCodec struct {
 	*jsonCodec
-	conn *websocket.Conn
+	conn          *websocket.Conn
+	authorization string
 
 	wg        sync.WaitGroup
 	pingReset chan struct{}
@@ -115,6 +115,43 @@ func TestWebsocketLargeCall(t *testing.T) {
 	}
 }
 
+type authorizationTestService struct{}
+
+func (s *authorizationTestService) Authorization(ctx context.Context) string {
+	return AuthorizationFromContext(ctx)
+}
+
+// This test checks that the Authorization header of the upgrade request is available to every call on the connection.
+func TestWebsocketAuthorizationHeader(t *testing.T) {
+	t.Parallel()
+	logger := log.New()
+
+	srv := newTestServer(logger)
+	if err := srv.RegisterName("auth", new(authorizationTestService)); err != nil {
+		t.Fatal(err)
+	}
+	httpsrv := httptest.NewServer(srv.WebsocketHandler([]string{"*"}, nil, false, logger))
+	wsURL := "ws://testuser:secret@" + strings.TrimPrefix(httpsrv.URL, "http://")
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	client, err := DialWebsocket(context.Background(), wsURL, "", logger)
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		var result string
+		if err := client.Call(&result, "auth_authorization"); err != nil {
+			t.Fatal(err)
+		}
+		if result != "Basic dGVzdHVzZXI6c2VjcmV0" {
+			t.Fatalf("wrong authorization %q", result)
+		}
+	}
+}
+
 // This test checks that client handles WebSocket ping frames correctly.
 func TestClientWebsocketPing(t *testing.T) {
 	if runtime.GOOS == "windows" {
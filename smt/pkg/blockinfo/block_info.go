@@ -54,16 +54,7 @@ func BuildBlockInfoTree(
 		receipt := txInfo.Receipt
 		t := txInfo.Tx
 
-		l2TxHash, err := zktx.ComputeL2TxHash(
-			t.GetChainID().ToBig(),
-			t.GetValue(),
-			t.GetPrice(),
-			t.GetNonce(),
-			t.GetGas(),
-			t.GetTo(),
-			txInfo.Signer,
-			t.GetData(),
-		)
+		l2TxHash, err := zktx.ComputeL2TxHashForTx(t, txInfo.Signer)
 		if err != nil {
 			return nil, err
 		}
@@ -31,11 +31,12 @@ const TableStats = "HermezSmtStats"
 const TableAccountValues = "HermezSmtAccountValues"
 const TableMetadata = "HermezSmtMetadata"
 const TableHashKey = "HermezSmtHashKey"
+const TablePrune = "HermezSmtPrune" // progress of the current SMT prune round, see zk/smt.Pruner
 
 const MetaLastRoot = "lastRoot"
 const MetaDepth = "depth"
 
-var HermezSmtTables = []string{TableSmt, TableStats, TableAccountValues, TableMetadata, TableHashKey}
+var HermezSmtTables = []string{TableSmt, TableStats, TableAccountValues, TableMetadata, TableHashKey, TablePrune}
 
 type EriDb struct {
 	kvTx kv.RwTx
@@ -77,6 +78,11 @@ func CreateEriDbBuckets(tx kv.RwTx) error {
 		return err
 	}
 
+	err = tx.CreateBucket(TablePrune)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -47,13 +47,19 @@ func FilterProofs(proofs []*SMTProofElement, key utils.NodeKey) []hexutility.Byt
 // Returns a slice of SMTProofElement containing the proof for each retained node,
 // or an error if the traversal fails.
 func BuildProofs(s *RoSMT, rd trie.RetainDecider, ctx context.Context) ([]*SMTProofElement, error) {
-	proofs := make([]*SMTProofElement, 0)
-
 	root, err := s.DbRo.GetLastRoot()
 	if err != nil {
 		return nil, err
 	}
 
+	return BuildProofsAtRoot(s, root, rd, ctx)
+}
+
+// BuildProofsAtRoot builds the proofs against an older root of the SMT, it fails with ErrNodeNotFound if any of the
+// nodes it needs below the root have been deleted
+func BuildProofsAtRoot(s *RoSMT, root *big.Int, rd trie.RetainDecider, ctx context.Context) ([]*SMTProofElement, error) {
+	proofs := make([]*SMTProofElement, 0)
+
 	action := func(prefix []byte, k utils.NodeKey, v utils.NodeValue12) (bool, error) {
 		retain := rd.Retain(prefix)
 
@@ -80,6 +86,9 @@ func BuildProofs(s *RoSMT, rd trie.RetainDecider, ctx context.Context) ([]*SMTPr
 			if err != nil {
 				return false, err
 			}
+			if v.IsZero() {
+				return false, fmt.Errorf("%w: value %s", ErrNodeNotFound, valHash.ToHex())
+			}
 
 			vInBytes := utils.ArrayBigToScalar(utils.BigIntArrayFromNodeValue8(v.GetNodeValue8())).Bytes()
 
@@ -94,8 +103,7 @@ func BuildProofs(s *RoSMT, rd trie.RetainDecider, ctx context.Context) ([]*SMTPr
 		return true, nil
 	}
 
-	err = s.Traverse(ctx, root, action)
-	if err != nil {
+	if err := s.traverseStrict(ctx, root, action); err != nil {
 		return nil, err
 	}
 
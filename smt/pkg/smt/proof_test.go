@@ -3,6 +3,7 @@ package smt_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -239,3 +240,38 @@ func TestVerifyAndGetVal(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildProofsMissingNode(t *testing.T) {
+	smtTrie, rl := prepareSMT(t)
+
+	smtRoot, err := smtTrie.RoSMT.DbRo.GetLastRoot()
+	if err != nil {
+		t.Fatalf("GetLastRoot() error = %v", err)
+	}
+	rootNode, err := smtTrie.Db.Get(utils.ScalarToRoot(smtRoot))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// a pruned child below a root that is still there must fail the proof rather than leave part of it out
+	child := utils.NodeKeyFromUint64Array(rootNode[0:4])
+	if child.IsZero() {
+		child = utils.NodeKeyFromUint64Array(rootNode[4:8])
+	}
+	if err = smtTrie.Db.DeleteByNodeKey(child); err != nil {
+		t.Fatalf("DeleteByNodeKey() error = %v", err)
+	}
+
+	_, err = smt.BuildProofs(smtTrie.RoSMT, rl, context.Background())
+	if !errors.Is(err, smt.ErrNodeNotFound) {
+		t.Errorf("BuildProofs() error = %v, want %v", err, smt.ErrNodeNotFound)
+	}
+
+	// other traversals, such as witness building, still read a missing node as an empty one
+	err = smtTrie.RoSMT.Traverse(context.Background(), smtRoot, func(prefix []byte, k utils.NodeKey, v utils.NodeValue12) (bool, error) {
+		return true, nil
+	})
+	if err != nil {
+		t.Errorf("Traverse() error = %v, want nil", err)
+	}
+}
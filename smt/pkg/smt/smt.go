@@ -59,7 +59,9 @@ type DebuggableDB interface {
 
 type SMT struct {
 	noSaveOnInsert bool
-	Db             DB
+	// keepReplacedNodes leaves the nodes InsertBatch replaces in the db, see SetKeepReplacedNodes
+	keepReplacedNodes bool
+	Db                DB
 	*RoSMT
 }
 
@@ -85,6 +87,12 @@ func NewSMT(database DB, noSaveOnInsert bool) *SMT {
 	}
 }
 
+// SetKeepReplacedNodes stops InsertBatch deleting the nodes it replaces, so the roots written before an insert stay
+// whole.  The nodes no root needs any more are left for the SMT pruner to delete.
+func (s *SMT) SetKeepReplacedNodes(keep bool) {
+	s.keepReplacedNodes = keep
+}
+
 func NewRoSMT(database RoDB) *RoSMT {
 	if database == nil {
 		database = db.NewMemDb()
@@ -674,11 +682,20 @@ func (s *RoSMT) GetDepth() int {
 
 type TraverseAction func(prefix []byte, k utils.NodeKey, v utils.NodeValue12) (bool, error)
 
+// ErrNodeNotFound is returned when a node below a root is missing from the db, for instance once it has been pruned
+var ErrNodeNotFound = errors.New("smt node not found")
+
 func (s *RoSMT) Traverse(ctx context.Context, node *big.Int, action TraverseAction) error {
-	return s.traverse(ctx, node, action, []byte{})
+	return s.traverse(ctx, node, action, []byte{}, false)
 }
 
-func (s *RoSMT) traverse(ctx context.Context, node *big.Int, action TraverseAction, prefix []byte) error {
+// traverseStrict is Traverse but fails with ErrNodeNotFound when a node below node is missing from the db instead of
+// passing it to action as an empty value
+func (s *RoSMT) traverseStrict(ctx context.Context, node *big.Int, action TraverseAction) error {
+	return s.traverse(ctx, node, action, []byte{}, true)
+}
+
+func (s *RoSMT) traverse(ctx context.Context, node *big.Int, action TraverseAction, prefix []byte, strict bool) error {
 	if node == nil || node.Cmp(big.NewInt(0)) == 0 {
 		return nil
 	}
@@ -696,6 +713,10 @@ func (s *RoSMT) traverse(ctx context.Context, node *big.Int, action TraverseActi
 	if err != nil {
 		return err
 	}
+	// no stored node is all zeroes, the db returns that for a key it doesn't hold
+	if strict && nodeValue.IsZero() {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, ky.ToHex())
+	}
 
 	shouldContinue, err := action(prefix, ky, nodeValue)
 
@@ -715,7 +736,7 @@ func (s *RoSMT) traverse(ctx context.Context, node *big.Int, action TraverseActi
 		childPrefix := make([]byte, len(prefix)+1)
 		copy(childPrefix, prefix)
 		childPrefix[len(prefix)] = byte(i)
-		err := s.traverse(ctx, child.ToBigInt(), action, childPrefix)
+		err := s.traverse(ctx, child.ToBigInt(), action, childPrefix, strict)
 		if err != nil {
 			return err
 		}
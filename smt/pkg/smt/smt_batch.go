@@ -178,8 +178,10 @@ func (s *SMT) InsertBatch(cfg InsertBatchConfig, nodeKeys []*utils.NodeKey, node
 		return nil, fmt.Errorf("updateDepth: %w", err)
 	}
 
-	if err := s.deleteBatchedNodeValues(cfg.logPrefix, nodeHashesForDelete); err != nil {
-		return nil, fmt.Errorf("deleteBatchedNodeValues: %w", err)
+	if !s.keepReplacedNodes {
+		if err := s.deleteBatchedNodeValues(cfg.logPrefix, nodeHashesForDelete); err != nil {
+			return nil, fmt.Errorf("deleteBatchedNodeValues: %w", err)
+		}
 	}
 
 	if err := s.saveBatchedNodeValues(cfg.logPrefix, nodeValues, nodeValuesHashes); err != nil {
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/ethclient"
+	"github.com/ledgerwatch/erigon/turbo/debug"
+	"github.com/ledgerwatch/erigon/zk/syncer"
+)
+
+var (
+	AccInputHashFromBatchFlag = cli.Uint64Flag{
+		Name:  "from-batch",
+		Usage: "First batch to verify, verification starts at the sequence containing it",
+	}
+	AccInputHashToBatchFlag = cli.Uint64Flag{
+		Name:  "to-batch",
+		Usage: "Last batch to verify, verification ends with the sequence containing it, 0 for the latest sequence",
+	}
+)
+
+var verifyAccInputHashCommand = cli.Command{
+	Name:  "verify-acc-input-hash",
+	Usage: "Recompute the acc input hash of every sequenced batch from the local chain and check it against L1, the node must be stopped",
+	Description: `Walks the sequences synced into the hermez db and recomputes the acc input hash of every batch from the local
+blocks, comparing it with the hash from the sequence calldata and, at the end of every sequence, with the rollup
+contract. Prints a JSON report and exits with an error at the first divergence.`,
+	Action: verifyAccInputHash,
+	Flags: joinFlags([]cli.Flag{
+		&utils.DataDirFlag,
+		&utils.L1RpcUrlFlag,
+		&utils.AddressRollupFlag,
+		&utils.L1RollupIdFlag,
+		&AccInputHashFromBatchFlag,
+		&AccInputHashToBatchFlag,
+	}),
+}
+
+func verifyAccInputHash(cliCtx *cli.Context) error {
+	if _, _, _, err := debug.Setup(cliCtx, true /* rootLogger */); err != nil {
+		return err
+	}
+
+	l1RpcUrl := cliCtx.String(utils.L1RpcUrlFlag.Name)
+	if l1RpcUrl == "" {
+		return fmt.Errorf("%s is required", utils.L1RpcUrlFlag.Name)
+	}
+	rollupAddress := cliCtx.String(utils.AddressRollupFlag.Name)
+	if !common.IsHexAddress(rollupAddress) {
+		return fmt.Errorf("%s must be a valid address", utils.AddressRollupFlag.Name)
+	}
+
+	var etherMans []syncer.IEtherman
+	for _, url := range strings.Split(l1RpcUrl, ",") {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", url, err)
+		}
+		defer client.Close()
+		etherMans = append(etherMans, client)
+	}
+	l1Syncer := syncer.NewL1Syncer(cliCtx.Context, etherMans, nil, nil, 0, 0, "")
+	source := syncer.NewRollupAccInputHashSource(l1Syncer, common.HexToAddress(rollupAddress), cliCtx.Uint64(utils.L1RollupIdFlag.Name))
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	chainDB := dbCfg(kv.ChainDB, dirs.Chaindata).MustOpen()
+	defer chainDB.Close()
+	tx, err := chainDB.BeginRo(cliCtx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	report, err := syncer.VerifyAccInputHashes(cliCtx.Context, tx, source, cliCtx.Uint64(AccInputHashFromBatchFlag.Name), cliCtx.Uint64(AccInputHashToBatchFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if d := report.Divergence; d != nil {
+		return fmt.Errorf("acc input hash diverges at batch %d (fork %d): %s", d.Batch, d.ForkId, d.Reason)
+	}
+	if report.Incomplete != "" {
+		log.Warn("Verification stopped before the last sequence", "reason", report.Incomplete)
+	}
+	if report.Sequences == 0 && len(report.Skipped) == 0 && report.Incomplete == "" {
+		return errors.New("no sequences to verify in the requested range")
+	}
+	log.Info("Acc input hashes verified", "fromBatch", report.FromBatch, "toBatch", report.ToBatch, "sequences", report.Sequences, "batches", report.Batches, "skipped", len(report.Skipped))
+	return nil
+}
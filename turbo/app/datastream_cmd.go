@@ -0,0 +1,151 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-data-streamer/datastreamer"
+	dslog "github.com/0xPolygonHermez/zkevm-data-streamer/log"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/turbo/debug"
+	"github.com/ledgerwatch/erigon/zk/datastream/archive"
+	"github.com/ledgerwatch/erigon/zk/datastream/server"
+)
+
+var (
+	DatastreamFromBatchFlag = cli.Uint64Flag{
+		Name:     "from-batch",
+		Usage:    "First batch to export",
+		Required: true,
+	}
+	DatastreamToBatchFlag = cli.Uint64Flag{
+		Name:     "to-batch",
+		Usage:    "Last batch to export (inclusive)",
+		Required: true,
+	}
+	DatastreamOutputFlag = cli.StringFlag{
+		Name:     "output",
+		Usage:    "Path of the archive file to write",
+		Required: true,
+	}
+)
+
+// datastreamCommand is built with the node action so that import can run the node syncing from the archive
+func datastreamCommand(nodeAction cli.ActionFunc, nodeFlags []cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:  "datastream",
+		Usage: "Export datastream batches to an archive file and sync a node from one",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "export",
+				Usage:  "Export a range of batches from the node's datastream to a checksummed archive, the node must be stopped",
+				Action: exportDatastream,
+				Flags: joinFlags([]cli.Flag{
+					&utils.DataDirFlag,
+					&DatastreamFromBatchFlag,
+					&DatastreamToBatchFlag,
+					&DatastreamOutputFlag,
+				}),
+			},
+			{
+				Name:      "import",
+				Usage:     "Run the node syncing batches from an archive instead of the L2 datastreamer endpoint",
+				ArgsUsage: "<archive>",
+				Action: func(cliCtx *cli.Context) error {
+					if cliCtx.NArg() != 1 {
+						return errors.New("the path to a datastream archive is required")
+					}
+					archivePath := cliCtx.Args().First()
+					if err := verifyDatastreamArchive(archivePath); err != nil {
+						return err
+					}
+					if err := cliCtx.Set(utils.L2DataStreamerFileFlag.Name, archivePath); err != nil {
+						return err
+					}
+					return nodeAction(cliCtx)
+				},
+				Flags: nodeFlags,
+			},
+		},
+	}
+}
+
+func exportDatastream(cliCtx *cli.Context) error {
+	if _, _, _, err := debug.Setup(cliCtx, true /* rootLogger */); err != nil {
+		return err
+	}
+
+	from := cliCtx.Uint64(DatastreamFromBatchFlag.Name)
+	to := cliCtx.Uint64(DatastreamToBatchFlag.Name)
+	output := cliCtx.String(DatastreamOutputFlag.Name)
+
+	streamFile := filepath.Join(cliCtx.String(utils.DataDirFlag.Name), "data-stream")
+	if _, err := os.Stat(streamFile); err != nil {
+		return fmt.Errorf("no datastream found in datadir: %w", err)
+	}
+
+	logConfig := &dslog.Config{
+		Environment: "production",
+		Level:       "warn",
+		Outputs:     nil,
+	}
+	// the server is never started, it is only used to read entries from the stream file
+	stream, err := server.NewZkEVMDataStreamServerFactory().CreateStreamServer(0, 1, datastreamer.StreamType(1), streamFile, 0, 0, time.Minute, logConfig)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	entries, err := server.ExportBatches(stream, f, from, to)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	// read the archive back in the same way the datastream server reads batches to be sure it is usable
+	reader, err := archive.Open(output)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	batches, err := server.ReadBatches(reader, from, to)
+	if err != nil {
+		return fmt.Errorf("exported archive could not be read back: %w", err)
+	}
+	blocks, txs := 0, 0
+	for _, batch := range batches {
+		blocks += len(batch)
+		for _, block := range batch {
+			txs += len(block.L2Txs)
+		}
+	}
+
+	log.Info("Datastream exported", "file", output, "fromBatch", from, "toBatch", to, "entries", entries, "blocks", blocks, "transactions", txs)
+	return nil
+}
+
+func verifyDatastreamArchive(path string) error {
+	reader, err := archive.Open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err = reader.Verify(); err != nil {
+		return err
+	}
+	header := reader.Header()
+	log.Info("Syncing from datastream archive", "file", path, "fromBatch", header.FromBatch, "toBatch", header.ToBatch, "entries", reader.EntryCount())
+	return nil
+}
@@ -22,17 +22,7 @@ func MakeApp_zkEvm(name string, action cli.ActionFunc, cliFlags []cli.Flag) *cli
 	app := cli2.NewApp(params.GitCommit, "erigon")
 	app.Name = name
 	app.UsageText = app.Name + ` [command] [flags]`
-	app.Action = func(context *cli.Context) error {
-		// handle case: unknown sub-command
-		if context.Args().Present() {
-			var goodNames []string
-			for _, c := range app.VisibleCommands() {
-				goodNames = append(goodNames, c.Name)
-			}
-			log.Error(fmt.Sprintf("Command '%s' not found. Available commands: %s", context.Args().First(), goodNames))
-			cli.ShowAppHelpAndExit(context, 1)
-		}
-
+	runNode := func(context *cli.Context) error {
 		// handle case: config flag
 		configFilePath := context.String(utils.ConfigFlag.Name)
 		if configFilePath != "" {
@@ -49,6 +39,19 @@ func MakeApp_zkEvm(name string, action cli.ActionFunc, cliFlags []cli.Flag) *cli
 		// run default action
 		return action(context)
 	}
+	app.Action = func(context *cli.Context) error {
+		// handle case: unknown sub-command
+		if context.Args().Present() {
+			var goodNames []string
+			for _, c := range app.VisibleCommands() {
+				goodNames = append(goodNames, c.Name)
+			}
+			log.Error(fmt.Sprintf("Command '%s' not found. Available commands: %s", context.Args().First(), goodNames))
+			cli.ShowAppHelpAndExit(context, 1)
+		}
+
+		return runNode(context)
+	}
 
 	app.Flags = appFlags(cliFlags)
 	app.After = func(ctx *cli.Context) error {
@@ -60,6 +63,9 @@ func MakeApp_zkEvm(name string, action cli.ActionFunc, cliFlags []cli.Flag) *cli
 		&importCommand,
 		&snapshotCommand,
 		&supportCommand,
+		datastreamCommand(runNode, appFlags(cliFlags)),
+		&smtSnapshotCommand,
+		&verifyAccInputHashCommand,
 		//&backupCommand,
 	}
 	return app
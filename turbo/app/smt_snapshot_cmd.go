@@ -0,0 +1,133 @@
+package app
+
+import (
+	"errors"
+	"os"
+
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ledgerwatch/erigon/cmd/utils"
+	"github.com/ledgerwatch/erigon/node"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/turbo/debug"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/smt"
+)
+
+var (
+	SmtSnapshotBatchFlag = cli.Uint64Flag{
+		Name:     "batch",
+		Usage:    "Batch whose end state root is exported",
+		Required: true,
+	}
+	SmtSnapshotOutputFlag = cli.StringFlag{
+		Name:     "output",
+		Usage:    "Path of the snapshot file to write",
+		Required: true,
+	}
+)
+
+var smtSnapshotCommand = cli.Command{
+	Name:  "smt-snapshot",
+	Usage: "Export the SMT at a batch to a snapshot file and bootstrap a node's SMT from one",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "export",
+			Usage:  "Export the SMT at the state root of a batch to a chunked, checksummed snapshot, the node must be stopped",
+			Action: exportSmtSnapshot,
+			Flags: joinFlags([]cli.Flag{
+				&utils.DataDirFlag,
+				&SmtSnapshotBatchFlag,
+				&SmtSnapshotOutputFlag,
+			}),
+		},
+		{
+			Name:      "import",
+			Usage:     "Bootstrap a new node, initialised with its genesis and stopped, at the block of a snapshot: load the SMT, rebuild the plain state from it and set the stages to the block",
+			ArgsUsage: "<snapshot>",
+			Action:    importSmtSnapshot,
+			Flags: joinFlags([]cli.Flag{
+				&utils.DataDirFlag,
+			}),
+		},
+	},
+}
+
+func exportSmtSnapshot(cliCtx *cli.Context) error {
+	if _, _, _, err := debug.Setup(cliCtx, true /* rootLogger */); err != nil {
+		return err
+	}
+
+	batch := cliCtx.Uint64(SmtSnapshotBatchFlag.Name)
+	output := cliCtx.String(SmtSnapshotOutputFlag.Name)
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+
+	chainDB := dbCfg(kv.ChainDB, dirs.Chaindata).MustOpen()
+	defer chainDB.Close()
+	tx, err := chainDB.BeginRo(cliCtx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	header, records, err := smt.ExportSnapshot(cliCtx.Context, tx, f, batch, dirs.Tmp)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	log.Info("SMT snapshot exported", "file", output, "batch", header.Batch, "block", header.Block, "root", header.Root, "records", records)
+	return nil
+}
+
+func importSmtSnapshot(cliCtx *cli.Context) error {
+	logger, _, _, err := debug.Setup(cliCtx, true /* rootLogger */)
+	if err != nil {
+		return err
+	}
+	if cliCtx.NArg() != 1 {
+		return errors.New("the path to an SMT snapshot is required")
+	}
+	path := cliCtx.Args().First()
+
+	stack := MakeConfigNodeDefault(cliCtx, logger)
+	defer stack.Close()
+	chainDB, err := node.OpenDatabase(cliCtx.Context, stack.Config(), kv.ChainDB, "", false, logger)
+	if err != nil {
+		return err
+	}
+	defer chainDB.Close()
+
+	tx, err := chainDB.BeginRw(cliCtx.Context)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = db2.CreateEriDbBuckets(tx); err != nil {
+		return err
+	}
+	if err = hermez_db.CreateHermezBuckets(tx); err != nil {
+		return err
+	}
+
+	header, records, err := smt.ImportSnapshot(cliCtx.Context, tx, path)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Info("SMT snapshot imported", "file", path, "batch", header.Batch, "block", header.Block, "root", header.Root, "records", records)
+	return nil
+}
@@ -179,6 +179,7 @@ var DefaultFlags = []cli.Flag{
 	&utils.L2DataStreamerUrlFlag,
 	&utils.L2DataStreamerMaxEntryChanFlag,
 	&utils.L2DataStreamerUseTLSFlag,
+	&utils.L2DataStreamerFileFlag,
 	&utils.L2DataStreamerTimeout,
 	&utils.L2ShortCircuitToVerifiedBatchFlag,
 	&utils.L1SyncStartBlock,
@@ -187,6 +188,7 @@ var DefaultFlags = []cli.Flag{
 	&utils.L1RpcUrlFlag,
 	&utils.L1CacheEnabledFlag,
 	&utils.L1CachePortFlag,
+	&utils.L1CacheMethodTTLsFlag,
 	&utils.AddressSequencerFlag,
 	&utils.AddressAdminFlag,
 	&utils.AddressRollupFlag,
@@ -203,9 +205,13 @@ var DefaultFlags = []cli.Flag{
 	&utils.L1ContractAddressRetrieveFlag,
 	&utils.RpcRateLimitsFlag,
 	&utils.RpcGetBatchWitnessConcurrencyLimitFlag,
+	&utils.RpcGetBatchStateProofConcurrencyLimitFlag,
 	&utils.RebuildTreeAfterFlag,
 	&utils.IncrementTreeAlways,
 	&utils.SmtRegenerateInMemory,
+	&utils.SmtPruneMode,
+	&utils.SmtPruneRetainBatches,
+	&utils.SmtPruneStep,
 	&utils.SequencerBlockSealTime,
 	&utils.SequencerEmptyBlockSealTime,
 	&utils.SequencerBatchSealTime,
@@ -216,8 +222,11 @@ var DefaultFlags = []cli.Flag{
 	&utils.SequencerResequence,
 	&utils.SequencerResequenceStrict,
 	&utils.SequencerResequenceReuseL1InfoIndex,
+	&utils.SequencerResequenceDryRun,
 	&utils.SequencerDecodedTxCacheSize,
 	&utils.SequencerDecodedTxCacheTTL,
+	&utils.SequencerTxOrdering,
+	&utils.SequencerMaxTxsPerSender,
 	&utils.ExecutorUrls,
 	&utils.ExecutorStrictMode,
 	&utils.ExecutorRequestTimeout,
@@ -226,7 +235,12 @@ var DefaultFlags = []cli.Flag{
 	&utils.WitnessMemdbSize,
 	&utils.WitnessUnwindLimit,
 	&utils.ExecutorMaxConcurrentRequests,
+	&utils.ExecutorWeights,
+	&utils.ExecutorConcurrencyLimits,
+	&utils.ExecutorCircuitBreakerFailures,
+	&utils.ExecutorCircuitBreakerCooldown,
 	&utils.Limbo,
+	&utils.AdminToken,
 	&utils.AllowFreeTransactions,
 	&utils.AllowPreEIP155Transactions,
 	&utils.EffectiveGasPriceForEthTransfer,
@@ -241,6 +255,10 @@ var DefaultFlags = []cli.Flag{
 	&utils.DataStreamWriteTimeout,
 	&utils.DataStreamInactivityTimeout,
 	&utils.DataStreamInactivityCheckInterval,
+	&utils.DataStreamTLSCert,
+	&utils.DataStreamTLSKey,
+	&utils.DataStreamTLSClientCA,
+	&utils.DataStreamTLSAllowedFingerprints,
 	&utils.WitnessFullFlag,
 	&utils.SyncLimit,
 	&utils.SyncLimitVerifiedEnabled,
@@ -255,9 +273,16 @@ var DefaultFlags = []cli.Flag{
 	&utils.OtsSearchMaxCapFlag,
 	&utils.PanicOnReorg,
 	&utils.ShadowSequencer,
+	&utils.SequencerRole,
+	&utils.SequencerLeaseFile,
+	&utils.SequencerLeaseTTL,
+	&utils.SequencerLeaseRpcUrl,
+	&utils.SequencerLeaseDataStreamUrl,
 	&utils.ZKGenesisConfigPathFlag,
 	&utils.L2InfoTreeUpdatesBatchSize,
 	&utils.L2InfoTreeUpdatesEnabled,
+	&utils.BridgeIndexerEnabled,
+	&utils.AddressL2BridgeFlag,
 
 	&utils.SilkwormExecutionFlag,
 	&utils.SilkwormRpcDaemonFlag,
@@ -295,9 +320,16 @@ var DefaultFlags = []cli.Flag{
 	&SyncLoopBreakAfterFlag,
 	&SyncLoopPruneLimitFlag,
 	&utils.PoolManagerUrl,
+	&utils.TxForwardingQueue,
+	&utils.TxForwardingQueueSize,
+	&utils.TxForwardingRetryInterval,
+	&utils.TxForwardingMaxRetryInterval,
 	&utils.TxPoolRejectSmartContractDeployments,
 	&utils.DisableVirtualCounters,
 	&utils.DAUrl,
+	&utils.DAQuorumUrls,
+	&utils.DAQuorumThreshold,
+	&utils.DACacheSize,
 	&utils.VirtualCountersSmtReduction,
 	&utils.BadBatches,
 	&utils.IgnoreBadBatchesCheck,
@@ -315,6 +347,8 @@ var DefaultFlags = []cli.Flag{
 	&utils.WitnessContractInclusion,
 	&utils.GasPriceCheckFrequency,
 	&utils.GasPriceHistoryCount,
+	&utils.L2GasPriceCheckFrequency,
+	&utils.RpcReceiptEffectiveGasPriceDetails,
 	&utils.RejectLowGasPriceTransactions,
 	&utils.RejectLowGasPriceTolerance,
 	&utils.BadTxAllowance,
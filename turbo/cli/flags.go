@@ -520,6 +520,10 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config, logger log.Logg
 		DataStreamWriteTimeout:            ctx.Duration(utils.DataStreamWriteTimeout.Name),
 		DataStreamInactivityTimeout:       ctx.Duration(utils.DataStreamInactivityTimeout.Name),
 		DataStreamInactivityCheckInterval: ctx.Duration(utils.DataStreamInactivityCheckInterval.Name),
+		DataStreamTLSCert:                 ctx.String(utils.DataStreamTLSCert.Name),
+		DataStreamTLSKey:                  ctx.String(utils.DataStreamTLSKey.Name),
+		DataStreamTLSClientCA:             ctx.String(utils.DataStreamTLSClientCA.Name),
+		DataStreamTLSAllowedFingerprints:  libcommon.CliString2Array(ctx.String(utils.DataStreamTLSAllowedFingerprints.Name)),
 		L2RpcUrl:                          ctx.String(utils.L2RpcUrlFlag.Name),
 		DisableStateRootCheck:             ctx.Bool(utils.DebugDisableStateRootCheck.Name),
 	}
@@ -137,6 +137,11 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		panic("Effective gas price for contract deployment must be in interval [0; 1]")
 	}
 
+	smtPruneMode := ctx.String(utils.SmtPruneMode.Name)
+	if smtPruneMode != "full" && smtPruneMode != "pruned" {
+		panic(fmt.Sprintf("invalid SMT prune mode %s, must be full or pruned", smtPruneMode))
+	}
+
 	witnessMemSize := utils.DatasizeFlagValue(ctx, utils.WitnessMemdbSize.Name)
 	witnessUnwindLimit := ctx.Uint64(utils.WitnessUnwindLimit.Name)
 
@@ -155,6 +160,40 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		badBatches = append(badBatches, val)
 	}
 
+	parseIntList := func(flagName string) []int {
+		var values []int
+		for _, s := range strings.Split(strings.ReplaceAll(ctx.String(flagName), " ", ""), ",") {
+			if s == "" {
+				continue
+			}
+			val, err := strconv.Atoi(s)
+			if err != nil || val < 1 {
+				panic(fmt.Sprintf("invalid value %s for flag %s, must be a positive integer", s, flagName))
+			}
+			values = append(values, val)
+		}
+		return values
+	}
+
+	l1CacheMethodTTLs := make(map[string]time.Duration)
+	for _, s := range strings.Split(strings.ReplaceAll(ctx.String(utils.L1CacheMethodTTLsFlag.Name), " ", ""), ",") {
+		if s == "" {
+			continue
+		}
+		method, ttl, found := strings.Cut(s, "=")
+		duration, err := time.ParseDuration(ttl)
+		if !found || err != nil || duration < 0 {
+			panic(fmt.Sprintf("invalid value %s for flag %s, must be method=duration", s, utils.L1CacheMethodTTLsFlag.Name))
+		}
+		l1CacheMethodTTLs[method] = duration
+	}
+
+	sequencerRole, err := sequencer.ResolveRole(ctx.String(utils.SequencerRole.Name))
+	if err != nil {
+		panic(fmt.Sprintf("invalid value for flag %s: %s", utils.SequencerRole.Name, err))
+	}
+	sequencer.SetRole(sequencerRole)
+
 	// witness cache flags
 	// if dicabled, set limit to 0 and only check for it to be 0 or not
 	witnessCacheEnabled := ctx.Bool(utils.WitnessCacheEnable.Name)
@@ -183,6 +222,7 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		L2DataStreamerUrl:                      ctx.String(utils.L2DataStreamerUrlFlag.Name),
 		L2DataStreamerMaxEntryChan:             ctx.Uint64(utils.L2DataStreamerMaxEntryChanFlag.Name),
 		L2DataStreamerUseTLS:                   ctx.Bool(utils.L2DataStreamerUseTLSFlag.Name),
+		L2DataStreamerFile:                     ctx.String(utils.L2DataStreamerFileFlag.Name),
 		L2DataStreamerTimeout:                  l2DataStreamTimeout,
 		L2ShortCircuitToVerifiedBatch:          l2ShortCircuitToVerifiedBatchVal,
 		L1SyncStartBlock:                       ctx.Uint64(utils.L1SyncStartBlock.Name),
@@ -191,6 +231,7 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		L1RpcUrl:                               ctx.String(utils.L1RpcUrlFlag.Name),
 		L1CacheEnabled:                         ctx.Bool(utils.L1CacheEnabledFlag.Name),
 		L1CachePort:                            ctx.Uint(utils.L1CachePortFlag.Name),
+		L1CacheMethodTTLs:                      l1CacheMethodTTLs,
 		AddressSequencer:                       libcommon.HexToAddress(ctx.String(utils.AddressSequencerFlag.Name)),
 		AddressAdmin:                           libcommon.HexToAddress(ctx.String(utils.AddressAdminFlag.Name)),
 		AddressRollup:                          libcommon.HexToAddress(ctx.String(utils.AddressRollupFlag.Name)),
@@ -206,9 +247,13 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		L1ContractAddressCheck:                 ctx.Bool(utils.L1ContractAddressCheckFlag.Name),
 		L1ContractAddressRetrieve:              ctx.Bool(utils.L1ContractAddressRetrieveFlag.Name),
 		RpcGetBatchWitnessConcurrencyLimit:     ctx.Int(utils.RpcGetBatchWitnessConcurrencyLimitFlag.Name),
+		RpcGetBatchStateProofConcurrencyLimit:  ctx.Int(utils.RpcGetBatchStateProofConcurrencyLimitFlag.Name),
 		RebuildTreeAfter:                       ctx.Uint64(utils.RebuildTreeAfterFlag.Name),
 		IncrementTreeAlways:                    ctx.Bool(utils.IncrementTreeAlways.Name),
 		SmtRegenerateInMemory:                  ctx.Bool(utils.SmtRegenerateInMemory.Name),
+		SmtPruneMode:                           smtPruneMode,
+		SmtPruneRetainBatches:                  ctx.Uint64(utils.SmtPruneRetainBatches.Name),
+		SmtPruneStep:                           ctx.Int(utils.SmtPruneStep.Name),
 		SequencerBlockSealTime:                 sequencerBlockSealTime,
 		SequencerEmptyBlockSealTime:            sequencerEmptyBlockSealTime,
 		SequencerBatchSealTime:                 sequencerBatchSealTime,
@@ -219,8 +264,11 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		SequencerResequence:                    ctx.Bool(utils.SequencerResequence.Name),
 		SequencerResequenceStrict:              ctx.Bool(utils.SequencerResequenceStrict.Name),
 		SequencerResequenceReuseL1InfoIndex:    ctx.Bool(utils.SequencerResequenceReuseL1InfoIndex.Name),
+		SequencerResequenceDryRun:              ctx.Bool(utils.SequencerResequenceDryRun.Name),
 		SequencerDecodedTxCacheSize:            ctx.Int(utils.SequencerDecodedTxCacheSize.Name),
 		SequencerDecodedTxCacheTTL:             ctx.Duration(utils.SequencerDecodedTxCacheTTL.Name),
+		SequencerTxOrdering:                    ctx.String(utils.SequencerTxOrdering.Name),
+		SequencerMaxTxsPerSender:               ctx.Int(utils.SequencerMaxTxsPerSender.Name),
 		ExecutorUrls:                           strings.Split(strings.ReplaceAll(ctx.String(utils.ExecutorUrls.Name), " ", ""), ","),
 		ExecutorStrictMode:                     ctx.Bool(utils.ExecutorStrictMode.Name),
 		ExecutorRequestTimeout:                 ctx.Duration(utils.ExecutorRequestTimeout.Name),
@@ -229,7 +277,12 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		WitnessMemdbSize:                       *witnessMemSize,
 		WitnessUnwindLimit:                     witnessUnwindLimit,
 		ExecutorMaxConcurrentRequests:          ctx.Int(utils.ExecutorMaxConcurrentRequests.Name),
+		ExecutorWeights:                        parseIntList(utils.ExecutorWeights.Name),
+		ExecutorConcurrencyLimits:              parseIntList(utils.ExecutorConcurrencyLimits.Name),
+		ExecutorCircuitBreakerFailures:         ctx.Int(utils.ExecutorCircuitBreakerFailures.Name),
+		ExecutorCircuitBreakerCooldown:         ctx.Duration(utils.ExecutorCircuitBreakerCooldown.Name),
 		Limbo:                                  ctx.Bool(utils.Limbo.Name),
+		AdminToken:                             ctx.String(utils.AdminToken.Name),
 		AllowFreeTransactions:                  ctx.Bool(utils.AllowFreeTransactions.Name),
 		AllowPreEIP155Transactions:             ctx.Bool(utils.AllowPreEIP155Transactions.Name),
 		EffectiveGasPriceForEthTransfer:        uint8(math.Round(effectiveGasPriceForEthTransferVal * 255.0)),
@@ -250,10 +303,17 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		DebugStepAfter:                         ctx.Uint64(utils.DebugStepAfter.Name),
 		DebugDisableStateRootCheck:             ctx.Bool(utils.DebugDisableStateRootCheck.Name),
 		PoolManagerUrl:                         ctx.String(utils.PoolManagerUrl.Name),
+		TxForwardingQueue:                      ctx.Bool(utils.TxForwardingQueue.Name),
+		TxForwardingQueueSize:                  ctx.Int(utils.TxForwardingQueueSize.Name),
+		TxForwardingRetryInterval:              ctx.Duration(utils.TxForwardingRetryInterval.Name),
+		TxForwardingMaxRetryInterval:           ctx.Duration(utils.TxForwardingMaxRetryInterval.Name),
 		TxPoolRejectSmartContractDeployments:   ctx.Bool(utils.TxPoolRejectSmartContractDeployments.Name),
 		DisableVirtualCounters:                 ctx.Bool(utils.DisableVirtualCounters.Name),
 		ExecutorPayloadOutput:                  ctx.String(utils.ExecutorPayloadOutput.Name),
-		DAUrl:                                  ctx.String(utils.DAUrl.Name),
+		DAUrls:                                 strings.Split(strings.ReplaceAll(ctx.String(utils.DAUrl.Name), " ", ""), ","),
+		DAQuorumUrls:                           strings.Split(strings.ReplaceAll(ctx.String(utils.DAQuorumUrls.Name), " ", ""), ","),
+		DAQuorumThreshold:                      ctx.Int(utils.DAQuorumThreshold.Name),
+		DACacheSize:                            ctx.Int(utils.DACacheSize.Name),
 		DataStreamHost:                         ctx.String(utils.DataStreamHost.Name),
 		DataStreamPort:                         ctx.Uint(utils.DataStreamPort.Name),
 		DataStreamWriteTimeout:                 ctx.Duration(utils.DataStreamWriteTimeout.Name),
@@ -274,25 +334,42 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		WitnessContractInclusion:               witnessInclusion,
 		GasPriceCheckFrequency:                 ctx.Duration(utils.GasPriceCheckFrequency.Name),
 		GasPriceHistoryCount:                   ctx.Uint64(utils.GasPriceHistoryCount.Name),
+		L2GasPriceCheckFrequency:               ctx.Duration(utils.L2GasPriceCheckFrequency.Name),
+		RpcReceiptEffectiveGasPriceDetails:     ctx.Bool(utils.RpcReceiptEffectiveGasPriceDetails.Name),
 		RejectLowGasPriceTransactions:          ctx.Bool(utils.RejectLowGasPriceTransactions.Name),
 		RejectLowGasPriceTolerance:             ctx.Float64(utils.RejectLowGasPriceTolerance.Name),
 		LogLevel:                               logLevel,
 		PanicOnReorg:                           ctx.Bool(utils.PanicOnReorg.Name),
 		ShadowSequencer:                        ctx.Bool(utils.ShadowSequencer.Name),
+		SequencerRole:                          sequencerRole,
+		SequencerLeaseFile:                     ctx.String(utils.SequencerLeaseFile.Name),
+		SequencerLeaseTTL:                      ctx.Duration(utils.SequencerLeaseTTL.Name),
+		SequencerLeaseRpcUrl:                   ctx.String(utils.SequencerLeaseRpcUrl.Name),
+		SequencerLeaseDataStreamUrl:            ctx.String(utils.SequencerLeaseDataStreamUrl.Name),
 		BadTxAllowance:                         ctx.Uint64(utils.BadTxAllowance.Name),
 		BadTxStoreValue:                        ctx.Uint64(utils.BadTxStoreValue.Name),
 		BadTxPurge:                             ctx.Bool(utils.BadTxPurge.Name),
 		L2InfoTreeUpdatesBatchSize:             ctx.Uint64(utils.L2InfoTreeUpdatesBatchSize.Name),
 		L2InfoTreeUpdatesEnabled:               ctx.Bool(utils.L2InfoTreeUpdatesEnabled.Name),
+		BridgeIndexerEnabled:                   ctx.Bool(utils.BridgeIndexerEnabled.Name),
+		AddressL2Bridge:                        libcommon.HexToAddress(ctx.String(utils.AddressL2BridgeFlag.Name)),
 	}
 
 	utils2.EnableTimer(cfg.DebugTimers)
 
 	checkFlag(utils.L2ChainIdFlag.Name, cfg.L2ChainId)
-	if !sequencer.IsSequencer() {
+	// a standby follows the sequencer until it is promoted so needs the flags of both roles
+	if sequencerRole != sequencer.RoleSequencer {
 		checkFlag(utils.L2RpcUrlFlag.Name, cfg.Zk.L2RpcUrl)
-		checkFlag(utils.L2DataStreamerUrlFlag.Name, cfg.L2DataStreamerUrl)
-	} else {
+		if cfg.L2DataStreamerFile == "" {
+			checkFlag(utils.L2DataStreamerUrlFlag.Name, cfg.L2DataStreamerUrl)
+		}
+	}
+	if sequencerRole == sequencer.RoleStandby {
+		checkFlag(utils.SequencerLeaseFile.Name, cfg.SequencerLeaseFile)
+		checkFlag(utils.SequencerLeaseTTL.Name, cfg.SequencerLeaseTTL)
+	}
+	if sequencerRole != sequencer.RoleRpc {
 		checkFlag(utils.ExecutorUrls.Name, cfg.ExecutorUrls)
 		checkFlag(utils.ExecutorStrictMode.Name, cfg.ExecutorStrictMode)
 		checkFlag(utils.ExecutorEnabled.Name, cfg.ExecutorEnabled)
@@ -316,6 +393,24 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		if cfg.UseExecutors() && cfg.DisableVirtualCounters {
 			panic("You cannot disable virtual counters when running with executors")
 		}
+
+		if len(cfg.ExecutorWeights) > len(cfg.ExecutorUrls) {
+			panic(fmt.Sprintf("More executor weights (%s) than executor urls", utils.ExecutorWeights.Name))
+		}
+
+		if len(cfg.ExecutorConcurrencyLimits) > len(cfg.ExecutorUrls) {
+			panic(fmt.Sprintf("More executor concurrency limits (%s) than executor urls", utils.ExecutorConcurrencyLimits.Name))
+		}
+
+		// an executor with no room for in-flight requests would block every request routed to it
+		if cfg.UseExecutors() && cfg.ExecutorMaxConcurrentRequests < 1 {
+			panic(fmt.Sprintf("%s must be at least 1", utils.ExecutorMaxConcurrentRequests.Name))
+		}
+		for _, limit := range cfg.ExecutorConcurrencyLimits {
+			if limit < 1 {
+				panic(fmt.Sprintf("Executor concurrency limits (%s) must be at least 1", utils.ExecutorConcurrencyLimits.Name))
+			}
+		}
 	}
 
 	checkFlag(utils.AddressZkevmFlag.Name, cfg.AddressZkevm)
@@ -332,5 +427,11 @@ func ApplyFlagsForZkConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 	checkFlag(utils.L1ContractAddressCheckFlag.Name, cfg.L1ContractAddressCheck)
 	checkFlag(utils.L1ContractAddressRetrieveFlag.Name, cfg.L1ContractAddressCheck)
 
-	verifyAddressFlag(utils.L2DataStreamerUrlFlag.Name, cfg.L2DataStreamerUrl)
+	if cfg.L2DataStreamerFile == "" {
+		verifyAddressFlag(utils.L2DataStreamerUrlFlag.Name, cfg.L2DataStreamerUrl)
+	}
+
+	if cfg.BridgeIndexerEnabled {
+		checkFlag(utils.AddressL2BridgeFlag.Name, cfg.AddressL2Bridge)
+	}
 }
@@ -18,8 +18,10 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/zk/datastream/server"
+	"github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
 	"github.com/ledgerwatch/erigon/zk/sequencer"
 	"github.com/ledgerwatch/erigon/zk/syncer"
+	"github.com/ledgerwatch/erigon/zk/txforwarder"
 	txpool2 "github.com/ledgerwatch/erigon/zk/txpool"
 )
 
@@ -28,7 +30,8 @@ func APIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, r
 	filters *rpchelper.Filters, stateCache kvcache.Cache,
 	blockReader services.FullBlockReader, agg *libstate.Aggregator, cfg *httpcfg.HttpCfg, engine consensus.EngineReader,
 	ethCfg *ethconfig.Config, l1Syncer *syncer.L1Syncer, logger log.Logger, dataStreamServer server.DataStreamServer,
-	gasTracker *RecurringL1GasPriceTracker,
+	gasTracker *RecurringL1GasPriceTracker, executors []*legacy_executor_verifier.Executor, txForwarder *txforwarder.Forwarder,
+	l2GasTracker *RecurringL2GasPriceTracker, failover *sequencer.Failover,
 ) (list []rpc.API) {
 	// non-sequencer nodes should forward on requests to the sequencer
 	rpcUrl := ""
@@ -39,6 +42,11 @@ func APIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, r
 	base := NewBaseApi(filters, stateCache, blockReader, agg, cfg.WithDatadir, cfg.EvmCallTimeout, engine, cfg.Dirs)
 	base.SetL2RpcUrl(ethCfg.Zk.L2RpcUrl)
 	base.SetGasless(ethCfg.AllowFreeTransactions)
+	base.SetTxForwarder(txForwarder)
+	base.SetL2GasTracker(l2GasTracker)
+	if failover != nil {
+		base.SetFailover(failover)
+	}
 	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.Feecap, cfg.ReturnDataLimit, ethCfg, cfg.AllowUnprotectedTxs, cfg.MaxGetProofRewindBlockCount, cfg.WebsocketSubscribeLogsChannelSize, logger, gasTracker, cfg.LogsMaxRange, ethCfg.DebugDisableStateRootCheck)
 	erigonImpl := NewErigonAPI(base, db, eth)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool, rawPool, rpcUrl)
@@ -70,6 +78,9 @@ func APIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, r
 	gqlImpl := NewGraphQLAPI(base, db)
 	overlayImpl := NewOverlayAPI(base, db, cfg.Gascap, cfg.OverlayGetLogsTimeout, cfg.OverlayReplayBlockTimeout, otsImpl)
 	zkEvmImpl := NewZkEvmAPI(ethImpl, db, cfg.ReturnDataLimit, ethCfg, l1Syncer, rpcUrl, dataStreamServer)
+	zkEvmImpl.SetExecutors(executors)
+	zkEvmAdminImpl := NewZkEvmAdminAPI(ethCfg, rawPool, failover)
+	zkEvmLimboImpl := NewZkEvmLimboAPI(ethCfg, rawPool, txPool)
 
 	if cfg.GraphQLEnabled {
 		list = append(list, rpc.API{
@@ -153,6 +164,11 @@ func APIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, r
 				Public:    false,
 				Service:   AdminAPI(adminImpl),
 				Version:   "1.0",
+			}, rpc.API{
+				Namespace: "admin",
+				Public:    false,
+				Service:   ZkEvmAdminAPI(zkEvmAdminImpl),
+				Version:   "1.0",
 			})
 		case "parity":
 			list = append(list, rpc.API{
@@ -174,6 +190,16 @@ func APIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, r
 				Public:    true,
 				Service:   ZkEvmAPI(zkEvmImpl),
 				Version:   "1.0",
+			}, rpc.API{
+				Namespace: "zkevm",
+				Public:    true,
+				Service:   ZkEvmLimboAPI(zkEvmLimboImpl),
+				Version:   "1.0",
+			}, rpc.API{
+				Namespace: "eth",
+				Public:    true,
+				Service:   ZkEvmBatchEventsAPI(NewZkEvmBatchEventsAPI(zkEvmImpl)),
+				Version:   "1.0",
 			})
 		case "clique":
 			list = append(list, clique.NewCliqueAPI(db, engine, blockReader))
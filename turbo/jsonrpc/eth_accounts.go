@@ -51,7 +51,7 @@ func (api *APIImpl) GetBalance(ctx context.Context, address libcommon.Address, b
 func (api *APIImpl) GetTransactionCount(ctx context.Context, address libcommon.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
 	// zkevm: forward requests to the sequencer
 	if !sequencer.IsSequencer() {
-		res, err := api.sendGetTransactionCountToSequencer(api.l2RpcUrl, address, blockNrOrHash)
+		res, err := api.sendGetTransactionCountToSequencer(api.sequencerRpcUrl(), address, blockNrOrHash)
 		if err != nil {
 			return nil, err
 		}
@@ -39,6 +39,8 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+	"github.com/ledgerwatch/erigon/zk/txforwarder"
 	"github.com/ledgerwatch/erigon/zk/utils"
 )
 
@@ -90,6 +92,8 @@ type EthAPI interface {
 	ChainId(ctx context.Context) (hexutil.Uint64, error) /* called eth_protocolVersion elsewhere */
 	ProtocolVersion(_ context.Context) (hexutil.Uint, error)
 	GasPrice(_ context.Context) (*hexutil.Big, error)
+	MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error)
+	FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error)
 
 	// Sending related (see ./eth_call.go)
 	Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (hexutility.Bytes, error)
@@ -115,6 +119,8 @@ type BaseAPI struct {
 	stateCache    kvcache.Cache
 	blocksLRU     *lru.Cache[common.Hash, *types.Block]
 	receiptsCache *lru.Cache[common.Hash, []*types.Receipt]
+	// blockL1GasPrices keeps the l1 gas prices rpc nodes got from the sequencer by block hash
+	blockL1GasPrices *lru.Cache[common.Hash, *big.Int]
 
 	filters      *rpchelper.Filters
 	_chainConfig atomic.Pointer[chain.Config]
@@ -131,13 +137,17 @@ type BaseAPI struct {
 	dirs           datadir.Dirs
 	l2RpcUrl       string
 	gasless        bool
+	txForwarder    *txforwarder.Forwarder
+	l2GasTracker   *RecurringL2GasPriceTracker
+	failover       *sequencer.Failover
 	logLevel       log.Lvl
 }
 
 func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.Aggregator, singleNodeMode bool, evmCallTimeout time.Duration, engine consensus.EngineReader, dirs datadir.Dirs) *BaseAPI {
 	var (
-		blocksLRUSize      = 128 // ~32Mb
-		receiptsCacheLimit = 32
+		blocksLRUSize              = 128 // ~32Mb
+		receiptsCacheLimit         = 32
+		blockL1GasPricesCacheLimit = 1024
 	)
 	// if RPCDaemon deployed as independent process: increase cache sizes
 	if !singleNodeMode {
@@ -152,18 +162,23 @@ func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader serv
 	if err != nil {
 		panic(err)
 	}
+	blockL1GasPrices, err := lru.New[common.Hash, *big.Int](blockL1GasPricesCacheLimit)
+	if err != nil {
+		panic(err)
+	}
 
 	return &BaseAPI{
-		filters:        f,
-		stateCache:     stateCache,
-		blocksLRU:      blocksLRU,
-		receiptsCache:  receiptsCache,
-		_blockReader:   blockReader,
-		_txnReader:     blockReader,
-		_agg:           agg,
-		evmCallTimeout: evmCallTimeout,
-		_engine:        engine,
-		dirs:           dirs,
+		filters:          f,
+		stateCache:       stateCache,
+		blocksLRU:        blocksLRU,
+		receiptsCache:    receiptsCache,
+		blockL1GasPrices: blockL1GasPrices,
+		_blockReader:     blockReader,
+		_txnReader:       blockReader,
+		_agg:             agg,
+		evmCallTimeout:   evmCallTimeout,
+		_engine:          engine,
+		dirs:             dirs,
 	}
 }
 
@@ -385,6 +400,7 @@ type APIImpl struct {
 	LogsMaxRange                  uint64
 	DisableStateRootCheck         bool
 	DisableVirtualCounters        bool
+	EffectiveGasPriceDetails      bool
 }
 
 // NewEthAPI returns APIImpl instance
@@ -427,6 +443,7 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		LogsMaxRange:                  LogsMaxRange,
 		DisableStateRootCheck:         disableStateRootCheck,
 		DisableVirtualCounters:        ethCfg.DisableVirtualCounters,
+		EffectiveGasPriceDetails:      ethCfg.RpcReceiptEffectiveGasPriceDetails,
 	}
 }
 
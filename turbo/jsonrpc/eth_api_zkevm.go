@@ -9,7 +9,9 @@ import (
 	types2 "github.com/ledgerwatch/erigon-lib/types"
 	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 	zktx "github.com/ledgerwatch/erigon/zk/tx"
+	"github.com/ledgerwatch/erigon/zk/txforwarder"
 )
 
 func (api *BaseAPI) SetL2RpcUrl(url string) {
@@ -23,10 +25,35 @@ func (api *BaseAPI) GetL2RpcUrl() string {
 	return api.l2RpcUrl
 }
 
+// SetFailover makes a node sharing the sequencer lease forward requests to whichever node holds it
+func (api *BaseAPI) SetFailover(failover *sequencer.Failover) {
+	api.failover = failover
+}
+
+// sequencerRpcUrl returns the url non-sequencer nodes forward requests to
+func (api *BaseAPI) sequencerRpcUrl() string {
+	if api.failover != nil {
+		if url := api.failover.SequencerRpcUrl(); url != "" {
+			return url
+		}
+	}
+	return api.l2RpcUrl
+}
+
 func (api *BaseAPI) SetGasless(gasless bool) {
 	api.gasless = gasless
 }
 
+// SetL2GasTracker makes non-sequencer nodes answer eth_gasPrice from the polled sequencer gas price
+func (api *BaseAPI) SetL2GasTracker(tracker *RecurringL2GasPriceTracker) {
+	api.l2GasTracker = tracker
+}
+
+// SetTxForwarder makes non-sequencer nodes forward transactions through the forwarding queue
+func (api *BaseAPI) SetTxForwarder(forwarder *txforwarder.Forwarder) {
+	api.txForwarder = forwarder
+}
+
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
 	BlockHash           *common.Hash       `json:"blockHash"`
@@ -60,16 +87,7 @@ func newRPCTransaction_zkevm(tx types.Transaction, blockHash common.Hash, blockN
 	result := NewRPCTransaction(tx, blockHash, blockNumber, index, baseFee)
 
 	if includeL2TxHash {
-		l2TxHash, err := zktx.ComputeL2TxHash(
-			tx.GetChainID().ToBig(),
-			tx.GetValue(),
-			tx.GetPrice(),
-			tx.GetNonce(),
-			tx.GetGas(),
-			tx.GetTo(),
-			&result.From,
-			tx.GetData(),
-		)
+		l2TxHash, err := zktx.ComputeL2TxHashForTx(tx, &result.From)
 		if err == nil {
 			result.L2Hash = &l2TxHash
 		}
@@ -684,7 +684,16 @@ func (api *APIImpl) GetTransactionReceipt(ctx context.Context, txnHash common.Ha
 		return nil, fmt.Errorf("block has less receipts than expected: %d <= %d, block: %d", len(receipts), int(txnIndex), blockNum)
 	}
 
-	return marshalReceipt(receipts[txnIndex], block.Transactions()[txnIndex], cc, block.HeaderNoCopy(), txnHash, true), nil
+	fields := marshalReceipt(receipts[txnIndex], block.Transactions()[txnIndex], cc, block.HeaderNoCopy(), txnHash, true)
+	details, err := api.addEffectiveGasPriceDetails(fields, tx, txn, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if details != nil {
+		tx.Rollback()
+		api.fillL1GasPrice(ctx, txnHash, block.Hash(), details)
+	}
+	return fields, nil
 }
 
 // GetBlockReceipts - receipts for individual block
@@ -716,9 +725,18 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, number rpc.BlockNumber
 		return nil, fmt.Errorf("getReceipts error: %w", err)
 	}
 	result := make([]map[string]interface{}, 0, len(receipts))
+	var details []*EffectiveGasPriceInfo
 	for _, receipt := range receipts {
 		txn := block.Transactions()[receipt.TransactionIndex]
-		result = append(result, marshalReceipt(receipt, txn, chainConfig, block.HeaderNoCopy(), txn.Hash(), true))
+		fields := marshalReceipt(receipt, txn, chainConfig, block.HeaderNoCopy(), txn.Hash(), true)
+		txnDetails, err := api.addEffectiveGasPriceDetails(fields, tx, txn, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if txnDetails != nil {
+			details = append(details, txnDetails)
+		}
+		result = append(result, fields)
 	}
 
 	if chainConfig.Bor != nil {
@@ -734,6 +752,11 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, number rpc.BlockNumber
 		}
 	}
 
+	if len(details) > 0 {
+		tx.Rollback()
+		api.fillL1GasPrice(ctx, block.Transactions()[0].Hash(), block.Hash(), details...)
+	}
+
 	return result, nil
 }
 
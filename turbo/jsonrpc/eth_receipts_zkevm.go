@@ -17,3 +17,23 @@ func (api *APIImpl) addEffectiveGasPercentage(fields map[string]interface{}, tx
 	fields["effectiveGasPrice"] = core.CalculateEffectiveGas(txn.GetPrice().Clone(), effectiveGasPricePercentage)
 	return fields, nil
 }
+
+// addEffectiveGasPriceDetails adds the fee details of txn to its receipt when enabled.  The returned details are to be
+// completed with fillL1GasPrice once the db tx is closed.
+func (api *APIImpl) addEffectiveGasPriceDetails(fields map[string]interface{}, tx kv.Tx, txn types.Transaction, blockNum uint64) (*EffectiveGasPriceInfo, error) {
+	if !api.EffectiveGasPriceDetails {
+		return nil, nil
+	}
+
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+	l1GasPrice, err := hermezDb.GetBlockL1GasPrice(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	details, err := getEffectiveGasPriceInfo(hermezDb, txn, l1GasPrice)
+	if err != nil {
+		return nil, err
+	}
+	fields["effectiveGasPriceDetails"] = details
+	return details, nil
+}
@@ -33,6 +33,7 @@ type RecurringL1GasPriceTracker struct {
 	defaultGasPrice uint64
 	maxGasPrice     uint64
 	latestPrice     *big.Int
+	latestL1Price   *big.Int
 	lowestPrice     *big.Int
 	priceHistory    []*big.Int
 	rpcUrl          string
@@ -80,6 +81,14 @@ func (t *RecurringL1GasPriceTracker) setLatestPrice(price *big.Int) {
 	t.latestPrice = price
 }
 
+// GetLatestL1Price returns the last l1 gas price fetched, before the factor is applied, or nil if none was fetched yet
+func (t *RecurringL1GasPriceTracker) GetLatestL1Price() *big.Int {
+	t.latestMtx.Lock()
+	defer t.latestMtx.Unlock()
+
+	return t.latestL1Price
+}
+
 func (t *RecurringL1GasPriceTracker) getLatestPrice() *big.Int {
 	t.latestMtx.Lock()
 	defer t.latestMtx.Unlock()
@@ -173,6 +182,9 @@ func (t *RecurringL1GasPriceTracker) fetchAndStoreNewL1GasPrice() error {
 	if err != nil {
 		return err
 	}
+	t.latestMtx.Lock()
+	t.latestL1Price = latest
+	t.latestMtx.Unlock()
 	t.setLatestPrice(factored)
 	t.calculateAndStoreNewLowestPrice(factored)
 	return nil
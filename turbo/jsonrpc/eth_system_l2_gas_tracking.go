@@ -0,0 +1,211 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	// l2GasPriceOnDemandMaxAge is how long a price fetched on demand is served for when the tracker is not polling
+	l2GasPriceOnDemandMaxAge = 3 * time.Second
+	// l2GasPriceFetchTimeout bounds a single request to the sequencer, a hung sequencer must not hold up eth_gasPrice.
+	// The same sequencer is not asked more often than that on demand either, so one that is down is not asked back to
+	// back by every eth_gasPrice call.
+	l2GasPriceFetchTimeout = 2 * time.Second
+)
+
+var errL2GasPriceUnavailable = errors.New("sequencer gas price unavailable")
+
+// RecurringL2GasPriceTracker keeps the gas price of the sequencer for non-sequencer nodes, so eth_gasPrice is answered
+// from memory rather than by dialing the sequencer on every call
+type RecurringL2GasPriceTracker struct {
+	// sequencerRpcUrl returns the url of the sequencer to ask, it changes when a node sharing the sequencer lease is
+	// handed over to another one and is empty while the node is sequencing itself
+	sequencerRpcUrl func() string
+	frequency       time.Duration
+	price           *big.Int
+	priceUrl        string
+	lastFetch       time.Time
+	attemptUrl      string
+	lastAttempt     time.Time
+	stop            chan struct{}
+	stopOnce        sync.Once
+	refresh         chan struct{}
+	mtx             *sync.Mutex
+	fetchMtx        *sync.Mutex
+	running         atomic.Bool
+}
+
+func NewRecurringL2GasPriceTracker(sequencerRpcUrl func() string, frequency time.Duration) *RecurringL2GasPriceTracker {
+	return &RecurringL2GasPriceTracker{
+		sequencerRpcUrl: sequencerRpcUrl,
+		frequency:       frequency,
+		stop:            make(chan struct{}),
+		refresh:         make(chan struct{}, 1),
+		mtx:             &sync.Mutex{},
+		fetchMtx:        &sync.Mutex{},
+	}
+}
+
+// maxAge is how long a fetched price is served for, a polled price outlives a few missed polls before it is stale
+func (t *RecurringL2GasPriceTracker) maxAge() time.Duration {
+	if t.frequency == 0 {
+		return l2GasPriceOnDemandMaxAge
+	}
+	return 3 * t.frequency
+}
+
+// getPrice returns the price fetched from url, a price fetched from a previous sequencer is not served
+func (t *RecurringL2GasPriceTracker) getPrice(url string) (*big.Int, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.price == nil || t.priceUrl != url || time.Since(t.lastFetch) > t.maxAge() {
+		return nil, false
+	}
+	return t.price, true
+}
+
+// fetchDue reports whether the sequencer at url may be asked for its price again
+func (t *RecurringL2GasPriceTracker) fetchDue(url string) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return t.attemptUrl != url || time.Since(t.lastAttempt) >= l2GasPriceFetchTimeout
+}
+
+func (t *RecurringL2GasPriceTracker) setAttempt(url string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.attemptUrl = url
+	t.lastAttempt = time.Now()
+}
+
+func (t *RecurringL2GasPriceTracker) setPrice(url string, price *big.Int) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.price = price
+	t.priceUrl = url
+	t.lastFetch = time.Now()
+}
+
+// GetLatestPrice returns the gas price of the sequencer.  It errors rather than wait on the sequencer when the price
+// is stale, callers are expected to fall back to the locally synced blocks.  While polling, a stale price only asks
+// the polling loop to refresh it.  Otherwise a single caller fetches it within ctx and l2GasPriceFetchTimeout, and
+// the callers arriving meanwhile fall back instead of queueing behind it.  Either way the sequencer is asked at most
+// once per l2GasPriceFetchTimeout.
+func (t *RecurringL2GasPriceTracker) GetLatestPrice(ctx context.Context) (*big.Int, error) {
+	url := t.sequencerRpcUrl()
+	if price, ok := t.getPrice(url); ok {
+		return price, nil
+	}
+
+	if !t.fetchDue(url) {
+		return nil, errL2GasPriceUnavailable
+	}
+
+	if t.running.Load() {
+		select {
+		case t.refresh <- struct{}{}:
+		default:
+		}
+		return nil, errL2GasPriceUnavailable
+	}
+
+	if !t.fetchMtx.TryLock() {
+		return nil, errL2GasPriceUnavailable
+	}
+	defer t.fetchMtx.Unlock()
+	if price, ok := t.getPrice(url); ok {
+		return price, nil
+	}
+	if !t.fetchDue(url) {
+		return nil, errL2GasPriceUnavailable
+	}
+
+	return t.fetchAndStoreNewL2GasPrice(ctx, url)
+}
+
+func (t *RecurringL2GasPriceTracker) Start() {
+	if t.frequency == 0 || !t.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-t.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		ticker := time.NewTicker(t.frequency)
+		defer ticker.Stop()
+		for {
+			log.Trace("[L2GasPriceTracker] Fetching and storing new L2 gas price")
+			if url := t.sequencerRpcUrl(); url != "" {
+				if _, err := t.fetchAndStoreNewL2GasPrice(ctx, url); err != nil {
+					log.Warn("[L2GasPriceTracker] Failed to fetch the sequencer gas price", "error", err)
+				}
+			}
+			// a refresh asked for while the fetch was in flight has been answered by it
+			select {
+			case <-t.refresh:
+			default:
+			}
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+			case <-t.refresh:
+			}
+		}
+	}()
+}
+
+func (t *RecurringL2GasPriceTracker) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	t.running.Store(false)
+}
+
+func (t *RecurringL2GasPriceTracker) fetchAndStoreNewL2GasPrice(ctx context.Context, url string) (*big.Int, error) {
+	if url == "" {
+		return nil, errors.New("no sequencer to ask for the gas price")
+	}
+	t.setAttempt(url)
+
+	ctx, cancel := context.WithTimeout(ctx, l2GasPriceFetchTimeout)
+	defer cancel()
+	res, err := client.JSONRPCCallWithContext(ctx, url, "eth_gasPrice")
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != nil {
+		return nil, fmt.Errorf("RPC error response: %s", res.Error.Message)
+	}
+
+	var price hexutil.Big
+	if err := json.Unmarshal(res.Result, &price); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %v", err)
+	}
+
+	t.setPrice(url, price.ToInt())
+	return price.ToInt(), nil
+}
@@ -0,0 +1,178 @@
+package jsonrpc
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RecurringL2GasPriceTracker_GetLatestPrice(t *testing.T) {
+	var calls atomic.Int32
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x3b9aca00"}`))
+	}))
+
+	tracker := NewRecurringL2GasPriceTracker(func() string { return sequencer.URL }, 0)
+
+	// the price is fetched once and then served from memory
+	for i := 0; i < 5; i++ {
+		price, err := tracker.GetLatestPrice(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1_000_000_000), price)
+	}
+	require.Equal(t, int32(1), calls.Load())
+
+	// once stale the sequencer is asked again, which fails while it is down
+	sequencer.Close()
+	tracker.lastFetch = time.Now().Add(-time.Minute)
+	tracker.lastAttempt = tracker.lastFetch
+	_, err := tracker.GetLatestPrice(context.Background())
+	require.Error(t, err)
+	require.NotErrorIs(t, err, errL2GasPriceUnavailable)
+
+	// and is not asked again straight away
+	_, err = tracker.GetLatestPrice(context.Background())
+	require.ErrorIs(t, err, errL2GasPriceUnavailable)
+}
+
+func Test_RecurringL2GasPriceTracker_FollowsSequencer(t *testing.T) {
+	serve := func(price string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"` + price + `"}`))
+		}))
+	}
+	first, second := serve("0x1"), serve("0x2")
+	defer first.Close()
+	defer second.Close()
+
+	var url atomic.Value
+	url.Store(first.URL)
+	tracker := NewRecurringL2GasPriceTracker(func() string { return url.Load().(string) }, 0)
+
+	price, err := tracker.GetLatestPrice(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1), price)
+
+	// once the lease is handed over the price of the new sequencer is served straight away
+	url.Store(second.URL)
+	price, err = tracker.GetLatestPrice(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(2), price)
+
+	// while the node is sequencing there is no sequencer to ask
+	url.Store("")
+	_, err = tracker.GetLatestPrice(context.Background())
+	require.Error(t, err)
+}
+
+func Test_RecurringL2GasPriceTracker_HungSequencer(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer sequencer.Close()
+	defer close(release)
+
+	tracker := NewRecurringL2GasPriceTracker(func() string { return sequencer.URL }, 0)
+
+	// a caller waiting on the sequencer gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	fetched := make(chan error)
+	go func() {
+		_, err := tracker.GetLatestPrice(ctx)
+		fetched <- err
+	}()
+
+	// the callers arriving meanwhile fall back straight away rather than queue behind it
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	start := time.Now()
+	_, err := tracker.GetLatestPrice(context.Background())
+	require.ErrorIs(t, err, errL2GasPriceUnavailable)
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+
+	select {
+	case err = <-fetched:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the fetch did not give up with its context")
+	}
+}
+
+func Test_RecurringL2GasPriceTracker_PollingServesFallback(t *testing.T) {
+	var calls atomic.Int32
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer sequencer.Close()
+
+	tracker := NewRecurringL2GasPriceTracker(func() string { return sequencer.URL }, time.Hour)
+	tracker.Start()
+	tracker.Start()
+	defer tracker.Stop()
+	require.Eventually(t, func() bool {
+		_, err := tracker.GetLatestPrice(context.Background())
+		return err == nil
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(1), calls.Load())
+
+	// a stale price is not fetched inline, the caller falls back and the polling loop refreshes it
+	tracker.mtx.Lock()
+	tracker.lastFetch = time.Now().Add(-4 * time.Hour)
+	tracker.lastAttempt = tracker.lastFetch
+	tracker.mtx.Unlock()
+	_, err := tracker.GetLatestPrice(context.Background())
+	require.ErrorIs(t, err, errL2GasPriceUnavailable)
+	require.Eventually(t, func() bool {
+		_, err := tracker.GetLatestPrice(context.Background())
+		return err == nil
+	}, time.Second, time.Millisecond)
+	require.Equal(t, int32(2), calls.Load())
+
+	// stopping twice must not panic
+	tracker.Stop()
+	tracker.Stop()
+}
+
+func Test_RecurringL2GasPriceTracker_RefreshRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer sequencer.Close()
+
+	tracker := NewRecurringL2GasPriceTracker(func() string { return sequencer.URL }, time.Hour)
+	tracker.Start()
+	defer tracker.Stop()
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the sequencer is down, callers fall back without asking the polling loop to refresh the price over and over
+	for i := 0; i < 10; i++ {
+		_, err := tracker.GetLatestPrice(context.Background())
+		require.ErrorIs(t, err, errL2GasPriceUnavailable)
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, int32(1), calls.Load())
+
+	// once the last attempt is old enough a caller asks for a refresh again
+	tracker.mtx.Lock()
+	tracker.lastAttempt = time.Now().Add(-l2GasPriceFetchTimeout)
+	tracker.mtx.Unlock()
+	_, err := tracker.GetLatestPrice(context.Background())
+	require.ErrorIs(t, err, errL2GasPriceUnavailable)
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond)
+}
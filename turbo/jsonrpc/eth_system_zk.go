@@ -4,8 +4,15 @@ import (
 	"context"
 	"math/big"
 
+	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/hexutil"
-	"github.com/ledgerwatch/erigon/ethclient"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/gasprice"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
 )
 
 type RpcL1GasPriceTracker interface {
@@ -37,16 +44,34 @@ func (api *APIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
 		return &price, nil
 	}
 
-	client, err := ethclient.DialContext(ctx, api.l2RpcUrl)
-	if err != nil {
-		return nil, err
+	if api.l2GasTracker != nil {
+		price, err := api.l2GasTracker.GetLatestPrice(ctx)
+		if err == nil {
+			return (*hexutil.Big)(price), nil
+		}
+		log.Debug("Sequencer gas price unavailable, suggesting from the local blocks", "err", err)
 	}
-	defer client.Close()
 
-	price, err := client.SuggestGasPrice(ctx)
+	return api.gasPriceFromBlocks(ctx, tx)
+}
+
+// gasPriceFromBlocks suggests a gas price from the gas prices signed in the latest locally synced blocks
+func (api *APIImpl) gasPriceFromBlocks(ctx context.Context, tx kv.Tx) (*hexutil.Big, error) {
+	oracle := gasprice.NewOracle(NewGasPriceOracleBackend(tx, api.BaseAPI), ethconfig.Defaults.GPO, api.gasCache)
+	tipcap, err := oracle.SuggestTipCap(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	price := new(big.Int).Set(tipcap)
+	if head := rawdb.ReadCurrentHeader(tx); head != nil && head.BaseFee != nil {
+		price.Add(price, head.BaseFee)
+	}
+
 	return (*hexutil.Big)(price), nil
 }
+
+// GetEffectiveGasPricePercentage makes the fee history report the gas prices the sequencer actually charged
+func (b *GasPriceOracleBackend) GetEffectiveGasPricePercentage(txHash common.Hash) (uint8, error) {
+	return hermez_db.NewHermezDbReader(b.tx).GetEffectiveGasPricePercentage(txHash)
+}
@@ -85,7 +85,7 @@ func (api *APIImpl) GetTransactionByHash_deprecated(ctx context.Context, txnHash
 
 	if !sequencer.IsSequencer() {
 		// forward the request on to the sequencer at this point as it is the only node with an active txpool
-		return api.forwardGetTransactionByHash(api.l2RpcUrl, txnHash, nil)
+		return api.forwardGetTransactionByHash(api.sequencerRpcUrl(), txnHash, nil)
 	}
 
 	curHeader := rawdb.ReadCurrentHeader(tx)
@@ -110,7 +110,7 @@ func (api *APIImpl) GetTransactionByHash(ctx context.Context, txnHash common.Has
 
 	if !sequencer.IsSequencer() {
 		// forward the request on to the sequencer at this point as it is the only node with an active txpool
-		return api.forwardGetTransactionByHash(api.l2RpcUrl, txnHash, includeExtraInfo)
+		return api.forwardGetTransactionByHash(api.sequencerRpcUrl(), txnHash, includeExtraInfo)
 	}
 
 	curHeader := rawdb.ReadCurrentHeader(tx)
@@ -15,6 +15,7 @@ import (
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
 	"github.com/ledgerwatch/erigon/zk/utils"
 )
 
@@ -41,7 +42,11 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutility
 			return api.sendTxZk(api.PoolManagerUrl, encodedTx, chainId.Uint64())
 		}
 
-		return api.sendTxZk(api.l2RpcUrl, encodedTx, chainId.Uint64())
+		if api.txForwarder != nil {
+			return api.txForwarder.Forward(encodedTx)
+		}
+
+		return api.sendTxZk(api.sequencerRpcUrl(), encodedTx, chainId.Uint64())
 	}
 
 	txn, err := types.DecodeWrappedTransaction(encodedTx)
@@ -75,8 +80,15 @@ func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutility
 			return common.Hash{}, err
 		}
 
+		// before London typed transactions are only taken once the batch l2 data can carry them
 		if !cc.IsLondon(latestBlock.NumberU64()) {
-			return common.Hash{}, errors.New("only legacy transactions are supported")
+			forkId, err := hermez_db.NewHermezDbReader(tx).GetForkIdByBlockNum(latestBlockNumber)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			if !zktx.TxTypeSupported(txn.Type(), forkId) {
+				return common.Hash{}, errors.New("only legacy transactions are supported")
+			}
 		}
 
 		if txn.Type() == types.BlobTxType {
@@ -0,0 +1,104 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+	"github.com/ledgerwatch/erigon/zk/txpool"
+)
+
+// ZkEvmAdminAPI is the interface for the sequencer's admin_* RPC commands.  These expose the internals of the
+// sequencer so are only served when the admin namespace is enabled, never as part of the public zkevm namespace.
+type ZkEvmAdminAPI interface {
+	GetLimboStats(ctx context.Context) (*txpool.LimboStats, error)
+	GetSequencerStatus(ctx context.Context) (*sequencer.FailoverStatus, error)
+	PromoteSequencer(ctx context.Context, force bool) (*sequencer.FailoverStatus, error)
+	StepDownSequencer(ctx context.Context) error
+}
+
+// ZkEvmAdminAPIImpl data structure to store things needed for the sequencer's admin_* commands.
+type ZkEvmAdminAPIImpl struct {
+	config   *ethconfig.Config
+	rawPool  *txpool.TxPool
+	failover *sequencer.Failover
+}
+
+// NewZkEvmAdminAPI returns ZkEvmAdminAPIImpl instance.  rawPool is nil unless the node runs it itself, failover is nil
+// unless the node shares a sequencer lease.
+func NewZkEvmAdminAPI(config *ethconfig.Config, rawPool *txpool.TxPool, failover *sequencer.Failover) *ZkEvmAdminAPIImpl {
+	return &ZkEvmAdminAPIImpl{
+		config:   config,
+		rawPool:  rawPool,
+		failover: failover,
+	}
+}
+
+// GetLimboStats returns the batches and transactions currently held in limbo after being rejected by the executor
+func (api *ZkEvmAdminAPIImpl) GetLimboStats(ctx context.Context) (*txpool.LimboStats, error) {
+	if !sequencer.IsSequencer() {
+		return nil, errors.New("method only supported from a sequencer node")
+	}
+	if api.rawPool == nil || !api.config.Limbo {
+		return nil, errors.New("limbo is not enabled on this node")
+	}
+
+	return api.rawPool.GetLimboStats(), nil
+}
+
+var errAdminUnauthorized = errors.New("unauthorized")
+
+// checkAdminToken guards the operator methods of the admin namespace, they are refused without the
+// zkevm.admin-token bearer token and disabled altogether when it is not set
+func checkAdminToken(ctx context.Context, config *ethconfig.Config) error {
+	token := config.AdminToken
+	if token == "" {
+		return errors.New("admin methods are disabled, zkevm.admin-token is not set")
+	}
+	auth := rpc.AuthorizationFromContext(ctx)
+	if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+		return errAdminUnauthorized
+	}
+
+	return nil
+}
+
+func (api *ZkEvmAdminAPIImpl) checkFailoverAccess(ctx context.Context) error {
+	if api.failover == nil {
+		return errors.New("sequencer failover is not enabled on this node, zkevm.sequencer-lease-file is not set")
+	}
+
+	return checkAdminToken(ctx, api.config)
+}
+
+// GetSequencerStatus returns the sequencer lease and, on a standby, how far behind the active sequencer it is
+func (api *ZkEvmAdminAPIImpl) GetSequencerStatus(ctx context.Context) (*sequencer.FailoverStatus, error) {
+	if err := api.checkFailoverAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	return api.failover.Status(), nil
+}
+
+// PromoteSequencer makes a following node the sequencer once the active one has stepped down or its lease has run out.
+// The node starts sequencing in place.  force skips checking the node has synced every block of the active sequencer.
+func (api *ZkEvmAdminAPIImpl) PromoteSequencer(ctx context.Context, force bool) (*sequencer.FailoverStatus, error) {
+	if err := api.checkFailoverAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	return api.failover.Promote(ctx, force)
+}
+
+// StepDownSequencer releases the lease of the sequencer so another node can be promoted.  The node goes on to follow
+// the next sequencer.
+func (api *ZkEvmAdminAPIImpl) StepDownSequencer(ctx context.Context) error {
+	if err := api.checkFailoverAccess(ctx); err != nil {
+		return err
+	}
+
+	return api.failover.StepDown()
+}
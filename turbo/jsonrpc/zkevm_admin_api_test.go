@@ -0,0 +1,47 @@
+package jsonrpc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+)
+
+func TestZkEvmAdminAPIFailoverAccess(t *testing.T) {
+	config := &ethconfig.Config{Zk: &ethconfig.Zk{}}
+	withAuth := func(auth string) context.Context {
+		return rpc.ContextWithAuthorization(context.Background(), auth)
+	}
+
+	api := NewZkEvmAdminAPI(config, nil, nil)
+	require.ErrorContains(t, api.checkFailoverAccess(withAuth("Bearer ")), "not enabled")
+
+	failover := sequencer.NewFailover(sequencer.FailoverConfig{
+		Role:      sequencer.RoleStandby,
+		LeaseFile: filepath.Join(t.TempDir(), "sequencer.lease"),
+		LeaseTTL:  time.Minute,
+		Holder:    sequencer.Leader{Holder: "standby"},
+	})
+	api = NewZkEvmAdminAPI(config, nil, failover)
+
+	// no token configured, the methods are disabled whatever the caller sends
+	require.ErrorContains(t, api.checkFailoverAccess(withAuth("Bearer ")), "disabled")
+
+	config.AdminToken = "secret"
+	require.ErrorIs(t, api.checkFailoverAccess(context.Background()), errAdminUnauthorized)
+	require.ErrorIs(t, api.checkFailoverAccess(withAuth("Bearer wrong")), errAdminUnauthorized)
+	_, err := api.PromoteSequencer(withAuth("secret"), true)
+	require.ErrorIs(t, err, errAdminUnauthorized)
+
+	status, err := api.GetSequencerStatus(withAuth("Bearer secret"))
+	require.NoError(t, err)
+	require.Equal(t, sequencer.RoleStandby, status.Role)
+	require.False(t, status.Sequencing)
+	require.Nil(t, status.Lease)
+}
@@ -34,14 +34,17 @@ import (
 	"github.com/ledgerwatch/erigon/smt/pkg/smt"
 	smtUtils "github.com/ledgerwatch/erigon/smt/pkg/utils"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/zk/bridge"
 	"github.com/ledgerwatch/erigon/zk/datastream/server"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
 	"github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
 	types "github.com/ledgerwatch/erigon/zk/rpcdaemon"
 	"github.com/ledgerwatch/erigon/zk/sequencer"
 	zkStages "github.com/ledgerwatch/erigon/zk/stages"
 	"github.com/ledgerwatch/erigon/zk/syncer"
 	zktx "github.com/ledgerwatch/erigon/zk/tx"
+	"github.com/ledgerwatch/erigon/zk/txforwarder"
 	"github.com/ledgerwatch/erigon/zk/utils"
 	"github.com/ledgerwatch/erigon/zk/witness"
 	"github.com/ledgerwatch/erigon/zkevm/hex"
@@ -71,7 +74,9 @@ type ZkEvmAPI interface {
 	GetExitRootsByGER(ctx context.Context, globalExitRoot common.Hash) (*ZkExitRoots, error)
 	GetL2BlockInfoTree(ctx context.Context, blockNum rpc.BlockNumberOrHash) (json.RawMessage, error)
 	EstimateCounters(ctx context.Context, argsOrNil *zkevmRPCTransaction) (json.RawMessage, error)
+	EstimateBatchCounters(ctx context.Context, txs []zkevmBatchCountersTx) (json.RawMessage, error)
 	GetBatchCountersByNumber(ctx context.Context, batchNumRpc rpc.BlockNumber) (res json.RawMessage, err error)
+	GetBatchStateProof(ctx context.Context, batchNumber rpc.BlockNumber, proofAccounts []zkevmBatchStateProofAccount) (*batchStateProofResponse, error)
 	GetExitRootTable(ctx context.Context, argsOrNil *zkevmRPCExitRootTableArgs) ([]l1InfoTreeData, error)
 	GetVersionHistory(ctx context.Context) (json.RawMessage, error)
 	GetForkId(ctx context.Context) (hexutil.Uint64, error)
@@ -81,6 +86,15 @@ type ZkEvmAPI interface {
 	GetRollupAddress(ctx context.Context) (res json.RawMessage, err error)
 	GetRollupManagerAddress(ctx context.Context) (res json.RawMessage, err error)
 	GetLatestDataStreamBlock(ctx context.Context) (hexutil.Uint64, error)
+	GetExecutorStatus(ctx context.Context) ([]legacy_executor_verifier.ExecutorStatus, error)
+	GetForwardingStatus(ctx context.Context) (*txforwarder.Status, error)
+	GetEffectiveGasPrice(ctx context.Context, txHash common.Hash) (*EffectiveGasPriceInfo, error)
+	GetBridgeDeposits(ctx context.Context, address common.Address) ([]*BridgeDepositInfo, error)
+	GetDepositProof(ctx context.Context, depositCount hexutil.Uint64, verifiedBatch *hexutil.Uint64) (*DepositProof, error)
+	GetClaimStatus(ctx context.Context, globalIndex *hexutil.Big) (*ClaimStatus, error)
+	GetL1InfoTreeLeaf(ctx context.Context, index hexutil.Uint64) (*L1InfoTreeLeaf, error)
+	GetL1InfoTreeProof(ctx context.Context, index hexutil.Uint64, root common.Hash) (*L1InfoTreeProof, error)
+	GetL1InfoTreeRootAtBlock(ctx context.Context, l2Block rpc.BlockNumber) (*L1InfoTreeRootAtBlock, error)
 }
 
 const getBatchWitness = "getBatchWitness"
@@ -96,6 +110,9 @@ type ZkEvmAPIImpl struct {
 	l2SequencerUrl   string
 	semaphores       map[string]chan struct{}
 	datastreamServer server.DataStreamServer
+	l1InfoTree       *l1infotree.HistoryTreeCache
+	bridgeExitTree   *l1infotree.HistoryTreeCache
+	executors        []*legacy_executor_verifier.Executor
 }
 
 func (api *ZkEvmAPIImpl) initializeSemaphores(functionLimits map[string]int) {
@@ -127,10 +144,13 @@ func NewZkEvmAPI(
 		l1Syncer:         l1Syncer,
 		l2SequencerUrl:   l2SequencerUrl,
 		datastreamServer: dataStreamServer,
+		l1InfoTree:       l1infotree.NewHistoryTreeCache(l1InfoTreeHeight),
+		bridgeExitTree:   l1infotree.NewHistoryTreeCache(bridge.ExitTreeHeight),
 	}
 
 	a.initializeSemaphores(map[string]int{
-		getBatchWitness: zkConfig.Zk.RpcGetBatchWitnessConcurrencyLimit,
+		getBatchWitness:    zkConfig.Zk.RpcGetBatchWitnessConcurrencyLimit,
+		getBatchStateProof: zkConfig.Zk.RpcGetBatchStateProofConcurrencyLimit,
 	})
 
 	return a
@@ -687,16 +707,7 @@ func (api *ZkEvmAPIImpl) fullTxBlockData(ctx context.Context, tx kv.Tx, hermezDb
 		hashes := make([]types.TransactionOrHash, len(bbj.Transactions))
 		for i, txn := range bbj.Transactions {
 			blkTx := blk.Transactions()[i]
-			l2TxHash, err := zktx.ComputeL2TxHash(
-				blkTx.GetChainID().ToBig(),
-				blkTx.GetValue(),
-				blkTx.GetPrice(),
-				blkTx.GetNonce(),
-				blkTx.GetGas(),
-				blkTx.GetTo(),
-				&txn.Tx.From,
-				blkTx.GetData(),
-			)
+			l2TxHash, err := zktx.ComputeL2TxHashForTx(blkTx, &txn.Tx.From)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -1707,10 +1718,12 @@ func (zkapi *ZkEvmAPIImpl) GetProof(ctx context.Context, address common.Address,
 
 	stateRootNode := smtUtils.ScalarToRoot(new(big.Int).SetBytes(header.Root.Bytes()))
 
-	if err != nil {
-		return nil, err
-	}
+	return smtAccountProof(proofs, stateRootNode, address, storageKeys)
+}
 
+// smtAccountProof filters the proofs for an account and its storage slots out of proofs built with smt.BuildProofs
+// and verifies each of them against the state root
+func smtAccountProof(proofs []*smt.SMTProofElement, stateRootNode smtUtils.NodeKey, address common.Address, storageKeys []common.Hash) (*accounts.SMTAccProofResult, error) {
 	balanceKey := smtUtils.KeyEthAddrBalance(address.String())
 	nonceKey := smtUtils.KeyEthAddrNonce(address.String())
 	codeHashKey := smtUtils.KeyContractCode(address.String())
@@ -1938,3 +1951,31 @@ func (api *ZkEvmAPIImpl) GetLatestDataStreamBlock(ctx context.Context) (hexutil.
 
 	return hexutil.Uint64(latestBlock), nil
 }
+
+// SetExecutors gives the API access to the executors used by the sequencer's verifier
+func (api *ZkEvmAPIImpl) SetExecutors(executors []*legacy_executor_verifier.Executor) {
+	api.executors = executors
+}
+
+// GetExecutorStatus returns the health, load and recent error rates of each executor the sequencer routes
+// verification requests to.  Executors are identified by their position in zkevm.executor-urls, the urls themselves
+// are not returned.  It requires the zkevm.admin-token bearer token.
+func (api *ZkEvmAPIImpl) GetExecutorStatus(ctx context.Context) ([]legacy_executor_verifier.ExecutorStatus, error) {
+	if !sequencer.IsSequencer() {
+		return nil, errors.New("method only supported from a sequencer node")
+	}
+	if err := checkAdminToken(ctx, api.config); err != nil {
+		return nil, err
+	}
+
+	return legacy_executor_verifier.ExecutorStatuses(api.executors), nil
+}
+
+// GetForwardingStatus returns the state of the transaction forwarding queue of a non-sequencer node
+func (api *ZkEvmAPIImpl) GetForwardingStatus(ctx context.Context) (*txforwarder.Status, error) {
+	if sequencer.IsSequencer() {
+		return nil, errors.New("method only supported from a non-sequencer node")
+	}
+
+	return api.ethApi.txForwarder.Status(), nil
+}
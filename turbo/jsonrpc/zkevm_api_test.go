@@ -28,6 +28,7 @@ import (
 	"github.com/ledgerwatch/erigon/zk/erigon_db"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
 	rpctypes "github.com/ledgerwatch/erigon/zk/rpcdaemon"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 	"github.com/ledgerwatch/erigon/zk/syncer"
 	"github.com/ledgerwatch/erigon/zk/syncer/mocks"
 	zktypes "github.com/ledgerwatch/erigon/zk/types"
@@ -1535,3 +1536,40 @@ func TestGetRollupManagerAddress(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal(result, common.HexToAddress("0x1"))
 }
+
+func TestGetForwardingStatus(t *testing.T) {
+	ctx := context.Background()
+	zkEvmImpl := &ZkEvmAPIImpl{ethApi: &APIImpl{BaseAPI: &BaseAPI{}}}
+
+	// without the forwarding queue the status reports it as disabled
+	status, err := zkEvmImpl.GetForwardingStatus(ctx)
+	assert.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	t.Setenv(sequencer.SEQUENCER_ENV_KEY, "1")
+	_, err = zkEvmImpl.GetForwardingStatus(ctx)
+	assert.EqualError(t, err, "method only supported from a non-sequencer node")
+}
+
+func TestGetExecutorStatusAccess(t *testing.T) {
+	t.Setenv(sequencer.SEQUENCER_ENV_KEY, "1")
+
+	config := &ethconfig.Config{Zk: &ethconfig.Zk{}}
+	api := &ZkEvmAPIImpl{config: config}
+	withAuth := func(auth string) context.Context {
+		return rpc.ContextWithAuthorization(context.Background(), auth)
+	}
+
+	_, err := api.GetExecutorStatus(withAuth("Bearer "))
+	assert.ErrorContains(t, err, "disabled")
+
+	config.AdminToken = "secret"
+	_, err = api.GetExecutorStatus(context.Background())
+	assert.ErrorIs(t, err, errAdminUnauthorized)
+	_, err = api.GetExecutorStatus(withAuth("Bearer wrong"))
+	assert.ErrorIs(t, err, errAdminUnauthorized)
+
+	statuses, err := api.GetExecutorStatus(withAuth("Bearer secret"))
+	assert.NoError(t, err)
+	assert.Empty(t, statuses)
+}
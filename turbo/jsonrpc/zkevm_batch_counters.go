@@ -0,0 +1,267 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/smt"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+// zkevmBatchCountersTx is a single entry in a zkevm_estimateBatchCounters request.  Either a signed
+// transaction is supplied as raw RLP or an unsigned one in the same format as zkevm_estimateCounters.
+// NewBlock starts a new L2 block before this transaction is added, the first entry always starts a block.
+// L1InfoTreeIndex is the L1 info tree update the new block uses, as chosen by the sequencer when a newer update is
+// available.  It must exist in the node's L1 info tree and increase from block to block.  Only the changeL2Block
+// merkle proof verification it causes is counted, the GER written to the state by it has no effect on the counters.
+type zkevmBatchCountersTx struct {
+	RawTx           hexutility.Bytes     `json:"rawTx,omitempty"`
+	Tx              *zkevmRPCTransaction `json:"tx,omitempty"`
+	NewBlock        bool                 `json:"newBlock,omitempty"`
+	L1InfoTreeIndex hexutil.Uint64       `json:"l1InfoTreeIndex,omitempty"`
+}
+
+type batchCountersTxResult struct {
+	Hash    common.Hash    `json:"hash"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type estimateBatchCountersResponse struct {
+	SmtDepth         int                     `json:"smtDepth"`
+	BlockCount       int                     `json:"blockCount"`
+	CountersUsed     combinecCounters        `json:"countersUsed"`
+	CountersLimits   combinecCounters        `json:"countersLimits"`
+	OverflowIndex    *int                    `json:"overflowIndex"`
+	OverflowCounters []vm.CounterName        `json:"overflowCounters,omitempty"`
+	Transactions     []batchCountersTxResult `json:"transactions"`
+}
+
+// EstimateBatchCounters implements zkevm_estimateBatchCounters.  It runs the supplied transactions in order on top
+// of the latest state, using the same batch counter collector as the sequencer, and reports the cumulative batch
+// counters along with the index of the first transaction that would overflow the batch.  Simulation stops at the
+// first overflow, the counters returned include the overflowing transaction.
+func (zkapi *ZkEvmAPIImpl) EstimateBatchCounters(ctx context.Context, txs []zkevmBatchCountersTx) (json.RawMessage, error) {
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("no transactions provided")
+	}
+
+	api := zkapi.ethApi
+
+	dbtx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	chainConfig, err := api.chainConfig(ctx, dbtx)
+	if err != nil {
+		return nil, err
+	}
+	engine := api.engine()
+
+	latestCanBlockNumber, latestCanHash, isLatest, err := rpchelper.GetCanonicalBlockNumber_zkevm(latestNumOrHash, dbtx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	block := api.tryBlockFromLru(latestCanHash)
+	if block == nil {
+		block, err = api.blockWithSenders(ctx, dbtx, latestCanHash, latestCanBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("could not find latest block in cache or db")
+	}
+
+	stateReader, err := rpchelper.CreateStateReaderFromBlockNumber(ctx, dbtx, latestCanBlockNumber, isLatest, 0, api.stateCache, api.historyV3(dbtx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	header := block.HeaderNoCopy()
+	ibs := state.New(stateReader)
+
+	blockCtx := core.NewEVMBlockContext(header, core.GetHashFn(header, nil), engine, nil)
+	rules := chainConfig.Rules(block.NumberU64(), header.Time)
+	signer := types.MakeSigner(chainConfig, header.Number.Uint64(), 0)
+
+	hermezDb := hermez_db.NewHermezDbReader(dbtx)
+	forkId, err := hermezDb.GetForkIdByBlockNum(block.NumberU64())
+	if err != nil {
+		return nil, err
+	}
+
+	smtDepth := smt.NewRoSMT(db2.NewRoEriDb(dbtx)).GetDepth()
+	batchCounters := vm.NewBatchCounterCollector(int(smtDepth), uint16(forkId), zkapi.config.Zk.VirtualCountersSmtReduction, false, nil)
+
+	res := estimateBatchCountersResponse{
+		SmtDepth:     int(smtDepth),
+		Transactions: make([]batchCountersTxResult, 0, len(txs)),
+	}
+
+	verifyMerkleProof := false
+	var lastInfoTreeIndex uint64
+	for i, entry := range txs {
+		if i != 0 && !entry.NewBlock && entry.L1InfoTreeIndex != 0 {
+			return nil, fmt.Errorf("transaction %d: l1InfoTreeIndex can only be set on an entry starting a new block", i)
+		}
+		if i == 0 || entry.NewBlock {
+			if i != 0 {
+				blockCtx.BlockNumber++
+				blockCtx.Time++
+			}
+			verifyMerkleProof = false
+			if entry.L1InfoTreeIndex != 0 {
+				infoTreeIndex := uint64(entry.L1InfoTreeIndex)
+				if infoTreeIndex <= lastInfoTreeIndex {
+					return nil, fmt.Errorf("transaction %d: l1 info tree index %d must be greater than the previous block's %d", i, infoTreeIndex, lastInfoTreeIndex)
+				}
+				update, err := hermezDb.GetL1InfoTreeUpdate(infoTreeIndex)
+				if err != nil {
+					return nil, err
+				}
+				if update == nil {
+					return nil, fmt.Errorf("transaction %d: l1 info tree index %d not found", i, infoTreeIndex)
+				}
+				lastInfoTreeIndex = infoTreeIndex
+				verifyMerkleProof = true
+			}
+			res.BlockCount++
+			overflow, err := batchCounters.StartNewBlock(verifyMerkleProof)
+			if err != nil {
+				return nil, err
+			}
+			if overflow {
+				res.OverflowIndex = &i
+				break
+			}
+		}
+
+		tx, checkNonce, err := entry.transaction(stateReader)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+
+		// keep a copy to roll back to should the transaction fail before execution
+		previousCounters := batchCounters.Clone()
+		txCounters := vm.NewTransactionCounter(tx, int(smtDepth), uint16(forkId), zkapi.config.Zk.VirtualCountersSmtReduction, false)
+		overflow, err := batchCounters.AddNewTransactionCounters(txCounters)
+		if err != nil {
+			return nil, err
+		}
+		if overflow {
+			res.Transactions = append(res.Transactions, batchCountersTxResult{Hash: tx.Hash()})
+			res.OverflowIndex = &i
+			break
+		}
+
+		msg, err := tx.AsMessage(*signer, header.BaseFee, rules)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		msg.SetCheckNonce(checkNonce)
+
+		zkConfig := vm.ZkConfig{Config: vm.Config{NoBaseFee: true}, CounterCollector: txCounters.ExecutionCounters()}
+		evm := vm.NewZkEVM(blockCtx, core.NewEVMTxContext(msg), ibs, chainConfig, zkConfig)
+		gp := new(core.GasPool).AddGas(msg.Gas())
+		ibs.Init(tx.Hash(), header.Hash(), i)
+
+		txResult := batchCountersTxResult{Hash: tx.Hash()}
+		execResult, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+		if err != nil {
+			// the sequencer would not include this transaction so it doesn't count towards the batch
+			batchCounters = previousCounters
+			txResult.Error = err.Error()
+			res.Transactions = append(res.Transactions, txResult)
+			continue
+		}
+		txResult.GasUsed = hexutil.Uint64(execResult.UsedGas)
+		if execResult.Err != nil {
+			txResult.Error = execResult.Err.Error()
+		}
+		res.Transactions = append(res.Transactions, txResult)
+
+		if err = txCounters.ProcessTx(ibs, execResult.ReturnData); err != nil {
+			return nil, err
+		}
+		if err = ibs.FinalizeTx(evm.ChainRules(), state.NewNoopWriter()); err != nil {
+			return nil, err
+		}
+
+		batchCounters.UpdateExecutionAndProcessingCountersCache(txCounters)
+		if overflow, err = batchCounters.CheckForOverflow(verifyMerkleProof); err != nil {
+			return nil, err
+		}
+		if overflow {
+			res.OverflowIndex = &i
+			break
+		}
+	}
+
+	combined, err := batchCounters.CombineCollectors(verifyMerkleProof)
+	if err != nil {
+		return nil, err
+	}
+	if res.OverflowIndex != nil {
+		res.OverflowCounters = combined.OverflownNames()
+	}
+
+	res.CountersUsed = combinecCounters{
+		KeccakHashes:     combined.GetKeccakHashes().Used(),
+		Poseidonhashes:   combined.GetPoseidonHashes().Used(),
+		PoseidonPaddings: combined.GetPoseidonPaddings().Used(),
+		MemAligns:        combined.GetMemAligns().Used(),
+		Arithmetics:      combined.GetArithmetics().Used(),
+		Binaries:         combined.GetBinaries().Used(),
+		Steps:            combined.GetSteps().Used(),
+		SHA256hashes:     combined.GetSHA256Hashes().Used(),
+	}
+	res.CountersLimits = combinecCounters{
+		KeccakHashes:     combined.GetKeccakHashes().Limit(),
+		Poseidonhashes:   combined.GetPoseidonHashes().Limit(),
+		PoseidonPaddings: combined.GetPoseidonPaddings().Limit(),
+		MemAligns:        combined.GetMemAligns().Limit(),
+		Arithmetics:      combined.GetArithmetics().Limit(),
+		Binaries:         combined.GetBinaries().Limit(),
+		Steps:            combined.GetSteps().Limit(),
+		SHA256hashes:     combined.GetSHA256Hashes().Limit(),
+	}
+	for _, t := range res.Transactions {
+		res.CountersUsed.Gas += uint64(t.GasUsed)
+	}
+
+	return json.Marshal(res)
+}
+
+// transaction returns the transaction for the entry and whether the nonce should be checked during execution.
+// Unsigned transactions are built with the sender's current nonce so the nonce isn't checked for them.
+func (e *zkevmBatchCountersTx) transaction(sr state.StateReader) (types.Transaction, bool, error) {
+	if len(e.RawTx) > 0 {
+		tx, err := types.DecodeTransaction(e.RawTx)
+		if err != nil {
+			return nil, false, err
+		}
+		return tx, true, nil
+	}
+	if e.Tx == nil {
+		return nil, false, fmt.Errorf("either rawTx or tx must be provided")
+	}
+	tx, err := e.Tx.Tx(sr)
+	if err != nil {
+		return nil, false, err
+	}
+	return tx, false, nil
+}
@@ -0,0 +1,194 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/accounts/abi/bind/backends"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+// keccakLoopAddress holds a contract that hashes 32 bytes of memory in a loop until it runs out of gas
+var keccakLoopAddress = common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+
+func newBatchCountersTestApi(t *testing.T) (*ZkEvmAPIImpl, kv.RwDB) {
+	t.Helper()
+	alloc := types.GenesisAlloc{
+		address:           {Balance: big.NewInt(9000000000000000000)},
+		address1:          {Balance: big.NewInt(200000000000000000)},
+		keccakLoopAddress: {Balance: big.NewInt(0), Code: common.FromHex("0x5b602060002050600056")},
+	}
+	contractBackend := backends.NewTestSimulatedBackendWithConfig(t, alloc, gspec.Config, gspec.GasLimit)
+	t.Cleanup(contractBackend.Close)
+	contractBackend.Commit()
+
+	ethCfg := ethconfig.Defaults
+	ethCfg.Zk = &ethconfig.Zk{VirtualCountersSmtReduction: 0.6}
+
+	db := contractBackend.DB()
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	hDB := hermez_db.NewHermezDb(tx)
+	// genesis and the block committed above
+	for blockNo := uint64(0); blockNo <= 1; blockNo++ {
+		require.NoError(t, hDB.WriteBlockBatch(blockNo, 1))
+	}
+	require.NoError(t, hDB.WriteForkId(1, 12))
+	require.NoError(t, tx.Commit())
+
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, contractBackend.BlockReader(), contractBackend.Agg(), false, rpccfg.DefaultEvmCallTimeout, contractBackend.Engine(), datadir.New(t.TempDir()))
+	ethImpl := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000, 100_000, &ethCfg, false, 100, 100, log.New(), defaultL1GasPriceTracker, 1000, false)
+	return NewZkEvmAPI(ethImpl, db, 100_000, &ethCfg, nil, "", nil), db
+}
+
+func signedTransfer(t *testing.T, nonce uint64, to common.Address) hexutility.Bytes {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, uint256.NewInt(1000), 21000, uint256.NewInt(0), nil), *types.LatestSignerForChainID(chainID), key)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, tx.MarshalBinary(&buf))
+	return buf.Bytes()
+}
+
+func unsignedTx(from, to common.Address, gas uint64, value *big.Int) *zkevmRPCTransaction {
+	return &zkevmRPCTransaction{
+		From:     &from,
+		To:       &to,
+		Gas:      hexutil.Uint64(gas),
+		GasPrice: (*hexutil.Big)(big.NewInt(0)),
+		Value:    (*hexutil.Big)(value),
+		Data:     hexutility.Bytes{},
+	}
+}
+
+func estimateBatchCounters(t *testing.T, api *ZkEvmAPIImpl, txs []zkevmBatchCountersTx) estimateBatchCountersResponse {
+	t.Helper()
+	raw, err := api.EstimateBatchCounters(ctx, txs)
+	require.NoError(t, err)
+	var res estimateBatchCountersResponse
+	require.NoError(t, json.Unmarshal(raw, &res))
+	return res
+}
+
+func TestEstimateBatchCounters_RawAndUnsignedAcrossBlocks(t *testing.T) {
+	api, _ := newBatchCountersTestApi(t)
+
+	txs := []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+		{Tx: unsignedTx(address1, address2, 21000, big.NewInt(1000)), NewBlock: true},
+		{RawTx: signedTransfer(t, 1, address2), NewBlock: true},
+	}
+	res := estimateBatchCounters(t, api, txs)
+
+	require.Nil(t, res.OverflowIndex)
+	require.Empty(t, res.OverflowCounters)
+	require.Equal(t, 3, res.BlockCount)
+	require.Len(t, res.Transactions, 3)
+	for i, tx := range res.Transactions {
+		require.Empty(t, tx.Error, "transaction %d", i)
+		require.Equal(t, hexutil.Uint64(21000), tx.GasUsed, "transaction %d", i)
+	}
+	require.Equal(t, uint64(3*21000), res.CountersUsed.Gas)
+
+	// the same transactions in a single block only pay for one changeL2Block
+	for i := range txs {
+		txs[i].NewBlock = false
+	}
+	single := estimateBatchCounters(t, api, txs)
+	require.Equal(t, 1, single.BlockCount)
+	require.Less(t, single.CountersUsed.Steps, res.CountersUsed.Steps)
+	require.Less(t, single.CountersUsed.Poseidonhashes, res.CountersUsed.Poseidonhashes)
+}
+
+func TestEstimateBatchCounters_RollsBackFailedTransactions(t *testing.T) {
+	api, _ := newBatchCountersTestApi(t)
+
+	withoutFailure := estimateBatchCounters(t, api, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+	})
+
+	// address2 has no balance so the transfer fails before execution
+	withFailure := estimateBatchCounters(t, api, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+		{Tx: unsignedTx(address2, address1, 21000, big.NewInt(1_000_000_000_000_000_000))},
+	})
+
+	require.Nil(t, withFailure.OverflowIndex)
+	require.Len(t, withFailure.Transactions, 2)
+	require.Empty(t, withFailure.Transactions[0].Error)
+	require.NotEmpty(t, withFailure.Transactions[1].Error)
+	require.Equal(t, hexutil.Uint64(0), withFailure.Transactions[1].GasUsed)
+	require.Equal(t, withoutFailure.CountersUsed, withFailure.CountersUsed)
+}
+
+func TestEstimateBatchCounters_Overflow(t *testing.T) {
+	api, _ := newBatchCountersTestApi(t)
+
+	res := estimateBatchCounters(t, api, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+		{Tx: unsignedTx(address1, keccakLoopAddress, 1_000_000, big.NewInt(0))},
+		{RawTx: signedTransfer(t, 1, address2)},
+	})
+
+	require.NotNil(t, res.OverflowIndex)
+	require.Equal(t, 1, *res.OverflowIndex)
+	// simulation stops at the overflowing transaction
+	require.Len(t, res.Transactions, 2)
+	require.Contains(t, res.OverflowCounters, vm.CounterKeyNames[vm.K])
+	require.Greater(t, res.CountersUsed.KeccakHashes, res.CountersLimits.KeccakHashes)
+}
+
+func TestEstimateBatchCounters_L1InfoTreeIndex(t *testing.T) {
+	api, db := newBatchCountersTestApi(t)
+
+	txs := []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2), L1InfoTreeIndex: 1},
+	}
+	_, err := api.EstimateBatchCounters(ctx, txs)
+	require.ErrorContains(t, err, "l1 info tree index 1 not found")
+
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	hDB := hermez_db.NewHermezDb(tx)
+	for i := uint64(1); i <= 2; i++ {
+		require.NoError(t, hDB.WriteL1InfoTreeUpdate(&zktypes.L1InfoTreeUpdate{Index: i, GER: common.BigToHash(new(big.Int).SetUint64(i))}))
+	}
+	require.NoError(t, tx.Commit())
+
+	withUpdate := estimateBatchCounters(t, api, txs)
+	withoutUpdate := estimateBatchCounters(t, api, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+	})
+	// the changeL2Block of a block using a new l1 info tree update verifies its merkle proof
+	require.Greater(t, withUpdate.CountersUsed.Steps, withoutUpdate.CountersUsed.Steps)
+
+	_, err = api.EstimateBatchCounters(ctx, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2), L1InfoTreeIndex: 2},
+		{RawTx: signedTransfer(t, 1, address2), NewBlock: true, L1InfoTreeIndex: 1},
+	})
+	require.ErrorContains(t, err, "must be greater than the previous block's 2")
+
+	_, err = api.EstimateBatchCounters(ctx, []zkevmBatchCountersTx{
+		{RawTx: signedTransfer(t, 0, address2)},
+		{RawTx: signedTransfer(t, 1, address2), L1InfoTreeIndex: 1},
+	})
+	require.ErrorContains(t, err, "can only be set on an entry starting a new block")
+}
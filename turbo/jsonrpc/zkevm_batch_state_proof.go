@@ -0,0 +1,290 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/membatchwithdb"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rpc"
+	smtDb "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/smt"
+	smtUtils "github.com/ledgerwatch/erigon/smt/pkg/utils"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zkSmt "github.com/ledgerwatch/erigon/zk/smt"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+	"github.com/ledgerwatch/erigon/zk/utils"
+	"github.com/ledgerwatch/erigon/zk/witness"
+)
+
+const getBatchStateProof = "getBatchStateProof"
+
+// zkevmBatchStateProofAccount is an account, and the storage slots of it, to prove in zkevm_getBatchStateProof
+type zkevmBatchStateProofAccount struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// batchL1Verification is the L1 verification covering a batch.  L1 only records the state root of the last batch in
+// each verification so the batch is reported as verified by that one, StateRootMatch compares the L1 root with the
+// local root at the end of VerifiedByBatch.
+type batchL1Verification struct {
+	VerifiedByBatch hexutil.Uint64 `json:"verifiedByBatch"`
+	L1BlockNumber   hexutil.Uint64 `json:"l1BlockNumber"`
+	L1TxHash        common.Hash    `json:"l1TxHash"`
+	StateRoot       common.Hash    `json:"stateRoot"`
+	StateRootMatch  bool           `json:"stateRootMatch"`
+}
+
+type batchStateProofResponse struct {
+	BatchNumber    hexutil.Uint64                `json:"batchNumber"`
+	BlockNumber    hexutil.Uint64                `json:"blockNumber"`
+	StateRoot      common.Hash                   `json:"stateRoot"`
+	L1Verification *batchL1Verification          `json:"l1Verification"`
+	Accounts       []*accounts.SMTAccProofResult `json:"accounts"`
+}
+
+// GetBatchStateProof implements zkevm_getBatchStateProof.  It returns SMT proofs for the given accounts and storage
+// slots against the state root at the end of the batch, the root L1 verifies, along with the L1 verification that
+// covers the batch if there is one yet.
+//
+// Writes to the SMT delete the nodes they replace, so only the latest root is whole.  With zkevm.smt-prune-mode=pruned
+// the roots of the last zkevm.smt-prune-retain-batches batches are kept whole too, and those batches are proven
+// straight from their root and the state history.  Any other batch is proven by unwinding the SMT in memory from the
+// head, which needs the last block of the batch to be within zkevm.witness-unwind-limit blocks of the head.  A proof
+// missing any node fails rather than being returned.
+func (zkapi *ZkEvmAPIImpl) GetBatchStateProof(ctx context.Context, batchNumber rpc.BlockNumber, proofAccounts []zkevmBatchStateProofAccount) (*batchStateProofResponse, error) {
+	if len(proofAccounts) == 0 {
+		return nil, fmt.Errorf("no accounts provided")
+	}
+
+	// unwinding the SMT is as expensive as building a witness so in-flight requests are limited, separately to witnesses
+	semaphore := zkapi.semaphores[getBatchStateProof]
+	if semaphore != nil {
+		select {
+		case semaphore <- struct{}{}:
+			defer func() { <-semaphore }()
+		default:
+			return nil, fmt.Errorf("busy")
+		}
+	}
+
+	api := zkapi.ethApi
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if api.historyV3(tx) {
+		return nil, fmt.Errorf("not supported by Erigon3")
+	}
+
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+
+	latestBatch, err := getLatestBatchNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	batchNo, _, err := rpchelper.GetBatchNumber(batchNumber, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	blockNo, found, err := hermezDb.GetHighestBlockInBatch(batchNo)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no blocks found for batch %d", batchNo)
+	}
+
+	// the latest batch may still be open, in which case there is no batch end state root to prove against yet
+	if batchNo >= latestBatch {
+		closed, err := hermezDb.GetBatchEnd(blockNo)
+		if err != nil {
+			return nil, err
+		}
+		if !closed {
+			return nil, fmt.Errorf("batch %d is not closed yet", batchNo)
+		}
+	}
+
+	latestBlock, err := rpchelper.GetLatestFinishedBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNo {
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNo)
+	}
+
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNo)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("header not found for block %d", blockNo)
+	}
+
+	verification, err := hermezDb.GetVerificationByBatchNoOrHighest(batchNo)
+	if err != nil {
+		return nil, err
+	}
+	l1Verification, err := zkapi.newBatchL1Verification(ctx, tx, hermezDb, verification, batchNo, header.Root)
+	if err != nil {
+		return nil, err
+	}
+	if l1Verification != nil && !l1Verification.StateRootMatch {
+		log.Warn("[zkevm_getBatchStateProof] Local batch state root differs from L1", "batch", verification.BatchNo, "l1", verification.StateRoot)
+	}
+
+	var dbTx kv.Tx = tx
+	rootAvailable, err := smtRootAvailable(tx, zkapi.config.Zk, batchNo, header.Root)
+	if err != nil {
+		return nil, err
+	}
+	if !rootAvailable && blockNo < latestBlock {
+		if latestBlock-blockNo > zkapi.config.WitnessUnwindLimit {
+			return nil, fmt.Errorf("the SMT no longer keeps the state root of batch %d and its last block must be within %d blocks of the head block number (currently %d) to unwind to it", batchNo, zkapi.config.WitnessUnwindLimit, latestBlock)
+		}
+
+		batch := membatchwithdb.NewMemoryBatchWithSize(tx, api.dirs.Tmp, zkapi.config.WitnessMemdbSize)
+		defer batch.Rollback()
+		if err = utils.PopulateMemoryMutationTables(batch); err != nil {
+			return nil, err
+		}
+		if err = witness.UnwindForWitness(ctx, batch, blockNo+1, latestBlock, api.dirs, api.historyV3(tx), api._agg); err != nil {
+			return nil, fmt.Errorf("UnwindForWitness: %w", err)
+		}
+		dbTx = batch
+	}
+
+	blockRpc := rpc.BlockNumber(blockNo)
+	reader, err := rpchelper.CreateStateReader(ctx, dbTx, rpc.BlockNumberOrHash{BlockNumber: &blockRpc}, 0, api.filters, api.stateCache, api.historyV3(tx), "")
+	if err != nil {
+		return nil, err
+	}
+
+	tds := state.NewTrieDbState(header.Root, dbTx, blockNo, nil)
+	tds.SetResolveReads(true)
+	tds.StartNewBuffer()
+	tds.SetStateReader(reader)
+
+	ibs := state.New(tds)
+	for _, account := range proofAccounts {
+		ibs.GetBalance(account.Address)
+		for _, key := range account.StorageKeys {
+			value := new(uint256.Int)
+			ibs.GetState(account.Address, &key, value)
+		}
+	}
+
+	rl, err := tds.ResolveSMTRetainList(map[common.Address][]common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+
+	// a single traversal of the trie builds the proofs for every account requested
+	proofs, err := smt.BuildProofsAtRoot(smt.NewRoSMT(smtDb.NewRoEriDb(dbTx)), header.Root.Big(), rl, ctx)
+	if errors.Is(err, smt.ErrNodeNotFound) {
+		return nil, fmt.Errorf("the SMT is missing nodes of the state root of batch %d: %w", batchNo, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stateRootNode := smtUtils.ScalarToRoot(new(big.Int).SetBytes(header.Root.Bytes()))
+
+	res := &batchStateProofResponse{
+		BatchNumber:    hexutil.Uint64(batchNo),
+		BlockNumber:    hexutil.Uint64(blockNo),
+		StateRoot:      header.Root,
+		L1Verification: l1Verification,
+		Accounts:       make([]*accounts.SMTAccProofResult, 0, len(proofAccounts)),
+	}
+	for _, account := range proofAccounts {
+		accProof, err := smtAccountProof(proofs, stateRootNode, account.Address, account.StorageKeys)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", account.Address, err)
+		}
+		res.Accounts = append(res.Accounts, accProof)
+	}
+
+	return res, nil
+}
+
+// newBatchL1Verification returns nil when the batch has not been verified on L1 yet
+func (zkapi *ZkEvmAPIImpl) newBatchL1Verification(ctx context.Context, tx kv.Tx, hermezDb *hermez_db.HermezDbReader, verification *zktypes.L1BatchInfo, batchNo uint64, stateRoot common.Hash) (*batchL1Verification, error) {
+	if verification == nil {
+		return nil, nil
+	}
+
+	// the local root to compare with is the one at the end of the batch the verification was for
+	if verification.BatchNo != batchNo {
+		verifiedBlockNo, found, err := hermezDb.GetHighestBlockInBatch(verification.BatchNo)
+		if err != nil {
+			return nil, err
+		}
+		stateRoot = common.Hash{}
+		if found {
+			header, err := zkapi.ethApi._blockReader.HeaderByNumber(ctx, tx, verifiedBlockNo)
+			if err != nil {
+				return nil, err
+			}
+			if header != nil {
+				stateRoot = header.Root
+			}
+		}
+	}
+
+	return &batchL1Verification{
+		VerifiedByBatch: hexutil.Uint64(verification.BatchNo),
+		L1BlockNumber:   hexutil.Uint64(verification.L1BlockNo),
+		L1TxHash:        verification.L1TxHash,
+		StateRoot:       verification.StateRoot,
+		StateRootMatch:  verification.StateRoot == stateRoot,
+	}, nil
+}
+
+// smtRootAvailable reports whether the nodes of the state root at the end of the batch can be read from the SMT.  The
+// last root always can.  Otherwise the root has to be one the pruner retains, writes delete the nodes they replace
+// unless the SMT is pruned.  The pruner sweeps the nodes of older roots over several stage loops, so their root node
+// can outlive the nodes below it and is no proof the root is whole.
+func smtRootAvailable(tx kv.Tx, zkCfg *ethconfig.Zk, batchNo uint64, root common.Hash) (bool, error) {
+	eridb := smtDb.NewRoEriDb(tx)
+	lastRoot, err := eridb.GetLastRoot()
+	if err != nil {
+		return false, err
+	}
+	if lastRoot.Cmp(root.Big()) == 0 {
+		return true, nil
+	}
+
+	if zkCfg == nil || zkCfg.SmtPruneMode != zkSmt.PruneModePruned {
+		return false, nil
+	}
+	fromBatch, _, err := zkSmt.RetainedBatches(tx, zkCfg.SmtPruneRetainBatches)
+	if err != nil {
+		return false, err
+	}
+	if batchNo < fromBatch {
+		return false, nil
+	}
+
+	node, err := eridb.Get(smtUtils.ScalarToRoot(root.Big()))
+	if err != nil {
+		return false, err
+	}
+	return !node.IsZero(), nil
+}
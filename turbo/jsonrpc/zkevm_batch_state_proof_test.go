@@ -0,0 +1,264 @@
+package jsonrpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/accounts/abi/bind/backends"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	smtDb "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/smt"
+	smtUtils "github.com/ledgerwatch/erigon/smt/pkg/utils"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zkSmt "github.com/ledgerwatch/erigon/zk/smt"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func Test_BatchL1Verification(t *testing.T) {
+	api, stateRoot := newBatchStateProofTestApi(t, 0, zkSmt.PruneModeFull)
+	tx, err := api.db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+
+	v, err := api.newBatchL1Verification(ctx, tx, hermezDb, nil, 2, stateRoot)
+	require.NoError(t, err)
+	require.Nil(t, v, "an unverified batch has no verification")
+
+	// the batch is the last one of the verification so its root is recorded on L1
+	v, err = api.newBatchL1Verification(ctx, tx, hermezDb, &zktypes.L1BatchInfo{BatchNo: 2, L1BlockNo: 100, StateRoot: stateRoot}, 2, stateRoot)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), v.VerifiedByBatch)
+	require.True(t, v.StateRootMatch)
+
+	v, err = api.newBatchL1Verification(ctx, tx, hermezDb, &zktypes.L1BatchInfo{BatchNo: 2, StateRoot: common.HexToHash("0x2")}, 2, stateRoot)
+	require.NoError(t, err)
+	require.False(t, v.StateRootMatch, "a state root mismatch must be reported")
+
+	// batch 1 is verified as part of batch 2, the L1 root is compared with the local root at the end of batch 2
+	v, err = api.newBatchL1Verification(ctx, tx, hermezDb, &zktypes.L1BatchInfo{BatchNo: 2, StateRoot: stateRoot}, 1, common.HexToHash("0x1"))
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), v.VerifiedByBatch)
+	require.True(t, v.StateRootMatch)
+}
+
+// storageContractAddress holds a contract with a single populated storage slot
+var storageContractAddress = common.HexToAddress("0x00000000000000000000000000000000000c0de2")
+
+// newBatchStateProofTestApi builds a chain of two blocks, block 1 in batch 1 and block 2 closing batch 2. The
+// simulated backend doesn't build an SMT so one is written through SetStorage, as the intermediate hashes stage would,
+// from the plain state at block 1 and then updated to the head, and the headers are rewritten to commit to the roots.
+func newBatchStateProofTestApi(t *testing.T, unwindLimit uint64, pruneMode string) (*ZkEvmAPIImpl, common.Hash) {
+	t.Helper()
+	alloc := types.GenesisAlloc{
+		address: {Balance: big.NewInt(9000000000000000000)},
+		storageContractAddress: {
+			Balance: big.NewInt(0),
+			Code:    common.FromHex("0x600160005500"),
+			Storage: map[common.Hash]common.Hash{common.HexToHash("0x1"): common.HexToHash("0x2a")},
+		},
+	}
+	contractBackend := backends.NewTestSimulatedBackendWithConfig(t, alloc, gspec.Config, gspec.GasLimit)
+	t.Cleanup(contractBackend.Close)
+	contractBackend.Commit()
+	transfer, err := types.SignTx(types.NewTransaction(0, address1, uint256.NewInt(1000), 21000, uint256.NewInt(0), nil), *types.LatestSignerForChainID(chainID), key)
+	require.NoError(t, err)
+	require.NoError(t, contractBackend.SendTransaction(ctx, transfer))
+	contractBackend.Commit()
+
+	db := contractBackend.DB()
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	hDB := hermez_db.NewHermezDb(tx)
+	require.NoError(t, hDB.WriteBlockBatch(0, 0))
+	require.NoError(t, hDB.WriteBlockBatch(1, 1))
+	require.NoError(t, hDB.WriteBlockBatch(2, 2))
+	require.NoError(t, hDB.WriteBatchEnd(1))
+	require.NoError(t, hDB.WriteBatchEnd(2))
+
+	require.NoError(t, smtDb.CreateEriDbBuckets(tx))
+	smtState := smt.NewSMT(smtDb.NewEriDb(tx), false)
+	smtState.SetKeepReplacedNodes(pruneMode == zkSmt.PruneModePruned)
+	// every account in the state is written, an unwind recomputes the root from all the accounts a block changed
+	var addrs []common.Address
+	require.NoError(t, tx.ForEach(kv.PlainState, nil, func(k, v []byte) error {
+		if len(k) == length.Addr {
+			addrs = append(addrs, common.BytesToAddress(k))
+		}
+		return nil
+	}))
+	setAccounts := func(reader state.StateReader, codeChanges map[common.Address]string, storageChanges map[common.Address]map[string]string) {
+		accChanges := make(map[common.Address]*accounts.Account)
+		for _, addr := range addrs {
+			acc, err := reader.ReadAccountData(addr)
+			require.NoError(t, err)
+			if acc != nil {
+				accChanges[addr] = acc
+			}
+		}
+		_, _, err := smtState.SetStorage(ctx, "test", accChanges, codeChanges, storageChanges)
+		require.NoError(t, err)
+	}
+	setAccounts(state.NewPlainState(tx, 2, nil),
+		map[common.Address]string{storageContractAddress: "0x600160005500"},
+		map[common.Address]map[string]string{storageContractAddress: {"0x1": "0x2a"}})
+	commitRoot(t, tx, 1, smtState.LastRoot())
+
+	setAccounts(state.NewPlainStateReader(tx), nil, nil)
+	header := commitRoot(t, tx, 2, smtState.LastRoot())
+	require.NoError(t, stages.SaveStageProgress(tx, stages.IntermediateHashes, 2))
+	require.NoError(t, tx.Commit())
+
+	ethCfg := ethconfig.Defaults
+	ethCfg.Zk = &ethconfig.Zk{WitnessUnwindLimit: unwindLimit, SmtPruneMode: pruneMode, SmtPruneRetainBatches: 2}
+
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, contractBackend.BlockReader(), contractBackend.Agg(), false, rpccfg.DefaultEvmCallTimeout, contractBackend.Engine(), datadir.New(t.TempDir()))
+	ethImpl := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000, 100_000, &ethCfg, false, 100, 100, log.New(), defaultL1GasPriceTracker, 1000, false)
+	return NewZkEvmAPI(ethImpl, db, 100_000, &ethCfg, nil, "", nil), header.Root
+}
+
+func commitRoot(t *testing.T, tx kv.RwTx, blockNo uint64, root *big.Int) *types.Header {
+	t.Helper()
+	header := rawdb.ReadHeaderByNumber(tx, blockNo)
+	require.NotNil(t, header)
+	header.Root = common.BigToHash(root)
+	require.NoError(t, rawdb.WriteHeader(tx, header))
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, header.Hash(), blockNo))
+	return header
+}
+
+func TestGetBatchStateProof_VerifiesAgainstBatchStateRoot(t *testing.T) {
+	api, stateRoot := newBatchStateProofTestApi(t, 0, zkSmt.PruneModeFull)
+
+	slot := common.HexToHash("0x1")
+	res, err := api.GetBatchStateProof(ctx, rpc.BlockNumber(2), []zkevmBatchStateProofAccount{
+		{Address: address},
+		{Address: storageContractAddress, StorageKeys: []common.Hash{slot}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), res.BatchNumber)
+	require.Equal(t, hexutil.Uint64(2), res.BlockNumber)
+	require.Equal(t, stateRoot, res.StateRoot)
+	require.Nil(t, res.L1Verification)
+	require.Len(t, res.Accounts, 2)
+
+	root := smtUtils.ScalarToRoot(new(big.Int).SetBytes(res.StateRoot.Bytes()))
+	verify := func(proof []hexutility.Bytes, key smtUtils.NodeKey) *big.Int {
+		value, err := smt.VerifyAndGetVal(root, proof, key)
+		require.NoError(t, err)
+		return new(big.Int).SetBytes(value)
+	}
+
+	eoa := res.Accounts[0]
+	require.Equal(t, address, eoa.Address)
+	require.Equal(t, uint64(1), verify(eoa.NonceProof, smtUtils.KeyEthAddrNonce(address.String())).Uint64())
+	require.Equal(t, eoa.Balance.ToInt(), verify(eoa.BalanceProof, smtUtils.KeyEthAddrBalance(address.String())))
+
+	contract := res.Accounts[1]
+	require.Equal(t, uint64(6), verify(contract.CodeLengthProof, smtUtils.KeyContractLength(storageContractAddress.String())).Uint64())
+	require.Len(t, contract.StorageProof, 1)
+	storageKey, err := smtUtils.KeyContractStorage(storageContractAddress.String(), slot.String())
+	require.NoError(t, err)
+	require.Equal(t, int64(0x2a), verify(contract.StorageProof[0].Proof, storageKey).Int64())
+
+	// a proof against any other root must not verify
+	_, err = smt.VerifyAndGetVal(smtUtils.ScalarToRoot(big.NewInt(1)), eoa.BalanceProof, smtUtils.KeyEthAddrBalance(address.String()))
+	require.Error(t, err)
+}
+
+func TestGetBatchStateProof_HistoricalBatch(t *testing.T) {
+	api, headRoot := newBatchStateProofTestApi(t, 0, zkSmt.PruneModePruned)
+
+	// batch 1 is behind the head and the unwind limit, the pruned SMT retains its root so it is proven without unwinding
+	res, err := api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), res.BlockNumber)
+	require.NotEqual(t, headRoot, res.StateRoot)
+
+	root := smtUtils.ScalarToRoot(new(big.Int).SetBytes(res.StateRoot.Bytes()))
+	nonce, err := smt.VerifyAndGetVal(root, res.Accounts[0].NonceProof, smtUtils.KeyEthAddrNonce(address.String()))
+	require.NoError(t, err)
+	require.Zero(t, new(big.Int).SetBytes(nonce).Uint64(), "the transfer from address is in block 2")
+}
+
+func TestGetBatchStateProof_WitnessUnwindLimit(t *testing.T) {
+	api, _ := newBatchStateProofTestApi(t, 0, zkSmt.PruneModeFull)
+
+	// writing block 2 deleted the nodes of the root of batch 1 it replaced, so batch 1 can only be proven by unwinding,
+	// which is further back than the limit
+	_, err := api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.ErrorContains(t, err, "no longer keeps the state root of batch 1")
+
+	_, err = api.GetBatchStateProof(ctx, rpc.BlockNumber(3), []zkevmBatchStateProofAccount{{Address: address}})
+	require.Error(t, err)
+}
+
+func TestGetBatchStateProof_PrunedSmt(t *testing.T) {
+	api, _ := newBatchStateProofTestApi(t, 0, zkSmt.PruneModePruned)
+
+	// with only the last batch retained the root of batch 1 may be partly swept, even though its root node is still
+	// there, so it is only proven by unwinding
+	api.config.Zk.SmtPruneRetainBatches = 1
+	_, err := api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.ErrorContains(t, err, "no longer keeps the state root of batch 1")
+
+	api.config.Zk.SmtPruneRetainBatches = 2
+	_, err = api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.NoError(t, err)
+}
+
+func TestGetBatchStateProof_MissingNode(t *testing.T) {
+	api, _ := newBatchStateProofTestApi(t, 0, zkSmt.PruneModePruned)
+
+	// a node missing below a root that is still there fails the proof instead of leaving part of it out
+	tx, err := api.db.(kv.RwDB).BeginRw(ctx)
+	require.NoError(t, err)
+	header := rawdb.ReadHeaderByNumber(tx, 1)
+	eriDb := smtDb.NewEriDb(tx)
+	rootNode, err := eriDb.Get(smtUtils.ScalarToRoot(header.Root.Big()))
+	require.NoError(t, err)
+	child := smtUtils.NodeKeyFromUint64Array(rootNode[0:4])
+	if child.IsZero() {
+		child = smtUtils.NodeKeyFromUint64Array(rootNode[4:8])
+	}
+	require.NoError(t, eriDb.DeleteByNodeKey(child))
+	require.NoError(t, tx.Commit())
+
+	_, err = api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.ErrorIs(t, err, smt.ErrNodeNotFound)
+}
+
+func TestGetBatchStateProof_UnwindsToBatch(t *testing.T) {
+	api, headRoot := newBatchStateProofTestApi(t, 1, zkSmt.PruneModeFull)
+
+	// the root of batch 1 is no longer whole but its last block is within the limit, the SMT is unwound to it
+	res, err := api.GetBatchStateProof(ctx, rpc.BlockNumber(1), []zkevmBatchStateProofAccount{{Address: address}})
+	require.NoError(t, err)
+	require.NotEqual(t, headRoot, res.StateRoot)
+
+	root := smtUtils.ScalarToRoot(new(big.Int).SetBytes(res.StateRoot.Bytes()))
+	nonce, err := smt.VerifyAndGetVal(root, res.Accounts[0].NonceProof, smtUtils.KeyEthAddrNonce(address.String()))
+	require.NoError(t, err)
+	require.Zero(t, new(big.Int).SetBytes(nonce).Uint64(), "the transfer from address is in block 2")
+}
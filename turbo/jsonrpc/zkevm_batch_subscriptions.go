@@ -0,0 +1,114 @@
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/rpc"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+// ZkEvmBatchEventsAPI is the interface for the batch lifecycle subscriptions.  They are served on the eth namespace
+// so eth_subscribe takes zkevm_batchClosed, zkevm_batchVirtualized and zkevm_batchVerified as the topic.
+type ZkEvmBatchEventsAPI interface {
+	Zkevm_batchClosed(ctx context.Context) (*rpc.Subscription, error)      //nolint:revive,stylecheck
+	Zkevm_batchVirtualized(ctx context.Context) (*rpc.Subscription, error) //nolint:revive,stylecheck
+	Zkevm_batchVerified(ctx context.Context) (*rpc.Subscription, error)    //nolint:revive,stylecheck
+}
+
+// ZkEvmBatchEventsAPIImpl is kept apart from ZkEvmAPIImpl so registering it on the eth namespace doesn't expose the
+// rest of the zkevm methods there.
+type ZkEvmBatchEventsAPIImpl struct {
+	zkevm *ZkEvmAPIImpl
+}
+
+func NewZkEvmBatchEventsAPI(zkevm *ZkEvmAPIImpl) *ZkEvmBatchEventsAPIImpl {
+	return &ZkEvmBatchEventsAPIImpl{zkevm: zkevm}
+}
+
+// BatchEventNotification is sent to the batch lifecycle subscribers, the fields the node doesn't know yet are null
+type BatchEventNotification struct {
+	BatchNumber   hexutil.Uint64  `json:"batchNumber"`
+	FromBlock     *hexutil.Uint64 `json:"fromBlock"`
+	ToBlock       *hexutil.Uint64 `json:"toBlock"`
+	L1TxHash      *common.Hash    `json:"l1TxHash"`
+	L1BlockNumber *hexutil.Uint64 `json:"l1BlockNumber"`
+	StateRoot     common.Hash     `json:"stateRoot"`
+	AccInputHash  *common.Hash    `json:"accInputHash"`
+}
+
+func newBatchEventNotification(event *zktypes.BatchEvent) *BatchEventNotification {
+	notification := &BatchEventNotification{
+		BatchNumber: hexutil.Uint64(event.BatchNumber),
+		StateRoot:   event.StateRoot,
+	}
+	if event.ToBlock != 0 {
+		from, to := hexutil.Uint64(event.FromBlock), hexutil.Uint64(event.ToBlock)
+		notification.FromBlock, notification.ToBlock = &from, &to
+	}
+	if event.L1TxHash != (common.Hash{}) {
+		l1TxHash, l1BlockNumber := event.L1TxHash, hexutil.Uint64(event.L1BlockNumber)
+		notification.L1TxHash, notification.L1BlockNumber = &l1TxHash, &l1BlockNumber
+	}
+	if event.AccInputHash != (common.Hash{}) {
+		accInputHash := event.AccInputHash
+		notification.AccInputHash = &accInputHash
+	}
+	return notification
+}
+
+// Zkevm_batchClosed sends a notification each time a batch is closed on the L2
+func (api *ZkEvmBatchEventsAPIImpl) Zkevm_batchClosed(ctx context.Context) (*rpc.Subscription, error) { //nolint:revive,stylecheck
+	return api.subscribe(ctx, zktypes.BatchClosed)
+}
+
+// Zkevm_batchVirtualized sends a notification each time a batch is sequenced on the L1
+func (api *ZkEvmBatchEventsAPIImpl) Zkevm_batchVirtualized(ctx context.Context) (*rpc.Subscription, error) { //nolint:revive,stylecheck
+	return api.subscribe(ctx, zktypes.BatchVirtualized)
+}
+
+// Zkevm_batchVerified sends a notification each time a batch is verified on the L1
+func (api *ZkEvmBatchEventsAPIImpl) Zkevm_batchVerified(ctx context.Context) (*rpc.Subscription, error) { //nolint:revive,stylecheck
+	return api.subscribe(ctx, zktypes.BatchVerified)
+}
+
+func (api *ZkEvmBatchEventsAPIImpl) subscribe(ctx context.Context, eventType zktypes.BatchEventType) (*rpc.Subscription, error) {
+	filters := api.zkevm.ethApi.filters
+	if filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		batchEvents, id := filters.SubscribeBatchEvents(32)
+		defer filters.UnsubscribeBatchEvents(id)
+		for {
+			select {
+			case event, ok := <-batchEvents:
+				if event != nil && event.Type == eventType {
+					if err := notifier.Notify(rpcSub.ID, newBatchEventNotification(event)); err != nil {
+						log.Warn("[rpc] error while notifying subscription", "err", err)
+					}
+				}
+				if !ok {
+					log.Warn("[rpc] batch events channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
@@ -0,0 +1,46 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func TestNewBatchEventNotification(t *testing.T) {
+	closed := newBatchEventNotification(&zktypes.BatchEvent{
+		Type:        zktypes.BatchClosed,
+		BatchNumber: 7,
+		FromBlock:   20,
+		ToBlock:     24,
+		StateRoot:   common.HexToHash("0x1"),
+	})
+	encoded, err := json.Marshal(closed)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"batchNumber": "0x7",
+		"fromBlock": "0x14",
+		"toBlock": "0x18",
+		"l1TxHash": null,
+		"l1BlockNumber": null,
+		"stateRoot": "0x0000000000000000000000000000000000000000000000000000000000000001",
+		"accInputHash": null
+	}`, string(encoded))
+
+	// the L1 syncer can see a sequence before the batches stage has synced its blocks
+	virtualized := newBatchEventNotification(&zktypes.BatchEvent{
+		Type:          zktypes.BatchVirtualized,
+		BatchNumber:   7,
+		L1TxHash:      common.HexToHash("0x2"),
+		L1BlockNumber: 100,
+		AccInputHash:  common.HexToHash("0x3"),
+	})
+	require.Nil(t, virtualized.FromBlock)
+	require.Nil(t, virtualized.ToBlock)
+	require.Equal(t, common.HexToHash("0x2"), *virtualized.L1TxHash)
+	require.Equal(t, uint64(100), uint64(*virtualized.L1BlockNumber))
+	require.Equal(t, common.HexToHash("0x3"), *virtualized.AccInputHash)
+}
@@ -138,16 +138,7 @@ func (api *ZkEvmAPIImpl) GetL2BlockInfoTree(ctx context.Context, blockNum rpc.Bl
 			}
 		}
 
-		l2TxHash, err := zktx.ComputeL2TxHash(
-			tx.GetChainID().ToBig(),
-			tx.GetValue(),
-			tx.GetPrice(),
-			tx.GetNonce(),
-			tx.GetGas(),
-			tx.GetTo(),
-			&txSender,
-			tx.GetData(),
-		)
+		l2TxHash, err := zktx.ComputeL2TxHashForTx(tx, &txSender)
 		if err != nil {
 			return nil, err
 		}
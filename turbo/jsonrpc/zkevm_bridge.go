@@ -0,0 +1,304 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+
+	"github.com/ledgerwatch/erigon/zk/bridge"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+var errBridgeIndexerDisabled = errors.New("the bridge indexer is disabled, enable it with zkevm.bridge-indexer")
+
+type BridgeDepositInfo struct {
+	LeafType           hexutil.Uint64   `json:"leafType"`
+	OriginNetwork      hexutil.Uint64   `json:"originNetwork"`
+	OriginAddress      common.Address   `json:"originAddress"`
+	DestinationNetwork hexutil.Uint64   `json:"destinationNetwork"`
+	DestinationAddress common.Address   `json:"destinationAddress"`
+	Amount             *hexutil.Big     `json:"amount"`
+	Metadata           hexutility.Bytes `json:"metadata"`
+	DepositCount       hexutil.Uint64   `json:"depositCount"`
+	LeafHash           common.Hash      `json:"leafHash"`
+	BlockNumber        hexutil.Uint64   `json:"blockNumber"`
+	TxHash             common.Hash      `json:"txHash"`
+}
+
+type DepositProof struct {
+	Deposit     *BridgeDepositInfo `json:"deposit"`
+	MerkleProof []common.Hash      `json:"merkleProof"`
+	// the local exit tree the proof is against, as it was after the last block of the verified batch
+	LocalExitRoot common.Hash    `json:"localExitRoot"`
+	LeafCount     hexutil.Uint64 `json:"leafCount"`
+	VerifiedBatch hexutil.Uint64 `json:"verifiedBatch"`
+	// the proof of the local exit root in the rollup exit tree of the first l1 info tree update to include it, along
+	// with the roots of that update
+	RollupMerkleProof []common.Hash  `json:"rollupMerkleProof"`
+	RollupExitRoot    common.Hash    `json:"rollupExitRoot"`
+	MainnetExitRoot   common.Hash    `json:"mainnetExitRoot"`
+	GlobalExitRoot    common.Hash    `json:"globalExitRoot"`
+	L1InfoTreeIndex   hexutil.Uint64 `json:"l1InfoTreeIndex"`
+}
+
+type ClaimStatus struct {
+	Claimed            bool            `json:"claimed"`
+	OriginNetwork      *hexutil.Uint64 `json:"originNetwork,omitempty"`
+	OriginAddress      *common.Address `json:"originAddress,omitempty"`
+	DestinationAddress *common.Address `json:"destinationAddress,omitempty"`
+	Amount             *hexutil.Big    `json:"amount,omitempty"`
+	BlockNumber        *hexutil.Uint64 `json:"blockNumber,omitempty"`
+	TxHash             *common.Hash    `json:"txHash,omitempty"`
+}
+
+func toBridgeDepositInfo(d *zktypes.BridgeDeposit) *BridgeDepositInfo {
+	return &BridgeDepositInfo{
+		LeafType:           hexutil.Uint64(d.LeafType),
+		OriginNetwork:      hexutil.Uint64(d.OriginNetwork),
+		OriginAddress:      d.OriginAddress,
+		DestinationNetwork: hexutil.Uint64(d.DestinationNetwork),
+		DestinationAddress: d.DestinationAddress,
+		Amount:             (*hexutil.Big)(d.Amount),
+		Metadata:           d.Metadata,
+		DepositCount:       hexutil.Uint64(d.DepositCount),
+		LeafHash:           bridge.LeafHash(d),
+		BlockNumber:        hexutil.Uint64(d.BlockNumber),
+		TxHash:             d.TxHash,
+	}
+}
+
+// bridgeExitLeaves reads the leaves of the local exit tree, in deposit count order, for its cache.  The cached tree
+// stops at the first missing deposit.
+type bridgeExitLeaves struct {
+	hermezDb *hermez_db.HermezDbReader
+}
+
+func (r bridgeExitLeaves) Leaf(index uint32) (common.Hash, bool, error) {
+	return r.hermezDb.GetBridgeExitLeaf(index)
+}
+
+func (r bridgeExitLeaves) LeavesFrom(index uint32) ([]common.Hash, error) {
+	return r.hermezDb.GetBridgeExitLeavesFrom(index)
+}
+
+// getDepositProof returns the proof of a deposit against the local exit tree as it was after the last block of the
+// verified batch, nil if the deposit has not been indexed
+func getDepositProof(hermezDb *hermez_db.HermezDbReader, cache *l1infotree.HistoryTreeCache, depositCount uint32, batchNo uint64) (*DepositProof, error) {
+	deposit, err := hermezDb.GetBridgeDeposit(depositCount)
+	if err != nil || deposit == nil {
+		return nil, err
+	}
+
+	lastBlock, found, err := hermezDb.GetHighestBlockInBatch(batchNo)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("batch %d has no blocks", batchNo)
+	}
+	leafCount, err := hermezDb.GetBridgeDepositCountAtBlock(lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	if depositCount >= leafCount {
+		return nil, fmt.Errorf("deposit %d was made after batch %d, whose local exit tree holds %d deposits", depositCount, batchNo, leafCount)
+	}
+	from, to, found, err := hermezDb.GetFirstBridgeDepositGap()
+	if err != nil {
+		return nil, err
+	}
+	if found && from < leafCount {
+		return nil, fmt.Errorf("the local exit tree of batch %d is missing deposits %d to %d, their receipts were pruned before the bridge indexer reached them", batchNo, from, to-1)
+	}
+
+	var (
+		siblings [][32]byte
+		root     common.Hash
+	)
+	if err = cache.With(bridgeExitLeaves{hermezDb}, func(tree *l1infotree.HistoryTree) error {
+		siblings, root, err = tree.Proof(depositCount, leafCount)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &DepositProof{
+		Deposit:       toBridgeDepositInfo(deposit),
+		MerkleProof:   toHashes(siblings),
+		LocalExitRoot: root,
+		LeafCount:     hexutil.Uint64(leafCount),
+		VerifiedBatch: hexutil.Uint64(batchNo),
+	}, nil
+}
+
+// addRollupExitProof proves the local exit root of the deposit proof in the rollup exit tree of the first l1 info tree
+// update after the batch was verified, rollupExitLeaves reads the leaves of the rollup exit tree at an l1 block
+func addRollupExitProof(
+	hermezDb *hermez_db.HermezDbReader,
+	proof *DepositProof,
+	rollupId uint64,
+	verifiedL1BlockNo uint64,
+	rollupExitLeaves func(l1BlockNo uint64) ([]common.Hash, error),
+) error {
+	// the rollup manager updates the exit roots when it verifies a batch, the l1 block's last update holds its state
+	update, err := hermezDb.GetLastL1InfoTreeUpdateFromL1Block(verifiedL1BlockNo)
+	if err != nil {
+		return err
+	}
+	if update == nil {
+		return fmt.Errorf("the l1 info tree has not been synced up to l1 block %d where batch %d was verified", verifiedL1BlockNo, proof.VerifiedBatch)
+	}
+
+	leaves, err := rollupExitLeaves(update.BlockNumber)
+	if err != nil {
+		return err
+	}
+	if rollupId == 0 || rollupId > uint64(len(leaves)) {
+		return fmt.Errorf("rollup %d is not one of the %d rollups at l1 block %d", rollupId, len(leaves), update.BlockNumber)
+	}
+	if leaves[rollupId-1] != proof.LocalExitRoot {
+		return fmt.Errorf("rollup %d has local exit root %s at l1 block %d, not the %s of batch %d", rollupId, leaves[rollupId-1], update.BlockNumber, proof.LocalExitRoot, proof.VerifiedBatch)
+	}
+
+	tree := l1infotree.NewHistoryTree(bridge.ExitTreeHeight)
+	for _, leaf := range leaves {
+		if err = tree.AddLeaf(leaf); err != nil {
+			return err
+		}
+	}
+	siblings, root, err := tree.Proof(uint32(rollupId-1), tree.LeafCount())
+	if err != nil {
+		return err
+	}
+	if root != update.RollupExitRoot {
+		return fmt.Errorf("the rollup exit tree at l1 block %d has root %s, not the %s of l1 info tree index %d", update.BlockNumber, root, update.RollupExitRoot, update.Index)
+	}
+
+	proof.RollupMerkleProof = toHashes(siblings)
+	proof.RollupExitRoot = update.RollupExitRoot
+	proof.MainnetExitRoot = update.MainnetExitRoot
+	proof.GlobalExitRoot = update.GER
+	proof.L1InfoTreeIndex = hexutil.Uint64(update.Index)
+	return nil
+}
+
+func toHashes(siblings [][32]byte) []common.Hash {
+	hashes := make([]common.Hash, len(siblings))
+	for i, sibling := range siblings {
+		hashes[i] = sibling
+	}
+	return hashes
+}
+
+func getClaimStatus(hermezDb *hermez_db.HermezDbReader, globalIndex *big.Int) (*ClaimStatus, error) {
+	claim, err := hermezDb.GetBridgeClaim(globalIndex)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		return &ClaimStatus{}, nil
+	}
+
+	originNetwork := hexutil.Uint64(claim.OriginNetwork)
+	blockNumber := hexutil.Uint64(claim.BlockNumber)
+	return &ClaimStatus{
+		Claimed:            true,
+		OriginNetwork:      &originNetwork,
+		OriginAddress:      &claim.OriginAddress,
+		DestinationAddress: &claim.DestinationAddress,
+		Amount:             (*hexutil.Big)(claim.Amount),
+		BlockNumber:        &blockNumber,
+		TxHash:             &claim.TxHash,
+	}, nil
+}
+
+// GetBridgeDeposits returns the deposits of the L2 bridge to the destination address
+func (api *ZkEvmAPIImpl) GetBridgeDeposits(ctx context.Context, address common.Address) ([]*BridgeDepositInfo, error) {
+	if !api.config.Zk.BridgeIndexerEnabled {
+		return nil, errBridgeIndexerDisabled
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deposits, err := hermez_db.NewHermezDbReader(tx).GetBridgeDepositsByAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*BridgeDepositInfo, len(deposits))
+	for i, deposit := range deposits {
+		result[i] = toBridgeDepositInfo(deposit)
+	}
+	return result, nil
+}
+
+// GetDepositProof returns the merkle proofs a deposit of the L2 bridge is claimed with, against the local exit tree
+// of the verified batch, the latest one if no batch is given, and of that local exit root against the rollup exit tree
+// on the L1. It returns nil if the deposit has not been indexed.
+func (api *ZkEvmAPIImpl) GetDepositProof(ctx context.Context, depositCount hexutil.Uint64, verifiedBatch *hexutil.Uint64) (*DepositProof, error) {
+	if !api.config.Zk.BridgeIndexerEnabled {
+		return nil, errBridgeIndexerDisabled
+	}
+	if api.l1Syncer == nil {
+		return nil, errors.New("the rollup exit tree can't be read without an l1 syncer")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+
+	var verification *zktypes.L1BatchInfo
+	if verifiedBatch != nil {
+		if verification, err = hermezDb.GetVerificationByBatchNo(uint64(*verifiedBatch)); err == nil && verification == nil {
+			err = fmt.Errorf("batch %d is not the last batch of an l1 verification", *verifiedBatch)
+		}
+	} else {
+		if verification, err = hermezDb.GetLatestVerification(); err == nil && verification == nil {
+			err = errors.New("no batch has been verified yet")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := getDepositProof(hermezDb, api.bridgeExitTree, uint32(depositCount), verification.BatchNo)
+	if err != nil || proof == nil {
+		return nil, err
+	}
+	if err = addRollupExitProof(hermezDb, proof, api.config.L1RollupId, verification.L1BlockNo, func(l1BlockNo uint64) ([]common.Hash, error) {
+		return api.l1Syncer.CallRollupExitLeaves(ctx, &api.config.AddressRollup, l1BlockNo)
+	}); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// GetClaimStatus reports whether the deposit with the global index has been claimed on the L2
+func (api *ZkEvmAPIImpl) GetClaimStatus(ctx context.Context, globalIndex *hexutil.Big) (*ClaimStatus, error) {
+	if !api.config.Zk.BridgeIndexerEnabled {
+		return nil, errBridgeIndexerDisabled
+	}
+	if globalIndex == nil {
+		return nil, errors.New("global index is required")
+	}
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getClaimStatus(hermez_db.NewHermezDbReader(tx), globalIndex.ToInt())
+}
@@ -0,0 +1,130 @@
+package jsonrpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/zk/bridge"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func TestGetDepositProof(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	// deposits 0 to 2 are in blocks 10 to 12, batch 1 ends with block 11 and batch 2 with block 12
+	var leaves []common.Hash
+	for i := uint32(0); i < 3; i++ {
+		deposit := &zktypes.BridgeDeposit{
+			DestinationAddress: common.HexToAddress("0xd"),
+			Amount:             big.NewInt(int64(i + 1)),
+			Metadata:           []byte{},
+			DepositCount:       i,
+			BlockNumber:        uint64(10 + i),
+		}
+		leaves = append(leaves, bridge.LeafHash(deposit))
+		require.NoError(t, hermezDb.WriteBridgeDeposit(deposit, leaves[i]))
+	}
+	require.NoError(t, hermezDb.WriteBlockBatch(10, 1))
+	require.NoError(t, hermezDb.WriteBlockBatch(11, 1))
+	require.NoError(t, hermezDb.WriteBlockBatch(12, 2))
+
+	cache := l1infotree.NewHistoryTreeCache(bridge.ExitTreeHeight)
+	proof, err := getDepositProof(hermezDb.HermezDbReader, cache, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), proof.Deposit.DepositCount)
+	require.Equal(t, hexutil.Uint64(2), proof.LeafCount)
+	require.Len(t, proof.MerkleProof, bridge.ExitTreeHeight)
+
+	siblings, root, err := bridge.ComputeDepositProof(leaves[:2], 1)
+	require.NoError(t, err)
+	require.Equal(t, root, proof.LocalExitRoot)
+	require.Equal(t, toHashes(siblings), proof.MerkleProof)
+	require.True(t, bridge.VerifyDepositProof(proof.Deposit.LeafHash, siblings, 1, proof.LocalExitRoot))
+
+	// the same deposit against the later batch
+	proof, err = getDepositProof(hermezDb.HermezDbReader, cache, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(3), proof.LeafCount)
+	_, root, err = bridge.ComputeDepositProof(leaves, 1)
+	require.NoError(t, err)
+	require.Equal(t, root, proof.LocalExitRoot)
+
+	_, err = getDepositProof(hermezDb.HermezDbReader, cache, 2, 1)
+	require.ErrorContains(t, err, "deposit 2 was made after batch 1")
+
+	proof, err = getDepositProof(hermezDb.HermezDbReader, cache, 3, 2)
+	require.NoError(t, err)
+	require.Nil(t, proof)
+
+	// the local exit root is the second leaf of the rollup exit tree synced in l1 block 105
+	proof, err = getDepositProof(hermezDb.HermezDbReader, cache, 1, 2)
+	require.NoError(t, err)
+	rollupLeaves := []common.Hash{common.HexToHash("0x1"), proof.LocalExitRoot, common.HexToHash("0x3")}
+	_, rollupExitRoot, err := bridge.ComputeDepositProof(rollupLeaves, 1)
+	require.NoError(t, err)
+	require.NoError(t, hermezDb.WriteL1InfoTreeUpdate(&zktypes.L1InfoTreeUpdate{Index: 0, BlockNumber: 100}))
+	require.NoError(t, hermezDb.WriteL1InfoTreeUpdate(&zktypes.L1InfoTreeUpdate{
+		Index:           1,
+		BlockNumber:     105,
+		GER:             common.HexToHash("0x9"),
+		MainnetExitRoot: common.HexToHash("0x8"),
+		RollupExitRoot:  rollupExitRoot,
+	}))
+	rollupExitLeaves := func(l1BlockNo uint64) ([]common.Hash, error) {
+		require.Equal(t, uint64(105), l1BlockNo)
+		return rollupLeaves, nil
+	}
+
+	require.NoError(t, addRollupExitProof(hermezDb.HermezDbReader, proof, 2, 103, rollupExitLeaves))
+	require.Equal(t, rollupExitRoot, proof.RollupExitRoot)
+	require.Equal(t, common.HexToHash("0x9"), proof.GlobalExitRoot)
+	require.Equal(t, hexutil.Uint64(1), proof.L1InfoTreeIndex)
+	rollupSiblings := make([][32]byte, len(proof.RollupMerkleProof))
+	for i, sibling := range proof.RollupMerkleProof {
+		rollupSiblings[i] = sibling
+	}
+	require.True(t, bridge.VerifyDepositProof(proof.LocalExitRoot, rollupSiblings, 1, rollupExitRoot))
+
+	require.ErrorContains(t, addRollupExitProof(hermezDb.HermezDbReader, proof, 1, 103, rollupExitLeaves), "rollup 1 has local exit root")
+	require.ErrorContains(t, addRollupExitProof(hermezDb.HermezDbReader, proof, 2, 106, rollupExitLeaves), "has not been synced up to l1 block 106")
+
+	// no proof is served from a local exit tree with missing deposits
+	require.NoError(t, hermezDb.WriteBridgeDepositGap(2, 3))
+	_, err = getDepositProof(hermezDb.HermezDbReader, cache, 1, 2)
+	require.ErrorContains(t, err, "missing deposits 2 to 2")
+	_, err = getDepositProof(hermezDb.HermezDbReader, cache, 1, 1)
+	require.NoError(t, err)
+}
+
+func TestGetClaimStatus(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	globalIndex := new(big.Int).Lsh(big.NewInt(1), 64)
+	require.NoError(t, hermezDb.WriteBridgeClaim(&zktypes.BridgeClaim{
+		GlobalIndex: globalIndex,
+		Amount:      big.NewInt(5),
+		BlockNumber: 12,
+		TxHash:      common.HexToHash("0xabc"),
+	}))
+
+	status, err := getClaimStatus(hermezDb.HermezDbReader, globalIndex)
+	require.NoError(t, err)
+	require.True(t, status.Claimed)
+	require.Equal(t, hexutil.Uint64(12), *status.BlockNumber)
+	require.Equal(t, common.HexToHash("0xabc"), *status.TxHash)
+
+	status, err = getClaimStatus(hermezDb.HermezDbReader, big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, &ClaimStatus{}, status)
+}
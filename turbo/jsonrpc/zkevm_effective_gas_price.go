@@ -0,0 +1,143 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+)
+
+// EffectiveGasPriceInfo explains how the fee of a transaction was derived.  The sequencer charges the percentage
+// (EffectiveGasPricePercentage+1)/256 of the signed gas price, picked by the category of the transaction.
+type EffectiveGasPriceInfo struct {
+	GasPrice                    *hexutil.Big    `json:"gasPrice"`
+	EffectiveGasPricePercentage hexutil.Uint64  `json:"effectiveGasPricePercentage"`
+	EffectiveGasPrice           *hexutil.Big    `json:"effectiveGasPrice"`
+	Category                    zktx.TxCategory `json:"category"`
+	// L1GasPrice is the l1 gas price when the sequencer started the block, null if the sequencer can't be asked for it
+	L1GasPrice *hexutil.Big `json:"l1GasPrice"`
+}
+
+func getEffectiveGasPriceInfo(hermezDb *hermez_db.HermezDbReader, txn types.Transaction, l1GasPrice *big.Int) (*EffectiveGasPriceInfo, error) {
+	percentage, err := hermezDb.GetEffectiveGasPricePercentage(txn.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	return &EffectiveGasPriceInfo{
+		GasPrice:                    (*hexutil.Big)(txn.GetPrice().ToBig()),
+		EffectiveGasPricePercentage: hexutil.Uint64(percentage),
+		EffectiveGasPrice:           (*hexutil.Big)(core.CalculateEffectiveGas(txn.GetPrice().Clone(), percentage).ToBig()),
+		Category:                    zktx.CategorizeTx(txn),
+		L1GasPrice:                  (*hexutil.Big)(l1GasPrice),
+	}, nil
+}
+
+// l1GasPriceFetchTimeout bounds the request to the sequencer for the l1 gas price of a block, the rest of the details
+// are returned without it rather than holding up the caller
+const l1GasPriceFetchTimeout = 2 * time.Second
+
+// fillL1GasPrice completes details read from the db with the l1 gas price of the block.  Only the sequencer records it,
+// rpc nodes ask the sequencer for it and keep the answer, an unknown price included, as it never changes for a block.
+// It must be called once the db tx is closed as the sequencer may be slow to answer.
+func (api *BaseAPI) fillL1GasPrice(ctx context.Context, txHash, blockHash common.Hash, details ...*EffectiveGasPriceInfo) {
+	if len(details) == 0 || details[0].L1GasPrice != nil || sequencer.IsSequencer() {
+		return
+	}
+
+	l1GasPrice, ok := api.blockL1GasPrices.Get(blockHash)
+	if !ok {
+		rpcUrl := api.sequencerRpcUrl()
+		if rpcUrl == "" {
+			return
+		}
+
+		var err error
+		if l1GasPrice, err = forwardGetL1GasPrice(ctx, rpcUrl, txHash); err != nil {
+			// the rest of the details are still worth returning
+			log.Debug("Could not get the L1 gas price of the block from the sequencer", "block", blockHash, "err", err)
+			return
+		}
+		api.blockL1GasPrices.Add(blockHash, l1GasPrice)
+	}
+
+	for _, d := range details {
+		d.L1GasPrice = (*hexutil.Big)(l1GasPrice)
+	}
+}
+
+func forwardGetL1GasPrice(ctx context.Context, rpcUrl string, txHash common.Hash) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, l1GasPriceFetchTimeout)
+	defer cancel()
+	res, err := client.JSONRPCCallWithContext(ctx, rpcUrl, "zkevm_getEffectiveGasPrice", txHash)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, fmt.Errorf("RPC error response is: %s", res.Error.Message)
+	}
+
+	var info *EffectiveGasPriceInfo
+	if err = json.Unmarshal(res.Result, &info); err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, nil
+	}
+
+	return (*big.Int)(info.L1GasPrice), nil
+}
+
+// GetEffectiveGasPrice returns how the fee of a mined transaction was derived, nil if the transaction is not known
+func (api *ZkEvmAPIImpl) GetEffectiveGasPrice(ctx context.Context, txHash common.Hash) (*EffectiveGasPriceInfo, error) {
+	info, blockHash, err := api.getEffectiveGasPrice(ctx, txHash)
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	api.ethApi.fillL1GasPrice(ctx, txHash, blockHash, info)
+	return info, nil
+}
+
+func (api *ZkEvmAPIImpl) getEffectiveGasPrice(ctx context.Context, txHash common.Hash) (*EffectiveGasPriceInfo, common.Hash, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	defer tx.Rollback()
+
+	blockNum, ok, err := api.ethApi.txnLookup(ctx, tx, txHash)
+	if err != nil || !ok {
+		return nil, common.Hash{}, err
+	}
+	block, err := api.ethApi.blockByNumberWithSenders(ctx, tx, blockNum)
+	if err != nil || block == nil {
+		return nil, common.Hash{}, err
+	}
+
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+	for _, txn := range block.Transactions() {
+		if txn.Hash() == txHash {
+			l1GasPrice, err := hermezDb.GetBlockL1GasPrice(blockNum)
+			if err != nil {
+				return nil, common.Hash{}, err
+			}
+			info, err := getEffectiveGasPriceInfo(hermezDb, txn, l1GasPrice)
+			return info, block.Hash(), err
+		}
+	}
+
+	return nil, common.Hash{}, nil
+}
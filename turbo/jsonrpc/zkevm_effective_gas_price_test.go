@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
+)
+
+func TestGetEffectiveGasPriceInfo(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	to := common.HexToAddress("0x1")
+	txn := types.NewTransaction(0, to, uint256.NewInt(1), 21000, uint256.NewInt(1_000_000_000), nil)
+	require.NoError(t, hermezDb.WriteEffectiveGasPricePercentage(txn.Hash(), 127))
+
+	info, err := getEffectiveGasPriceInfo(hermezDb.HermezDbReader, txn, nil)
+	require.NoError(t, err)
+	require.Equal(t, &EffectiveGasPriceInfo{
+		GasPrice:                    (*hexutil.Big)(big.NewInt(1_000_000_000)),
+		EffectiveGasPricePercentage: 127,
+		EffectiveGasPrice:           (*hexutil.Big)(big.NewInt(500_000_000)),
+		Category:                    zktx.TxCategoryEthTransfer,
+	}, info)
+
+	info, err = getEffectiveGasPriceInfo(hermezDb.HermezDbReader, txn, big.NewInt(30_000_000_000))
+	require.NoError(t, err)
+	require.Equal(t, (*hexutil.Big)(big.NewInt(30_000_000_000)), info.L1GasPrice)
+}
+
+func TestFillL1GasPrice(t *testing.T) {
+	var calls atomic.Int32
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.Contains(r.URL.Path, "unknown") {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"l1GasPrice":"0x6fc23ac00"}}`))
+	}))
+	defer sequencer.Close()
+
+	api := NewBaseApi(nil, nil, nil, nil, false, 0, nil, datadir.Dirs{})
+	txHash, blockHash := common.HexToHash("0x1"), common.HexToHash("0x2")
+	ctx := context.Background()
+
+	// no sequencer to ask, blocks synced from the datastream carry no l1 gas price
+	details := &EffectiveGasPriceInfo{}
+	api.fillL1GasPrice(ctx, txHash, blockHash, details)
+	require.Nil(t, details.L1GasPrice)
+
+	// rpc nodes ask the sequencer once per block
+	api.SetL2RpcUrl(sequencer.URL)
+	for i := 0; i < 2; i++ {
+		first, second := &EffectiveGasPriceInfo{}, &EffectiveGasPriceInfo{}
+		api.fillL1GasPrice(ctx, txHash, blockHash, first, second)
+		require.Equal(t, (*hexutil.Big)(big.NewInt(30_000_000_000)), first.L1GasPrice)
+		require.Equal(t, (*hexutil.Big)(big.NewInt(30_000_000_000)), second.L1GasPrice)
+	}
+	require.Equal(t, int32(1), calls.Load())
+
+	// the price recorded by the sequencer that built the block is used as is
+	details = &EffectiveGasPriceInfo{L1GasPrice: (*hexutil.Big)(big.NewInt(20_000_000_000))}
+	api.fillL1GasPrice(ctx, txHash, common.HexToHash("0x3"), details)
+	require.Equal(t, (*hexutil.Big)(big.NewInt(20_000_000_000)), details.L1GasPrice)
+	require.Equal(t, int32(1), calls.Load())
+
+	// a price the sequencer does not know is not asked for again
+	api.SetL2RpcUrl(sequencer.URL + "/unknown")
+	for i := 0; i < 2; i++ {
+		details = &EffectiveGasPriceInfo{}
+		api.fillL1GasPrice(ctx, txHash, common.HexToHash("0x4"), details)
+		require.Nil(t, details.L1GasPrice)
+	}
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestFillL1GasPriceCancelled(t *testing.T) {
+	hung := make(chan struct{})
+	sequencer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer sequencer.Close()
+	defer close(hung)
+
+	api := NewBaseApi(nil, nil, nil, nil, false, 0, nil, datadir.Dirs{})
+	api.SetL2RpcUrl(sequencer.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	details := &EffectiveGasPriceInfo{}
+	api.fillL1GasPrice(ctx, common.HexToHash("0x1"), common.HexToHash("0x2"), details)
+	require.Nil(t, details.L1GasPrice)
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,191 @@
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+)
+
+const l1InfoTreeHeight = 32
+
+type L1InfoTreeLeaf struct {
+	Index             hexutil.Uint64 `json:"index"`
+	Leaf              common.Hash    `json:"leaf"`
+	GlobalExitRoot    common.Hash    `json:"globalExitRoot"`
+	MainnetExitRoot   common.Hash    `json:"mainnetExitRoot"`
+	RollupExitRoot    common.Hash    `json:"rollupExitRoot"`
+	PreviousBlockHash common.Hash    `json:"previousBlockHash"`
+	MinTimestamp      hexutil.Uint64 `json:"minTimestamp"`
+	L1BlockNumber     hexutil.Uint64 `json:"l1BlockNumber"`
+	// the root of the tree once this leaf was added
+	L1InfoRoot common.Hash `json:"l1InfoRoot"`
+}
+
+type L1InfoTreeProof struct {
+	Index       hexutil.Uint64 `json:"index"`
+	Leaf        common.Hash    `json:"leaf"`
+	L1InfoRoot  common.Hash    `json:"l1InfoRoot"`
+	MerkleProof []common.Hash  `json:"merkleProof"`
+}
+
+type L1InfoTreeRootAtBlock struct {
+	L2BlockNumber hexutil.Uint64 `json:"l2BlockNumber"`
+	Index         hexutil.Uint64 `json:"index"`
+	L1InfoRoot    common.Hash    `json:"l1InfoRoot"`
+}
+
+// l1InfoTreeLeaves reads the leaves of the l1 info tree for its cache
+type l1InfoTreeLeaves struct {
+	hermezDb *hermez_db.HermezDbReader
+}
+
+func (r l1InfoTreeLeaves) Leaf(index uint32) (common.Hash, bool, error) {
+	return r.hermezDb.GetL1InfoTreeLeaf(uint64(index))
+}
+
+func (r l1InfoTreeLeaves) LeavesFrom(index uint32) ([]common.Hash, error) {
+	return r.hermezDb.GetL1InfoTreeLeavesFrom(uint64(index))
+}
+
+// getL1InfoTreeLeaf returns nil if there is no leaf at the index
+func getL1InfoTreeLeaf(hermezDb *hermez_db.HermezDbReader, cache *l1infotree.HistoryTreeCache, index uint64) (*L1InfoTreeLeaf, error) {
+	update, err := hermezDb.GetL1InfoTreeUpdate(index)
+	if err != nil || update == nil {
+		return nil, err
+	}
+	leaf, found, err := hermezDb.GetL1InfoTreeLeaf(index)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var root common.Hash
+	if err = cache.With(l1InfoTreeLeaves{hermezDb}, func(tree *l1infotree.HistoryTree) error {
+		root, err = tree.Root(uint32(index + 1))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &L1InfoTreeLeaf{
+		Index:             hexutil.Uint64(index),
+		Leaf:              leaf,
+		GlobalExitRoot:    update.GER,
+		MainnetExitRoot:   update.MainnetExitRoot,
+		RollupExitRoot:    update.RollupExitRoot,
+		PreviousBlockHash: update.ParentHash,
+		MinTimestamp:      hexutil.Uint64(update.Timestamp),
+		L1BlockNumber:     hexutil.Uint64(update.BlockNumber),
+		L1InfoRoot:        root,
+	}, nil
+}
+
+func getL1InfoTreeProof(hermezDb *hermez_db.HermezDbReader, cache *l1infotree.HistoryTreeCache, index uint64, root common.Hash) (*L1InfoTreeProof, error) {
+	rootIndex, found, err := hermezDb.GetL1InfoTreeIndexByRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown l1 info root %s", root)
+	}
+	if index > rootIndex {
+		return nil, fmt.Errorf("leaf %d was added after l1 info root %s, which covers the leaves up to %d", index, root, rootIndex)
+	}
+
+	var (
+		leaf     [32]byte
+		siblings [][32]byte
+		computed common.Hash
+	)
+	if err = cache.With(l1InfoTreeLeaves{hermezDb}, func(tree *l1infotree.HistoryTree) error {
+		if leaf, err = tree.Leaf(uint32(index)); err != nil {
+			return err
+		}
+		siblings, computed, err = tree.Proof(uint32(index), uint32(rootIndex+1))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if computed != root {
+		return nil, fmt.Errorf("the local l1 info tree after leaf %d has root %s, not %s", rootIndex, computed, root)
+	}
+
+	proof := make([]common.Hash, len(siblings))
+	for i, sibling := range siblings {
+		proof[i] = sibling
+	}
+	return &L1InfoTreeProof{
+		Index:       hexutil.Uint64(index),
+		Leaf:        leaf,
+		L1InfoRoot:  root,
+		MerkleProof: proof,
+	}, nil
+}
+
+// getL1InfoTreeRootAtBlock returns nil if no block up to the given one used the l1 info tree
+func getL1InfoTreeRootAtBlock(hermezDb *hermez_db.HermezDbReader, cache *l1infotree.HistoryTreeCache, blockNo uint64) (*L1InfoTreeRootAtBlock, error) {
+	index, found, err := hermezDb.GetL1InfoTreeIndexAtBlock(blockNo)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var root common.Hash
+	if err = cache.With(l1InfoTreeLeaves{hermezDb}, func(tree *l1infotree.HistoryTree) error {
+		root, err = tree.Root(uint32(index + 1))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &L1InfoTreeRootAtBlock{
+		L2BlockNumber: hexutil.Uint64(blockNo),
+		Index:         hexutil.Uint64(index),
+		L1InfoRoot:    root,
+	}, nil
+}
+
+// GetL1InfoTreeLeaf returns the l1 info tree leaf at the index along with the root of the tree once it was added,
+// nil if there is no such leaf
+func (api *ZkEvmAPIImpl) GetL1InfoTreeLeaf(ctx context.Context, index hexutil.Uint64) (*L1InfoTreeLeaf, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getL1InfoTreeLeaf(hermez_db.NewHermezDbReader(tx), api.l1InfoTree, uint64(index))
+}
+
+// GetL1InfoTreeProof returns the merkle proof of the l1 info tree leaf at the index against the given l1 info root
+func (api *ZkEvmAPIImpl) GetL1InfoTreeProof(ctx context.Context, index hexutil.Uint64, root common.Hash) (*L1InfoTreeProof, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getL1InfoTreeProof(hermez_db.NewHermezDbReader(tx), api.l1InfoTree, uint64(index), root)
+}
+
+// GetL1InfoTreeRootAtBlock returns the l1 info root of the highest l1 info tree index used by the L2 blocks up to the
+// given one, nil if none of them used one
+func (api *ZkEvmAPIImpl) GetL1InfoTreeRootAtBlock(ctx context.Context, l2Block rpc.BlockNumber) (*L1InfoTreeRootAtBlock, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNo, _, _, err := rpchelper.GetBlockNumber_zkevm(rpc.BlockNumberOrHashWithNumber(l2Block), tx, api.ethApi.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return getL1InfoTreeRootAtBlock(hermez_db.NewHermezDbReader(tx), api.l1InfoTree, blockNo)
+}
@@ -0,0 +1,137 @@
+package jsonrpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+// writeTestL1InfoTree writes the updates, leaves and roots the l1 info tree updater would and returns the roots
+func writeTestL1InfoTree(t *testing.T, hermezDb *hermez_db.HermezDb, from, to uint64, leaves *[][32]byte) []common.Hash {
+	var roots []common.Hash
+	for i := from; i < to; i++ {
+		update := &zktypes.L1InfoTreeUpdate{
+			Index:       i,
+			GER:         common.BigToHash(big.NewInt(int64(i + 1))),
+			ParentHash:  common.HexToHash("0xabc"),
+			Timestamp:   1000 + i,
+			BlockNumber: 100 + i,
+		}
+		leaf := l1infotree.HashLeafData(update.GER, update.ParentHash, update.Timestamp)
+		*leaves = append(*leaves, leaf)
+
+		mt, err := l1infotree.NewL1InfoTree(l1InfoTreeHeight, append([][32]byte{}, *leaves...))
+		require.NoError(t, err)
+		root, _, _ := mt.GetCurrentRootCountAndSiblings()
+		roots = append(roots, root)
+
+		require.NoError(t, hermezDb.WriteL1InfoTreeUpdate(update))
+		require.NoError(t, hermezDb.WriteL1InfoTreeLeaf(i, leaf))
+		require.NoError(t, hermezDb.WriteL1InfoTreeRoot(root, i))
+	}
+	return roots
+}
+
+func TestL1InfoTreeRpcHelpers(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+	cache := l1infotree.NewHistoryTreeCache(l1InfoTreeHeight)
+
+	var leaves [][32]byte
+	roots := writeTestL1InfoTree(t, hermezDb, 0, 5, &leaves)
+
+	leaf, err := getL1InfoTreeLeaf(hermezDb.HermezDbReader, cache, 2)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(2), leaf.Index)
+	require.Equal(t, common.Hash(leaves[2]), leaf.Leaf)
+	require.Equal(t, common.BigToHash(big.NewInt(3)), leaf.GlobalExitRoot)
+	require.Equal(t, hexutil.Uint64(102), leaf.L1BlockNumber)
+	require.Equal(t, roots[2], leaf.L1InfoRoot)
+
+	leaf, err = getL1InfoTreeLeaf(hermezDb.HermezDbReader, cache, 5)
+	require.NoError(t, err)
+	require.Nil(t, leaf)
+
+	// a proof against an older root still verifies once more leaves have been added
+	roots = append(roots, writeTestL1InfoTree(t, hermezDb, 5, 7, &leaves)...)
+	mt, err := l1infotree.NewL1InfoTree(l1InfoTreeHeight, [][32]byte{})
+	require.NoError(t, err)
+	for _, rootIndex := range []uint64{1, 3, 6} {
+		for index := uint64(0); index <= rootIndex; index++ {
+			proof, err := getL1InfoTreeProof(hermezDb.HermezDbReader, cache, index, roots[rootIndex])
+			require.NoError(t, err)
+			require.Equal(t, common.Hash(leaves[index]), proof.Leaf)
+
+			expected, _, err := mt.ComputeMerkleProof(uint32(index), append([][32]byte{}, leaves[:rootIndex+1]...))
+			require.NoError(t, err)
+			require.Len(t, proof.MerkleProof, len(expected))
+			for i := range expected {
+				require.Equal(t, common.Hash(expected[i]), proof.MerkleProof[i])
+			}
+		}
+	}
+
+	_, err = getL1InfoTreeProof(hermezDb.HermezDbReader, cache, 4, roots[3])
+	require.ErrorContains(t, err, "added after")
+	_, err = getL1InfoTreeProof(hermezDb.HermezDbReader, cache, 0, common.HexToHash("0x1"))
+	require.ErrorContains(t, err, "unknown l1 info root")
+
+	require.NoError(t, hermezDb.WriteBlockL1InfoTreeIndex(10, 3))
+	require.NoError(t, hermezDb.WriteBlockL1InfoTreeIndex(12, 6))
+
+	atBlock, err := getL1InfoTreeRootAtBlock(hermezDb.HermezDbReader, cache, 11)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(3), atBlock.Index)
+	require.Equal(t, roots[3], atBlock.L1InfoRoot)
+
+	atBlock, err = getL1InfoTreeRootAtBlock(hermezDb.HermezDbReader, cache, 12)
+	require.NoError(t, err)
+	require.Equal(t, roots[6], atBlock.L1InfoRoot)
+
+	atBlock, err = getL1InfoTreeRootAtBlock(hermezDb.HermezDbReader, cache, 9)
+	require.NoError(t, err)
+	require.Nil(t, atBlock)
+}
+
+func TestL1InfoTreeCacheFollowsReorgs(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+	cache := l1infotree.NewHistoryTreeCache(l1InfoTreeHeight)
+
+	var leaves [][32]byte
+	writeTestL1InfoTree(t, hermezDb, 0, 4, &leaves)
+	_, err := getL1InfoTreeLeaf(hermezDb.HermezDbReader, cache, 3)
+	require.NoError(t, err)
+
+	// the L1 reorgs from block 102, the updates from there on come back with other data
+	_, _, err = hermezDb.TruncateL1InfoTreeAbove(101)
+	require.NoError(t, err)
+	leaves = leaves[:2]
+	var replaced [][32]byte
+	for i := uint64(2); i < 4; i++ {
+		update := &zktypes.L1InfoTreeUpdate{Index: i, GER: common.BigToHash(big.NewInt(int64(i + 100))), BlockNumber: 100 + i}
+		leaf := l1infotree.HashLeafData(update.GER, update.ParentHash, update.Timestamp)
+		replaced = append(replaced, leaf)
+		require.NoError(t, hermezDb.WriteL1InfoTreeUpdate(update))
+		require.NoError(t, hermezDb.WriteL1InfoTreeLeaf(i, leaf))
+	}
+
+	mt, err := l1infotree.NewL1InfoTree(l1InfoTreeHeight, append(leaves, replaced...))
+	require.NoError(t, err)
+	expected, _, _ := mt.GetCurrentRootCountAndSiblings()
+
+	leaf, err := getL1InfoTreeLeaf(hermezDb.HermezDbReader, cache, 3)
+	require.NoError(t, err)
+	require.Equal(t, common.Hash(replaced[1]), leaf.Leaf)
+	require.Equal(t, expected, leaf.L1InfoRoot)
+}
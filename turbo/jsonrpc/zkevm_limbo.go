@@ -0,0 +1,98 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	txPoolProto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+	"github.com/ledgerwatch/erigon/zk/txpool"
+)
+
+// ZkEvmLimboAPI is the interface for the limbo operator commands.  They are served on the zkevm namespace and
+// refuse every request without the zkevm.admin-token bearer token.
+type ZkEvmLimboAPI interface {
+	GetLimboDetails(ctx context.Context) (*txpool.LimboDetails, error)
+	ResolveLimboTx(ctx context.Context, hash common.Hash, resolution txpool.LimboResolution) error
+}
+
+// ZkEvmLimboAPIImpl data structure to store things needed for the limbo operator commands.
+type ZkEvmLimboAPIImpl struct {
+	config  *ethconfig.Config
+	rawPool *txpool.TxPool
+	txPool  txPoolProto.TxpoolClient
+}
+
+// NewZkEvmLimboAPI returns ZkEvmLimboAPIImpl instance.  rawPool is nil unless the node runs the pool itself.
+func NewZkEvmLimboAPI(config *ethconfig.Config, rawPool *txpool.TxPool, txPool txPoolProto.TxpoolClient) *ZkEvmLimboAPIImpl {
+	return &ZkEvmLimboAPIImpl{
+		config:  config,
+		rawPool: rawPool,
+		txPool:  txPool,
+	}
+}
+
+func (api *ZkEvmLimboAPIImpl) checkAccess(ctx context.Context) error {
+	if !sequencer.IsSequencer() {
+		return errors.New("method only supported from a sequencer node")
+	}
+	if api.rawPool == nil || !api.config.Limbo {
+		return errors.New("limbo is not enabled on this node")
+	}
+
+	return checkAdminToken(ctx, api.config)
+}
+
+// GetLimboDetails lists the batches held in limbo with their blocks and transactions, along with the transactions
+// parked or waiting to be discarded by the pool
+func (api *ZkEvmLimboAPIImpl) GetLimboDetails(ctx context.Context) (*txpool.LimboDetails, error) {
+	if err := api.checkAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	return api.rawPool.GetLimboDetails(), nil
+}
+
+// ResolveLimboTx takes a transaction of an invalid limbo batch out of limbo.  With "drop" it is also removed from the
+// pool, with "requeue" it is returned to the pool so the sequencer can include it again.
+func (api *ZkEvmLimboAPIImpl) ResolveLimboTx(ctx context.Context, hash common.Hash, resolution txpool.LimboResolution) error {
+	if err := api.checkAccess(ctx); err != nil {
+		return err
+	}
+
+	rlp, err := api.rawPool.ResolveLimboTx(hash, resolution)
+	if err != nil {
+		return err
+	}
+	log.Info("Resolved limbo transaction", "hash", hash, "resolution", resolution)
+	if rlp == nil {
+		return nil
+	}
+
+	// limbo keeps the rlp encoding of the transaction, the pool expects the canonical binary encoding
+	txn, err := types.DecodeTransaction(rlp)
+	if err != nil {
+		return fmt.Errorf("could not decode limbo transaction: %w", err)
+	}
+	var buf bytes.Buffer
+	if err = txn.MarshalBinary(&buf); err != nil {
+		return err
+	}
+
+	res, err := api.txPool.Add(ctx, &txPoolProto.AddRequest{RlpTxs: [][]byte{buf.Bytes()}})
+	if err != nil {
+		return err
+	}
+	if res.Imported[0] != txPoolProto.ImportResult_SUCCESS {
+		return fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
+	}
+
+	return nil
+}
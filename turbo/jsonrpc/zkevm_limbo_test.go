@@ -0,0 +1,35 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
+	"github.com/ledgerwatch/erigon/zk/txpool"
+)
+
+func TestZkEvmLimboAPIAccess(t *testing.T) {
+	t.Setenv(sequencer.SEQUENCER_ENV_KEY, "1")
+
+	config := &ethconfig.Config{Zk: &ethconfig.Zk{Limbo: true}}
+	api := NewZkEvmLimboAPI(config, &txpool.TxPool{}, nil)
+	withAuth := func(auth string) context.Context {
+		return rpc.ContextWithAuthorization(context.Background(), auth)
+	}
+
+	// no token configured, the methods are disabled whatever the caller sends
+	require.ErrorContains(t, api.checkAccess(withAuth("Bearer ")), "disabled")
+
+	config.AdminToken = "secret"
+	require.ErrorIs(t, api.checkAccess(context.Background()), errAdminUnauthorized)
+	require.ErrorIs(t, api.checkAccess(withAuth("secret")), errAdminUnauthorized)
+	require.ErrorIs(t, api.checkAccess(withAuth("Bearer wrong")), errAdminUnauthorized)
+	require.NoError(t, api.checkAccess(withAuth("Bearer secret")))
+
+	config.Limbo = false
+	require.ErrorContains(t, api.checkAccess(withAuth("Bearer secret")), "limbo is not enabled")
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
 )
 
 type Filters struct {
@@ -35,6 +37,7 @@ type Filters struct {
 	pendingLogsSubs  *SyncMap[PendingLogsSubID, Sub[types.Logs]]
 	pendingBlockSubs *SyncMap[PendingBlockSubID, Sub[*types.Block]]
 	pendingTxsSubs   *SyncMap[PendingTxsSubID, Sub[[]types.Transaction]]
+	batchEventsSubs  *SyncMap[BatchEventsSubID, Sub[*zktypes.BatchEvent]]
 	logsSubs         *LogsFilterAggregator
 	logsRequestor    atomic.Value
 	onNewSnapshot    func()
@@ -54,6 +57,7 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		pendingTxsSubs:     NewSyncMap[PendingTxsSubID, Sub[[]types.Transaction]](),
 		pendingLogsSubs:    NewSyncMap[PendingLogsSubID, Sub[types.Logs]](),
 		pendingBlockSubs:   NewSyncMap[PendingBlockSubID, Sub[*types.Block]](),
+		batchEventsSubs:    NewSyncMap[BatchEventsSubID, Sub[*zktypes.BatchEvent]](),
 		logsSubs:           NewLogsFilterAggregator(),
 		onNewSnapshot:      onNewSnapshot,
 		logsStores:         NewSyncMap[LogsSubID, []*types.Log](),
@@ -468,6 +472,8 @@ func (ff *Filters) onNewEvent(event *remote.SubscribeReply) error {
 		return ff.onPendingLog(event)
 	case remote.Event_PENDING_BLOCK:
 		return ff.onPendingBlock(event)
+	case shards.EventZkBatch:
+		return ff.onBatchEvent(event)
 	default:
 		return fmt.Errorf("unsupported event type")
 	}
@@ -0,0 +1,37 @@
+package rpchelper
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func (ff *Filters) SubscribeBatchEvents(size int) (<-chan *zktypes.BatchEvent, BatchEventsSubID) {
+	id := BatchEventsSubID(generateSubscriptionID())
+	sub := newChanSub[*zktypes.BatchEvent](size)
+	ff.batchEventsSubs.Put(id, sub)
+	return sub.ch, id
+}
+
+func (ff *Filters) UnsubscribeBatchEvents(id BatchEventsSubID) bool {
+	ch, ok := ff.batchEventsSubs.Get(id)
+	if !ok {
+		return false
+	}
+	ch.Close()
+	_, ok = ff.batchEventsSubs.Delete(id)
+	return ok
+}
+
+func (ff *Filters) onBatchEvent(event *remote.SubscribeReply) error {
+	batchEvent := &zktypes.BatchEvent{}
+	if err := batchEvent.Unmarshall(event.Data); err != nil {
+		return fmt.Errorf("unprocessable payload: %w", err)
+	}
+	return ff.batchEventsSubs.Range(func(k BatchEventsSubID, v Sub[*zktypes.BatchEvent]) error {
+		v.Send(batchEvent)
+		return nil
+	})
+}
@@ -0,0 +1,33 @@
+package rpchelper
+
+import (
+	"context"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func TestFilters_BatchEvents(t *testing.T) {
+	t.Parallel()
+	f := New(context.TODO(), nil, nil, nil, func() {}, log.New())
+
+	outChan, id := f.SubscribeBatchEvents(10)
+
+	event := &zktypes.BatchEvent{Type: zktypes.BatchVerified, BatchNumber: 7, StateRoot: libcommon.HexToHash("0x1")}
+	f.OnNewEvent(&remote.SubscribeReply{Type: shards.EventZkBatch, Data: event.Marshall()})
+	require.Len(t, outChan, 1)
+	require.Equal(t, event, <-outChan)
+
+	// payloads that can't be decoded are dropped
+	f.OnNewEvent(&remote.SubscribeReply{Type: shards.EventZkBatch, Data: []byte{1}})
+	require.Len(t, outChan, 0)
+
+	require.True(t, f.UnsubscribeBatchEvents(id))
+	require.False(t, f.UnsubscribeBatchEvents(id))
+}
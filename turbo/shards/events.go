@@ -6,6 +6,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon/core/types"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
 )
 
 type RpcEventType uint64
@@ -27,6 +28,8 @@ type Events struct {
 	pendingTxsSubscriptions   map[int]PendingTxsSubscription
 	logsSubscriptions         map[int]chan []*remote.SubscribeLogsReply
 	hasLogSubscriptions       bool
+	batchEventSubscriptions   map[int]chan []*zktypes.BatchEvent
+	pendingBatchEvents        []*zktypes.BatchEvent
 	lock                      sync.RWMutex
 }
 
@@ -38,6 +41,7 @@ func NewEvents() *Events {
 		pendingTxsSubscriptions:   map[int]PendingTxsSubscription{},
 		logsSubscriptions:         map[int]chan []*remote.SubscribeLogsReply{},
 		newSnapshotSubscription:   map[int]chan struct{}{},
+		batchEventSubscriptions:   map[int]chan []*zktypes.BatchEvent{},
 	}
 }
 
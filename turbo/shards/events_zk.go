@@ -0,0 +1,52 @@
+package shards
+
+import (
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+// EventZkBatch is the remote event the batch lifecycle events are sent to the rpc daemon as, numbered clear of the
+// events of the ethbackend interface
+const EventZkBatch = remote.Event(100)
+
+func (e *Events) AddBatchEventSubscription() (chan []*zktypes.BatchEvent, func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	ch := make(chan []*zktypes.BatchEvent, 8)
+	e.id++
+	id := e.id
+	e.batchEventSubscriptions[id] = ch
+	return ch, func() {
+		delete(e.batchEventSubscriptions, id)
+		close(ch)
+	}
+}
+
+// QueueBatchEvents holds the batch events raised by a stage until FlushBatchEvents is called once the tx they were
+// written in has been committed
+func (e *Events) QueueBatchEvents(events ...*zktypes.BatchEvent) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pendingBatchEvents = append(e.pendingBatchEvents, events...)
+}
+
+// DropBatchEvents forgets the queued batch events of a tx that was rolled back
+func (e *Events) DropBatchEvents() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.pendingBatchEvents = nil
+}
+
+func (e *Events) FlushBatchEvents() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.pendingBatchEvents) == 0 {
+		return
+	}
+	for _, ch := range e.batchEventSubscriptions {
+		common.PrioritizedSend(ch, e.pendingBatchEvents)
+	}
+	e.pendingBatchEvents = nil
+}
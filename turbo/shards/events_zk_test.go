@@ -0,0 +1,36 @@
+package shards
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+func TestBatchEventsAreSentOnFlush(t *testing.T) {
+	events := NewEvents()
+	ch, clean := events.AddBatchEventSubscription()
+	defer clean()
+
+	closed := &zktypes.BatchEvent{Type: zktypes.BatchClosed, BatchNumber: 1}
+	events.QueueBatchEvents(closed)
+	require.Len(t, ch, 0)
+
+	// the events of a rolled back tx are never sent
+	events.QueueBatchEvents(&zktypes.BatchEvent{Type: zktypes.BatchClosed, BatchNumber: 2})
+	events.DropBatchEvents()
+	events.FlushBatchEvents()
+	require.Len(t, ch, 0)
+
+	events.QueueBatchEvents(closed)
+	events.QueueBatchEvents(&zktypes.BatchEvent{Type: zktypes.BatchVirtualized, BatchNumber: 1})
+	events.FlushBatchEvents()
+	require.Len(t, ch, 1)
+	sent := <-ch
+	require.Len(t, sent, 2)
+	require.Equal(t, closed, sent[0])
+
+	events.FlushBatchEvents()
+	require.Len(t, ch, 0)
+}
@@ -65,6 +65,9 @@ func StageLoop(ctx context.Context,
 		select {
 		case <-hd.ShutdownCh:
 			return
+		case <-ctx.Done():
+			// a node sharing the sequencer lease stops the loop to switch role
+			return
 		default:
 			// continue
 		}
@@ -257,6 +260,10 @@ func (h *Hook) beforeRun(tx kv.Tx, inSync bool) error {
 		}
 		notifications.Accumulator.Reset(stateVersion)
 	}
+	if notifications != nil && notifications.Events != nil {
+		// batch events still queued were raised in a tx that never committed
+		notifications.Events.DropBatchEvents()
+	}
 	return nil
 }
 func (h *Hook) BeforeRun(tx kv.Tx, inSync bool) error {
@@ -303,6 +310,7 @@ func (h *Hook) sendNotifications(notifications *shards.Notifications, tx kv.Tx,
 		if err = stagedsync.NotifyNewHeaders(h.ctx, finishProgressBefore, finishStageAfterSync, prevUnwindPoint, notifications.Events, tx, h.logger, h.blockReader); err != nil {
 			return nil
 		}
+		notifications.Events.FlushBatchEvents()
 	}
 
 	currentHeader := rawdb.ReadCurrentHeader(tx)
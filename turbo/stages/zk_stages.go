@@ -15,9 +15,11 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/engineapi/engine_helpers"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync/freezeblocks"
+	"github.com/ledgerwatch/erigon/zk/da"
 	"github.com/ledgerwatch/erigon/zk/datastream/server"
 	"github.com/ledgerwatch/erigon/zk/l1infotree"
 	"github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 	zkStages "github.com/ledgerwatch/erigon/zk/stages"
 	"github.com/ledgerwatch/erigon/zk/syncer"
 	"github.com/ledgerwatch/erigon/zk/txpool"
@@ -51,9 +53,9 @@ func NewDefaultZkStages(ctx context.Context,
 	runInTestMode := cfg.ImportMode
 
 	return zkStages.DefaultZkStages(ctx,
-		zkStages.StageL1SyncerCfg(db, l1Syncer, cfg.Zk),
+		zkStages.StageL1SyncerCfg(db, l1Syncer, cfg.Zk, notifications.Events, syncer.NewRollupAccInputHashSource(l1Syncer, cfg.Zk.AddressRollup, cfg.Zk.L1RollupId)),
 		zkStages.StageL1InfoTreeCfg(db, cfg.Zk, infoTreeUpdater),
-		zkStages.StageBatchesCfg(db, datastreamClient, cfg.Zk, controlServer.ChainConfig, &cfg.Miner),
+		zkStages.StageBatchesCfg(db, datastreamClient, cfg.Zk, controlServer.ChainConfig, &cfg.Miner, zkStages.WithBatchEvents(notifications.Events)),
 		zkStages.StageDataStreamCatchupCfg(dataStreamServer, db, cfg.Genesis.Config.ChainID.Uint64()),
 		stagedsync.StageBlockHashesCfg(db, dirs.Tmp, controlServer.ChainConfig, blockWriter),
 		stagedsync.StageSendersCfg(db, controlServer.ChainConfig, false, dirs.Tmp, cfg.Prune, blockReader, controlServer.Hd, nil),
@@ -85,6 +87,7 @@ func NewDefaultZkStages(ctx context.Context,
 		stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp, cfg.Genesis.Config.NoPruneContracts),
 		stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
 		stagedsync.StageTxLookupCfg(db, cfg.Prune, dirs.Tmp, controlServer.ChainConfig.Bor, blockReader),
+		zkStages.StageBridgeIndexerCfg(db, cfg.Zk),
 		stagedsync.StageFinishCfg(db, dirs.Tmp, forkValidator),
 		runInTestMode)
 }
@@ -108,6 +111,9 @@ func NewSequencerZkStages(ctx context.Context,
 	txPoolDb kv.RwDB,
 	verifier *legacy_executor_verifier.LegacyExecutorVerifier,
 	infoTreeUpdater *l1infotree.Updater,
+	l1GasPriceProvider zkStages.L1GasPriceProvider,
+	daBackend da.DABackend,
+	failover *sequencer.Failover,
 	hook *Hook,
 ) []*stagedsync.Stage {
 	dirs := cfg.Dirs
@@ -118,10 +124,10 @@ func NewSequencerZkStages(ctx context.Context,
 	runInTestMode := cfg.ImportMode
 
 	return zkStages.SequencerZkStages(ctx,
-		zkStages.StageL1SyncerCfg(db, l1Syncer, cfg.Zk),
+		zkStages.StageL1SyncerCfg(db, l1Syncer, cfg.Zk, notifications.Events, syncer.NewRollupAccInputHashSource(l1Syncer, cfg.Zk.AddressRollup, cfg.Zk.L1RollupId)),
 		zkStages.StageL1SequencerSyncCfg(db, cfg.Zk, sequencerStageSyncer),
 		zkStages.StageL1InfoTreeCfg(db, cfg.Zk, infoTreeUpdater),
-		zkStages.StageSequencerL1BlockSyncCfg(db, cfg.Zk, l1BlockSyncer),
+		zkStages.StageSequencerL1BlockSyncCfg(db, cfg.Zk, l1BlockSyncer, daBackend),
 		zkStages.StageDataStreamCatchupCfg(dataStreamServer, db, cfg.Genesis.Config.ChainID.Uint64()),
 		zkStages.StageSequenceBlocksCfg(
 			db,
@@ -148,6 +154,9 @@ func NewSequencerZkStages(ctx context.Context,
 			verifier,
 			uint16(cfg.YieldSize),
 			infoTreeUpdater,
+			l1GasPriceProvider,
+			failover,
+			notifications.Events,
 			hook,
 		),
 		stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV3, agg),
@@ -156,6 +165,7 @@ func NewSequencerZkStages(ctx context.Context,
 		stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp, cfg.Genesis.Config.NoPruneContracts),
 		stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
 		stagedsync.StageTxLookupCfg(db, cfg.Prune, dirs.Tmp, controlServer.ChainConfig.Bor, blockReader),
+		zkStages.StageBridgeIndexerCfg(db, cfg.Zk),
 		stagedsync.StageFinishCfg(db, dirs.Tmp, forkValidator),
 		runInTestMode)
 }
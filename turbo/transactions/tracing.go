@@ -176,6 +176,7 @@ func TraceTx(
 			Txn:               txCtx.Txn,
 			CumulativeGasUsed: txCtx.CumulativeGasUsed,
 			BlockNum:          blockCtx.BlockNumber,
+			CounterCollector:  executionCounters,
 		}, cfg); err != nil {
 			stream.WriteNil()
 			return err
@@ -0,0 +1,162 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/zk/contracts"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+// ExitTreeHeight is the depth of the local exit tree kept by the bridge contract
+const ExitTreeHeight = 32
+
+var (
+	bridgeEventAbi  = mustParseAbi(contracts.BridgeEventAbi)
+	claimEventAbi   = mustParseAbi(contracts.ClaimEventAbi)
+	claimEventAbiV1 = mustParseAbi(contracts.ClaimEventAbiV1)
+)
+
+func mustParseAbi(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// ParseLogs returns the deposits and claims found in the logs of a transaction, logs not emitted by the bridge
+// contract are ignored
+func ParseLogs(logs types.Logs, bridgeAddress common.Address, blockNum uint64, txHash common.Hash) ([]*zktypes.BridgeDeposit, []*zktypes.BridgeClaim, error) {
+	var deposits []*zktypes.BridgeDeposit
+	var claims []*zktypes.BridgeClaim
+	for _, l := range logs {
+		if l.Address != bridgeAddress || len(l.Topics) == 0 {
+			continue
+		}
+		switch l.Topics[0] {
+		case contracts.BridgeEventTopic:
+			deposit, err := ParseDeposit(l.Data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ParseDeposit: %w", err)
+			}
+			deposit.BlockNumber = blockNum
+			deposit.TxHash = txHash
+			deposits = append(deposits, deposit)
+		case contracts.ClaimEventTopic, contracts.ClaimEventTopicV1:
+			claim, err := ParseClaim(l.Topics[0], l.Data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ParseClaim: %w", err)
+			}
+			claim.BlockNumber = blockNum
+			claim.TxHash = txHash
+			claims = append(claims, claim)
+		}
+	}
+	return deposits, claims, nil
+}
+
+// ParseDeposit decodes the data of a BridgeEvent log
+func ParseDeposit(data []byte) (*zktypes.BridgeDeposit, error) {
+	values, err := bridgeEventAbi.Unpack("BridgeEvent", data)
+	if err != nil {
+		return nil, err
+	}
+	return &zktypes.BridgeDeposit{
+		LeafType:           values[0].(uint8),
+		OriginNetwork:      values[1].(uint32),
+		OriginAddress:      values[2].(common.Address),
+		DestinationNetwork: values[3].(uint32),
+		DestinationAddress: values[4].(common.Address),
+		Amount:             values[5].(*big.Int),
+		Metadata:           values[6].([]byte),
+		DepositCount:       values[7].(uint32),
+	}, nil
+}
+
+// ParseClaim decodes the data of a ClaimEvent log, claims from before the global index was introduced carry the
+// deposit count of the claimed deposit in its place
+func ParseClaim(topic common.Hash, data []byte) (*zktypes.BridgeClaim, error) {
+	claim := &zktypes.BridgeClaim{}
+	if topic == contracts.ClaimEventTopicV1 {
+		values, err := claimEventAbiV1.Unpack("ClaimEvent", data)
+		if err != nil {
+			return nil, err
+		}
+		claim.GlobalIndex = new(big.Int).SetUint64(uint64(values[0].(uint32)))
+		claim.OriginNetwork = values[1].(uint32)
+		claim.OriginAddress = values[2].(common.Address)
+		claim.DestinationAddress = values[3].(common.Address)
+		claim.Amount = values[4].(*big.Int)
+		return claim, nil
+	}
+
+	values, err := claimEventAbi.Unpack("ClaimEvent", data)
+	if err != nil {
+		return nil, err
+	}
+	claim.GlobalIndex = values[0].(*big.Int)
+	claim.OriginNetwork = values[1].(uint32)
+	claim.OriginAddress = values[2].(common.Address)
+	claim.DestinationAddress = values[3].(common.Address)
+	claim.Amount = values[4].(*big.Int)
+	return claim, nil
+}
+
+// LeafHash returns the local exit tree leaf of a deposit, the same value as getLeafValue of the bridge contract
+func LeafHash(d *zktypes.BridgeDeposit) common.Hash {
+	packed := make([]byte, 0, 1+4+20+4+20+32+32)
+	packed = append(packed, d.LeafType)
+	packed = binary.BigEndian.AppendUint32(packed, d.OriginNetwork)
+	packed = append(packed, d.OriginAddress[:]...)
+	packed = binary.BigEndian.AppendUint32(packed, d.DestinationNetwork)
+	packed = append(packed, d.DestinationAddress[:]...)
+	amount := make([]byte, 32)
+	if d.Amount != nil {
+		d.Amount.FillBytes(amount)
+	}
+	packed = append(packed, amount...)
+	packed = append(packed, crypto.Keccak256(d.Metadata)...)
+	return crypto.Keccak256Hash(packed)
+}
+
+// ComputeDepositProof returns the siblings proving the leaf of a deposit against the root of the local exit tree
+// built from the given leaves
+func ComputeDepositProof(leaves []common.Hash, depositCount uint32) ([][32]byte, common.Hash, error) {
+	if uint64(depositCount) >= uint64(len(leaves)) {
+		return nil, common.Hash{}, fmt.Errorf("deposit %d is not in an exit tree of %d leaves", depositCount, len(leaves))
+	}
+	tree, err := l1infotree.NewL1InfoTree(ExitTreeHeight, nil)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	treeLeaves := make([][32]byte, len(leaves))
+	for i, leaf := range leaves {
+		treeLeaves[i] = leaf
+	}
+	return tree.ComputeMerkleProof(depositCount, treeLeaves)
+}
+
+// VerifyDepositProof checks that the leaf at the deposit count is part of the tree with the given root
+func VerifyDepositProof(leaf common.Hash, proof [][32]byte, depositCount uint32, root common.Hash) bool {
+	if len(proof) != ExitTreeHeight {
+		return false
+	}
+	node := [32]byte(leaf)
+	for h, sibling := range proof {
+		if depositCount&(1<<h) > 0 {
+			node = l1infotree.Hash(sibling, node)
+		} else {
+			node = l1infotree.Hash(node, sibling)
+		}
+	}
+	return common.Hash(node) == root
+}
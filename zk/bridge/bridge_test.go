@@ -0,0 +1,115 @@
+package bridge
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/zk/contracts"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+)
+
+var testBridgeAddress = common.HexToAddress("0x2a3dd3eb832af982ec71669e178424b10dca2ede")
+
+func TestLeafHash(t *testing.T) {
+	deposit := &zktypes.BridgeDeposit{
+		LeafType:           1,
+		OriginNetwork:      2,
+		OriginAddress:      common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		DestinationNetwork: 3,
+		DestinationAddress: common.HexToAddress("0xc949254d682d8c9ad5682521675b8f43b102aec4"),
+		Amount:             new(big.Int).SetUint64(10000000000000000000),
+		Metadata:           []byte{0xab},
+	}
+
+	// abi.encodePacked(leafType, originNetwork, originAddress, destinationNetwork, destinationAddress, amount, keccak256(metadata))
+	packed := common.FromHex("0x01" +
+		"00000002" +
+		"1111111111111111111111111111111111111111" +
+		"00000003" +
+		"c949254d682d8c9ad5682521675b8f43b102aec4" +
+		"0000000000000000000000000000000000000000000000008ac7230489e80000")
+	packed = append(packed, crypto.Keccak256([]byte{0xab})...)
+	require.Equal(t, crypto.Keccak256Hash(packed), LeafHash(deposit))
+
+	// the deposit count and where it was emitted are not part of the leaf
+	deposit.DepositCount, deposit.BlockNumber = 5, 6
+	require.Equal(t, crypto.Keccak256Hash(packed), LeafHash(deposit))
+}
+
+func TestParseLogs(t *testing.T) {
+	origin := common.HexToAddress("0x1")
+	destination := common.HexToAddress("0x2")
+	txHash := common.HexToHash("0xabc")
+
+	bridgeData, err := bridgeEventAbi.Events["BridgeEvent"].Inputs.Pack(uint8(1), uint32(0), origin, uint32(1), destination, big.NewInt(100), []byte{1, 2}, uint32(7))
+	require.NoError(t, err)
+	globalIndex := new(big.Int).Lsh(big.NewInt(1), 64)
+	claimData, err := claimEventAbi.Events["ClaimEvent"].Inputs.Pack(globalIndex, uint32(0), origin, destination, big.NewInt(5))
+	require.NoError(t, err)
+	claimDataV1, err := claimEventAbiV1.Events["ClaimEvent"].Inputs.Pack(uint32(3), uint32(0), origin, destination, big.NewInt(6))
+	require.NoError(t, err)
+
+	logs := types.Logs{
+		{Address: testBridgeAddress, Topics: []common.Hash{contracts.BridgeEventTopic}, Data: bridgeData},
+		{Address: testBridgeAddress, Topics: []common.Hash{contracts.ClaimEventTopic}, Data: claimData},
+		{Address: testBridgeAddress, Topics: []common.Hash{contracts.ClaimEventTopicV1}, Data: claimDataV1},
+		// the same event from another contract is not a deposit
+		{Address: common.HexToAddress("0xdead"), Topics: []common.Hash{contracts.BridgeEventTopic}, Data: bridgeData},
+		{Address: testBridgeAddress, Topics: []common.Hash{common.HexToHash("0x1234")}},
+	}
+
+	deposits, claims, err := ParseLogs(logs, testBridgeAddress, 10, txHash)
+	require.NoError(t, err)
+	require.Equal(t, []*zktypes.BridgeDeposit{{
+		LeafType:           1,
+		OriginNetwork:      0,
+		OriginAddress:      origin,
+		DestinationNetwork: 1,
+		DestinationAddress: destination,
+		Amount:             big.NewInt(100),
+		Metadata:           []byte{1, 2},
+		DepositCount:       7,
+		BlockNumber:        10,
+		TxHash:             txHash,
+	}}, deposits)
+	require.Len(t, claims, 2)
+	require.Equal(t, globalIndex, claims[0].GlobalIndex)
+	require.Equal(t, big.NewInt(5), claims[0].Amount)
+	require.Equal(t, uint64(10), claims[0].BlockNumber)
+	require.Equal(t, txHash, claims[0].TxHash)
+	require.Equal(t, big.NewInt(3), claims[1].GlobalIndex)
+	require.Equal(t, destination, claims[1].DestinationAddress)
+
+	_, _, err = ParseLogs(types.Logs{{Address: testBridgeAddress, Topics: []common.Hash{contracts.BridgeEventTopic}, Data: []byte{1}}}, testBridgeAddress, 10, txHash)
+	require.Error(t, err)
+}
+
+func TestComputeDepositProof(t *testing.T) {
+	tree, err := l1infotree.NewL1InfoTree(ExitTreeHeight, nil)
+	require.NoError(t, err)
+
+	var leaves []common.Hash
+	for i := uint32(0); i < 5; i++ {
+		leaf := LeafHash(&zktypes.BridgeDeposit{DepositCount: i, Amount: big.NewInt(int64(i + 1))})
+		leaves = append(leaves, leaf)
+		expectedRoot, err := tree.AddLeaf(i, leaf)
+		require.NoError(t, err)
+
+		for depositCount := uint32(0); depositCount <= i; depositCount++ {
+			proof, root, err := ComputeDepositProof(leaves, depositCount)
+			require.NoError(t, err)
+			require.Equal(t, common.Hash(expectedRoot), root)
+			require.True(t, VerifyDepositProof(leaves[depositCount], proof, depositCount, root))
+			require.False(t, VerifyDepositProof(leaves[depositCount], proof, depositCount+1, root))
+		}
+	}
+
+	_, _, err = ComputeDepositProof(leaves, 5)
+	require.Error(t, err)
+}
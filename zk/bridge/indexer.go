@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+// IndexBlocks writes the deposits and claims the bridge contract emitted from fromBlock to toBlock, both inclusive,
+// to the hermez db. The receipts of the blocks must still be stored.
+func IndexBlocks(tx kv.RwTx, bridgeAddress common.Address, fromBlock, toBlock uint64) (deposits, claims int, err error) {
+	hermezDb := hermez_db.NewHermezDb(tx)
+	nextDepositCount, err := hermezDb.GetBridgeDepositCount()
+	if err != nil {
+		return 0, 0, fmt.Errorf("GetBridgeDepositCount: %w", err)
+	}
+
+	for blockNum := fromBlock; blockNum <= toBlock; blockNum++ {
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		if len(receipts) == 0 {
+			continue
+		}
+		block, err := rawdb.ReadBlockByNumber(tx, blockNum)
+		if err != nil {
+			return 0, 0, fmt.Errorf("ReadBlockByNumber: %w", err)
+		}
+		if block == nil {
+			return 0, 0, fmt.Errorf("block %d not found", blockNum)
+		}
+		txs := block.Transactions()
+
+		for i, receipt := range receipts {
+			if len(receipt.Logs) == 0 || i >= len(txs) {
+				continue
+			}
+			blockDeposits, blockClaims, err := ParseLogs(receipt.Logs, bridgeAddress, blockNum, txs[i].Hash())
+			if err != nil {
+				return 0, 0, fmt.Errorf("block %d tx %d: %w", blockNum, i, err)
+			}
+
+			for _, deposit := range blockDeposits {
+				if deposit.DepositCount < nextDepositCount {
+					return 0, 0, fmt.Errorf("deposit %d in block %d is below the %d indexed deposits", deposit.DepositCount, blockNum, nextDepositCount)
+				}
+				// the receipts of the blocks with the deposits in between were pruned before the indexer reached them.
+				// The gap is recorded so no proof is served from an exit tree holding it, and later deposits and
+				// claims are still indexed
+				if deposit.DepositCount > nextDepositCount {
+					log.Warn("Bridge deposits are missing from the local exit tree, the receipts of earlier blocks may have been pruned",
+						"fromDeposit", nextDepositCount, "toDeposit", deposit.DepositCount-1, "block", blockNum)
+					if err = hermezDb.WriteBridgeDepositGap(nextDepositCount, deposit.DepositCount); err != nil {
+						return 0, 0, fmt.Errorf("WriteBridgeDepositGap: %w", err)
+					}
+				}
+				if err = hermezDb.WriteBridgeDeposit(deposit, LeafHash(deposit)); err != nil {
+					return 0, 0, fmt.Errorf("WriteBridgeDeposit: %w", err)
+				}
+				nextDepositCount = deposit.DepositCount + 1
+			}
+			for _, claim := range blockClaims {
+				if err = hermezDb.WriteBridgeClaim(claim); err != nil {
+					return 0, 0, fmt.Errorf("WriteBridgeClaim: %w", err)
+				}
+			}
+			deposits += len(blockDeposits)
+			claims += len(blockClaims)
+		}
+	}
+
+	return deposits, claims, nil
+}
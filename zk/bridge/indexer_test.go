@@ -0,0 +1,95 @@
+package bridge
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/contracts"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+// writeTestBlock writes a block with one transaction emitting the logs
+func writeTestBlock(t *testing.T, tx kv.RwTx, blockNum uint64, logs ...*types.Log) common.Hash {
+	txn := types.NewTransaction(blockNum, common.Address{}, u256.Num0, 0, u256.Num0, nil)
+	block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(blockNum)}).WithBody([]types.Transaction{txn}, nil)
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, block.Hash(), blockNum))
+	require.NoError(t, rawdb.WriteBlock(tx, block))
+	require.NoError(t, rawdb.WriteReceipts(tx, blockNum, types.Receipts{{Logs: logs}}))
+	return txn.Hash()
+}
+
+func testDepositLog(t *testing.T, destination common.Address, depositCount uint32) *types.Log {
+	data, err := bridgeEventAbi.Events["BridgeEvent"].Inputs.Pack(uint8(0), uint32(0), common.Address{}, uint32(0), destination, big.NewInt(1), []byte{}, depositCount)
+	require.NoError(t, err)
+	return &types.Log{Address: testBridgeAddress, Topics: []common.Hash{contracts.BridgeEventTopic}, Data: data}
+}
+
+func TestIndexBlocks(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	destination := common.HexToAddress("0xd")
+	claimData, err := claimEventAbi.Events["ClaimEvent"].Inputs.Pack(big.NewInt(9), uint32(0), common.Address{}, destination, big.NewInt(1))
+	require.NoError(t, err)
+
+	depositTx := writeTestBlock(t, tx, 1, testDepositLog(t, destination, 0))
+	claimTx := writeTestBlock(t, tx, 2, &types.Log{Address: testBridgeAddress, Topics: []common.Hash{contracts.ClaimEventTopic}, Data: claimData})
+	writeTestBlock(t, tx, 3, testDepositLog(t, destination, 1), testDepositLog(t, destination, 2))
+
+	deposits, claims, err := IndexBlocks(tx, testBridgeAddress, 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, deposits)
+	require.Equal(t, 1, claims)
+
+	indexed, err := hermezDb.GetBridgeDepositsByAddress(destination)
+	require.NoError(t, err)
+	require.Len(t, indexed, 3)
+	require.Equal(t, depositTx, indexed[0].TxHash)
+	require.Equal(t, uint64(3), indexed[2].BlockNumber)
+
+	leaves, err := hermezDb.GetBridgeExitLeavesFrom(0)
+	require.NoError(t, err)
+	require.Equal(t, LeafHash(indexed[1]), leaves[1])
+	for blockNum, expected := range []uint32{0, 1, 1, 3} {
+		count, err := hermezDb.GetBridgeDepositCountAtBlock(uint64(blockNum))
+		require.NoError(t, err)
+		require.Equal(t, expected, count, blockNum)
+	}
+
+	claim, err := hermezDb.GetBridgeClaim(big.NewInt(9))
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+	require.Equal(t, claimTx, claim.TxHash)
+
+	// a deposit that skips part of the exit tree records the gap and indexing carries on
+	writeTestBlock(t, tx, 4, testDepositLog(t, destination, 5))
+	writeTestBlock(t, tx, 5, testDepositLog(t, destination, 6))
+	deposits, _, err = IndexBlocks(tx, testBridgeAddress, 4, 5)
+	require.NoError(t, err)
+	require.Equal(t, 2, deposits)
+	from, to, found, err := hermezDb.GetFirstBridgeDepositGap()
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint32(3), from)
+	require.Equal(t, uint32(5), to)
+	count, err := hermezDb.GetBridgeDepositCount()
+	require.NoError(t, err)
+	require.Equal(t, uint32(7), count)
+	leaves, err = hermezDb.GetBridgeExitLeavesFrom(0)
+	require.NoError(t, err)
+	require.Len(t, leaves, 3)
+
+	// a deposit already indexed is refused
+	writeTestBlock(t, tx, 6, testDepositLog(t, destination, 6))
+	_, _, err = IndexBlocks(tx, testBridgeAddress, 6, 6)
+	require.ErrorContains(t, err, "is below the 7 indexed deposits")
+}
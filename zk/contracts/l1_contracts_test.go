@@ -76,6 +76,21 @@ func TestZkContractsEventsHash(t *testing.T) {
 			eventSig:     "RollbackBatches(uint64,bytes32)",
 			expectedHash: RollbackBatchesTopic,
 		},
+		{
+			name:         "BridgeEvent",
+			eventSig:     "BridgeEvent(uint8,uint32,address,uint32,address,uint256,bytes,uint32)",
+			expectedHash: BridgeEventTopic,
+		},
+		{
+			name:         "ClaimEvent",
+			eventSig:     "ClaimEvent(uint256,uint32,address,address,uint256)",
+			expectedHash: ClaimEventTopic,
+		},
+		{
+			name:         "ClaimEvent_V1",
+			eventSig:     "ClaimEvent(uint32,uint32,address,address,uint256)",
+			expectedHash: ClaimEventTopicV1,
+		},
 	}
 
 	for _, c := range cases {
@@ -0,0 +1,16 @@
+package contracts
+
+import (
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+// events emitted by the bridge contract deployed on the L2
+var (
+	BridgeEventTopic  = common.HexToHash("0x501781209a1f8899323b96b4ef08b168df93e0a90c673d1e4cce39366cb62f9b")
+	ClaimEventTopic   = common.HexToHash("0x1df3f2a973a00d6635911755c260704e95e8a5876997546798770f76396fda4d")
+	ClaimEventTopicV1 = common.HexToHash("0x25308c93ceeed162da955b3f7ce3e3f93606579e40fb92029faa9efe27545983")
+)
+
+const BridgeEventAbi = `[{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint8","name":"leafType","type":"uint8"},{"indexed":false,"internalType":"uint32","name":"originNetwork","type":"uint32"},{"indexed":false,"internalType":"address","name":"originAddress","type":"address"},{"indexed":false,"internalType":"uint32","name":"destinationNetwork","type":"uint32"},{"indexed":false,"internalType":"address","name":"destinationAddress","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"},{"indexed":false,"internalType":"bytes","name":"metadata","type":"bytes"},{"indexed":false,"internalType":"uint32","name":"depositCount","type":"uint32"}],"name":"BridgeEvent","type":"event"}]`
+const ClaimEventAbi = `[{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint256","name":"globalIndex","type":"uint256"},{"indexed":false,"internalType":"uint32","name":"originNetwork","type":"uint32"},{"indexed":false,"internalType":"address","name":"originAddress","type":"address"},{"indexed":false,"internalType":"address","name":"destinationAddress","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"}],"name":"ClaimEvent","type":"event"}]`
+const ClaimEventAbiV1 = `[{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint32","name":"index","type":"uint32"},{"indexed":false,"internalType":"uint32","name":"originNetwork","type":"uint32"},{"indexed":false,"internalType":"address","name":"originAddress","type":"address"},{"indexed":false,"internalType":"address","name":"destinationAddress","type":"address"},{"indexed":false,"internalType":"uint256","name":"amount","type":"uint256"}],"name":"ClaimEvent","type":"event"}]`
@@ -0,0 +1,172 @@
+package da
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/zk/utils"
+)
+
+// ErrNotFound is returned by a backend that has no data for a hash, the client moves on to the next backend
+var ErrNotFound = errors.New("off chain data not found")
+
+// ErrHashMismatch is returned when a backend returns data that does not hash to the batch hash sequenced on L1
+var ErrHashMismatch = errors.New("off chain data does not match its hash")
+
+// DABackend fetches the off chain transaction data of validium batches by the hash sequenced on L1
+type DABackend interface {
+	GetOffChainData(ctx context.Context, hash common.Hash) ([]byte, error)
+	// Name labels the backend in logs and metrics
+	Name() string
+}
+
+type Config struct {
+	// URLs of the backends in the order they are tried, see NewBackend for the supported schemes
+	URLs []string
+	// QuorumURLs are committee member URLs queried together, the quorum is tried after URLs
+	QuorumURLs      []string
+	QuorumThreshold int
+	// CacheSize is the number of batches whose data is kept in memory
+	CacheSize int
+}
+
+// NewBackend creates the backend for a URL:
+//
+//	http(s)://host            a DAC member serving sync_getOffChainData
+//	file:///dir               files in dir named by hash
+//	objstore+http(s)://host/p objects under host/p named by hash, e.g. a bucket served over http
+func NewBackend(url string) (DABackend, error) {
+	switch {
+	case strings.HasPrefix(url, "http://"), strings.HasPrefix(url, "https://"):
+		return NewDACBackend(url), nil
+	case strings.HasPrefix(url, "file://"):
+		return NewFileBackend(strings.TrimPrefix(url, "file://")), nil
+	case strings.HasPrefix(url, "objstore+"):
+		return NewObjectStoreBackend(strings.TrimPrefix(url, "objstore+")), nil
+	default:
+		return nil, fmt.Errorf("unsupported data availability url %s", url)
+	}
+}
+
+// NewClientFromConfig returns nil when no backend is configured
+func NewClientFromConfig(cfg Config) (*Client, error) {
+	var backends []DABackend
+	for _, url := range cfg.URLs {
+		if url == "" {
+			continue
+		}
+		backend, err := NewBackend(url)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, backend)
+	}
+
+	var members []DABackend
+	for _, url := range cfg.QuorumURLs {
+		if url != "" {
+			members = append(members, NewDACBackend(url))
+		}
+	}
+	if len(members) > 0 {
+		quorum, err := NewQuorumBackend(members, cfg.QuorumThreshold)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, quorum)
+	}
+
+	if len(backends) == 0 {
+		return nil, nil
+	}
+	return NewClient(backends, cfg.CacheSize)
+}
+
+// Client tries its backends in order until one returns data matching the hash, caching what it finds
+type Client struct {
+	backends []DABackend
+	cache    *lru.Cache[common.Hash, []byte]
+}
+
+func NewClient(backends []DABackend, cacheSize int) (*Client, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("at least one data availability backend is required")
+	}
+	c := &Client{backends: backends}
+	if cacheSize > 0 {
+		cache, err := lru.New[common.Hash, []byte](cacheSize)
+		if err != nil {
+			return nil, err
+		}
+		c.cache = cache
+	}
+	return c, nil
+}
+
+func (c *Client) Name() string {
+	return "client"
+}
+
+func (c *Client) GetOffChainData(ctx context.Context, hash common.Hash) ([]byte, error) {
+	if c.cache != nil {
+		if data, ok := c.cache.Get(hash); ok {
+			cacheHitsCounter.Inc()
+			return data, nil
+		}
+		cacheMissesCounter.Inc()
+	}
+
+	var errs []error
+	for _, backend := range c.backends {
+		data, err := fetch(ctx, backend, hash)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Warn("Failed to fetch off chain data, trying the next backend", "backend", backend.Name(), "hash", hash, "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", backend.Name(), err))
+			continue
+		}
+		if c.cache != nil {
+			c.cache.Add(hash, data)
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("unable to fetch off chain data for hash %s: %w", hash, errors.Join(errs...))
+}
+
+// fetch gets the data from a backend, checking it against the hash and recording metrics
+func fetch(ctx context.Context, backend DABackend, hash common.Hash) ([]byte, error) {
+	start := time.Now()
+	data, err := backend.GetOffChainData(ctx, hash)
+	requestSummary(backend.Name()).ObserveDuration(start)
+	if err == nil {
+		err = verifyOffChainData(hash, data)
+	}
+	result := "ok"
+	switch {
+	case errors.Is(err, ErrNotFound):
+		result = "not_found"
+	case errors.Is(err, ErrHashMismatch):
+		result = "mismatch"
+	case err != nil:
+		result = "error"
+	}
+	requestCounter(backend.Name(), result).Inc()
+	return data, err
+}
+
+func verifyOffChainData(hash common.Hash, data []byte) error {
+	if actual := common.BytesToHash(utils.CalculateBatchHashData(data)); actual != hash {
+		return fmt.Errorf("%w: got %s", ErrHashMismatch, actual)
+	}
+	return nil
+}
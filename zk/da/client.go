@@ -4,25 +4,37 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/hexutil"
 	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/types"
 )
 
 const maxAttempts = 10
 const retryDelay = 500 * time.Millisecond
 
+// requestTimeout bounds a single request to a committee member, a hung member must not hold up the failover to the
+// next one
+const requestTimeout = 10 * time.Second
+
 func GetOffChainData(ctx context.Context, url string, hash common.Hash) ([]byte, error) {
 	attemp := 0
 
 	for attemp < maxAttempts {
-		response, err := client.JSONRPCCall(url, "sync_getOffChainData", hash)
+		response, err := getOffChainData(ctx, url, hash)
 
 		if httpErr, ok := err.(*client.HTTPError); ok && httpErr.StatusCode == http.StatusTooManyRequests {
-			time.Sleep(retryDelay)
+			timer := time.NewTimer(retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
 			attemp += 1
 			continue
 		}
@@ -40,3 +52,32 @@ func GetOffChainData(ctx context.Context, url string, hash common.Hash) ([]byte,
 
 	return nil, fmt.Errorf("max attempts of data fetching reached, attempts: %v, DA url: %s", maxAttempts, url)
 }
+
+func getOffChainData(ctx context.Context, url string, hash common.Hash) (types.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return client.JSONRPCCallWithContext(ctx, url, "sync_getOffChainData", hash)
+}
+
+// DACBackend queries a single data availability committee member
+type DACBackend struct {
+	endpoint string
+	name     string
+}
+
+func NewDACBackend(endpoint string) *DACBackend {
+	// the host tells the members apart in the logs and metrics without exposing any credentials in the url
+	name := "dac"
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		name = "dac:" + u.Host
+	}
+	return &DACBackend{endpoint: endpoint, name: name}
+}
+
+func (b *DACBackend) Name() string {
+	return b.name
+}
+
+func (b *DACBackend) GetOffChainData(ctx context.Context, hash common.Hash) ([]byte, error) {
+	return GetOffChainData(ctx, b.endpoint, hash)
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/types"
@@ -89,3 +90,32 @@ func TestClient_GetOffChainData(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_GetOffChainDataCancelled(t *testing.T) {
+	hung := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		<-hung
+	}))
+	defer svr.Close()
+	defer close(hung)
+
+	// a member that does not answer is given up on with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := GetOffChainData(ctx, svr.URL, common.Hash{})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	// and so is one asking us to back off
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = GetOffChainData(ctx, svr.URL+"/busy", common.Hash{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,21 @@
+package da
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/metrics"
+)
+
+var (
+	cacheHitsCounter   = metrics.GetOrCreateCounter(`zkevm_da_cache{result="hit"}`)
+	cacheMissesCounter = metrics.GetOrCreateCounter(`zkevm_da_cache{result="miss"}`)
+)
+
+// requestCounter counts requests to a backend by result: ok, not_found, mismatch or error
+func requestCounter(backend, result string) metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`zkevm_da_requests{backend="%s",result="%s"}`, backend, result))
+}
+
+func requestSummary(backend string) metrics.Summary {
+	return metrics.GetOrCreateSummary(fmt.Sprintf(`zkevm_da_request_seconds{backend="%s"}`, backend))
+}
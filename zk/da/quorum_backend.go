@@ -0,0 +1,70 @@
+package da
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// QuorumBackend queries several committee members at once and only returns data once threshold of them have
+// returned data matching the hash, so a single faulty or lagging member can neither stall nor mislead the node
+type QuorumBackend struct {
+	members   []DABackend
+	threshold int
+}
+
+func NewQuorumBackend(members []DABackend, threshold int) (*QuorumBackend, error) {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if threshold > len(members) {
+		return nil, fmt.Errorf("quorum threshold %d is more than the %d committee members", threshold, len(members))
+	}
+	return &QuorumBackend{members: members, threshold: threshold}, nil
+}
+
+func (b *QuorumBackend) Name() string {
+	return "quorum"
+}
+
+type quorumResponse struct {
+	member int
+	data   []byte
+	err    error
+}
+
+func (b *QuorumBackend) GetOffChainData(ctx context.Context, hash common.Hash) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make(chan quorumResponse, len(b.members))
+	for i, member := range b.members {
+		go func(i int, member DABackend) {
+			data, err := fetch(ctx, member, hash)
+			responses <- quorumResponse{member: i, data: data, err: err}
+		}(i, member)
+	}
+
+	var data []byte
+	var errs []error
+	agreed := 0
+	for range b.members {
+		response := <-responses
+		if response.err != nil {
+			if errors.Is(response.err, ErrHashMismatch) {
+				log.Warn("Committee member returned off chain data not matching its hash", "member", b.members[response.member].Name(), "hash", hash)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", b.members[response.member].Name(), response.err))
+			continue
+		}
+		data = response.data
+		if agreed++; agreed == b.threshold {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("only %d of the %d committee members required returned valid data: %w", agreed, b.threshold, errors.Join(errs...))
+}
@@ -0,0 +1,78 @@
+package da
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+const objectStoreTimeout = 30 * time.Second
+
+// FileBackend reads the data of a batch from a file named by its hash, e.g. 0x1234...abcd
+type FileBackend struct {
+	dir string
+}
+
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+func (b *FileBackend) Name() string {
+	return "file"
+}
+
+func (b *FileBackend) GetOffChainData(_ context.Context, hash common.Hash) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.dir, hash.Hex()))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// ObjectStoreBackend fetches the data of a batch from an object named by its hash under a base URL, which works with
+// any object store that serves objects over plain GET requests
+type ObjectStoreBackend struct {
+	baseUrl string
+	client  *http.Client
+}
+
+func NewObjectStoreBackend(baseUrl string) *ObjectStoreBackend {
+	return &ObjectStoreBackend{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		client:  &http.Client{Timeout: objectStoreTimeout},
+	}
+}
+
+func (b *ObjectStoreBackend) Name() string {
+	return "objstore"
+}
+
+func (b *ObjectStoreBackend) GetOffChainData(ctx context.Context, hash common.Hash) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseUrl+"/"+hash.Hex(), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(res.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("object store returned status %d", res.StatusCode)
+	}
+}
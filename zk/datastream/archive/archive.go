@@ -0,0 +1,263 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+)
+
+// An archive holds a contiguous range of raw datastream entries so that batches can be moved between nodes or kept
+// for later analysis without a live connection to a datastream server.
+//
+// Layout:
+//
+//	header  | magic (8) | version (2) | from batch (8) | to batch (8)
+//	entries | datastream file entries as encoded by types.FileEntry.Encode
+//	trailer | entry count (8) | sha256 of header and entries (32)
+
+const (
+	Version uint16 = 1
+
+	headerSize  = 8 + 2 + 8 + 8
+	trailerSize = 8 + sha256.Size
+
+	// packetTypeData is the datastream packet type of a data entry
+	packetTypeData uint8 = 2
+)
+
+var (
+	magic = [8]byte{'Z', 'K', 'D', 'S', 'A', 'R', 'C', 0}
+
+	ErrInvalidArchive  = errors.New("not a datastream archive")
+	ErrChecksumInvalid = errors.New("datastream archive checksum mismatch")
+)
+
+type Header struct {
+	Version   uint16
+	FromBatch uint64
+	ToBatch   uint64
+}
+
+func (h Header) encode() []byte {
+	b := make([]byte, 0, headerSize)
+	b = append(b, magic[:]...)
+	b = binary.BigEndian.AppendUint16(b, h.Version)
+	b = binary.BigEndian.AppendUint64(b, h.FromBatch)
+	b = binary.BigEndian.AppendUint64(b, h.ToBatch)
+	return b
+}
+
+func decodeHeader(b []byte) (Header, error) {
+	if len(b) < headerSize || !bytes.Equal(b[:8], magic[:]) {
+		return Header{}, ErrInvalidArchive
+	}
+	h := Header{
+		Version:   binary.BigEndian.Uint16(b[8:10]),
+		FromBatch: binary.BigEndian.Uint64(b[10:18]),
+		ToBatch:   binary.BigEndian.Uint64(b[18:26]),
+	}
+	if h.Version != Version {
+		return Header{}, fmt.Errorf("unsupported datastream archive version %d", h.Version)
+	}
+	return h, nil
+}
+
+// Writer writes datastream entries to an archive, Close must be called to write the trailer
+type Writer struct {
+	w       *bufio.Writer
+	hasher  hash.Hash
+	entries uint64
+}
+
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Version = Version
+	aw := &Writer{
+		w:      bufio.NewWriter(w),
+		hasher: sha256.New(),
+	}
+	if err := aw.write(header.encode()); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+func (w *Writer) write(b []byte) error {
+	w.hasher.Write(b)
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *Writer) WriteEntry(entry *types.FileEntry) error {
+	normalised := types.FileEntry{
+		PacketType: packetTypeData,
+		Length:     types.FileEntryMinSize + uint32(len(entry.Data)),
+		EntryType:  entry.EntryType,
+		EntryNum:   entry.EntryNum,
+		Data:       entry.Data,
+	}
+	if err := w.write(normalised.Encode()); err != nil {
+		return err
+	}
+	w.entries++
+	return nil
+}
+
+func (w *Writer) EntryCount() uint64 {
+	return w.entries
+}
+
+// Close writes the trailer and flushes the archive, it does not close the underlying writer
+func (w *Writer) Close() error {
+	trailer := binary.BigEndian.AppendUint64(nil, w.entries)
+	w.hasher.Write(trailer)
+	trailer = append(trailer, w.hasher.Sum(nil)...)
+	if _, err := w.w.Write(trailer); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Reader reads entries from an archive.  It implements client.FileEntryIterator so the entries can be parsed with
+// client.ReadParsedProto and server.ReadBatches.
+type Reader struct {
+	f       *os.File
+	r       *bufio.Reader
+	header  Header
+	entries uint64
+
+	// offset of the next entry and the offset the entries end at
+	offset, end int64
+}
+
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func newReader(f *os.File) (*Reader, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < headerSize+trailerSize {
+		return nil, ErrInvalidArchive
+	}
+
+	headerBytes := make([]byte, headerSize)
+	if _, err := f.ReadAt(headerBytes, 0); err != nil {
+		return nil, err
+	}
+	header, err := decodeHeader(headerBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	end := info.Size() - trailerSize
+	count := make([]byte, 8)
+	if _, err := f.ReadAt(count, end); err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		f:       f,
+		header:  header,
+		entries: binary.BigEndian.Uint64(count),
+		end:     end,
+	}
+	if err := r.SetOffset(headerSize); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+func (r *Reader) EntryCount() uint64 {
+	return r.entries
+}
+
+// Verify checks the archive checksum, it does not move the read position
+func (r *Reader) Verify() error {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, io.NewSectionReader(r.f, 0, r.end+8)); err != nil {
+		return err
+	}
+	expected := make([]byte, sha256.Size)
+	if _, err := r.f.ReadAt(expected, r.end+8); err != nil {
+		return err
+	}
+	if !bytes.Equal(hasher.Sum(nil), expected) {
+		return ErrChecksumInvalid
+	}
+	return nil
+}
+
+// Offset returns the position of the next entry, to be used with SetOffset
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+func (r *Reader) SetOffset(offset int64) error {
+	if offset < headerSize || offset > r.end {
+		return fmt.Errorf("offset %d outside of archive entries", offset)
+	}
+	r.offset = offset
+	r.r = bufio.NewReader(io.NewSectionReader(r.f, offset, r.end-offset))
+	return nil
+}
+
+// NextFileEntry returns the next entry in the archive or nil once all entries have been read
+func (r *Reader) NextFileEntry() (*types.FileEntry, error) {
+	if r.offset >= r.end {
+		return nil, nil
+	}
+
+	fixed := make([]byte, types.FileEntryMinSize)
+	if _, err := io.ReadFull(r.r, fixed); err != nil {
+		return nil, fmt.Errorf("read entry header at offset %d: %w", r.offset, err)
+	}
+	length := binary.BigEndian.Uint32(fixed[1:5])
+	if length < types.FileEntryMinSize || int64(length) > r.end-r.offset {
+		return nil, fmt.Errorf("invalid entry length %d at offset %d", length, r.offset)
+	}
+	raw := make([]byte, length)
+	copy(raw, fixed)
+	if _, err := io.ReadFull(r.r, raw[types.FileEntryMinSize:]); err != nil {
+		return nil, fmt.Errorf("read entry at offset %d: %w", r.offset, err)
+	}
+
+	entry, err := types.DecodeFileEntry(raw)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(length)
+	return entry, nil
+}
+
+// GetEntryNumberLimit is only used to stop reading inside a block, the archive signals its end by returning a nil
+// entry so there is no limit
+func (r *Reader) GetEntryNumberLimit() uint64 {
+	return ^uint64(0)
+}
+
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestArchive(t *testing.T, entries []*types.FileEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{FromBatch: 1, ToBatch: 2})
+	require.NoError(t, err)
+	for _, e := range entries {
+		require.NoError(t, w.WriteEntry(e))
+	}
+	require.NoError(t, w.Close())
+
+	path := filepath.Join(t.TempDir(), "batches.dsa")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+	return path
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	entries := []*types.FileEntry{
+		{EntryType: types.BookmarkEntryType, EntryNum: 10, Data: []byte{1, 2, 3}},
+		{EntryType: types.EntryTypeBatchStart, EntryNum: 11, Data: []byte{4}},
+		{EntryType: types.EntryTypeBatchEnd, EntryNum: 12, Data: nil},
+	}
+	path := writeTestArchive(t, entries)
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	require.NoError(t, r.Verify())
+	require.Equal(t, Header{Version: Version, FromBatch: 1, ToBatch: 2}, r.Header())
+	require.Equal(t, uint64(3), r.EntryCount())
+
+	var offsets []int64
+	for _, expected := range entries {
+		offsets = append(offsets, r.Offset())
+		e, err := r.NextFileEntry()
+		require.NoError(t, err)
+		require.Equal(t, expected.EntryType, e.EntryType)
+		require.Equal(t, expected.EntryNum, e.EntryNum)
+		require.Equal(t, len(expected.Data), len(e.Data))
+	}
+	e, err := r.NextFileEntry()
+	require.NoError(t, err)
+	require.Nil(t, e)
+
+	require.NoError(t, r.SetOffset(offsets[1]))
+	e, err = r.NextFileEntry()
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), e.EntryNum)
+}
+
+func TestArchiveChecksum(t *testing.T) {
+	path := writeTestArchive(t, []*types.FileEntry{
+		{EntryType: types.EntryTypeBatchStart, EntryNum: 1, Data: []byte{1, 2, 3, 4}},
+	})
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	raw[headerSize+int(types.FileEntryMinSize)] ^= 0xff
+	require.NoError(t, os.WriteFile(path, raw, 0600))
+
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+	require.ErrorIs(t, r.Verify(), ErrChecksumInvalid)
+
+	notArchive := filepath.Join(t.TempDir(), "junk")
+	require.NoError(t, os.WriteFile(notArchive, bytes.Repeat([]byte{1}, 100), 0600))
+	_, err = Open(notArchive)
+	require.ErrorIs(t, err, ErrInvalidArchive)
+}
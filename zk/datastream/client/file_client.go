@@ -0,0 +1,316 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon/zk/datastream/archive"
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/ledgerwatch/log/v3"
+)
+
+type fileBlockIndex struct {
+	offset int64
+	forkId uint64
+}
+
+// fileArchiveIndex holds the position of every l2 block in an archive.  Verifying and indexing means reading the
+// whole archive so it is done once and shared by every client of the same archive.
+type fileArchiveIndex struct {
+	mtx    sync.Mutex
+	blocks map[uint64]fileBlockIndex
+	// the first and last l2 blocks in the archive
+	firstBlock, lastBlock uint64
+}
+
+// FileClient serves the entries of a datastream archive in the same way StreamClient serves the entries of a live
+// datastream server, so an archive can be synced from whilst offline
+type FileClient struct {
+	ctx   context.Context
+	path  string
+	index *fileArchiveIndex
+
+	mtx    sync.Mutex
+	reader *archive.Reader
+
+	progress             atomic.Uint64
+	stopReadingToChannel atomic.Bool
+
+	entryChan        chan interface{}
+	maxEntryChanSize uint64
+}
+
+func NewFileClient(ctx context.Context, path string, maxEntryChanSize uint64) *FileClient {
+	return &FileClient{
+		ctx:              ctx,
+		path:             path,
+		index:            &fileArchiveIndex{},
+		entryChan:        make(chan interface{}, DefaultEntryChannelSize),
+		maxEntryChanSize: maxEntryChanSize,
+	}
+}
+
+// NewQueryClient returns a client of the same archive with its own reader and entry channel, reusing the index so
+// the archive isn't verified and indexed again
+func (c *FileClient) NewQueryClient(ctx context.Context) *FileClient {
+	return &FileClient{
+		ctx:              ctx,
+		path:             c.path,
+		index:            c.index,
+		entryChan:        make(chan interface{}, DefaultEntryChannelSize),
+		maxEntryChanSize: DefaultEntryChannelSize,
+	}
+}
+
+// Start opens the archive, verifying its checksum and indexing the position of every l2 block in it the first time
+// the archive is opened
+func (c *FileClient) Start() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.reader != nil {
+		return nil
+	}
+
+	reader, err := archive.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("open datastream archive: %w", err)
+	}
+	if err = c.index.build(c.path, reader); err != nil {
+		reader.Close()
+		return err
+	}
+	c.reader = reader
+
+	return nil
+}
+
+func (idx *fileArchiveIndex) build(path string, reader *archive.Reader) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	// the stage stops and restarts the client on every run
+	if idx.blocks != nil {
+		return nil
+	}
+	if err := reader.Verify(); err != nil {
+		return fmt.Errorf("verify datastream archive %s: %w", path, err)
+	}
+
+	blocks := make(map[uint64]fileBlockIndex)
+	var firstBlock, lastBlock, forkId uint64
+	for {
+		offset := reader.Offset()
+		entry, err := reader.NextFileEntry()
+		if err != nil {
+			return fmt.Errorf("index datastream archive: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+
+		switch {
+		case entry.IsBatchStart():
+			batchStart, err := types.UnmarshalBatchStart(entry.Data)
+			if err != nil {
+				return fmt.Errorf("UnmarshalBatchStart: %w", err)
+			}
+			forkId = batchStart.ForkId
+		case entry.IsL2Block():
+			block, err := types.UnmarshalL2Block(entry.Data)
+			if err != nil {
+				return fmt.Errorf("UnmarshalL2Block: %w", err)
+			}
+			if len(blocks) == 0 {
+				firstBlock = block.L2BlockNumber
+			}
+			lastBlock = block.L2BlockNumber
+			blocks[block.L2BlockNumber] = fileBlockIndex{offset: offset, forkId: forkId}
+		}
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("datastream archive %s contains no l2 blocks", path)
+	}
+
+	header := reader.Header()
+	log.Info("[Datastream file client] Opened datastream archive", "path", path, "fromBatch", header.FromBatch, "toBatch", header.ToBatch, "fromBlock", firstBlock, "toBlock", lastBlock)
+
+	idx.blocks = blocks
+	idx.firstBlock = firstBlock
+	idx.lastBlock = lastBlock
+
+	return nil
+}
+
+func (idx *fileArchiveIndex) lookup(blockNum uint64) (fileBlockIndex, bool) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	block, ok := idx.blocks[blockNum]
+	return block, ok
+}
+
+func (idx *fileArchiveIndex) bounds() (firstBlock, lastBlock uint64) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.firstBlock, idx.lastBlock
+}
+
+func (c *FileClient) HandleStart() error {
+	return c.Start()
+}
+
+func (c *FileClient) Stop() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.reader == nil {
+		return nil
+	}
+	err := c.reader.Close()
+	c.reader = nil
+	return err
+}
+
+func (c *FileClient) GetEntryChan() *chan interface{} {
+	return &c.entryChan
+}
+
+func (c *FileClient) GetProgressAtomic() *atomic.Uint64 {
+	return &c.progress
+}
+
+func (c *FileClient) RenewEntryChannel() {
+	c.renewEntryChannel(DefaultEntryChannelSize)
+}
+
+func (c *FileClient) RenewMaxEntryChannel() {
+	c.renewEntryChannel(c.maxEntryChanSize)
+}
+
+func (c *FileClient) renewEntryChannel(size uint64) {
+	close(c.entryChan)
+	for range c.entryChan {
+	}
+	c.entryChan = make(chan interface{}, size)
+}
+
+func (c *FileClient) StopReadingToChannel() {
+	c.stopReadingToChannel.Store(true)
+}
+
+func (c *FileClient) GetL2BlockByNumber(blockNum uint64) (*types.FullL2Block, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.readBlock(blockNum)
+}
+
+func (c *FileClient) GetLatestL2Block() (*types.FullL2Block, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	_, lastBlock := c.index.bounds()
+	return c.readBlock(lastBlock)
+}
+
+func (c *FileClient) readBlock(blockNum uint64) (*types.FullL2Block, error) {
+	if c.reader == nil {
+		return nil, fmt.Errorf("datastream file client not started")
+	}
+	idx, ok := c.index.lookup(blockNum)
+	if !ok {
+		return nil, ErrFileEntryNotFound
+	}
+	if err := c.reader.SetOffset(idx.offset); err != nil {
+		return nil, err
+	}
+	parsed, _, err := ReadParsedProto(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	block, ok := parsed.(*types.FullL2Block)
+	if !ok {
+		return nil, fmt.Errorf("expected l2 block at offset %d, got %T", idx.offset, parsed)
+	}
+	block.ForkId = idx.forkId
+	return block, nil
+}
+
+// ReadAllEntriesToChannel sends the parsed entries following the current progress to the entry channel and a nil
+// entry once the end of the archive is reached.  It reads with its own handle on the archive so that blocks can
+// still be looked up whilst the channel is full.
+func (c *FileClient) ReadAllEntriesToChannel() error {
+	c.mtx.Lock()
+	started := c.reader != nil
+	c.mtx.Unlock()
+	if !started {
+		return fmt.Errorf("datastream file client not started")
+	}
+	c.stopReadingToChannel.Store(false)
+
+	reader, err := archive.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var currentFork uint64
+	progress := c.progress.Load()
+	firstBlock, lastBlock := c.index.bounds()
+	switch {
+	case progress+1 < firstBlock && (progress != 0 || firstBlock > 1):
+		return fmt.Errorf("datastream archive starts at block %d, the node is at block %d", firstBlock, progress)
+	case progress+1 > lastBlock:
+		// nothing newer in the archive
+		return c.sendEntry(nil)
+	case progress != 0:
+		idx, ok := c.index.lookup(progress + 1)
+		if !ok {
+			return fmt.Errorf("block %d missing from datastream archive", progress+1)
+		}
+		if err = reader.SetOffset(idx.offset); err != nil {
+			return err
+		}
+		currentFork = idx.forkId
+	}
+
+	for {
+		if c.stopReadingToChannel.Load() {
+			return nil
+		}
+
+		parsed, _, err := ReadParsedProto(reader)
+		if err != nil {
+			return err
+		}
+
+		switch entry := parsed.(type) {
+		case nil:
+			return c.sendEntry(nil)
+		case *types.BookmarkProto, *types.L2BlockEndProto:
+			continue
+		case *types.BatchStart:
+			currentFork = entry.ForkId
+		case *types.FullL2Block:
+			entry.ForkId = currentFork
+			log.Trace("[Datastream file client] writing block to channel", "blockNumber", entry.L2BlockNumber, "batchNumber", entry.BatchNumber)
+		}
+
+		if err = c.sendEntry(parsed); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *FileClient) sendEntry(entry interface{}) error {
+	select {
+	case c.entryChan <- entry:
+		return nil
+	case <-c.ctx.Done():
+		return fmt.Errorf("context done - stopping")
+	}
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/zk/datastream/archive"
+	"github.com/ledgerwatch/erigon/zk/datastream/proto/github.com/0xPolygonHermez/zkevm-node/state/datastream"
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/stretchr/testify/require"
+)
+
+type testArchiveBuilder struct {
+	t       *testing.T
+	w       *archive.Writer
+	nextNum uint64
+}
+
+func (b *testArchiveBuilder) add(entryType types.EntryType, entry interface{ Marshal() ([]byte, error) }) {
+	b.t.Helper()
+	data, err := entry.Marshal()
+	require.NoError(b.t, err)
+	require.NoError(b.t, b.w.WriteEntry(&types.FileEntry{EntryType: entryType, EntryNum: b.nextNum, Data: data}))
+	b.nextNum++
+}
+
+func (b *testArchiveBuilder) batch(number, forkId uint64, blocks ...uint64) {
+	b.add(types.BookmarkEntryType, types.NewBookmarkProto(number, datastream.BookmarkType_BOOKMARK_TYPE_BATCH))
+	b.add(types.EntryTypeBatchStart, (&types.BatchStartProto{BatchStart: &datastream.BatchStart{Number: number, ForkId: forkId}}))
+	for _, block := range blocks {
+		b.add(types.BookmarkEntryType, types.NewBookmarkProto(block, datastream.BookmarkType_BOOKMARK_TYPE_L2_BLOCK))
+		b.add(types.EntryTypeL2Block, (&types.L2BlockProto{L2Block: &datastream.L2Block{Number: block, BatchNumber: number}}))
+		b.add(types.EntryTypeL2Tx, (&types.TxProto{Transaction: &datastream.Transaction{L2BlockNumber: block, Encoded: []byte{0x01}}}))
+		b.add(types.EntryTypeL2BlockEnd, (&types.L2BlockEndProto{Number: block}))
+	}
+	b.add(types.EntryTypeBatchEnd, (&types.BatchEndProto{BatchEnd: &datastream.BatchEnd{Number: number}}))
+}
+
+func createTestArchive(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "batches.dsa")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w, err := archive.NewWriter(f, archive.Header{FromBatch: 1, ToBatch: 2})
+	require.NoError(t, err)
+	b := &testArchiveBuilder{t: t, w: w}
+	b.batch(1, 8, 1, 2)
+	b.batch(2, 9, 3)
+	require.NoError(t, w.Close())
+
+	return path
+}
+
+func readEntriesFromChannel(t *testing.T, c *FileClient) []interface{} {
+	t.Helper()
+	require.NoError(t, c.ReadAllEntriesToChannel())
+	var entries []interface{}
+	for {
+		entry := <-c.entryChan
+		entries = append(entries, entry)
+		if entry == nil {
+			return entries
+		}
+	}
+}
+
+func TestFileClientBlocks(t *testing.T) {
+	c := NewFileClient(context.Background(), createTestArchive(t), DefaultEntryChannelSize)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	latest, err := c.GetLatestL2Block()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), latest.L2BlockNumber)
+	require.Equal(t, uint64(9), latest.ForkId)
+	require.Len(t, latest.L2Txs, 1)
+
+	block, err := c.GetL2BlockByNumber(2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), block.BatchNumber)
+	require.Equal(t, uint64(8), block.ForkId)
+
+	_, err = c.GetL2BlockByNumber(4)
+	require.ErrorIs(t, err, ErrFileEntryNotFound)
+}
+
+func TestFileClientReadAllEntriesToChannel(t *testing.T) {
+	c := NewFileClient(context.Background(), createTestArchive(t), DefaultEntryChannelSize)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	entries := readEntriesFromChannel(t, c)
+	require.Len(t, entries, 8)
+	require.IsType(t, &types.BatchStart{}, entries[0])
+	require.Equal(t, uint64(1), entries[1].(*types.FullL2Block).L2BlockNumber)
+	require.Equal(t, uint64(8), entries[1].(*types.FullL2Block).ForkId)
+	require.IsType(t, &types.BatchEnd{}, entries[3])
+	require.Equal(t, uint64(9), entries[5].(*types.FullL2Block).ForkId)
+
+	// resuming part way through only sends the blocks after the progress
+	c.GetProgressAtomic().Store(2)
+	entries = readEntriesFromChannel(t, c)
+	require.Len(t, entries, 3)
+	require.Equal(t, uint64(3), entries[0].(*types.FullL2Block).L2BlockNumber)
+	require.Equal(t, uint64(9), entries[0].(*types.FullL2Block).ForkId)
+
+	// nothing left to send
+	c.GetProgressAtomic().Store(3)
+	require.Equal(t, []interface{}{nil}, readEntriesFromChannel(t, c))
+}
+
+func TestFileClientRejectsCorruptArchive(t *testing.T) {
+	path := createTestArchive(t)
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	raw[len(raw)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, raw, 0600))
+
+	c := NewFileClient(context.Background(), path, DefaultEntryChannelSize)
+	require.ErrorIs(t, c.Start(), archive.ErrChecksumInvalid)
+}
+
+func TestFileClientQueryClientSharesIndex(t *testing.T) {
+	c := NewFileClient(context.Background(), createTestArchive(t), DefaultEntryChannelSize)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	query := c.NewQueryClient(context.Background())
+	require.Same(t, c.index, query.index)
+	require.NoError(t, query.Start())
+
+	latest, err := query.GetLatestL2Block()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), latest.L2BlockNumber)
+
+	// stopping the query client leaves the syncing client's reader open
+	require.NoError(t, query.Stop())
+	block, err := c.GetL2BlockByNumber(1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), block.ForkId)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ledgerwatch/erigon/zk/datastream/archive"
+	"github.com/ledgerwatch/erigon/zk/datastream/proto/github.com/0xPolygonHermez/zkevm-node/state/datastream"
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+)
+
+// ExportBatches writes the raw stream entries for batches start to end inclusive to an archive, starting at the
+// batch bookmark of start and finishing with the batch end entry of end.  Returns the number of entries written.
+func ExportBatches(stream StreamServer, w io.Writer, start, end uint64) (uint64, error) {
+	if end < start {
+		return 0, fmt.Errorf("invalid batch range %d-%d", start, end)
+	}
+
+	bookmark := types.NewBookmarkProto(start, datastream.BookmarkType_BOOKMARK_TYPE_BATCH)
+	marshalled, err := bookmark.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	entryNum, err := stream.GetBookmark(marshalled)
+	if err != nil {
+		return 0, fmt.Errorf("batch %d not found in the stream: %w", start, err)
+	}
+
+	aw, err := archive.NewWriter(w, archive.Header{FromBatch: start, ToBatch: end})
+	if err != nil {
+		return 0, err
+	}
+
+	iterator := newDataStreamServerIterator(stream, entryNum)
+	for {
+		entry, err := iterator.NextFileEntry()
+		if err != nil {
+			return 0, err
+		}
+		if entry == nil {
+			return 0, fmt.Errorf("stream ended before the end of batch %d", end)
+		}
+		if err = aw.WriteEntry(entry); err != nil {
+			return 0, err
+		}
+
+		if entry.IsBatchEnd() {
+			batchEnd, err := types.UnmarshalBatchEnd(entry.Data)
+			if err != nil {
+				return 0, err
+			}
+			if batchEnd.Number == end {
+				break
+			}
+		}
+	}
+
+	if err = aw.Close(); err != nil {
+		return 0, err
+	}
+
+	return aw.EntryCount(), nil
+}
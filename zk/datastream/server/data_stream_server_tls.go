@@ -0,0 +1,277 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/metrics"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const tlsHandshakeTimeout = 10 * time.Second
+
+var (
+	dsTLSAccepted            = metrics.GetOrCreateCounter(`datastream_tls_connections_total{result="accepted"}`)
+	dsTLSRejectedHandshake   = metrics.GetOrCreateCounter(`datastream_tls_connections_total{result="rejected_handshake"}`)
+	dsTLSRejectedNoCert      = metrics.GetOrCreateCounter(`datastream_tls_connections_total{result="rejected_no_certificate"}`)
+	dsTLSRejectedFingerprint = metrics.GetOrCreateCounter(`datastream_tls_connections_total{result="rejected_fingerprint"}`)
+	dsTLSRejectedStopped     = metrics.GetOrCreateCounter(`datastream_tls_connections_total{result="rejected_stopped"}`)
+)
+
+// TLSConfig configures the TLS listener placed in front of the data stream server
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS, client certificates must be signed by one of the CAs in the file
+	ClientCAFile string
+	// AllowedFingerprints is a list of hex encoded SHA-256 fingerprints of client certificates that may connect.
+	// When set clients must present a certificate even if no client CA is configured.
+	AllowedFingerprints []string
+}
+
+func (c *TLSConfig) Enabled() bool {
+	return c != nil && c.CertFile != ""
+}
+
+// listenerStreamServer is a stream server that can serve the clients accepted by a listener it is given
+type listenerStreamServer interface {
+	StreamServer
+	StartWithListener(ln net.Listener) error
+}
+
+// tlsStreamServer exposes a stream server on port over TLS.  Clients are authenticated by the TLS listener and then
+// handed to the stream server in process, the plaintext stream is not served on any port.
+type tlsStreamServer struct {
+	listenerStreamServer
+	port      uint16
+	tlsConfig *tls.Config
+	allowed   map[string]struct{}
+
+	ln       net.Listener
+	conns    *connListener
+	stopOnce sync.Once
+}
+
+// CreateTLSStreamServer wraps a stream server so that it is served on port over TLS, the stream server is started
+// by the wrapper and does not listen on its own port
+func (f *ZkEVMDataStreamServerFactory) CreateTLSStreamServer(streamServer StreamServer, port uint16, cfg *TLSConfig) (StreamServer, error) {
+	inner, ok := streamServer.(listenerStreamServer)
+	if !ok {
+		return nil, fmt.Errorf("datastream TLS is not supported by stream server %T", streamServer)
+	}
+
+	tlsConfig, allowed, err := cfg.build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsStreamServer{
+		listenerStreamServer: inner,
+		port:                 port,
+		tlsConfig:            tlsConfig,
+		allowed:              allowed,
+	}, nil
+}
+
+func (c *TLSConfig) build() (*tls.Config, map[string]struct{}, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load datastream TLS key pair: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read datastream client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in datastream client CA file %s", c.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	var allowed map[string]struct{}
+	if len(c.AllowedFingerprints) > 0 {
+		allowed = make(map[string]struct{}, len(c.AllowedFingerprints))
+		for _, fp := range c.AllowedFingerprints {
+			normalised := normaliseFingerprint(fp)
+			if len(normalised) != sha256.Size*2 {
+				return nil, nil, fmt.Errorf("invalid datastream client certificate fingerprint %q", fp)
+			}
+			allowed[normalised] = struct{}{}
+		}
+		if tlsConfig.ClientAuth == tls.NoClientCert {
+			// certificates are pinned by fingerprint so there is no chain to verify
+			tlsConfig.ClientAuth = tls.RequireAnyClientCert
+		}
+	}
+
+	return tlsConfig, allowed, nil
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 fingerprint of a certificate as used in the allowlist
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func normaliseFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+}
+
+func (s *tlsStreamServer) Start() error {
+	ln, err := tls.Listen("tcp", ":"+strconv.Itoa(int(s.port)), s.tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to start datastream TLS listener on port %d: %w", s.port, err)
+	}
+
+	s.ln = ln
+	s.conns = newConnListener(ln.Addr())
+	if err = s.listenerStreamServer.StartWithListener(s.conns); err != nil {
+		ln.Close()
+		return err
+	}
+
+	log.Info("[dataStream] TLS listener started", "port", s.port, "mTLS", s.tlsConfig.ClientAuth != tls.NoClientCert, "allowlist", len(s.allowed))
+	go s.acceptConnections()
+
+	return nil
+}
+
+// Stop closes the TLS listener, which ends acceptConnections, and stops handing clients to the stream server.  Clients
+// already connected are left to the stream server.
+func (s *tlsStreamServer) Stop() {
+	s.stopOnce.Do(func() {
+		if s.ln == nil {
+			return
+		}
+		if err := s.ln.Close(); err != nil {
+			log.Warn("[dataStream] failed to close the TLS listener", "err", err)
+		}
+		s.conns.Close()
+	})
+}
+
+func (s *tlsStreamServer) acceptConnections() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Warn("[dataStream] TLS accept failed", "err", err)
+			continue
+		}
+		go s.handleConnection(conn.(*tls.Conn))
+	}
+}
+
+func (s *tlsStreamServer) handleConnection(conn *tls.Conn) {
+	remote := conn.RemoteAddr().String()
+
+	if err := s.authenticate(conn); err != nil {
+		log.Warn("[dataStream] rejected client connection", "remote", remote, "reason", err)
+		conn.Close()
+		return
+	}
+
+	if !s.conns.hand(conn) {
+		dsTLSRejectedStopped.Inc()
+		conn.Close()
+		return
+	}
+
+	dsTLSAccepted.Inc()
+	log.Debug("[dataStream] client connected over TLS", "remote", remote)
+}
+
+func (s *tlsStreamServer) authenticate(conn *tls.Conn) error {
+	_ = conn.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		dsTLSRejectedHandshake.Inc()
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if s.allowed == nil {
+		return nil
+	}
+
+	peerCerts := conn.ConnectionState().PeerCertificates
+	if len(peerCerts) == 0 {
+		dsTLSRejectedNoCert.Inc()
+		return errors.New("no client certificate presented")
+	}
+	fingerprint := CertificateFingerprint(peerCerts[0])
+	if _, ok := s.allowed[fingerprint]; !ok {
+		dsTLSRejectedFingerprint.Inc()
+		return fmt.Errorf("client certificate %s is not in the allowlist", fingerprint)
+	}
+
+	return nil
+}
+
+// connListener is an in-process listener the TLS listener hands authenticated connections to, the stream server
+// accepts its clients from it
+type connListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:   addr,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// hand waits for the stream server to accept conn, it returns false if the listener is closed first
+func (l *connListener) hand(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.closed:
+		return false
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-data-streamer/datastreamer"
+	"github.com/stretchr/testify/require"
+)
+
+// echoStreamServer stands in for the stream server, it echoes back what its clients send
+type echoStreamServer struct {
+	StreamServer
+	stopped chan struct{}
+}
+
+func newEchoStreamServer() *echoStreamServer {
+	return &echoStreamServer{stopped: make(chan struct{})}
+}
+
+func (s *echoStreamServer) StartWithListener(ln net.Listener) error {
+	go func() {
+		defer close(s.stopped)
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return nil
+}
+
+func generateTestCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writeTestKeyPair(t *testing.T, cert tls.Certificate) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyDer, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func freePort(t *testing.T) uint16 {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return uint16(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestTLSStreamServerFingerprintAllowlist(t *testing.T) {
+	serverCert := generateTestCert(t)
+	allowedClient := generateTestCert(t)
+	otherClient := generateTestCert(t)
+	certFile, keyFile := writeTestKeyPair(t, serverCert)
+
+	port := freePort(t)
+	srv, err := NewZkEVMDataStreamServerFactory().CreateTLSStreamServer(newEchoStreamServer(), port, &TLSConfig{
+		CertFile:            certFile,
+		KeyFile:             keyFile,
+		AllowedFingerprints: []string{CertificateFingerprint(allowedClient.Leaf)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.(*tlsStreamServer).Stop()
+
+	dial := func(clientCert *tls.Certificate) ([]byte, error) {
+		cfg := &tls.Config{InsecureSkipVerify: true}
+		if clientCert != nil {
+			cfg.Certificates = []tls.Certificate{*clientCert}
+		}
+		conn, err := tls.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))), cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err = conn.Write([]byte("ping")); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		return buf, err
+	}
+
+	res, err := dial(&allowedClient)
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), res)
+
+	_, err = dial(&otherClient)
+	require.Error(t, err)
+
+	_, err = dial(nil)
+	require.Error(t, err)
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	certFile, keyFile := writeTestKeyPair(t, generateTestCert(t))
+	factory := NewZkEVMDataStreamServerFactory()
+
+	_, err := factory.CreateTLSStreamServer(nil, 6900, &TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.Error(t, err, "stream server must be able to serve a listener")
+
+	_, err = factory.CreateTLSStreamServer(newEchoStreamServer(), 6900, &TLSConfig{CertFile: certFile, KeyFile: keyFile, AllowedFingerprints: []string{"abcd"}})
+	require.Error(t, err, "fingerprint must be a sha256 hash")
+
+	require.Equal(t, "aabb", normaliseFingerprint(" AA:BB "))
+}
+
+func TestTLSStreamServerServesStreamServer(t *testing.T) {
+	serverCert := generateTestCert(t)
+	certFile, keyFile := writeTestKeyPair(t, serverCert)
+	factory := NewZkEVMDataStreamServerFactory()
+	port := freePort(t)
+
+	// the stream server is given the public port too, the TLS listener could not bind it had the stream server done so
+	streamServer, err := factory.CreateStreamServer(port, 1, datastreamer.StreamType(1), filepath.Join(t.TempDir(), "data-stream"), time.Second, time.Minute, time.Minute, nil)
+	require.NoError(t, err)
+	srv, err := factory.CreateTLSStreamServer(streamServer, port, &TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.(*tlsStreamServer).Stop()
+
+	require.NoError(t, srv.StartAtomicOp())
+	_, err = srv.AddStreamEntry(1, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, srv.CommitAtomicOp())
+
+	// the header command is answered by the stream server through the TLS listener
+	conn, err := tls.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	command := make([]byte, 0, 16)
+	command = binary.BigEndian.AppendUint64(command, uint64(datastreamer.CmdHeader))
+	command = binary.BigEndian.AppendUint64(command, 1)
+	_, err = conn.Write(command)
+	require.NoError(t, err)
+
+	result := make([]byte, 9)
+	_, err = io.ReadFull(conn, result)
+	require.NoError(t, err)
+	require.Equal(t, byte(datastreamer.PtResult), result[0])
+}
+
+func TestTLSStreamServerStop(t *testing.T) {
+	certFile, keyFile := writeTestKeyPair(t, generateTestCert(t))
+	port := freePort(t)
+	upstream := newEchoStreamServer()
+	srv, err := NewZkEVMDataStreamServerFactory().CreateTLSStreamServer(upstream, port, &TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+
+	tlsSrv := srv.(*tlsStreamServer)
+	tlsSrv.Stop()
+	tlsSrv.Stop()
+
+	// the stream server stops accepting clients and the public port is released
+	select {
+	case <-upstream.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream server still accepting clients")
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))))
+	require.NoError(t, err)
+	ln.Close()
+}
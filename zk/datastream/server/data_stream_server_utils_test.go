@@ -0,0 +1,78 @@
+package server
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/require"
+
+	eritypes "github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/zk/datastream/types"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
+)
+
+func TestTransactionProtoRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	chainId := big.NewInt(1000)
+	signer := eritypes.LatestSignerForChainID(chainId)
+	to := libcommon.HexToAddress("0x1275fbb540c8efc58b812ba83b0d0b8b9917ae98")
+	accessList := types2.AccessList{{Address: to, StorageKeys: []libcommon.Hash{libcommon.HexToHash("0x01")}}}
+
+	txs := map[string]eritypes.Transaction{
+		"legacy": eritypes.NewTransaction(1, to, uint256.NewInt(1), 21000, uint256.NewInt(1000000000), nil),
+		"access list": &eritypes.AccessListTx{
+			LegacyTx: eritypes.LegacyTx{
+				CommonTx: eritypes.CommonTx{Nonce: 2, Gas: 30000, To: &to, Value: uint256.NewInt(0)},
+				GasPrice: uint256.NewInt(1000000000),
+			},
+			ChainID:    uint256.MustFromBig(chainId),
+			AccessList: accessList,
+		},
+		"dynamic fee": &eritypes.DynamicFeeTransaction{
+			CommonTx:   eritypes.CommonTx{Nonce: 3, Gas: 30000, To: &to, Value: uint256.NewInt(0), Data: []byte{1, 2, 3}},
+			ChainID:    uint256.MustFromBig(chainId),
+			Tip:        uint256.NewInt(1),
+			FeeCap:     uint256.NewInt(2000000000),
+			AccessList: accessList,
+		},
+	}
+
+	for name, tx := range txs {
+		t.Run(name, func(t *testing.T) {
+			signed, err := eritypes.SignTx(tx, *signer, key)
+			require.NoError(t, err)
+
+			txProto, err := newTransactionProto(128, libcommon.HexToHash("0xabc"), signed, 10)
+			require.NoError(t, err)
+			data, err := txProto.Marshal()
+			require.NoError(t, err)
+			l2Tx, err := types.UnmarshalTx(data)
+			require.NoError(t, err)
+
+			decoded, pct, err := zktx.DecodeTx(l2Tx.Encoded, l2Tx.EffectiveGasPricePercentage, uint64(chain.ForkId14TypedTxs))
+			require.NoError(t, err)
+			require.Equal(t, uint8(128), pct)
+			require.Equal(t, signed.Hash(), decoded.Hash())
+
+			sender, err := decoded.Sender(*signer)
+			require.NoError(t, err)
+			require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), sender)
+
+			// the batch l2 data rebuilt from the streamed transaction decodes back to the same transaction
+			l2Data, err := zktx.TransactionToL2Data(decoded, uint16(chain.ForkId14TypedTxs), pct)
+			require.NoError(t, err)
+			batch, err := zktx.DecodeBatchL2Blocks(l2Data, uint64(chain.ForkId14TypedTxs))
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+			require.Len(t, batch[0].Transactions, 1)
+			require.Equal(t, signed.Hash(), batch[0].Transactions[0].Hash())
+			require.Equal(t, []uint8{pct}, batch[0].EffectiveGasPricePercentages)
+		})
+	}
+}
@@ -220,16 +220,7 @@ func (d *DbDataRetriever) populateBlocksAndTransactions(batch *rpcTypes.Batch, b
 					return err
 				}
 
-				l2TxHash, err := zktx.ComputeL2TxHash(
-					tx.GetChainID().ToBig(),
-					tx.GetValue(),
-					tx.GetPrice(),
-					tx.GetNonce(),
-					tx.GetGas(),
-					tx.GetTo(),
-					&rpcTx.From,
-					tx.GetData(),
-				)
+				l2TxHash, err := zktx.ComputeL2TxHashForTx(tx, &rpcTx.From)
 				if err != nil {
 					return err
 				}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"sort"
 
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
 
 	"encoding/json"
@@ -55,6 +57,15 @@ const BATCH_ENDS = "batch_ends"                                         // batch
 const WITNESS_CACHE = "witness_cache"                                   // block number -> witness for 1 block
 const BAD_TX_HASHES = "bad_tx_hashes"                                   // tx hash -> integer counter
 const BAD_TX_HASHES_LOOKUP = "bad_tx_hashes_lookup"                     // timestamp -> tx hash
+const L1_BLOCK_HASHES = "l1_block_hashes"                               // l1 block number -> l1 block hash of blocks with sequences or verifications
+const L1_INFO_TREE_BLOCK_HASHES = "l1_info_tree_block_hashes"           // l1 block number -> l1 block hash of blocks with info tree updates
+const BLOCK_L1_GAS_PRICES = "block_l1_gas_prices"                       // block number -> l1 gas price seen by the sequencer when the block was built
+const BRIDGE_DEPOSITS = "bridge_deposits"                               // deposit count -> deposit emitted by the l2 bridge contract
+const BRIDGE_EXIT_LEAVES = "bridge_exit_leaves"                         // deposit count -> local exit tree leaf of the deposit
+const BRIDGE_DEPOSITS_BY_ADDRESS = "bridge_deposits_by_address"         // destination address, deposit count -> deposit count
+const BRIDGE_CLAIMS = "bridge_claims"                                   // global index -> claim emitted by the l2 bridge contract
+const BRIDGE_BLOCK_CLAIMS = "bridge_block_claims"                       // block number, global index -> global index
+const BRIDGE_DEPOSIT_GAPS = "bridge_deposit_gaps"                       // first missing deposit count -> deposit count indexed after the gap
 
 var HermezDbTables = []string{
 	L1VERIFICATIONS,
@@ -94,6 +105,15 @@ var HermezDbTables = []string{
 	BAD_TX_HASHES,
 	BAD_TX_HASHES_LOOKUP,
 	WITNESS_CACHE,
+	L1_BLOCK_HASHES,
+	L1_INFO_TREE_BLOCK_HASHES,
+	BLOCK_L1_GAS_PRICES,
+	BRIDGE_DEPOSITS,
+	BRIDGE_EXIT_LEAVES,
+	BRIDGE_DEPOSITS_BY_ADDRESS,
+	BRIDGE_CLAIMS,
+	BRIDGE_BLOCK_CLAIMS,
+	BRIDGE_DEPOSIT_GAPS,
 }
 
 type HermezDb struct {
@@ -534,6 +554,38 @@ func (db *HermezDbReader) GetLatestVerification() (*types.L1BatchInfo, error) {
 	return db.getLatest(L1VERIFICATIONS)
 }
 
+// GetSequencesFromBatch returns, in L1 order, every sequence ending at or after batchNo
+func (db *HermezDbReader) GetSequencesFromBatch(batchNo uint64) ([]*types.L1BatchInfo, error) {
+	c, err := db.tx.Cursor(L1SEQUENCES)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var sequences []*types.L1BatchInfo
+	for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		l1BlockNo, batch, err := SplitKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if batch < batchNo {
+			continue
+		}
+
+		sequence, err := parseL1BatchInfo(l1BlockNo, batch, v)
+		if err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	return sequences, nil
+}
+
 func (db *HermezDbReader) getLatest(table string) (*types.L1BatchInfo, error) {
 	c, err := db.tx.Cursor(table)
 	if err != nil {
@@ -677,6 +729,138 @@ func (db *HermezDb) TruncateVerifications(l2BlockNo uint64) error {
 	return nil
 }
 
+// DeleteL1SequencesAbove deletes the sequences found in l1 blocks higher than the given one
+func (db *HermezDb) DeleteL1SequencesAbove(l1BlockNo uint64) error {
+	return db.deleteL1BatchInfoAbove(L1SEQUENCES, l1BlockNo)
+}
+
+// DeleteL1VerificationsAbove deletes the verifications found in l1 blocks higher than the given one
+func (db *HermezDb) DeleteL1VerificationsAbove(l1BlockNo uint64) error {
+	return db.deleteL1BatchInfoAbove(L1VERIFICATIONS, l1BlockNo)
+}
+
+func (db *HermezDb) deleteL1BatchInfoAbove(table string, l1BlockNo uint64) error {
+	c, err := db.tx.Cursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var keys [][]byte
+	for k, _, err := c.Seek(ConcatKey(l1BlockNo+1, 0)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		keys = append(keys, common.CopyBytes(k))
+	}
+
+	for _, k := range keys {
+		if err = db.tx.Delete(table, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *HermezDb) WriteL1BlockHash(l1BlockNo uint64, l1BlockHash common.Hash) error {
+	return db.tx.Put(L1_BLOCK_HASHES, Uint64ToBytes(l1BlockNo), l1BlockHash.Bytes())
+}
+
+// GetPreviousL1BlockHash returns the hash of the highest l1 block lower than the given one that has sequences or
+// verifications recorded
+func (db *HermezDbReader) GetPreviousL1BlockHash(l1BlockNo uint64) (blockNo uint64, hash common.Hash, found bool, err error) {
+	return db.getPreviousL1BlockHash(L1_BLOCK_HASHES, l1BlockNo)
+}
+
+func (db *HermezDb) DeleteL1BlockHashesAbove(l1BlockNo uint64) error {
+	return db.deleteL1BlockHashesAbove(L1_BLOCK_HASHES, l1BlockNo)
+}
+
+func (db *HermezDb) WriteL1InfoTreeBlockHash(l1BlockNo uint64, l1BlockHash common.Hash) error {
+	return db.tx.Put(L1_INFO_TREE_BLOCK_HASHES, Uint64ToBytes(l1BlockNo), l1BlockHash.Bytes())
+}
+
+// GetPreviousL1InfoTreeBlockHash returns the hash of the highest l1 block lower than the given one that has l1 info
+// tree updates recorded
+func (db *HermezDbReader) GetPreviousL1InfoTreeBlockHash(l1BlockNo uint64) (blockNo uint64, hash common.Hash, found bool, err error) {
+	return db.getPreviousL1BlockHash(L1_INFO_TREE_BLOCK_HASHES, l1BlockNo)
+}
+
+func (db *HermezDb) DeleteL1InfoTreeBlockHashesAbove(l1BlockNo uint64) error {
+	return db.deleteL1BlockHashesAbove(L1_INFO_TREE_BLOCK_HASHES, l1BlockNo)
+}
+
+// GetPreviousRecordedL1BlockHash returns the hash of the highest l1 block lower than the given one that has
+// sequences, verifications or l1 info tree updates recorded
+func (db *HermezDbReader) GetPreviousRecordedL1BlockHash(l1BlockNo uint64) (blockNo uint64, hash common.Hash, found bool, err error) {
+	blockNo, hash, found, err = db.GetPreviousL1BlockHash(l1BlockNo)
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	infoTreeBlockNo, infoTreeHash, infoTreeFound, err := db.GetPreviousL1InfoTreeBlockHash(l1BlockNo)
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	if infoTreeFound && (!found || infoTreeBlockNo > blockNo) {
+		return infoTreeBlockNo, infoTreeHash, true, nil
+	}
+
+	return blockNo, hash, found, nil
+}
+
+func (db *HermezDbReader) getPreviousL1BlockHash(table string, l1BlockNo uint64) (uint64, common.Hash, bool, error) {
+	c, err := db.tx.Cursor(table)
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	defer c.Close()
+
+	// position on the first block at or above the given one, the previous entry is the one we want
+	k, _, err := c.Seek(Uint64ToBytes(l1BlockNo))
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	var v []byte
+	if k == nil {
+		k, v, err = c.Last()
+	} else {
+		k, v, err = c.Prev()
+	}
+	if err != nil {
+		return 0, common.Hash{}, false, err
+	}
+	if k == nil {
+		return 0, common.Hash{}, false, nil
+	}
+
+	return BytesToUint64(k), common.BytesToHash(v), true, nil
+}
+
+func (db *HermezDb) deleteL1BlockHashesAbove(table string, l1BlockNo uint64) error {
+	c, err := db.tx.Cursor(table)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var keys [][]byte
+	for k, _, err := c.Seek(Uint64ToBytes(l1BlockNo + 1)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		keys = append(keys, common.CopyBytes(k))
+	}
+
+	for _, k := range keys {
+		if err = db.tx.Delete(table, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (db *HermezDb) WriteBlockBatch(l2BlockNo, batchNo uint64) error {
 	// first store the block -> batch record
 	err := db.tx.Put(BLOCKBATCHES, Uint64ToBytes(l2BlockNo), Uint64ToBytes(batchNo))
@@ -1154,6 +1338,24 @@ func (db *HermezDbReader) GetEffectiveGasPricePercentage(txHash common.Hash) (ui
 	return BytesToUint8(data), nil
 }
 
+func (db *HermezDb) WriteBlockL1GasPrice(l2BlockNo uint64, l1GasPrice *big.Int) error {
+	return db.tx.Put(BLOCK_L1_GAS_PRICES, Uint64ToBytes(l2BlockNo), l1GasPrice.Bytes())
+}
+
+// GetBlockL1GasPrice returns nil when the block was not built by this node
+func (db *HermezDbReader) GetBlockL1GasPrice(l2BlockNo uint64) (*big.Int, error) {
+	data, err := db.tx.GetOne(BLOCK_L1_GAS_PRICES, Uint64ToBytes(l2BlockNo))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func (db *HermezDb) DeleteBlockL1GasPrices(fromBlockNum, toBlockNum uint64) error {
+	return db.deleteFromBucketWithUintKeysRange(BLOCK_L1_GAS_PRICES, fromBlockNum, toBlockNum)
+}
+
 func (db *HermezDb) DeleteEffectiveGasPricePercentages(txHashes *[]common.Hash) error {
 	for _, txHash := range *txHashes {
 		err := db.tx.Delete(TX_PRICE_PERCENTAGE, txHash.Bytes())
@@ -1261,6 +1463,48 @@ func (db *HermezDbReader) GetL1InfoTreeUpdate(idx uint64) (*types.L1InfoTreeUpda
 	return update, nil
 }
 
+// GetLastL1InfoTreeUpdateFromL1Block returns the last l1 info tree update of the first l1 block at or after the given
+// one that has any, nil if there are none yet
+func (db *HermezDbReader) GetLastL1InfoTreeUpdateFromL1Block(l1BlockNo uint64) (*types.L1InfoTreeUpdate, error) {
+	latest, err := db.GetLatestL1InfoTreeUpdate()
+	if err != nil || latest == nil || latest.BlockNumber < l1BlockNo {
+		return nil, err
+	}
+
+	// updates are indexed in l1 block order
+	var searchErr error
+	index := sort.Search(int(latest.Index+1), func(i int) bool {
+		update, err := db.GetL1InfoTreeUpdate(uint64(i))
+		if err != nil || update == nil {
+			if searchErr == nil {
+				searchErr = fmt.Errorf("l1 info tree update %d: %v", i, err)
+			}
+			return true
+		}
+		return update.BlockNumber >= l1BlockNo
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	update, err := db.GetL1InfoTreeUpdate(uint64(index))
+	if err != nil {
+		return nil, err
+	}
+	for update.Index < latest.Index {
+		next, err := db.GetL1InfoTreeUpdate(update.Index + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || next.BlockNumber != update.BlockNumber {
+			break
+		}
+		update = next
+	}
+
+	return update, nil
+}
+
 func (db *HermezDbReader) GetLatestL1InfoTreeUpdate() (*types.L1InfoTreeUpdate, error) {
 	cursor, err := db.tx.Cursor(L1_INFO_TREE_UPDATES)
 	if err != nil {
@@ -1360,6 +1604,55 @@ func (db *HermezDbReader) GetLatestBlockL1InfoTreeIndexProgress() (uint64, uint6
 	return BytesToUint64(k), BytesToUint64(v), nil
 }
 
+// GetL1InfoTreeIndexAtBlock returns the highest l1 info tree index used by the blocks up to and including the given
+// one, found is false when none of them used an index
+func (db *HermezDbReader) GetL1InfoTreeIndexAtBlock(blockNumber uint64) (index uint64, found bool, err error) {
+	// the progress is only written at save points, so start from the last one at or below the block and check the
+	// blocks after it
+	progress, err := db.tx.Cursor(BLOCK_L1_INFO_TREE_INDEX_PROGRESS)
+	if err != nil {
+		return 0, false, err
+	}
+	defer progress.Close()
+
+	fromBlock := uint64(0)
+	k, v, err := progress.Seek(Uint64ToBytes(blockNumber))
+	if err != nil {
+		return 0, false, err
+	}
+	if k == nil {
+		k, v, err = progress.Last()
+	} else if BytesToUint64(k) > blockNumber {
+		k, v, err = progress.Prev()
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if k != nil {
+		index, found, fromBlock = BytesToUint64(v), true, BytesToUint64(k)+1
+	}
+
+	c, err := db.tx.Cursor(BLOCK_L1_INFO_TREE_INDEX)
+	if err != nil {
+		return 0, false, err
+	}
+	defer c.Close()
+
+	for k, v, err := c.Seek(Uint64ToBytes(fromBlock)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return 0, false, err
+		}
+		if BytesToUint64(k) > blockNumber {
+			break
+		}
+		if blockIndex := BytesToUint64(v); !found || blockIndex > index {
+			index, found = blockIndex, true
+		}
+	}
+
+	return index, found, nil
+}
+
 func (db *HermezDb) DeleteBlockL1InfoTreeIndexesProgress(fromBlockNum, toBlockNum uint64) error {
 	return db.deleteFromBucketWithUintKeysRange(BLOCK_L1_INFO_TREE_INDEX_PROGRESS, fromBlockNum, toBlockNum)
 }
@@ -1611,11 +1904,41 @@ func (db *HermezDbReader) GetAllL1InfoTreeLeaves() ([]common.Hash, error) {
 	return leaves, nil
 }
 
+func (db *HermezDbReader) GetL1InfoTreeLeaf(l1Index uint64) (common.Hash, bool, error) {
+	v, err := db.tx.GetOne(L1_INFO_LEAVES, Uint64ToBytes(l1Index))
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	return common.BytesToHash(v), v != nil, nil
+}
+
+// GetL1InfoTreeLeavesFrom returns the leaves from the given index onwards, erroring if an index is missing
+func (db *HermezDbReader) GetL1InfoTreeLeavesFrom(fromIndex uint64) ([]common.Hash, error) {
+	c, err := db.tx.Cursor(L1_INFO_LEAVES)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var leaves []common.Hash
+	for k, v, err := c.Seek(Uint64ToBytes(fromIndex)); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if index := BytesToUint64(k); index != fromIndex+uint64(len(leaves)) {
+			return nil, fmt.Errorf("l1 info tree leaf %d is missing, found leaf %d", fromIndex+uint64(len(leaves)), index)
+		}
+		leaves = append(leaves, common.BytesToHash(v))
+	}
+
+	return leaves, nil
+}
+
 func (db *HermezDb) WriteL1InfoTreeRoot(hash common.Hash, index uint64) error {
 	return db.tx.Put(L1_INFO_ROOTS, hash.Bytes(), Uint64ToBytes(index))
 }
 
-func (db *HermezDb) GetL1InfoTreeIndexByRoot(hash common.Hash) (uint64, bool, error) {
+func (db *HermezDbReader) GetL1InfoTreeIndexByRoot(hash common.Hash) (uint64, bool, error) {
 	data, err := db.tx.GetOne(L1_INFO_ROOTS, hash.Bytes())
 	if err != nil {
 		return 0, false, err
@@ -1643,6 +1966,88 @@ func (db *HermezDbReader) GetL1InfoTreeIndexToRoots() (map[uint64]common.Hash, e
 	return indexToRoot, nil
 }
 
+// TruncateL1InfoTreeAbove deletes the l1 info tree updates found in l1 blocks higher than the given one, along with
+// their leaves, their global exit roots and the roots of the tree they produced.  It returns the lowest index removed,
+// l2 blocks that used it or a later one were built on updates l1 no longer has.
+func (db *HermezDb) TruncateL1InfoTreeAbove(l1BlockNo uint64) (lowestRemovedIndex uint64, removed bool, err error) {
+	c, err := db.tx.Cursor(L1_INFO_TREE_UPDATES)
+	if err != nil {
+		return 0, false, err
+	}
+	defer c.Close()
+
+	var updates []*types.L1InfoTreeUpdate
+	for k, v, err := c.Last(); k != nil; k, v, err = c.Prev() {
+		if err != nil {
+			return 0, false, err
+		}
+		update := &types.L1InfoTreeUpdate{}
+		update.Unmarshall(v)
+		if update.BlockNumber <= l1BlockNo {
+			break
+		}
+		updates = append(updates, update)
+	}
+
+	if len(updates) == 0 {
+		return 0, false, nil
+	}
+
+	for _, update := range updates {
+		if err = db.tx.Delete(L1_INFO_TREE_UPDATES, Uint64ToBytes(update.Index)); err != nil {
+			return 0, false, err
+		}
+		if err = db.tx.Delete(L1_INFO_TREE_UPDATES_BY_GER, update.GER.Bytes()); err != nil {
+			return 0, false, err
+		}
+		if err = db.tx.Delete(GLOBAL_EXIT_ROOTS, update.GER.Bytes()); err != nil {
+			return 0, false, err
+		}
+		if err = db.tx.Delete(L1_INFO_LEAVES, Uint64ToBytes(update.Index)); err != nil {
+			return 0, false, err
+		}
+	}
+
+	// updates are walked from the highest index down so the last one removed is the lowest
+	lowestRemovedIndex = updates[len(updates)-1].Index
+	indexToRoot, err := db.GetL1InfoTreeIndexToRoots()
+	if err != nil {
+		return 0, false, err
+	}
+	for index, root := range indexToRoot {
+		if index < lowestRemovedIndex {
+			continue
+		}
+		if err = db.tx.Delete(L1_INFO_ROOTS, root.Bytes()); err != nil {
+			return 0, false, err
+		}
+	}
+
+	return lowestRemovedIndex, true, nil
+}
+
+// GetFirstBlockUsingL1InfoTreeIndex returns the lowest l2 block that used the given l1 info tree index or a later one
+func (db *HermezDbReader) GetFirstBlockUsingL1InfoTreeIndex(l1InfoTreeIndex uint64) (blockNo uint64, found bool, err error) {
+	c, err := db.tx.Cursor(BLOCK_L1_INFO_TREE_INDEX)
+	if err != nil {
+		return 0, false, err
+	}
+	defer c.Close()
+
+	// the index used only ever grows with the block number so the walk down stops at the first block before it
+	for k, v, err := c.Last(); k != nil; k, v, err = c.Prev() {
+		if err != nil {
+			return 0, false, err
+		}
+		if BytesToUint64(v) < l1InfoTreeIndex {
+			break
+		}
+		blockNo, found = BytesToUint64(k), true
+	}
+
+	return blockNo, found, nil
+}
+
 func (db *HermezDbReader) GetForkIdByBlockNum(blockNum uint64) (uint64, error) {
 	blockbatch, err := db.GetBatchNoByL2Block(blockNum)
 	if err != nil {
@@ -2009,3 +2414,299 @@ func (db *HermezDb) TruncateWitnessCacheBelow(below uint64) error {
 
 	return nil
 }
+
+func bridgeAddressKey(address common.Address, depositCount uint32) []byte {
+	return append(address.Bytes(), Uint64ToBytes(uint64(depositCount))...)
+}
+
+func bridgeBlockClaimKey(blockNum uint64, globalIndex *big.Int) []byte {
+	return append(Uint64ToBytes(blockNum), common.BigToHash(globalIndex).Bytes()...)
+}
+
+func (db *HermezDb) WriteBridgeDeposit(deposit *types.BridgeDeposit, leaf common.Hash) error {
+	depositCount := Uint64ToBytes(uint64(deposit.DepositCount))
+	if err := db.tx.Put(BRIDGE_DEPOSITS, depositCount, deposit.Marshall()); err != nil {
+		return err
+	}
+	if err := db.tx.Put(BRIDGE_EXIT_LEAVES, depositCount, leaf.Bytes()); err != nil {
+		return err
+	}
+	return db.tx.Put(BRIDGE_DEPOSITS_BY_ADDRESS, bridgeAddressKey(deposit.DestinationAddress, deposit.DepositCount), depositCount)
+}
+
+// GetBridgeDeposit returns nil if the deposit has not been indexed
+func (db *HermezDbReader) GetBridgeDeposit(depositCount uint32) (*types.BridgeDeposit, error) {
+	data, err := db.tx.GetOne(BRIDGE_DEPOSITS, Uint64ToBytes(uint64(depositCount)))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	deposit := &types.BridgeDeposit{}
+	if err = deposit.Unmarshall(data); err != nil {
+		return nil, err
+	}
+	return deposit, nil
+}
+
+// GetBridgeDepositsByAddress returns the deposits to the destination address ordered by deposit count
+func (db *HermezDbReader) GetBridgeDepositsByAddress(address common.Address) ([]*types.BridgeDeposit, error) {
+	c, err := db.tx.Cursor(BRIDGE_DEPOSITS_BY_ADDRESS)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var deposits []*types.BridgeDeposit
+	for k, v, err := c.Seek(address.Bytes()); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if common.BytesToAddress(k[:length.Addr]) != address {
+			break
+		}
+		deposit, err := db.GetBridgeDeposit(uint32(BytesToUint64(v)))
+		if err != nil {
+			return nil, err
+		}
+		if deposit == nil {
+			return nil, fmt.Errorf("deposit %d indexed for %s is missing", BytesToUint64(v), address)
+		}
+		deposits = append(deposits, deposit)
+	}
+
+	return deposits, nil
+}
+
+func (db *HermezDbReader) GetBridgeExitLeaf(depositCount uint32) (common.Hash, bool, error) {
+	v, err := db.tx.GetOne(BRIDGE_EXIT_LEAVES, Uint64ToBytes(uint64(depositCount)))
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	return common.BytesToHash(v), v != nil, nil
+}
+
+// GetBridgeExitLeavesFrom returns the leaves of the local exit tree from the given deposit count onwards, up to the
+// first deposit that is missing
+func (db *HermezDbReader) GetBridgeExitLeavesFrom(depositCount uint32) ([]common.Hash, error) {
+	c, err := db.tx.Cursor(BRIDGE_EXIT_LEAVES)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var leaves []common.Hash
+	for k, v, err := c.Seek(Uint64ToBytes(uint64(depositCount))); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if BytesToUint64(k) != uint64(depositCount)+uint64(len(leaves)) {
+			break
+		}
+		leaves = append(leaves, common.BytesToHash(v))
+	}
+
+	return leaves, nil
+}
+
+// GetBridgeDepositCountAtBlock returns the number of leaves in the local exit tree after the deposits of the blocks up
+// to and including the given one
+func (db *HermezDbReader) GetBridgeDepositCountAtBlock(blockNum uint64) (uint32, error) {
+	depositCount, err := db.GetBridgeDepositCount()
+	if err != nil {
+		return 0, err
+	}
+
+	c, err := db.tx.Cursor(BRIDGE_DEPOSITS)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	// deposit counts only grow with the block number, so find the lowest count whose deposit, or the next indexed one
+	// when it is missing, was made after the block
+	var searchErr error
+	count := sort.Search(int(depositCount), func(i int) bool {
+		k, v, err := c.Seek(Uint64ToBytes(uint64(i)))
+		if err == nil && k == nil {
+			err = fmt.Errorf("no bridge deposit from %d", i)
+		}
+		deposit := &types.BridgeDeposit{}
+		if err == nil {
+			err = deposit.Unmarshall(v)
+		}
+		if err != nil {
+			if searchErr == nil {
+				searchErr = err
+			}
+			return true
+		}
+		return deposit.BlockNumber > blockNum
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+
+	return uint32(count), nil
+}
+
+// GetBridgeDepositCount returns the number of leaves in the local exit tree, the deposit count of the next deposit
+func (db *HermezDbReader) GetBridgeDepositCount() (uint32, error) {
+	c, err := db.tx.Cursor(BRIDGE_EXIT_LEAVES)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	k, _, err := c.Last()
+	if err != nil {
+		return 0, err
+	}
+	if k == nil {
+		return 0, nil
+	}
+
+	return uint32(BytesToUint64(k)) + 1, nil
+}
+
+// WriteBridgeDepositGap records that the deposits from the first deposit count up to, but not including, the second
+// could not be indexed
+func (db *HermezDb) WriteBridgeDepositGap(from, to uint32) error {
+	return db.tx.Put(BRIDGE_DEPOSIT_GAPS, Uint64ToBytes(uint64(from)), Uint64ToBytes(uint64(to)))
+}
+
+// GetFirstBridgeDepositGap returns the lowest range of deposits that could not be indexed, every local exit tree
+// holding them is incomplete
+func (db *HermezDbReader) GetFirstBridgeDepositGap() (from, to uint32, found bool, err error) {
+	c, err := db.tx.Cursor(BRIDGE_DEPOSIT_GAPS)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer c.Close()
+
+	k, v, err := c.First()
+	if err != nil || k == nil {
+		return 0, 0, false, err
+	}
+
+	return uint32(BytesToUint64(k)), uint32(BytesToUint64(v)), true, nil
+}
+
+func (db *HermezDb) WriteBridgeClaim(claim *types.BridgeClaim) error {
+	globalIndex := common.BigToHash(claim.GlobalIndex).Bytes()
+	if err := db.tx.Put(BRIDGE_CLAIMS, globalIndex, claim.Marshall()); err != nil {
+		return err
+	}
+	return db.tx.Put(BRIDGE_BLOCK_CLAIMS, bridgeBlockClaimKey(claim.BlockNumber, claim.GlobalIndex), globalIndex)
+}
+
+// GetBridgeClaim returns nil if no claim of the global index has been indexed
+func (db *HermezDbReader) GetBridgeClaim(globalIndex *big.Int) (*types.BridgeClaim, error) {
+	data, err := db.tx.GetOne(BRIDGE_CLAIMS, common.BigToHash(globalIndex).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	claim := &types.BridgeClaim{}
+	if err = claim.Unmarshall(data); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// TruncateBridgeAbove removes the deposits and claims emitted in blocks above the given block
+func (db *HermezDb) TruncateBridgeAbove(blockNum uint64) error {
+	c, err := db.tx.Cursor(BRIDGE_DEPOSITS)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// deposit counts only grow with the block number so walk down from the last deposit
+	var removed []*types.BridgeDeposit
+	for k, v, err := c.Last(); k != nil; k, v, err = c.Prev() {
+		if err != nil {
+			return err
+		}
+		deposit := &types.BridgeDeposit{}
+		if err = deposit.Unmarshall(v); err != nil {
+			return err
+		}
+		if deposit.BlockNumber <= blockNum {
+			break
+		}
+		removed = append(removed, deposit)
+	}
+
+	for _, deposit := range removed {
+		depositCount := Uint64ToBytes(uint64(deposit.DepositCount))
+		if err = db.tx.Delete(BRIDGE_DEPOSITS, depositCount); err != nil {
+			return err
+		}
+		if err = db.tx.Delete(BRIDGE_EXIT_LEAVES, depositCount); err != nil {
+			return err
+		}
+		if err = db.tx.Delete(BRIDGE_DEPOSITS_BY_ADDRESS, bridgeAddressKey(deposit.DestinationAddress, deposit.DepositCount)); err != nil {
+			return err
+		}
+	}
+
+	// a gap is gone along with the deposit indexed after it
+	depositCount, err := db.GetBridgeDepositCount()
+	if err != nil {
+		return err
+	}
+	gc, err := db.tx.Cursor(BRIDGE_DEPOSIT_GAPS)
+	if err != nil {
+		return err
+	}
+	defer gc.Close()
+
+	var gaps [][]byte
+	for k, v, err := gc.Last(); k != nil; k, v, err = gc.Prev() {
+		if err != nil {
+			return err
+		}
+		if uint32(BytesToUint64(v)) < depositCount {
+			break
+		}
+		gaps = append(gaps, common.CopyBytes(k))
+	}
+
+	for _, k := range gaps {
+		if err = db.tx.Delete(BRIDGE_DEPOSIT_GAPS, k); err != nil {
+			return err
+		}
+	}
+
+	cc, err := db.tx.Cursor(BRIDGE_BLOCK_CLAIMS)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+
+	var keys, globalIndexes [][]byte
+	for k, v, err := cc.Seek(Uint64ToBytes(blockNum + 1)); k != nil; k, v, err = cc.Next() {
+		if err != nil {
+			return err
+		}
+		keys = append(keys, common.CopyBytes(k))
+		globalIndexes = append(globalIndexes, common.CopyBytes(v))
+	}
+
+	for i, k := range keys {
+		if err = db.tx.Delete(BRIDGE_BLOCK_CLAIMS, k); err != nil {
+			return err
+		}
+		if err = db.tx.Delete(BRIDGE_CLAIMS, globalIndexes[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/big"
 	"testing"
 
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/zk/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -90,6 +92,32 @@ func TestGetSequenceByBatchNo(t *testing.T) {
 	assert.Equal(t, common.HexToHash("0xdefg"), info.StateRoot)
 }
 
+func TestGetSequencesFromBatch(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+	db := NewHermezDb(tx)
+
+	require.NoError(t, db.WriteSequence(1, 1001, common.HexToHash("0xabc"), common.HexToHash("0xabc"), common.HexToHash("0x1")))
+	require.NoError(t, db.WriteSequence(5, 1005, common.HexToHash("0xdef"), common.HexToHash("0xdef"), common.HexToHash("0x2")))
+	require.NoError(t, db.WriteSequence(9, 1009, common.HexToHash("0x123"), common.HexToHash("0x123"), common.HexToHash("0x3")))
+
+	sequences, err := db.GetSequencesFromBatch(1003)
+	require.NoError(t, err)
+	require.Len(t, sequences, 2)
+	assert.Equal(t, uint64(1005), sequences[0].BatchNo)
+	assert.Equal(t, common.HexToHash("0x2"), sequences[0].L1InfoRoot)
+	assert.Equal(t, uint64(9), sequences[1].L1BlockNo)
+	assert.Equal(t, uint64(1009), sequences[1].BatchNo)
+
+	sequences, err = db.GetSequencesFromBatch(0)
+	require.NoError(t, err)
+	require.Len(t, sequences, 3)
+
+	sequences, err = db.GetSequencesFromBatch(1010)
+	require.NoError(t, err)
+	require.Empty(t, sequences)
+}
+
 func TestGetVerificationByL1BlockAndBatchNo(t *testing.T) {
 	tx, cleanup := GetDbTx()
 	defer cleanup()
@@ -679,3 +707,395 @@ func TestTruncateBadTxs(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteL1DataAbove(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	for i := uint64(1); i <= 10; i++ {
+		require.NoError(t, db.WriteSequence(i*10, i, common.HexToHash("0xabc"), common.HexToHash("0xabc"), common.HexToHash("0x0")))
+		require.NoError(t, db.WriteVerification(i*10, i, common.HexToHash("0xabc"), common.HexToHash("0xabc")))
+		require.NoError(t, db.WriteL1BlockHash(i*10, common.BigToHash(big.NewInt(int64(i)))))
+	}
+
+	require.NoError(t, db.DeleteL1SequencesAbove(55))
+	require.NoError(t, db.DeleteL1VerificationsAbove(50))
+	require.NoError(t, db.DeleteL1BlockHashesAbove(50))
+
+	seq, err := db.GetLatestSequence()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), seq.BatchNo)
+	ver, err := db.GetLatestVerification()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), ver.BatchNo)
+
+	blockNo, hash, found, err := db.GetPreviousL1BlockHash(math.MaxUint64)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(50), blockNo)
+	assert.Equal(t, common.BigToHash(big.NewInt(5)), hash)
+
+	blockNo, _, found, err = db.GetPreviousL1BlockHash(50)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(40), blockNo)
+
+	_, _, found, err = db.GetPreviousL1BlockHash(10)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestTruncateL1InfoTreeAbove(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	for i := uint64(0); i < 5; i++ {
+		update := &types.L1InfoTreeUpdate{
+			Index:       i,
+			GER:         common.BigToHash(big.NewInt(int64(i + 1))),
+			BlockNumber: 100 + i,
+		}
+		require.NoError(t, db.WriteL1InfoTreeUpdate(update))
+		require.NoError(t, db.WriteL1InfoTreeUpdateToGer(update))
+		require.NoError(t, db.WriteL1InfoTreeLeaf(i, common.BigToHash(big.NewInt(int64(i+10)))))
+		require.NoError(t, db.WriteL1InfoTreeRoot(common.BigToHash(big.NewInt(int64(i+20))), i))
+	}
+
+	require.NoError(t, db.WriteGlobalExitRoot(common.BigToHash(big.NewInt(4))))
+	lowestRemoved, removed, err := db.TruncateL1InfoTreeAbove(102)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.Equal(t, uint64(3), lowestRemoved)
+
+	latest, err := db.GetLatestL1InfoTreeUpdate()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), latest.Index)
+
+	byGer, err := db.GetL1InfoTreeUpdateByGer(common.BigToHash(big.NewInt(4)))
+	require.NoError(t, err)
+	assert.Nil(t, byGer)
+	gerWritten, err := db.CheckGlobalExitRootWritten(common.BigToHash(big.NewInt(4)))
+	require.NoError(t, err)
+	assert.False(t, gerWritten)
+
+	leaves, err := db.GetAllL1InfoTreeLeaves()
+	require.NoError(t, err)
+	assert.Len(t, leaves, 3)
+
+	roots, err := db.GetL1InfoTreeIndexToRoots()
+	require.NoError(t, err)
+	assert.Len(t, roots, 3)
+	assert.Equal(t, common.BigToHash(big.NewInt(22)), roots[2])
+
+	// nothing above the highest update, nothing to remove
+	_, removed, err = db.TruncateL1InfoTreeAbove(200)
+	require.NoError(t, err)
+	assert.False(t, removed)
+	leaves, err = db.GetAllL1InfoTreeLeaves()
+	require.NoError(t, err)
+	assert.Len(t, leaves, 3)
+}
+
+func TestGetPreviousRecordedL1BlockHash(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+	require.NoError(t, db.WriteL1BlockHash(10, common.BigToHash(big.NewInt(10))))
+	require.NoError(t, db.WriteL1BlockHash(30, common.BigToHash(big.NewInt(30))))
+	require.NoError(t, db.WriteL1InfoTreeBlockHash(20, common.BigToHash(big.NewInt(20))))
+	require.NoError(t, db.WriteL1InfoTreeBlockHash(40, common.BigToHash(big.NewInt(40))))
+
+	for _, tc := range []struct {
+		before   uint64
+		expected uint64
+	}{{math.MaxUint64, 40}, {40, 30}, {30, 20}, {20, 10}} {
+		blockNo, hash, found, err := db.GetPreviousRecordedL1BlockHash(tc.before)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, tc.expected, blockNo)
+		assert.Equal(t, common.BigToHash(new(big.Int).SetUint64(tc.expected)), hash)
+	}
+
+	_, _, found, err := db.GetPreviousRecordedL1BlockHash(10)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestGetFirstBlockUsingL1InfoTreeIndex(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+	for blockNo, index := range map[uint64]uint64{1: 1, 3: 2, 5: 2, 8: 4} {
+		require.NoError(t, db.WriteBlockL1InfoTreeIndex(blockNo, index))
+	}
+
+	blockNo, found, err := db.GetFirstBlockUsingL1InfoTreeIndex(2)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(3), blockNo)
+
+	blockNo, found, err = db.GetFirstBlockUsingL1InfoTreeIndex(3)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(8), blockNo)
+
+	_, found, err = db.GetFirstBlockUsingL1InfoTreeIndex(5)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestL1InfoTreeLeavesAndIndexAtBlock(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	for i := uint64(0); i < 4; i++ {
+		require.NoError(t, db.WriteL1InfoTreeLeaf(i, common.BigToHash(big.NewInt(int64(i+10)))))
+	}
+
+	leaf, found, err := db.GetL1InfoTreeLeaf(2)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, common.BigToHash(big.NewInt(12)), leaf)
+	_, found, err = db.GetL1InfoTreeLeaf(4)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	leaves, err := db.GetL1InfoTreeLeavesFrom(1)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Hash{common.BigToHash(big.NewInt(11)), common.BigToHash(big.NewInt(12)), common.BigToHash(big.NewInt(13))}, leaves)
+
+	require.NoError(t, db.WriteL1InfoTreeLeaf(6, common.BigToHash(big.NewInt(16))))
+	_, err = db.GetL1InfoTreeLeavesFrom(1)
+	assert.ErrorContains(t, err, "leaf 4 is missing")
+
+	_, found, err = db.GetL1InfoTreeIndexAtBlock(10)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// progress is written at save points while the per block index is written for every block that uses one
+	require.NoError(t, db.WriteBlockL1InfoTreeIndex(3, 1))
+	require.NoError(t, db.WriteBlockL1InfoTreeIndex(5, 2))
+	require.NoError(t, db.WriteBlockL1InfoTreeIndexProgress(5, 2))
+	require.NoError(t, db.WriteBlockL1InfoTreeIndex(8, 3))
+	require.NoError(t, db.WriteBlockL1InfoTreeIndex(9, 2))
+
+	for block, expected := range map[uint64]uint64{3: 1, 4: 1, 5: 2, 7: 2, 8: 3, 9: 3, 100: 3} {
+		index, found, err := db.GetL1InfoTreeIndexAtBlock(block)
+		require.NoError(t, err)
+		assert.True(t, found, "block %d", block)
+		assert.Equal(t, expected, index, "block %d", block)
+	}
+	_, found, err = db.GetL1InfoTreeIndexAtBlock(2)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestBlockL1GasPrices(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	for i := uint64(1); i <= 5; i++ {
+		require.NoError(t, db.WriteBlockL1GasPrice(i, big.NewInt(int64(i*1000))))
+	}
+
+	price, err := db.GetBlockL1GasPrice(3)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(3000), price)
+
+	require.NoError(t, db.DeleteBlockL1GasPrices(3, 5))
+
+	price, err = db.GetBlockL1GasPrice(3)
+	require.NoError(t, err)
+	assert.Nil(t, price)
+	price, err = db.GetBlockL1GasPrice(2)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2000), price)
+}
+
+func TestBridgeDepositsAndClaims(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	count, err := db.GetBridgeDepositCount()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), count)
+
+	alice := common.HexToAddress("0xa")
+	bob := common.HexToAddress("0xb")
+	destinations := []common.Address{alice, bob, alice, alice}
+	for i, destination := range destinations {
+		deposit := &types.BridgeDeposit{
+			DestinationAddress: destination,
+			Amount:             big.NewInt(int64(i + 1)),
+			Metadata:           []byte{},
+			DepositCount:       uint32(i),
+			BlockNumber:        uint64(10 + i),
+		}
+		require.NoError(t, db.WriteBridgeDeposit(deposit, common.BigToHash(big.NewInt(int64(100+i)))))
+	}
+	for i := uint64(0); i < 3; i++ {
+		require.NoError(t, db.WriteBridgeClaim(&types.BridgeClaim{
+			GlobalIndex: new(big.Int).Lsh(big.NewInt(int64(i+1)), 64),
+			Amount:      big.NewInt(1),
+			BlockNumber: 11 + i,
+		}))
+	}
+
+	count, err = db.GetBridgeDepositCount()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4), count)
+
+	deposit, err := db.GetBridgeDeposit(1)
+	require.NoError(t, err)
+	assert.Equal(t, bob, deposit.DestinationAddress)
+	assert.Equal(t, uint64(11), deposit.BlockNumber)
+	deposit, err = db.GetBridgeDeposit(10)
+	require.NoError(t, err)
+	assert.Nil(t, deposit)
+
+	deposits, err := db.GetBridgeDepositsByAddress(alice)
+	require.NoError(t, err)
+	require.Len(t, deposits, 3)
+	for i, depositCount := range []uint32{0, 2, 3} {
+		assert.Equal(t, depositCount, deposits[i].DepositCount)
+	}
+
+	leaves, err := db.GetBridgeExitLeavesFrom(0)
+	require.NoError(t, err)
+	require.Len(t, leaves, 4)
+	assert.Equal(t, common.BigToHash(big.NewInt(102)), leaves[2])
+
+	claim, err := db.GetBridgeClaim(new(big.Int).Lsh(big.NewInt(2), 64))
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+	assert.Equal(t, uint64(12), claim.BlockNumber)
+	claim, err = db.GetBridgeClaim(big.NewInt(2))
+	require.NoError(t, err)
+	assert.Nil(t, claim)
+
+	count, err = db.GetBridgeDepositCountAtBlock(9)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), count)
+	count, err = db.GetBridgeDepositCountAtBlock(12)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), count)
+	count, err = db.GetBridgeDepositCountAtBlock(20)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4), count)
+	leaves, err = db.GetBridgeExitLeavesFrom(3)
+	require.NoError(t, err)
+	assert.Equal(t, []common.Hash{common.BigToHash(big.NewInt(103))}, leaves)
+
+	// deposits 2 and 3 and the claims of blocks 12 and 13 are above block 11
+	require.NoError(t, db.TruncateBridgeAbove(11))
+
+	leaves, err = db.GetBridgeExitLeavesFrom(0)
+	require.NoError(t, err)
+	assert.Len(t, leaves, 2)
+	count, err = db.GetBridgeDepositCount()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), count)
+	count, err = db.GetBridgeDepositCountAtBlock(20)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), count)
+	deposits, err = db.GetBridgeDepositsByAddress(alice)
+	require.NoError(t, err)
+	require.Len(t, deposits, 1)
+	deposits, err = db.GetBridgeDepositsByAddress(bob)
+	require.NoError(t, err)
+	require.Len(t, deposits, 1)
+	deposit, err = db.GetBridgeDeposit(2)
+	require.NoError(t, err)
+	assert.Nil(t, deposit)
+
+	claim, err = db.GetBridgeClaim(new(big.Int).Lsh(big.NewInt(1), 64))
+	require.NoError(t, err)
+	assert.NotNil(t, claim)
+	claim, err = db.GetBridgeClaim(new(big.Int).Lsh(big.NewInt(2), 64))
+	require.NoError(t, err)
+	assert.Nil(t, claim)
+}
+
+func TestBridgeDepositGaps(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	_, _, found, err := db.GetFirstBridgeDepositGap()
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// deposits 2 to 4 are missing and 5 is indexed in block 12, 8 and 9 are missing and 10 is indexed in block 13
+	for i, depositCount := range []uint32{0, 1, 5, 10} {
+		deposit := &types.BridgeDeposit{Amount: big.NewInt(1), DepositCount: depositCount, BlockNumber: uint64(10 + i)}
+		require.NoError(t, db.WriteBridgeDeposit(deposit, common.Hash{}))
+	}
+	require.NoError(t, db.WriteBridgeDepositGap(8, 10))
+	require.NoError(t, db.WriteBridgeDepositGap(2, 5))
+
+	from, to, found, err := db.GetFirstBridgeDepositGap()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint32(2), from)
+	assert.Equal(t, uint32(5), to)
+
+	// the leaves of a gap come before the deposit indexed after it
+	for blockNum, expected := range map[uint64]uint32{11: 2, 12: 6, 13: 11} {
+		count, err := db.GetBridgeDepositCountAtBlock(blockNum)
+		require.NoError(t, err)
+		assert.Equal(t, expected, count, blockNum)
+	}
+
+	require.NoError(t, db.TruncateBridgeAbove(12))
+	from, _, found, err = db.GetFirstBridgeDepositGap()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint32(2), from)
+	has, err := tx.Has(BRIDGE_DEPOSIT_GAPS, Uint64ToBytes(8))
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	require.NoError(t, db.TruncateBridgeAbove(11))
+	_, _, found, err = db.GetFirstBridgeDepositGap()
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestGetLastL1InfoTreeUpdateFromL1Block(t *testing.T) {
+	tx, cleanup := GetDbTx()
+	defer cleanup()
+
+	db := NewHermezDb(tx)
+
+	update, err := db.GetLastL1InfoTreeUpdateFromL1Block(1)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+
+	for i, l1BlockNo := range []uint64{100, 105, 105, 110} {
+		require.NoError(t, db.WriteL1InfoTreeUpdate(&types.L1InfoTreeUpdate{Index: uint64(i), BlockNumber: l1BlockNo}))
+	}
+
+	for l1BlockNo, expected := range map[uint64]uint64{1: 0, 100: 0, 101: 2, 105: 2, 106: 3, 110: 3} {
+		update, err = db.GetLastL1InfoTreeUpdateFromL1Block(l1BlockNo)
+		require.NoError(t, err)
+		require.NotNil(t, update, l1BlockNo)
+		assert.Equal(t, expected, update.Index, l1BlockNo)
+	}
+
+	update, err = db.GetLastL1InfoTreeUpdateFromL1Block(111)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+}
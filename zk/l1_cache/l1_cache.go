@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
-	"errors"
-
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
 	"github.com/ledgerwatch/log/v3"
@@ -19,29 +20,35 @@ import (
 const (
 	bucketName   = "Cache"
 	expiryBucket = "Expiry"
-)
 
-// methods we don't cache
-var methodsToIgnore = map[string]struct{}{}
-
-// methods we configure expiry for
-var methodsToExpire = map[string]time.Duration{
-	//"eth_getBlockByNumber": 1 * time.Minute, // example here but currently we don't want any methods to expire
-}
+	defaultFinalizedPollInterval = time.Minute
+)
 
-// params that trigger expiration
-var paramsToExpire = map[string]struct{}{
-	"latest":    {},
-	"finalized": {},
+type Config struct {
+	DbPath string
+	Port   uint
+	// ChainID namespaces the cache for requests that don't pass a chainid param, the node passes its L2 chain id
+	ChainID uint64
+	// Upstreams are tried in turn after the endpoint passed with a request fails, or from the first when none is
+	Upstreams []string
+	// MethodTTLs replace the default ttl of responses that may still change, 0 stops them from being cached
+	MethodTTLs            map[string]time.Duration
+	FinalizedPollInterval time.Duration
 }
 
 type L1Cache struct {
-	server *http.Server
-	db     kv.RwDB
+	server    *http.Server
+	db        kv.RwDB
+	chainID   uint64
+	policies  map[string]methodPolicy
+	upstreams *upstreams
+	stats     *stats
+	// finalized is the latest finalized L1 block, responses about blocks up to it never change
+	finalized atomic.Uint64
 }
 
-func NewL1Cache(ctx context.Context, dbPath string, port uint) (*L1Cache, error) {
-	db := mdbx.NewMDBX(log.New()).Path(dbPath).MustOpen()
+func NewL1Cache(ctx context.Context, cfg Config) (*L1Cache, error) {
+	db := mdbx.NewMDBX(log.New()).Path(cfg.DbPath).MustOpen()
 
 	tx, err := db.BeginRw(ctx)
 	if err != nil {
@@ -59,36 +66,83 @@ func NewL1Cache(ctx context.Context, dbPath string, port uint) (*L1Cache, error)
 		return nil, err
 	}
 
-	http.HandleFunc("/", handleRequest(db))
-	addr := fmt.Sprintf(":%d", port)
-	server := &http.Server{
+	c := &L1Cache{
+		db:        db,
+		chainID:   cfg.ChainID,
+		policies:  policiesWithTTLs(cfg.MethodTTLs),
+		upstreams: newUpstreams(cfg.Upstreams),
+		stats:     newStats(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", c.handleStats)
+	mux.HandleFunc("/", c.handleRequest)
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	c.server = &http.Server{
 		Addr:           addr,
-		Handler:        nil,
+		Handler:        mux,
 		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+		WriteTimeout:   upstreamTimeout + 10*time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
 
 	go func() {
-		log.Info("Starting L1 Cache Server on port:", "port", port)
-		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Info("Starting L1 Cache Server on port:", "port", cfg.Port, "upstreams", len(c.upstreams.endpoints))
+		if err := c.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 			log.Error("L1 Cache Server stopped", "error", err)
 		}
 	}()
 
+	if len(c.upstreams.endpoints) > 0 {
+		interval := cfg.FinalizedPollInterval
+		if interval == 0 {
+			interval = defaultFinalizedPollInterval
+		}
+		go c.pollFinalized(ctx, interval)
+	}
+
 	go func() {
 		<-ctx.Done()
 		log.Info("Shutting down L1 Cache Server...")
-		if err := server.Shutdown(context.Background()); err != nil {
+		if err := c.server.Shutdown(context.Background()); err != nil {
 			log.Error("Failed to shutdown L1 Cache Server", "error", err)
 		}
 		db.Close()
 	}()
 
-	return &L1Cache{
-		server: server,
-		db:     db,
-	}, nil
+	return c, nil
+}
+
+func (c *L1Cache) pollFinalized(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		finalized, err := c.upstreams.finalizedBlock(ctx)
+		if err != nil {
+			log.Warn("L1 cache failed to get the finalized block", "err", err)
+		} else {
+			c.finalized.Store(finalized)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *L1Cache) Stats() Stats {
+	methods, ratio := c.stats.methodStats()
+	upstreams := make([]UpstreamStats, 0, len(c.upstreams.endpoints))
+	for _, e := range c.upstreams.endpoints {
+		upstreams = append(upstreams, UpstreamStats{Url: redactedUrl(e.url), Requests: e.requests.Load(), Failures: e.failures.Load()})
+	}
+	return Stats{
+		FinalizedBlock: c.finalized.Load(),
+		HitRatio:       ratio,
+		Methods:        methods,
+		Upstreams:      upstreams,
+	}
 }
 
 func fetchFromCache(tx kv.RwTx, key string) ([]byte, bool) {
@@ -157,113 +211,238 @@ func generateCacheKey(chainID string, body []byte) (string, error) {
 	return fmt.Sprintf("%s_%s", chainID, modifiedBody), nil
 }
 
-func handleRequest(db kv.RwDB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		endpoint := r.URL.Query().Get("endpoint")
-		chainID := r.URL.Query().Get("chainid")
-		if endpoint == "" || chainID == "" {
-			http.Error(w, "Missing endpoint or chainid parameter", http.StatusBadRequest)
-			return
-		}
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
 
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+func isBatch(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// withID returns a cached or upstream response with the id of the request it answers
+func withID(response []byte, id json.RawMessage) ([]byte, error) {
+	var res rpcResponse
+	if err := json.Unmarshal(response, &res); err != nil {
+		return nil, err
+	}
+	res.ID = id
+	return json.Marshal(res)
+}
+
+// handleRequest serves single and batched JSON-RPC requests, answering what it can from the cache and forwarding the
+// rest upstream in one request
+func (c *L1Cache) handleRequest(w http.ResponseWriter, r *http.Request) {
+	endpoint := r.URL.Query().Get("endpoint")
+	chainID := r.URL.Query().Get("chainid")
+	if chainID == "" && c.chainID != 0 {
+		chainID = strconv.FormatUint(c.chainID, 10)
+	}
+	if chainID == "" || (endpoint == "" && len(c.upstreams.endpoints) == 0) {
+		http.Error(w, "Missing endpoint or chainid parameter", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+		return
+	}
+	defer r.Body.Close()
+
+	batch := isBatch(body)
+	var raws []json.RawMessage
+	if batch {
+		if err := json.Unmarshal(body, &raws); err != nil || len(raws) == 0 {
+			http.Error(w, "Invalid JSON-RPC batch", http.StatusBadRequest)
 			return
 		}
-		defer r.Body.Close()
+	} else {
+		raws = []json.RawMessage{body}
+	}
 
-		var request map[string]interface{}
-		if err := json.Unmarshal(body, &request); err != nil {
+	requests := make([]rpcRequest, len(raws))
+	params := make([][]json.RawMessage, len(raws))
+	keys := make([]string, len(raws))
+	responses := make([][]byte, len(raws))
+	for i, raw := range raws {
+		if err := json.Unmarshal(raw, &requests[i]); err != nil {
 			http.Error(w, "Invalid JSON-RPC request", http.StatusBadRequest)
 			return
 		}
-
-		method, ok := request["method"].(string)
-		if !ok {
+		if requests[i].Method == "" {
 			http.Error(w, "Invalid JSON-RPC method", http.StatusBadRequest)
 			return
 		}
-
-		cacheKey, err := generateCacheKey(chainID, body)
-		if err != nil {
-			http.Error(w, "Failed to generate cache key", http.StatusInternalServerError)
-			return
+		if len(requests[i].Params) > 0 {
+			// params that are not an array are forwarded as they are and never match a block
+			_ = json.Unmarshal(requests[i].Params, &params[i])
 		}
-
-		if _, ignore := methodsToIgnore[method]; !ignore {
-			tx, err := db.BeginRw(r.Context())
-			if err != nil {
-				http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+		if policy, ok := c.policies[requests[i].Method]; ok && policy.mayCache() {
+			if keys[i], err = generateCacheKey(chainID, raw); err != nil {
+				http.Error(w, "Failed to generate cache key", http.StatusInternalServerError)
 				return
 			}
-			if cachedResponse, found := fetchFromCache(tx, cacheKey); found {
-				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("X-Cache-Status", "HIT")
-				w.Write(cachedResponse)
-				tx.Commit()
-				return
+		}
+	}
+
+	// look everything up in one transaction as expired entries are evicted on the way
+	var forward []int
+	tx, err := c.db.BeginRw(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
+		return
+	}
+	for i, request := range requests {
+		if keys[i] == "" {
+			c.stats.uncached(request.Method)
+			forward = append(forward, i)
+			continue
+		}
+		if cached, found := fetchFromCache(tx, keys[i]); found {
+			if responses[i], err = withID(cached, request.ID); err == nil {
+				c.stats.hit(request.Method)
+				continue
 			}
-			tx.Rollback()
 		}
+		c.stats.miss(request.Method)
+		forward = append(forward, i)
+	}
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		return
+	}
 
-		resp, err := http.Post(endpoint, "application/json", bytes.NewBuffer(body))
-		if err != nil {
-			http.Error(w, "Failed to fetch from upstream", http.StatusInternalServerError)
+	cacheStatus := "HIT"
+	if len(forward) > 0 {
+		cacheStatus = "MISS"
+		if len(forward) < len(requests) {
+			cacheStatus = "PARTIAL"
+		}
+		if err = c.forward(r.Context(), endpoint, batch, requests, params, keys, forward, responses); err != nil {
+			log.Warn("L1 cache failed to fetch from upstream", "err", err)
+			http.Error(w, "Failed to fetch from upstream", http.StatusBadGateway)
 			return
 		}
-		defer resp.Body.Close()
+	}
 
-		responseBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			http.Error(w, "Failed to read upstream response", http.StatusInternalServerError)
-			return
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cache-Status", cacheStatus)
+	if batch {
+		w.Write([]byte{'['})
+		w.Write(bytes.Join(responses, []byte{','}))
+		w.Write([]byte{']'})
+	} else {
+		w.Write(responses[0])
+	}
+}
+
+// forward sends the requests at the forward indexes upstream, numbering them so the responses can be matched up even
+// when the client reused ids, and caches the responses the policies allow
+func (c *L1Cache) forward(ctx context.Context, endpoint string, batch bool, requests []rpcRequest, params [][]json.RawMessage, keys []string, forward []int, responses [][]byte) error {
+	upstreamRequests := make([]rpcRequest, len(forward))
+	for j, i := range forward {
+		upstreamRequests[j] = requests[i]
+		upstreamRequests[j].ID = json.RawMessage(strconv.Itoa(j))
+	}
+
+	var body []byte
+	var err error
+	if batch {
+		body, err = json.Marshal(upstreamRequests)
+	} else {
+		body, err = json.Marshal(upstreamRequests[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	responseBody, err := c.upstreams.post(ctx, endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	var upstreamResponses []rpcResponse
+	if batch && isBatch(responseBody) {
+		err = json.Unmarshal(responseBody, &upstreamResponses)
+	} else {
+		var res rpcResponse
+		err = json.Unmarshal(responseBody, &res)
+		upstreamResponses = []rpcResponse{res}
+		// a single error for a whole batch, e.g. when the upstream doesn't support batches, answers every request
+		if batch && res.Error != nil {
+			upstreamResponses = make([]rpcResponse, len(forward))
+			for j := range forward {
+				upstreamResponses[j] = res
+				upstreamResponses[j].ID = json.RawMessage(strconv.Itoa(j))
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse upstream response: %w", err)
+	}
+
+	tx, err := c.db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	finalized := c.finalized.Load()
+	for _, res := range upstreamResponses {
+		j, err := strconv.Atoi(string(res.ID))
+		if err != nil || j < 0 || j >= len(forward) {
+			continue
+		}
+		i := forward[j]
+		res.ID = requests[i].ID
+		if responses[i], err = json.Marshal(res); err != nil {
+			return err
 		}
 
-		if resp.StatusCode == http.StatusOK {
-			// Check if the response contains a JSON-RPC error
-			var jsonResponse map[string]interface{}
-			if err := json.Unmarshal(responseBody, &jsonResponse); err == nil {
-				if _, hasError := jsonResponse["error"]; hasError {
-					log.Warn("Received error response from upstream, not caching", "error", jsonResponse["error"])
-				} else {
-					if _, ignore := methodsToIgnore[method]; !ignore {
-						cacheDuration := time.Duration(0)
-						if duration, found := methodsToExpire[method]; found {
-							if method == "eth_getBlockByNumber" {
-								params, ok := request["params"].([]interface{})
-								if ok && len(params) > 0 {
-									param, ok := params[0].(string)
-									if ok {
-										if _, shouldExpire := paramsToExpire[param]; shouldExpire {
-											cacheDuration = duration
-										}
-									}
-								}
-							} else {
-								cacheDuration = duration
-							}
-						}
-						tx, err := db.BeginRw(r.Context())
-						if err != nil {
-							http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
-							return
-						}
-						defer tx.Rollback()
-						if err := saveToCache(tx, cacheKey, responseBody, cacheDuration); err != nil {
-							http.Error(w, "Failed to save to cache", http.StatusInternalServerError)
-							return
-						}
-						tx.Commit()
-					}
-				}
-			} else {
-				fmt.Println("Failed to parse upstream response, not caching")
+		if keys[i] == "" {
+			continue
+		}
+		if res.Error != nil {
+			log.Warn("Received error response from upstream, not caching", "method", requests[i].Method, "error", string(res.Error))
+			continue
+		}
+		duration, cacheable := c.policies[requests[i].Method].cacheDuration(params[i], res.Result, finalized)
+		if !cacheable {
+			continue
+		}
+		if err := saveToCache(tx, keys[i], responses[i], duration); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, i := range forward {
+		if responses[i] == nil {
+			res := rpcResponse{JSONRPC: "2.0", ID: requests[i].ID, Error: json.RawMessage(`{"code":-32603,"message":"no response from upstream"}`)}
+			if responses[i], err = json.Marshal(res); err != nil {
+				return err
 			}
 		}
+	}
+	return nil
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("X-Cache-Status", "MISS")
-		w.Write(responseBody)
+func (c *L1Cache) handleStats(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Stats()); err != nil {
+		log.Warn("Failed to write L1 cache stats", "err", err)
 	}
 }
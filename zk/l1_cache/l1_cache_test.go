@@ -0,0 +1,234 @@
+package l1_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodPolicy_CacheDuration(t *testing.T) {
+	const finalized = 100
+	policies := policiesWithTTLs(map[string]time.Duration{"eth_getBalance": 0})
+	tests := []struct {
+		method    string
+		params    string
+		result    string
+		duration  time.Duration
+		cacheable bool
+	}{
+		{"eth_chainId", `[]`, `"0x1"`, 0, true},
+		{"eth_blockNumber", `[]`, `"0x70"`, 2 * time.Second, true},
+		{"eth_getBlockByNumber", `["0x64",false]`, `{}`, 0, true},
+		{"eth_getBlockByNumber", `["0x65",false]`, `{}`, defaultTTL, true},
+		{"eth_getBlockByNumber", `["latest",false]`, `{}`, defaultTTL, true},
+		{"eth_getBlockByNumber", `["earliest",false]`, `{}`, 0, true},
+		{"eth_call", `[{}]`, `"0x"`, defaultTTL, true},
+		{"eth_call", `[{},{"blockHash":"0x01"}]`, `"0x"`, 0, true},
+		{"eth_call", `[{},{"blockNumber":"0x10"}]`, `"0x"`, 0, true},
+		{"eth_getLogs", `[{"fromBlock":"0x1","toBlock":"0x64"}]`, `[]`, 0, true},
+		{"eth_getLogs", `[{"fromBlock":"0x1"}]`, `[]`, defaultTTL, true},
+		{"eth_getLogs", `[{"blockHash":"0x01"}]`, `[]`, 0, true},
+		{"eth_getTransactionReceipt", `["0x01"]`, `{"blockNumber":"0x64"}`, 0, true},
+		{"eth_getTransactionReceipt", `["0x01"]`, `{"blockNumber":"0x65"}`, defaultTTL, true},
+		{"eth_getTransactionReceipt", `["0x01"]`, `null`, defaultTTL, true},
+		{"eth_getBalance", `["0x01","latest"]`, `"0x0"`, 0, false},
+		{"eth_getBalance", `["0x01","0x1"]`, `"0x0"`, 0, true},
+		{"eth_sendRawTransaction", `["0x01"]`, `"0x02"`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.params+tt.result, func(t *testing.T) {
+			var params []json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(tt.params), &params))
+			duration, cacheable := policies[tt.method].cacheDuration(params, json.RawMessage(tt.result), finalized)
+			require.Equal(t, tt.cacheable, cacheable)
+			if cacheable {
+				require.Equal(t, tt.duration, duration)
+			}
+		})
+	}
+}
+
+type testUpstream struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	batches int
+	fail    atomic.Bool
+}
+
+func (u *testUpstream) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if u.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		answer := func(raw json.RawMessage) rpcResponse {
+			var req rpcRequest
+			require.NoError(t, json.Unmarshal(raw, &req))
+			u.mu.Lock()
+			u.calls[req.Method]++
+			u.mu.Unlock()
+			switch req.Method {
+			case "eth_getBlockByNumber":
+				return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{"number":"0x64"}`)}
+			case "eth_unknown":
+				return rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: json.RawMessage(`{"code":-32601,"message":"not found"}`)}
+			default:
+				return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"0x1"`)}
+			}
+		}
+		if isBatch(body) {
+			u.mu.Lock()
+			u.batches++
+			u.mu.Unlock()
+			var raws []json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &raws))
+			responses := make([]rpcResponse, len(raws))
+			// answered in reverse to check responses are matched up by id
+			for i, raw := range raws {
+				responses[len(raws)-1-i] = answer(raw)
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(responses))
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(answer(body)))
+	}
+}
+
+func (u *testUpstream) count(method string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls[method]
+}
+
+func TestL1Cache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failing := &testUpstream{calls: map[string]int{}}
+	failing.fail.Store(true)
+	failingSvr := httptest.NewServer(failing.handler(t))
+	defer failingSvr.Close()
+	upstream := &testUpstream{calls: map[string]int{}}
+	svr := httptest.NewServer(upstream.handler(t))
+	defer svr.Close()
+
+	cache, err := NewL1Cache(ctx, Config{
+		DbPath:     filepath.Join(t.TempDir(), "l1cache"),
+		ChainID:    1,
+		Upstreams:  []string{failingSvr.URL, svr.URL},
+		MethodTTLs: map[string]time.Duration{"eth_blockNumber": 0},
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return cache.finalized.Load() == 100 }, 5*time.Second, 10*time.Millisecond)
+	finalizedCalls := upstream.count("eth_getBlockByNumber")
+
+	post := func(body string) (string, string) {
+		rec := httptest.NewRecorder()
+		cache.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String(), rec.Header().Get("X-Cache-Status")
+	}
+
+	// a finalized block is cached and served with the id of each request
+	for i, status := range []string{"MISS", "HIT"} {
+		res, cacheStatus := post(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"eth_getBlockByNumber","params":["0x10",false]}`, i+10))
+		require.Equal(t, status, cacheStatus)
+		require.JSONEq(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"number":"0x64"}}`, i+10), res)
+	}
+	require.Equal(t, finalizedCalls+1, upstream.count("eth_getBlockByNumber"))
+
+	// eth_blockNumber has its ttl set to 0 so it is never cached
+	for i := 0; i < 2; i++ {
+		_, cacheStatus := post(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+		require.Equal(t, "MISS", cacheStatus)
+	}
+	require.Equal(t, 2, upstream.count("eth_blockNumber"))
+
+	// a batch is answered from the cache where possible and the rest forwarded in one request, in order
+	res, cacheStatus := post(`[
+		{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x10",false]},
+		{"jsonrpc":"2.0","id":"a","method":"eth_chainId","params":[]},
+		{"jsonrpc":"2.0","id":1,"method":"eth_unknown","params":[]}
+	]`)
+	require.Equal(t, "PARTIAL", cacheStatus)
+	require.JSONEq(t, `[
+		{"jsonrpc":"2.0","id":1,"result":{"number":"0x64"}},
+		{"jsonrpc":"2.0","id":"a","result":"0x1"},
+		{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"not found"}}
+	]`, res)
+	require.Equal(t, 1, upstream.batches)
+
+	_, cacheStatus = post(`[{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}]`)
+	require.Equal(t, "HIT", cacheStatus)
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(100), stats.FinalizedBlock)
+	require.Len(t, stats.Upstreams, 2)
+	require.NotZero(t, stats.Upstreams[0].Failures)
+	require.Zero(t, stats.Upstreams[1].Failures)
+	for _, m := range stats.Methods {
+		switch m.Method {
+		case "eth_getBlockByNumber":
+			require.Equal(t, MethodStats{Method: m.Method, Hits: 2, Misses: 1, HitRatio: 2.0 / 3}, m)
+		case "eth_blockNumber":
+			require.Equal(t, MethodStats{Method: m.Method, Uncached: 2}, m)
+		case "eth_unknown":
+			require.Equal(t, MethodStats{Method: m.Method, Uncached: 1}, m)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	cache.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var served Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Equal(t, stats.Methods, served.Methods)
+}
+
+func TestL1CacheEndpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := &testUpstream{calls: map[string]int{}}
+	firstSvr := httptest.NewServer(first.handler(t))
+	defer firstSvr.Close()
+	second := &testUpstream{calls: map[string]int{}}
+	secondSvr := httptest.NewServer(second.handler(t))
+	defer secondSvr.Close()
+
+	cache, err := NewL1Cache(ctx, Config{
+		DbPath:                filepath.Join(t.TempDir(), "l1cache"),
+		Upstreams:             []string{firstSvr.URL, secondSvr.URL},
+		FinalizedPollInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return cache.finalized.Load() == 100 }, 5*time.Second, 10*time.Millisecond)
+
+	// each client is served by the provider in its url, under the chain id it passes
+	post := func(endpoint string) {
+		target := fmt.Sprintf("/?endpoint=%s&chainid=2440", url.QueryEscape(endpoint))
+		rec := httptest.NewRecorder()
+		cache.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x01"]}`)))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	post(secondSvr.URL)
+	require.Equal(t, 0, first.count("eth_sendRawTransaction"))
+	require.Equal(t, 1, second.count("eth_sendRawTransaction"))
+
+	// and falls back to the others when it fails
+	second.fail.Store(true)
+	post(secondSvr.URL)
+	require.Equal(t, 1, first.count("eth_sendRawTransaction"))
+}
@@ -0,0 +1,196 @@
+package l1_cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cacheKind int
+
+const (
+	// the method is always forwarded, e.g. eth_sendRawTransaction
+	kindNever cacheKind = iota
+	// the response never changes, e.g. eth_chainId
+	kindForever
+	// the response is cached for the ttl
+	kindTTL
+	// the response is cached forever when every block referenced by the params is finalized, for the ttl otherwise
+	kindBlockParams
+	// the response is cached forever when the block it is in is finalized, for the ttl otherwise
+	kindBlockResult
+)
+
+const defaultTTL = 12 * time.Second
+
+type methodPolicy struct {
+	kind cacheKind
+	// ttl applies to responses that may still change, 0 stops them from being cached
+	ttl time.Duration
+	// blockParams returns the blocks a kindBlockParams request refers to
+	blockParams func(params []json.RawMessage) []blockRef
+	// resultField holds the block number in the result of a kindBlockResult method
+	resultField string
+}
+
+// methods missing from the policies are forwarded without being cached
+var defaultPolicies = map[string]methodPolicy{
+	"eth_sendRawTransaction":               {kind: kindNever},
+	"eth_chainId":                          {kind: kindForever},
+	"net_version":                          {kind: kindForever},
+	"eth_blockNumber":                      {kind: kindTTL, ttl: 2 * time.Second},
+	"eth_gasPrice":                         {kind: kindTTL, ttl: 10 * time.Second},
+	"eth_maxPriorityFeePerGas":             {kind: kindTTL, ttl: 10 * time.Second},
+	"eth_feeHistory":                       {kind: kindTTL, ttl: 10 * time.Second},
+	"eth_getBlockByNumber":                 {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(0)},
+	"eth_getBlockTransactionCountByNumber": {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(0)},
+	"eth_getBlockReceipts":                 {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(0)},
+	"eth_getBalance":                       {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(1)},
+	"eth_getCode":                          {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(1)},
+	"eth_getTransactionCount":              {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(1)},
+	"eth_call":                             {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(1)},
+	"eth_getStorageAt":                     {kind: kindBlockParams, ttl: defaultTTL, blockParams: blockParamAt(2)},
+	"eth_getLogs":                          {kind: kindBlockParams, ttl: defaultTTL, blockParams: logFilterBlocks},
+	"eth_getBlockByHash":                   {kind: kindBlockResult, ttl: defaultTTL, resultField: "number"},
+	"eth_getTransactionByHash":             {kind: kindBlockResult, ttl: defaultTTL, resultField: "blockNumber"},
+	"eth_getTransactionReceipt":            {kind: kindBlockResult, ttl: defaultTTL, resultField: "blockNumber"},
+}
+
+// policiesWithTTLs returns the default policies with the ttls of the given methods replaced
+func policiesWithTTLs(ttls map[string]time.Duration) map[string]methodPolicy {
+	policies := make(map[string]methodPolicy, len(defaultPolicies)+len(ttls))
+	for method, policy := range defaultPolicies {
+		policies[method] = policy
+	}
+	for method, ttl := range ttls {
+		policy, ok := policies[method]
+		if !ok {
+			policy = methodPolicy{kind: kindTTL}
+		}
+		policy.ttl = ttl
+		policies[method] = policy
+	}
+	return policies
+}
+
+// blockRef is a block referenced by a request, blocks referenced by hash never change
+type blockRef struct {
+	number uint64
+	hash   bool
+	tag    bool
+}
+
+func (r blockRef) isFinal(finalized uint64) bool {
+	return r.hash || (!r.tag && finalized > 0 && r.number <= finalized)
+}
+
+func parseBlockRef(raw json.RawMessage) blockRef {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseBlockTag(s)
+	}
+	// EIP-1898 block parameter
+	var obj struct {
+		BlockHash   *string `json:"blockHash"`
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil {
+		if obj.BlockHash != nil {
+			return blockRef{hash: true}
+		}
+		if obj.BlockNumber != nil {
+			return parseBlockTag(*obj.BlockNumber)
+		}
+	}
+	return blockRef{tag: true}
+}
+
+func parseBlockTag(s string) blockRef {
+	if s == "earliest" {
+		return blockRef{}
+	}
+	if n, ok := parseQuantity(s); ok {
+		return blockRef{number: n}
+	}
+	// latest, safe, finalized, pending or anything we don't understand
+	return blockRef{tag: true}
+}
+
+func parseQuantity(s string) (uint64, bool) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 64)
+	return n, err == nil
+}
+
+// blockParamAt returns the block param at index, which defaults to latest when it is left out
+func blockParamAt(index int) func(params []json.RawMessage) []blockRef {
+	return func(params []json.RawMessage) []blockRef {
+		if index >= len(params) {
+			return []blockRef{{tag: true}}
+		}
+		return []blockRef{parseBlockRef(params[index])}
+	}
+}
+
+func logFilterBlocks(params []json.RawMessage) []blockRef {
+	if len(params) == 0 {
+		return []blockRef{{tag: true}}
+	}
+	var filter struct {
+		BlockHash *string         `json:"blockHash"`
+		FromBlock json.RawMessage `json:"fromBlock"`
+		ToBlock   json.RawMessage `json:"toBlock"`
+	}
+	if err := json.Unmarshal(params[0], &filter); err != nil {
+		return []blockRef{{tag: true}}
+	}
+	if filter.BlockHash != nil {
+		return []blockRef{{hash: true}}
+	}
+	refs := make([]blockRef, 0, 2)
+	for _, raw := range []json.RawMessage{filter.FromBlock, filter.ToBlock} {
+		if len(raw) == 0 {
+			refs = append(refs, blockRef{tag: true})
+		} else {
+			refs = append(refs, parseBlockRef(raw))
+		}
+	}
+	return refs
+}
+
+// mayCache reports whether any response to the method can be cached
+func (p methodPolicy) mayCache() bool {
+	return p.kind != kindNever && (p.kind != kindTTL || p.ttl > 0)
+}
+
+// cacheDuration returns how long a successful response can be cached for, 0 meaning forever
+func (p methodPolicy) cacheDuration(params []json.RawMessage, result json.RawMessage, finalized uint64) (time.Duration, bool) {
+	switch p.kind {
+	case kindForever:
+		return 0, true
+	case kindBlockParams:
+		final := true
+		for _, ref := range p.blockParams(params) {
+			final = final && ref.isFinal(finalized)
+		}
+		if final {
+			return 0, true
+		}
+	case kindBlockResult:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(result, &fields); err == nil && fields != nil {
+			var number string
+			if err := json.Unmarshal(fields[p.resultField], &number); err == nil {
+				if n, ok := parseQuantity(number); ok && finalized > 0 && n <= finalized {
+					return 0, true
+				}
+			}
+		}
+	case kindNever:
+		return 0, false
+	}
+	return p.ttl, p.ttl > 0
+}
@@ -0,0 +1,86 @@
+package l1_cache
+
+import (
+	"sort"
+	"sync"
+)
+
+type methodCounts struct {
+	hits   uint64
+	misses uint64
+	// uncached requests are for methods, or responses, the policies don't allow to be cached
+	uncached uint64
+}
+
+type stats struct {
+	mu      sync.Mutex
+	methods map[string]*methodCounts
+}
+
+func newStats() *stats {
+	return &stats{methods: make(map[string]*methodCounts)}
+}
+
+func (s *stats) record(method string, update func(c *methodCounts)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.methods[method]
+	if !ok {
+		c = &methodCounts{}
+		s.methods[method] = c
+	}
+	update(c)
+}
+
+func (s *stats) hit(method string)      { s.record(method, func(c *methodCounts) { c.hits++ }) }
+func (s *stats) miss(method string)     { s.record(method, func(c *methodCounts) { c.misses++ }) }
+func (s *stats) uncached(method string) { s.record(method, func(c *methodCounts) { c.uncached++ }) }
+
+type MethodStats struct {
+	Method   string  `json:"method"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Uncached uint64  `json:"uncached"`
+	HitRatio float64 `json:"hitRatio"`
+}
+
+type UpstreamStats struct {
+	Url      string `json:"url"`
+	Requests uint64 `json:"requests"`
+	Failures uint64 `json:"failures"`
+}
+
+type Stats struct {
+	FinalizedBlock uint64          `json:"finalizedBlock"`
+	HitRatio       float64         `json:"hitRatio"`
+	Methods        []MethodStats   `json:"methods"`
+	Upstreams      []UpstreamStats `json:"upstreams"`
+}
+
+func hitRatio(hits, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+func (s *stats) methodStats() ([]MethodStats, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	methods := make([]MethodStats, 0, len(s.methods))
+	var hits, total uint64
+	for method, c := range s.methods {
+		methodTotal := c.hits + c.misses + c.uncached
+		methods = append(methods, MethodStats{
+			Method:   method,
+			Hits:     c.hits,
+			Misses:   c.misses,
+			Uncached: c.uncached,
+			HitRatio: hitRatio(c.hits, methodTotal),
+		})
+		hits += c.hits
+		total += methodTotal
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Method < methods[j].Method })
+	return methods, hitRatio(hits, total)
+}
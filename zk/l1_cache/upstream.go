@@ -0,0 +1,151 @@
+package l1_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+)
+
+const upstreamTimeout = 20 * time.Second
+
+type upstream struct {
+	url      string
+	requests atomic.Uint64
+	failures atomic.Uint64
+}
+
+// upstreams forwards requests to the first endpoint that answers, starting from the last one that did
+type upstreams struct {
+	endpoints []*upstream
+	preferred atomic.Int32
+	client    *http.Client
+}
+
+func newUpstreams(urls []string) *upstreams {
+	u := &upstreams{client: &http.Client{Timeout: upstreamTimeout}}
+	for _, endpoint := range urls {
+		if endpoint != "" {
+			u.endpoints = append(u.endpoints, &upstream{url: endpoint})
+		}
+	}
+	return u
+}
+
+// endpoint returns the upstream for a url passed with a request, which may not be a configured one
+func (u *upstreams) endpoint(endpoint string) *upstream {
+	for _, e := range u.endpoints {
+		if e.url == endpoint {
+			return e
+		}
+	}
+	return &upstream{url: endpoint}
+}
+
+// redactedUrl keeps API keys in the path or query of an upstream url out of logs and stats
+func redactedUrl(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "invalid url"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// post tries the requested endpoint first when one is given and then the configured endpoints in turn
+func (u *upstreams) post(ctx context.Context, requested string, body []byte) ([]byte, error) {
+	var candidates []*upstream
+	if requested != "" {
+		candidates = append(candidates, u.endpoint(requested))
+	}
+	preferred := int(u.preferred.Load())
+	for i := range u.endpoints {
+		idx := (preferred + i) % len(u.endpoints)
+		if e := u.endpoints[idx]; e.url != requested {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("no upstream configured and no endpoint given")
+	}
+
+	var errs []error
+	for _, e := range candidates {
+		response, err := u.postTo(ctx, e, body)
+		if err == nil {
+			for i, configured := range u.endpoints {
+				if configured == e {
+					u.preferred.Store(int32(i))
+				}
+			}
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Warn("L1 cache upstream failed, trying the next", "upstream", redactedUrl(e.url), "err", err)
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (u *upstreams) postTo(ctx context.Context, e *upstream, body []byte) ([]byte, error) {
+	e.requests.Add(1)
+	response, err := func() ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := u.client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				// the url.Error would repeat the full url
+				return nil, fmt.Errorf("upstream %s: %w", redactedUrl(e.url), urlErr.Err)
+			}
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("upstream %s returned status %d", redactedUrl(e.url), resp.StatusCode)
+		}
+		return io.ReadAll(resp.Body)
+	}()
+	if err != nil {
+		e.failures.Add(1)
+	}
+	return response, err
+}
+
+// finalizedBlock asks the upstreams for the number of the latest finalized block
+func (u *upstreams) finalizedBlock(ctx context.Context) (uint64, error) {
+	body, err := u.post(ctx, "", []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["finalized",false]}`))
+	if err != nil {
+		return 0, err
+	}
+	var response struct {
+		Result *struct {
+			Number string `json:"number"`
+		} `json:"result"`
+		Error json.RawMessage `json:"error"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return 0, err
+	}
+	if response.Result == nil {
+		return 0, fmt.Errorf("no finalized block returned: %s", response.Error)
+	}
+	n, ok := parseQuantity(response.Result.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid finalized block number %s", response.Result.Number)
+	}
+	return n, nil
+}
@@ -37,7 +37,7 @@ func BuildSequencesForRollup(data []byte) ([]RollupBaseEtrogBatchData, error) {
 	return sequences, err
 }
 
-func BuildSequencesForValidium(data []byte, daUrl string) ([]RollupBaseEtrogBatchData, error) {
+func BuildSequencesForValidium(data []byte, daBackend da.DABackend) ([]RollupBaseEtrogBatchData, error) {
 	var sequences []RollupBaseEtrogBatchData
 	var validiumSequences []ValidiumBatchData
 	err := json.Unmarshal(data, &validiumSequences)
@@ -48,7 +48,7 @@ func BuildSequencesForValidium(data []byte, daUrl string) ([]RollupBaseEtrogBatc
 
 	for _, validiumSequence := range validiumSequences {
 		hash := common.BytesToHash(validiumSequence.TransactionsHash[:])
-		data, err := da.GetOffChainData(context.Background(), daUrl, hash)
+		data, err := daBackend.GetOffChainData(context.Background(), hash)
 		if err != nil {
 			return nil, err
 		}
@@ -69,7 +69,7 @@ func BuildSequencesForValidium(data []byte, daUrl string) ([]RollupBaseEtrogBatc
 	return sequences, nil
 }
 
-func DecodeL1BatchData(txData []byte, daUrl string) ([][]byte, common.Address, uint64, error) {
+func DecodeL1BatchData(txData []byte, daBackend da.DABackend) ([][]byte, common.Address, uint64, error) {
 	// we need to know which version of the ABI to use here so lets find it
 	idAsString := fmt.Sprintf("%x", txData[:4])
 	abiMapped, found := contracts.SequenceBatchesMapping[idAsString]
@@ -117,8 +117,8 @@ func DecodeL1BatchData(txData []byte, daUrl string) ([][]byte, common.Address, u
 		}
 		limitTimstamp = ts
 	case contracts.SequenceBatchesValidiumElderBerry:
-		if daUrl == "" {
-			return nil, common.Address{}, 0, fmt.Errorf("data availability url is required for validium")
+		if daBackend == nil {
+			return nil, common.Address{}, 0, fmt.Errorf("data availability backend is required for validium")
 		}
 		isValidium = true
 		cb, ok := data[3].(common.Address)
@@ -143,8 +143,8 @@ func DecodeL1BatchData(txData []byte, daUrl string) ([][]byte, common.Address, u
 		}
 		limitTimstamp = ts
 	case contracts.SequenceBatchesValidiumBanana:
-		if daUrl == "" {
-			return nil, common.Address{}, 0, fmt.Errorf("data availability url is required for validium")
+		if daBackend == nil {
+			return nil, common.Address{}, 0, fmt.Errorf("data availability backend is required for validium")
 		}
 		isValidium = true
 		cb, ok := data[4].(common.Address)
@@ -169,7 +169,7 @@ func DecodeL1BatchData(txData []byte, daUrl string) ([][]byte, common.Address, u
 	}
 
 	if isValidium {
-		sequences, err = BuildSequencesForValidium(bytedata, daUrl)
+		sequences, err = BuildSequencesForValidium(bytedata, daBackend)
 	} else {
 		sequences, err = BuildSequencesForRollup(bytedata)
 	}
@@ -0,0 +1,99 @@
+package l1infotree
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+// HistoryTree keeps every complete subtree of the L1InfoTree in memory so the root and the merkle proofs of the tree
+// as it was after any number of leaves are computed from at most height hashes per level instead of a rebuild
+type HistoryTree struct {
+	height     uint8
+	zeroHashes [][32]byte
+	// nodes[h][i] is the root of the complete subtree of height h over the leaves i<<h to ((i+1)<<h)-1
+	nodes [][][32]byte
+}
+
+// NewHistoryTree creates an empty HistoryTree.
+func NewHistoryTree(height uint8) *HistoryTree {
+	return &HistoryTree{
+		height:     height,
+		zeroHashes: generateZeroHashes(height),
+		nodes:      make([][][32]byte, height+1),
+	}
+}
+
+// LeafCount returns the number of leaves in the tree
+func (t *HistoryTree) LeafCount() uint32 {
+	return uint32(len(t.nodes[0]))
+}
+
+// Leaf returns the leaf at the given index
+func (t *HistoryTree) Leaf(index uint32) ([32]byte, error) {
+	if index >= t.LeafCount() {
+		return [32]byte{}, fmt.Errorf("leaf %d not in the tree of %d leaves", index, t.LeafCount())
+	}
+	return t.nodes[0][index], nil
+}
+
+// AddLeaf appends a leaf to the tree, completing the subtrees it closes
+func (t *HistoryTree) AddLeaf(leaf [32]byte) error {
+	if uint64(len(t.nodes[0])) >= uint64(1)<<t.height {
+		return fmt.Errorf("tree of height %d is full", t.height)
+	}
+	t.nodes[0] = append(t.nodes[0], leaf)
+	for h := uint8(0); h < t.height; h++ {
+		n := len(t.nodes[h])
+		if n%2 == 1 {
+			break
+		}
+		t.nodes[h+1] = append(t.nodes[h+1], Hash(t.nodes[h][n-2], t.nodes[h][n-1]))
+	}
+	return nil
+}
+
+// Truncate drops every leaf from the given count onwards
+func (t *HistoryTree) Truncate(count uint32) {
+	for h := range t.nodes {
+		if keep := int(uint64(count) >> h); keep < len(t.nodes[h]) {
+			t.nodes[h] = t.nodes[h][:keep]
+		}
+	}
+}
+
+// Root returns the root of the tree holding only the first count leaves
+func (t *HistoryTree) Root(count uint32) (common.Hash, error) {
+	if count > t.LeafCount() {
+		return common.Hash{}, fmt.Errorf("root after %d leaves requested from a tree of %d leaves", count, t.LeafCount())
+	}
+	return t.node(t.height, 0, uint64(count)), nil
+}
+
+// Proof returns the siblings of the leaf at the given index and the root of the tree holding only the first count
+// leaves, matching the proof L1InfoTree.ComputeMerkleProof returns for those leaves
+func (t *HistoryTree) Proof(index, count uint32) ([][32]byte, common.Hash, error) {
+	if count > t.LeafCount() {
+		return nil, common.Hash{}, fmt.Errorf("proof after %d leaves requested from a tree of %d leaves", count, t.LeafCount())
+	}
+	if index >= count {
+		return nil, common.Hash{}, fmt.Errorf("leaf %d not in the tree of %d leaves", index, count)
+	}
+	siblings := make([][32]byte, t.height)
+	for h := uint8(0); h < t.height; h++ {
+		siblings[h] = t.node(h, (uint64(index)>>h)^1, uint64(count))
+	}
+	return siblings, t.node(t.height, 0, uint64(count)), nil
+}
+
+// node returns the node at height h and position i of the tree holding only the first count leaves. Nodes with no
+// leaves are zero hashes and complete subtrees are stored, so only the nodes on the path of the last leaf are hashed
+func (t *HistoryTree) node(h uint8, i uint64, count uint64) [32]byte {
+	if i<<h >= count {
+		return t.zeroHashes[h]
+	}
+	if (i+1)<<h <= count {
+		return t.nodes[h][i]
+	}
+	return Hash(t.node(h-1, 2*i, count), t.node(h-1, 2*i+1, count))
+}
@@ -0,0 +1,71 @@
+package l1infotree
+
+import (
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+)
+
+// LeafReader reads the leaves a HistoryTreeCache is kept in step with
+type LeafReader interface {
+	// Leaf returns the leaf at the given index, found is false if there is none
+	Leaf(index uint32) (leaf common.Hash, found bool, err error)
+	// LeavesFrom returns the leaves from the given index up to the first missing one
+	LeavesFrom(index uint32) ([]common.Hash, error)
+}
+
+// HistoryTreeCache keeps a HistoryTree in memory, in step with the leaves in the db, so roots and proofs don't rebuild
+// the whole tree on every call
+type HistoryTreeCache struct {
+	height uint8
+	mu     sync.Mutex
+	tree   *HistoryTree
+}
+
+// NewHistoryTreeCache creates an empty HistoryTreeCache for trees of the given height
+func NewHistoryTreeCache(height uint8) *HistoryTreeCache {
+	return &HistoryTreeCache{height: height}
+}
+
+// With brings the cached tree up to date with the leaves the reader sees and calls fn with it
+func (c *HistoryTreeCache) With(reader LeafReader, fn func(tree *HistoryTree) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.sync(reader); err != nil {
+		return err
+	}
+	return fn(c.tree)
+}
+
+func (c *HistoryTreeCache) sync(reader LeafReader) error {
+	if c.tree == nil {
+		c.tree = NewHistoryTree(c.height)
+	}
+
+	// an unwind replaces the leaves above the unwind point, start over if the last cached leaf is gone or changed
+	if count := c.tree.LeafCount(); count > 0 {
+		cached, err := c.tree.Leaf(count - 1)
+		if err != nil {
+			return err
+		}
+		leaf, found, err := reader.Leaf(count - 1)
+		if err != nil {
+			return err
+		}
+		if !found || leaf != cached {
+			c.tree = NewHistoryTree(c.height)
+		}
+	}
+
+	leaves, err := reader.LeavesFrom(c.tree.LeafCount())
+	if err != nil {
+		return err
+	}
+	for _, leaf := range leaves {
+		if err = c.tree.AddLeaf(leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
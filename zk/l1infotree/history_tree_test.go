@@ -0,0 +1,144 @@
+package l1infotree_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryTreeMatchesL1InfoTree(t *testing.T) {
+	history := l1infotree.NewHistoryTree(32)
+	mt, err := l1infotree.NewL1InfoTree(32, [][32]byte{})
+	require.NoError(t, err)
+
+	emptyRoot, err := history.Root(0)
+	require.NoError(t, err)
+	initialRoot, _, _ := mt.GetCurrentRootCountAndSiblings()
+	require.Equal(t, initialRoot, emptyRoot)
+
+	var leaves [][32]byte
+	roots := []common.Hash{emptyRoot}
+	for i := 0; i < 19; i++ {
+		leaf := l1infotree.HashLeafData(common.BigToHash(common.Big1), common.HexToHash("0xabc"), uint64(i))
+		leaves = append(leaves, leaf)
+		require.NoError(t, history.AddLeaf(leaf))
+		root, err := mt.AddLeaf(uint32(i), leaf)
+		require.NoError(t, err)
+		roots = append(roots, root)
+	}
+
+	for count := uint32(1); count <= uint32(len(leaves)); count++ {
+		root, err := history.Root(count)
+		require.NoError(t, err)
+		require.Equal(t, roots[count], root)
+
+		for index := uint32(0); index < count; index++ {
+			leavesCopy := append([][32]byte{}, leaves[:count]...)
+			expectedProof, expectedRoot, err := mt.ComputeMerkleProof(index, leavesCopy)
+			require.NoError(t, err)
+
+			proof, root, err := history.Proof(index, count)
+			require.NoError(t, err)
+			require.Equal(t, expectedRoot, root)
+			require.Equal(t, expectedProof, proof)
+		}
+	}
+
+	_, _, err = history.Proof(5, 5)
+	require.Error(t, err)
+	_, err = history.Root(20)
+	require.Error(t, err)
+}
+
+func TestHistoryTreeTruncate(t *testing.T) {
+	history := l1infotree.NewHistoryTree(32)
+	for i := 0; i < 9; i++ {
+		require.NoError(t, history.AddLeaf(common.BigToHash(big.NewInt(int64(i+1)))))
+	}
+	rootAfterFive, err := history.Root(5)
+	require.NoError(t, err)
+
+	history.Truncate(5)
+	require.Equal(t, uint32(5), history.LeafCount())
+	root, err := history.Root(5)
+	require.NoError(t, err)
+	require.Equal(t, rootAfterFive, root)
+
+	// leaves added after the truncation replace the dropped subtrees
+	replaced := [32]byte{0xff}
+	require.NoError(t, history.AddLeaf(replaced))
+	leaf, err := history.Leaf(5)
+	require.NoError(t, err)
+	require.Equal(t, replaced, leaf)
+
+	rebuilt := l1infotree.NewHistoryTree(32)
+	for i := uint32(0); i < 5; i++ {
+		leaf, err := history.Leaf(i)
+		require.NoError(t, err)
+		require.NoError(t, rebuilt.AddLeaf(leaf))
+	}
+	require.NoError(t, rebuilt.AddLeaf(replaced))
+	expected, err := rebuilt.Root(6)
+	require.NoError(t, err)
+	root, err = history.Root(6)
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+// sliceLeaves is a LeafReader over the leaves of a slice
+type sliceLeaves []common.Hash
+
+func (s sliceLeaves) Leaf(index uint32) (common.Hash, bool, error) {
+	if int(index) >= len(s) {
+		return common.Hash{}, false, nil
+	}
+	return s[index], true, nil
+}
+
+func (s sliceLeaves) LeavesFrom(index uint32) ([]common.Hash, error) {
+	if int(index) >= len(s) {
+		return nil, nil
+	}
+	return s[index:], nil
+}
+
+func TestHistoryTreeCacheFollowsUnwinds(t *testing.T) {
+	cache := l1infotree.NewHistoryTreeCache(32)
+	rootOf := func(leaves sliceLeaves) common.Hash {
+		var root common.Hash
+		require.NoError(t, cache.With(leaves, func(tree *l1infotree.HistoryTree) error {
+			require.Equal(t, uint32(len(leaves)), tree.LeafCount())
+			var err error
+			root, err = tree.Root(tree.LeafCount())
+			return err
+		}))
+		return root
+	}
+	expectedRoot := func(leaves sliceLeaves) common.Hash {
+		tree := l1infotree.NewHistoryTree(32)
+		for _, leaf := range leaves {
+			require.NoError(t, tree.AddLeaf(leaf))
+		}
+		root, err := tree.Root(tree.LeafCount())
+		require.NoError(t, err)
+		return root
+	}
+
+	leaves := sliceLeaves{{1}, {2}, {3}}
+	require.Equal(t, expectedRoot(leaves), rootOf(leaves))
+
+	// new leaves are appended to the cached tree
+	leaves = append(leaves, common.Hash{4})
+	require.Equal(t, expectedRoot(leaves), rootOf(leaves))
+
+	// the last leaf was replaced by an unwind
+	leaves = sliceLeaves{{1}, {2}, {3}, {5}, {6}}
+	require.Equal(t, expectedRoot(leaves), rootOf(leaves))
+
+	// or is gone
+	leaves = leaves[:2]
+	require.Equal(t, expectedRoot(leaves), rootOf(leaves))
+}
@@ -87,6 +87,15 @@ func (u *Updater) WarmUp(tx kv.RwTx) (err error) {
 		progress = u.cfg.L1FirstBlock - 1
 	}
 
+	// the l1 syncer stage moves the progress back when l1 reorganised the blocks the tree was built from, logs
+	// already fetched by the running syncer may come from the old fork
+	if progress < u.progress && u.syncer.IsSyncStarted() {
+		log.Warn("L1 info tree progress moved back by an L1 reorg, restarting the syncer", "from", u.progress, "to", progress)
+		u.syncer.StopQueryBlocks()
+		u.syncer.ConsumeQueryBlocks()
+		u.syncer.WaitQueryBlocksToFinish()
+	}
+
 	u.progress = progress
 
 	latestUpdate, err := hermezDb.GetLatestL1InfoTreeUpdate()
@@ -190,6 +199,9 @@ LOOP:
 				if err != nil {
 					return nil, fmt.Errorf("createL1InfoTreeUpdate: %w", err)
 				}
+				if err = hermezDb.WriteL1InfoTreeBlockHash(l.BlockNumber, header.Hash()); err != nil {
+					return nil, fmt.Errorf("WriteL1InfoTreeBlockHash: %w", err)
+				}
 
 				leafHash := HashLeafData(tmpUpdate.GER, tmpUpdate.ParentHash, tmpUpdate.Timestamp)
 				if tree.LeafExists(leafHash) {
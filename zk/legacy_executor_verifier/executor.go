@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -31,6 +32,13 @@ type Config struct {
 	Timeout               time.Duration
 	MaxConcurrentRequests int
 	OutputLocation        string
+
+	// Weights and InFlightLimits are matched to GrpcUrls by index, executors without an entry get a weight of 1 and
+	// MaxConcurrentRequests in-flight requests
+	Weights                []int
+	InFlightLimits         []int
+	CircuitBreakerFailures int
+	CircuitBreakerCooldown time.Duration
 }
 
 type Payload struct {
@@ -61,6 +69,9 @@ type Executor struct {
 	connCancel context.CancelFunc
 	client     executor.ExecutorServiceClient
 	semaphore  chan struct{}
+	// health is created on first use for executors not built by NewExecutors, access it through getHealth
+	health     *executorHealth
+	healthOnce sync.Once
 
 	// if not empty then the executor will write the payload to this location before sending it to the
 	// remote executor
@@ -70,7 +81,16 @@ type Executor struct {
 func NewExecutors(cfg Config) []*Executor {
 	executors := make([]*Executor, len(cfg.GrpcUrls))
 	for i, grpcUrl := range cfg.GrpcUrls {
-		executors[i] = NewExecutor(grpcUrl, cfg.Timeout, cfg.MaxConcurrentRequests, cfg.OutputLocation)
+		weight := 1
+		if i < len(cfg.Weights) {
+			weight = cfg.Weights[i]
+		}
+		inFlightLimit := cfg.MaxConcurrentRequests
+		if i < len(cfg.InFlightLimits) {
+			inFlightLimit = cfg.InFlightLimits[i]
+		}
+		executors[i] = NewExecutor(grpcUrl, cfg.Timeout, inFlightLimit, cfg.OutputLocation)
+		executors[i].health = newExecutorHealth(weight, cfg.CircuitBreakerFailures, cfg.CircuitBreakerCooldown)
 	}
 	return executors
 }
@@ -116,6 +136,27 @@ func (e *Executor) QueueLength() int {
 	return len(e.semaphore)
 }
 
+// InFlightLimit is the number of requests the executor is sent at once, further requests queue for access
+func (e *Executor) InFlightLimit() int {
+	return cap(e.semaphore)
+}
+
+func (e *Executor) getHealth() *executorHealth {
+	e.healthOnce.Do(func() {
+		if e.health == nil {
+			e.health = newExecutorHealth(1, 0, 0)
+		}
+	})
+	return e.health
+}
+
+func (e *Executor) Status() ExecutorStatus {
+	status := e.getHealth().status(time.Now())
+	status.InFlight = e.QueueLength()
+	status.InFlightLimit = e.InFlightLimit()
+	return status
+}
+
 func (e *Executor) AquireAccess() {
 	e.semaphore <- struct{}{}
 }
@@ -217,13 +258,17 @@ func (e *Executor) Verify(p *Payload, request *VerifierRequest, oldStateRoot com
 		}
 	}
 
+	start := time.Now()
 	resp, err := e.client.ProcessStatelessBatchV2(ctx, grpcRequest, grpc.MaxCallSendMsgSize(size), grpc.MaxCallRecvMsgSize(size))
 	if err != nil {
+		e.getHealth().recordFailure(time.Now())
 		return false, nil, nil, fmt.Errorf("failed to process stateless batch: %w", err)
 	}
 	if resp == nil {
+		e.getHealth().recordFailure(time.Now())
 		return false, nil, nil, fmt.Errorf("nil response")
 	}
+	e.getHealth().recordSuccess(time.Since(start))
 
 	counters := map[string]int{
 		"SHA": int(resp.CntSha256Hashes),
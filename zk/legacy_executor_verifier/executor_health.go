@@ -0,0 +1,226 @@
+package legacy_executor_verifier
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// weight given to the latest sample when updating an executor's average latency
+	latencyDecay = 0.2
+	// number of recent requests the error rate of an executor is calculated over
+	recentResultsSize = 100
+	// the slowest executors still get this fraction of their configured weight so they are never starved
+	minLatencyFactor = 0.1
+)
+
+// ExecutorStatus is a snapshot of an executor's health and load used for routing decisions
+type ExecutorStatus struct {
+	Index               int     `json:"index"`
+	Weight              int     `json:"weight"`
+	Online              bool    `json:"online"`
+	InFlight            int     `json:"inFlight"`
+	InFlightLimit       int     `json:"inFlightLimit"`
+	CircuitOpen         bool    `json:"circuitOpen"`
+	CircuitOpenUntil    int64   `json:"circuitOpenUntil,omitempty"`
+	ConsecutiveFailures int     `json:"consecutiveFailures"`
+	AverageLatencyMs    float64 `json:"averageLatencyMs"`
+	RecentRequests      int     `json:"recentRequests"`
+	RecentErrorRate     float64 `json:"recentErrorRate"`
+	TotalRequests       uint64  `json:"totalRequests"`
+	TotalFailures       uint64  `json:"totalFailures"`
+}
+
+// executorHealth tracks the latency and failures of requests to a single executor.  After failureThreshold
+// consecutive failures the circuit opens and the executor is left out of routing until cooldown has passed, after
+// which it is tried again and the circuit closes on the first success or reopens on the next failure.
+type executorHealth struct {
+	mtx sync.Mutex
+
+	weight           int
+	failureThreshold int
+	cooldown         time.Duration
+
+	online              bool
+	consecutiveFailures int
+	openUntil           time.Time
+	latency             time.Duration
+
+	recent      [recentResultsSize]bool
+	recentNext  int
+	recentCount int
+
+	total, failures uint64
+}
+
+func newExecutorHealth(weight, failureThreshold int, cooldown time.Duration) *executorHealth {
+	if weight < 1 {
+		weight = 1
+	}
+	return &executorHealth{
+		weight:           weight,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		online:           true,
+	}
+}
+
+func (h *executorHealth) recordResult(failed bool) {
+	h.recent[h.recentNext] = failed
+	h.recentNext = (h.recentNext + 1) % recentResultsSize
+	if h.recentCount < recentResultsSize {
+		h.recentCount++
+	}
+	h.total++
+	if failed {
+		h.failures++
+	}
+}
+
+func (h *executorHealth) recordSuccess(latency time.Duration) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.recordResult(false)
+	h.consecutiveFailures = 0
+	h.openUntil = time.Time{}
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(h.latency))
+	}
+}
+
+func (h *executorHealth) recordFailure(now time.Time) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.recordResult(true)
+	h.consecutiveFailures++
+	if h.failureThreshold > 0 && h.consecutiveFailures >= h.failureThreshold {
+		h.openUntil = now.Add(h.cooldown)
+	}
+}
+
+func (h *executorHealth) setOnline(online bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.online = online
+}
+
+// available is false whilst the circuit is open
+func (h *executorHealth) available(now time.Time) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return !now.Before(h.openUntil)
+}
+
+func (h *executorHealth) averageLatency() time.Duration {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.latency
+}
+
+func (h *executorHealth) status(now time.Time) ExecutorStatus {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	recentFailures := 0
+	for i := 0; i < h.recentCount; i++ {
+		if h.recent[i] {
+			recentFailures++
+		}
+	}
+	var errorRate float64
+	if h.recentCount > 0 {
+		errorRate = float64(recentFailures) / float64(h.recentCount)
+	}
+
+	s := ExecutorStatus{
+		Weight:              h.weight,
+		Online:              h.online,
+		CircuitOpen:         now.Before(h.openUntil),
+		ConsecutiveFailures: h.consecutiveFailures,
+		AverageLatencyMs:    float64(h.latency) / float64(time.Millisecond),
+		RecentRequests:      h.recentCount,
+		RecentErrorRate:     errorRate,
+		TotalRequests:       h.total,
+		TotalFailures:       h.failures,
+	}
+	if s.CircuitOpen {
+		s.CircuitOpenUntil = h.openUntil.Unix()
+	}
+	return s
+}
+
+// executorSelector routes requests across executors with smooth weighted round robin.  The configured weight of
+// each executor is scaled down by how much slower it has been than the fastest executor, executors with an open
+// circuit are skipped and executors at their in-flight limit are only used once every executor is.
+type executorSelector struct {
+	mtx     sync.Mutex
+	current map[*Executor]float64
+}
+
+func newExecutorSelector() *executorSelector {
+	return &executorSelector{current: make(map[*Executor]float64)}
+}
+
+// rank returns the executors to try in order, the first is the routing choice and the rest are fallbacks for when
+// it turns out to be offline
+func (s *executorSelector) rank(executors []*Executor, now time.Time) []*Executor {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	candidates := make([]*Executor, 0, len(executors))
+	for _, e := range executors {
+		if e.getHealth().available(now) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	withCapacity := make([]*Executor, 0, len(candidates))
+	for _, e := range candidates {
+		if e.QueueLength() < e.InFlightLimit() {
+			withCapacity = append(withCapacity, e)
+		}
+	}
+	if len(withCapacity) > 0 {
+		candidates = withCapacity
+	}
+
+	var fastest time.Duration
+	for _, e := range candidates {
+		if l := e.getHealth().averageLatency(); l > 0 && (fastest == 0 || l < fastest) {
+			fastest = l
+		}
+	}
+
+	weights := make(map[*Executor]float64, len(candidates))
+	var total float64
+	for _, e := range candidates {
+		w := float64(e.getHealth().weight)
+		if l := e.getHealth().averageLatency(); fastest > 0 && l > 0 {
+			factor := float64(fastest) / float64(l)
+			if factor < minLatencyFactor {
+				factor = minLatencyFactor
+			}
+			w *= factor
+		}
+		weights[e] = w
+		total += w
+		s.current[e] += w
+	}
+
+	ranked := make([]*Executor, len(candidates))
+	copy(ranked, candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return s.current[ranked[i]] > s.current[ranked[j]]
+	})
+	s.current[ranked[0]] -= total
+
+	return ranked
+}
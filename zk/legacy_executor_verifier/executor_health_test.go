@@ -0,0 +1,115 @@
+package legacy_executor_verifier
+
+import (
+	"testing"
+	"time"
+)
+
+func testExecutor(url string, weight, inFlightLimit int) *Executor {
+	return &Executor{
+		grpcUrl:   url,
+		semaphore: make(chan struct{}, inFlightLimit),
+		health:    newExecutorHealth(weight, 2, time.Minute),
+	}
+}
+
+func TestExecutorSelectorWeights(t *testing.T) {
+	a := testExecutor("a", 3, 1)
+	b := testExecutor("b", 1, 1)
+	selector := newExecutorSelector()
+
+	picks := map[string]int{}
+	for i := 0; i < 8; i++ {
+		picks[selector.rank([]*Executor{a, b}, time.Now())[0].grpcUrl]++
+	}
+	if picks["a"] != 6 || picks["b"] != 2 {
+		t.Fatalf("expected a 3:1 split, got %v", picks)
+	}
+}
+
+func TestExecutorSelectorLatency(t *testing.T) {
+	fast := testExecutor("fast", 1, 1)
+	slow := testExecutor("slow", 1, 1)
+	fast.health.recordSuccess(100 * time.Millisecond)
+	slow.health.recordSuccess(400 * time.Millisecond)
+	selector := newExecutorSelector()
+
+	picks := map[string]int{}
+	for i := 0; i < 10; i++ {
+		picks[selector.rank([]*Executor{fast, slow}, time.Now())[0].grpcUrl]++
+	}
+	if picks["fast"] != 8 || picks["slow"] != 2 {
+		t.Fatalf("expected a 4:1 split in favour of the faster executor, got %v", picks)
+	}
+}
+
+func TestExecutorSelectorPrefersSpareCapacity(t *testing.T) {
+	busy := testExecutor("busy", 10, 1)
+	idle := testExecutor("idle", 1, 1)
+	busy.AquireAccess()
+	selector := newExecutorSelector()
+
+	ranked := selector.rank([]*Executor{busy, idle}, time.Now())
+	if len(ranked) != 1 || ranked[0] != idle {
+		t.Fatalf("expected only the idle executor to be ranked")
+	}
+
+	// once every executor is full they are all candidates again
+	idle.AquireAccess()
+	if ranked = selector.rank([]*Executor{busy, idle}, time.Now()); len(ranked) != 2 {
+		t.Fatalf("expected both executors to be ranked, got %d", len(ranked))
+	}
+}
+
+func TestExecutorCircuitBreaker(t *testing.T) {
+	e := testExecutor("a", 1, 1)
+	selector := newExecutorSelector()
+	now := time.Now()
+
+	e.health.recordFailure(now)
+	if len(selector.rank([]*Executor{e}, now)) != 1 {
+		t.Fatalf("circuit should stay closed below the failure threshold")
+	}
+
+	e.health.recordFailure(now)
+	if len(selector.rank([]*Executor{e}, now)) != 0 {
+		t.Fatalf("circuit should open at the failure threshold")
+	}
+	status := e.Status()
+	if !status.CircuitOpen || status.ConsecutiveFailures != 2 || status.RecentErrorRate != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// after the cooldown the executor is tried again and a success closes the circuit
+	later := now.Add(time.Minute)
+	if len(selector.rank([]*Executor{e}, later)) != 1 {
+		t.Fatalf("executor should be retried after the cooldown")
+	}
+	e.health.recordSuccess(time.Millisecond)
+	status = e.Status()
+	if status.CircuitOpen || status.ConsecutiveFailures != 0 || status.TotalRequests != 3 || status.TotalFailures != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestExecutorStatusesWithoutHealth(t *testing.T) {
+	// executors built outside NewExecutors have no health until first used
+	executors := []*Executor{
+		{grpcUrl: "internal-a:50071", semaphore: make(chan struct{}, 1)},
+		{grpcUrl: "internal-b:50071", semaphore: make(chan struct{}, 2)},
+	}
+	executors[1].getHealth().recordFailure(time.Now())
+
+	statuses := ExecutorStatuses(executors)
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(statuses))
+	}
+	for i, status := range statuses {
+		if status.Index != i || status.Weight != 1 || status.InFlightLimit != i+1 {
+			t.Fatalf("unexpected status %+v", status)
+		}
+	}
+	if statuses[0].TotalFailures != 0 || statuses[1].TotalFailures != 1 {
+		t.Fatalf("unexpected failures %+v", statuses)
+	}
+}
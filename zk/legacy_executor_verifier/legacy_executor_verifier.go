@@ -118,7 +118,7 @@ type LegacyExecutorVerifier struct {
 	db                     kv.RwDB
 	cfg                    ethconfig.Zk
 	executors              []*Executor
-	executorNumber         int
+	executorSelector       *executorSelector
 	cancelAllVerifications atomic.Bool
 
 	streamServer     server.DataStreamServer
@@ -139,7 +139,7 @@ func NewLegacyExecutorVerifier(
 		db:                     db,
 		cfg:                    cfg,
 		executors:              executors,
-		executorNumber:         0,
+		executorSelector:       newExecutorSelector(),
 		cancelAllVerifications: atomic.Bool{},
 		streamServer:           streamServer,
 		WitnessGenerator:       witnessGenerator,
@@ -428,25 +428,33 @@ func (v *LegacyExecutorVerifier) CancelAllRequests() {
 	v.promises = make([]*Promise[*VerifierBundle], 0)
 }
 
+// GetNextOnlineAvailableExecutor picks an executor by weight, latency and spare capacity, falling back through the
+// others in order if it is offline.  Returns nil if every executor is offline or has an open circuit.
 func (v *LegacyExecutorVerifier) GetNextOnlineAvailableExecutor() *Executor {
-	var exec *Executor
-
-	// TODO: find executors with spare capacity
-
-	// attempt to find an executor that is online amongst them all
-	for i := 0; i < len(v.executors); i++ {
-		v.executorNumber++
-		if v.executorNumber >= len(v.executors) {
-			v.executorNumber = 0
-		}
-		temp := v.executors[v.executorNumber]
-		if temp.CheckOnline() {
-			exec = temp
-			break
+	now := time.Now()
+	for _, e := range v.executorSelector.rank(v.executors, now) {
+		online := e.CheckOnline()
+		e.getHealth().setOnline(online)
+		if online {
+			return e
 		}
+		// failing to connect counts towards opening the circuit in the same way as a failed request
+		e.getHealth().recordFailure(now)
 	}
 
-	return exec
+	return nil
+}
+
+// ExecutorStatuses returns the health and load of each executor in the order they were configured.  Executors are
+// identified by index rather than url so the status can be shared without revealing internal addresses.
+func ExecutorStatuses(executors []*Executor) []ExecutorStatus {
+	statuses := make([]ExecutorStatus, 0, len(executors))
+	for i, e := range executors {
+		status := e.Status()
+		status.Index = i
+		statuses = append(statuses, status)
+	}
+	return statuses
 }
 
 func (v *LegacyExecutorVerifier) GetWholeBatchStreamBytes(
@@ -0,0 +1,355 @@
+package sequencer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+)
+
+type FailoverConfig struct {
+	Role      Role
+	LeaseFile string
+	LeaseTTL  time.Duration
+	// Holder identifies this node in the lease file, with the urls other nodes follow it on while it holds the lease
+	Holder Leader
+	// SequencerRpcUrl and SequencerDataStreamUrl are followed when the lease holder doesn't record its own urls
+	SequencerRpcUrl        string
+	SequencerDataStreamUrl string
+	// LocalBlock returns the highest block this node has synced
+	LocalBlock func(ctx context.Context) (uint64, error)
+}
+
+// FailoverStatus is what a node knows about the sequencer lease and, while following, the active sequencer
+type FailoverStatus struct {
+	Role           Role      `json:"role"`
+	Sequencing     bool      `json:"sequencing"`
+	Holder         string    `json:"holder"`
+	Lease          *Lease    `json:"lease"`
+	Leader         Leader    `json:"leader"`
+	LocalBlock     uint64    `json:"localBlock"`
+	SequencerBlock uint64    `json:"sequencerBlock"`
+	LastSeen       time.Time `json:"lastSeen"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Failover keeps a sequencer's lease renewed and lets the other nodes sharing the lease follow the holder until one
+// of them is promoted.  Nodes change role in place, Switch stops following and starts sequencing as soon as the node
+// acquires the lease, and goes back to following the new holder when it steps down or the lease is taken over.
+type Failover struct {
+	cfg   FailoverConfig
+	lease *LeaseFile
+
+	mu             sync.Mutex
+	sequencing     bool
+	leader         Leader
+	localBlock     uint64
+	sequencerBlock uint64
+	lastSeen       time.Time
+	lastErr        error
+
+	// changed is signalled when the node starts or stops sequencing, or the node it follows changes
+	changed chan struct{}
+}
+
+func NewFailover(cfg FailoverConfig) *Failover {
+	return &Failover{
+		cfg:     cfg,
+		lease:   NewLeaseFile(cfg.LeaseFile, cfg.Holder, cfg.LeaseTTL),
+		leader:  Leader{RpcUrl: cfg.SequencerRpcUrl, DataStreamUrl: cfg.SequencerDataStreamUrl},
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Resume takes the lease for a sequencer, or for a standby that still holds it from before a restart, and reports
+// whether the node should start producing blocks.  A sequencer whose lease has been taken over by another node
+// follows it instead.
+func (f *Failover) Resume() (bool, error) {
+	current, err := f.lease.Read()
+	if err != nil {
+		return false, err
+	}
+	f.follow(current)
+
+	switch f.cfg.Role {
+	case RoleSequencer:
+	case RoleStandby:
+		if current == nil || current.Holder != f.cfg.Holder.Holder || !time.Now().Before(current.Expires) {
+			log.Info("Starting as a standby sequencer", "leaseHolder", leaseHolder(current))
+			return false, nil
+		}
+	default:
+		return false, fmt.Errorf("role %s does not take part in sequencer failover", f.cfg.Role)
+	}
+
+	lease, err := f.lease.Acquire()
+	if errors.Is(err, ErrLeaseHeld) {
+		log.Warn("Sequencer lease is held by another node, following it until this node is promoted", "err", err)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Info("Acquired the sequencer lease", "holder", lease.Holder, "epoch", lease.Epoch, "expires", lease.Expires)
+	f.setSequencing(true)
+	return true, nil
+}
+
+func leaseHolder(lease *Lease) string {
+	if lease == nil {
+		return "none"
+	}
+	return lease.Holder
+}
+
+// Sequencing reports whether the node holds the lease and should be producing blocks
+func (f *Failover) Sequencing() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sequencing
+}
+
+func (f *Failover) setSequencing(sequencing bool) {
+	f.mu.Lock()
+	changed := f.sequencing != sequencing
+	f.sequencing = sequencing
+	f.mu.Unlock()
+	if changed {
+		f.notify()
+	}
+}
+
+func (f *Failover) notify() {
+	select {
+	case f.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Leader returns the node to follow while this node is not sequencing, the configured urls stand in for a lease
+// holder that doesn't record its own
+func (f *Failover) Leader() Leader {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.leader
+}
+
+// SequencerRpcUrl returns the rpc url of the node to forward requests to while this node is not sequencing
+func (f *Failover) SequencerRpcUrl() string {
+	return f.Leader().RpcUrl
+}
+
+// follow records the holder of the lease as the node to follow, signalling a change when it is a different node
+func (f *Failover) follow(lease *Lease) {
+	leader := Leader{RpcUrl: f.cfg.SequencerRpcUrl, DataStreamUrl: f.cfg.SequencerDataStreamUrl}
+	if lease != nil && lease.Holder != f.cfg.Holder.Holder {
+		leader.Holder = lease.Holder
+		if lease.RpcUrl != "" {
+			leader.RpcUrl = lease.RpcUrl
+		}
+		if lease.DataStreamUrl != "" {
+			leader.DataStreamUrl = lease.DataStreamUrl
+		}
+	}
+
+	f.mu.Lock()
+	changed := f.leader != leader
+	f.leader = leader
+	sequencing := f.sequencing
+	f.mu.Unlock()
+	if changed && !sequencing {
+		log.Info("Following the sequencer", "holder", leader.Holder, "rpc", leader.RpcUrl, "datastream", leader.DataStreamUrl)
+		f.notify()
+	}
+}
+
+// Switch runs sequence while the node holds the lease and follow, towards the lease holder, while it doesn't.  The
+// one running is cancelled through its context as soon as the node changes role or the lease changes hands, and the
+// other started once it has returned.  Switch returns when ctx is done.
+func (f *Failover) Switch(ctx context.Context, sequence func(ctx context.Context), follow func(ctx context.Context, leader Leader)) {
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		sequencing, leader := f.Sequencing(), f.Leader()
+		go func() {
+			defer close(done)
+			if sequencing {
+				sequence(runCtx)
+			} else {
+				follow(runCtx, leader)
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+		case <-f.changed:
+		}
+		cancel()
+		<-done
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Run renews the lease while sequencing and polls the lease holder while following, until ctx is done
+func (f *Failover) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.cfg.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		if f.Sequencing() {
+			f.renew()
+		} else {
+			f.poll(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Failover) renew() {
+	_, err := f.lease.Renew()
+	if err == nil {
+		return
+	}
+	if errors.Is(err, ErrLeaseLost) {
+		// another node has been promoted, we must not produce another block and follow it instead
+		log.Error("Sequencer lease taken over by another node, following it", "err", err)
+		f.setSequencing(false)
+		f.poll(context.Background())
+		return
+	}
+	// block production stops by itself if the lease can't be renewed before it runs out
+	log.Warn("Failed to renew the sequencer lease", "err", err)
+}
+
+func (f *Failover) poll(ctx context.Context) {
+	lease, err := f.lease.Read()
+	if err == nil {
+		f.follow(lease)
+	}
+
+	var localBlock, sequencerBlock uint64
+	if err == nil {
+		localBlock, err = f.cfg.LocalBlock(ctx)
+	}
+	if err == nil {
+		sequencerBlock, err = fetchBlockNumber(f.SequencerRpcUrl())
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastErr = err
+	if err != nil {
+		log.Warn("Failed to reach the active sequencer", "err", err)
+		return
+	}
+	f.localBlock = localBlock
+	f.sequencerBlock = sequencerBlock
+	f.lastSeen = time.Now()
+}
+
+func fetchBlockNumber(url string) (uint64, error) {
+	res, err := client.JSONRPCCall(url, "eth_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	if res.Error != nil {
+		return 0, fmt.Errorf("RPC error response: %s", res.Error.Message)
+	}
+	var number hexutil.Uint64
+	if err = json.Unmarshal(res.Result, &number); err != nil {
+		return 0, err
+	}
+	return uint64(number), nil
+}
+
+// Check fences block production, it fails unless the node holds a lease it renewed recently.  A nil Failover,
+// when no lease file is configured, never fences.
+func (f *Failover) Check() error {
+	if f == nil {
+		return nil
+	}
+	return f.lease.Check()
+}
+
+func (f *Failover) Status() *FailoverStatus {
+	lease, err := f.lease.Read()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := &FailoverStatus{
+		Role:           f.cfg.Role,
+		Sequencing:     f.sequencing,
+		Holder:         f.cfg.Holder.Holder,
+		Lease:          lease,
+		Leader:         f.leader,
+		LocalBlock:     f.localBlock,
+		SequencerBlock: f.sequencerBlock,
+		LastSeen:       f.lastSeen,
+	}
+	if err == nil {
+		err = f.lastErr
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Promote makes a following node the sequencer.  It fails while the active sequencer keeps its lease renewed, so it
+// has to step down or be gone for a lease ttl first.  Unless force is set the node must also have synced every block
+// it last saw on the active sequencer.  The node starts sequencing in place once it holds the lease.
+func (f *Failover) Promote(ctx context.Context, force bool) (*FailoverStatus, error) {
+	if f.Sequencing() {
+		return nil, errors.New("node is already the sequencer")
+	}
+
+	if !force {
+		f.poll(ctx)
+		f.mu.Lock()
+		localBlock, sequencerBlock, lastSeen := f.localBlock, f.sequencerBlock, f.lastSeen
+		f.mu.Unlock()
+		if lastSeen.IsZero() {
+			return nil, errors.New("the active sequencer has never been reached, promote with force to skip the sync check")
+		}
+		if localBlock < sequencerBlock {
+			return nil, fmt.Errorf("node is %d blocks behind the active sequencer, promote with force to skip the sync check", sequencerBlock-localBlock)
+		}
+	}
+
+	lease, err := f.lease.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Promoted to sequencer", "epoch", lease.Epoch)
+	f.setSequencing(true)
+
+	return f.Status(), nil
+}
+
+// StepDown releases the lease of the sequencer so another node can be promoted straight away.  The node goes on to
+// follow whichever node is promoted next.
+func (f *Failover) StepDown() error {
+	if !f.Sequencing() {
+		return errors.New("node is not the sequencer")
+	}
+
+	if err := f.lease.Release(); err != nil {
+		return err
+	}
+	log.Info("Released the sequencer lease, following the next sequencer")
+	f.setSequencing(false)
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package sequencer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+)
+
+const testLeaseTTL = 600 * time.Millisecond
+
+// testBlock records the node that produced a block and the lease epoch it was produced under
+type testBlock struct {
+	Producer string `json:"producer"`
+	Epoch    uint64 `json:"epoch"`
+}
+
+// testNode is a node with its own chain and rpc server.  It produces a block every few ms while it passes the fence
+// and otherwise syncs the chain of the lease holder from the rpc url recorded in the lease, switching between the
+// two in place through Failover.Switch.  Its chain outlives a restart like a datadir does.
+type testNode struct {
+	t      *testing.T
+	name   string
+	cfg    FailoverConfig
+	server *httptest.Server
+
+	mu    sync.Mutex
+	chain []testBlock
+
+	failover *Failover
+	cancel   context.CancelFunc
+	// cancelRun stops renewing the lease while the node keeps running, as if its process were paused
+	cancelRun context.CancelFunc
+	stopped   chan struct{}
+}
+
+func newTestNode(t *testing.T, name string, role Role, leaseFile, sequencerUrl string) *testNode {
+	n := &testNode{t: t, name: name}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serve))
+	t.Cleanup(n.server.Close)
+	n.cfg = FailoverConfig{
+		Role:            role,
+		LeaseFile:       leaseFile,
+		LeaseTTL:        testLeaseTTL,
+		Holder:          Leader{Holder: name, RpcUrl: n.server.URL},
+		SequencerRpcUrl: sequencerUrl,
+		LocalBlock: func(ctx context.Context) (uint64, error) {
+			return n.head(), nil
+		},
+	}
+	return n
+}
+
+func (n *testNode) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var result interface{}
+	switch req.Method {
+	case "eth_blockNumber":
+		result = hexutil.Uint64(n.head())
+	case "test_chain":
+		result = n.blocks()
+	default:
+		http.Error(w, "unknown method", http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+}
+
+func (n *testNode) head() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return uint64(len(n.chain))
+}
+
+func (n *testNode) blocks() []testBlock {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]testBlock(nil), n.chain...)
+}
+
+func (n *testNode) start() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, cancelRun := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	n.cancel, n.cancelRun, n.stopped = cancel, cancelRun, stopped
+	n.failover = NewFailover(n.cfg)
+	n.t.Cleanup(cancel)
+
+	sequencing, err := n.failover.Resume()
+	require.NoError(n.t, err)
+	go n.failover.Run(runCtx)
+	go func(failover *Failover) {
+		defer close(stopped)
+		failover.Switch(ctx, func(ctx context.Context) {
+			n.sequence(ctx, failover)
+		}, n.follow)
+	}(n.failover)
+	return sequencing
+}
+
+func (n *testNode) stop() {
+	n.cancel()
+	<-n.stopped
+}
+
+func (n *testNode) sequence(ctx context.Context, failover *Failover) {
+	for ctx.Err() == nil {
+		if failover.Check() == nil {
+			if epoch := failover.lease.Epoch(); epoch != 0 {
+				n.mu.Lock()
+				n.chain = append(n.chain, testBlock{Producer: n.name, Epoch: epoch})
+				n.mu.Unlock()
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// follow copies the blocks of the leader this node doesn't have yet, like the datastream does
+func (n *testNode) follow(ctx context.Context, leader Leader) {
+	for ctx.Err() == nil {
+		res, err := client.JSONRPCCall(leader.RpcUrl, "test_chain")
+		if err == nil && res.Error == nil {
+			var chain []testBlock
+			require.NoError(n.t, json.Unmarshal(res.Result, &chain))
+			n.mu.Lock()
+			if len(chain) > len(n.chain) {
+				n.chain = append(n.chain, chain[len(n.chain):]...)
+			}
+			n.mu.Unlock()
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (n *testNode) grows() bool {
+	head := n.head()
+	return assert.Eventually(n.t, func() bool { return n.head() >= head+5 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFailoverPromotion(t *testing.T) {
+	// both nodes were configured with the url of a sequencer that is gone, they follow whoever holds the lease
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	leaseFile := filepath.Join(t.TempDir(), "sequencer.lease")
+	a := newTestNode(t, "a", RoleSequencer, leaseFile, gone.URL)
+	b := newTestNode(t, "b", RoleStandby, leaseFile, gone.URL)
+
+	// a takes the free lease and b follows it
+	require.True(t, a.start())
+	require.False(t, b.start())
+	require.True(t, a.grows())
+	require.True(t, b.grows())
+	require.Equal(t, a.server.URL, b.failover.SequencerRpcUrl())
+
+	// b can't be promoted while a keeps its lease renewed
+	_, err := b.failover.Promote(context.Background(), true)
+	require.ErrorIs(t, err, ErrLeaseHeld)
+	require.False(t, b.failover.Sequencing())
+
+	// a planned switch, a steps down and follows b which is promoted in place once it has a's last block
+	require.NoError(t, a.failover.StepDown())
+	require.Eventually(t, func() bool {
+		_, err = b.failover.Promote(context.Background(), false)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.True(t, b.failover.Sequencing())
+	require.False(t, a.failover.Sequencing())
+	require.True(t, b.grows())
+	require.True(t, a.grows())
+
+	// a restarted as the sequencer finds the lease held by b and follows it instead of refusing to start
+	a.stop()
+	require.False(t, a.start())
+	require.True(t, a.grows())
+	require.Equal(t, b.server.URL, a.failover.SequencerRpcUrl())
+
+	// b is paused and a takes over once b's lease runs out, b stops producing blocks before that
+	b.cancelRun()
+	require.Eventually(t, func() bool {
+		_, err = a.failover.Promote(context.Background(), true)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.ErrorIs(t, b.failover.Check(), ErrNoLease)
+	require.True(t, a.grows())
+
+	// when b resumes it finds out on its next renewal it was superseded and follows a in place
+	b.failover.renew()
+	require.False(t, b.failover.Sequencing())
+	require.True(t, b.grows())
+
+	status := b.failover.Status()
+	require.Equal(t, "a", status.Lease.Holder)
+	require.Equal(t, a.server.URL, status.Lease.RpcUrl)
+	require.Equal(t, uint64(3), status.Lease.Epoch)
+
+	a.stop()
+	b.stop()
+	chainA, chainB := a.blocks(), b.blocks()
+	require.Equal(t, chainA[:len(chainB)], chainB, "b diverged from a")
+	for i := 1; i < len(chainA); i++ {
+		require.LessOrEqual(t, chainA[i-1].Epoch, chainA[i].Epoch, "block %d produced under a superseded lease", i)
+	}
+	require.Equal(t, testBlock{Producer: "a", Epoch: 1}, chainA[0])
+	require.Equal(t, testBlock{Producer: "a", Epoch: 3}, chainA[len(chainA)-1])
+}
+
+func TestLeaseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequencer.lease")
+	a := NewLeaseFile(path, Leader{Holder: "a"}, time.Minute)
+	b := NewLeaseFile(path, Leader{Holder: "b"}, time.Minute)
+
+	lease, err := a.Read()
+	require.NoError(t, err)
+	require.Nil(t, lease)
+	require.ErrorIs(t, a.Check(), ErrNoLease)
+	_, err = a.Renew()
+	require.ErrorIs(t, err, ErrNoLease)
+
+	lease, err = a.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), lease.Epoch)
+	require.NoError(t, a.Check())
+
+	_, err = b.Acquire()
+	require.ErrorIs(t, err, ErrLeaseHeld)
+
+	// re-acquiring our own lease, after a restart, moves to a new epoch
+	lease, err = a.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), lease.Epoch)
+	lease, err = a.Renew()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), lease.Epoch)
+
+	require.NoError(t, a.Release())
+	require.ErrorIs(t, a.Check(), ErrNoLease)
+	lease, err = b.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), lease.Epoch)
+
+	// a stale holder can neither renew nor release the lease
+	stale := NewLeaseFile(path, Leader{Holder: "a"}, time.Minute)
+	stale.held(2, time.Now())
+	_, err = stale.Renew()
+	require.ErrorIs(t, err, ErrLeaseLost)
+	require.ErrorIs(t, stale.Check(), ErrNoLease)
+	stale.held(2, time.Now())
+	require.ErrorIs(t, stale.Release(), ErrLeaseLost)
+	require.NoError(t, b.Check())
+}
+
+func TestResolveRole(t *testing.T) {
+	t.Setenv(SEQUENCER_ENV_KEY, "")
+	role, err := ResolveRole("")
+	require.NoError(t, err)
+	require.Equal(t, RoleRpc, role)
+	role, err = ResolveRole("standby")
+	require.NoError(t, err)
+	require.Equal(t, RoleStandby, role)
+	_, err = ResolveRole("leader")
+	require.Error(t, err)
+
+	t.Setenv(SEQUENCER_ENV_KEY, "1")
+	role, err = ResolveRole("")
+	require.NoError(t, err)
+	require.Equal(t, RoleSequencer, role)
+	_, err = ResolveRole("rpc")
+	require.Error(t, err)
+}
+
+func TestIsSequencerFollowsRole(t *testing.T) {
+	defer SetRole(CurrentRole())
+
+	// a sequencer started with the environment variable that steps down must stop counting as the sequencer
+	t.Setenv(SEQUENCER_ENV_KEY, "1")
+	SetRole(RoleSequencer)
+	require.True(t, IsSequencer())
+	SetRole(RoleRpc)
+	require.False(t, IsSequencer())
+	SetRole(RoleStandby)
+	require.False(t, IsSequencer())
+}
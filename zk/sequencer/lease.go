@@ -0,0 +1,196 @@
+package sequencer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+var (
+	ErrLeaseHeld = errors.New("sequencer lease is held by another node")
+	ErrLeaseLost = errors.New("sequencer lease was taken over by another node")
+	ErrNoLease   = errors.New("this node does not hold a valid sequencer lease")
+)
+
+// Leader identifies a node in the lease file along with the urls other nodes follow it on while it holds the lease
+type Leader struct {
+	Holder        string `json:"holder"`
+	RpcUrl        string `json:"rpcUrl,omitempty"`
+	DataStreamUrl string `json:"dataStreamUrl,omitempty"`
+}
+
+// Lease is the content of the lease file.  The epoch is the fencing token, it increases every time the lease is
+// acquired so a sequencer that was superseded finds out on its next renewal.
+type Lease struct {
+	Leader
+	Epoch   uint64    `json:"epoch"`
+	Expires time.Time `json:"expires"`
+}
+
+// LeaseFile fences block production between sequencers sharing the file, only the holder of an unexpired lease may
+// produce blocks.  Updates are serialised by a lock file next to it.
+type LeaseFile struct {
+	path   string
+	holder Leader
+	ttl    time.Duration
+
+	mu sync.Mutex
+	// epoch of the lease we hold, 0 when we don't hold it
+	epoch uint64
+	// validUntil stops us producing blocks a margin before other nodes see the lease expire, to allow for clock skew
+	validUntil time.Time
+}
+
+func NewLeaseFile(path string, holder Leader, ttl time.Duration) *LeaseFile {
+	return &LeaseFile{path: path, holder: holder, ttl: ttl}
+}
+
+// Read returns the lease in the file, nil when it has never been acquired
+func (l *LeaseFile) Read() (*Lease, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lease Lease
+	if err = json.Unmarshal(data, &lease); err != nil {
+		return nil, fmt.Errorf("invalid lease file %s: %w", l.path, err)
+	}
+	return &lease, nil
+}
+
+func (l *LeaseFile) update(next func(current *Lease, now time.Time) (*Lease, error)) (*Lease, error) {
+	lock := flock.New(l.path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+	defer lock.Unlock()
+
+	current, err := l.Read()
+	if err != nil {
+		return nil, err
+	}
+	lease, err := next(current, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmp.Name(), l.path); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+func (l *LeaseFile) held(epoch uint64, start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.epoch = epoch
+	if epoch == 0 {
+		l.validUntil = time.Time{}
+		return
+	}
+	l.validUntil = start.Add(l.ttl - l.ttl/4)
+}
+
+// Acquire takes the lease when it is free, expired or already ours, with a new epoch
+func (l *LeaseFile) Acquire() (*Lease, error) {
+	start := time.Now()
+	lease, err := l.update(func(current *Lease, now time.Time) (*Lease, error) {
+		if current != nil && current.Holder != l.holder.Holder && now.Before(current.Expires) {
+			return nil, fmt.Errorf("%w: %s until %s", ErrLeaseHeld, current.Holder, current.Expires.Format(time.RFC3339))
+		}
+		next := &Lease{Leader: l.holder, Epoch: 1, Expires: now.Add(l.ttl)}
+		if current != nil {
+			next.Epoch = current.Epoch + 1
+		}
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.held(lease.Epoch, start)
+	return lease, nil
+}
+
+// Renew extends the lease we hold, failing with ErrLeaseLost when another node has acquired it since
+func (l *LeaseFile) Renew() (*Lease, error) {
+	epoch := l.Epoch()
+	if epoch == 0 {
+		return nil, ErrNoLease
+	}
+	start := time.Now()
+	lease, err := l.update(func(current *Lease, now time.Time) (*Lease, error) {
+		if current == nil || current.Holder != l.holder.Holder || current.Epoch != epoch {
+			return nil, ErrLeaseLost
+		}
+		return &Lease{Leader: l.holder, Epoch: epoch, Expires: now.Add(l.ttl)}, nil
+	})
+	if errors.Is(err, ErrLeaseLost) {
+		l.held(0, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.held(epoch, start)
+	return lease, nil
+}
+
+// Release gives up the lease we hold so another node can acquire it straight away
+func (l *LeaseFile) Release() error {
+	epoch := l.Epoch()
+	if epoch == 0 {
+		return ErrNoLease
+	}
+	l.held(0, time.Time{})
+	_, err := l.update(func(current *Lease, now time.Time) (*Lease, error) {
+		if current == nil || current.Holder != l.holder.Holder || current.Epoch != epoch {
+			return nil, ErrLeaseLost
+		}
+		// the lease still names us so the other nodes keep following us until one of them is promoted
+		return &Lease{Leader: l.holder, Epoch: epoch, Expires: now}, nil
+	})
+	return err
+}
+
+// Epoch returns the epoch of the lease we hold, 0 when we don't hold it
+func (l *LeaseFile) Epoch() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.epoch
+}
+
+// Check returns ErrNoLease unless we hold the lease and it has been renewed recently enough to produce blocks
+func (l *LeaseFile) Check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.epoch == 0 || !time.Now().Before(l.validUntil) {
+		return ErrNoLease
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package sequencer
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+type Role string
+
+const (
+	// RoleRpc follows the sequencer through its datastream and forwards transactions to it
+	RoleRpc Role = "rpc"
+	// RoleSequencer produces the blocks
+	RoleSequencer Role = "sequencer"
+	// RoleStandby follows the active sequencer like an rpc node until it is promoted and takes over the lease
+	RoleStandby Role = "standby"
+)
+
+var role atomic.Value
+
+// ResolveRole returns the role for the zkevm.sequencer-role flag, falling back to the environment variable when the
+// flag is not set
+func ResolveRole(flag string) (Role, error) {
+	envSequencer := os.Getenv(SEQUENCER_ENV_KEY) == "1"
+	if flag == "" {
+		if envSequencer {
+			return RoleSequencer, nil
+		}
+		return RoleRpc, nil
+	}
+
+	r := Role(flag)
+	switch r {
+	case RoleRpc, RoleSequencer, RoleStandby:
+	default:
+		return "", fmt.Errorf("unknown sequencer role %q, must be one of %s, %s or %s", flag, RoleRpc, RoleSequencer, RoleStandby)
+	}
+	if envSequencer && r != RoleSequencer {
+		return "", fmt.Errorf("sequencer role %q conflicts with %s=1", flag, SEQUENCER_ENV_KEY)
+	}
+	return r, nil
+}
+
+// SetRole sets the role the node is running as, a standby is switched to RoleSequencer when it holds the lease
+func SetRole(r Role) {
+	role.Store(r)
+}
+
+// CurrentRole returns the role set with SetRole.  The tools that don't parse the zkevm.sequencer-role flag never set
+// one, they run as a sequencer when the environment variable asks for it.
+func CurrentRole() Role {
+	if r, ok := role.Load().(Role); ok {
+		return r
+	}
+	if r, err := ResolveRole(""); err == nil {
+		return r
+	}
+	return RoleRpc
+}
@@ -1,14 +1,13 @@
 package sequencer
 
-import "os"
-
 const (
-	// Env variable to enable sequencer
+	// Env variable to enable sequencer, superseded by the zkevm.sequencer-role flag
 	SEQUENCER_ENV_KEY = "CDK_ERIGON_SEQUENCER"
 )
 
+// IsSequencer reports whether the node is producing blocks.  It follows the role alone, so a node sharing the
+// sequencer lease stops counting as the sequencer as soon as it steps down or loses the lease, whatever the
+// environment variable says.
 func IsSequencer() bool {
-	// TODO: SEQ: make a commmand-line flag for that and replace the env variable
-	// read from the environment
-	return os.Getenv(SEQUENCER_ENV_KEY) == "1"
+	return CurrentRole() == RoleSequencer
 }
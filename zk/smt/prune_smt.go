@@ -0,0 +1,358 @@
+package smt
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/metrics"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/utils"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+const (
+	// PruneModeFull leaves the SMT as it has always been, writes delete the nodes they replace and only the latest root
+	// is whole
+	PruneModeFull = "full"
+	// PruneModePruned keeps the nodes replaced by writes so the roots of the retained batches stay whole, the pruner
+	// deletes the nodes no longer reachable from them
+	PruneModePruned = "pruned"
+)
+
+var (
+	prunedNodesCounter = metrics.GetOrCreateCounter("zkevm_smt_pruned_nodes")
+	prunedBytesCounter = metrics.GetOrCreateCounter("zkevm_smt_pruned_bytes")
+)
+
+// the prune table holds the whole state of a round, the kind of each entry is given by the first byte of its key
+const (
+	prunePrefixMarked  = byte('m') // node key -> empty, nodes reachable from the retained roots
+	prunePrefixPending = byte('p') // node key -> empty, marked nodes whose children are still to be marked
+)
+
+var (
+	pruneProgressSweepFrom   = []byte("sweepFrom")
+	pruneProgressRoundMarked = []byte("roundMarked")
+	pruneProgressRoundNodes  = []byte("roundNodes")
+	pruneProgressRoundBytes  = []byte("roundBytes")
+)
+
+type PruneStats struct {
+	Rounds         uint64
+	NodesDeleted   uint64
+	BytesReclaimed uint64
+}
+
+// Pruner garbage collects the tables of an SMT written with SMT.SetKeepReplacedNodes.  A round marks every node
+// reachable from the retained roots and then sweeps the SMT table deleting the rest.  Each call to Prune only does a
+// bounded amount of work, so a round spans many stage loops.  Roots that appear while a round is in progress are
+// marked before sweeping continues, the nodes below them are therefore never deleted.  The marked nodes, the nodes
+// still to visit and the sweep position are kept in the HermezSmtPrune table rather than in memory, so a round survives
+// a restart and resumes where the last committed call left it.
+type Pruner struct {
+	step  int
+	stats PruneStats
+}
+
+func NewPruner(step int) *Pruner {
+	if step <= 0 {
+		step = 1
+	}
+	return &Pruner{step: step}
+}
+
+func (p *Pruner) Stats() PruneStats {
+	return p.stats
+}
+
+// RetainedBatches returns the batches from fromBatch to latestBatch, the last retainBatches up to the batch the SMT has
+// been built to, whose state roots the pruner keeps whole.  The nodes below any older root may already be partly
+// deleted, even while its root node is still there.
+func RetainedBatches(tx kv.Tx, retainBatches uint64) (fromBatch, latestBatch uint64, err error) {
+	latestBlock, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return 0, 0, err
+	}
+	latestBatch, err = hermez_db.NewHermezDbReader(tx).GetBatchNoByL2Block(latestBlock)
+	if err != nil {
+		return 0, 0, err
+	}
+	fromBatch = latestBatch + 1
+	if fromBatch > retainBatches {
+		fromBatch -= retainBatches
+	} else {
+		fromBatch = 0
+	}
+	return fromBatch, latestBatch, nil
+}
+
+// Prune marks the retained roots, the last root of the SMT is always retained, and sweeps as much of the SMT table as
+// the step allows.  It must run in the same transaction as anything writing to the SMT since the last call.
+func (p *Pruner) Prune(logPrefix string, tx kv.RwTx, roots []*big.Int) error {
+	eridb := db2.NewRoEriDb(tx)
+
+	lastRoot, err := eridb.GetLastRoot()
+	if err != nil {
+		return err
+	}
+	for _, root := range append(roots, lastRoot) {
+		if err = p.markPending(tx, utils.ScalarToRoot(root)); err != nil {
+			return err
+		}
+	}
+
+	budget, err := p.mark(tx, eridb, p.step)
+	if err != nil {
+		return err
+	}
+	pending, err := hasPendingPruneNodes(tx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return nil
+	}
+
+	return p.sweep(logPrefix, tx, budget)
+}
+
+// markPending queues the node to be marked unless it already is
+func (p *Pruner) markPending(tx kv.RwTx, key utils.NodeKey) error {
+	if key.IsZero() {
+		return nil
+	}
+	marked, err := tx.Has(db2.TablePrune, pruneKey(prunePrefixMarked, key))
+	if err != nil || marked {
+		return err
+	}
+	return tx.Put(db2.TablePrune, pruneKey(prunePrefixPending, key), []byte{})
+}
+
+// mark walks the trees below the pending nodes, visiting at most budget nodes, and returns the budget left
+func (p *Pruner) mark(tx kv.RwTx, eridb *db2.EriRoDb, budget int) (int, error) {
+	roundMarked, err := getPruneProgressCounter(tx, pruneProgressRoundMarked)
+	if err != nil {
+		return 0, err
+	}
+	startMarked := roundMarked
+
+	c, err := tx.RwCursor(db2.TablePrune)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	for budget > 0 {
+		k, _, err := c.Seek([]byte{prunePrefixPending})
+		if err != nil {
+			return 0, err
+		}
+		if k == nil || k[0] != prunePrefixPending {
+			break
+		}
+		key := nodeKeyFromBytes(k[1:])
+		if err = c.DeleteCurrent(); err != nil {
+			return 0, err
+		}
+
+		marked, err := tx.Has(db2.TablePrune, pruneKey(prunePrefixMarked, key))
+		if err != nil {
+			return 0, err
+		}
+		if marked {
+			continue
+		}
+		if err = tx.Put(db2.TablePrune, pruneKey(prunePrefixMarked, key), []byte{}); err != nil {
+			return 0, err
+		}
+		roundMarked++
+		budget--
+
+		node, err := eridb.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if node.IsZero() {
+			// already deleted, e.g. a root that was unwound
+			continue
+		}
+
+		if node.IsFinalNode() {
+			// a leaf references its value by hash, values have no children
+			valueKey := utils.NodeKeyFromUint64Array(node[4:8])
+			if err = tx.Put(db2.TablePrune, pruneKey(prunePrefixMarked, valueKey), []byte{}); err != nil {
+				return 0, err
+			}
+			roundMarked++
+			continue
+		}
+		for _, child := range []utils.NodeKey{utils.NodeKeyFromUint64Array(node[0:4]), utils.NodeKeyFromUint64Array(node[4:8])} {
+			if err = p.markPending(tx, child); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if roundMarked != startMarked {
+		if err = putPruneProgressCounter(tx, pruneProgressRoundMarked, roundMarked); err != nil {
+			return 0, err
+		}
+	}
+	return budget, nil
+}
+
+func (p *Pruner) sweep(logPrefix string, tx kv.RwTx, budget int) error {
+	sweepFrom, err := tx.GetOne(db2.TablePrune, pruneProgressSweepFrom)
+	if err != nil {
+		return err
+	}
+	roundMarked, err := getPruneProgressCounter(tx, pruneProgressRoundMarked)
+	if err != nil {
+		return err
+	}
+	roundNodes, err := getPruneProgressCounter(tx, pruneProgressRoundNodes)
+	if err != nil {
+		return err
+	}
+	roundBytes, err := getPruneProgressCounter(tx, pruneProgressRoundBytes)
+	if err != nil {
+		return err
+	}
+
+	c, err := tx.RwCursor(db2.TableSmt)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var k, v []byte
+	if sweepFrom == nil {
+		k, v, err = c.First()
+	} else {
+		k, v, err = c.Seek(sweepFrom)
+	}
+
+	for ; k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if budget == 0 {
+			if err = tx.Put(db2.TablePrune, pruneProgressSweepFrom, common.Copy(k)); err != nil {
+				return err
+			}
+			if err = putPruneProgressCounter(tx, pruneProgressRoundNodes, roundNodes); err != nil {
+				return err
+			}
+			return putPruneProgressCounter(tx, pruneProgressRoundBytes, roundBytes)
+		}
+		budget--
+
+		key, ok := nodeKeyFromDbKey(k)
+		if !ok {
+			continue
+		}
+		marked, err := tx.Has(db2.TablePrune, pruneKey(prunePrefixMarked, key))
+		if err != nil {
+			return err
+		}
+		if marked {
+			continue
+		}
+
+		reclaimed := uint64(len(k) + len(v))
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+
+		// leaves also have their key stored by hash
+		hashKey := utils.ArrayToBytes(key[:])
+		var hashKeyValue []byte
+		if hashKeyValue, err = tx.GetOne(db2.TableHashKey, hashKey); err != nil {
+			return err
+		}
+		if hashKeyValue != nil {
+			reclaimed += uint64(len(hashKey) + len(hashKeyValue))
+			if err = tx.Delete(db2.TableHashKey, hashKey); err != nil {
+				return err
+			}
+		}
+
+		roundNodes++
+		roundBytes += reclaimed
+		prunedNodesCounter.Inc()
+		prunedBytesCounter.AddUint64(reclaimed)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.stats.Rounds++
+	p.stats.NodesDeleted += roundNodes
+	p.stats.BytesReclaimed += roundBytes
+	log.Info(fmt.Sprintf("[%s] SMT prune round done", logPrefix), "retained", roundMarked, "deleted", roundNodes, "reclaimed", common.ByteCount(roundBytes))
+
+	return tx.ClearBucket(db2.TablePrune)
+}
+
+func hasPendingPruneNodes(tx kv.Tx) (bool, error) {
+	c, err := tx.Cursor(db2.TablePrune)
+	if err != nil {
+		return false, err
+	}
+	defer c.Close()
+
+	k, _, err := c.Seek([]byte{prunePrefixPending})
+	if err != nil {
+		return false, err
+	}
+	return k != nil && k[0] == prunePrefixPending, nil
+}
+
+func getPruneProgressCounter(tx kv.Getter, key []byte) (uint64, error) {
+	v, err := tx.GetOne(db2.TablePrune, key)
+	if err != nil || len(v) != 8 {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func putPruneProgressCounter(tx kv.Putter, key []byte, value uint64) error {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint64(v, value)
+	return tx.Put(db2.TablePrune, key, v)
+}
+
+// pruneKey stores the node key in binary rather than the hex the SMT tables use, it halves the size of the table
+func pruneKey(prefix byte, key utils.NodeKey) []byte {
+	k := make([]byte, 33)
+	k[0] = prefix
+	for i, part := range key {
+		binary.BigEndian.PutUint64(k[1+i*8:], part)
+	}
+	return k
+}
+
+func nodeKeyFromBytes(k []byte) utils.NodeKey {
+	return utils.NodeKey{
+		binary.BigEndian.Uint64(k[0:8]),
+		binary.BigEndian.Uint64(k[8:16]),
+		binary.BigEndian.Uint64(k[16:24]),
+		binary.BigEndian.Uint64(k[24:32]),
+	}
+}
+
+// nodeKeyFromDbKey reverses utils.NodeKey.ToHex which the SMT tables are keyed by
+func nodeKeyFromDbKey(k []byte) (utils.NodeKey, bool) {
+	b, err := hex.DecodeString(string(k))
+	if err != nil || len(b) != 32 {
+		return utils.NodeKey{}, false
+	}
+	return nodeKeyFromBytes(b), true
+}
@@ -0,0 +1,176 @@
+package smt
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/smt"
+	"github.com/ledgerwatch/erigon/smt/pkg/utils"
+)
+
+func countSmtNodes(t *testing.T, tx kv.Tx) int {
+	count := 0
+	require.NoError(t, tx.ForEach(db2.TableSmt, nil, func(k, v []byte) error {
+		count++
+		return nil
+	}))
+	return count
+}
+
+// requireCompleteTree fails if any node below root is missing
+func requireCompleteTree(t *testing.T, s *smt.SMT, root *big.Int) {
+	err := s.Traverse(context.Background(), root, func(prefix []byte, k utils.NodeKey, v utils.NodeValue12) (bool, error) {
+		require.False(t, v.IsZero(), "node %s is missing", k.ToHex())
+		if v.IsFinalNode() {
+			value, err := s.Db.Get(utils.NodeKeyFromUint64Array(v[4:8]))
+			require.NoError(t, err)
+			require.False(t, value.IsZero(), "value of leaf %s is missing", k.ToHex())
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+}
+
+// treeComplete reports whether every node below root is still there
+func treeComplete(t *testing.T, s *smt.SMT, root *big.Int) bool {
+	complete := true
+	err := s.Traverse(context.Background(), root, func(prefix []byte, k utils.NodeKey, v utils.NodeValue12) (bool, error) {
+		if v.IsZero() {
+			complete = false
+			return false, nil
+		}
+		return true, nil
+	})
+	require.NoError(t, err)
+	return complete
+}
+
+// setBalances writes the balances of the accounts at the given addresses through SetStorage, the way the stages write
+// the SMT, and returns the new root
+func setBalances(t *testing.T, s *smt.SMT, balances map[int64]uint64) *big.Int {
+	accChanges := make(map[common.Address]*accounts.Account, len(balances))
+	for addr, balance := range balances {
+		acc := accounts.NewAccount()
+		acc.Balance.SetUint64(balance)
+		accChanges[common.BigToAddress(big.NewInt(addr))] = &acc
+	}
+	_, _, err := s.SetStorage(context.Background(), "test", accChanges, nil, nil)
+	require.NoError(t, err)
+	return s.LastRoot()
+}
+
+// writeTestRoots writes three roots, each changing the balances of half the accounts of the one before
+func writeTestRoots(t *testing.T, s *smt.SMT) []*big.Int {
+	first := make(map[int64]uint64)
+	second := make(map[int64]uint64)
+	third := make(map[int64]uint64)
+	for i := int64(1); i <= 20; i++ {
+		first[i] = uint64(i)
+		if i%2 == 1 {
+			second[i] = uint64(i * 100)
+		} else {
+			third[i] = uint64(i * 1000)
+		}
+	}
+	return []*big.Int{setBalances(t, s, first), setBalances(t, s, second), setBalances(t, s, third)}
+}
+
+func TestInsertBatchDeletesReplacedNodes(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, db2.CreateEriDbBuckets(tx))
+	s := smt.NewSMT(db2.NewEriDb(tx), false)
+
+	// without pruning only the last root is whole, the pruner could not keep the others
+	roots := writeTestRoots(t, s)
+	require.False(t, treeComplete(t, s, roots[0]))
+	require.False(t, treeComplete(t, s, roots[1]))
+	requireCompleteTree(t, s, roots[2])
+}
+
+func TestPruner(t *testing.T) {
+	for _, step := range []int{3, 1_000_000} {
+		_, tx := memdb.NewTestTx(t)
+		require.NoError(t, db2.CreateEriDbBuckets(tx))
+		s := smt.NewSMT(db2.NewEriDb(tx), false)
+		s.SetKeepReplacedNodes(true)
+
+		roots := writeTestRoots(t, s)
+		for _, root := range roots {
+			requireCompleteTree(t, s, root)
+		}
+		before := countSmtNodes(t, tx)
+
+		// the retained root and the last one are kept whole, the nodes only the other root needed are deleted
+		pruner := NewPruner(step)
+		for pruner.Stats().Rounds == 0 {
+			require.NoError(t, pruner.Prune("test", tx, []*big.Int{roots[0]}))
+		}
+		requireCompleteTree(t, s, roots[0])
+		requireCompleteTree(t, s, roots[2])
+		require.False(t, treeComplete(t, s, roots[1]))
+		remaining := countSmtNodes(t, tx)
+		require.NotZero(t, pruner.Stats().NodesDeleted)
+		require.Equal(t, before-int(pruner.Stats().NodesDeleted), remaining)
+
+		// once it is no longer retained only the last root is kept
+		for pruner.Stats().Rounds == 1 {
+			require.NoError(t, pruner.Prune("test", tx, nil))
+		}
+		requireCompleteTree(t, s, roots[2])
+		require.False(t, treeComplete(t, s, roots[0]))
+		require.Less(t, countSmtNodes(t, tx), remaining)
+		require.Equal(t, before-int(pruner.Stats().NodesDeleted), countSmtNodes(t, tx))
+		require.NotZero(t, pruner.Stats().BytesReclaimed)
+
+		// writes after a round keep the last root of the round whole until it is pruned
+		latest := setBalances(t, s, map[int64]uint64{1: 1})
+		requireCompleteTree(t, s, roots[2])
+		requireCompleteTree(t, s, latest)
+	}
+}
+
+func TestPrunerResumesAfterRestart(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, db2.CreateEriDbBuckets(tx))
+	s := smt.NewSMT(db2.NewEriDb(tx), false)
+	s.SetKeepReplacedNodes(true)
+
+	roots := writeTestRoots(t, s)
+	lastRoot := roots[len(roots)-1]
+	before := countSmtNodes(t, tx)
+
+	// the round is left half way, its progress is in the db rather than in the pruner
+	pruner := NewPruner(5)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, pruner.Prune("test", tx, nil))
+	}
+	require.Zero(t, pruner.Stats().Rounds)
+	marked, err := getPruneProgressCounter(tx, pruneProgressRoundMarked)
+	require.NoError(t, err)
+	require.NotZero(t, marked)
+
+	// a new pruner, as after a restart, finishes the round
+	pruner = NewPruner(5)
+	for pruner.Stats().Rounds == 0 {
+		require.NoError(t, pruner.Prune("test", tx, nil))
+	}
+	requireCompleteTree(t, s, lastRoot)
+	require.NotZero(t, pruner.Stats().NodesDeleted)
+	require.Equal(t, before-countSmtNodes(t, tx), int(pruner.Stats().NodesDeleted))
+
+	// the prune table is emptied for the next round
+	c, err := tx.Cursor(db2.TablePrune)
+	require.NoError(t, err)
+	defer c.Close()
+	count, err := c.Count()
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
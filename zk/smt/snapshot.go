@@ -0,0 +1,822 @@
+package smt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/membatchwithdb"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rlp"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/utils"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zkUtils "github.com/ledgerwatch/erigon/zk/utils"
+)
+
+// An SMT snapshot holds the last block of a batch and every node reachable from its state root, along with the hash
+// keys and key sources of the leaves and the bytecode of the contracts, so that a node can be bootstrapped at that
+// block without executing or regenerating the SMT from genesis.  The plain state is rebuilt from the leaves.
+//
+// Layout:
+//
+//	header  | magic (8) | version (2) | batch (8) | block (8) | fork id (8) | root (32) | depth (1)
+//	chunks  | record count (4) | payload length (4) | payload | sha256 of payload (32)
+//	trailer | chunk count (8) | record count (8) | sha256 of header and chunks (32)
+//
+// A record is | type (1) | key length (2) | key | value length (4) | value |.  The block comes first, then the nodes
+// depth first, left before right, with each leaf followed by its value node, hash key and key source, and the
+// bytecode when the leaf is a contract's code hash.  The same root therefore always produces the same snapshot and
+// the importer can check the shape of the tree and the key of every leaf while reading it.
+
+const (
+	SnapshotVersion uint16 = 1
+
+	snapshotHeaderSize      = 8 + 2 + 8 + 8 + 8 + 32 + 1
+	snapshotChunkHeaderSize = 4 + 4
+	snapshotTrailerSize     = 8 + 8 + sha256.Size
+
+	// chunks are closed once their payload reaches this size
+	snapshotChunkSize = 1 << 20
+
+	recordNode      byte = 1
+	recordHashKey   byte = 2
+	recordKeySource byte = 3
+	recordCode      byte = 4 // contract address -> bytecode
+	recordBlock     byte = 5 // block hash -> RLP of the block
+
+	// metaSnapshotBlock is set in the SMT stats table to the block of an imported snapshot
+	metaSnapshotBlock = "snapshotBlock"
+)
+
+var (
+	snapshotMagic = [8]byte{'Z', 'K', 'S', 'M', 'T', 'S', 'N', 'P'}
+
+	ErrInvalidSnapshot  = errors.New("not an SMT snapshot")
+	ErrSnapshotChecksum = errors.New("SMT snapshot checksum mismatch")
+)
+
+type SnapshotHeader struct {
+	Version uint16
+	Batch   uint64
+	Block   uint64
+	ForkId  uint64
+	Root    common.Hash
+	Depth   uint8
+}
+
+// snapshotStages are set to the snapshot's block on import, the hashed state is left to be promoted from the plain
+// state and the L1 stages sync from L1 as usual
+var snapshotStages = []stages.SyncStage{
+	stages.Batches,
+	stages.BlockHashes,
+	stages.Senders,
+	stages.Execution,
+	stages.IntermediateHashes,
+	stages.CallTraces,
+	stages.AccountHistoryIndex,
+	stages.StorageHistoryIndex,
+	stages.LogIndex,
+	stages.TxLookup,
+	stages.DataStream,
+	stages.Witness,
+	stages.BridgeIndexer,
+	stages.Finish,
+}
+
+func (h SnapshotHeader) encode() []byte {
+	b := make([]byte, 0, snapshotHeaderSize)
+	b = append(b, snapshotMagic[:]...)
+	b = binary.BigEndian.AppendUint16(b, h.Version)
+	b = binary.BigEndian.AppendUint64(b, h.Batch)
+	b = binary.BigEndian.AppendUint64(b, h.Block)
+	b = binary.BigEndian.AppendUint64(b, h.ForkId)
+	b = append(b, h.Root.Bytes()...)
+	return append(b, h.Depth)
+}
+
+func decodeSnapshotHeader(b []byte) (SnapshotHeader, error) {
+	if len(b) < snapshotHeaderSize || !bytes.Equal(b[:8], snapshotMagic[:]) {
+		return SnapshotHeader{}, ErrInvalidSnapshot
+	}
+	h := SnapshotHeader{
+		Version: binary.BigEndian.Uint16(b[8:10]),
+		Batch:   binary.BigEndian.Uint64(b[10:18]),
+		Block:   binary.BigEndian.Uint64(b[18:26]),
+		ForkId:  binary.BigEndian.Uint64(b[26:34]),
+		Root:    common.BytesToHash(b[34:66]),
+		Depth:   b[66],
+	}
+	if h.Version != SnapshotVersion {
+		return SnapshotHeader{}, fmt.Errorf("unsupported SMT snapshot version %d", h.Version)
+	}
+	return h, nil
+}
+
+type snapshotWriter struct {
+	w      *bufio.Writer
+	hasher hash.Hash
+	// reads the bytecode of the contracts as of the snapshot's block
+	state *state.PlainState
+
+	chunk        bytes.Buffer
+	chunkRecords uint32
+	chunks       uint64
+	records      uint64
+}
+
+func newSnapshotWriter(w io.Writer, header SnapshotHeader, state *state.PlainState) (*snapshotWriter, error) {
+	sw := &snapshotWriter{
+		w:      bufio.NewWriter(w),
+		hasher: sha256.New(),
+		state:  state,
+	}
+	if err := sw.write(header.encode()); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (w *snapshotWriter) write(b []byte) error {
+	w.hasher.Write(b)
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *snapshotWriter) writeRecord(table byte, k, v []byte) error {
+	w.chunk.WriteByte(table)
+	w.chunk.Write(binary.BigEndian.AppendUint16(nil, uint16(len(k))))
+	w.chunk.Write(k)
+	w.chunk.Write(binary.BigEndian.AppendUint32(nil, uint32(len(v))))
+	w.chunk.Write(v)
+	w.chunkRecords++
+	w.records++
+
+	if w.chunk.Len() >= snapshotChunkSize {
+		return w.flushChunk()
+	}
+	return nil
+}
+
+func (w *snapshotWriter) flushChunk() error {
+	if w.chunkRecords == 0 {
+		return nil
+	}
+	payload := w.chunk.Bytes()
+	chunkHeader := binary.BigEndian.AppendUint32(nil, w.chunkRecords)
+	chunkHeader = binary.BigEndian.AppendUint32(chunkHeader, uint32(len(payload)))
+	checksum := sha256.Sum256(payload)
+	for _, b := range [][]byte{chunkHeader, payload, checksum[:]} {
+		if err := w.write(b); err != nil {
+			return err
+		}
+	}
+	w.chunks++
+	w.chunk.Reset()
+	w.chunkRecords = 0
+	return nil
+}
+
+// close writes the last chunk and the trailer and flushes the snapshot, it does not close the underlying writer
+func (w *snapshotWriter) close() error {
+	if err := w.flushChunk(); err != nil {
+		return err
+	}
+	trailer := binary.BigEndian.AppendUint64(nil, w.chunks)
+	trailer = binary.BigEndian.AppendUint64(trailer, w.records)
+	w.hasher.Write(trailer)
+	trailer = append(trailer, w.hasher.Sum(nil)...)
+	if _, err := w.w.Write(trailer); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// writeTree writes the subtree below key
+func (w *snapshotWriter) writeTree(ctx context.Context, tx kv.Tx, key utils.NodeKey) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	node, err := w.writeNode(tx, key)
+	if err != nil {
+		return err
+	}
+
+	if !node.IsFinalNode() {
+		for i := 0; i < 2; i++ {
+			child := utils.NodeKeyFromUint64Array(node[i*4 : i*4+4])
+			if child.IsZero() {
+				continue
+			}
+			if err = w.writeTree(ctx, tx, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := w.writeNode(tx, utils.NodeKeyFromUint64Array(node[4:8]))
+	if err != nil {
+		return err
+	}
+
+	// the key sources are needed to rebuild the plain state from the leaves
+	hashKey := utils.ArrayToBytes(key[:])
+	fullKey, err := tx.GetOne(db2.TableHashKey, hashKey)
+	if err != nil {
+		return err
+	}
+	if fullKey == nil {
+		return fmt.Errorf("SMT leaf %s has no hash key", key.ToHex())
+	}
+	if err = w.writeRecord(recordHashKey, hashKey, fullKey); err != nil {
+		return err
+	}
+	keySource, err := tx.GetOne(db2.TableMetadata, fullKey)
+	if err != nil {
+		return err
+	}
+	if keySource == nil {
+		return fmt.Errorf("SMT leaf %s has no key source", key.ToHex())
+	}
+	if err = w.writeRecord(recordKeySource, fullKey, keySource); err != nil {
+		return err
+	}
+
+	keyType, addr, _, err := utils.DecodeKeySource(keySource)
+	if err != nil || keyType != utils.SC_CODE {
+		return err
+	}
+	code, err := w.readCode(addr)
+	if err != nil {
+		return err
+	}
+	if err = checkSnapshotCode(addr, code, utils.NodeValue8ToBigInt(value.GetNodeValue8())); err != nil {
+		return err
+	}
+	return w.writeRecord(recordCode, addr.Bytes(), code)
+}
+
+func (w *snapshotWriter) readCode(addr common.Address) ([]byte, error) {
+	account, err := w.state.ReadAccountData(addr)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	return w.state.ReadAccountCode(addr, account.Incarnation, account.CodeHash)
+}
+
+func (w *snapshotWriter) writeNode(tx kv.Tx, key utils.NodeKey) (utils.NodeValue12, error) {
+	k := []byte(key.ToHex())
+	v, err := tx.GetOne(db2.TableSmt, k)
+	if err != nil {
+		return utils.NodeValue12{}, err
+	}
+	if v == nil {
+		return utils.NodeValue12{}, fmt.Errorf("SMT node %s is missing", key.ToHex())
+	}
+	if err = w.writeRecord(recordNode, k, v); err != nil {
+		return utils.NodeValue12{}, err
+	}
+	return utils.ScalarToNodeValue(utils.ConvertHexToBigInt(string(v))), nil
+}
+
+// ExportSnapshot writes the SMT at the state root of the end of batch to w.  When the SMT is ahead of the batch it is
+// unwound in memory first and the bytecode is read from the state history, which needs the change sets of the blocks
+// in between.
+func ExportSnapshot(ctx context.Context, tx kv.Tx, w io.Writer, batch uint64, tmpDir string) (*SnapshotHeader, uint64, error) {
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+	blockNo, found, err := hermezDb.GetHighestBlockInBatch(batch)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !found {
+		return nil, 0, fmt.Errorf("batch %d not found", batch)
+	}
+	smtProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, 0, err
+	}
+	if blockNo > smtProgress {
+		return nil, 0, fmt.Errorf("batch %d ends at block %d but the SMT is at block %d", batch, blockNo, smtProgress)
+	}
+	header := rawdb.ReadHeaderByNumber(tx, blockNo)
+	if header == nil {
+		return nil, 0, fmt.Errorf("header not found for block %d", blockNo)
+	}
+	blockHash, err := rawdb.ReadCanonicalHash(tx, blockNo)
+	if err != nil {
+		return nil, 0, err
+	}
+	block := rawdb.ReadBlock(tx, blockHash, blockNo)
+	if block == nil {
+		return nil, 0, fmt.Errorf("block %d not found", blockNo)
+	}
+	encodedBlock, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, 0, err
+	}
+	forkId, err := hermezDb.GetForkId(batch)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, depth, err := hermezDb.GetClosestSmtDepth(blockNo)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var smtTx kv.Tx = tx
+	if blockNo < smtProgress {
+		batch := membatchwithdb.NewMemoryBatch(tx, tmpDir, log.New())
+		defer batch.Rollback()
+		if err = zkUtils.PopulateMemoryMutationTables(batch); err != nil {
+			return nil, 0, err
+		}
+		expectedRoot := header.Root
+		if _, err = UnwindZkSMT(ctx, "SMT snapshot", smtProgress, blockNo, batch, true, &expectedRoot, false, false); err != nil {
+			return nil, 0, fmt.Errorf("UnwindZkSMT: %w", err)
+		}
+		smtTx = batch
+	}
+
+	snapshotHeader := &SnapshotHeader{
+		Version: SnapshotVersion,
+		Batch:   batch,
+		Block:   blockNo,
+		ForkId:  forkId,
+		Root:    header.Root,
+		Depth:   uint8(depth),
+	}
+	sw, err := newSnapshotWriter(w, *snapshotHeader, state.NewPlainState(tx, blockNo+1, nil))
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = sw.writeRecord(recordBlock, blockHash.Bytes(), encodedBlock); err != nil {
+		return nil, 0, err
+	}
+	root := utils.ScalarToRoot(header.Root.Big())
+	if !root.IsZero() {
+		if err = sw.writeTree(ctx, smtTx, root); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err = sw.close(); err != nil {
+		return nil, 0, err
+	}
+
+	return snapshotHeader, sw.records, nil
+}
+
+type expectedSnapshotNode struct {
+	key utils.NodeKey
+	// the bits of the path from the root, the remaining key of a leaf is its full key without them
+	path []int
+	// value nodes hold the value of a leaf and have no children
+	value bool
+}
+
+// snapshotImporter checks the records of a snapshot as they are read and writes them to the SMT tables and the plain
+// state
+type snapshotImporter struct {
+	tx     kv.RwTx
+	header SnapshotHeader
+	reader *state.PlainStateReader
+	writer *state.PlainStateWriter
+
+	// nodes still to come depth first
+	pending []expectedSnapshotNode
+	// the record type the last leaf needs next, 0 once it is complete
+	expect    byte
+	leaf      expectedSnapshotNode
+	leafRKey  utils.NodeKey
+	leafValue *big.Int
+	fullKey   []byte
+	codeOwner common.Address
+
+	blockFound bool
+}
+
+// ImportSnapshot loads the snapshot at path into a node that has only its genesis.  Every node is checked to hash to
+// its key, the tree to be complete and the key of every leaf to be derived from its key source.  The plain state is
+// replaced by the one rebuilt from the leaves and the stages up to the SMT are set to the snapshot's block, so the node
+// continues syncing from there.  The hashed state is promoted from the plain state by its stage.
+func ImportSnapshot(ctx context.Context, tx kv.RwTx, path string) (*SnapshotHeader, uint64, error) {
+	eridb := db2.NewEriDb(tx)
+	lastRoot, err := eridb.GetLastRoot()
+	if err != nil {
+		return nil, 0, err
+	}
+	if lastRoot.Sign() != 0 {
+		return nil, 0, fmt.Errorf("the SMT is not empty, its root is %s", common.BigToHash(lastRoot))
+	}
+	genesisHash, err := rawdb.ReadCanonicalHash(tx, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	if genesisHash == (common.Hash{}) {
+		return nil, 0, errors.New("the node has no genesis, initialise it first")
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return nil, 0, err
+	}
+	if executed != 0 {
+		return nil, 0, fmt.Errorf("the node has executed up to block %d, a snapshot can only be imported into a new node", executed)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() < snapshotHeaderSize+snapshotTrailerSize {
+		return nil, 0, ErrInvalidSnapshot
+	}
+
+	hasher := sha256.New()
+	body := io.TeeReader(bufio.NewReader(io.NewSectionReader(f, 0, info.Size()-sha256.Size)), hasher)
+
+	headerBytes := make([]byte, snapshotHeaderSize)
+	if _, err = io.ReadFull(body, headerBytes); err != nil {
+		return nil, 0, err
+	}
+	header, err := decodeSnapshotHeader(headerBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the genesis state is replaced by the snapshot's, accounts emptied since genesis have no leaves to overwrite it
+	for _, table := range []string{kv.PlainState, kv.PlainContractCode, kv.HashedAccounts, kv.HashedStorage, kv.ContractCode} {
+		if err = tx.ClearBucket(table); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	im := &snapshotImporter{
+		tx:     tx,
+		header: header,
+		reader: state.NewPlainStateReader(tx),
+		writer: state.NewPlainStateWriterNoHistory(tx),
+	}
+	if root := utils.ScalarToRoot(header.Root.Big()); !root.IsZero() {
+		im.pending = append(im.pending, expectedSnapshotNode{key: root})
+	}
+
+	var chunks, records uint64
+	remaining := info.Size() - snapshotHeaderSize - snapshotTrailerSize
+	for remaining > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		default:
+		}
+
+		chunkHeader := make([]byte, snapshotChunkHeaderSize)
+		if _, err = io.ReadFull(body, chunkHeader); err != nil {
+			return nil, 0, fmt.Errorf("read chunk %d: %w", chunks, err)
+		}
+		count := binary.BigEndian.Uint32(chunkHeader[0:4])
+		length := int64(binary.BigEndian.Uint32(chunkHeader[4:8]))
+		if snapshotChunkHeaderSize+length+sha256.Size > remaining {
+			return nil, 0, fmt.Errorf("chunk %d is truncated", chunks)
+		}
+		payload := make([]byte, length+sha256.Size)
+		if _, err = io.ReadFull(body, payload); err != nil {
+			return nil, 0, fmt.Errorf("read chunk %d: %w", chunks, err)
+		}
+		if checksum := sha256.Sum256(payload[:length]); !bytes.Equal(checksum[:], payload[length:]) {
+			return nil, 0, fmt.Errorf("chunk %d: %w", chunks, ErrSnapshotChecksum)
+		}
+		if err = im.importChunk(payload[:length], count); err != nil {
+			return nil, 0, fmt.Errorf("chunk %d: %w", chunks, err)
+		}
+
+		chunks++
+		records += uint64(count)
+		remaining -= snapshotChunkHeaderSize + length + sha256.Size
+	}
+
+	trailer := make([]byte, 16)
+	if _, err = io.ReadFull(body, trailer); err != nil {
+		return nil, 0, err
+	}
+	checksum := make([]byte, sha256.Size)
+	if _, err = f.ReadAt(checksum, info.Size()-sha256.Size); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(hasher.Sum(nil), checksum) {
+		return nil, 0, ErrSnapshotChecksum
+	}
+	if binary.BigEndian.Uint64(trailer[0:8]) != chunks || binary.BigEndian.Uint64(trailer[8:16]) != records {
+		return nil, 0, fmt.Errorf("snapshot has %d chunks and %d records, the trailer expects %d and %d", chunks, records, binary.BigEndian.Uint64(trailer[0:8]), binary.BigEndian.Uint64(trailer[8:16]))
+	}
+	if len(im.pending) > 0 {
+		return nil, 0, fmt.Errorf("snapshot is incomplete, SMT node %s is missing", im.pending[len(im.pending)-1].key.ToHex())
+	}
+	if err = im.leafComplete(); err != nil {
+		return nil, 0, err
+	}
+	if !im.blockFound {
+		return nil, 0, fmt.Errorf("snapshot is incomplete, block %d is missing", header.Block)
+	}
+
+	if err = eridb.SetLastRoot(header.Root.Big()); err != nil {
+		return nil, 0, err
+	}
+	if err = eridb.SetDepth(header.Depth); err != nil {
+		return nil, 0, err
+	}
+	hermezDb := hermez_db.NewHermezDb(tx)
+	if err = hermezDb.WriteSmtDepth(header.Block, uint64(header.Depth)); err != nil {
+		return nil, 0, err
+	}
+	if err = hermezDb.WriteBlockBatch(header.Block, header.Batch); err != nil {
+		return nil, 0, err
+	}
+	if err = hermezDb.WriteForkId(header.Batch, header.ForkId); err != nil {
+		return nil, 0, err
+	}
+	if err = tx.Put(db2.TableStats, []byte(metaSnapshotBlock), binary.BigEndian.AppendUint64(nil, header.Block)); err != nil {
+		return nil, 0, err
+	}
+	for _, stage := range snapshotStages {
+		if err = stages.SaveStageProgress(tx, stage, header.Block); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err = stages.SaveStageProgress(tx, stages.ForkId, header.ForkId); err != nil {
+		return nil, 0, err
+	}
+
+	return &header, records, nil
+}
+
+func (im *snapshotImporter) importChunk(payload []byte, count uint32) error {
+	r := bytes.NewReader(payload)
+	for i := uint32(0); i < count; i++ {
+		fixed := make([]byte, 3)
+		if _, err := io.ReadFull(r, fixed); err != nil {
+			return err
+		}
+		k := make([]byte, binary.BigEndian.Uint16(fixed[1:3]))
+		if _, err := io.ReadFull(r, k); err != nil {
+			return err
+		}
+		valueLength := make([]byte, 4)
+		if _, err := io.ReadFull(r, valueLength); err != nil {
+			return err
+		}
+		v := make([]byte, binary.BigEndian.Uint32(valueLength))
+		if _, err := io.ReadFull(r, v); err != nil {
+			return err
+		}
+
+		if err := im.importRecord(fixed[0], k, v); err != nil {
+			return err
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d bytes left after the last record", r.Len())
+	}
+	return nil
+}
+
+func (im *snapshotImporter) importRecord(recordType byte, k, v []byte) error {
+	if im.expect != 0 && recordType != im.expect {
+		return fmt.Errorf("record type %d found where %d was expected for SMT leaf %s", recordType, im.expect, im.leaf.key.ToHex())
+	}
+
+	switch recordType {
+	case recordNode:
+		return im.importNode(k, v)
+	case recordHashKey:
+		if !bytes.Equal(k, utils.ArrayToBytes(im.leaf.key[:])) {
+			return fmt.Errorf("hash key %x does not belong to SMT leaf %s", k, im.leaf.key.ToHex())
+		}
+		im.fullKey = v
+		im.expect = recordKeySource
+		return im.tx.Put(db2.TableHashKey, k, v)
+	case recordKeySource:
+		if !bytes.Equal(k, im.fullKey) {
+			return fmt.Errorf("key source %x does not belong to SMT leaf %s", k, im.leaf.key.ToHex())
+		}
+		if err := im.importKeySource(v); err != nil {
+			return err
+		}
+		return im.tx.Put(db2.TableMetadata, k, v)
+	case recordCode:
+		return im.importCode(k, v)
+	case recordBlock:
+		return im.importBlock(k, v)
+	default:
+		return fmt.Errorf("unknown record type %d", recordType)
+	}
+}
+
+// leafComplete checks the last leaf had all its records
+func (im *snapshotImporter) leafComplete() error {
+	if im.expect != 0 {
+		return fmt.Errorf("SMT leaf %s is missing record type %d", im.leaf.key.ToHex(), im.expect)
+	}
+	return nil
+}
+
+// importNode checks the node is the next one expected depth first and that it hashes to its key
+func (im *snapshotImporter) importNode(k, v []byte) error {
+	if len(im.pending) == 0 {
+		return fmt.Errorf("unexpected SMT node %s", k)
+	}
+	expected := im.pending[len(im.pending)-1]
+	im.pending = im.pending[:len(im.pending)-1]
+
+	key, ok := nodeKeyFromDbKey(k)
+	if !ok || key != expected.key {
+		return fmt.Errorf("SMT node %s found where %s was expected", k, expected.key.ToHex())
+	}
+	node := utils.ScalarToNodeValue(utils.ConvertHexToBigInt(string(v)))
+	if utils.NodeKey(utils.Hash(node.Get0to8(), [4]uint64{node[8], node[9], node[10], node[11]})) != key {
+		return fmt.Errorf("SMT node %s does not hash to its key", k)
+	}
+
+	switch {
+	case expected.value:
+		im.leafValue = utils.NodeValue8ToBigInt(node.GetNodeValue8())
+		im.expect = recordHashKey
+	case node.IsFinalNode():
+		im.leaf = expected
+		im.leafRKey = utils.NodeKeyFromUint64Array(node[0:4])
+		im.pending = append(im.pending, expectedSnapshotNode{key: utils.NodeKeyFromUint64Array(node[4:8]), value: true})
+	default:
+		// pushed right first so the left child is expected next
+		for i := 1; i >= 0; i-- {
+			if child := utils.NodeKeyFromUint64Array(node[i*4 : i*4+4]); !child.IsZero() {
+				path := append(append(make([]int, 0, len(expected.path)+1), expected.path...), i)
+				im.pending = append(im.pending, expectedSnapshotNode{key: child, path: path})
+			}
+		}
+	}
+	return im.tx.Put(db2.TableSmt, k, v)
+}
+
+// importKeySource checks the key of the leaf is derived from its key source and writes its value to the plain state
+func (im *snapshotImporter) importKeySource(keySource []byte) error {
+	keyType, addr, storageKey, err := utils.DecodeKeySource(keySource)
+	if err != nil {
+		return err
+	}
+	var fullKey utils.NodeKey
+	if keyType == utils.SC_STORAGE {
+		if fullKey, err = utils.KeyContractStorage(addr.String(), storageKey.String()); err != nil {
+			return err
+		}
+	} else {
+		fullKey = utils.Key(addr.String(), keyType)
+	}
+	if !bytes.Equal(utils.ArrayToBytes(fullKey[:]), im.fullKey) {
+		return fmt.Errorf("key source of SMT leaf %s does not hash to its key", im.leaf.key.ToHex())
+	}
+	path := fullKey.GetPath()
+	for i, bit := range im.leaf.path {
+		if path[i] != bit {
+			return fmt.Errorf("SMT leaf %s is not on the path of its key", im.leaf.key.ToHex())
+		}
+	}
+	if utils.RemoveKeyBits(fullKey, len(im.leaf.path)) != im.leafRKey {
+		return fmt.Errorf("SMT leaf %s does not hold the remaining bits of its key", im.leaf.key.ToHex())
+	}
+
+	im.expect = 0
+	value, overflow := uint256.FromBig(im.leafValue)
+	if overflow {
+		return fmt.Errorf("SMT leaf %s value overflows", im.leaf.key.ToHex())
+	}
+
+	switch keyType {
+	case utils.KEY_BALANCE:
+		return im.updateAccount(addr, func(account *accounts.Account) {
+			account.Balance = *value
+		})
+	case utils.KEY_NONCE:
+		return im.updateAccount(addr, func(account *accounts.Account) {
+			account.Nonce = value.Uint64()
+		})
+	case utils.SC_CODE:
+		im.codeOwner = addr
+		im.expect = recordCode
+		return nil
+	case utils.SC_STORAGE:
+		if err = im.updateAccount(addr, func(account *accounts.Account) {
+			account.Incarnation = state.FirstContractIncarnation
+		}); err != nil {
+			return err
+		}
+		return im.writer.WriteAccountStorage(addr, state.FirstContractIncarnation, &storageKey, new(uint256.Int), value)
+	}
+	// the code length is implied by the code
+	return nil
+}
+
+// importCode checks the bytecode hashes to the code leaf before it and writes it to the plain state
+func (im *snapshotImporter) importCode(addr, code []byte) error {
+	if !bytes.Equal(addr, im.codeOwner.Bytes()) {
+		return fmt.Errorf("code of %x found where the code of %s was expected", addr, im.codeOwner)
+	}
+	if err := checkSnapshotCode(im.codeOwner, code, im.leafValue); err != nil {
+		return err
+	}
+	im.expect = 0
+
+	codeHash := crypto.Keccak256Hash(code)
+	if err := im.updateAccount(im.codeOwner, func(account *accounts.Account) {
+		account.Incarnation = state.FirstContractIncarnation
+		account.CodeHash = codeHash
+	}); err != nil {
+		return err
+	}
+	return im.writer.UpdateAccountCode(im.codeOwner, state.FirstContractIncarnation, codeHash, code)
+}
+
+// importBlock writes the snapshot's block, its header must have the snapshot's state root
+func (im *snapshotImporter) importBlock(hash, encoded []byte) error {
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(encoded, block); err != nil {
+		return fmt.Errorf("decode block: %w", err)
+	}
+	if block.NumberU64() != im.header.Block || block.Root() != im.header.Root {
+		return fmt.Errorf("block %d with root %s found where block %d with root %s was expected", block.NumberU64(), block.Root(), im.header.Block, im.header.Root)
+	}
+
+	blockHash := common.BytesToHash(hash)
+	if err := rawdb.WriteHeaderWithhash(im.tx, blockHash, block.Header()); err != nil {
+		return err
+	}
+	if err := rawdb.WriteBody(im.tx, blockHash, block.NumberU64(), block.Body()); err != nil {
+		return err
+	}
+	if err := rawdb.WriteCanonicalHash(im.tx, blockHash, block.NumberU64()); err != nil {
+		return err
+	}
+	if err := rawdb.WriteHeadHeaderHash(im.tx, blockHash); err != nil {
+		return err
+	}
+	rawdb.WriteHeadBlockHash(im.tx, blockHash)
+	im.blockFound = true
+	return nil
+}
+
+func (im *snapshotImporter) updateAccount(addr common.Address, update func(*accounts.Account)) error {
+	original, err := im.reader.ReadAccountData(addr)
+	if err != nil {
+		return err
+	}
+	account := accounts.NewAccount()
+	if original != nil {
+		account = *original
+	} else {
+		original = new(accounts.Account)
+	}
+	update(&account)
+	return im.writer.UpdateAccountData(addr, original, &account)
+}
+
+// checkSnapshotCode checks the bytecode hashes to the value of the contract's code leaf
+func checkSnapshotCode(addr common.Address, code []byte, codeLeafValue *big.Int) error {
+	if utils.HashContractBytecodeBigInt(hex.EncodeToString(code)).Cmp(codeLeafValue) != 0 {
+		return fmt.Errorf("code of %s does not hash to its SMT leaf", addr)
+	}
+	return nil
+}
+
+// ImportedSnapshotBlock returns the block of the snapshot the SMT was imported from
+func ImportedSnapshotBlock(tx kv.Getter) (uint64, bool, error) {
+	v, err := tx.GetOne(db2.TableStats, []byte(metaSnapshotBlock))
+	if err != nil || len(v) != 8 {
+		return 0, false, err
+	}
+	return binary.BigEndian.Uint64(v), true, nil
+}
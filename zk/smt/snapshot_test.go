@@ -0,0 +1,190 @@
+package smt
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	"github.com/ledgerwatch/erigon/smt/pkg/smt"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+func tableContents(t *testing.T, tx kv.Tx, table string) map[string]string {
+	contents := map[string]string{}
+	require.NoError(t, tx.ForEach(table, nil, func(k, v []byte) error {
+		contents[string(k)] = string(v)
+		return nil
+	}))
+	return contents
+}
+
+// newSnapshotTestTx must only be called once the previous test tx is rolled back as write txs are exclusive
+func newSnapshotTestTx(t *testing.T) kv.RwTx {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, db2.CreateEriDbBuckets(tx))
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	return tx
+}
+
+// newSnapshotTestNode returns a tx of a node that only has its genesis, with an account the snapshot does not have
+func newSnapshotTestNode(t *testing.T) kv.RwTx {
+	tx := newSnapshotTestTx(t)
+	genesis := &types.Header{Number: big.NewInt(0)}
+	require.NoError(t, rawdb.WriteHeader(tx, genesis))
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, genesis.Hash(), 0))
+	account := accounts.NewAccount()
+	account.Balance = *uint256.NewInt(1)
+	require.NoError(t, state.NewPlainStateWriterNoHistory(tx).UpdateAccountData(common.Address{0xff}, new(accounts.Account), &account))
+	return tx
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tx := newSnapshotTestTx(t)
+	s := smt.NewSMT(db2.NewEriDb(tx), false)
+	for i := int64(1); i <= 50; i++ {
+		_, err := s.SetAccountBalance(fmt.Sprintf("0x%040x", i), big.NewInt(i))
+		require.NoError(t, err)
+	}
+	contract := common.Address{0xc0}
+	code := []byte{0x60, 0x01, 0x60, 0x00, 0x55}
+	storageKey := common.BigToHash(big.NewInt(1))
+	require.NoError(t, s.SetContractBytecode(contract.String(), hex.EncodeToString(code)))
+	_, err := s.SetContractStorage(contract.String(), map[string]string{"0x1": "0x2a"}, nil)
+	require.NoError(t, err)
+	root := common.BigToHash(s.LastRoot())
+
+	// the bytecode is read from the plain state
+	codeHash := crypto.Keccak256Hash(code)
+	contractAccount := accounts.NewAccount()
+	contractAccount.Incarnation = state.FirstContractIncarnation
+	contractAccount.CodeHash = codeHash
+	writer := state.NewPlainStateWriterNoHistory(tx)
+	require.NoError(t, writer.UpdateAccountData(contract, new(accounts.Account), &contractAccount))
+	require.NoError(t, writer.UpdateAccountCode(contract, state.FirstContractIncarnation, codeHash, code))
+
+	const block, batch, forkId = 5, 2, 12
+	require.NoError(t, hermez_db.NewHermezDb(tx).WriteBlockBatch(block, batch))
+	require.NoError(t, hermez_db.NewHermezDb(tx).WriteForkId(batch, forkId))
+	require.NoError(t, hermez_db.NewHermezDb(tx).WriteSmtDepth(block, 8))
+	exportedBlock := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(block), Root: root})
+	require.NoError(t, rawdb.WriteBlock(tx, exportedBlock))
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, exportedBlock.Hash(), block))
+	require.NoError(t, stages.SaveStageProgress(tx, stages.IntermediateHashes, block))
+
+	var first, second bytes.Buffer
+	header, records, err := ExportSnapshot(context.Background(), tx, &first, batch, t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, root, header.Root)
+	require.Equal(t, uint64(block), header.Block)
+	require.Equal(t, uint8(8), header.Depth)
+	require.Equal(t, uint64(forkId), header.ForkId)
+	_, _, err = ExportSnapshot(context.Background(), tx, &second, batch, t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, first.Bytes(), second.Bytes(), "the snapshot of a root must be deterministic")
+
+	path := filepath.Join(t.TempDir(), "smt.snapshot")
+	require.NoError(t, os.WriteFile(path, first.Bytes(), 0644))
+	exported := map[string]map[string]string{}
+	for _, table := range []string{db2.TableSmt, db2.TableHashKey, db2.TableMetadata} {
+		exported[table] = tableContents(t, tx, table)
+	}
+	tx.Rollback()
+
+	imported := newSnapshotTestNode(t)
+	importedHeader, importedRecords, err := ImportSnapshot(context.Background(), imported, path)
+	require.NoError(t, err)
+	require.Equal(t, *header, *importedHeader)
+	require.Equal(t, records, importedRecords)
+
+	// only the nodes and leaves under the root are carried over, earlier roots are left behind
+	for table, contents := range exported {
+		importedContents := tableContents(t, imported, table)
+		require.NotEmpty(t, importedContents, table)
+		for k, v := range importedContents {
+			require.Equal(t, contents[k], v, table)
+		}
+	}
+	require.Equal(t, exported[db2.TableMetadata], tableContents(t, imported, db2.TableMetadata))
+	requireCompleteTree(t, smt.NewSMT(db2.NewEriDb(imported), false), root.Big())
+	lastRoot, err := db2.NewEriDb(imported).GetLastRoot()
+	require.NoError(t, err)
+	require.Equal(t, root, common.BigToHash(lastRoot))
+	for _, stage := range []stages.SyncStage{stages.Batches, stages.Execution, stages.IntermediateHashes, stages.Finish} {
+		progress, err := stages.GetStageProgress(imported, stage)
+		require.NoError(t, err)
+		require.Equal(t, uint64(block), progress, stage)
+	}
+	// the hashed state is promoted from scratch
+	progress, err := stages.GetStageProgress(imported, stages.HashState)
+	require.NoError(t, err)
+	require.Zero(t, progress)
+	importedBlockHash, err := rawdb.ReadCanonicalHash(imported, block)
+	require.NoError(t, err)
+	require.Equal(t, exportedBlock.Hash(), importedBlockHash)
+	importedBatch, err := hermez_db.NewHermezDbReader(imported).GetBatchNoByL2Block(block)
+	require.NoError(t, err)
+	require.Equal(t, uint64(batch), importedBatch)
+
+	// the plain state is rebuilt from the leaves and replaces the genesis state
+	reader := state.NewPlainStateReader(imported)
+	for i := int64(1); i <= 50; i++ {
+		account, err := reader.ReadAccountData(common.HexToAddress(fmt.Sprintf("0x%040x", i)))
+		require.NoError(t, err)
+		require.NotNil(t, account)
+		require.Equal(t, uint64(i), account.Balance.Uint64())
+	}
+	account, err := reader.ReadAccountData(contract)
+	require.NoError(t, err)
+	require.Equal(t, codeHash, account.CodeHash)
+	require.Equal(t, uint64(state.FirstContractIncarnation), account.Incarnation)
+	importedCode, err := reader.ReadAccountCode(contract, account.Incarnation, account.CodeHash)
+	require.NoError(t, err)
+	require.Equal(t, code, importedCode)
+	storageValue, err := reader.ReadAccountStorage(contract, account.Incarnation, &storageKey)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x2a}, storageValue)
+	account, err = reader.ReadAccountData(common.Address{0xff})
+	require.NoError(t, err)
+	require.Nil(t, account)
+	snapshotBlock, found, err := ImportedSnapshotBlock(imported)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(block), snapshotBlock)
+
+	// importing twice is refused as the SMT is no longer empty
+	_, _, err = ImportSnapshot(context.Background(), imported, path)
+	require.Error(t, err)
+	imported.Rollback()
+
+	// as is importing into a node that has already executed blocks
+	executed := newSnapshotTestNode(t)
+	require.NoError(t, stages.SaveStageProgress(executed, stages.Execution, 1))
+	_, _, err = ImportSnapshot(context.Background(), executed, path)
+	require.Error(t, err)
+	executed.Rollback()
+
+	// any corruption is caught by the checksums
+	corrupt := bytes.Clone(first.Bytes())
+	corrupt[snapshotHeaderSize+snapshotChunkHeaderSize+10] ^= 0xff
+	require.NoError(t, os.WriteFile(path, corrupt, 0644))
+	_, _, err = ImportSnapshot(context.Background(), newSnapshotTestNode(t), path)
+	require.ErrorIs(t, err, ErrSnapshotChecksum)
+}
@@ -17,7 +17,9 @@ import (
 	"github.com/ledgerwatch/erigon/zkevm/log"
 )
 
-func UnwindZkSMT(ctx context.Context, logPrefix string, from, to uint64, tx kv.RwTx, checkRoot bool, expectedRootHash *common.Hash, quiet bool) (common.Hash, error) {
+// UnwindZkSMT unwinds the SMT from block from to block to.  keepReplacedNodes leaves the nodes of the unwound roots in
+// the db for the SMT pruner, it has to be set whenever the SMT is pruned so the retained roots are not broken.
+func UnwindZkSMT(ctx context.Context, logPrefix string, from, to uint64, tx kv.RwTx, checkRoot bool, expectedRootHash *common.Hash, quiet, keepReplacedNodes bool) (common.Hash, error) {
 	if !quiet {
 		log.Info(fmt.Sprintf("[%s] Unwind trie hashes started", logPrefix))
 		defer log.Info(fmt.Sprintf("[%s] Unwind ended", logPrefix))
@@ -27,6 +29,7 @@ func UnwindZkSMT(ctx context.Context, logPrefix string, from, to uint64, tx kv.R
 	eridb.RollbackBatch()
 
 	dbSmt := smt.NewSMT(eridb, false)
+	dbSmt.SetKeepReplacedNodes(keepReplacedNodes)
 
 	if !quiet {
 		log.Info(fmt.Sprintf("[%s]", logPrefix), "last root", common.BigToHash(dbSmt.LastRoot()))
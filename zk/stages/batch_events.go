@@ -0,0 +1,100 @@
+package stages
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/turbo/shards"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/syncer"
+	"github.com/ledgerwatch/erigon/zk/types"
+)
+
+// queueBatchEvents holds the batch events until the tx they were written in commits, nodes without rpc events
+// have nowhere to send them
+func queueBatchEvents(events *shards.Events, batchEvents ...*types.BatchEvent) {
+	if events == nil || len(batchEvents) == 0 {
+		return
+	}
+	events.QueueBatchEvents(batchEvents...)
+}
+
+// flushBatchEvents sends the queued batch events, to be called once the stage has committed its own tx
+func flushBatchEvents(events *shards.Events) {
+	if events == nil {
+		return
+	}
+	events.FlushBatchEvents()
+}
+
+// fillBatchEventBlocks sets the block range of the batch from the blocks synced so far and, when the event carries
+// none, the state root of its last block. Batches whose blocks are not synced yet are left as they are
+func fillBatchEventBlocks(tx kv.Tx, hermezDb *hermez_db.HermezDbReader, event *types.BatchEvent) error {
+	blocks, err := hermezDb.GetL2BlockNosByBatch(event.BatchNumber)
+	if err != nil || len(blocks) == 0 {
+		return err
+	}
+
+	event.FromBlock, event.ToBlock = blocks[0], blocks[0]
+	for _, block := range blocks[1:] {
+		if block < event.FromBlock {
+			event.FromBlock = block
+		}
+		if block > event.ToBlock {
+			event.ToBlock = block
+		}
+	}
+
+	if event.StateRoot == (common.Hash{}) {
+		if header := rawdb.ReadHeaderByNumber(tx, event.ToBlock); header != nil {
+			event.StateRoot = header.Root
+		}
+	}
+	return nil
+}
+
+// fillBatchEventAccInputHashes sets the acc input hash of batches sequenced or verified on the L1, working out every
+// sequence once from its calldata so the rpc subscribers don't each go to the L1 for it.  The hash is left zero for
+// the batches it can't be worked out for, their events are sent without it
+func fillBatchEventAccInputHashes(ctx context.Context, hermezDb *hermez_db.HermezDbReader, source syncer.AccInputHashSource, events []*types.BatchEvent) {
+	sequences := make(map[uint64]map[uint64]common.Hash)
+	for _, event := range events {
+		if event.Type == types.BatchClosed || event.AccInputHash != (common.Hash{}) {
+			continue
+		}
+
+		prevSequence, sequence, err := hermezDb.GetRangeSequencesByBatch(event.BatchNumber)
+		if err != nil || prevSequence == nil || sequence == nil {
+			log.Debug("No sequence to work out the acc input hash of the batch from", "batch", event.BatchNumber, "err", err)
+			continue
+		}
+
+		hashes, ok := sequences[sequence.BatchNo]
+		if !ok {
+			// failures are kept too so a sequence is only asked for once
+			if hashes, err = sequenceAccInputHashes(ctx, hermezDb, source, sequence, prevSequence.BatchNo); err != nil {
+				log.Debug("Failed to work out the acc input hashes of the sequence", "batch", sequence.BatchNo, "err", err)
+			}
+			sequences[sequence.BatchNo] = hashes
+		}
+		event.AccInputHash = hashes[event.BatchNumber]
+	}
+}
+
+func sequenceAccInputHashes(ctx context.Context, hermezDb *hermez_db.HermezDbReader, source syncer.AccInputHashSource, sequence *types.L1BatchInfo, prevSequenceBatch uint64) (map[uint64]common.Hash, error) {
+	// a batch sequenced ahead of the synced ones has no fork id of its own yet, the latest fork it can be on is used
+	forkId, err := hermezDb.GetForkId(sequence.BatchNo)
+	if err != nil {
+		return nil, err
+	}
+	if forkId == 0 {
+		if forkId, _, err = hermezDb.GetLatestForkHistory(); err != nil {
+			return nil, err
+		}
+	}
+	return syncer.SequenceAccInputHashes(ctx, source, sequence, prevSequenceBatch, forkId)
+}
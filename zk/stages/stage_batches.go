@@ -21,6 +21,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/zk"
 	"github.com/ledgerwatch/erigon/zk/datastream/client"
 	"github.com/ledgerwatch/erigon/zk/datastream/types"
@@ -87,6 +88,7 @@ type BatchesCfg struct {
 	zkCfg                *ethconfig.Zk
 	chainConfig          *chain.Config
 	miningConfig         *params.MiningConfig
+	events               *shards.Events
 }
 
 func StageBatchesCfg(db kv.RwDB, dsClient DatastreamClient, zkCfg *ethconfig.Zk, chainConfig *chain.Config, miningConfig *params.MiningConfig, options ...Option) BatchesCfg {
@@ -115,6 +117,13 @@ func WithDSClientCreator(handler dsClientCreatorHandler) Option {
 	}
 }
 
+// WithBatchEvents is a functional option to set where the batchClosed events go.
+func WithBatchEvents(events *shards.Events) Option {
+	return func(c *BatchesCfg) {
+		c.events = events
+	}
+}
+
 var emptyHash = common.Hash{0}
 
 func SpawnStageBatches(
@@ -361,10 +370,15 @@ func SpawnStageBatches(
 				return fmt.Errorf("WriteBlockL1InfoTreeIndexProgress: %w", err)
 			}
 
+			if err := queueClosedBatches(tx, hermezDb.HermezDbReader, batchProcessor, cfg.events); err != nil {
+				return fmt.Errorf("queueClosedBatches: %w", err)
+			}
+
 			if freshTx {
 				if err := tx.Commit(); err != nil {
 					return fmt.Errorf("failed to commit tx, %w", err)
 				}
+				flushBatchEvents(cfg.events)
 
 				if tx, err = cfg.db.BeginRw(ctx); err != nil {
 					return fmt.Errorf("failed to open tx, %w", err)
@@ -395,15 +409,35 @@ func SpawnStageBatches(
 	elapsed := time.Since(startSyncTime)
 	log.Info(fmt.Sprintf("[%s] Finished writing blocks", logPrefix), "blocksWritten", batchProcessor.TotalBlocksWritten(), "elapsed", elapsed)
 
+	if err := queueClosedBatches(tx, hermezDb.HermezDbReader, batchProcessor, cfg.events); err != nil {
+		return fmt.Errorf("queueClosedBatches: %w", err)
+	}
+
 	if freshTx {
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("tx.Commit: %w", err)
 		}
+		flushBatchEvents(cfg.events)
 	}
 
 	return nil
 }
 
+// queueClosedBatches queues the batches the processor closed, with their first block, to be sent once tx commits
+func queueClosedBatches(tx kv.Tx, hermezDb *hermez_db.HermezDbReader, batchProcessor *BatchesProcessor, events *shards.Events) error {
+	closed := batchProcessor.TakeClosedBatches()
+	if events == nil {
+		return nil
+	}
+	for _, batchEvent := range closed {
+		if err := fillBatchEventBlocks(tx, hermezDb, batchEvent); err != nil {
+			return err
+		}
+	}
+	queueBatchEvents(events, closed...)
+	return nil
+}
+
 func saveStageProgress(tx kv.RwTx, logPrefix string, highestHashableL2BlockNo, highestSeenBatchNo, lastBlockHeight, lastForkId uint64) error {
 	var err error
 	// store the highest hashable block number
@@ -854,12 +888,15 @@ func getHighestDSL2Block(ctx context.Context, batchCfg BatchesCfg, latestFork ui
 
 	// first try the sequencer rpc endpoint, it might not have been upgraded to the
 	// latest version yet so if we get an error back from this call we can try the older
-	// method of calling the datastream directly
-	highestBlock, err := GetSequencerHighestDataStreamBlock(cfg.L2RpcUrl)
-	if err == nil {
-		return highestBlock, nil
+	// method of calling the datastream directly.  When syncing from an archive the sequencer is likely
+	// unreachable, and would be ahead of the archive anyway, so we go straight to the archive
+	if cfg.L2DataStreamerFile == "" {
+		highestBlock, err := GetSequencerHighestDataStreamBlock(cfg.L2RpcUrl)
+		if err == nil {
+			return highestBlock, nil
+		}
+		log.Warn("problem getting highest ds l2 block from sequencer rpc", "err", err)
 	}
-	log.Warn("problem getting highest ds l2 block from sequencer rpc", "err", err)
 
 	// so something went wrong with the rpc call, let's try the older method,
 	// but we're going to open a new connection rather than use the one for syncing blocks.
@@ -882,7 +919,14 @@ func getHighestDSL2Block(ctx context.Context, batchCfg BatchesCfg, latestFork ui
 	return fullBlock.L2BlockNumber, nil
 }
 
-func buildNewStreamClient(ctx context.Context, batchesCfg BatchesCfg, latestFork uint16) *client.StreamClient {
+func buildNewStreamClient(ctx context.Context, batchesCfg BatchesCfg, latestFork uint16) DatastreamClient {
 	cfg := batchesCfg.zkCfg
+	if cfg.L2DataStreamerFile != "" {
+		// share the index of the syncing client rather than verifying and indexing the archive again
+		if fileClient, ok := batchesCfg.dsClient.(*client.FileClient); ok {
+			return fileClient.NewQueryClient(ctx)
+		}
+		return client.NewFileClient(ctx, cfg.L2DataStreamerFile, client.DefaultEntryChannelSize)
+	}
 	return client.NewClient(ctx, cfg.L2DataStreamerUrl, cfg.L2DataStreamerUseTLS, cfg.L2DataStreamerTimeout, latestFork, client.DefaultEntryChannelSize)
 }
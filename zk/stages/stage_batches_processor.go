@@ -16,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/zk/datastream/types"
 	txtype "github.com/ledgerwatch/erigon/zk/tx"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
 	"github.com/ledgerwatch/erigon/zk/utils"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -86,6 +87,9 @@ type BatchesProcessor struct {
 	lastBlockHash common.Hash
 	chainConfig  *chain.Config
 	miningConfig *params.MiningConfig
+
+	// closedBatches are the batches whose end was processed since they were last taken
+	closedBatches []*zktypes.BatchEvent
 }
 
 func NewBatchesProcessor(
@@ -176,9 +180,22 @@ func (p *BatchesProcessor) processBatchEndEntry(batchEnd *types.BatchEnd) (err e
 	if err = p.hermezDb.WriteBatchEnd(p.lastBlockHeight); err != nil {
 		return err
 	}
+	p.closedBatches = append(p.closedBatches, &zktypes.BatchEvent{
+		Type:        zktypes.BatchClosed,
+		BatchNumber: batchEnd.Number,
+		ToBlock:     p.lastBlockHeight,
+		StateRoot:   batchEnd.StateRoot,
+	})
 	return nil
 }
 
+// TakeClosedBatches returns the batches closed since the last call and forgets them
+func (p *BatchesProcessor) TakeClosedBatches() []*zktypes.BatchEvent {
+	closed := p.closedBatches
+	p.closedBatches = nil
+	return closed
+}
+
 func (p *BatchesProcessor) processBatchStartEntry(batchStart *types.BatchStart) (err error) {
 	// check if the batch is invalid so that we can replicate this over in the stream
 	// when we re-populate it
@@ -0,0 +1,120 @@
+package stages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/zk/bridge"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+type BridgeIndexerCfg struct {
+	db    kv.RwDB
+	zkCfg *ethconfig.Zk
+}
+
+func StageBridgeIndexerCfg(db kv.RwDB, zkCfg *ethconfig.Zk) BridgeIndexerCfg {
+	return BridgeIndexerCfg{
+		db:    db,
+		zkCfg: zkCfg,
+	}
+}
+
+// SpawnStageBridgeIndexer indexes the deposits and claims of the L2 bridge contract from the logs of the executed blocks
+func SpawnStageBridgeIndexer(
+	s *stagedsync.StageState,
+	ctx context.Context,
+	tx kv.RwTx,
+	cfg BridgeIndexerCfg,
+) error {
+	logPrefix := s.LogPrefix()
+	if !cfg.zkCfg.BridgeIndexerEnabled {
+		return nil
+	}
+
+	freshTx := tx == nil
+	if freshTx {
+		var err error
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return fmt.Errorf("cfg.db.BeginRw: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	progress, err := stages.GetStageProgress(tx, stages.BridgeIndexer)
+	if err != nil {
+		return fmt.Errorf("GetStageProgress: %w", err)
+	}
+	executionProgress, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return fmt.Errorf("GetStageProgress: %w", err)
+	}
+	if executionProgress <= progress {
+		return nil
+	}
+
+	deposits, claims, err := bridge.IndexBlocks(tx, cfg.zkCfg.AddressL2Bridge, progress+1, executionProgress)
+	if err != nil {
+		return fmt.Errorf("IndexBlocks: %w", err)
+	}
+	if deposits > 0 || claims > 0 {
+		log.Info(fmt.Sprintf("[%s] Indexed bridge events", logPrefix), "fromBlock", progress+1, "toBlock", executionProgress, "deposits", deposits, "claims", claims)
+	}
+
+	if err = stages.SaveStageProgress(tx, stages.BridgeIndexer, executionProgress); err != nil {
+		return fmt.Errorf("SaveStageProgress: %w", err)
+	}
+
+	if freshTx {
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("tx.Commit: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func UnwindBridgeIndexerStage(u *stagedsync.UnwindState, tx kv.RwTx, cfg BridgeIndexerCfg, ctx context.Context) (err error) {
+	if !cfg.zkCfg.BridgeIndexerEnabled {
+		return nil
+	}
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		if tx, err = cfg.db.BeginRw(ctx); err != nil {
+			return fmt.Errorf("cfg.db.BeginRw: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	log.Info(fmt.Sprintf("[%s] Unwinding bridge events", u.LogPrefix()), "toBlock", u.UnwindPoint)
+
+	if err = hermez_db.NewHermezDb(tx).TruncateBridgeAbove(u.UnwindPoint); err != nil {
+		return fmt.Errorf("TruncateBridgeAbove: %w", err)
+	}
+
+	if err = stages.SaveStageProgress(tx, stages.BridgeIndexer, u.UnwindPoint); err != nil {
+		return fmt.Errorf("SaveStageProgress: %w", err)
+	}
+
+	if err = u.Done(tx); err != nil {
+		return fmt.Errorf("u.Done: %w", err)
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("tx.Commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// PruneBridgeIndexerStage keeps every deposit, proofs need all the leaves of the exit tree
+func PruneBridgeIndexerStage(s *stagedsync.PruneState, tx kv.RwTx, cfg BridgeIndexerCfg, ctx context.Context) error {
+	return nil
+}
@@ -50,6 +50,9 @@ type ZkInterHashesCfg struct {
 	historyV3 bool
 	agg       *state.Aggregator
 	zk        *ethconfig.Zk
+
+	// nil unless the SMT is pruned, it keeps its progress across stage loops
+	smtPruner *zkSmt.Pruner
 }
 
 func StageZkInterHashesCfg(
@@ -62,6 +65,11 @@ func StageZkInterHashesCfg(
 	agg *state.Aggregator,
 	zk *ethconfig.Zk,
 ) ZkInterHashesCfg {
+	var smtPruner *zkSmt.Pruner
+	if zk != nil && zk.SmtPruneMode == zkSmt.PruneModePruned {
+		smtPruner = zkSmt.NewPruner(zk.SmtPruneStep)
+	}
+
 	return ZkInterHashesCfg{
 		db:                db,
 		checkRoot:         checkRoot,
@@ -74,6 +82,8 @@ func StageZkInterHashesCfg(
 		historyV3: historyV3,
 		agg:       agg,
 		zk:        zk,
+
+		smtPruner: smtPruner,
 	}
 }
 
@@ -127,6 +137,7 @@ func SpawnZkIntermediateHashesStage(s *stagedsync.StageState, u stagedsync.Unwin
 
 	eridb := db2.NewEriDb(tx)
 	smt := smt.NewSMT(eridb, false)
+	smt.SetKeepReplacedNodes(keepReplacedSmtNodes(cfg.zk))
 
 	if shouldIncrement {
 		if shouldIncrementBecauseOfAFlag {
@@ -203,6 +214,15 @@ func UnwindZkIntermediateHashesStage(u *stagedsync.UnwindState, s *stagedsync.St
 		log.Debug(fmt.Sprintf("[%s] Unwinding intermediate hashes", s.LogPrefix()), "from", s.BlockNumber, "to", u.UnwindPoint)
 	}
 
+	// there are no change sets below the block of an imported snapshot
+	snapshotBlock, imported, err := zkSmt.ImportedSnapshotBlock(tx)
+	if err != nil {
+		return err
+	}
+	if imported && u.UnwindPoint < snapshotBlock {
+		return fmt.Errorf("cannot unwind the SMT to block %d, it was imported from a snapshot at block %d", u.UnwindPoint, snapshotBlock)
+	}
+
 	var expectedRootHash common.Hash
 	syncHeadHeader := rawdb.ReadHeaderByNumber(tx, u.UnwindPoint)
 	if err != nil {
@@ -214,7 +234,7 @@ func UnwindZkIntermediateHashesStage(u *stagedsync.UnwindState, s *stagedsync.St
 		expectedRootHash = syncHeadHeader.Root
 	}
 
-	if _, err = zkSmt.UnwindZkSMT(ctx, s.LogPrefix(), s.BlockNumber, u.UnwindPoint, tx, cfg.checkRoot, &expectedRootHash, silent); err != nil {
+	if _, err = zkSmt.UnwindZkSMT(ctx, s.LogPrefix(), s.BlockNumber, u.UnwindPoint, tx, cfg.checkRoot, &expectedRootHash, silent, keepReplacedSmtNodes(cfg.zk)); err != nil {
 		return err
 	}
 	hermezDb := hermez_db.NewHermezDb(tx)
@@ -233,6 +253,76 @@ func UnwindZkIntermediateHashesStage(u *stagedsync.UnwindState, s *stagedsync.St
 	return nil
 }
 
+// PruneZkIntermediateHashesStage deletes SMT nodes no longer reachable from the state roots of the last
+// zkevm.smt-prune-retain-batches batches, a zkevm.smt-prune-step sized chunk per stage loop
+func PruneZkIntermediateHashesStage(p *stagedsync.PruneState, tx kv.RwTx, cfg ZkInterHashesCfg, ctx context.Context) (err error) {
+	if cfg.smtPruner == nil {
+		return nil
+	}
+
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	roots, err := retainedSmtRoots(tx, cfg.zk.SmtPruneRetainBatches)
+	if err != nil {
+		return err
+	}
+	if err = cfg.smtPruner.Prune(p.LogPrefix(), tx, roots); err != nil {
+		return err
+	}
+
+	if !useExternalTx {
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepReplacedSmtNodes tells whether the SMT keeps the nodes replaced by its writes, it does when pruned so that the
+// roots of the retained batches stay whole until the pruner deletes what no retained root needs
+func keepReplacedSmtNodes(zk *ethconfig.Zk) bool {
+	return zk != nil && zk.SmtPruneMode == zkSmt.PruneModePruned
+}
+
+// retainedSmtRoots returns the state roots at the end of the last retainBatches batches
+func retainedSmtRoots(tx kv.Tx, retainBatches uint64) ([]*big.Int, error) {
+	latestBlock, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	fromBatch, latestBatch, err := zkSmt.RetainedBatches(tx, retainBatches)
+	if err != nil {
+		return nil, err
+	}
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+
+	roots := make([]*big.Int, 0, retainBatches)
+	for batch := latestBatch; batch >= fromBatch; batch-- {
+		blockNo, found, err := hermezDb.GetHighestBlockInBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		// the open batch ends at the latest block, batches above it have been unwound
+		if found && blockNo <= latestBlock {
+			if header := rawdb.ReadHeaderByNumber(tx, blockNo); header != nil {
+				roots = append(roots, header.Root.Big())
+			}
+		}
+		if batch == 0 {
+			break
+		}
+	}
+
+	return roots, nil
+}
+
 func regenerateIntermediateHashes(ctx context.Context, logPrefix string, db kv.RwTx, eridb *db2.EriDb, smtIn *smt.SMT, toBlock uint64) (common.Hash, error) {
 	log.Info(fmt.Sprintf("[%s] Regeneration trie hashes started", logPrefix))
 	defer log.Info(fmt.Sprintf("[%s] Regeneration ended", logPrefix))
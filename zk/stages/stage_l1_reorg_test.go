@@ -0,0 +1,213 @@
+package stages_test
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/accounts/abi/bind"
+	"github.com/ledgerwatch/erigon/accounts/abi/bind/backends"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	"github.com/ledgerwatch/erigon/zk/l1infotree"
+	zkStages "github.com/ledgerwatch/erigon/zk/stages"
+	"github.com/ledgerwatch/erigon/zk/syncer"
+	zkTypes "github.com/ledgerwatch/erigon/zk/types"
+	"github.com/ledgerwatch/erigon/zkevm/etherman"
+)
+
+// newSimulatedL1 returns the simulated l1 of the etherman, its head is the block the contracts were deployed in
+func newSimulatedL1(t *testing.T) *backends.SimulatedBackend {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	require.NoError(t, err)
+	_, l1, _, _, err := etherman.NewSimulatedEtherman(etherman.Config{}, auth)
+	require.NoError(t, err)
+	return l1
+}
+
+// l1BlockHash returns the canonical hash of the block, or a made up one for blocks of a fork l1 dropped
+func l1BlockHash(t *testing.T, l1 *backends.SimulatedBackend, l1BlockNo uint64, canonical bool) common.Hash {
+	if !canonical {
+		return common.BigToHash(new(big.Int).SetUint64(l1BlockNo))
+	}
+	header, err := l1.HeaderByNumber(context.Background(), new(big.Int).SetUint64(l1BlockNo))
+	require.NoError(t, err)
+	return header.Hash()
+}
+
+func stopL1Syncer(t *testing.T, l1Syncer *syncer.L1Syncer) {
+	t.Cleanup(func() {
+		l1Syncer.StopQueryBlocks()
+		l1Syncer.ConsumeQueryBlocks()
+		l1Syncer.WaitQueryBlocksToFinish()
+	})
+}
+
+// testUnwinder records the unwind a stage asked for
+type testUnwinder struct {
+	unwindPoint *uint64
+}
+
+func (u *testUnwinder) UnwindTo(unwindPoint uint64, _ stagedsync.UnwindReason) {
+	u.unwindPoint = &unwindPoint
+}
+
+func (u *testUnwinder) IsUnwindSet() bool {
+	return u.unwindPoint != nil
+}
+
+func TestSpawnStageL1SyncerReorg(t *testing.T) {
+	// Arrange
+	ctx, db1 := context.Background(), memdb.NewTestDB(t)
+	tx := memdb.BeginRw(t, db1)
+	err := hermez_db.CreateHermezBuckets(tx)
+	require.NoError(t, err)
+
+	l1 := newSimulatedL1(t)
+
+	// batch 1 was sequenced in the l1 head, 2 and 3 in blocks of a fork l1 dropped for a shorter one
+	hDB := hermez_db.NewHermezDb(tx)
+	for l1BlockNo := uint64(1); l1BlockNo <= 3; l1BlockNo++ {
+		err = hDB.WriteSequence(l1BlockNo, l1BlockNo, common.HexToHash("0x1"), common.Hash{}, common.Hash{})
+		require.NoError(t, err)
+		err = hDB.WriteL1BlockHash(l1BlockNo, l1BlockHash(t, l1, l1BlockNo, l1BlockNo == 1))
+		require.NoError(t, err)
+	}
+	err = hDB.WriteVerification(1, 1, common.HexToHash("0x2"), common.HexToHash("0x99"))
+	require.NoError(t, err)
+	err = hDB.WriteVerification(3, 2, common.HexToHash("0x3"), common.HexToHash("0x99"))
+	require.NoError(t, err)
+	err = stages.SaveStageProgress(tx, stages.L1Syncer, 3)
+	require.NoError(t, err)
+	err = stages.SaveStageProgress(tx, stages.L1VerificationsBatchNo, 2)
+	require.NoError(t, err)
+
+	// the first info tree update is in the l1 head, the second in a block of the dropped fork and l2 block 6 used it
+	writeReorgTestL1InfoTree(t, hDB, l1)
+	err = stages.SaveStageProgress(tx, stages.L1InfoTree, 4)
+	require.NoError(t, err)
+	for l2BlockNo, index := range map[uint64]uint64{2: 0, 6: 1} {
+		err = hDB.WriteBlockL1InfoTreeIndex(l2BlockNo, index)
+		require.NoError(t, err)
+	}
+
+	s := &stagedsync.StageState{ID: stages.L1Syncer, BlockNumber: 3}
+	u := &testUnwinder{}
+
+	l1Syncer := syncer.NewL1Syncer(ctx, []syncer.IEtherman{l1}, []common.Address{common.HexToAddress("0x1")}, [][]common.Hash{{common.HexToHash("0x1")}}, 10, 0, "latest")
+	stopL1Syncer(t, l1Syncer)
+	cfg := zkStages.StageL1SyncerCfg(db1, l1Syncer, &ethconfig.Zk{L1FirstBlock: 1}, nil, nil)
+
+	// Act
+	err = zkStages.SpawnStageL1Syncer(s, u, ctx, tx, cfg, false)
+	require.NoError(t, err)
+
+	// Assert
+	for batchNo := uint64(1); batchNo <= 3; batchNo++ {
+		sequence, err := hDB.GetSequenceByBatchNo(batchNo)
+		require.NoError(t, err)
+		if batchNo == 1 {
+			require.NotNil(t, sequence)
+		} else {
+			require.Nil(t, sequence)
+		}
+	}
+	verification, err := hDB.GetLatestVerification()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), verification.BatchNo)
+
+	l1BlockNo, _, found, err := hDB.GetPreviousRecordedL1BlockHash(math.MaxUint64)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(1), l1BlockNo)
+
+	latestUpdate, err := hDB.GetLatestL1InfoTreeUpdate()
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), latestUpdate.Index)
+	leaves, err := hDB.GetAllL1InfoTreeLeaves()
+	require.NoError(t, err)
+	require.Len(t, leaves, 1)
+	roots, err := hDB.GetL1InfoTreeIndexToRoots()
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+
+	progress, err := stages.GetStageProgress(tx, stages.L1Syncer)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), progress)
+	progress, err = stages.GetStageProgress(tx, stages.L1InfoTree)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), progress)
+	verifiedBatchNo, err := stages.GetStageProgress(tx, stages.L1VerificationsBatchNo)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), verifiedBatchNo)
+
+	// l2 block 6 used the removed index so the l2 chain goes back to the block before it
+	require.True(t, u.IsUnwindSet())
+	require.Equal(t, uint64(5), *u.unwindPoint)
+}
+
+func TestSpawnL1InfoTreeStageAfterReorg(t *testing.T) {
+	// Arrange
+	ctx, db1 := context.Background(), memdb.NewTestDB(t)
+	tx := memdb.BeginRw(t, db1)
+	err := hermez_db.CreateHermezBuckets(tx)
+	require.NoError(t, err)
+
+	l1 := newSimulatedL1(t)
+
+	hDB := hermez_db.NewHermezDb(tx)
+	writeReorgTestL1InfoTree(t, hDB, l1)
+	err = stages.SaveStageProgress(tx, stages.L1InfoTree, 4)
+	require.NoError(t, err)
+
+	zkCfg := &ethconfig.Zk{L1FirstBlock: 1}
+	l1Syncer := syncer.NewL1Syncer(ctx, []syncer.IEtherman{l1}, []common.Address{common.HexToAddress("0x1")}, [][]common.Hash{{common.HexToHash("0x1")}}, 10, 0, "latest")
+	stopL1Syncer(t, l1Syncer)
+	l1SyncerCfg := zkStages.StageL1SyncerCfg(db1, l1Syncer, zkCfg, nil, nil)
+	infoTreeSyncer := syncer.NewL1Syncer(ctx, []syncer.IEtherman{l1}, []common.Address{common.HexToAddress("0x1")}, [][]common.Hash{{common.HexToHash("0x1")}}, 10, 0, "latest")
+	stopL1Syncer(t, infoTreeSyncer)
+	updater := l1infotree.NewUpdater(zkCfg, infoTreeSyncer, l1infotree.NewInfoTreeL2RpcSyncer(ctx, zkCfg))
+	cfg := zkStages.StageL1InfoTreeCfg(db1, zkCfg, updater)
+
+	// Act
+	err = zkStages.SpawnStageL1Syncer(&stagedsync.StageState{ID: stages.L1Syncer}, &testUnwinder{}, ctx, tx, l1SyncerCfg, false)
+	require.NoError(t, err)
+	err = zkStages.SpawnL1InfoTreeStage(&stagedsync.StageState{ID: stages.L1InfoTree, BlockNumber: 4}, &testUnwinder{}, tx, cfg, ctx, log.New())
+	require.NoError(t, err)
+
+	// Assert
+	require.Equal(t, uint64(0), updater.GetLatestUpdate().Index)
+	require.Equal(t, uint64(1), updater.GetProgress())
+
+	l1BlockNo, _, found, err := hDB.GetPreviousL1InfoTreeBlockHash(math.MaxUint64)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, uint64(1), l1BlockNo)
+}
+
+// writeReorgTestL1InfoTree writes an l1 info tree update in the l1 head and another in a block of a fork l1 dropped
+func writeReorgTestL1InfoTree(t *testing.T, hDB *hermez_db.HermezDb, l1 *backends.SimulatedBackend) {
+	for index, l1BlockNo := range []uint64{1, 3} {
+		update := &zkTypes.L1InfoTreeUpdate{
+			Index:       uint64(index),
+			GER:         common.BigToHash(big.NewInt(int64(index + 1))),
+			BlockNumber: l1BlockNo,
+		}
+		require.NoError(t, hDB.WriteL1InfoTreeUpdate(update))
+		require.NoError(t, hDB.WriteL1InfoTreeUpdateToGer(update))
+		require.NoError(t, hDB.WriteL1InfoTreeLeaf(update.Index, common.BigToHash(big.NewInt(int64(index+10)))))
+		require.NoError(t, hDB.WriteL1InfoTreeRoot(common.BigToHash(big.NewInt(int64(index+20))), update.Index))
+		require.NoError(t, hDB.WriteL1InfoTreeBlockHash(l1BlockNo, l1BlockHash(t, l1, l1BlockNo, l1BlockNo == 1)))
+	}
+}
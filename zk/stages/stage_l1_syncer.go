@@ -18,9 +18,11 @@ import (
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/zk/contracts"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
 	"github.com/ledgerwatch/erigon/zk/sequencer"
+	"github.com/ledgerwatch/erigon/zk/syncer"
 	"github.com/ledgerwatch/erigon/zk/types"
 )
 
@@ -54,13 +56,20 @@ type L1SyncerCfg struct {
 	syncer IL1Syncer
 
 	zkCfg *ethconfig.Zk
+
+	// events receives the batchVirtualized and batchVerified events, nil when the node serves no rpc subscriptions
+	events *shards.Events
+	// accInputHashes works out the acc input hashes of the batches in the events, nil to send them without
+	accInputHashes syncer.AccInputHashSource
 }
 
-func StageL1SyncerCfg(db kv.RwDB, syncer IL1Syncer, zkCfg *ethconfig.Zk) L1SyncerCfg {
+func StageL1SyncerCfg(db kv.RwDB, syncer IL1Syncer, zkCfg *ethconfig.Zk, events *shards.Events, accInputHashes syncer.AccInputHashSource) L1SyncerCfg {
 	return L1SyncerCfg{
-		db:     db,
-		syncer: syncer,
-		zkCfg:  zkCfg,
+		db:             db,
+		syncer:         syncer,
+		zkCfg:          zkCfg,
+		events:         events,
+		accInputHashes: accInputHashes,
 	}
 }
 
@@ -107,6 +116,16 @@ func SpawnStageL1Syncer(
 		return fmt.Errorf("GetStageProgress, %w", err)
 	}
 
+	// the data recorded so far has to be canonical before anything new is added on top of it, the l1 info tree is
+	// checked here too so the l1 headers are only fetched once per cycle
+	forkBlock, reorged, err := unwindL1Reorg(logPrefix, u, tx, hermezDb, cfg)
+	if err != nil {
+		return fmt.Errorf("unwindL1Reorg: %w", err)
+	}
+	if reorged && l1BlockProgress > forkBlock {
+		l1BlockProgress = forkBlock
+	}
+
 	// start syncer if not started
 	if !cfg.syncer.IsSyncStarted() {
 		if l1BlockProgress == 0 {
@@ -131,6 +150,17 @@ func SpawnStageL1Syncer(
 	newVerificationsCount := 0
 	newSequencesCount := 0
 	highestWrittenL1BlockNo := uint64(0)
+	var batchEvents []*types.BatchEvent
+
+	// a sequence or verification covers every batch after the last one sequenced or verified before it
+	lastSequencedBatch, err := latestL1BatchNo(hermezDb.GetLatestSequence)
+	if err != nil {
+		return fmt.Errorf("GetLatestSequence: %w", err)
+	}
+	lastVerifiedBatch, err := latestL1BatchNo(hermezDb.GetLatestVerification)
+	if err != nil {
+		return fmt.Errorf("GetLatestVerification: %w", err)
+	}
 Loop:
 	for {
 		select {
@@ -149,6 +179,11 @@ Loop:
 					if err := hermezDb.WriteSequence(info.L1BlockNo, info.BatchNo, info.L1TxHash, info.StateRoot, info.L1InfoRoot); err != nil {
 						return fmt.Errorf("WriteSequence: %w", err)
 					}
+					batchEvents = append(batchEvents, newL1BatchEvents(types.BatchVirtualized, lastSequencedBatch, info)...)
+					lastSequencedBatch = info.BatchNo
+					if err := writeL1BlockHash(hermezDb, &l); err != nil {
+						return fmt.Errorf("WriteL1BlockHash: %w", err)
+					}
 					if info.L1BlockNo > highestWrittenL1BlockNo {
 						highestWrittenL1BlockNo = info.L1BlockNo
 					}
@@ -157,6 +192,12 @@ Loop:
 					if err := hermezDb.RollbackSequences(info.BatchNo); err != nil {
 						return fmt.Errorf("RollbackSequences: %w", err)
 					}
+					if info.BatchNo < lastSequencedBatch {
+						lastSequencedBatch = info.BatchNo
+					}
+					if err := writeL1BlockHash(hermezDb, &l); err != nil {
+						return fmt.Errorf("WriteL1BlockHash: %w", err)
+					}
 					if info.L1BlockNo > highestWrittenL1BlockNo {
 						highestWrittenL1BlockNo = info.L1BlockNo
 					}
@@ -173,6 +214,13 @@ Loop:
 					if err := hermezDb.WriteVerification(info.L1BlockNo, info.BatchNo, info.L1TxHash, info.StateRoot); err != nil {
 						return fmt.Errorf("WriteVerification for block %d: %w", info.L1BlockNo, funcErr)
 					}
+					batchEvents = append(batchEvents, newL1BatchEvents(types.BatchVerified, lastVerifiedBatch, info)...)
+					if info.BatchNo > lastVerifiedBatch {
+						lastVerifiedBatch = info.BatchNo
+					}
+					if err := writeL1BlockHash(hermezDb, &l); err != nil {
+						return fmt.Errorf("WriteL1BlockHash: %w", err)
+					}
 					if info.L1BlockNo > highestWrittenL1BlockNo {
 						highestWrittenL1BlockNo = info.L1BlockNo
 					}
@@ -221,16 +269,150 @@ Loop:
 		log.Info(fmt.Sprintf("[%s] No new L1 blocks to sync", logPrefix))
 	}
 
+	for _, batchEvent := range batchEvents {
+		if err := fillBatchEventBlocks(tx, hermezDb.HermezDbReader, batchEvent); err != nil {
+			return fmt.Errorf("fillBatchEventBlocks: %w", err)
+		}
+	}
+	if cfg.events != nil && cfg.accInputHashes != nil {
+		fillBatchEventAccInputHashes(ctx, hermezDb.HermezDbReader, cfg.accInputHashes, batchEvents)
+	}
+	queueBatchEvents(cfg.events, batchEvents...)
+
 	if internalTxOpened {
 		log.Debug("l1 sync: first cycle, committing tx")
 		if err := tx.Commit(); err != nil {
 			return fmt.Errorf("tx.Commit: %w", err)
 		}
+		flushBatchEvents(cfg.events)
 	}
 
 	return nil
 }
 
+// newL1BatchEvents are the events of a sequence or verification on the L1, one for each batch after lastBatch up to
+// the batch of the log.  Only the last batch gets the state root from the L1, the others take it from their synced
+// blocks.  With nothing sequenced or verified before, lastBatch is 0 and only the batch of the log is known.
+func newL1BatchEvents(eventType types.BatchEventType, lastBatch uint64, info types.L1BatchInfo) []*types.BatchEvent {
+	fromBatch := lastBatch + 1
+	if lastBatch == 0 || fromBatch > info.BatchNo {
+		fromBatch = info.BatchNo
+	}
+
+	events := make([]*types.BatchEvent, 0, info.BatchNo-fromBatch+1)
+	for batchNo := fromBatch; batchNo <= info.BatchNo; batchNo++ {
+		event := &types.BatchEvent{
+			Type:          eventType,
+			BatchNumber:   batchNo,
+			L1TxHash:      info.L1TxHash,
+			L1BlockNumber: info.L1BlockNo,
+		}
+		if batchNo == info.BatchNo {
+			event.StateRoot = info.StateRoot
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func latestL1BatchNo(getLatest func() (*types.L1BatchInfo, error)) (uint64, error) {
+	info, err := getLatest()
+	if err != nil || info == nil {
+		return 0, err
+	}
+	return info.BatchNo, nil
+}
+
+// writeL1BlockHash records the hash of the l1 block the log was found in so a later reorg of it can be detected
+func writeL1BlockHash(hermezDb *hermez_db.HermezDb, l *ethTypes.Log) error {
+	if l.BlockHash == (common.Hash{}) {
+		return nil
+	}
+	return hermezDb.WriteL1BlockHash(l.BlockNumber, l.BlockHash)
+}
+
+// unwindL1Reorg checks that the l1 blocks sequences, verifications and l1 info tree updates were recorded from are
+// still canonical.  If l1 has reorganised them away the syncer is stopped, everything recorded above the fork block is
+// deleted and the progress of this stage and the l1 info tree stage is moved back to it so they pick up the new fork.
+// L2 blocks built on the l1 info tree updates that were removed are unwound.
+func unwindL1Reorg(logPrefix string, u stagedsync.Unwinder, tx kv.RwTx, hermezDb *hermez_db.HermezDb, cfg L1SyncerCfg) (uint64, bool, error) {
+	forkBlock, reorged, found, err := syncer.FindL1ReorgForkBlock(cfg.syncer.GetHeader, hermezDb.GetPreviousRecordedL1BlockHash)
+	if err != nil {
+		return 0, false, err
+	}
+	if !reorged {
+		return 0, false, nil
+	}
+	if !found {
+		forkBlock = cfg.zkCfg.L1FirstBlock - 1
+	}
+
+	log.Warn(fmt.Sprintf("[%s] L1 reorg detected, unwinding the data recorded from L1", logPrefix), "forkBlock", forkBlock)
+
+	// logs already fetched by the running syncer may come from the old fork
+	if cfg.syncer.IsSyncStarted() {
+		cfg.syncer.StopQueryBlocks()
+		cfg.syncer.ConsumeQueryBlocks()
+		cfg.syncer.WaitQueryBlocksToFinish()
+	}
+
+	if err = hermezDb.DeleteL1SequencesAbove(forkBlock); err != nil {
+		return 0, false, fmt.Errorf("DeleteL1SequencesAbove: %w", err)
+	}
+	if err = hermezDb.DeleteL1VerificationsAbove(forkBlock); err != nil {
+		return 0, false, fmt.Errorf("DeleteL1VerificationsAbove: %w", err)
+	}
+	if err = hermezDb.DeleteL1BlockHashesAbove(forkBlock); err != nil {
+		return 0, false, fmt.Errorf("DeleteL1BlockHashesAbove: %w", err)
+	}
+	lowestRemovedIndex, infoTreeRemoved, err := hermezDb.TruncateL1InfoTreeAbove(forkBlock)
+	if err != nil {
+		return 0, false, fmt.Errorf("TruncateL1InfoTreeAbove: %w", err)
+	}
+	if err = hermezDb.DeleteL1InfoTreeBlockHashesAbove(forkBlock); err != nil {
+		return 0, false, fmt.Errorf("DeleteL1InfoTreeBlockHashesAbove: %w", err)
+	}
+
+	// the l1 info tree stage restarts its own syncer when it finds its progress moved back
+	for _, stage := range []stages.SyncStage{stages.L1Syncer, stages.L1InfoTree} {
+		progress, err := stages.GetStageProgress(tx, stage)
+		if err != nil {
+			return 0, false, fmt.Errorf("GetStageProgress: %w", err)
+		}
+		if progress > forkBlock {
+			if err = stages.SaveStageProgress(tx, stage, forkBlock); err != nil {
+				return 0, false, fmt.Errorf("SaveStageProgress: %w", err)
+			}
+		}
+	}
+
+	var verifiedBatchNo uint64
+	latestVerification, err := hermezDb.GetLatestVerification()
+	if err != nil {
+		return 0, false, fmt.Errorf("GetLatestVerification: %w", err)
+	}
+	if latestVerification != nil {
+		verifiedBatchNo = latestVerification.BatchNo
+	}
+	if err = stages.SaveStageProgress(tx, stages.L1VerificationsBatchNo, verifiedBatchNo); err != nil {
+		return 0, false, fmt.Errorf("SaveStageProgress: %w", err)
+	}
+
+	if infoTreeRemoved {
+		// the blocks that used a removed index point at global exit roots l1 no longer has
+		firstBlock, found, err := hermezDb.GetFirstBlockUsingL1InfoTreeIndex(lowestRemovedIndex)
+		if err != nil {
+			return 0, false, fmt.Errorf("GetFirstBlockUsingL1InfoTreeIndex: %w", err)
+		}
+		if found && firstBlock > 0 {
+			log.Warn(fmt.Sprintf("[%s] L2 blocks used L1 info tree indexes removed by the L1 reorg, unwinding them", logPrefix), "l1InfoTreeIndex", lowestRemovedIndex, "unwindTo", firstBlock-1)
+			u.UnwindTo(firstBlock-1, stagedsync.StagedUnwind)
+		}
+	}
+
+	return forkBlock, true, nil
+}
+
 type BatchLogType byte
 
 var (
@@ -2,13 +2,17 @@ package stages
 
 import (
 	"context"
+	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
 	ethereum "github.com/ledgerwatch/erigon"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
@@ -19,6 +23,8 @@ import (
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
 	"github.com/ledgerwatch/erigon/zk/syncer"
 	"github.com/ledgerwatch/erigon/zk/syncer/mocks"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+	"github.com/ledgerwatch/erigon/zk/utils"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -302,7 +308,7 @@ func TestSpawnStageL1Syncer(t *testing.T) {
 		L1RollupId:   rollupID,
 		L1FirstBlock: l1FirstBlock.Uint64(),
 	}
-	cfg := StageL1SyncerCfg(db1, l1Syncer, zkCfg)
+	cfg := StageL1SyncerCfg(db1, l1Syncer, zkCfg, nil, nil)
 	quiet := false
 
 	// Act
@@ -314,3 +320,98 @@ func TestSpawnStageL1Syncer(t *testing.T) {
 		tc.assert(t, hDB)
 	}
 }
+
+func TestNewL1BatchEvents(t *testing.T) {
+	info := zktypes.L1BatchInfo{BatchNo: 7, L1BlockNo: 20, L1TxHash: common.HexToHash("0x1"), StateRoot: common.HexToHash("0x2")}
+
+	// a verification of batches 5 to 7 after batch 4 was verified
+	events := newL1BatchEvents(zktypes.BatchVerified, 4, info)
+	require.Len(t, events, 3)
+	for i, event := range events {
+		require.Equal(t, zktypes.BatchVerified, event.Type)
+		require.Equal(t, uint64(5+i), event.BatchNumber)
+		require.Equal(t, info.L1TxHash, event.L1TxHash)
+		require.Equal(t, info.L1BlockNo, event.L1BlockNumber)
+	}
+	require.Equal(t, common.Hash{}, events[0].StateRoot)
+	require.Equal(t, info.StateRoot, events[2].StateRoot)
+
+	// nothing recorded before, or a batch at or below the last one, only covers the batch of the log
+	for _, lastBatch := range []uint64{0, 7, 9} {
+		events = newL1BatchEvents(zktypes.BatchVirtualized, lastBatch, info)
+		require.Len(t, events, 1)
+		require.Equal(t, uint64(7), events[0].BatchNumber)
+	}
+}
+
+// testAccInputHashSource serves a single banana sequence and counts the transactions asked for
+type testAccInputHashSource struct {
+	txs      map[common.Hash]types.Transaction
+	hashes   map[uint64]common.Hash
+	txLookup int
+}
+
+func (s *testAccInputHashSource) GetTransaction(hash common.Hash) (types.Transaction, bool, error) {
+	s.txLookup++
+	tx, ok := s.txs[hash]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown tx %s", hash)
+	}
+	return tx, false, nil
+}
+
+func (s *testAccInputHashSource) GetL1BlockTimestamp(ctx context.Context, l1BlockNo uint64) (uint64, error) {
+	return 0, fmt.Errorf("unknown L1 block %d", l1BlockNo)
+}
+
+func (s *testAccInputHashSource) GetAccInputHash(ctx context.Context, forkId, batchNo uint64) (common.Hash, error) {
+	return s.hashes[batchNo], nil
+}
+
+func TestFillBatchEventAccInputHashes(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	l1InfoRoot, coinbase := common.HexToHash("0xa1"), common.HexToAddress("0xc0ffee")
+	txA, txB := common.HexToHash("0xa"), common.HexToHash("0xb")
+	require.NoError(t, hermezDb.WriteSequence(10, 1, txA, common.Hash{}, l1InfoRoot))
+	require.NoError(t, hermezDb.WriteSequence(20, 3, txB, common.Hash{}, l1InfoRoot))
+	require.NoError(t, hermezDb.WriteSequence(30, 4, common.HexToHash("0xc"), common.Hash{}, l1InfoRoot))
+
+	sequenceAbi, err := abi.JSON(strings.NewReader(contracts.SequenceBatchesAbiBanana))
+	require.NoError(t, err)
+	type batchData struct {
+		Transactions         []byte
+		ForcedGlobalExitRoot [32]byte
+		ForcedTimestamp      uint64
+		ForcedBlockHashL1    [32]byte
+	}
+	calldata, err := sequenceAbi.Pack("sequenceBatches", []batchData{{Transactions: []byte{2}}, {Transactions: []byte{3}}}, uint32(0), uint64(2000), [32]byte{}, coinbase)
+	require.NoError(t, err)
+	source := &testAccInputHashSource{
+		txs:    map[common.Hash]types.Transaction{txB: types.NewTransaction(0, common.Address{}, u256.Num0, 0, u256.Num0, calldata)},
+		hashes: map[uint64]common.Hash{1: common.HexToHash("0x1")},
+	}
+
+	events := []*zktypes.BatchEvent{
+		{Type: zktypes.BatchClosed, BatchNumber: 2},
+		{Type: zktypes.BatchVirtualized, BatchNumber: 2},
+		{Type: zktypes.BatchVirtualized, BatchNumber: 3},
+		{Type: zktypes.BatchVerified, BatchNumber: 3},
+		{Type: zktypes.BatchVirtualized, BatchNumber: 4},
+	}
+	fillBatchEventAccInputHashes(context.Background(), hermezDb.HermezDbReader, source, events)
+
+	batch2 := *utils.CalculateEtrogAccInputHash(source.hashes[1], []byte{2}, l1InfoRoot, 2000, coinbase, common.Hash{})
+	batch3 := *utils.CalculateEtrogAccInputHash(batch2, []byte{3}, l1InfoRoot, 2000, coinbase, common.Hash{})
+	require.Equal(t, common.Hash{}, events[0].AccInputHash)
+	require.Equal(t, batch2, events[1].AccInputHash)
+	require.Equal(t, batch3, events[2].AccInputHash)
+	require.Equal(t, batch3, events[3].AccInputHash)
+	// the sequence of batch 4 can't be fetched, its event goes out without the hash
+	require.Equal(t, common.Hash{}, events[4].AccInputHash)
+
+	// every sequence is only fetched once
+	require.Equal(t, 2, source.txLookup)
+}
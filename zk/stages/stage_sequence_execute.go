@@ -48,7 +48,7 @@ func SpawnSequencingStage(
 		return err
 	}
 
-	if lastSequence != nil && lastBatch < lastSequence.BatchNo && !(cfg.zk.IsL1Recovery() || cfg.zk.SequencerResequence) {
+	if lastSequence != nil && lastBatch < lastSequence.BatchNo && !(cfg.zk.IsL1Recovery() || cfg.zk.SequencerResequence || cfg.zk.SequencerResequenceDryRun) {
 		if !cfg.zk.ShadowSequencer {
 			panic(fmt.Sprintf("lastBatch %d < lastSequence.BatchNo %d", lastBatch, lastSequence.BatchNo))
 		}
@@ -59,6 +59,18 @@ func SpawnSequencingStage(
 		return err
 	}
 
+	// a dry run never sequences, it only reports on what resequencing the datastream would do
+	if cfg.zk.SequencerResequenceDryRun {
+		if lastBatch < highestBatchInDs && !resequenceDryRunDone {
+			if err = resequenceDryRun(s, ctx, cfg, historyCfg, lastBatch, highestBatchInDs); err != nil {
+				return err
+			}
+		}
+		log.Info(fmt.Sprintf("[%s] Resequencing dry run completed. Please restart sequencer without resequence dry run flag.", s.LogPrefix()))
+		time.Sleep(10 * time.Minute)
+		return nil
+	}
+
 	if lastBatch < highestBatchInDs {
 		if !cfg.zk.SequencerResequence {
 			if err = cfg.dataStreamServer.UnwindToBatchStart(lastBatch + 1); err != nil {
@@ -90,12 +102,26 @@ func sequencingBatchStep(
 	log.Info(fmt.Sprintf("[%s] Starting sequencing stage", logPrefix))
 	defer log.Info(fmt.Sprintf("[%s] Finished sequencing stage", logPrefix))
 
+	// the batches closed since the last block's done hook are only sent once the step has committed them
+	defer func() {
+		if err == nil {
+			flushBatchEvents(cfg.events)
+		}
+	}()
+
+	// a sequencer sharing a lease with standbys waits until it holds the lease
+	if err := cfg.failover.Check(); err != nil {
+		log.Warn(fmt.Sprintf("[%s] Not sequencing", logPrefix), "err", err)
+		time.Sleep(time.Second)
+		return nil
+	}
+
 	// at this point of time the datastream could not be ahead of the executor
 	if err := validateIfDatastreamIsAheadOfExecution(s, ctx, cfg); err != nil {
 		return err
 	}
 
-	sdb, err := newStageDb(ctx, cfg.db)
+	sdb, err := newStageDb(ctx, cfg.db, keepReplacedSmtNodes(cfg.zk))
 	if err != nil {
 		return err
 	}
@@ -261,6 +287,9 @@ func sequencingBatchStep(
 			log.Debug(fmt.Sprintf("[%s] Closing batch due to timeout", logPrefix))
 			break
 		}
+		if err = cfg.failover.Check(); err != nil {
+			return fmt.Errorf("[%s] stopped before block %d: %w", logPrefix, blockNumber, err)
+		}
 		startTime := time.Now()
 		log.Info(fmt.Sprintf("[%s] Starting block %d (forkid %v)...", logPrefix, blockNumber, batchState.forkId))
 		logTicker.Reset(10 * time.Second)
@@ -329,6 +358,10 @@ func sequencingBatchStep(
 		coinbase := batchState.getCoinbase(&cfg)
 		blockContext := core.NewEVMBlockContext(header, getHashFn, cfg.engine, &coinbase)
 		batchState.blockState.builtBlockElements.resetBlockBuildingArrays()
+		batchState.blockState.l1GasPrice = nil
+		if cfg.l1GasPriceProvider != nil {
+			batchState.blockState.l1GasPrice = cfg.l1GasPriceProvider.GetLatestL1Price()
+		}
 
 		parentRoot := parentBlock.Root()
 		if err := handleStateForNewBlockStarting(batchContext, ibs, blockNumber, batchState.batchNumber, header.Time, &parentRoot, l1TreeUpdate, shouldWriteGerToContract); err != nil {
@@ -412,7 +445,7 @@ func sequencingBatchStep(
 				var newTransactions []types.Transaction
 				var newIds []common.Hash
 
-				newTransactions, newIds, _, err = getNextPoolTransactions(ctx, cfg, executionAt, batchState.forkId, batchState.yieldedTransactions)
+				newTransactions, newIds, _, err = getNextPoolTransactions(ctx, cfg, executionAt, batchState.forkId, batchState.batchNumber, batchState.yieldedTransactions)
 				if err != nil {
 					return err
 				}
@@ -553,7 +586,9 @@ func sequencingBatchStep(
 						if singleTxOverflow || (!batchState.hasAnyTransactionsInThisBatch && len(batchState.builtBlocks) == 0) {
 							ocs, _ := tempCounters.CounterStats(l1TreeUpdateIndex != 0)
 							// mark the transaction to be removed from the pool
-							cfg.txPool.MarkForDiscardFromPendingBest(txHash)
+							if cfg.txPool != nil {
+								cfg.txPool.MarkForDiscardFromPendingBest(txHash)
+							}
 							counter, err := handleBadTxHashCounter(sdb.hermezDb, txHash)
 							if err != nil {
 								return err
@@ -677,6 +712,11 @@ func sequencingBatchStep(
 			break
 		}
 
+		// the lease may have run out while the block was being built
+		if err = cfg.failover.Check(); err != nil {
+			return fmt.Errorf("[%s] stopped before finishing block %d: %w", logPrefix, blockNumber, err)
+		}
+
 		if block, err = doFinishBlockAndUpdateState(batchContext, ibs, header, parentBlock, batchState, ger, l1BlockHash, l1TreeUpdateIndex, infoTreeIndexProgress, batchCounters); err != nil {
 			return err
 		}
@@ -702,8 +742,10 @@ func sequencingBatchStep(
 
 		// remove mined transactions from the pool
 		toRemove := append(batchState.blockState.builtBlockElements.txSlots, batchState.blockState.transactionsToDiscard...)
-		if err := cfg.txPool.RemoveMinedTransactions(ctx, sdb.tx, header.GasLimit, toRemove); err != nil {
-			return err
+		if cfg.txPool != nil {
+			if err := cfg.txPool.RemoveMinedTransactions(ctx, sdb.tx, header.GasLimit, toRemove); err != nil {
+				return err
+			}
 		}
 
 		// remove the decoded transactions from the cache
@@ -715,8 +757,10 @@ func sequencingBatchStep(
 		}
 
 		// now trigger sender state changes in the pool where we encountered nonce issues during execution
-		if err := cfg.txPool.TriggerSenderStateChanges(ctx, sdb.tx, header.GasLimit, sendersToTriggerStatechanges); err != nil {
-			return err
+		if cfg.txPool != nil {
+			if err := cfg.txPool.TriggerSenderStateChanges(ctx, sdb.tx, header.GasLimit, sendersToTriggerStatechanges); err != nil {
+				return err
+			}
 		}
 
 		t.LogTimer()
@@ -243,6 +243,13 @@ func finaliseBlock(
 		return nil, fmt.Errorf("write block batch error: %v", err)
 	}
 
+	// keep the l1 gas price the block was built with, so the effective gas price of its transactions can be explained
+	if l1GasPrice := batchState.blockState.l1GasPrice; l1GasPrice != nil {
+		if err := batchContext.sdb.hermezDb.WriteBlockL1GasPrice(newNum.Uint64(), l1GasPrice); err != nil {
+			return nil, err
+		}
+	}
+
 	// write batch counters
 	err = batchContext.sdb.hermezDb.WriteBatchCounters(newNum.Uint64(), batchCounters.CombineCollectorsNoChanges().UsedAsArray())
 	if err != nil {
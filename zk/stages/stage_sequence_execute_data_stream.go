@@ -9,6 +9,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/zk/datastream/server"
 	verifier "github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
 	"github.com/ledgerwatch/erigon/zk/utils"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -171,5 +172,13 @@ func finalizeLastBatchInDatastream(batchContext *BatchContext, batchToClose, blo
 	if err = batchContext.cfg.dataStreamServer.WriteBatchEnd(batchContext.sdb.hermezDb, batchToClose, &root, &ler); err != nil {
 		return err
 	}
+
+	if batchContext.cfg.events != nil {
+		batchEvent := &zktypes.BatchEvent{Type: zktypes.BatchClosed, BatchNumber: batchToClose, StateRoot: root}
+		if err = fillBatchEventBlocks(batchContext.sdb.tx, batchContext.sdb.hermezDb.HermezDbReader, batchEvent); err != nil {
+			return err
+		}
+		queueBatchEvents(batchContext.cfg.events, batchEvent)
+	}
 	return nil
 }
@@ -71,6 +71,8 @@ func handleLimbo(batchContext *BatchContext, batchState *BatchState, verifierBun
 	}
 
 	limboBlock.BlockTimestamp = block.Time()
-	batchContext.cfg.txPool.ProcessUncheckedLimboBlockDetails(limboBlock)
+	if batchContext.cfg.txPool != nil {
+		batchContext.cfg.txPool.ProcessUncheckedLimboBlockDetails(limboBlock)
+	}
 	return nil
 }
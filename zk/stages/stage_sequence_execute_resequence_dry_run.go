@@ -0,0 +1,261 @@
+package stages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/backup"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/shirou/gopsutil/v3/disk"
+	"golang.org/x/exp/maps"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	dsTypes "github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	verifier "github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
+)
+
+const (
+	resequenceDryRunDir    = "resequence-dry-run"
+	resequenceDryRunReport = "report.json"
+)
+
+// resequenceDryRunSkippedTables only serve history queries and unwinds, neither of which the dry run makes, so they
+// are left out of the scratch copy of the chain data
+var resequenceDryRunSkippedTables = map[string]struct{}{
+	kv.AccountChangeSet:  {},
+	kv.StorageChangeSet:  {},
+	kv.E2AccountsHistory: {},
+	kv.E2StorageHistory:  {},
+	kv.CallTraceSet:      {},
+	kv.CallFromIndex:     {},
+	kv.CallToIndex:       {},
+	kv.LogTopicIndex:     {},
+	kv.LogAddressIndex:   {},
+	kv.TxLookup:          {},
+}
+
+// resequenceDryRunDone stops the dry run from being repeated on every stage loop, the node has to be restarted
+// without the flag once it has run
+var resequenceDryRunDone = false
+
+// resequenceDryRun resequences the batches the datastream holds above lastBatch into a copy of the chain data and
+// writes a report comparing them with the resequenced ones.  The datastream is only read and the node's own db is
+// only copied, neither is written to.
+func resequenceDryRun(
+	s *stagedsync.StageState,
+	ctx context.Context,
+	cfg SequenceBlockCfg,
+	historyCfg stagedsync.HistoryCfg,
+	lastBatch, highestBatchInDs uint64,
+) (err error) {
+	logPrefix := s.LogPrefix()
+	log.Info(fmt.Sprintf("[%s] Last batch %d is lower than highest batch in datastream %d, resequencing dry run...", logPrefix, lastBatch, highestBatchInDs))
+
+	batches, err := cfg.dataStreamServer.ReadBatchesWithConcurrency(lastBatch+1, highestBatchInDs)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(cfg.dirs.DataDir, resequenceDryRunDir)
+	if err = os.RemoveAll(dir); err != nil {
+		return err
+	}
+	scratchPath := filepath.Join(dir, "chaindata")
+	scratchDb, err := openResequenceScratchDb(ctx, logPrefix, cfg.db, scratchPath)
+	if err != nil {
+		return fmt.Errorf("scratch db: %w", err)
+	}
+	defer func() {
+		scratchDb.Close()
+		if removeErr := os.RemoveAll(scratchPath); removeErr != nil {
+			log.Warn(fmt.Sprintf("[%s] Failed to remove the scratch db", logPrefix), "path", scratchPath, "err", removeErr)
+		}
+	}()
+
+	executionAt, err := resequenceExecutionProgress(ctx, scratchDb)
+	if err != nil {
+		return err
+	}
+
+	dryRunCfg := cfg.resequenceDryRunCfg(scratchDb, newDryRunStream(cfg.dataStreamServer.GetChainId(), lastBatch, executionAt))
+	unwinder := &dryRunUnwinder{}
+
+	resequencedTo := make([]uint64, 0, len(batches))
+	for _, batch := range batches {
+		batchJob := NewResequenceBatchJob(batch)
+		subBatchCount := 0
+		for batchJob.HasMoreBlockToProcess() {
+			if err = sequencingBatchStep(s, unwinder, ctx, dryRunCfg, historyCfg, batchJob); err != nil {
+				return err
+			}
+			if unwinder.IsUnwindSet() {
+				return fmt.Errorf("resequencing original batch %d would unwind to block %d", batchJob.batchToProcess[0].BatchNumber, unwinder.unwindPoint)
+			}
+			subBatchCount += 1
+		}
+
+		to, err := resequenceExecutionProgress(ctx, scratchDb)
+		if err != nil {
+			return err
+		}
+		resequencedTo = append(resequencedTo, to)
+		log.Info(fmt.Sprintf("[%s] Dry run resequenced original batch %d with %d batches", logPrefix, batchJob.batchToProcess[0].BatchNumber, subBatchCount))
+	}
+
+	report, err := readResequenceReport(ctx, cfg.db, scratchDb, batches, resequencedTo, executionAt+1)
+	if err != nil {
+		return err
+	}
+
+	reportPath := filepath.Join(dir, resequenceDryRunReport)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(reportPath, data, 0644); err != nil {
+		return err
+	}
+
+	resequenceDryRunDone = true
+	log.Info(fmt.Sprintf("[%s] Resequencing dry run completed", logPrefix),
+		"batches", fmt.Sprintf("%d-%d", report.FromBatch, report.ToBatch),
+		"split", report.SplitBatches,
+		"stateRootMismatches", report.StateRootMismatches,
+		"droppedTransactions", report.DroppedTransactions,
+		"report", reportPath,
+	)
+	return nil
+}
+
+// resequenceDryRunCfg swaps everything the sequencer writes through for the scratch db and stream.  The txpool is left
+// out so the resequenced transactions are neither discarded from nor marked as mined in the node's pool, the executors
+// as they would read the blocks from the node's own db, and strict mode is off so the report covers every batch rather
+// than stopping at the first one strict mode would refuse.
+func (sCfg *SequenceBlockCfg) resequenceDryRunCfg(scratchDb kv.RwDB, stream *dryRunStream) SequenceBlockCfg {
+	zkCfg := *sCfg.zk
+	zkCfg.ExecutorEnabled = false
+	zkCfg.SequencerResequenceStrict = false
+
+	dryRunCfg := *sCfg
+	dryRunCfg.db = scratchDb
+	dryRunCfg.zk = &zkCfg
+	dryRunCfg.dataStreamServer = stream
+	dryRunCfg.legacyVerifier = verifier.NewLegacyExecutorVerifier(zkCfg, nil, scratchDb, nil, stream)
+	dryRunCfg.txPool = nil
+	dryRunCfg.txPoolDb = nil
+	dryRunCfg.accumulator = nil
+	dryRunCfg.failover = nil
+	dryRunCfg.events = nil
+	dryRunCfg.doneHook = dryRunDoneHook{}
+	return dryRunCfg
+}
+
+// openResequenceScratchDb copies the tables of db the sequencer reads, the hermez ones created at runtime included, into
+// a new db at path.  It fails before copying anything when the disk holding path has less free space than the tables
+// take up.
+func openResequenceScratchDb(ctx context.Context, logPrefix string, db kv.RwDB, path string) (kv.RwDB, error) {
+	tables := maps.Clone(db.AllTables())
+	copyTables, size, err := resequenceScratchTables(ctx, db, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Free < size {
+		return nil, fmt.Errorf("copying %s of chain data needs more than the %s free at %s", libcommon.ByteCount(size), libcommon.ByteCount(usage.Free), path)
+	}
+
+	log.Info(fmt.Sprintf("[%s] Copying the chain data to a scratch db", logPrefix), "path", path, "size", libcommon.ByteCount(size), "free", libcommon.ByteCount(usage.Free))
+	scratchDb, err := mdbx.NewMDBX(log.New()).
+		Label(kv.ChainDB).
+		Path(path).
+		PageSize(db.PageSize()).
+		WithTableCfg(func(_ kv.TableCfg) kv.TableCfg { return tables }).
+		Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = backup.Kv2kv(ctx, db, scratchDb, copyTables, backup.ReadAheadThreads, log.New()); err != nil {
+		scratchDb.Close()
+		return nil, err
+	}
+	return scratchDb, nil
+}
+
+// resequenceScratchTables returns the tables to copy into the scratch db and the space they take up in db
+func resequenceScratchTables(ctx context.Context, db kv.RoDB, tables kv.TableCfg) (copyTables []string, size uint64, err error) {
+	err = db.View(ctx, func(tx kv.Tx) error {
+		for name, cfg := range tables {
+			if _, skip := resequenceDryRunSkippedTables[name]; skip || cfg.IsDeprecated {
+				continue
+			}
+			tableSize, err := tx.BucketSize(name)
+			if err != nil {
+				return fmt.Errorf("BucketSize %s: %w", name, err)
+			}
+			copyTables = append(copyTables, name)
+			size += tableSize
+		}
+		return nil
+	})
+	return copyTables, size, err
+}
+
+func resequenceExecutionProgress(ctx context.Context, db kv.RoDB) (executionAt uint64, err error) {
+	err = db.View(ctx, func(tx kv.Tx) error {
+		executionAt, err = stages.GetStageProgress(tx, stages.Execution)
+		return err
+	})
+	return executionAt, err
+}
+
+func readResequenceReport(ctx context.Context, nodeDb, scratchDb kv.RoDB, batches [][]*dsTypes.FullL2Block, resequencedTo []uint64, firstBlock uint64) (*resequenceReport, error) {
+	nodeTx, err := nodeDb.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer nodeTx.Rollback()
+	scratchTx, err := scratchDb.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer scratchTx.Rollback()
+
+	return buildResequenceReport(batches, resequencedTo, firstBlock, hermez_db.NewHermezDbReader(nodeTx), scratchTx)
+}
+
+// dryRunDoneHook keeps the blocks built in the scratch db away from the node's subscribers
+type dryRunDoneHook struct{}
+
+func (dryRunDoneHook) AfterRun(kv.Tx, uint64, *uint64) error {
+	return nil
+}
+
+// dryRunUnwinder records an unwind the resequenced batches would need instead of unwinding the node
+type dryRunUnwinder struct {
+	unwindPoint uint64
+	unwindSet   bool
+}
+
+func (u *dryRunUnwinder) UnwindTo(unwindPoint uint64, _ stagedsync.UnwindReason) {
+	u.unwindPoint = unwindPoint
+	u.unwindSet = true
+}
+
+func (u *dryRunUnwinder) IsUnwindSet() bool {
+	return u.unwindSet
+}
@@ -0,0 +1,112 @@
+package stages
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	eritypes "github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/datastream/server"
+	dsTypes "github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+var errDryRunStream = errors.New("not available to a resequencing dry run")
+
+// dryRunStream stands in for the datastream while resequencing into a scratch db.  It only keeps track of the
+// positions the sequencer reads back, the entries themselves are thrown away so the real datastream is never written
+type dryRunStream struct {
+	chainId            uint64
+	highestBlock       uint64
+	highestBatch       uint64
+	highestClosedBatch uint64
+	lastEntryBatchEnd  bool
+}
+
+// newDryRunStream starts as the datastream would be once unwound to the start of the batch after lastBatch
+func newDryRunStream(chainId, lastBatch, lastBlock uint64) *dryRunStream {
+	return &dryRunStream{
+		chainId:            chainId,
+		highestBlock:       lastBlock,
+		highestBatch:       lastBatch,
+		highestClosedBatch: lastBatch,
+		lastEntryBatchEnd:  true,
+	}
+}
+
+var _ server.DataStreamServer = (*dryRunStream)(nil)
+
+func (d *dryRunStream) GetStreamServer() server.StreamServer {
+	return nil
+}
+
+func (d *dryRunStream) GetChainId() uint64 {
+	return d.chainId
+}
+
+func (d *dryRunStream) IsLastEntryBatchEnd() (bool, error) {
+	return d.lastEntryBatchEnd, nil
+}
+
+func (d *dryRunStream) GetHighestBlockNumber() (uint64, error) {
+	return d.highestBlock, nil
+}
+
+func (d *dryRunStream) GetHighestBatchNumber() (uint64, error) {
+	return d.highestBatch, nil
+}
+
+func (d *dryRunStream) GetHighestClosedBatch() (uint64, error) {
+	return d.highestClosedBatch, nil
+}
+
+func (d *dryRunStream) GetHighestClosedBatchNoCache() (uint64, error) {
+	return d.highestClosedBatch, nil
+}
+
+func (d *dryRunStream) UnwindToBlock(uint64) error {
+	return errDryRunStream
+}
+
+func (d *dryRunStream) UnwindToBatchStart(uint64) error {
+	return errDryRunStream
+}
+
+func (d *dryRunStream) ReadBatches(uint64, uint64) ([][]*dsTypes.FullL2Block, error) {
+	return nil, errDryRunStream
+}
+
+func (d *dryRunStream) ReadBatchesWithConcurrency(uint64, uint64) ([][]*dsTypes.FullL2Block, error) {
+	return nil, errDryRunStream
+}
+
+func (d *dryRunStream) WriteWholeBatchToStream(string, kv.Tx, server.DbReader, uint64, uint64) error {
+	return errDryRunStream
+}
+
+func (d *dryRunStream) WriteBlocksToStreamConsecutively(context.Context, string, kv.Tx, server.DbReader, uint64, uint64) error {
+	return errDryRunStream
+}
+
+func (d *dryRunStream) WriteBlockWithBatchStartToStream(_ string, _ kv.Tx, _ server.DbReader, _, batchNum, _ uint64, _, block eritypes.Block) error {
+	d.highestBlock = block.NumberU64()
+	d.highestBatch = batchNum
+	d.lastEntryBatchEnd = false
+	return nil
+}
+
+func (d *dryRunStream) UnwindIfNecessary(string, server.DbReader, uint64, uint64, uint64) error {
+	return errDryRunStream
+}
+
+func (d *dryRunStream) WriteBatchEnd(_ server.DbReader, batchNumber uint64, _ *common.Hash, _ *common.Hash) error {
+	d.highestClosedBatch = batchNumber
+	d.lastEntryBatchEnd = true
+	return nil
+}
+
+func (d *dryRunStream) WriteGenesisToStream(*eritypes.Block, *hermez_db.HermezDbReader, kv.Tx) error {
+	return errDryRunStream
+}
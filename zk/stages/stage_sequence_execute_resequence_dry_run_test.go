@@ -0,0 +1,25 @@
+package stages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResequenceScratchTables(t *testing.T) {
+	db := memdb.NewTestDB(t)
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.PlainState, []byte{1}, []byte{1})
+	}))
+
+	tables, size, err := resequenceScratchTables(context.Background(), db, db.AllTables())
+	require.NoError(t, err)
+	require.NotZero(t, size)
+	require.Contains(t, tables, kv.PlainState)
+	require.Contains(t, tables, kv.Headers)
+	require.NotContains(t, tables, kv.AccountChangeSet)
+	require.NotContains(t, tables, kv.TxLookup)
+}
@@ -0,0 +1,198 @@
+package stages
+
+import (
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/vm"
+	dsTypes "github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
+)
+
+// resequenceReport compares the batches read from the datastream with the batches a resequencing dry run built
+// from them
+type resequenceReport struct {
+	FromBatch           uint64                 `json:"fromBatch"`
+	ToBatch             uint64                 `json:"toBatch"`
+	SplitBatches        int                    `json:"splitBatches"`
+	StateRootMismatches int                    `json:"stateRootMismatches"`
+	DroppedTransactions int                    `json:"droppedTransactions"`
+	Batches             []*resequenceBatchDiff `json:"batches"`
+}
+
+type resequenceBatchDiff struct {
+	Original    *resequenceBatch   `json:"original"`
+	Resequenced []*resequenceBatch `json:"resequenced"`
+	// the transactions that ended up in a block with another number than the one they were in
+	MovedTransactions   []resequenceMovedTx `json:"movedTransactions,omitempty"`
+	DroppedTransactions []common.Hash       `json:"droppedTransactions,omitempty"`
+	// the resequenced blocks whose state root differs from the original block with the same number
+	MismatchedBlocks []uint64 `json:"mismatchedBlocks,omitempty"`
+	StateRootMatches bool     `json:"stateRootMatches"`
+}
+
+type resequenceBatch struct {
+	Number    uint64             `json:"number"`
+	StateRoot common.Hash        `json:"stateRoot"`
+	Blocks    []*resequenceBlock `json:"blocks"`
+	// the counters of the original batch are only known while the node still has its blocks
+	Counters map[string]int `json:"counters,omitempty"`
+}
+
+type resequenceBlock struct {
+	Number       uint64        `json:"number"`
+	StateRoot    common.Hash   `json:"stateRoot"`
+	Transactions []common.Hash `json:"transactions"`
+}
+
+type resequenceMovedTx struct {
+	Hash             common.Hash `json:"hash"`
+	OriginalBlock    uint64      `json:"originalBlock"`
+	ResequencedBlock uint64      `json:"resequencedBlock"`
+}
+
+// buildResequenceReport compares every original batch with the blocks its resequencing produced in the scratch
+// db, resequencedTo[i] being the last block built for original[i].  The counters of the original batches come from
+// the node's own db as the datastream doesn't carry them
+func buildResequenceReport(original [][]*dsTypes.FullL2Block, resequencedTo []uint64, firstBlock uint64, nodeDb *hermez_db.HermezDbReader, scratchTx kv.Tx) (*resequenceReport, error) {
+	if len(original) != len(resequencedTo) {
+		return nil, fmt.Errorf("%d original batches but %d resequenced block ranges", len(original), len(resequencedTo))
+	}
+
+	report := &resequenceReport{}
+	scratchDb := hermez_db.NewHermezDbReader(scratchTx)
+	from := firstBlock
+	for i, blocks := range original {
+		if len(blocks) == 0 {
+			continue
+		}
+
+		diff, err := diffResequencedBatch(blocks, from, resequencedTo[i], nodeDb, scratchDb, scratchTx)
+		if err != nil {
+			return nil, err
+		}
+		from = resequencedTo[i] + 1
+
+		if report.FromBatch == 0 {
+			report.FromBatch = diff.Original.Number
+		}
+		report.ToBatch = diff.Original.Number
+		if len(diff.Resequenced) > 1 {
+			report.SplitBatches++
+		}
+		if !diff.StateRootMatches {
+			report.StateRootMismatches++
+		}
+		report.DroppedTransactions += len(diff.DroppedTransactions)
+		report.Batches = append(report.Batches, diff)
+	}
+
+	return report, nil
+}
+
+func diffResequencedBatch(blocks []*dsTypes.FullL2Block, from, to uint64, nodeDb, scratchDb *hermez_db.HermezDbReader, scratchTx kv.Tx) (*resequenceBatchDiff, error) {
+	diff := &resequenceBatchDiff{
+		Original: &resequenceBatch{Number: blocks[0].BatchNumber},
+	}
+
+	originalBlocks := make(map[uint64]*resequenceBlock, len(blocks))
+	originalTxBlocks := make(map[common.Hash]uint64)
+	var originalTxs []common.Hash
+	for _, l2Block := range blocks {
+		block := &resequenceBlock{Number: l2Block.L2BlockNumber, StateRoot: l2Block.StateRoot, Transactions: []common.Hash{}}
+		for _, l2Tx := range l2Block.L2Txs {
+			tx, _, err := zktx.DecodeTx(l2Tx.Encoded, l2Tx.EffectiveGasPricePercentage, l2Block.ForkId)
+			if err != nil {
+				return nil, fmt.Errorf("decode tx of block %d: %w", l2Block.L2BlockNumber, err)
+			}
+			block.Transactions = append(block.Transactions, tx.Hash())
+			originalTxBlocks[tx.Hash()] = l2Block.L2BlockNumber
+			originalTxs = append(originalTxs, tx.Hash())
+		}
+		originalBlocks[block.Number] = block
+		diff.Original.Blocks = append(diff.Original.Blocks, block)
+	}
+	diff.Original.StateRoot = blocks[len(blocks)-1].StateRoot
+
+	counters, err := resequenceBatchCounters(nodeDb, diff.Original.Number)
+	if err != nil {
+		return nil, err
+	}
+	diff.Original.Counters = counters
+
+	resequencedTxs := make(map[common.Hash]struct{})
+	var batch *resequenceBatch
+	for blockNo := from; blockNo <= to; blockNo++ {
+		batchNo, err := scratchDb.GetBatchNoByL2Block(blockNo)
+		if err != nil {
+			return nil, err
+		}
+		if batch == nil || batch.Number != batchNo {
+			batch = &resequenceBatch{Number: batchNo}
+			diff.Resequenced = append(diff.Resequenced, batch)
+		}
+
+		resequenced, err := rawdb.ReadBlockByNumber(scratchTx, blockNo)
+		if err != nil {
+			return nil, err
+		}
+		if resequenced == nil {
+			return nil, fmt.Errorf("resequenced block %d is missing", blockNo)
+		}
+		block := &resequenceBlock{Number: blockNo, StateRoot: resequenced.Root(), Transactions: []common.Hash{}}
+		for _, tx := range resequenced.Transactions() {
+			block.Transactions = append(block.Transactions, tx.Hash())
+			resequencedTxs[tx.Hash()] = struct{}{}
+			if originalBlock, ok := originalTxBlocks[tx.Hash()]; ok && originalBlock != blockNo {
+				diff.MovedTransactions = append(diff.MovedTransactions, resequenceMovedTx{Hash: tx.Hash(), OriginalBlock: originalBlock, ResequencedBlock: blockNo})
+			}
+		}
+		batch.Blocks = append(batch.Blocks, block)
+		batch.StateRoot = block.StateRoot
+
+		if originalBlock, ok := originalBlocks[blockNo]; ok && originalBlock.StateRoot != block.StateRoot {
+			diff.MismatchedBlocks = append(diff.MismatchedBlocks, blockNo)
+		}
+	}
+
+	for _, resequencedBatch := range diff.Resequenced {
+		if resequencedBatch.Counters, err = resequenceBatchCounters(scratchDb, resequencedBatch.Number); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, hash := range originalTxs {
+		if _, ok := resequencedTxs[hash]; !ok {
+			diff.DroppedTransactions = append(diff.DroppedTransactions, hash)
+		}
+	}
+
+	diff.StateRootMatches = batch != nil && batch.StateRoot == diff.Original.StateRoot
+	return diff, nil
+}
+
+// resequenceBatchCounters returns nil if the db has no blocks or counters for the batch
+func resequenceBatchCounters(hermezDb *hermez_db.HermezDbReader, batchNo uint64) (map[string]int, error) {
+	blocks, err := hermezDb.GetL2BlockNosByBatch(batchNo)
+	if err != nil || len(blocks) == 0 {
+		return nil, err
+	}
+	counters, found, err := hermezDb.GetLatestBatchCounters(batchNo)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	named := make(map[string]int, len(counters))
+	for i, used := range counters {
+		if i < len(vm.CounterKeyNames) {
+			named[string(vm.CounterKeyNames[i])] = used
+		} else {
+			named[fmt.Sprintf("%d", i)] = used
+		}
+	}
+	return named, nil
+}
@@ -0,0 +1,107 @@
+package stages
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	dsTypes "github.com/ledgerwatch/erigon/zk/datastream/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+)
+
+func TestBuildResequenceReport(t *testing.T) {
+	_, nodeTx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(nodeTx))
+	_, scratchTx := memdb.NewTestTx(t)
+	require.NoError(t, hermez_db.CreateHermezBuckets(scratchTx))
+
+	txs := make([]types.Transaction, 4)
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+	}
+
+	// original batch 2 holds blocks 11 and 12 with two txs each
+	original := [][]*dsTypes.FullL2Block{{
+		reportTestL2Block(t, 2, 11, common.Hash{11}, txs[0], txs[1]),
+		reportTestL2Block(t, 2, 12, common.Hash{12}, txs[2], txs[3]),
+	}}
+
+	nodeDb := hermez_db.NewHermezDb(nodeTx)
+	require.NoError(t, nodeDb.WriteBlockBatch(11, 2))
+	require.NoError(t, nodeDb.WriteBlockBatch(12, 2))
+	require.NoError(t, nodeDb.WriteBatchCounters(12, []int{10, 20}))
+
+	// resequenced into batch 2 with block 11 and a tx pushed out to block 12, batch 3 takes block 13 and txs[3] is
+	// dropped
+	scratchDb := hermez_db.NewHermezDb(scratchTx)
+	reportTestWriteBlock(t, scratchTx, 11, common.Hash{11}, txs[0])
+	reportTestWriteBlock(t, scratchTx, 12, common.Hash{12}, txs[1])
+	reportTestWriteBlock(t, scratchTx, 13, common.Hash{13}, txs[2])
+	require.NoError(t, scratchDb.WriteBlockBatch(11, 2))
+	require.NoError(t, scratchDb.WriteBlockBatch(12, 2))
+	require.NoError(t, scratchDb.WriteBlockBatch(13, 3))
+	require.NoError(t, scratchDb.WriteBatchCounters(12, []int{5}))
+	require.NoError(t, scratchDb.WriteBatchCounters(13, []int{7}))
+
+	report, err := buildResequenceReport(original, []uint64{13}, 11, hermez_db.NewHermezDbReader(nodeTx), scratchTx)
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(2), report.FromBatch)
+	require.Equal(t, uint64(2), report.ToBatch)
+	require.Equal(t, 1, report.SplitBatches)
+	require.Equal(t, 1, report.StateRootMismatches)
+	require.Equal(t, 1, report.DroppedTransactions)
+	require.Len(t, report.Batches, 1)
+
+	diff := report.Batches[0]
+	require.Equal(t, common.Hash{12}, diff.Original.StateRoot)
+	require.Len(t, diff.Original.Counters, 2)
+	require.Len(t, diff.Resequenced, 2)
+	require.Equal(t, uint64(2), diff.Resequenced[0].Number)
+	require.Len(t, diff.Resequenced[0].Blocks, 2)
+	require.Len(t, diff.Resequenced[0].Counters, 1)
+	require.Equal(t, uint64(3), diff.Resequenced[1].Number)
+	require.Equal(t, common.Hash{13}, diff.Resequenced[1].StateRoot)
+	require.Equal(t, []resequenceMovedTx{
+		{Hash: txs[1].Hash(), OriginalBlock: 11, ResequencedBlock: 12},
+		{Hash: txs[2].Hash(), OriginalBlock: 12, ResequencedBlock: 13},
+	}, diff.MovedTransactions)
+	require.Equal(t, []common.Hash{txs[3].Hash()}, diff.DroppedTransactions)
+	require.Empty(t, diff.MismatchedBlocks)
+	require.False(t, diff.StateRootMatches)
+
+	_, err = buildResequenceReport(original, nil, 11, hermez_db.NewHermezDbReader(nodeTx), scratchTx)
+	require.Error(t, err)
+}
+
+func reportTestL2Block(t *testing.T, batchNo, blockNo uint64, stateRoot common.Hash, txs ...types.Transaction) *dsTypes.FullL2Block {
+	l2Block := &dsTypes.FullL2Block{
+		BatchNumber:   batchNo,
+		L2BlockNumber: blockNo,
+		StateRoot:     stateRoot,
+		ForkId:        12,
+	}
+	for _, tx := range txs {
+		var buf bytes.Buffer
+		require.NoError(t, tx.EncodeRLP(&buf))
+		l2Block.L2Txs = append(l2Block.L2Txs, dsTypes.L2TransactionProto{
+			EffectiveGasPricePercentage: 255,
+			Encoded:                     buf.Bytes(),
+		})
+	}
+	return l2Block
+}
+
+func reportTestWriteBlock(t *testing.T, tx kv.RwTx, blockNo uint64, stateRoot common.Hash, txs ...types.Transaction) {
+	block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(blockNo), Root: stateRoot}).WithBody(txs, nil)
+	require.NoError(t, rawdb.WriteBlock(tx, block))
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, block.Hash(), blockNo))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/big"
 
 	mapset "github.com/deckarep/golang-set/v2"
 
@@ -72,7 +73,11 @@ func newBatchState(forkId, batchNumber, blockNumber uint64, hasExecutorForThisBa
 			batchState.batchL1RecoveryData = newBatchL1RecoveryData(batchState)
 		}
 
-		limboBlock, limboTxHash := txPool.GetLimboDetailsForRecovery(blockNumber)
+		var limboBlock *txpool.LimboBlockDetails
+		var limboTxHash *common.Hash
+		if txPool != nil {
+			limboBlock, limboTxHash = txPool.GetLimboDetailsForRecovery(blockNumber)
+		}
 		if limboTxHash != nil {
 			// batchNumber == limboBlock.BatchNumber then we've unwound to the very beginning of the batch. 'limboBlock.BlockNumber' is the 1st block of 'batchNumber' batch. Everything is fine.
 
@@ -258,6 +263,8 @@ type BlockState struct {
 	builtBlockElements       BuiltBlockElements
 	blockL1RecoveryData      *zktx.DecodedBatchL2Data
 	transactionsToDiscard    []common.Hash
+	// l1GasPrice is the l1 gas price when the block was started, the one its transactions are priced against
+	l1GasPrice *big.Int
 }
 
 func newBlockState() *BlockState {
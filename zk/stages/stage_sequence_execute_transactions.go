@@ -20,7 +20,7 @@ import (
 	"github.com/ledgerwatch/log/v3"
 )
 
-func getNextPoolTransactions(ctx context.Context, cfg SequenceBlockCfg, executionAt, forkId uint64, alreadyYielded mapset.Set[[32]byte]) ([]types.Transaction, []common.Hash, bool, error) {
+func getNextPoolTransactions(ctx context.Context, cfg SequenceBlockCfg, executionAt, forkId, batchNo uint64, alreadyYielded mapset.Set[[32]byte]) ([]types.Transaction, []common.Hash, bool, error) {
 	var ids []common.Hash
 	var transactions []types.Transaction
 	var allConditionsOk bool
@@ -36,7 +36,7 @@ func getNextPoolTransactions(ctx context.Context, cfg SequenceBlockCfg, executio
 
 	if err := cfg.txPoolDb.View(ctx, func(poolTx kv.Tx) error {
 		slots := types2.TxsRlp{}
-		if allConditionsOk, _, err = cfg.txPool.YieldBest(cfg.yieldSize, &slots, poolTx, executionAt, gasLimit, 0, alreadyYielded); err != nil {
+		if allConditionsOk, _, err = cfg.txPool.YieldBestForBatch(batchNo, cfg.yieldSize, &slots, poolTx, executionAt, gasLimit, 0, alreadyYielded); err != nil {
 			return err
 		}
 		yieldedTxs, yieldedIds, toRemove, err := extractTransactionsFromSlot(&slots, executionAt, cfg)
@@ -210,6 +210,9 @@ func attemptAddTransaction(
 	if err = txCounters.ProcessTx(ibs, execResult.ReturnData); err != nil {
 		return nil, nil, txCounters, overflowNone, err
 	}
+	if cfg.txPool != nil {
+		cfg.txPool.UpdateTxCounterUsage(transaction.Hash(), txCounters.UsageRatio())
+	}
 
 	batchCounters.UpdateExecutionAndProcessingCountersCache(txCounters)
 	// now that we have executed we can check again for an overflow
@@ -138,6 +138,10 @@ func UnwindSequenceExecutionStageDbWrites(ctx context.Context, u *stagedsync.Unw
 	if err := hermezDb.DeleteBatchCounters(u.UnwindPoint+1, s.BlockNumber); err != nil {
 		return fmt.Errorf("truncate block batches error: %v", err)
 	}
+	// only seq
+	if err := hermezDb.DeleteBlockL1GasPrices(u.UnwindPoint+1, s.BlockNumber); err != nil {
+		return fmt.Errorf("truncate block l1 gas prices error: %v", err)
+	}
 
 	return nil
 }
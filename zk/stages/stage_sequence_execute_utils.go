@@ -3,6 +3,7 @@ package stages
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/c2h5oh/datasize"
@@ -32,6 +33,7 @@ import (
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
 	"github.com/ledgerwatch/erigon/zk/l1infotree"
 	verifier "github.com/ledgerwatch/erigon/zk/legacy_executor_verifier"
+	"github.com/ledgerwatch/erigon/zk/sequencer"
 	zktx "github.com/ledgerwatch/erigon/zk/tx"
 	"github.com/ledgerwatch/erigon/zk/txpool"
 	zktypes "github.com/ledgerwatch/erigon/zk/types"
@@ -59,6 +61,12 @@ type DoneHook interface {
 	AfterRun(tx kv.Tx, finishProgressBefore uint64, prevUnwindPoint *uint64) error
 }
 
+// L1GasPriceProvider gives the latest l1 gas price seen by the node, it is recorded against every block the sequencer
+// builds so the fee of a transaction can be explained later.  nil is returned while no price is known yet.
+type L1GasPriceProvider interface {
+	GetLatestL1Price() *big.Int
+}
+
 type SequenceBlockCfg struct {
 	db            kv.RwDB
 	batchSize     datasize.ByteSize
@@ -81,16 +89,25 @@ type SequenceBlockCfg struct {
 	zk               *ethconfig.Zk
 	miningConfig     *params.MiningConfig
 
+	// txPool is nil on a resequencing dry run, which must leave the node's pool untouched
 	txPool   *txpool.TxPool
 	txPoolDb kv.RwDB
 
 	legacyVerifier *verifier.LegacyExecutorVerifier
 	yieldSize      uint16
 
-	infoTreeUpdater *l1infotree.Updater
+	infoTreeUpdater    *l1infotree.Updater
+	l1GasPriceProvider L1GasPriceProvider
 
 	decodedTxCache *expirable.LRU[common.Hash, *types.Transaction]
-	doneHook       DoneHook
+
+	// failover fences block production when the sequencer shares a lease with standbys, nil otherwise
+	failover *sequencer.Failover
+
+	// events receives the batchClosed events, nil when the node serves no rpc subscriptions
+	events *shards.Events
+
+	doneHook DoneHook
 }
 
 func StageSequenceBlocksCfg(
@@ -120,38 +137,44 @@ func StageSequenceBlocksCfg(
 	legacyVerifier *verifier.LegacyExecutorVerifier,
 	yieldSize uint16,
 	infoTreeUpdater *l1infotree.Updater,
+	l1GasPriceProvider L1GasPriceProvider,
+	failover *sequencer.Failover,
+	events *shards.Events,
 	doneHook DoneHook,
 ) SequenceBlockCfg {
 
 	decodedTxCache := expirable.NewLRU[common.Hash, *types.Transaction](zk.SequencerDecodedTxCacheSize, nil, zk.SequencerDecodedTxCacheTTL)
 
 	return SequenceBlockCfg{
-		db:               db,
-		prune:            pm,
-		batchSize:        batchSize,
-		changeSetHook:    changeSetHook,
-		chainConfig:      chainConfig,
-		engine:           engine,
-		zkVmConfig:       vmConfig,
-		dirs:             dirs,
-		accumulator:      accumulator,
-		stateStream:      stateStream,
-		badBlockHalt:     badBlockHalt,
-		blockReader:      blockReader,
-		genesis:          genesis,
-		historyV3:        historyV3,
-		syncCfg:          syncCfg,
-		agg:              agg,
-		dataStreamServer: dataStreamServer,
-		zk:               zk,
-		miningConfig:     miningConfig,
-		txPool:           txPool,
-		txPoolDb:         txPoolDb,
-		legacyVerifier:   legacyVerifier,
-		yieldSize:        yieldSize,
-		infoTreeUpdater:  infoTreeUpdater,
-		decodedTxCache:   decodedTxCache,
-		doneHook:         doneHook,
+		db:                 db,
+		prune:              pm,
+		batchSize:          batchSize,
+		changeSetHook:      changeSetHook,
+		chainConfig:        chainConfig,
+		engine:             engine,
+		zkVmConfig:         vmConfig,
+		dirs:               dirs,
+		accumulator:        accumulator,
+		stateStream:        stateStream,
+		badBlockHalt:       badBlockHalt,
+		blockReader:        blockReader,
+		genesis:            genesis,
+		historyV3:          historyV3,
+		syncCfg:            syncCfg,
+		agg:                agg,
+		dataStreamServer:   dataStreamServer,
+		zk:                 zk,
+		miningConfig:       miningConfig,
+		txPool:             txPool,
+		txPoolDb:           txPoolDb,
+		legacyVerifier:     legacyVerifier,
+		yieldSize:          yieldSize,
+		infoTreeUpdater:    infoTreeUpdater,
+		l1GasPriceProvider: l1GasPriceProvider,
+		decodedTxCache:     decodedTxCache,
+		failover:           failover,
+		events:             events,
+		doneHook:           doneHook,
 	}
 }
 
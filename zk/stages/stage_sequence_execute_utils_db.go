@@ -19,9 +19,11 @@ type stageDb struct {
 	eridb       *db2.EriDb
 	stateReader *state.PlainStateReader
 	smt         *smtNs.SMT
+
+	keepReplacedSmtNodes bool
 }
 
-func newStageDb(ctx context.Context, db kv.RwDB) (sdb *stageDb, err error) {
+func newStageDb(ctx context.Context, db kv.RwDB, keepReplacedSmtNodes bool) (sdb *stageDb, err error) {
 	var tx kv.RwTx
 	if tx, err = db.BeginRw(ctx); err != nil {
 		return nil, err
@@ -30,6 +32,8 @@ func newStageDb(ctx context.Context, db kv.RwDB) (sdb *stageDb, err error) {
 	sdb = &stageDb{
 		ctx: ctx,
 		db:  db,
+
+		keepReplacedSmtNodes: keepReplacedSmtNodes,
 	}
 	sdb.SetTx(tx)
 	return sdb, nil
@@ -41,6 +45,7 @@ func (sdb *stageDb) SetTx(tx kv.RwTx) {
 	sdb.eridb = db2.NewEriDb(tx)
 	sdb.stateReader = state.NewPlainStateReader(tx)
 	sdb.smt = smtNs.NewSMT(sdb.eridb, false)
+	sdb.smt.SetKeepReplacedNodes(sdb.keepReplacedSmtNodes)
 }
 
 func (sdb *stageDb) CommitAndStart() (err error) {
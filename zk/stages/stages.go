@@ -30,6 +30,7 @@ func SequencerZkStages(
 	logIndex stages.LogIndexCfg,
 	callTraces stages.CallTracesCfg,
 	txLookup stages.TxLookupCfg,
+	bridgeIndexerCfg BridgeIndexerCfg,
 	finish stages.FinishCfg,
 	test bool,
 ) []*stages.Stage {
@@ -205,6 +206,19 @@ func SequencerZkStages(
 				return stages.PruneTxLookup(p, tx, txLookup, ctx, firstCycle, logger)
 			},
 		},
+		{
+			ID:          stages2.BridgeIndexer,
+			Description: "Index the deposits and claims of the L2 bridge",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *stages.StageState, u stages.Unwinder, txc wrap.TxContainer, logger log.Logger) error {
+				return SpawnStageBridgeIndexer(s, ctx, txc.Tx, bridgeIndexerCfg)
+			},
+			Unwind: func(firstCycle bool, u *stages.UnwindState, s *stages.StageState, txc wrap.TxContainer, logger log.Logger) error {
+				return UnwindBridgeIndexerStage(u, txc.Tx, bridgeIndexerCfg, ctx)
+			},
+			Prune: func(firstCycle bool, p *stages.PruneState, tx kv.RwTx, logger log.Logger) error {
+				return PruneBridgeIndexerStage(p, tx, bridgeIndexerCfg, ctx)
+			},
+		},
 		{
 			ID:          stages2.Finish,
 			Description: "Final: update current block for the RPC API",
@@ -237,6 +251,7 @@ func DefaultZkStages(
 	logIndex stages.LogIndexCfg,
 	callTraces stages.CallTracesCfg,
 	txLookup stages.TxLookupCfg,
+	bridgeIndexerCfg BridgeIndexerCfg,
 	finish stages.FinishCfg,
 	test bool,
 ) []*stages.Stage {
@@ -351,8 +366,7 @@ func DefaultZkStages(
 				return UnwindZkIntermediateHashesStage(u, s, txc.Tx, zkInterHashesCfg, ctx, false)
 			},
 			Prune: func(firstCycle bool, p *stages.PruneState, tx kv.RwTx, logger log.Logger) error {
-				// TODO: implement this in zk interhashes
-				return nil
+				return PruneZkIntermediateHashesStage(p, tx, zkInterHashesCfg, ctx)
 			},
 		},
 		{
@@ -453,6 +467,19 @@ func DefaultZkStages(
 				return PruneWitnessStage(p, tx, stageWitnessCfg, ctx)
 			},
 		},
+		{
+			ID:          stages2.BridgeIndexer,
+			Description: "Index the deposits and claims of the L2 bridge",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *stages.StageState, u stages.Unwinder, txc wrap.TxContainer, logger log.Logger) error {
+				return SpawnStageBridgeIndexer(s, ctx, txc.Tx, bridgeIndexerCfg)
+			},
+			Unwind: func(firstCycle bool, u *stages.UnwindState, s *stages.StageState, txc wrap.TxContainer, logger log.Logger) error {
+				return UnwindBridgeIndexerStage(u, txc.Tx, bridgeIndexerCfg, ctx)
+			},
+			Prune: func(firstCycle bool, p *stages.PruneState, tx kv.RwTx, logger log.Logger) error {
+				return PruneBridgeIndexerStage(p, tx, bridgeIndexerCfg, ctx)
+			},
+		},
 		{
 			ID:          stages2.Finish,
 			Description: "Final: update current block for the RPC API",
@@ -480,10 +507,12 @@ var AllStagesZk = []stages2.SyncStage{
 	stages2.LogIndex,
 	stages2.CallTraces,
 	stages2.TxLookup,
+	stages2.BridgeIndexer,
 	stages2.Finish,
 }
 
 var ZkSequencerUnwindOrder = stages.UnwindOrder{
+	stages2.BridgeIndexer,
 	stages2.TxLookup,
 	stages2.LogIndex,
 	stages2.HashState,
@@ -498,6 +527,7 @@ var ZkSequencerUnwindOrder = stages.UnwindOrder{
 }
 
 var ZkUnwindOrder = stages.UnwindOrder{
+	stages2.BridgeIndexer,
 	stages2.TxLookup,
 	stages2.LogIndex,
 	stages2.HashState,
@@ -512,3 +542,9 @@ var ZkUnwindOrder = stages.UnwindOrder{
 	stages2.L1Syncer,
 	stages2.Finish,
 }
+
+// ZkPruneOrder only prunes the SMT, the prune functions of the other zk stages are not meant to run every stage loop
+// (PruneBatchesStage truncates all blocks)
+var ZkPruneOrder = stages.PruneOrder{
+	stages2.IntermediateHashes,
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
 	db2 "github.com/ledgerwatch/erigon/smt/pkg/db"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
 	jsonClient "github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
 	jsonTypes "github.com/ledgerwatch/erigon/zkevm/jsonrpc/types"
 )
@@ -138,27 +139,16 @@ func RpcGetHighestTxNo(rpcEndpoint string) (uint64, error) {
 }
 
 func DeriveEffectiveGasPrice(cfg SequenceBlockCfg, tx types.Transaction) uint8 {
-	if tx.GetTo() == nil {
+	switch zktx.CategorizeTx(tx) {
+	case zktx.TxCategoryContractDeployment:
 		return cfg.zk.EffectiveGasPriceForContractDeployment
-	}
-
-	data := tx.GetData()
-	dataLen := len(data)
-	if dataLen != 0 {
-		if dataLen >= 8 {
-			// transfer's method id 0xa9059cbb
-			isTransfer := data[0] == 169 && data[1] == 5 && data[2] == 156 && data[3] == 187
-			// transfer's method id 0x23b872dd
-			isTransferFrom := data[0] == 35 && data[1] == 184 && data[2] == 114 && data[3] == 221
-			if isTransfer || isTransferFrom {
-				return cfg.zk.EffectiveGasPriceForErc20Transfer
-			}
-		}
-
+	case zktx.TxCategoryErc20Transfer:
+		return cfg.zk.EffectiveGasPriceForErc20Transfer
+	case zktx.TxCategoryContractInvocation:
 		return cfg.zk.EffectiveGasPriceForContractInvocation
+	default:
+		return cfg.zk.EffectiveGasPriceForEthTransfer
 	}
-
-	return cfg.zk.EffectiveGasPriceForEthTransfer
 }
 
 func GetSequencerHighestDataStreamBlock(endpoint string) (uint64, error) {
@@ -0,0 +1,484 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	ethTypes "github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+	"github.com/ledgerwatch/erigon/zk/utils"
+)
+
+// AccInputHashSource gives the verifier the sequence transactions, the timestamps of the L1 blocks they were mined in
+// and the acc input hashes the rollup contract reports
+type AccInputHashSource interface {
+	GetTransaction(hash common.Hash) (ethTypes.Transaction, bool, error)
+	GetL1BlockTimestamp(ctx context.Context, l1BlockNo uint64) (uint64, error)
+	GetAccInputHash(ctx context.Context, forkId, batchNo uint64) (common.Hash, error)
+}
+
+type rollupAccInputHashSource struct {
+	l1Syncer      *L1Syncer
+	rollupAddress common.Address
+	rollupId      uint64
+}
+
+// NewRollupAccInputHashSource reads the acc input hashes from the rollup contract the same way zkevm_getBatchByNumber does
+func NewRollupAccInputHashSource(l1Syncer *L1Syncer, rollupAddress common.Address, rollupId uint64) AccInputHashSource {
+	return &rollupAccInputHashSource{
+		l1Syncer:      l1Syncer,
+		rollupAddress: rollupAddress,
+		rollupId:      rollupId,
+	}
+}
+
+func (s *rollupAccInputHashSource) GetTransaction(hash common.Hash) (ethTypes.Transaction, bool, error) {
+	return s.l1Syncer.GetTransaction(hash)
+}
+
+func (s *rollupAccInputHashSource) GetL1BlockTimestamp(ctx context.Context, l1BlockNo uint64) (uint64, error) {
+	header, err := s.l1Syncer.GetHeader(l1BlockNo)
+	if err != nil {
+		return 0, err
+	}
+	return header.Time, nil
+}
+
+func (s *rollupAccInputHashSource) GetAccInputHash(ctx context.Context, forkId, batchNo uint64) (common.Hash, error) {
+	if forkId < uint64(chain.ForkID8Elderberry) {
+		return s.l1Syncer.GetPreElderberryAccInputHash(ctx, &s.rollupAddress, batchNo)
+	}
+	return s.l1Syncer.GetElderberryAccInputHash(ctx, &s.rollupAddress, s.rollupId, batchNo)
+}
+
+// AccInputHashInputs are the values a batch contributes to the acc input hash. Before etrog the L1 info root is the
+// global exit root of the batch and the timestamp the batch timestamp, from etrog on the timestamp is the limit
+// timestamp of the sequence. Forced batches hash their forced global exit root and forced timestamp instead.
+type AccInputHashInputs struct {
+	BatchDataHash     common.Hash    `json:"batchDataHash"`
+	L1InfoRoot        common.Hash    `json:"l1InfoRoot"`
+	Timestamp         uint64         `json:"timestamp"`
+	Coinbase          common.Address `json:"coinbase"`
+	ForcedBlockHashL1 common.Hash    `json:"forcedBlockHashL1"`
+}
+
+func (in AccInputHashInputs) accInputHash(preEtrog bool, oldAccInputHash common.Hash) common.Hash {
+	if preEtrog {
+		return *utils.CalculatePreEtrogValidiumAccInputHash(oldAccInputHash, in.BatchDataHash, in.L1InfoRoot, in.Timestamp, in.Coinbase)
+	}
+	return *utils.CalculateEtrogValidiumAccInputHash(oldAccInputHash, in.BatchDataHash, in.L1InfoRoot, in.Timestamp, in.Coinbase, in.ForcedBlockHashL1)
+}
+
+func (in AccInputHashInputs) diff(other AccInputHashInputs) []string {
+	var fields []string
+	if in.BatchDataHash != other.BatchDataHash {
+		fields = append(fields, "batchDataHash")
+	}
+	if in.L1InfoRoot != other.L1InfoRoot {
+		fields = append(fields, "l1InfoRoot")
+	}
+	if in.Timestamp != other.Timestamp {
+		fields = append(fields, "timestamp")
+	}
+	if in.Coinbase != other.Coinbase {
+		fields = append(fields, "coinbase")
+	}
+	if in.ForcedBlockHashL1 != other.ForcedBlockHashL1 {
+		fields = append(fields, "forcedBlockHashL1")
+	}
+	return fields
+}
+
+// AccInputHashDivergence describes the first batch whose acc input hash could not be reproduced
+type AccInputHashDivergence struct {
+	Batch                uint64             `json:"batch"`
+	ForkId               uint64             `json:"forkId"`
+	SequenceL1TxHash     common.Hash        `json:"sequenceL1TxHash"`
+	Reason               string             `json:"reason"`
+	OldAccInputHash      common.Hash        `json:"oldAccInputHash"`
+	Local                AccInputHashInputs `json:"local"`
+	L1                   AccInputHashInputs `json:"l1"`
+	LocalAccInputHash    common.Hash        `json:"localAccInputHash"`
+	L1AccInputHash       common.Hash        `json:"l1AccInputHash"`
+	ContractAccInputHash *common.Hash       `json:"contractAccInputHash,omitempty"`
+}
+
+type SkippedSequence struct {
+	Batch    uint64      `json:"batch"`
+	L1TxHash common.Hash `json:"l1TxHash"`
+	Reason   string      `json:"reason"`
+}
+
+type AccInputHashReport struct {
+	FromBatch  uint64                  `json:"fromBatch"`
+	ToBatch    uint64                  `json:"toBatch"`
+	Sequences  uint64                  `json:"sequences"`
+	Batches    uint64                  `json:"batches"`
+	Skipped    []SkippedSequence       `json:"skipped,omitempty"`
+	Incomplete string                  `json:"incomplete,omitempty"`
+	Divergence *AccInputHashDivergence `json:"divergence,omitempty"`
+}
+
+// VerifyAccInputHashes walks the sequences in the hermez db that end in or after fromBatch, up to the one covering
+// toBatch or the last one when toBatch is 0. For every batch it recomputes the acc input hash from the local blocks
+// and the local sequence record next to the one from the sequence calldata, and it checks the hash at the end of
+// every sequence against the rollup contract. It stops at the first divergence.
+func VerifyAccInputHashes(ctx context.Context, tx kv.Tx, source AccInputHashSource, fromBatch, toBatch uint64) (*AccInputHashReport, error) {
+	hermezDb := hermez_db.NewHermezDbReader(tx)
+	report := &AccInputHashReport{FromBatch: fromBatch}
+
+	sequences, err := hermezDb.GetSequencesFromBatch(fromBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(sequences) == 0 {
+		return report, nil
+	}
+
+	prevSequenceBatch := uint64(0)
+	if fromBatch > 0 {
+		prev, _, err := hermezDb.GetRangeSequencesByBatch(sequences[0].BatchNo)
+		if err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			prevSequenceBatch = prev.BatchNo
+		}
+	}
+	report.FromBatch = prevSequenceBatch + 1
+
+	// the old acc input hash of a sequence is the contract one at the end of the previous sequence, so a skipped
+	// sequence doesn't stop the walk
+	var oldAccInputHash *common.Hash
+	for _, sequence := range sequences {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if toBatch != 0 && prevSequenceBatch >= toBatch {
+			break
+		}
+		if sequence.BatchNo <= prevSequenceBatch {
+			log.Warn("Skipping sequence that does not move the batch number on", "batch", sequence.BatchNo, "l1Tx", sequence.L1TxHash)
+			continue
+		}
+
+		forkId, err := hermezDb.GetForkId(sequence.BatchNo)
+		if err != nil {
+			return nil, err
+		}
+		if oldAccInputHash == nil {
+			oldAccInputHash = &common.Hash{}
+			if prevSequenceBatch > 0 {
+				h, err := source.GetAccInputHash(ctx, forkId, prevSequenceBatch)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get the acc input hash of batch %d: %w", prevSequenceBatch, err)
+				}
+				oldAccInputHash = &h
+			}
+		}
+
+		result, err := verifySequence(ctx, tx, hermezDb, source, sequence, prevSequenceBatch, forkId, *oldAccInputHash)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case result.incomplete != "":
+			report.Incomplete = result.incomplete
+			return report, nil
+		case result.divergence != nil:
+			report.Divergence = result.divergence
+			return report, nil
+		case result.skipped != "":
+			report.Skipped = append(report.Skipped, SkippedSequence{Batch: sequence.BatchNo, L1TxHash: sequence.L1TxHash, Reason: result.skipped})
+			h, err := source.GetAccInputHash(ctx, forkId, sequence.BatchNo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the acc input hash of batch %d: %w", sequence.BatchNo, err)
+			}
+			oldAccInputHash = &h
+		default:
+			report.Sequences++
+			report.Batches += sequence.BatchNo - prevSequenceBatch
+			oldAccInputHash = &result.accInputHash
+		}
+
+		report.ToBatch = sequence.BatchNo
+		prevSequenceBatch = sequence.BatchNo
+		log.Debug("Verified sequence", "batch", sequence.BatchNo, "forkId", forkId)
+	}
+
+	return report, nil
+}
+
+// sequenceResult is the outcome of verifying one sequence, at most one of divergence, skipped and incomplete is set
+type sequenceResult struct {
+	divergence   *AccInputHashDivergence
+	skipped      string
+	incomplete   string
+	accInputHash common.Hash
+}
+
+// verifySequence checks the batches (prevSequenceBatch, sequence.BatchNo], the hash at the end of the sequence must
+// match the rollup contract
+func verifySequence(
+	ctx context.Context,
+	tx kv.Tx,
+	hermezDb *hermez_db.HermezDbReader,
+	source AccInputHashSource,
+	sequence *zktypes.L1BatchInfo,
+	prevSequenceBatch, forkId uint64,
+	oldAccInputHash common.Hash,
+) (result sequenceResult, err error) {
+	l1Transaction, _, err := source.GetTransaction(sequence.L1TxHash)
+	if err != nil {
+		return result, fmt.Errorf("failed to get transaction data for tx %s: %w", sequence.L1TxHash, err)
+	}
+	calldata := l1Transaction.GetData()
+	if len(calldata) < 4 {
+		result.skipped = fmt.Sprintf("calldata of tx %s is too short", sequence.L1TxHash)
+		return result, nil
+	}
+	decoded, err := DecodeSequenceBatchesCalldata(calldata)
+	if err != nil {
+		// the initial batch of a rollup is injected by the contract and not sequenced through calldata
+		result.skipped = fmt.Sprintf("tx %s is not a sequenceBatches call: %s", sequence.L1TxHash, err)
+		return result, nil
+	}
+	var l1BlockTime uint64
+	switch decoded.(type) {
+	case *SequenceBatchesCalldataEtrog, *SequenceBatchesCalldataValidiumEtrog:
+		// the etrog contract has no limit timestamp in the calldata and hashes the timestamp of the L1 block instead
+		if l1BlockTime, err = source.GetL1BlockTimestamp(ctx, sequence.L1BlockNo); err != nil {
+			return result, fmt.Errorf("failed to get the timestamp of L1 block %d: %w", sequence.L1BlockNo, err)
+		}
+	}
+	l1Inputs, preEtrog, err := sequenceCalldataInputs(decoded, sequence.L1InfoRoot, l1BlockTime)
+	if err != nil {
+		return result, err
+	}
+
+	localAccInputHash, l1AccInputHash := oldAccInputHash, oldAccInputHash
+	for batchNo := prevSequenceBatch + 1; batchNo <= sequence.BatchNo; batchNo++ {
+		index := int(batchNo - prevSequenceBatch - 1)
+		batchForkId, err := hermezDb.GetForkId(batchNo)
+		if err != nil {
+			return result, err
+		}
+		local, lastBlockTime, ok, err := localBatchInputs(tx, hermezDb, batchNo, batchForkId, sequence.L1InfoRoot, preEtrog)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			result.incomplete = fmt.Sprintf("batch %d has no blocks locally", batchNo)
+			return result, nil
+		}
+
+		newDivergence := func(reason string) *AccInputHashDivergence {
+			return &AccInputHashDivergence{
+				Batch:            batchNo,
+				ForkId:           batchForkId,
+				SequenceL1TxHash: sequence.L1TxHash,
+				Reason:           reason,
+				OldAccInputHash:  localAccInputHash,
+				Local:            local,
+			}
+		}
+		if index >= len(l1Inputs) {
+			result.divergence = newDivergence(fmt.Sprintf("the sequence calldata has %d batches but the sequence ends at batch %d", len(l1Inputs), sequence.BatchNo))
+			return result, nil
+		}
+		l1 := l1Inputs[index]
+		if !preEtrog {
+			// the limit timestamp and the forced values are parameters of the sequence, not of the batch data
+			local.L1InfoRoot, local.Timestamp, local.ForcedBlockHashL1 = l1.L1InfoRoot, l1.Timestamp, l1.ForcedBlockHashL1
+		}
+
+		d := newDivergence("")
+		d.L1 = l1
+		d.LocalAccInputHash = local.accInputHash(preEtrog, localAccInputHash)
+		d.L1AccInputHash = l1.accInputHash(preEtrog, l1AccInputHash)
+		localAccInputHash, l1AccInputHash = d.LocalAccInputHash, d.L1AccInputHash
+
+		switch {
+		case preEtrog != (batchForkId < uint64(chain.ForkID7Etrog)):
+			d.Reason = fmt.Sprintf("the local fork id %d does not match the format of the sequence calldata", batchForkId)
+			result.divergence = d
+			return result, nil
+		case !preEtrog && lastBlockTime > l1.Timestamp:
+			d.Reason = fmt.Sprintf("the last block timestamp %d is after the limit timestamp of the sequence", lastBlockTime)
+			result.divergence = d
+			return result, nil
+		}
+		if fields := local.diff(l1); len(fields) > 0 {
+			d.Reason = fmt.Sprintf("local inputs differ from the sequence calldata: %s", strings.Join(fields, ", "))
+			result.divergence = d
+			return result, nil
+		}
+
+		if batchNo == sequence.BatchNo {
+			contractAccInputHash, err := source.GetAccInputHash(ctx, forkId, batchNo)
+			if err != nil {
+				return result, fmt.Errorf("failed to get the acc input hash of batch %d: %w", batchNo, err)
+			}
+			if contractAccInputHash != localAccInputHash {
+				d.ContractAccInputHash = &contractAccInputHash
+				d.Reason = "the recomputed acc input hash does not match the rollup contract"
+				result.divergence = d
+				return result, nil
+			}
+		}
+	}
+
+	result.accInputHash = localAccInputHash
+	return result, nil
+}
+
+// SequenceAccInputHashes works out the acc input hash of every batch (prevSequenceBatch, sequence.BatchNo] from the
+// sequence calldata, starting from the acc input hash the rollup contract reports for prevSequenceBatch
+func SequenceAccInputHashes(ctx context.Context, source AccInputHashSource, sequence *zktypes.L1BatchInfo, prevSequenceBatch, forkId uint64) (map[uint64]common.Hash, error) {
+	accInputHash, err := source.GetAccInputHash(ctx, forkId, prevSequenceBatch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the acc input hash of batch %d: %w", prevSequenceBatch, err)
+	}
+
+	l1Transaction, _, err := source.GetTransaction(sequence.L1TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction data for tx %s: %w", sequence.L1TxHash, err)
+	}
+	calldata := l1Transaction.GetData()
+	if len(calldata) < 4 {
+		return nil, fmt.Errorf("calldata of tx %s is too short", sequence.L1TxHash)
+	}
+	decoded, err := DecodeSequenceBatchesCalldata(calldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode calldata for tx %s: %w", sequence.L1TxHash, err)
+	}
+	var l1BlockTime uint64
+	switch decoded.(type) {
+	case *SequenceBatchesCalldataEtrog, *SequenceBatchesCalldataValidiumEtrog:
+		if l1BlockTime, err = source.GetL1BlockTimestamp(ctx, sequence.L1BlockNo); err != nil {
+			return nil, fmt.Errorf("failed to get the timestamp of L1 block %d: %w", sequence.L1BlockNo, err)
+		}
+	}
+	inputs, preEtrog, err := sequenceCalldataInputs(decoded, sequence.L1InfoRoot, l1BlockTime)
+	if err != nil {
+		return nil, err
+	}
+	if sequence.BatchNo-prevSequenceBatch > uint64(len(inputs)) {
+		return nil, fmt.Errorf("the calldata of tx %s has %d batches but the sequence ends at batch %d", sequence.L1TxHash, len(inputs), sequence.BatchNo)
+	}
+
+	hashes := make(map[uint64]common.Hash, sequence.BatchNo-prevSequenceBatch)
+	for batchNo := prevSequenceBatch + 1; batchNo <= sequence.BatchNo; batchNo++ {
+		accInputHash = inputs[batchNo-prevSequenceBatch-1].accInputHash(preEtrog, accInputHash)
+		hashes[batchNo] = accInputHash
+	}
+	return hashes, nil
+}
+
+// localBatchInputs builds the inputs of a batch from its blocks, ok is false when the batch has none locally
+func localBatchInputs(tx kv.Tx, hermezDb *hermez_db.HermezDbReader, batchNo, forkId uint64, l1InfoRoot common.Hash, preEtrog bool) (inputs AccInputHashInputs, lastBlockTime uint64, ok bool, err error) {
+	blockNos, err := hermezDb.GetL2BlockNosByBatch(batchNo)
+	if err != nil || len(blockNos) == 0 {
+		return inputs, 0, false, err
+	}
+	blocks := make([]*ethTypes.Block, 0, len(blockNos))
+	for _, blockNo := range blockNos {
+		block, err := rawdb.ReadBlockByNumber(tx, blockNo)
+		if err != nil {
+			return inputs, 0, false, err
+		}
+		if block == nil {
+			return inputs, 0, false, nil
+		}
+		blocks = append(blocks, block)
+	}
+
+	batchL2Data, err := utils.GenerateBatchDataFromDb(tx, hermezDb, blocks, forkId)
+	if err != nil {
+		return inputs, 0, false, err
+	}
+	lastBlock := blocks[len(blocks)-1]
+	inputs = AccInputHashInputs{
+		BatchDataHash: common.BytesToHash(utils.CalculateBatchHashData(batchL2Data)),
+		L1InfoRoot:    l1InfoRoot,
+		Coinbase:      blocks[0].Coinbase(),
+	}
+	if preEtrog {
+		inputs.Timestamp = lastBlock.Time()
+		inputs.L1InfoRoot = common.Hash{}
+		gerUpdate, err := hermezDb.GetBatchGlobalExitRoot(batchNo)
+		if err != nil {
+			return inputs, 0, false, err
+		}
+		if gerUpdate != nil {
+			inputs.L1InfoRoot = gerUpdate.GlobalExitRoot
+		}
+	}
+
+	return inputs, lastBlock.Time(), true, nil
+}
+
+// sequenceCalldataInputs returns the inputs of every batch in decoded sequence calldata and whether they hash with
+// the pre-etrog formula. l1BlockTime is the limit timestamp of an etrog sequence.
+func sequenceCalldataInputs(decodedSequenceInterface interface{}, l1InfoRoot common.Hash, l1BlockTime uint64) (inputs []AccInputHashInputs, preEtrog bool, err error) {
+	dataHash := func(transactions []byte) common.Hash {
+		return common.BytesToHash(utils.CalculateBatchHashData(transactions))
+	}
+	// from etrog on the contract hashes a forced batch with its own global exit root, timestamp and L1 block hash
+	// and every other batch with the L1 info root and the limit timestamp of the sequence
+	etrogInputs := func(batchDataHash common.Hash, limitTimestamp uint64, coinbase common.Address, forcedGer common.Hash, forcedTimestamp uint64, forcedBlockHashL1 common.Hash) AccInputHashInputs {
+		if forcedTimestamp > 0 {
+			return AccInputHashInputs{BatchDataHash: batchDataHash, L1InfoRoot: forcedGer, Timestamp: forcedTimestamp, Coinbase: coinbase, ForcedBlockHashL1: forcedBlockHashL1}
+		}
+		return AccInputHashInputs{BatchDataHash: batchDataHash, L1InfoRoot: l1InfoRoot, Timestamp: limitTimestamp, Coinbase: coinbase}
+	}
+
+	switch decodedSequence := decodedSequenceInterface.(type) {
+	case *SequenceBatchesCalldataPreEtrog:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, AccInputHashInputs{BatchDataHash: dataHash(batch.Transactions), L1InfoRoot: batch.GlobalExitRoot, Timestamp: batch.Timestamp, Coinbase: decodedSequence.L2Coinbase})
+		}
+		return inputs, true, nil
+	case *SequenceBatchesCalldataValidiumPreEtrog:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, AccInputHashInputs{BatchDataHash: batch.TransactionsHash, L1InfoRoot: batch.GlobalExitRoot, Timestamp: batch.Timestamp, Coinbase: decodedSequence.L2Coinbase})
+		}
+		return inputs, true, nil
+	case *SequenceBatchesCalldataEtrog:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(dataHash(batch.Transactions), l1BlockTime, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	case *SequenceBatchesCalldataValidiumEtrog:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(batch.TransactionsHash, l1BlockTime, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	case *SequenceBatchesCalldataElderberry:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(dataHash(batch.Transactions), decodedSequence.MaxSequenceTimestamp, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	case *SequenceBatchesCalldataValidiumElderberry:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(batch.TransactionsHash, decodedSequence.MaxSequenceTimestamp, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	case *SequenceBatchesCalldataBanana:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(dataHash(batch.Transactions), decodedSequence.MaxSequenceTimestamp, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	case *SequenceBatchesCalldataValidiumBanana:
+		for _, batch := range decodedSequence.Batches {
+			inputs = append(inputs, etrogInputs(batch.TransactionsHash, decodedSequence.MaxSequenceTimestamp, decodedSequence.L2Coinbase, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, batch.ForcedBlockHashL1))
+		}
+	default:
+		return nil, false, fmt.Errorf("unexpected type of decoded sequence calldata: %T", decodedSequenceInterface)
+	}
+
+	return inputs, false, nil
+}
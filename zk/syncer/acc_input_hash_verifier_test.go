@@ -0,0 +1,324 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zk/contracts"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktypes "github.com/ledgerwatch/erigon/zk/types"
+	"github.com/ledgerwatch/erigon/zk/utils"
+)
+
+var testCoinbase = common.HexToAddress("0xc0ffee")
+
+type testAccInputHashSource struct {
+	txs        map[common.Hash]types.Transaction
+	blockTimes map[uint64]uint64
+	hashes     map[uint64]common.Hash
+}
+
+func newTestAccInputHashSource() *testAccInputHashSource {
+	return &testAccInputHashSource{txs: map[common.Hash]types.Transaction{}, blockTimes: map[uint64]uint64{}, hashes: map[uint64]common.Hash{}}
+}
+
+func (s *testAccInputHashSource) GetTransaction(hash common.Hash) (types.Transaction, bool, error) {
+	tx, ok := s.txs[hash]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown tx %s", hash)
+	}
+	return tx, false, nil
+}
+
+func (s *testAccInputHashSource) GetL1BlockTimestamp(ctx context.Context, l1BlockNo uint64) (uint64, error) {
+	t, ok := s.blockTimes[l1BlockNo]
+	if !ok {
+		return 0, fmt.Errorf("unknown L1 block %d", l1BlockNo)
+	}
+	return t, nil
+}
+
+func (s *testAccInputHashSource) GetAccInputHash(ctx context.Context, forkId, batchNo uint64) (common.Hash, error) {
+	return s.hashes[batchNo], nil
+}
+
+// sequence registers a banana sequenceBatches tx for the batch data and records the acc input hashes the contract
+// would store for it
+func (s *testAccInputHashSource) sequence(t *testing.T, txHash common.Hash, prevBatch uint64, l1InfoRoot common.Hash, maxSequenceTimestamp uint64, batchesData ...[]byte) {
+	sequenceAbi, err := abi.JSON(strings.NewReader(contracts.SequenceBatchesAbiBanana))
+	require.NoError(t, err)
+
+	type batchData struct {
+		Transactions         []byte
+		ForcedGlobalExitRoot [32]byte
+		ForcedTimestamp      uint64
+		ForcedBlockHashL1    [32]byte
+	}
+	batches := make([]batchData, len(batchesData))
+	for i, data := range batchesData {
+		batches[i].Transactions = data
+	}
+	calldata, err := sequenceAbi.Pack("sequenceBatches", batches, uint32(0), maxSequenceTimestamp, [32]byte{}, testCoinbase)
+	require.NoError(t, err)
+	s.txs[txHash] = types.NewTransaction(0, common.Address{}, u256.Num0, 0, u256.Num0, calldata)
+
+	decoded, err := DecodeSequenceBatchesCalldata(calldata)
+	require.NoError(t, err)
+	calcFn, total, err := GetAccInputDataCalcFunction(l1InfoRoot, decoded)
+	require.NoError(t, err)
+	accInputHash := s.hashes[prevBatch]
+	for i := 0; i < total; i++ {
+		accInputHash = *calcFn(accInputHash, i)
+	}
+	s.hashes[prevBatch+uint64(total)] = accInputHash
+}
+
+type etrogTestBatch struct {
+	Transactions         []byte
+	ForcedGlobalExitRoot [32]byte
+	ForcedTimestamp      uint64
+	ForcedBlockHashL1    [32]byte
+}
+
+// sequenceEtrog registers an etrog sequenceBatches tx mined in L1 block l1BlockNo at l1BlockTime and records the acc
+// input hashes the etrog contract would store for it, which hashes the L1 block timestamp rather than a limit
+// timestamp from the calldata
+func (s *testAccInputHashSource) sequenceEtrog(t *testing.T, txHash common.Hash, l1BlockNo, l1BlockTime, prevBatch uint64, l1InfoRoot common.Hash, batches ...etrogTestBatch) {
+	sequenceAbi, err := abi.JSON(strings.NewReader(contracts.SequenceBatchesAbiv5_0))
+	require.NoError(t, err)
+	calldata, err := sequenceAbi.Pack("sequenceBatches", batches, testCoinbase)
+	require.NoError(t, err)
+	s.txs[txHash] = types.NewTransaction(0, common.Address{}, u256.Num0, 0, u256.Num0, calldata)
+	s.blockTimes[l1BlockNo] = l1BlockTime
+
+	accInputHash := s.hashes[prevBatch]
+	for _, batch := range batches {
+		if batch.ForcedTimestamp > 0 {
+			accInputHash = *utils.CalculateEtrogAccInputHash(accInputHash, batch.Transactions, batch.ForcedGlobalExitRoot, batch.ForcedTimestamp, testCoinbase, batch.ForcedBlockHashL1)
+		} else {
+			accInputHash = *utils.CalculateEtrogAccInputHash(accInputHash, batch.Transactions, l1InfoRoot, l1BlockTime, testCoinbase, common.Hash{})
+		}
+	}
+	s.hashes[prevBatch+uint64(len(batches))] = accInputHash
+}
+
+// writeTestBatches writes a genesis block and one block per batch and returns the batch data of every batch
+func writeTestBatches(t *testing.T, tx kv.RwTx, batches uint64, forkId chain.ForkId) map[uint64][]byte {
+	require.NoError(t, hermez_db.CreateHermezBuckets(tx))
+	hermezDb := hermez_db.NewHermezDb(tx)
+
+	for blockNo := uint64(0); blockNo <= batches; blockNo++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:   new(big.Int).SetUint64(blockNo),
+			Time:     1000 + blockNo,
+			Coinbase: testCoinbase,
+		})
+		require.NoError(t, rawdb.WriteCanonicalHash(tx, block.Hash(), blockNo))
+		require.NoError(t, rawdb.WriteBlock(tx, block))
+		require.NoError(t, hermezDb.WriteBlockBatch(blockNo, blockNo))
+		require.NoError(t, hermezDb.WriteForkId(blockNo, uint64(forkId)))
+	}
+
+	batchData := make(map[uint64][]byte, batches)
+	for batchNo := uint64(1); batchNo <= batches; batchNo++ {
+		block, err := rawdb.ReadBlockByNumber(tx, batchNo)
+		require.NoError(t, err)
+		data, err := utils.GenerateBatchDataFromDb(tx, hermezDb.HermezDbReader, []*types.Block{block}, uint64(forkId))
+		require.NoError(t, err)
+		batchData[batchNo] = data
+	}
+	return batchData
+}
+
+func TestVerifyAccInputHashes(t *testing.T) {
+	ctx := context.Background()
+	rootA, rootB := common.HexToHash("0xa1"), common.HexToHash("0xb1")
+	txA, txB, txC := common.HexToHash("0xa"), common.HexToHash("0xb"), common.HexToHash("0xc")
+
+	setup := func(t *testing.T) (kv.RwTx, map[uint64][]byte, *testAccInputHashSource) {
+		_, tx := memdb.NewTestTx(t)
+		batchData := writeTestBatches(t, tx, 4, chain.ForkID12Banana)
+		hermezDb := hermez_db.NewHermezDb(tx)
+		require.NoError(t, hermezDb.WriteSequence(10, 2, txA, common.Hash{}, rootA))
+		require.NoError(t, hermezDb.WriteSequence(20, 4, txB, common.Hash{}, rootB))
+		return tx, batchData, newTestAccInputHashSource()
+	}
+
+	t.Run("matching chain", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequence(t, txA, 0, rootA, 2000, batchData[1], batchData[2])
+		source.sequence(t, txB, 2, rootB, 2000, batchData[3], batchData[4])
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, report.Divergence)
+		require.Empty(t, report.Incomplete)
+		require.Equal(t, uint64(1), report.FromBatch)
+		require.Equal(t, uint64(4), report.ToBatch)
+		require.Equal(t, uint64(2), report.Sequences)
+		require.Equal(t, uint64(4), report.Batches)
+
+		// starting part way picks up from the contract hash at the end of the previous sequence
+		report, err = VerifyAccInputHashes(ctx, tx, source, 3, 0)
+		require.NoError(t, err)
+		require.Nil(t, report.Divergence)
+		require.Equal(t, uint64(3), report.FromBatch)
+		require.Equal(t, uint64(1), report.Sequences)
+
+		report, err = VerifyAccInputHashes(ctx, tx, source, 0, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), report.ToBatch)
+		require.Equal(t, uint64(1), report.Sequences)
+	})
+
+	t.Run("batch data divergence", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequence(t, txA, 0, rootA, 2000, batchData[1], batchData[2])
+		source.sequence(t, txB, 2, rootB, 2000, batchData[3], append(batchData[4], 0xff))
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), report.Sequences)
+		d := report.Divergence
+		require.NotNil(t, d)
+		require.Equal(t, uint64(4), d.Batch)
+		require.Equal(t, uint64(chain.ForkID12Banana), d.ForkId)
+		require.Equal(t, txB, d.SequenceL1TxHash)
+		require.Contains(t, d.Reason, "batchDataHash")
+		require.NotContains(t, d.Reason, "coinbase")
+		require.NotEqual(t, d.Local.BatchDataHash, d.L1.BatchDataHash)
+		require.Equal(t, rootB, d.Local.L1InfoRoot)
+		require.Equal(t, testCoinbase, d.Local.Coinbase)
+		require.Equal(t, uint64(2000), d.Local.Timestamp)
+		require.Equal(t, source.hashes[4], d.L1AccInputHash)
+		require.NotEqual(t, d.L1AccInputHash, d.LocalAccInputHash)
+	})
+
+	t.Run("limit timestamp before the last block", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequence(t, txA, 0, rootA, 1001, batchData[1], batchData[2])
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.NotNil(t, report.Divergence)
+		require.Equal(t, uint64(2), report.Divergence.Batch)
+		require.Contains(t, report.Divergence.Reason, "limit timestamp")
+	})
+
+	t.Run("contract mismatch", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequence(t, txA, 0, rootA, 2000, batchData[1], batchData[2])
+		source.hashes[2] = common.HexToHash("0xbad")
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.NotNil(t, report.Divergence)
+		require.Equal(t, uint64(2), report.Divergence.Batch)
+		require.Equal(t, common.HexToHash("0xbad"), *report.Divergence.ContractAccInputHash)
+	})
+
+	t.Run("skipped and incomplete sequences", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		// an injected first batch has no sequenceBatches calldata
+		source.txs[txA] = types.NewTransaction(0, common.Address{}, u256.Num0, 0, u256.Num0, common.FromHex("0xdeadbeef"))
+		source.hashes[2] = common.HexToHash("0x2")
+		source.sequence(t, txB, 2, rootB, 2000, batchData[3], batchData[4])
+		// batch 5 is sequenced on L1 but not synced yet
+		source.sequence(t, txC, 4, rootB, 2000, []byte{0x01})
+		require.NoError(t, hermez_db.NewHermezDb(tx).WriteSequence(30, 5, txC, common.Hash{}, rootB))
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, report.Divergence)
+		require.Len(t, report.Skipped, 1)
+		require.Equal(t, uint64(2), report.Skipped[0].Batch)
+		require.Equal(t, uint64(1), report.Sequences)
+		require.Equal(t, uint64(4), report.ToBatch)
+		require.Contains(t, report.Incomplete, "batch 5 has no blocks")
+	})
+}
+
+func TestVerifyAccInputHashesEtrog(t *testing.T) {
+	ctx := context.Background()
+	rootA := common.HexToHash("0xa1")
+	txA, txB := common.HexToHash("0xa"), common.HexToHash("0xb")
+
+	setup := func(t *testing.T) (kv.RwTx, map[uint64][]byte, *testAccInputHashSource) {
+		_, tx := memdb.NewTestTx(t)
+		batchData := writeTestBatches(t, tx, 3, chain.ForkID7Etrog)
+		hermezDb := hermez_db.NewHermezDb(tx)
+		require.NoError(t, hermezDb.WriteSequence(10, 2, txA, common.Hash{}, rootA))
+		require.NoError(t, hermezDb.WriteSequence(20, 3, txB, common.Hash{}, rootA))
+		return tx, batchData, newTestAccInputHashSource()
+	}
+
+	t.Run("limit timestamp is the L1 block timestamp", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequenceEtrog(t, txA, 10, 2000, 0, rootA, etrogTestBatch{Transactions: batchData[1]}, etrogTestBatch{Transactions: batchData[2]})
+		source.sequenceEtrog(t, txB, 20, 2100, 2, rootA, etrogTestBatch{Transactions: batchData[3]})
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, report.Divergence)
+		require.Empty(t, report.Incomplete)
+		require.Equal(t, uint64(2), report.Sequences)
+		require.Equal(t, uint64(3), report.Batches)
+	})
+
+	t.Run("L1 block before the last block", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		source.sequenceEtrog(t, txA, 10, 1001, 0, rootA, etrogTestBatch{Transactions: batchData[1]}, etrogTestBatch{Transactions: batchData[2]})
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.NotNil(t, report.Divergence)
+		require.Equal(t, uint64(2), report.Divergence.Batch)
+		require.Equal(t, uint64(1001), report.Divergence.L1.Timestamp)
+		require.Contains(t, report.Divergence.Reason, "limit timestamp")
+	})
+
+	t.Run("forced batch", func(t *testing.T) {
+		tx, batchData, source := setup(t)
+		forcedGer, forcedBlockHash := common.HexToHash("0xf1"), common.HexToHash("0xf2")
+		source.sequenceEtrog(t, txA, 10, 2000, 0, rootA, etrogTestBatch{Transactions: batchData[1]}, etrogTestBatch{Transactions: batchData[2]})
+		source.sequenceEtrog(t, txB, 20, 2100, 2, rootA, etrogTestBatch{Transactions: batchData[3], ForcedGlobalExitRoot: forcedGer, ForcedTimestamp: 1500, ForcedBlockHashL1: forcedBlockHash})
+
+		report, err := VerifyAccInputHashes(ctx, tx, source, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, report.Divergence)
+		require.Equal(t, uint64(2), report.Sequences)
+	})
+}
+
+func TestSequenceAccInputHashes(t *testing.T) {
+	ctx := context.Background()
+	rootA := common.HexToHash("0xa1")
+	txA, txB := common.HexToHash("0xa"), common.HexToHash("0xb")
+
+	source := newTestAccInputHashSource()
+	source.sequence(t, txA, 0, rootA, 2000, []byte{1})
+	source.sequence(t, txB, 1, rootA, 2100, []byte{2}, []byte{3})
+
+	hashes, err := SequenceAccInputHashes(ctx, source, &zktypes.L1BatchInfo{BatchNo: 3, L1TxHash: txB, L1InfoRoot: rootA}, 1, uint64(chain.ForkID12Banana))
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+	require.Equal(t, source.hashes[3], hashes[3])
+	require.Equal(t, *utils.CalculateEtrogAccInputHash(source.hashes[1], []byte{2}, rootA, 2100, testCoinbase, common.Hash{}), hashes[2])
+
+	// the sequence record claims more batches than the calldata has
+	_, err = SequenceAccInputHashes(ctx, source, &zktypes.L1BatchInfo{BatchNo: 4, L1TxHash: txB, L1InfoRoot: rootA}, 1, uint64(chain.ForkID12Banana))
+	require.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+
+	ethereum "github.com/ledgerwatch/erigon"
+	ethTypes "github.com/ledgerwatch/erigon/core/types"
+)
+
+// PreviousL1BlockHashFunc returns the highest recorded l1 block lower than the given one along with its hash
+type PreviousL1BlockHashFunc func(l1BlockNo uint64) (blockNo uint64, hash common.Hash, found bool, err error)
+
+// FindL1ReorgForkBlock checks the recorded l1 block hashes against the ones l1 reports now.  When the highest recorded
+// block is no longer canonical it walks back to the highest recorded block that still is and returns it as the fork
+// block, everything recorded above it belongs to the old fork.  A recorded block l1 no longer has, as the new chain
+// can be shorter than the old one, is not canonical either.  If no recorded block is canonical anymore found is
+// false and the whole of the recorded data has to be dropped.
+func FindL1ReorgForkBlock(getHeader func(number uint64) (*ethTypes.Header, error), previousHash PreviousL1BlockHashFunc) (forkBlock uint64, reorged bool, found bool, err error) {
+	next := uint64(math.MaxUint64)
+	for {
+		blockNo, hash, ok, err := previousHash(next)
+		if err != nil {
+			return 0, false, false, err
+		}
+		if !ok {
+			// nothing recorded, or none of the recorded blocks survived the reorg
+			return 0, reorged, false, nil
+		}
+
+		header, err := getHeader(blockNo)
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return 0, false, false, fmt.Errorf("GetHeader %d: %w", blockNo, err)
+		}
+		if header != nil && header.Hash() == hash {
+			return blockNo, reorged, true, nil
+		}
+
+		reorged = true
+		next = blockNo
+	}
+}
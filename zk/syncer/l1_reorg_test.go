@@ -0,0 +1,77 @@
+package syncer
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	ethereum "github.com/ledgerwatch/erigon"
+	ethTypes "github.com/ledgerwatch/erigon/core/types"
+)
+
+func TestFindL1ReorgForkBlock(t *testing.T) {
+	// l1 after a reorg to a shorter chain, blocks above 20 no longer exist
+	l1 := map[uint64]*ethTypes.Header{}
+	for _, n := range []uint64{10, 20} {
+		l1[n] = &ethTypes.Header{Number: new(big.Int).SetUint64(n)}
+	}
+	getHeader := func(number uint64) (*ethTypes.Header, error) {
+		if h, ok := l1[number]; ok {
+			return h, nil
+		}
+		return nil, ethereum.NotFound
+	}
+	previousHash := func(recorded map[uint64]common.Hash) PreviousL1BlockHashFunc {
+		return func(l1BlockNo uint64) (uint64, common.Hash, bool, error) {
+			var blockNos []uint64
+			for n := range recorded {
+				if n < l1BlockNo {
+					blockNos = append(blockNos, n)
+				}
+			}
+			if len(blockNos) == 0 {
+				return 0, common.Hash{}, false, nil
+			}
+			sort.Slice(blockNos, func(i, j int) bool { return blockNos[i] > blockNos[j] })
+			return blockNos[0], recorded[blockNos[0]], true, nil
+		}
+	}
+
+	t.Run("no reorg", func(t *testing.T) {
+		forkBlock, reorged, found, err := FindL1ReorgForkBlock(getHeader, previousHash(map[uint64]common.Hash{10: l1[10].Hash(), 20: l1[20].Hash()}))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.False(t, reorged)
+		require.Equal(t, uint64(20), forkBlock)
+	})
+
+	t.Run("recorded blocks missing from the new chain", func(t *testing.T) {
+		recorded := map[uint64]common.Hash{10: l1[10].Hash(), 20: common.HexToHash("0x20"), 30: common.HexToHash("0x30"), 40: common.HexToHash("0x40")}
+		forkBlock, reorged, found, err := FindL1ReorgForkBlock(getHeader, previousHash(recorded))
+		require.NoError(t, err)
+		require.True(t, found)
+		require.True(t, reorged)
+		require.Equal(t, uint64(10), forkBlock)
+	})
+
+	t.Run("nothing recorded survived", func(t *testing.T) {
+		recorded := map[uint64]common.Hash{10: common.HexToHash("0x10"), 30: common.HexToHash("0x30")}
+		_, reorged, found, err := FindL1ReorgForkBlock(getHeader, previousHash(recorded))
+		require.NoError(t, err)
+		require.False(t, found)
+		require.True(t, reorged)
+	})
+
+	t.Run("l1 error", func(t *testing.T) {
+		l1Err := errors.New("connection refused")
+		failing := func(number uint64) (*ethTypes.Header, error) {
+			return nil, l1Err
+		}
+		_, _, _, err := FindL1ReorgForkBlock(failing, previousHash(map[uint64]common.Hash{10: l1[10].Hash()}))
+		require.ErrorIs(t, err, l1Err)
+	})
+}
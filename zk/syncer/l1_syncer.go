@@ -25,6 +25,7 @@ var (
 
 var errorShortResponseLT32 = fmt.Errorf("response too short to contain hash data")
 var errorShortResponseLT96 = fmt.Errorf("response too short to contain last batch number data")
+var errorShortResponseLT160 = fmt.Errorf("response too short to contain last local exit root data")
 
 const (
 	rollupSequencedBatchesSignature = "0x25280169" // hardcoded abi signature
@@ -33,6 +34,8 @@ const (
 	admin                           = "0xf851a440"
 	trustedSequencer                = "0xcfa8ed47"
 	sequencedBatchesMapSignature    = "0xb4d63f58"
+	rollupCountSignature            = "0xf4e92675"
+	rollupIDToRollupDataSignature   = "0xf9c4c2ae"
 )
 
 //go:generate mockgen -typed=true -destination=./mocks/etherman_mock.go -package=mocks . IEtherman
@@ -519,6 +522,43 @@ func (s *L1Syncer) callGetRollupSequencedBatches(ctx context.Context, addr *comm
 	return h, lastBatchNumber, nil
 }
 
+// CallRollupExitLeaves returns the last local exit root of every rollup of the rollup manager as of the given l1
+// block, the leaves of its rollup exit tree ordered by rollup id
+func (s *L1Syncer) CallRollupExitLeaves(ctx context.Context, addr *common.Address, l1BlockNo uint64) ([]common.Hash, error) {
+	em := s.getNextEtherman()
+	blockNumber := new(big.Int).SetUint64(l1BlockNo)
+
+	resp, err := em.CallContract(ctx, ethereum.CallMsg{
+		To:   addr,
+		Data: common.FromHex(rollupCountSignature),
+	}, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 32 {
+		return nil, errorShortResponseLT32
+	}
+	rollupCount := binary.BigEndian.Uint32(resp[28:32])
+
+	leaves := make([]common.Hash, rollupCount)
+	for rollupId := uint32(1); rollupId <= rollupCount; rollupId++ {
+		resp, err = em.CallContract(ctx, ethereum.CallMsg{
+			To:   addr,
+			Data: common.FromHex(fmt.Sprintf("%s%064x", rollupIDToRollupDataSignature, rollupId)),
+		}, blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		// rollupContract, chainID, verifier and forkID come ahead of lastLocalExitRoot
+		if len(resp) < 160 {
+			return nil, errorShortResponseLT160
+		}
+		leaves[rollupId-1] = common.BytesToHash(resp[128:160])
+	}
+
+	return leaves, nil
+}
+
 func (s *L1Syncer) CallAdmin(ctx context.Context, addr *common.Address) (common.Address, error) {
 	return s.callGetAddress(ctx, addr, admin)
 }
@@ -0,0 +1,151 @@
+#!/bin/bash
+
+set -e  # Exit immediately if a command exits with a non-zero status
+set -o pipefail  # Capture errors in pipelines
+
+# Hands the sequencer lease from one cdk-erigon node to another and back, checking each time that the node stepping
+# down stops sequencing, syncs the blocks of the new sequencer and answers eth_gasPrice from it.  CONFIG must be a
+# sequencer config for a chain whose L1 is reachable, e.g. a local devnet, both nodes run from it.
+
+# Variables
+config="${CONFIG:?set CONFIG to a sequencer config for a chain with a reachable L1}"
+binary="./build/bin/cdk-erigon"
+dataPath="./datadir-failover"
+leaseFile="$dataPath/sequencer.lease"
+leaseTTL="15s"
+token="failover-test"
+timeout=300
+logFile="failover.log"
+
+# Redirect stdout to log file and keep stderr to console
+exec > >(tee -i "$logFile")  # Redirect stdout to log file
+exec 2> >(tee -a "$logFile" >&2)  # Redirect stderr to log file and keep it on console
+
+pids=()
+
+# Cleanup function
+cleanup() {
+    echo "[$(date)] Cleaning up..."
+    for pid in "${pids[@]}"; do
+        kill "$pid" 2>/dev/null || true
+    done
+    wait || true
+
+    echo "[$(date)] Total execution time: $SECONDS seconds"
+}
+trap cleanup EXIT
+
+# start_node name role offset [env] starts a node on ports shifted by offset, recording its own urls in the lease
+start_node() {
+    local name=$1
+    local role=$2
+    local offset=$3
+    local rpcPort=$((8123 + offset))
+    local dsPort=$((6900 + offset))
+
+    echo "[$(date)] Starting node $name as $role, rpc on $rpcPort and datastream on $dsPort"
+    env ${4:-} "$binary" \
+        --config="$config" \
+        --datadir="$dataPath/$name" \
+        --http.port="$rpcPort" \
+        --http.api="eth,net,web3,zkevm,admin" \
+        --private.api.addr="localhost:$((9090 + offset))" \
+        --authrpc.port="$((8551 + offset))" \
+        --port="$((30303 + offset))" \
+        --torrent.port="$((42069 + offset))" \
+        --zkevm.data-stream-host="127.0.0.1" \
+        --zkevm.data-stream-port="$dsPort" \
+        --zkevm.sequencer-role="$role" \
+        --zkevm.sequencer-lease-file="$leaseFile" \
+        --zkevm.sequencer-lease-ttl="$leaseTTL" \
+        --zkevm.sequencer-lease-rpc-url="http://127.0.0.1:$rpcPort" \
+        --zkevm.sequencer-lease-datastream-url="127.0.0.1:$dsPort" \
+        --zkevm.admin-token="$token" \
+        > "$dataPath/$name.log" 2>&1 &
+    pids+=($!)
+}
+
+rpc() {
+    curl -sf -X POST -H "Content-Type: application/json" -H "Authorization: Bearer $token" \
+        --data "{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"$2\",\"params\":${3:-[]}}" "$1"
+}
+
+block_number() {
+    local hex
+    hex=$(rpc "$1" eth_blockNumber | jq -r .result)
+    echo $((hex))
+}
+
+sequencing() {
+    rpc "$1" admin_getSequencerStatus | jq -r .result.sequencing
+}
+
+leader_rpc() {
+    rpc "$1" admin_getSequencerStatus | jq -r .result.leader.rpcUrl
+}
+
+# wait_until description command... retries the command until it succeeds or the timeout runs out
+wait_until() {
+    local description=$1
+    shift
+    local deadline=$((SECONDS + timeout))
+    echo "[$(date)] Waiting until $description..."
+    until "$@" >/dev/null 2>&1; do
+        if ((SECONDS > deadline)); then
+            echo "[$(date)] Timed out waiting until $description" >&2
+            exit 1
+        fi
+        sleep 2
+    done
+}
+
+is_sequencing() { [[ "$(sequencing "$1")" == "true" ]]; }
+is_following() { [[ "$(sequencing "$1")" == "false" && "$(leader_rpc "$1")" == "$2" ]]; }
+has_block() { (($(block_number "$1") >= $2)); }
+promote() { rpc "$1" admin_promoteSequencer "[false]" | jq -e '.error == null'; }
+same_gas_price() { [[ "$(rpc "$1" eth_gasPrice | jq -r .result)" == "$(rpc "$2" eth_gasPrice | jq -r .result)" ]]; }
+
+# handover from to steps the sequencer at url from down and promotes the node at url to, then checks from follows it
+handover() {
+    local from=$1
+    local to=$2
+
+    echo "[$(date)] Handing the sequencer lease over from $from to $to"
+    wait_until "$to has synced the blocks of $from" has_block "$to" "$(block_number "$from")"
+    rpc "$from" admin_stepDownSequencer | jq -e '.error == null'
+    wait_until "$to is promoted" promote "$to"
+
+    wait_until "$to is sequencing" is_sequencing "$to"
+    wait_until "$from follows $to" is_following "$from" "$to"
+
+    local handedOver
+    handedOver=$(block_number "$to")
+    wait_until "$to produces blocks" has_block "$to" $((handedOver + 2))
+    wait_until "$from syncs the blocks of $to" has_block "$from" $((handedOver + 2))
+    wait_until "$from answers eth_gasPrice from $to" same_gas_price "$from" "$to"
+}
+
+if [[ ! -x "$binary" ]]; then
+    echo "[$(date)] $binary not found, run make cdk-erigon first" >&2
+    exit 1
+fi
+
+echo "[$(date)] Removing existing data directory..."
+rm -rf "$dataPath"
+mkdir -p "$dataPath"
+
+nodeA="http://127.0.0.1:8123"
+nodeB="http://127.0.0.1:8124"
+
+# node a is started the legacy way as well, stepping down must still turn it into a follower
+start_node a sequencer 0 CDK_ERIGON_SEQUENCER=1
+wait_until "a is sequencing" is_sequencing "$nodeA"
+wait_until "a produces blocks" has_block "$nodeA" 2
+
+start_node b standby 1
+wait_until "b follows a" is_following "$nodeB" "$nodeA"
+
+handover "$nodeA" "$nodeB"
+handover "$nodeB" "$nodeA"
+
+echo "[$(date)] No error"
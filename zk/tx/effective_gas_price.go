@@ -0,0 +1,41 @@
+package tx
+
+import (
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// TxCategory is the kind of transaction the sequencer picks an effective gas price percentage for
+type TxCategory string
+
+const (
+	TxCategoryEthTransfer        TxCategory = "ethTransfer"
+	TxCategoryErc20Transfer      TxCategory = "erc20Transfer"
+	TxCategoryContractInvocation TxCategory = "contractInvocation"
+	TxCategoryContractDeployment TxCategory = "contractDeployment"
+)
+
+// CategorizeTx returns the category the sequencer prices the transaction as, erc20 transfers are recognised by the
+// transfer and transferFrom method ids
+func CategorizeTx(tx types.Transaction) TxCategory {
+	if tx.GetTo() == nil {
+		return TxCategoryContractDeployment
+	}
+
+	data := tx.GetData()
+	dataLen := len(data)
+	if dataLen != 0 {
+		if dataLen >= 8 {
+			// transfer's method id 0xa9059cbb
+			isTransfer := data[0] == 169 && data[1] == 5 && data[2] == 156 && data[3] == 187
+			// transfer's method id 0x23b872dd
+			isTransferFrom := data[0] == 35 && data[1] == 184 && data[2] == 114 && data[3] == 221
+			if isTransfer || isTransferFrom {
+				return TxCategoryErc20Transfer
+			}
+		}
+
+		return TxCategoryContractInvocation
+	}
+
+	return TxCategoryEthTransfer
+}
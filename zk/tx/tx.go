@@ -16,7 +16,9 @@ import (
 	"github.com/holiman/uint256"
 	constants "github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/smt/pkg/utils"
 	"github.com/ledgerwatch/erigon/zk/hermez_db"
@@ -40,6 +42,7 @@ const (
 	shortRlp                       uint64 = 55  // length of the short rlp codification
 	f7                             uint64 = 247 // 192 + 55 = c0 + shortRlp
 	efficiencyPercentageByteLength uint64 = 1
+	txTypeByteLength               uint64 = 1
 
 	changeL2BlockTxType = 11
 	changeL2BlockLength = 9
@@ -101,6 +104,23 @@ func DecodeBatchL2Blocks(txsData []byte, forkID uint64) ([]DecodedBatchL2Data, e
 			continue
 		}
 
+		// typed transactions carry their EIP-2718 type byte ahead of the rlp list of their fields
+		txType := byte(types.LegacyTxType)
+		rlpPos := pos
+		if num == types.AccessListTxType || num == types.DynamicFeeTxType {
+			if !TxTypeSupported(byte(num), forkID) {
+				log.Debug("error typed transaction before fork: ", num, forkID)
+				return result, ErrInvalidData
+			}
+			rlpPos += txTypeByteLength
+			if rlpPos >= txDataLength {
+				log.Debug("error typed transaction without rlp data: ", num)
+				return result, ErrInvalidData
+			}
+			txType = byte(num)
+			num = uint64(txsData[rlpPos])
+		}
+
 		// First byte is the length and must be ignored
 		if num < c0 {
 			log.Debug("error num < c0 : %d, %d", num, c0)
@@ -109,11 +129,11 @@ func DecodeBatchL2Blocks(txsData []byte, forkID uint64) ([]DecodedBatchL2Data, e
 		length := num - c0
 		if length > shortRlp { // If rlp is bigger than length 55
 			// n is the length of the rlp data without the header (1 byte) for example "0xf7"
-			if (pos + 1 + num - f7) > txDataLength {
+			if (rlpPos + 1 + num - f7) > txDataLength {
 				log.Debug("error parsing length: ", err)
 				return result, err
 			}
-			n, err := strconv.ParseUint(hex.EncodeToString(txsData[pos+1:pos+1+num-f7]), hex.Base, hex.BitSize64) // +1 is the header. For example 0xf7
+			n, err := strconv.ParseUint(hex.EncodeToString(txsData[rlpPos+1:rlpPos+1+num-f7]), hex.Base, hex.BitSize64) // +1 is the header. For example 0xf7
 			if err != nil {
 				log.Debug("error parsing length: ", err)
 				return result, err
@@ -125,7 +145,7 @@ func DecodeBatchL2Blocks(txsData []byte, forkID uint64) ([]DecodedBatchL2Data, e
 			length = n + num - f7 // num - f7 is the header. For example 0xf7
 		}
 
-		endPos := pos + length + rLength + sLength + vLength + headerByteLength
+		endPos := rlpPos + length + rLength + sLength + vLength + headerByteLength
 
 		if forkID >= uint64(constants.ForkID5Dragonfruit) {
 			endPos += efficiencyPercentageByteLength
@@ -150,8 +170,8 @@ func DecodeBatchL2Blocks(txsData []byte, forkID uint64) ([]DecodedBatchL2Data, e
 		}
 
 		fullDataTx := txsData[pos:endPos]
-		dataStart := pos + length + headerByteLength
-		txInfo := txsData[pos:dataStart]
+		dataStart := rlpPos + length + headerByteLength
+		txInfo := txsData[rlpPos:dataStart]
 		rData := txsData[dataStart : dataStart+rLength]
 		sData := txsData[dataStart+rLength : dataStart+rLength+sLength]
 		vData := txsData[dataStart+rLength+sLength : dataStart+rLength+sLength+vLength]
@@ -163,6 +183,16 @@ func DecodeBatchL2Blocks(txsData []byte, forkID uint64) ([]DecodedBatchL2Data, e
 
 		pos = endPos
 
+		if txType != types.LegacyTxType {
+			typedTx, err := rlpToTypedTx(txType, txInfo, vData, rData, sData)
+			if err != nil {
+				log.Debug("error creating typed tx from rlp: ", err, ". fullDataTx: ", hex.EncodeToString(fullDataTx), "\n tx: ", hex.EncodeToString(txInfo), "\n Transactions received: ", hex.EncodeToString(txsData))
+				return result, ErrInvalidData
+			}
+			currentData.Transactions = append(currentData.Transactions, typedTx)
+			continue
+		}
+
 		// Decode rlpFields
 		var rlpFields [][]byte
 		err = rlp.DecodeBytes(txInfo, &rlpFields)
@@ -206,6 +236,19 @@ func DecodeTx(encodedTx []byte, efficiencyPercentage byte, forkId uint64) (types
 	return tx, efficiencyPercentage, nil
 }
 
+// TxTypeSupported reports whether transactions of the given type can be carried in the batch l2 data of a fork.
+// Access list and dynamic fee transactions are only supported from ForkId14TypedTxs, blob transactions never are.
+func TxTypeSupported(txType byte, forkId uint64) bool {
+	switch txType {
+	case types.LegacyTxType:
+		return true
+	case types.AccessListTxType, types.DynamicFeeTxType:
+		return forkId >= uint64(constants.ForkId14TypedTxs)
+	default:
+		return false
+	}
+}
+
 // RlpFieldsToLegacyTx parses the rlp fields slice into a type.LegacyTx
 // in this specific order:
 //
@@ -294,6 +337,100 @@ func rlpFieldsToLegacyTx(fields [][]byte, v, r, s []byte) (tx *types.LegacyTx, e
 	}, nil
 }
 
+// accessListTxFields is the unsigned EIP-2930 payload as it is carried in the batch l2 data after the type byte
+type accessListTxFields struct {
+	ChainID    []byte
+	Nonce      []byte
+	GasPrice   []byte
+	Gas        []byte
+	To         []byte
+	Value      []byte
+	Data       []byte
+	AccessList types2.AccessList
+}
+
+// dynamicFeeTxFields is the unsigned EIP-1559 payload as it is carried in the batch l2 data after the type byte
+type dynamicFeeTxFields struct {
+	ChainID    []byte
+	Nonce      []byte
+	Tip        []byte
+	FeeCap     []byte
+	Gas        []byte
+	To         []byte
+	Value      []byte
+	Data       []byte
+	AccessList types2.AccessList
+}
+
+// rlpToTypedTx parses the rlp payload of an access list or dynamic fee transaction, v is the y parity of the
+// signature plus 27 as it is for legacy transactions
+func rlpToTypedTx(txType byte, payload []byte, v, r, s []byte) (types.Transaction, error) {
+	if len(v) != int(vLength) || v[0] < ether155V || v[0] > ether155V+1 {
+		return nil, fmt.Errorf("invalid v %x for typed transaction", v)
+	}
+
+	commonTx := types.CommonTx{}
+	commonTx.V.SetUint64(uint64(v[0] - ether155V))
+	commonTx.R.SetBytes(r)
+	commonTx.S.SetBytes(s)
+
+	switch txType {
+	case types.AccessListTxType:
+		var fields accessListTxFields
+		if err := rlp.DecodeBytes(payload, &fields); err != nil {
+			return nil, err
+		}
+		commonTx.Nonce = new(big.Int).SetBytes(fields.Nonce).Uint64()
+		commonTx.Gas = new(big.Int).SetBytes(fields.Gas).Uint64()
+		commonTx.To = bytesToAddress(fields.To)
+		commonTx.Value = new(uint256.Int).SetBytes(fields.Value)
+		commonTx.Data = fields.Data
+		return &types.AccessListTx{
+			LegacyTx: types.LegacyTx{
+				CommonTx: commonTx,
+				GasPrice: new(uint256.Int).SetBytes(fields.GasPrice),
+			},
+			ChainID:    new(uint256.Int).SetBytes(fields.ChainID),
+			AccessList: nilIfEmpty(fields.AccessList),
+		}, nil
+	case types.DynamicFeeTxType:
+		var fields dynamicFeeTxFields
+		if err := rlp.DecodeBytes(payload, &fields); err != nil {
+			return nil, err
+		}
+		commonTx.Nonce = new(big.Int).SetBytes(fields.Nonce).Uint64()
+		commonTx.Gas = new(big.Int).SetBytes(fields.Gas).Uint64()
+		commonTx.To = bytesToAddress(fields.To)
+		commonTx.Value = new(uint256.Int).SetBytes(fields.Value)
+		commonTx.Data = fields.Data
+		return &types.DynamicFeeTransaction{
+			CommonTx:   commonTx,
+			ChainID:    new(uint256.Int).SetBytes(fields.ChainID),
+			Tip:        new(uint256.Int).SetBytes(fields.Tip),
+			FeeCap:     new(uint256.Int).SetBytes(fields.FeeCap),
+			AccessList: nilIfEmpty(fields.AccessList),
+		}, nil
+	default:
+		return nil, types.ErrTxTypeNotSupported
+	}
+}
+
+// nilIfEmpty matches the canonical transaction decoding which leaves an empty access list nil
+func nilIfEmpty(accessList types2.AccessList) types2.AccessList {
+	if len(accessList) == 0 {
+		return nil
+	}
+	return accessList
+}
+
+func bytesToAddress(b []byte) *common.Address {
+	if len(b) == 0 {
+		return nil
+	}
+	addr := common.BytesToAddress(b)
+	return &addr
+}
+
 /*
 *
 Copy of TransactionToL2Data with modifications:
@@ -303,6 +440,13 @@ Copy of TransactionToL2Data with modifications:
 Tne encoding is based on zkemv-commonjs/src/process-utils as of eb1ed1a1c05e2666cd32e3900beff5121bdeb4db
 */
 func TransactionToL2Data(tx types.Transaction, forkId uint16, efficiencyPercentage uint8) ([]byte, error) {
+	// until the batch l2 data can carry typed transactions, as taken on normalcy chains, they are encoded with their
+	// legacy fields
+	txType := tx.Type()
+	if !TxTypeSupported(txType, uint64(forkId)) {
+		txType = types.LegacyTxType
+	}
+
 	nonceBytes := hermez_db.Uint64ToBytes(tx.GetNonce())
 	gasPriceBytes := tx.GetPrice().Bytes()
 	gas := hermez_db.Uint64ToBytes(tx.GetGas())
@@ -320,28 +464,57 @@ func TransactionToL2Data(tx types.Transaction, forkId uint16, efficiencyPercenta
 
 	v, r, s := tx.RawSignatureValues()
 
-	toEncode := [][]byte{
-		removeLeadingZeroesFromBytes(nonceBytes),
-		removeLeadingZeroesFromBytes(gasPriceBytes),
-		removeLeadingZeroesFromBytes(gas),
-		to, // don't remove leading 0s from addr
-		removeLeadingZeroesFromBytes(valueBytes),
-		tx.GetData(),
-	}
+	var encoded []byte
+	var err error
+	switch txType {
+	case types.AccessListTxType:
+		encoded, err = rlp.EncodeToBytes(&accessListTxFields{
+			ChainID:    removeLeadingZeroesFromBytes(chainIdBytes.Bytes()),
+			Nonce:      removeLeadingZeroesFromBytes(nonceBytes),
+			GasPrice:   removeLeadingZeroesFromBytes(gasPriceBytes),
+			Gas:        removeLeadingZeroesFromBytes(gas),
+			To:         to,
+			Value:      removeLeadingZeroesFromBytes(valueBytes),
+			Data:       tx.GetData(),
+			AccessList: tx.GetAccessList(),
+		})
+		encoded = append([]byte{tx.Type()}, encoded...)
+	case types.DynamicFeeTxType:
+		encoded, err = rlp.EncodeToBytes(&dynamicFeeTxFields{
+			ChainID:    removeLeadingZeroesFromBytes(chainIdBytes.Bytes()),
+			Nonce:      removeLeadingZeroesFromBytes(nonceBytes),
+			Tip:        removeLeadingZeroesFromBytes(tx.GetTip().Bytes()),
+			FeeCap:     removeLeadingZeroesFromBytes(tx.GetFeeCap().Bytes()),
+			Gas:        removeLeadingZeroesFromBytes(gas),
+			To:         to,
+			Value:      removeLeadingZeroesFromBytes(valueBytes),
+			Data:       tx.GetData(),
+			AccessList: tx.GetAccessList(),
+		})
+		encoded = append([]byte{tx.Type()}, encoded...)
+	default:
+		toEncode := [][]byte{
+			removeLeadingZeroesFromBytes(nonceBytes),
+			removeLeadingZeroesFromBytes(gasPriceBytes),
+			removeLeadingZeroesFromBytes(gas),
+			to, // don't remove leading 0s from addr
+			removeLeadingZeroesFromBytes(valueBytes),
+			tx.GetData(),
+		}
 
-	if !tx.GetChainID().Eq(uint256.NewInt(0)) || !(v.Eq(uint256.NewInt(27)) || v.Eq(uint256.NewInt(28))) {
-		toEncode = append(toEncode, removeLeadingZeroesFromBytes(chainIdBytes.Bytes()))
-		toEncode = append(toEncode, []byte{})
-		toEncode = append(toEncode, []byte{})
-	}
+		if !tx.GetChainID().Eq(uint256.NewInt(0)) || !(v.Eq(uint256.NewInt(27)) || v.Eq(uint256.NewInt(28))) {
+			toEncode = append(toEncode, removeLeadingZeroesFromBytes(chainIdBytes.Bytes()))
+			toEncode = append(toEncode, []byte{})
+			toEncode = append(toEncode, []byte{})
+		}
 
-	encoded, err := rlp.EncodeToBytes(toEncode)
+		encoded, err = rlp.EncodeToBytes(toEncode)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	// reverse the eip-155 changes for the V value for transport
-	v = GetDecodedV(tx, v)
+	v = GetBatchL2DataV(tx, v, uint64(forkId))
 	txV := new(big.Int).SetBytes(v.Bytes())
 
 	vBytes := txV.Bytes()
@@ -386,6 +559,15 @@ func GetDecodedV(tx types.Transaction, v *uint256.Int) *uint256.Int {
 	return result
 }
 
+// GetBatchL2DataV returns the V value as it is carried in the batch l2 data of a fork.  Legacy transactions reverse
+// the eip-155 changes, typed transactions sign with the bare y parity which is shifted into the same 27/28 range.
+func GetBatchL2DataV(tx types.Transaction, v *uint256.Int, forkId uint64) *uint256.Int {
+	if txType := tx.Type(); txType != types.LegacyTxType && TxTypeSupported(txType, forkId) {
+		return new(uint256.Int).AddUint64(v, ether155V)
+	}
+	return GetDecodedV(tx, v)
+}
+
 type BatchTxData struct {
 	Transaction                 types.Transaction
 	EffectiveGasPricePercentage uint8
@@ -436,21 +618,67 @@ func ComputeL2TxHash(
 		txType = "00"
 	}
 
+	return computeL2TxHash(txType, chainId, value, []*uint256.Int{gasPrice}, nonce, txGasLimit, to, from, data, nil)
+}
+
+// ComputeL2TxHashForTx computes the l2 tx hash of any transaction that can be carried in the batch l2 data.  Legacy
+// transactions hash exactly as ComputeL2TxHash, access list transactions use type 02 and dynamic fee transactions
+// type 03 with both the fee cap and the tip in place of the gas price.  Typed transactions append the keccak of their
+// rlp encoded access list after the chain id.
+func ComputeL2TxHashForTx(tx types.Transaction, from *common.Address) (common.Hash, error) {
+	var txType string
+	var gasPrices []*uint256.Int
+	switch tx.Type() {
+	case types.LegacyTxType:
+		return ComputeL2TxHash(tx.GetChainID().ToBig(), tx.GetValue(), tx.GetPrice(), tx.GetNonce(), tx.GetGas(), tx.GetTo(), from, tx.GetData())
+	case types.AccessListTxType:
+		txType = "02"
+		gasPrices = []*uint256.Int{tx.GetPrice()}
+	case types.DynamicFeeTxType:
+		txType = "03"
+		gasPrices = []*uint256.Int{tx.GetFeeCap(), tx.GetTip()}
+	default:
+		return common.Hash{}, fmt.Errorf("%w: type %d", types.ErrTxTypeNotSupported, tx.Type())
+	}
+
+	accessList, err := rlp.EncodeToBytes(tx.GetAccessList())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	accessListHash := crypto.Keccak256Hash(accessList)
+
+	return computeL2TxHash(txType, tx.GetChainID().ToBig(), tx.GetValue(), gasPrices, tx.GetNonce(), tx.GetGas(), tx.GetTo(), from, tx.GetData(), &accessListHash)
+}
+
+func computeL2TxHash(
+	txType string,
+	chainId *big.Int,
+	value *uint256.Int,
+	gasPrices []*uint256.Int,
+	nonce, txGasLimit uint64,
+	to, from *common.Address,
+	data []byte,
+	accessListHash *common.Hash,
+) (common.Hash, error) {
 	// add txType, nonce, gasPrice and gasLimit
 	noncePart, err := formatL2TxHashParam(nonce, 8)
 	if err != nil {
 		return common.Hash{}, err
 
 	}
-	gasPricePart, err := formatL2TxHashParam(gasPrice, 32)
-	if err != nil {
-		return common.Hash{}, err
+	hash := txType + noncePart
+	for _, gasPrice := range gasPrices {
+		gasPricePart, err := formatL2TxHashParam(gasPrice, 32)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		hash += gasPricePart
 	}
 	gasLimitPart, err := formatL2TxHashParam(txGasLimit, 8)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	hash := fmt.Sprintf("%s%s%s%s", txType, noncePart, gasPricePart, gasLimitPart)
+	hash += gasLimitPart
 
 	// check is deploy
 	if to == nil {
@@ -500,6 +728,15 @@ func ComputeL2TxHash(
 		hash += chainIDPart
 	}
 
+	// add the access list of typed transactions
+	if accessListHash != nil {
+		accessListPart, err := formatL2TxHashParam(accessListHash.Bytes(), 32)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		hash += accessListPart
+	}
+
 	// add from
 	fromPart, err := formatL2TxHashParam(from.Hex(), 20)
 	if err != nil {
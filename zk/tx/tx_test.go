@@ -1,6 +1,7 @@
 package tx
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"math/big"
@@ -13,7 +14,9 @@ import (
 	"github.com/holiman/uint256"
 	constants "github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -527,3 +530,234 @@ func Test_ComplexMixOfBlocks(t *testing.T) {
 		}
 	}
 }
+
+func TestCategorizeTx(t *testing.T) {
+	to := common.HexToAddress("0x1")
+	scenarios := map[string]struct {
+		to       *common.Address
+		data     []byte
+		expected TxCategory
+	}{
+		"eth transfer":        {to: &to, expected: TxCategoryEthTransfer},
+		"erc20 transfer":      {to: &to, data: common.FromHex("0xa9059cbb0000000000000000"), expected: TxCategoryErc20Transfer},
+		"erc20 transferFrom":  {to: &to, data: common.FromHex("0x23b872dd0000000000000000"), expected: TxCategoryErc20Transfer},
+		"contract invocation": {to: &to, data: common.FromHex("0x12345678"), expected: TxCategoryContractInvocation},
+		"contract deployment": {data: common.FromHex("0x6080"), expected: TxCategoryContractDeployment},
+	}
+
+	for name, scenario := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			tx := &types.LegacyTx{CommonTx: types.CommonTx{To: scenario.to, Data: scenario.data}}
+			assert.Equal(t, scenario.expected, CategorizeTx(tx))
+		})
+	}
+}
+
+func typedTestTransactions() map[string]types.Transaction {
+	toAddress := common.HexToAddress("0x1275fbb540c8efc58b812ba83b0d0b8b9917ae98")
+	accessList := types2.AccessList{
+		{
+			Address:     common.HexToAddress("0x4d5Cf5032B2a844602278b01199ED191A86c93ff"),
+			StorageKeys: []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")},
+		},
+	}
+
+	return map[string]types.Transaction{
+		"access list": &types.AccessListTx{
+			LegacyTx: types.LegacyTx{
+				CommonTx: types.CommonTx{
+					Nonce: 2,
+					Gas:   21000,
+					To:    &toAddress,
+					Value: uint256.NewInt(4),
+					Data:  []byte{5},
+					V:     *uint256.NewInt(1),
+					R:     *uint256.NewInt(7),
+					S:     *uint256.NewInt(8),
+				},
+				GasPrice: uint256.NewInt(1000000000),
+			},
+			ChainID:    uint256.NewInt(1000),
+			AccessList: accessList,
+		},
+		"dynamic fee": &types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{
+				Nonce: 3,
+				Gas:   100000,
+				To:    &toAddress,
+				Value: uint256.NewInt(0),
+				Data:  common.FromHex("0x188ec356"),
+				V:     *uint256.NewInt(0),
+				R:     *uint256.NewInt(9),
+				S:     *uint256.NewInt(10),
+			},
+			ChainID:    uint256.NewInt(1000),
+			Tip:        uint256.NewInt(1000000000),
+			FeeCap:     uint256.NewInt(2000000000),
+			AccessList: accessList,
+		},
+		"dynamic fee deploy": &types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{
+				Nonce: 4,
+				Gas:   300000,
+				Value: uint256.NewInt(0),
+				Data:  common.FromHex("0x6080604052"),
+				V:     *uint256.NewInt(1),
+				R:     *uint256.NewInt(11),
+				S:     *uint256.NewInt(12),
+			},
+			ChainID: uint256.NewInt(1000),
+			Tip:     uint256.NewInt(1),
+			FeeCap:  uint256.NewInt(1),
+		},
+	}
+}
+
+func Test_EncodeTypedTxsToBatchL2DataAndBack(t *testing.T) {
+	vectors := map[string]string{
+		"access list":        "01f8808203e802843b9aca00825208941275fbb540c8efc58b812ba83b0d0b8b9917ae980405f85bf859944d5cf5032b2a844602278b01199ed191a86c93fff842a00000000000000000000000000000000000000000000000000000000000000001a00000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000700000000000000000000000000000000000000000000000000000000000000081cff",
+		"dynamic fee":        "02f88a8203e803843b9aca008477359400830186a0941275fbb540c8efc58b812ba83b0d0b8b9917ae988084188ec356f85bf859944d5cf5032b2a844602278b01199ed191a86c93fff842a00000000000000000000000000000000000000000000000000000000000000001a000000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000009000000000000000000000000000000000000000000000000000000000000000a1bff",
+		"dynamic fee deploy": "02d38203e8040101830493e08080856080604052c0000000000000000000000000000000000000000000000000000000000000000b000000000000000000000000000000000000000000000000000000000000000c1cff",
+	}
+
+	for name, tx := range typedTestTransactions() {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := TransactionToL2Data(tx, uint16(constants.ForkId14TypedTxs), 255)
+			require.NoError(t, err)
+			require.Equal(t, vectors[name], hex.EncodeToString(encoded))
+
+			decoded, err := DecodeBatchL2Blocks(encoded, uint64(constants.ForkId14TypedTxs))
+			require.NoError(t, err)
+			require.Len(t, decoded, 1)
+			require.Len(t, decoded[0].Transactions, 1)
+			require.Equal(t, tx, decoded[0].Transactions[0])
+			require.Equal(t, []uint8{255}, decoded[0].EffectiveGasPricePercentages)
+
+			// before their fork typed transactions are still encoded, with their legacy fields, as they were for
+			// normalcy chains
+			legacyEncoded, err := TransactionToL2Data(tx, uint16(constants.ForkId13Durian), 255)
+			require.NoError(t, err)
+			require.NotEqual(t, encoded, legacyEncoded)
+			_, err = DecodeBatchL2Blocks(encoded, uint64(constants.ForkId13Durian))
+			require.ErrorIs(t, err, ErrInvalidData)
+		})
+	}
+}
+
+func Test_BlockBatchL2DataWithTypedTxs(t *testing.T) {
+	toAddress := common.HexToAddress("0x1")
+	legacy := &types.LegacyTx{
+		CommonTx: types.CommonTx{
+			ChainID: uint256.NewInt(1000),
+			Nonce:   1,
+			Gas:     21000,
+			To:      &toAddress,
+			Value:   uint256.NewInt(4),
+			Data:    []byte{5},
+			V:       *uint256.NewInt(2036),
+			R:       *uint256.NewInt(7),
+			S:       *uint256.NewInt(8),
+		},
+		GasPrice: uint256.NewInt(100),
+	}
+	typed := typedTestTransactions()
+
+	var batchL2Data []byte
+	blocks := [][]BatchTxData{
+		{{Transaction: legacy, EffectiveGasPricePercentage: 255}, {Transaction: typed["dynamic fee"], EffectiveGasPricePercentage: 128}},
+		{},
+		{{Transaction: typed["access list"], EffectiveGasPricePercentage: 1}, {Transaction: typed["dynamic fee deploy"], EffectiveGasPricePercentage: 2}},
+	}
+	for i, block := range blocks {
+		data, err := GenerateBlockBatchL2Data(uint16(constants.ForkId14TypedTxs), uint32(i), uint32(i), block)
+		require.NoError(t, err)
+		batchL2Data = append(batchL2Data, data...)
+	}
+
+	decoded, err := DecodeBatchL2Blocks(batchL2Data, uint64(constants.ForkId14TypedTxs))
+	require.NoError(t, err)
+	require.Len(t, decoded, len(blocks))
+	for i, block := range blocks {
+		require.Equal(t, uint32(i), decoded[i].DeltaTimestamp)
+		require.Len(t, decoded[i].Transactions, len(block))
+		for j, txData := range block {
+			require.Equal(t, txData.Transaction, decoded[i].Transactions[j])
+			require.Equal(t, txData.EffectiveGasPricePercentage, decoded[i].EffectiveGasPricePercentages[j])
+		}
+	}
+}
+
+func TestDecodeTypedTx(t *testing.T) {
+	for name, tx := range typedTestTransactions() {
+		t.Run(name, func(t *testing.T) {
+			// the datastream carries transactions in their canonical rlp encoding
+			var buf bytes.Buffer
+			require.NoError(t, tx.EncodeRLP(&buf))
+
+			decoded, pct, err := DecodeTx(buf.Bytes(), 200, uint64(constants.ForkId14TypedTxs))
+			require.NoError(t, err)
+			require.Equal(t, uint8(200), pct)
+			require.Equal(t, tx.Hash(), decoded.Hash())
+
+			// typed transactions accepted on london chains come through the datastream before their fork too
+			decoded, _, err = DecodeTx(buf.Bytes(), 200, uint64(constants.ForkId13Durian))
+			require.NoError(t, err)
+			require.Equal(t, tx.Hash(), decoded.Hash())
+		})
+	}
+}
+
+func TestComputeL2TxHashForTx(t *testing.T) {
+	from := common.HexToAddress("0x4d5Cf5032B2a844602278b01199ED191A86c93ff")
+	expected := map[string]string{
+		"access list":        "0x69b9bf12579d61eb8d7f52d2265a9783bba2fca8ceee12c125e9ccb765a3f5de",
+		"dynamic fee":        "0x829de169a9501331c69b627b5ca9153ccad3f066269303508e0de97e2361f25c",
+		"dynamic fee deploy": "0xd2a0feacf2d8e5c95f4d890326193355a1054453c988cdb82a212aa18b5fbc08",
+	}
+	for name, tx := range typedTestTransactions() {
+		hash, err := ComputeL2TxHashForTx(tx, &from)
+		require.NoError(t, err)
+		require.Equal(t, common.HexToHash(expected[name]), hash, name)
+	}
+
+	// legacy transactions hash the same as before
+	to := common.HexToAddress("0x1275fbb540c8efc58b812ba83b0d0b8b9917ae98")
+	legacy := &types.LegacyTx{
+		CommonTx: types.CommonTx{
+			ChainID: uint256.NewInt(1000),
+			Gas:     30000000,
+			To:      &to,
+			Value:   uint256.NewInt(0),
+			Data:    common.FromHex("0x188ec356"),
+			V:       *uint256.NewInt(2036),
+		},
+		GasPrice: uint256.NewInt(1000000000),
+	}
+	hash, err := ComputeL2TxHashForTx(legacy, &from)
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0xf3de9c9f50d72933104d5bb109915d93e4958117de78c9a7d1a58b5c6e4cbb77"), hash)
+}
+
+func TestTypedTxSenderSurvivesBatchL2Data(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1000))
+
+	for name, tx := range typedTestTransactions() {
+		t.Run(name, func(t *testing.T) {
+			signed, err := types.SignTx(tx, *signer, key)
+			require.NoError(t, err)
+
+			encoded, err := TransactionToL2Data(signed, uint16(constants.ForkId14TypedTxs), 255)
+			require.NoError(t, err)
+			decoded, err := DecodeBatchL2Blocks(encoded, uint64(constants.ForkId14TypedTxs))
+			require.NoError(t, err)
+
+			decodedTx := decoded[0].Transactions[0]
+			require.Equal(t, signed.Hash(), decodedTx.Hash())
+			sender, err := decodedTx.Sender(*signer)
+			require.NoError(t, err)
+			require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), sender)
+		})
+	}
+}
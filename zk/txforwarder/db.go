@@ -0,0 +1,84 @@
+package txforwarder
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	mdbx2 "github.com/erigontech/mdbx-go/mdbx"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	TxForwarderDB kv.Label = 254
+
+	forwarderFolder = "txforwarder"
+
+	// ForwardingQueue holds the forwarded transactions by hash until they are mined or found invalid
+	ForwardingQueue = "ForwardingQueue"
+)
+
+var TxForwarderTablesCfg = kv.TableCfg{
+	ForwardingQueue: kv.TableCfgItem{},
+}
+
+// OpenTxForwarderDB opens the forwarding queue database in the given data directory
+func OpenTxForwarderDB(ctx context.Context, dataDir string) (kv.RwDB, error) {
+	return mdbx.NewMDBX(log.New()).Label(TxForwarderDB).Path(filepath.Join(dataDir, forwarderFolder)).
+		WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg { return TxForwarderTablesCfg }).
+		Flags(func(f uint) uint { return f ^ mdbx2.Durable | mdbx2.SafeNoSync }).
+		GrowthStep(16 * datasize.MB).
+		SyncPeriod(30 * time.Second).
+		Open(ctx)
+}
+
+type entryState byte
+
+const (
+	// stateQueued has not been accepted by the sequencer yet
+	stateQueued entryState = iota
+	// stateForwarded was accepted by the sequencer and is waiting to be mined
+	stateForwarded
+)
+
+type entry struct {
+	addedAt     time.Time
+	nextAttempt time.Time
+	attempts    uint32
+	state       entryState
+	tx          []byte
+
+	// inFlight is set while a request for the entry is outstanding, it is not persisted
+	inFlight bool
+}
+
+// entries are stored as addedAt | nextAttempt | attempts | state | tx
+const entryHeaderLen = 8 + 8 + 4 + 1
+
+func encodeEntry(e *entry) []byte {
+	value := make([]byte, entryHeaderLen, entryHeaderLen+len(e.tx))
+	binary.BigEndian.PutUint64(value[0:8], uint64(e.addedAt.UnixNano()))
+	binary.BigEndian.PutUint64(value[8:16], uint64(e.nextAttempt.UnixNano()))
+	binary.BigEndian.PutUint32(value[16:20], e.attempts)
+	value[20] = byte(e.state)
+	return append(value, e.tx...)
+}
+
+func decodeEntry(value []byte) (*entry, error) {
+	if len(value) <= entryHeaderLen {
+		return nil, fmt.Errorf("invalid forwarding queue entry length %d", len(value))
+	}
+	return &entry{
+		addedAt:     time.Unix(0, int64(binary.BigEndian.Uint64(value[0:8]))),
+		nextAttempt: time.Unix(0, int64(binary.BigEndian.Uint64(value[8:16]))),
+		attempts:    binary.BigEndian.Uint32(value[16:20]),
+		state:       entryState(value[20]),
+		tx:          common.CopyBytes(value[entryHeaderLen:]),
+	}, nil
+}
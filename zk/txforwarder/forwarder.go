@@ -0,0 +1,380 @@
+package txforwarder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutil"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/metrics"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/zkevm/jsonrpc/client"
+)
+
+var (
+	awaitingForwardGauge   = metrics.GetOrCreateGauge(`zkevm_tx_forwarding_queue_depth{state="awaiting_forward"}`)
+	awaitingInclusionGauge = metrics.GetOrCreateGauge(`zkevm_tx_forwarding_queue_depth{state="awaiting_inclusion"}`)
+)
+
+type Config struct {
+	// SequencerRpcUrl returns the url of the sequencer that receives the transactions and is asked whether they were
+	// mined.  It is resolved on every request as it changes when the sequencer lease is handed over, and is empty while
+	// the node is sequencing itself.
+	SequencerRpcUrl func() string
+	// MaxSize is the number of transactions kept, once full transactions are sent once without retries
+	MaxSize int
+	// RetryInterval is the delay before the first retry, it doubles on every attempt up to MaxRetryInterval
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+// Status is a snapshot of the forwarding queue
+type Status struct {
+	Enabled               bool   `json:"enabled"`
+	QueueDepth            int    `json:"queueDepth"`
+	AwaitingForward       int    `json:"awaitingForward"`
+	AwaitingInclusion     int    `json:"awaitingInclusion"`
+	OldestEntryAgeSeconds uint64 `json:"oldestEntryAgeSeconds"`
+	LastError             string `json:"lastError,omitempty"`
+}
+
+// rejectedError is returned when the sequencer answers with an rpc error, the transaction will never be accepted
+type rejectedError struct {
+	message string
+}
+
+func (e *rejectedError) Error() string {
+	return fmt.Sprintf("RPC error response: %s", e.message)
+}
+
+// errNoSequencer is returned while the node holds the sequencer lease itself, the queue is kept for the next holder
+var errNoSequencer = errors.New("no sequencer to forward to")
+
+// Forwarder keeps the transactions an rpc node forwards to the sequencer until they are mined.  Transactions the
+// sequencer cannot be reached for are retried with backoff, and the ones the sequencer forgot about, usually because it
+// restarted, are sent to it again.  The queue is persisted so it survives restarts of the rpc node too.
+type Forwarder struct {
+	cfg Config
+	db  kv.RwDB
+
+	mtx       sync.Mutex
+	entries   map[common.Hash]*entry
+	lastError string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	running  atomic.Bool
+}
+
+// New opens the forwarding queue in the given data directory and loads the transactions left from a previous run
+func New(ctx context.Context, dataDir string, cfg Config) (*Forwarder, error) {
+	db, err := OpenTxForwarderDB(ctx, dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Forwarder{
+		cfg:     cfg,
+		db:      db,
+		entries: make(map[common.Hash]*entry),
+		stop:    make(chan struct{}),
+	}
+
+	// whatever happened while we were down, check every transaction again straight away
+	now := time.Now()
+	if err = db.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(ForwardingQueue, nil, func(k, v []byte) error {
+			e, err := decodeEntry(v)
+			if err != nil {
+				return err
+			}
+			e.nextAttempt = now
+			f.entries[common.BytesToHash(k)] = e
+			return nil
+		})
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if len(f.entries) > 0 {
+		log.Info("[TxForwarder] Loaded forwarding queue", "transactions", len(f.entries))
+	}
+
+	return f, nil
+}
+
+func (f *Forwarder) Start() {
+	if !f.running.CompareAndSwap(false, true) {
+		return
+	}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.cfg.RetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				f.process(time.Now())
+			}
+		}
+	}()
+}
+
+func (f *Forwarder) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+		f.wg.Wait()
+		f.running.Store(false)
+		f.db.Close()
+	})
+}
+
+// Forward sends the transaction to the sequencer and keeps it until it is mined.  A transaction already in the queue is
+// not sent again.  When the sequencer cannot be reached the hash is returned and the transaction is retried in the
+// background, an error is only returned when the sequencer rejects it.
+func (f *Forwarder) Forward(encodedTx []byte) (common.Hash, error) {
+	txn, err := types.DecodeWrappedTransaction(encodedTx)
+	if err != nil {
+		// leave it to the sequencer to report what is wrong with it
+		return sendRawTransaction(f.cfg.SequencerRpcUrl(), encodedTx)
+	}
+	hash := txn.Hash()
+
+	f.mtx.Lock()
+	if _, ok := f.entries[hash]; ok {
+		f.mtx.Unlock()
+		return hash, nil
+	}
+	if len(f.entries) >= f.cfg.MaxSize {
+		f.mtx.Unlock()
+		log.Warn("[TxForwarder] Forwarding queue is full, sending transaction without retries", "hash", hash)
+		return sendRawTransaction(f.cfg.SequencerRpcUrl(), encodedTx)
+	}
+	now := time.Now()
+	e := &entry{addedAt: now, nextAttempt: now, state: stateQueued, tx: common.CopyBytes(encodedTx), inFlight: true}
+	f.entries[hash] = e
+	f.persist(hash, e)
+	f.mtx.Unlock()
+
+	return hash, f.send(hash, e, now)
+}
+
+// send sends an entry to the sequencer, on success it waits for inclusion, on rejection it is dropped and on any other
+// failure it is retried later
+func (f *Forwarder) send(hash common.Hash, e *entry, now time.Time) error {
+	_, err := sendRawTransaction(f.cfg.SequencerRpcUrl(), e.tx)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	e.inFlight = false
+
+	var rejected *rejectedError
+	switch {
+	case err == nil || isAlreadyKnown(err):
+		e.state = stateForwarded
+		e.attempts = 0
+		e.nextAttempt = now.Add(f.cfg.RetryInterval)
+		f.persist(hash, e)
+		return nil
+	case errors.As(err, &rejected):
+		f.remove(hash)
+		return err
+	default:
+		f.retryLater(hash, e, now, err)
+		return nil
+	}
+}
+
+// checkInclusion asks the sequencer about a forwarded entry, mined entries are dropped and the ones the sequencer does
+// not know about are sent again
+func (f *Forwarder) checkInclusion(hash common.Hash, e *entry, now time.Time) {
+	url := f.cfg.SequencerRpcUrl()
+	if url == "" {
+		f.mtx.Lock()
+		f.retryLater(hash, e, now, errNoSequencer)
+		f.mtx.Unlock()
+		return
+	}
+
+	res, err := client.JSONRPCCall(url, "eth_getTransactionByHash", hash)
+	if err == nil && res.Error != nil {
+		err = &rejectedError{message: res.Error.Message}
+	}
+	if err != nil {
+		f.mtx.Lock()
+		f.retryLater(hash, e, now, err)
+		f.mtx.Unlock()
+		return
+	}
+
+	var txn *struct {
+		BlockNumber *hexutil.Big `json:"blockNumber"`
+	}
+	if err = json.Unmarshal(res.Result, &txn); err != nil {
+		f.mtx.Lock()
+		f.retryLater(hash, e, now, err)
+		f.mtx.Unlock()
+		return
+	}
+
+	if txn == nil {
+		log.Info("[TxForwarder] Sequencer does not know the transaction, sending it again", "hash", hash)
+		if err = f.send(hash, e, now); err != nil {
+			log.Info("[TxForwarder] Dropping transaction rejected by the sequencer", "hash", hash, "err", err)
+		}
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if txn.BlockNumber != nil {
+		log.Debug("[TxForwarder] Transaction mined", "hash", hash, "block", txn.BlockNumber.ToInt())
+		f.remove(hash)
+		return
+	}
+
+	// still pending in the sequencer pool
+	e.inFlight = false
+	e.nextAttempt = now.Add(f.backoff(e.attempts))
+	e.attempts++
+	f.persist(hash, e)
+}
+
+// process retries the queued entries and checks on the forwarded ones that are due
+func (f *Forwarder) process(now time.Time) {
+	f.mtx.Lock()
+	due := make(map[common.Hash]*entry)
+	for hash, e := range f.entries {
+		if !e.inFlight && !now.Before(e.nextAttempt) {
+			e.inFlight = true
+			due[hash] = e
+		}
+	}
+	f.mtx.Unlock()
+
+	for hash, e := range due {
+		select {
+		case <-f.stop:
+			return
+		default:
+		}
+
+		if e.state == stateQueued {
+			if err := f.send(hash, e, now); err != nil {
+				log.Info("[TxForwarder] Dropping transaction rejected by the sequencer", "hash", hash, "err", err)
+			}
+		} else {
+			f.checkInclusion(hash, e, now)
+		}
+	}
+
+	status := f.Status()
+	awaitingForwardGauge.SetInt(status.AwaitingForward)
+	awaitingInclusionGauge.SetInt(status.AwaitingInclusion)
+}
+
+// Status returns a snapshot of the queue, a nil forwarder reports the queue as disabled
+func (f *Forwarder) Status() *Status {
+	if f == nil {
+		return &Status{}
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	status := &Status{Enabled: true, QueueDepth: len(f.entries), LastError: f.lastError}
+	var oldest time.Time
+	for _, e := range f.entries {
+		if e.state == stateQueued {
+			status.AwaitingForward++
+		} else {
+			status.AwaitingInclusion++
+		}
+		if oldest.IsZero() || e.addedAt.Before(oldest) {
+			oldest = e.addedAt
+		}
+	}
+	if !oldest.IsZero() {
+		status.OldestEntryAgeSeconds = uint64(time.Since(oldest).Seconds())
+	}
+
+	return status
+}
+
+// retryLater must be called with the lock held
+func (f *Forwarder) retryLater(hash common.Hash, e *entry, now time.Time, err error) {
+	log.Debug("[TxForwarder] Sequencer request failed, retrying later", "hash", hash, "attempts", e.attempts+1, "err", err)
+	f.lastError = err.Error()
+	e.inFlight = false
+	e.nextAttempt = now.Add(f.backoff(e.attempts))
+	e.attempts++
+	f.persist(hash, e)
+}
+
+// backoff doubles the retry interval on every attempt up to the max retry interval
+func (f *Forwarder) backoff(attempts uint32) time.Duration {
+	if attempts >= 32 {
+		return f.cfg.MaxRetryInterval
+	}
+	d := f.cfg.RetryInterval << attempts
+	if d <= 0 || d > f.cfg.MaxRetryInterval {
+		return f.cfg.MaxRetryInterval
+	}
+	return d
+}
+
+// persist and remove must be called with the lock held, the in memory queue stays authoritative if the db write fails
+func (f *Forwarder) persist(hash common.Hash, e *entry) {
+	if err := f.db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(ForwardingQueue, hash.Bytes(), encodeEntry(e))
+	}); err != nil {
+		log.Error("[TxForwarder] Failed to persist transaction", "hash", hash, "err", err)
+	}
+}
+
+func (f *Forwarder) remove(hash common.Hash) {
+	delete(f.entries, hash)
+	if err := f.db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(ForwardingQueue, hash.Bytes())
+	}); err != nil {
+		log.Error("[TxForwarder] Failed to remove transaction", "hash", hash, "err", err)
+	}
+}
+
+func sendRawTransaction(url string, encodedTx []byte) (common.Hash, error) {
+	if url == "" {
+		return common.Hash{}, errNoSequencer
+	}
+
+	res, err := client.JSONRPCCall(url, "eth_sendRawTransaction", hexutility.Bytes(encodedTx))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if res.Error != nil {
+		return common.Hash{}, &rejectedError{message: res.Error.Message}
+	}
+
+	//hash comes in escaped quotes, so we trim them here
+	return common.HexToHash(strings.Trim(string(res.Result), "\"")), nil
+}
+
+// isAlreadyKnown checks for the pool error returned when the sequencer already has the transaction
+func isAlreadyKnown(err error) bool {
+	return strings.Contains(err.Error(), "already known")
+}
@@ -0,0 +1,233 @@
+package txforwarder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// fakeSequencer answers eth_sendRawTransaction and eth_getTransactionByHash from its own pool
+type fakeSequencer struct {
+	mtx       sync.Mutex
+	pool      map[common.Hash]bool // hash -> mined
+	sent      int
+	rejectErr string
+}
+
+func newFakeSequencer(t *testing.T) (*fakeSequencer, *httptest.Server) {
+	s := &fakeSequencer{pool: map[common.Hash]bool{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		var result interface{}
+		var rpcErr interface{}
+		switch req.Method {
+		case "eth_sendRawTransaction":
+			s.sent++
+			if s.rejectErr != "" {
+				rpcErr = map[string]interface{}{"code": -32000, "message": s.rejectErr}
+				break
+			}
+			var encoded string
+			require.NoError(t, json.Unmarshal(req.Params[0], &encoded))
+			txn, err := types.DecodeWrappedTransaction(common.FromHex(encoded))
+			require.NoError(t, err)
+			if _, ok := s.pool[txn.Hash()]; ok {
+				rpcErr = map[string]interface{}{"code": -32000, "message": "ALREADY_EXISTS: already known"}
+				break
+			}
+			s.pool[txn.Hash()] = false
+			result = txn.Hash()
+		case "eth_getTransactionByHash":
+			var hash common.Hash
+			require.NoError(t, json.Unmarshal(req.Params[0], &hash))
+			if mined, ok := s.pool[hash]; ok {
+				txn := map[string]interface{}{"hash": hash, "blockNumber": nil}
+				if mined {
+					txn["blockNumber"] = "0x1"
+				}
+				result = txn
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result, "error": rpcErr})
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func newTestForwarder(t *testing.T, dataDir, url string) *Forwarder {
+	f, err := New(context.Background(), dataDir, Config{
+		SequencerRpcUrl:  func() string { return url },
+		MaxSize:          10,
+		RetryInterval:    time.Second,
+		MaxRetryInterval: 4 * time.Second,
+	})
+	require.NoError(t, err)
+	return f
+}
+
+func encodedTestTx(t *testing.T, nonce uint64) []byte {
+	var buf bytes.Buffer
+	txn := types.NewTransaction(nonce, common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+	require.NoError(t, txn.MarshalBinary(&buf))
+	return buf.Bytes()
+}
+
+func TestForwardDeduplicates(t *testing.T) {
+	sequencer, server := newFakeSequencer(t)
+	f := newTestForwarder(t, t.TempDir(), server.URL)
+	defer f.Stop()
+
+	encoded := encodedTestTx(t, 0)
+	hash, err := f.Forward(encoded)
+	require.NoError(t, err)
+	again, err := f.Forward(encoded)
+	require.NoError(t, err)
+
+	require.Equal(t, hash, again)
+	require.Equal(t, 1, sequencer.sent)
+	require.Equal(t, &Status{Enabled: true, QueueDepth: 1, AwaitingInclusion: 1}, f.Status())
+}
+
+func TestForwardRejected(t *testing.T) {
+	sequencer, server := newFakeSequencer(t)
+	sequencer.rejectErr = "INVALID: nonce too low"
+	f := newTestForwarder(t, t.TempDir(), server.URL)
+	defer f.Stop()
+
+	_, err := f.Forward(encodedTestTx(t, 0))
+	require.EqualError(t, err, "RPC error response: INVALID: nonce too low")
+	require.Equal(t, 0, f.Status().QueueDepth)
+}
+
+func TestForwardRetriesUntilMined(t *testing.T) {
+	sequencer, server := newFakeSequencer(t)
+	url := server.URL
+	server.Close()
+
+	dataDir := t.TempDir()
+	f := newTestForwarder(t, dataDir, url)
+
+	// the sequencer is down, the transaction is kept and retried with backoff
+	hash, err := f.Forward(encodedTestTx(t, 0))
+	require.NoError(t, err)
+	status := f.Status()
+	require.Equal(t, 1, status.AwaitingForward)
+	require.NotEmpty(t, status.LastError)
+
+	now := time.Now().Add(time.Minute)
+	f.process(now)
+	require.Equal(t, uint32(2), f.entries[hash].attempts)
+	require.Equal(t, now.Add(2*time.Second), f.entries[hash].nextAttempt)
+
+	// the queue survives a restart and is sent once the sequencer is back
+	f.Stop()
+	server = httptest.NewServer(server.Config.Handler)
+	defer server.Close()
+	f = newTestForwarder(t, dataDir, server.URL)
+	defer f.Stop()
+	require.Equal(t, 1, f.Status().AwaitingForward)
+
+	f.process(time.Now())
+	require.Equal(t, 1, sequencer.sent)
+	require.Equal(t, 1, f.Status().AwaitingInclusion)
+
+	// the sequencer restarted and lost its pool, the transaction is sent again
+	sequencer.pool = map[common.Hash]bool{}
+	f.process(time.Now().Add(time.Minute))
+	require.Equal(t, 2, sequencer.sent)
+	require.Contains(t, sequencer.pool, hash)
+
+	// still pending, nothing to do
+	f.process(time.Now().Add(2 * time.Minute))
+	require.Equal(t, 2, sequencer.sent)
+	require.Equal(t, 1, f.Status().QueueDepth)
+
+	sequencer.pool[hash] = true
+	f.process(time.Now().Add(3 * time.Minute))
+	require.Equal(t, 0, f.Status().QueueDepth)
+}
+
+func TestForwardQueueFull(t *testing.T) {
+	sequencer, server := newFakeSequencer(t)
+	f := newTestForwarder(t, t.TempDir(), server.URL)
+	defer f.Stop()
+	f.cfg.MaxSize = 1
+
+	_, err := f.Forward(encodedTestTx(t, 0))
+	require.NoError(t, err)
+	_, err = f.Forward(encodedTestTx(t, 1))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, sequencer.sent)
+	require.Equal(t, 1, f.Status().QueueDepth)
+}
+
+func TestBackoff(t *testing.T) {
+	f := &Forwarder{cfg: Config{RetryInterval: time.Second, MaxRetryInterval: 5 * time.Second}}
+	require.Equal(t, time.Second, f.backoff(0))
+	require.Equal(t, 4*time.Second, f.backoff(2))
+	require.Equal(t, 5*time.Second, f.backoff(3))
+	require.Equal(t, 5*time.Second, f.backoff(100))
+}
+
+func TestForwardFollowsSequencerUrl(t *testing.T) {
+	first, firstServer := newFakeSequencer(t)
+	second, secondServer := newFakeSequencer(t)
+
+	url := firstServer.URL
+	f, err := New(context.Background(), t.TempDir(), Config{
+		SequencerRpcUrl:  func() string { return url },
+		MaxSize:          10,
+		RetryInterval:    time.Second,
+		MaxRetryInterval: 4 * time.Second,
+	})
+	require.NoError(t, err)
+	defer f.Stop()
+
+	_, err = f.Forward(encodedTestTx(t, 0))
+	require.NoError(t, err)
+	require.Equal(t, 1, first.sent)
+
+	// the node holds the lease, the queue is kept rather than sent anywhere
+	url = ""
+	hash, err := f.Forward(encodedTestTx(t, 1))
+	require.NoError(t, err)
+	require.Equal(t, 1, f.Status().AwaitingForward)
+	require.Equal(t, errNoSequencer.Error(), f.Status().LastError)
+
+	// the lease moved on, the queue is sent to the new holder
+	url = secondServer.URL
+	f.process(time.Now().Add(time.Minute))
+	require.Equal(t, 1, first.sent)
+	require.Equal(t, 2, second.sent)
+	require.Contains(t, second.pool, hash)
+}
+
+func TestStopTwice(t *testing.T) {
+	_, server := newFakeSequencer(t)
+	f := newTestForwarder(t, t.TempDir(), server.URL)
+	f.Start()
+	f.Start()
+	f.Stop()
+	f.Stop()
+}
@@ -26,6 +26,8 @@ const (
 	Allowlist          = "Allowlist"
 	BlockList          = "BlockList"
 	PolicyTransactions = "PolicyTransactions"
+	PolicyWindows      = "PolicyWindows"
+	ScheduledModes     = "ScheduledModes"
 )
 
 func (t ACLTable) String() string {
@@ -42,6 +44,10 @@ func ResolveACLTable(table string) (ACLTable, error) {
 		return BlockList, nil
 	case "policytransactions":
 		return PolicyTransactions, nil
+	case "policywindows":
+		return PolicyWindows, nil
+	case "scheduledmodes":
+		return ScheduledModes, nil
 	default:
 		return "", errUnknownACLTable
 	}
@@ -92,15 +98,19 @@ var (
 		Allowlist,
 		BlockList,
 		PolicyTransactions,
+		PolicyWindows,
+		ScheduledModes,
 	}
 
 	ACLTablesCfg = kv.TableCfg{}
 
-	errInvalidMode        = errors.New("unsupported mode")
-	errUnsupportedACLType = errors.New("unsupported acl type")
-	errUnknownACLTable    = errors.New("unknown acl table")
-	errUnknownPolicy      = errors.New("unknown policy")
-	errWrongOperation     = errors.New("wrong operation")
+	errInvalidMode         = errors.New("unsupported mode")
+	errUnsupportedACLType  = errors.New("unsupported acl type")
+	errUnknownACLTable     = errors.New("unknown acl table")
+	errUnknownPolicy       = errors.New("unknown policy")
+	errWrongOperation      = errors.New("wrong operation")
+	errInvalidPolicyWindow = errors.New("policy window ends before it starts")
+	errScheduleInPast      = errors.New("scheduled mode change must be in the future")
 )
 
 const ACLDB kv.Label = 255
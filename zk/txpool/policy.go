@@ -226,11 +226,13 @@ func policyName(policy Policy) string {
 
 // DoesAccountHavePolicy checks if the given account has the given policy for the online ACL mode
 func DoesAccountHavePolicy(ctx context.Context, aclDB kv.RwDB, addr common.Address, policy Policy) (bool, error) {
-	hasPolicy, _, err := checkIfAccountHasPolicy(ctx, aclDB, addr, policy)
+	hasPolicy, _, err := checkIfAccountHasPolicy(ctx, aclDB, addr, policy, time.Now())
 	return hasPolicy, err
 }
 
-func checkIfAccountHasPolicy(ctx context.Context, aclDB kv.RwDB, addr common.Address, policy Policy) (bool, ACLMode, error) {
+// checkIfAccountHasPolicy checks the account policy in the mode active at the given time, a policy outside of its
+// window is treated as absent
+func checkIfAccountHasPolicy(ctx context.Context, aclDB kv.RwDB, addr common.Address, policy Policy, now time.Time) (bool, ACLMode, error) {
 	if !IsSupportedPolicy(policy) {
 		return false, DisabledMode, errUnknownPolicy
 	}
@@ -242,25 +244,24 @@ func checkIfAccountHasPolicy(ctx context.Context, aclDB kv.RwDB, addr common.Add
 	)
 
 	err := aclDB.View(ctx, func(tx kv.Tx) error {
-		value, err := tx.GetOne(Config, []byte("mode"))
+		var err error
+		mode, err = effectiveMode(tx, now)
 		if err != nil {
 			return err
 		}
 
-		if value == nil || string(value) == DisabledMode {
+		if mode == DisabledMode {
 			hasPolicy = true
 			return nil
 		}
 
-		mode = ACLMode(value)
-
-		table := BlockList
+		table, aclType := BlockList, BlockListTypeB
 		if mode == AllowlistMode {
-			table = Allowlist
+			table, aclType = Allowlist, AllowListTypeB
 		}
 
 		var policyBytes []byte
-		value, err = tx.GetOne(table, addr.Bytes())
+		value, err := tx.GetOne(table, addr.Bytes())
 		if err != nil {
 			return err
 		}
@@ -269,7 +270,11 @@ func checkIfAccountHasPolicy(ctx context.Context, aclDB kv.RwDB, addr common.Add
 		if policyBytes != nil && containsPolicy(policyBytes, policy) {
 			// If address is in the allowlist and has the policy, return true
 			// If address is in the blocklist and has the policy, return false
-			hasPolicy = true
+			window, err := getPolicyWindow(tx, aclType, addr, policy)
+			if err != nil {
+				return err
+			}
+			hasPolicy = window.Contains(now)
 		}
 
 		return nil
@@ -300,6 +305,11 @@ func UpdatePolicies(ctx context.Context, aclDB kv.RwDB, aclType string, addrs []
 				timeTx:    timeNow,
 			})
 
+			// the policies given are the final state of the address, without any window
+			if err := deletePolicyWindows(tx, ResolveACLTypeToBinary(aclType), addr); err != nil {
+				return err
+			}
+
 			if len(policies[i]) > 0 {
 				// just update the policies for the address to match the one provided
 				policyBytes := make([]byte, 0, len(policies[i]))
@@ -482,9 +492,18 @@ func (pt PolicyTransaction) ToString() string {
 
 // AddPolicy adds a policy to the ACL of given address
 func AddPolicy(ctx context.Context, aclDB kv.RwDB, aclType string, addr common.Address, policy Policy) error {
+	return AddPolicyWithWindow(ctx, aclDB, aclType, addr, policy, PolicyWindow{})
+}
+
+// AddPolicyWithWindow adds a policy to the ACL of given address that only applies within the given window.  Adding a
+// policy the address already has replaces its window.
+func AddPolicyWithWindow(ctx context.Context, aclDB kv.RwDB, aclType string, addr common.Address, policy Policy, window PolicyWindow) error {
 	if !IsSupportedPolicy(policy) {
 		return errUnknownPolicy
 	}
+	if err := window.validate(); err != nil {
+		return err
+	}
 
 	table, err := resolveTable(aclType)
 	if err != nil {
@@ -497,6 +516,10 @@ func AddPolicy(ctx context.Context, aclDB kv.RwDB, aclType string, addr common.A
 			return err
 		}
 
+		if err := putPolicyWindow(tx, ResolveACLTypeToBinary(aclType), addr, policy, window); err != nil {
+			return err
+		}
+
 		policyBytes := policy.ToByteArray()
 		if value == nil {
 			return tx.Put(table, addr.Bytes(), policyBytes)
@@ -543,6 +566,10 @@ func RemovePolicy(ctx context.Context, aclDB kv.RwDB, aclType string, addr commo
 			return nil
 		}
 
+		if err := putPolicyWindow(tx, ResolveACLTypeToBinary(aclType), addr, policy, PolicyWindow{}); err != nil {
+			return err
+		}
+
 		updatedPolicies := []byte{}
 
 		for _, p := range policies {
@@ -578,6 +605,7 @@ func ListContentAtACL(ctx context.Context, db kv.RwDB) ([]string, error) {
 	var bufferConfig bytes.Buffer
 	var bufferBlockList bytes.Buffer
 	var bufferAllowlist bytes.Buffer
+	var bufferScheduledModes bytes.Buffer
 
 	tables := db.AllTables()
 	buffer.WriteString(" \n")
@@ -598,10 +626,18 @@ func ListContentAtACL(ctx context.Context, db kv.RwDB) ([]string, error) {
 		// BlockList table
 		var BlockListContent strings.Builder
 		err = tx.ForEach(BlockList, nil, func(k, v []byte) error {
+			policies := policyMapping(v, policiesList)
+			windows, err := policyWindowsMapping(tx, BlockListTypeB, common.BytesToAddress(k))
+			if err != nil {
+				return err
+			}
+			if windows != "" {
+				policies += "\n" + windows
+			}
 			BlockListContent.WriteString(fmt.Sprintf(
 				"Key: %s, Value: {\n%s\n}\n",
 				hex.EncodeToString(k),
-				policyMapping(v, policiesList),
+				policies,
 			))
 			return nil
 		})
@@ -624,10 +660,18 @@ func ListContentAtACL(ctx context.Context, db kv.RwDB) ([]string, error) {
 		// Allowlist table
 		var AllowlistContent strings.Builder
 		err = tx.ForEach(Allowlist, nil, func(k, v []byte) error {
+			policies := policyMapping(v, policiesList)
+			windows, err := policyWindowsMapping(tx, AllowListTypeB, common.BytesToAddress(k))
+			if err != nil {
+				return err
+			}
+			if windows != "" {
+				policies += "\n" + windows
+			}
 			AllowlistContent.WriteString(fmt.Sprintf(
 				"Key: %s, Value: {\n%s\n}\n",
 				hex.EncodeToString(k),
-				policyMapping(v, policiesList),
+				policies,
 			))
 			return nil
 		})
@@ -648,6 +692,40 @@ func ListContentAtACL(ctx context.Context, db kv.RwDB) ([]string, error) {
 			bufferAllowlist.WriteString("\nAllowlist is empty")
 		}
 
+		// ScheduledModes table
+		now := time.Now()
+		var ScheduledModesContent strings.Builder
+		err = tx.ForEach(ScheduledModes, nil, func(k, v []byte) error {
+			activatesAt := bytesToTimestamp(k)
+			state := "pending"
+			if !activatesAt.After(now) {
+				state = "active"
+			}
+			ScheduledModesContent.WriteString(fmt.Sprintf(
+				"Mode: %s, At: %s, State: %s\n",
+				string(v),
+				activatesAt.UTC().Format(time.RFC3339),
+				state,
+			))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if ScheduledModesContent.String() != "" {
+			buffer.WriteString(fmt.Sprintf(
+				"\nScheduled modes\n%s",
+				ScheduledModesContent.String(),
+			))
+			bufferScheduledModes.WriteString(fmt.Sprintf(
+				"\nScheduled modes\n%s",
+				ScheduledModesContent.String(),
+			))
+		} else {
+			buffer.WriteString("\nNo scheduled modes")
+			bufferScheduledModes.WriteString("\nNo scheduled modes")
+		}
+
 		return err
 	})
 
@@ -655,6 +733,7 @@ func ListContentAtACL(ctx context.Context, db kv.RwDB) ([]string, error) {
 	combinedBuffers = append(combinedBuffers, bufferConfig.String())
 	combinedBuffers = append(combinedBuffers, bufferBlockList.String())
 	combinedBuffers = append(combinedBuffers, bufferAllowlist.String())
+	combinedBuffers = append(combinedBuffers, bufferScheduledModes.String())
 
 	return combinedBuffers, err
 }
@@ -667,6 +746,11 @@ func SetMode(ctx context.Context, aclDB kv.RwDB, mode string) error {
 	}
 
 	return aclDB.Update(ctx, func(tx kv.RwTx) error {
+		// a mode set by hand replaces the scheduled changes that are already active
+		if err := deleteActivatedModes(tx, time.Now()); err != nil {
+			return err
+		}
+
 		err := tx.Put(Config, []byte(modeKey), []byte(m))
 
 		// Timestamp bytes + single byte.
@@ -682,17 +766,13 @@ func SetMode(ctx context.Context, aclDB kv.RwDB, mode string) error {
 	})
 }
 
-// GetMode gets the mode of the ACL
+// GetMode gets the mode of the ACL currently in effect, taking scheduled mode changes into account
 func GetMode(ctx context.Context, aclDB kv.RwDB) (ACLMode, error) {
 	var mode ACLMode
 	err := aclDB.View(ctx, func(tx kv.Tx) error {
-		value, err := tx.GetOne(Config, []byte(modeKey))
-		if err != nil {
-			return err
-		}
-
-		mode = ACLMode(value)
-		return nil
+		var err error
+		mode, err = effectiveMode(tx, time.Now())
+		return err
 	})
 
 	return mode, err
@@ -723,10 +803,11 @@ func resolvePolicy(txn *types.TxSlot) Policy {
 
 type Validator struct {
 	aclDB kv.RwDB
+	now   func() time.Time
 }
 
 func NewPolicyValidator(aclDB kv.RwDB) *Validator {
-	return &Validator{aclDB: aclDB}
+	return &Validator{aclDB: aclDB, now: time.Now}
 }
 
 // IsActionAllowed checks if the given action is allowed for the given address
@@ -736,7 +817,7 @@ func (v *Validator) IsActionAllowed(ctx context.Context, addr common.Address, po
 		return false, err
 	}
 
-	hasPolicy, mode, err := checkIfAccountHasPolicy(ctx, v.aclDB, addr, p)
+	hasPolicy, mode, err := checkIfAccountHasPolicy(ctx, v.aclDB, addr, p, v.now())
 	if err != nil {
 		return false, err
 	}
@@ -697,3 +697,142 @@ func TestListContentAtACL(t *testing.T) {
 		})
 	}
 }
+
+func TestPolicyWindows(t *testing.T) {
+	db := newTestACLDB(t, "")
+	ctx := context.Background()
+
+	// windows are stored with a precision of a second
+	now := time.Unix(time.Now().Unix(), 0)
+	validator := NewPolicyValidator(db)
+	validator.now = func() time.Time { return now }
+
+	addr := common.HexToAddress("0x1234567890abcdef")
+	window := PolicyWindow{NotBefore: now.Add(time.Hour), NotAfter: now.Add(2 * time.Hour)}
+
+	t.Run("AddPolicyWithWindow - Invalid Window", func(t *testing.T) {
+		err := AddPolicyWithWindow(ctx, db, "allowlist", addr, Deploy, PolicyWindow{NotBefore: now, NotAfter: now.Add(-time.Second)})
+		require.ErrorIs(t, err, errInvalidPolicyWindow)
+	})
+
+	t.Run("isActionAllowed - AllowlistMode - Within Window", func(t *testing.T) {
+		require.NoError(t, SetMode(ctx, db, AllowlistMode))
+		require.NoError(t, AddPolicyWithWindow(ctx, db, "allowlist", addr, Deploy, window))
+
+		for _, tc := range []struct {
+			at      time.Time
+			allowed bool
+		}{
+			{now, false},
+			{window.NotBefore, true},
+			{window.NotAfter, true},
+			{window.NotAfter.Add(time.Second), false},
+		} {
+			now = tc.at
+			allowed, err := validator.IsActionAllowed(ctx, addr, Deploy.ToByte())
+			require.NoError(t, err)
+			require.Equal(t, tc.allowed, allowed, tc.at)
+		}
+	})
+
+	t.Run("isActionAllowed - BlocklistMode - Window Only Applies To Its ACL", func(t *testing.T) {
+		require.NoError(t, SetMode(ctx, db, BlocklistMode))
+		require.NoError(t, AddPolicy(ctx, db, "blocklist", addr, Deploy))
+
+		// the allowlist window is not carried over to the blocklist policy
+		now = window.NotAfter.Add(time.Second)
+		allowed, err := validator.IsActionAllowed(ctx, addr, Deploy.ToByte())
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("AddPolicy - Clears Window", func(t *testing.T) {
+		require.NoError(t, SetMode(ctx, db, AllowlistMode))
+		require.NoError(t, AddPolicy(ctx, db, "allowlist", addr, Deploy))
+
+		now = window.NotAfter.Add(time.Second)
+		allowed, err := validator.IsActionAllowed(ctx, addr, Deploy.ToByte())
+		require.NoError(t, err)
+		require.True(t, allowed)
+	})
+
+	t.Run("RemovePolicy - Removes Window", func(t *testing.T) {
+		require.NoError(t, AddPolicyWithWindow(ctx, db, "allowlist", addr, SendTx, window))
+		require.NoError(t, RemovePolicy(ctx, db, "allowlist", addr, SendTx))
+
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+			w, err := getPolicyWindow(tx, AllowListTypeB, addr, SendTx)
+			require.True(t, w.IsZero())
+			return err
+		}))
+	})
+
+	t.Run("ListContentAtACL - Shows Window", func(t *testing.T) {
+		require.NoError(t, AddPolicyWithWindow(ctx, db, "allowlist", addr, SendTx, window))
+
+		content, err := ListContentAtACL(ctx, db)
+		require.NoError(t, err)
+		require.Contains(t, content[3], "sendTx window: "+window.String())
+	})
+
+	t.Run("UpdatePolicies - Clears Windows", func(t *testing.T) {
+		require.NoError(t, UpdatePolicies(ctx, db, "allowlist", []common.Address{addr}, [][]Policy{{SendTx}}))
+
+		now = window.NotAfter.Add(time.Second)
+		allowed, err := validator.IsActionAllowed(ctx, addr, SendTx.ToByte())
+		require.NoError(t, err)
+		require.True(t, allowed)
+	})
+}
+
+func TestScheduledModes(t *testing.T) {
+	db := newTestACLDB(t, "")
+	ctx := context.Background()
+
+	start := time.Unix(time.Now().Unix(), 0)
+	now := start
+	validator := NewPolicyValidator(db)
+	validator.now = func() time.Time { return now }
+
+	addr := common.HexToAddress("0x1234567890abcdef")
+	require.NoError(t, AddPolicy(ctx, db, "blocklist", addr, SendTx))
+	require.NoError(t, SetMode(ctx, db, DisabledMode))
+
+	require.ErrorIs(t, ScheduleMode(ctx, db, BlocklistMode, start.Add(-time.Minute)), errScheduleInPast)
+	require.ErrorIs(t, ScheduleMode(ctx, db, "unknown", start.Add(time.Hour)), errInvalidMode)
+
+	require.NoError(t, ScheduleMode(ctx, db, BlocklistMode, start.Add(time.Hour)))
+	require.NoError(t, ScheduleMode(ctx, db, DisabledMode, start.Add(2*time.Hour)))
+
+	scheduled, err := GetScheduledModes(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, scheduled, 2)
+	require.Equal(t, ACLMode(BlocklistMode), scheduled[0].Mode)
+	require.Equal(t, start.Add(time.Hour).Unix(), scheduled[0].ActivatesAt.Unix())
+
+	for _, tc := range []struct {
+		at      time.Time
+		allowed bool
+	}{
+		{start, true},
+		{start.Add(time.Hour), false},
+		{start.Add(2 * time.Hour), true},
+	} {
+		now = tc.at
+		allowed, err := validator.IsActionAllowed(ctx, addr, SendTx.ToByte())
+		require.NoError(t, err)
+		require.Equal(t, tc.allowed, allowed, tc.at)
+	}
+
+	content, err := ListContentAtACL(ctx, db)
+	require.NoError(t, err)
+	require.Contains(t, content[4], "Mode: blocklist")
+	require.Contains(t, content[4], "State: pending")
+
+	// without the change back to disabled the blocklist stays in effect
+	require.NoError(t, CancelScheduledMode(ctx, db, start.Add(2*time.Hour)))
+	now = start.Add(3 * time.Hour)
+	allowed, err := validator.IsActionAllowed(ctx, addr, SendTx.ToByte())
+	require.NoError(t, err)
+	require.False(t, allowed)
+}
@@ -0,0 +1,214 @@
+package txpool
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// PolicyWindow bounds the time during which a policy applies, a zero bound leaves that side of the window open
+type PolicyWindow struct {
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+func (w PolicyWindow) IsZero() bool {
+	return w.NotBefore.IsZero() && w.NotAfter.IsZero()
+}
+
+// Contains checks if the policy applies at the given time, both bounds are inclusive
+func (w PolicyWindow) Contains(t time.Time) bool {
+	if !w.NotBefore.IsZero() && t.Before(w.NotBefore) {
+		return false
+	}
+	if !w.NotAfter.IsZero() && t.After(w.NotAfter) {
+		return false
+	}
+	return true
+}
+
+func (w PolicyWindow) validate() error {
+	if !w.NotBefore.IsZero() && !w.NotAfter.IsZero() && w.NotAfter.Before(w.NotBefore) {
+		return errInvalidPolicyWindow
+	}
+	return nil
+}
+
+func (w PolicyWindow) String() string {
+	bounds := make([]string, 0, 2)
+	if !w.NotBefore.IsZero() {
+		bounds = append(bounds, "not before "+w.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if !w.NotAfter.IsZero() {
+		bounds = append(bounds, "not after "+w.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(bounds, ", ")
+}
+
+// window bounds are stored as unix seconds, 0 is an open bound
+func windowBoundToBytes(t time.Time) []byte {
+	if t.IsZero() {
+		return make([]byte, 8)
+	}
+	return timestampToBytes(t)
+}
+
+func bytesToWindowBound(b []byte) time.Time {
+	if binary.BigEndian.Uint64(b) == 0 {
+		return time.Time{}
+	}
+	return bytesToTimestamp(b)
+}
+
+func encodePolicyWindow(w PolicyWindow) []byte {
+	return append(windowBoundToBytes(w.NotBefore), windowBoundToBytes(w.NotAfter)...)
+}
+
+func decodePolicyWindow(value []byte) (PolicyWindow, error) {
+	if len(value) != 16 {
+		return PolicyWindow{}, fmt.Errorf("invalid policy window length %d", len(value))
+	}
+	return PolicyWindow{
+		NotBefore: bytesToWindowBound(value[:8]),
+		NotAfter:  bytesToWindowBound(value[8:]),
+	}, nil
+}
+
+// policyWindowKey is the acl type, address and policy a window applies to
+func policyWindowKey(aclType ACLTypeBinary, addr common.Address, policy Policy) []byte {
+	key := make([]byte, 0, 22)
+	key = append(key, aclType.ToByte())
+	key = append(key, addr.Bytes()...)
+	return append(key, policy.ToByte())
+}
+
+func getPolicyWindow(tx kv.Tx, aclType ACLTypeBinary, addr common.Address, policy Policy) (PolicyWindow, error) {
+	value, err := tx.GetOne(PolicyWindows, policyWindowKey(aclType, addr, policy))
+	if err != nil || value == nil {
+		return PolicyWindow{}, err
+	}
+	return decodePolicyWindow(value)
+}
+
+func putPolicyWindow(tx kv.RwTx, aclType ACLTypeBinary, addr common.Address, policy Policy, window PolicyWindow) error {
+	key := policyWindowKey(aclType, addr, policy)
+	if window.IsZero() {
+		return tx.Delete(PolicyWindows, key)
+	}
+	return tx.Put(PolicyWindows, key, encodePolicyWindow(window))
+}
+
+// deletePolicyWindows removes the windows of every policy of the address in the given acl type
+func deletePolicyWindows(tx kv.RwTx, aclType ACLTypeBinary, addr common.Address) error {
+	for _, policy := range policiesList {
+		if err := tx.Delete(PolicyWindows, policyWindowKey(aclType, addr, policy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyWindowsMapping returns the windows set on the address policies, one line per policy in the style of policyMapping
+func policyWindowsMapping(tx kv.Tx, aclType ACLTypeBinary, addr common.Address) (string, error) {
+	var windows []string
+	for _, policy := range policiesList {
+		window, err := getPolicyWindow(tx, aclType, addr, policy)
+		if err != nil {
+			return "", err
+		}
+		if !window.IsZero() {
+			windows = append(windows, fmt.Sprintf("\t%s window: %s", policyName(policy), window.String()))
+		}
+	}
+	return strings.Join(windows, "\n"), nil
+}
+
+// ScheduledModeChange is a mode the ACL switches to once its activation time is reached
+type ScheduledModeChange struct {
+	Mode        ACLMode
+	ActivatesAt time.Time
+}
+
+// ScheduleMode schedules a change of the ACL mode at the given time.  Scheduling a change for a time that already has
+// one replaces it.
+func ScheduleMode(ctx context.Context, aclDB kv.RwDB, mode string, at time.Time) error {
+	m, err := ResolveACLMode(mode)
+	if err != nil {
+		return err
+	}
+	if !at.After(time.Now()) {
+		return errScheduleInPast
+	}
+
+	return aclDB.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(ScheduledModes, timestampToBytes(at), []byte(m))
+	})
+}
+
+// CancelScheduledMode removes the mode change scheduled at the given time
+func CancelScheduledMode(ctx context.Context, aclDB kv.RwDB, at time.Time) error {
+	return aclDB.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Delete(ScheduledModes, timestampToBytes(at))
+	})
+}
+
+// GetScheduledModes returns the scheduled mode changes in activation order, including the ones already active
+func GetScheduledModes(ctx context.Context, aclDB kv.RwDB) ([]ScheduledModeChange, error) {
+	var changes []ScheduledModeChange
+	err := aclDB.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(ScheduledModes, nil, func(k, v []byte) error {
+			changes = append(changes, ScheduledModeChange{Mode: ACLMode(v), ActivatesAt: bytesToTimestamp(k)})
+			return nil
+		})
+	})
+	return changes, err
+}
+
+// effectiveMode returns the mode of the ACL at the given time.  The latest scheduled change already activated takes
+// precedence over the configured mode, SetMode clears the activated changes so a mode set by hand is kept.
+func effectiveMode(tx kv.Tx, now time.Time) (ACLMode, error) {
+	value, err := tx.GetOne(Config, []byte(modeKey))
+	if err != nil {
+		return DisabledMode, err
+	}
+	mode := ACLMode(DisabledMode)
+	if value != nil {
+		mode = ACLMode(value)
+	}
+
+	nowBytes := timestampToBytes(now)
+	err = tx.ForEach(ScheduledModes, nil, func(k, v []byte) error {
+		if string(k) <= string(nowBytes) {
+			mode = ACLMode(v)
+		}
+		return nil
+	})
+	return mode, err
+}
+
+// deleteActivatedModes removes the scheduled mode changes that are already active
+func deleteActivatedModes(tx kv.RwTx, now time.Time) error {
+	nowBytes := timestampToBytes(now)
+	var activated [][]byte
+	err := tx.ForEach(ScheduledModes, nil, func(k, v []byte) error {
+		if string(k) <= string(nowBytes) {
+			activated = append(activated, common.CopyBytes(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range activated {
+		if err := tx.Delete(ScheduledModes, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
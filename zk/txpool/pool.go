@@ -33,6 +33,7 @@ import (
 	"time"
 
 	"github.com/ledgerwatch/erigon/zk/acl"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
 
 	"github.com/VictoriaMetrics/metrics"
 	mapset "github.com/deckarep/golang-set/v2"
@@ -235,14 +236,19 @@ type metaTx struct {
 	bestIndex                 int
 	worstIndex                int
 	timestamp                 uint64 // when it was added to pool
+	arrival                   uint64 // order in which it was added to the pool, used by the fifo ordering
 	created                   uint64 // unix timestamp of creation
 	subPool                   SubPoolMarker
 	currentSubPool            SubPoolType
 	alreadyYielded            bool
 }
 
+// metaTxArrivals is incremented for every transaction added to the pool to give it an arrival order, block numbers
+// are too coarse for this as most transactions in the pool arrive during the same block
+var metaTxArrivals atomic.Uint64
+
 func newMetaTx(slot *types.TxSlot, isLocal bool, timestmap uint64) *metaTx {
-	mt := &metaTx{Tx: slot, worstIndex: -1, bestIndex: -1, timestamp: timestmap, created: uint64(time.Now().Unix())}
+	mt := &metaTx{Tx: slot, worstIndex: -1, bestIndex: -1, timestamp: timestmap, arrival: metaTxArrivals.Add(1), created: uint64(time.Now().Unix())}
 	if isLocal {
 		mt.subPool = IsLocal
 	}
@@ -324,6 +330,7 @@ type TxPool struct {
 	cfg                     txpoolcfg.Config
 	chainID                 uint256.Int
 	lastSeenBlock           atomic.Uint64
+	lastSeenForkId          atomic.Uint64
 	started                 atomic.Bool
 	pendingBaseFee          atomic.Uint64
 	blockGasLimit           atomic.Uint64
@@ -345,6 +352,9 @@ type TxPool struct {
 
 	logLevel log.Lvl
 
+	// orderer decides the order in which pending transactions are yielded to the sequencer
+	orderer *txOrderer
+
 	// PoolMetrics contains metrics for tx/s in and out of the pool
 	// and a median wait time of tx/s waiting in the pool
 	metrics *Metrics
@@ -379,8 +389,18 @@ func New(newTxs chan types.Announcements, coreDB kv.RoDB, cfg txpoolcfg.Config,
 	}
 
 	logLevel := log.LvlInfo
+	ordering := OrderingPriorityFee
+	maxTxsPerSender := 0
 	if ethCfg.Zk != nil {
 		logLevel = ethCfg.Zk.LogLevel
+		if ordering, err = ParseTxOrdering(ethCfg.Zk.SequencerTxOrdering); err != nil {
+			return nil, err
+		}
+		maxTxsPerSender = ethCfg.Zk.SequencerMaxTxsPerSender
+	}
+	orderer, err := newTxOrderer(ordering, maxTxsPerSender)
+	if err != nil {
+		return nil, err
 	}
 
 	var policyValidator PolicyValidator
@@ -421,6 +441,7 @@ func New(newTxs chan types.Announcements, coreDB kv.RoDB, cfg txpoolcfg.Config,
 		limbo:                   newLimbo(),
 		logLevel:                logLevel,
 		policyValidator:         policyValidator,
+		orderer:                 orderer,
 		metrics:                 &Metrics{},
 	}, nil
 }
@@ -440,6 +461,7 @@ func (p *TxPool) OnNewBlock(ctx context.Context, stateChanges *remote.StateChang
 	defer p.lock.Unlock()
 
 	p.lastSeenBlock.Store(stateChanges.ChangeBatch[len(stateChanges.ChangeBatch)-1].BlockHeight)
+	p.updateLastSeenForkId(coreTx)
 	if !p.started.Load() {
 		if err := p.fromDB(ctx, tx, coreTx); err != nil {
 			return fmt.Errorf("loading txs from DB: %w", err)
@@ -712,12 +734,18 @@ func (p *TxPool) ResetYieldedStatus() {
 }
 
 func (p *TxPool) YieldBest(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableGas, availableBlobGas uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
-	return p.best(n, txs, tx, onTopOf, availableGas, availableBlobGas, toSkip)
+	return p.best(nil, n, txs, tx, onTopOf, availableGas, availableBlobGas, toSkip)
+}
+
+// YieldBestForBatch is YieldBest for the sequencer, the per sender cap counts every transaction yielded for the
+// batch rather than only those of this call
+func (p *TxPool) YieldBestForBatch(batchNo uint64, n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableGas, availableBlobGas uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
+	return p.best(&batchNo, n, txs, tx, onTopOf, availableGas, availableBlobGas, toSkip)
 }
 
 func (p *TxPool) PeekBest(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableGas, availableBlobGas uint64) (bool, error) {
 	set := mapset.NewThreadUnsafeSet[[32]byte]()
-	onTime, _, err := p.best(n, txs, tx, onTopOf, availableGas, availableBlobGas, set)
+	onTime, _, err := p.best(nil, n, txs, tx, onTopOf, availableGas, availableBlobGas, set)
 	return onTime, err
 }
 
@@ -755,7 +783,7 @@ func (p *TxPool) validateTx(txn *types.TxSlot, isLocal bool, stateCache kvcache.
 	}
 
 	isLondon := p.isLondon()
-	if !isLondon && txn.Type == 0x2 {
+	if !isLondon && txn.Type == 0x2 && !zktx.TxTypeSupported(txn.Type, p.lastSeenForkId.Load()) {
 		return UnsupportedTx
 	}
 
@@ -1451,6 +1479,7 @@ func MainLoop(ctx context.Context, db kv.RwDB, coreDB kv.RoDB, p *TxPool, newTxs
 			p.logStats()
 		case <-txIoTicker.C:
 			p.metrics.Update(p)
+			p.updateLimboMetrics()
 		case <-processRemoteTxsEvery.C:
 			if !p.Started() {
 				continue
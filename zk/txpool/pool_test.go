@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/common/u256"
@@ -244,3 +245,26 @@ func TestOnNewBlock(t *testing.T) {
 	assert.ErrorIs(t, io.EOF, err)
 	assert.Equal(t, 3, len(minedTxs.Txs))
 }
+
+func TestValidateTxTypedBeforeLondon(t *testing.T) {
+	ch := make(chan types.Announcements, 100)
+	_, coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	defer coreDB.Close()
+
+	path := fmt.Sprintf("/tmp/db-test-%v", time.Now().UTC().Format(time.RFC3339Nano))
+	aclsDB := newTestACLDB(t, path)
+	defer aclsDB.Close()
+
+	pool, err := New(ch, coreDB, txpoolcfg.DefaultConfig, &ethconfig.Defaults, kvcache.New(kvcache.DefaultCoherentConfig), *u256.N1, nil, nil, aclsDB)
+	require.NoError(t, err)
+	require.False(t, pool.isLondon())
+
+	// without gas the transaction fails right after the type check
+	txn := &types.TxSlot{Type: types.DynamicFeeTxType, FeeCap: *uint256.NewInt(300000)}
+	pool.lastSeenForkId.Store(uint64(chain.ForkId13Durian))
+	require.Equal(t, UnsupportedTx, pool.validateTx(txn, true, nil, common.Address{}))
+
+	// from the fork the batch l2 data carries them they are taken
+	pool.lastSeenForkId.Store(uint64(chain.ForkId14TypedTxs))
+	require.Equal(t, IntrinsicGas, pool.validateTx(txn, true, nil, common.Address{}))
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/ledgerwatch/erigon-lib/types"
 	types2 "github.com/ledgerwatch/erigon-lib/types"
 	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/zk/hermez_db"
+	zktx "github.com/ledgerwatch/erigon/zk/tx"
 	"github.com/ledgerwatch/erigon/zk/utils"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -154,8 +156,9 @@ func (p *TxPool) onSenderStateChange(senderID uint64, senderNonce uint64, sender
 }
 
 // zk: the implementation of best here is changed only to not take into account block gas limits as we don't care about
-// these in zk.  Instead we do a quick check on the transaction maximum gas in zk
-func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableGas, availableBlobGas uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
+// these in zk.  Instead we do a quick check on the transaction maximum gas in zk.  batchNo is the batch the
+// transactions are yielded for, nil when the sender cap only applies to this call.
+func (p *TxPool) best(batchNo *uint64, n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableGas, availableBlobGas uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -173,6 +176,7 @@ func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableG
 	isShanghai := p.isShanghai()
 	isLondon := p.isLondon()
 	_ = isLondon
+	forkId := p.lastSeenForkId.Load()
 	best := p.pending.best
 
 	txs.Resize(uint(cmp.Min(int(n), len(best.ms))))
@@ -180,14 +184,19 @@ func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableG
 	count := 0
 
 	p.pending.EnforceBestInvariants()
+	ordered := p.orderer.order(best.ms)
+	yieldedPerSender := make(map[uint64]int)
+	if batchNo != nil {
+		yieldedPerSender = p.orderer.batchSenderCounts(*batchNo)
+	}
 
-	for i := 0; count < int(n) && i < len(best.ms); i++ {
+	for i := 0; count < int(n) && i < len(ordered); i++ {
 		// if we wouldn't have enough gas for a standard transaction then quit out early
 		if availableGas < fixedgas.TxGas {
 			break
 		}
 
-		mt := best.ms[i]
+		mt := ordered[i]
 		p.Trace("Processing transaction", "txID", mt.Tx.IDHash)
 
 		if toSkip.Contains(mt.Tx.IDHash) {
@@ -195,11 +204,16 @@ func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableG
 			continue
 		}
 
-		if !isLondon && mt.Tx.Type == 0x2 {
-			// remove ldn txs when not in london
+		if p.orderer.senderCapReached(yieldedPerSender, mt.Tx.SenderID) {
+			p.Trace("Skipping transaction, sender reached the per batch cap", "txID", mt.Tx.IDHash)
+			continue
+		}
+
+		if !isLondon && mt.Tx.Type == 0x2 && !zktx.TxTypeSupported(mt.Tx.Type, forkId) {
+			// remove ldn txs when not in london and before the batch l2 data can carry them
 			toRemove = append(toRemove, mt)
 			toSkip.Add(mt.Tx.IDHash)
-			log.Info("Removing London transaction in non-London environment", "txID", mt.Tx.IDHash)
+			log.Info("Removing London transaction in non-London environment", "txID", mt.Tx.IDHash, "forkId", forkId)
 			continue
 		}
 
@@ -248,6 +262,7 @@ func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableG
 		copy(txs.Senders.At(count), sender.Bytes())
 		txs.IsLocal[count] = isLocal
 		toSkip.Add(mt.Tx.IDHash)
+		yieldedPerSender[mt.Tx.SenderID]++
 		count++
 	}
 
@@ -262,6 +277,18 @@ func (p *TxPool) best(n uint16, txs *types.TxsRlp, tx kv.Tx, onTopOf, availableG
 	return true, count, nil
 }
 
+// updateLastSeenForkId tracks the fork of the last seen block so that transaction types the batch l2 data can only
+// carry from a later fork are kept out of the pool until the chain reaches it
+func (p *TxPool) updateLastSeenForkId(coreTx kv.Tx) {
+	blockNumber := p.lastSeenBlock.Load()
+	forkId, err := hermez_db.NewHermezDbReader(coreTx).GetForkIdByBlockNum(blockNumber)
+	if err != nil {
+		log.Debug("[txpool] Could not read the fork id of the last seen block", "block", blockNumber, "err", err)
+		return
+	}
+	p.lastSeenForkId.Store(forkId)
+}
+
 func (p *TxPool) ForceUpdateLatestBlock(blockNumber uint64) {
 	if p != nil {
 		p.lastSeenBlock.Store(blockNumber)
@@ -249,6 +249,8 @@ func (p *TxPool) ProcessUncheckedLimboBlockDetails(limboBlock *LimboBlockDetails
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	p.limbo.uncheckedLimboBlocks = append(p.limbo.uncheckedLimboBlocks, limboBlock)
+	limboBatchesEnteredCounter.Inc()
+	p.updateLimboMetricsLocked()
 
 	/*
 		as we know we're about to enter an unwind we need to ensure that all the transactions have been
@@ -288,6 +290,7 @@ func (p *TxPool) MarkProcessedLimboDetails(size int, invalidBatchesIndices []int
 		p.limbo.invalidLimboBlocks = append(p.limbo.invalidLimboBlocks, p.limbo.uncheckedLimboBlocks[invalidBatchesIndex])
 	}
 	p.limbo.uncheckedLimboBlocks = p.limbo.uncheckedLimboBlocks[size:]
+	p.updateLimboMetricsLocked()
 }
 
 // should be called from within a locked context from the pool
@@ -339,6 +342,8 @@ func (p *TxPool) finalizeLimboOnNewBlock(limboTxs *types.TxSlots) {
 	for _, idHash := range forDelete {
 		delete(p.limbo.invalidTxsMap, *idHash)
 	}
+
+	p.updateLimboMetricsLocked()
 }
 
 // should be called from within a locked context from the pool
@@ -0,0 +1,225 @@
+package txpool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/status-im/keycard-go/hexutils"
+)
+
+type LimboResolution string
+
+const (
+	// LimboResolutionDrop removes the transaction from limbo and from the pool for good
+	LimboResolutionDrop LimboResolution = "drop"
+	// LimboResolutionRequeue removes the transaction from limbo and lets it be yielded to the sequencer again
+	LimboResolutionRequeue LimboResolution = "requeue"
+)
+
+var (
+	ErrLimboTxNotFound   = errors.New("transaction is not in limbo")
+	ErrLimboTxRecovering = errors.New("transaction is part of a limbo batch that is still being recovered")
+)
+
+type LimboBatchState string
+
+const (
+	LimboBatchUnchecked LimboBatchState = "unchecked"
+	LimboBatchInvalid   LimboBatchState = "invalid"
+)
+
+type LimboTxDetails struct {
+	Hash        common.Hash      `json:"hash"`
+	Sender      common.Address   `json:"sender"`
+	StreamBytes hexutility.Bytes `json:"streamBytes"`
+	Root        *common.Hash     `json:"root"`
+	State       LimboTxState     `json:"state"`
+}
+
+type LimboBlockSummary struct {
+	BlockNumber    uint64           `json:"blockNumber"`
+	BlockTimestamp uint64           `json:"blockTimestamp"`
+	HasWitness     bool             `json:"hasWitness"`
+	WitnessSize    int              `json:"witnessSize"`
+	Transactions   []LimboTxDetails `json:"transactions"`
+}
+
+type LimboBatchDetails struct {
+	BatchNumber uint64              `json:"batchNumber"`
+	ForkId      uint64              `json:"forkId"`
+	State       LimboBatchState     `json:"state"`
+	Blocks      []LimboBlockSummary `json:"blocks"`
+}
+
+type LimboDetails struct {
+	Batches []LimboBatchDetails `json:"batches"`
+	// ParkedTxs were unwound with a limbo batch and are held back until its recovery completes
+	ParkedTxs []common.Hash `json:"parkedTxs"`
+	// PendingDiscardTxs failed verification and are dropped from the pool as soon as the unwind returns them
+	PendingDiscardTxs []common.Hash `json:"pendingDiscardTxs"`
+}
+
+// should be called from within a locked context from the pool
+func (p *TxPool) limboDetailsLocked() *LimboDetails {
+	details := &LimboDetails{
+		Batches:           make([]LimboBatchDetails, 0),
+		ParkedTxs:         make([]common.Hash, 0, len(p.limbo.limboSlots.Txs)),
+		PendingDiscardTxs: make([]common.Hash, 0, len(p.limbo.invalidTxsMap)),
+	}
+
+	addBlocks := func(limboBlocks []*LimboBlockDetails, state LimboBatchState) {
+		for _, limboBlock := range limboBlocks {
+			// limbo blocks are stored in order, consecutive blocks of the same batch make up the limbo batch
+			last := len(details.Batches) - 1
+			if last < 0 || details.Batches[last].BatchNumber != limboBlock.BatchNumber || details.Batches[last].State != state {
+				details.Batches = append(details.Batches, LimboBatchDetails{
+					BatchNumber: limboBlock.BatchNumber,
+					ForkId:      limboBlock.ForkId,
+					State:       state,
+					Blocks:      make([]LimboBlockSummary, 0, 1),
+				})
+				last++
+			}
+
+			block := LimboBlockSummary{
+				BlockNumber:    limboBlock.BlockNumber,
+				BlockTimestamp: limboBlock.BlockTimestamp,
+				HasWitness:     len(limboBlock.Witness) > 0,
+				WitnessSize:    len(limboBlock.Witness),
+				Transactions:   make([]LimboTxDetails, 0, len(limboBlock.Transactions)),
+			}
+			for _, limboTx := range limboBlock.Transactions {
+				txDetails := LimboTxDetails{
+					Hash:        limboTx.Hash,
+					Sender:      limboTx.Sender,
+					StreamBytes: common.CopyBytes(limboTx.StreamBytes),
+				}
+				switch {
+				case state == LimboBatchInvalid:
+					txDetails.State = LimboTxInvalid
+				case limboTx.hasRoot():
+					txDetails.State = LimboTxAwaitingVerification
+				default:
+					txDetails.State = LimboTxAwaitingRoot
+				}
+				if limboTx.hasRoot() {
+					root := limboTx.Root
+					txDetails.Root = &root
+				}
+				block.Transactions = append(block.Transactions, txDetails)
+			}
+			details.Batches[last].Blocks = append(details.Batches[last].Blocks, block)
+		}
+	}
+	addBlocks(p.limbo.uncheckedLimboBlocks, LimboBatchUnchecked)
+	addBlocks(p.limbo.invalidLimboBlocks, LimboBatchInvalid)
+
+	for _, slot := range p.limbo.limboSlots.Txs {
+		details.ParkedTxs = append(details.ParkedTxs, slot.IDHash)
+	}
+	for idHash, handled := range p.limbo.invalidTxsMap {
+		if handled == 0 {
+			details.PendingDiscardTxs = append(details.PendingDiscardTxs, common.BytesToHash(hexutils.HexToBytes(idHash)))
+		}
+	}
+
+	return details
+}
+
+// GetLimboDetails returns a copy of everything the pool currently holds in limbo
+func (p *TxPool) GetLimboDetails() *LimboDetails {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.limboDetailsLocked()
+}
+
+// ResolveLimboTx takes a transaction out of limbo.  Only transactions whose limbo batch has been found invalid can be
+// resolved, while a batch is being recovered its transactions are still needed to re-execute it.
+//
+// When re-queueing, the returned rlp is non-nil if the transaction is no longer known to the pool and has to be
+// added back to it by the caller.
+func (p *TxPool) ResolveLimboTx(hash common.Hash, resolution LimboResolution) ([]byte, error) {
+	if resolution != LimboResolutionDrop && resolution != LimboResolutionRequeue {
+		return nil, fmt.Errorf("unknown limbo resolution %q", resolution)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.isTxKnownToLimbo(hash) || p.isTxParkedInLimbo(hash) {
+		return nil, ErrLimboTxRecovering
+	}
+
+	idHash := hexutils.BytesToHex(hash[:])
+	handled, pendingDiscard := p.limbo.invalidTxsMap[idHash]
+	limboTx := p.limbo.removeInvalidTx(hash)
+	if limboTx == nil && !pendingDiscard {
+		return nil, ErrLimboTxNotFound
+	}
+	defer p.updateLimboMetricsLocked()
+
+	mt, inPool := p.byHash[string(hash[:])]
+
+	if resolution == LimboResolutionDrop {
+		// a pending discard entry is kept so the tx is dropped if an unwind still to be processed returns it
+		if inPool {
+			p.removeFromSubPoolLocked(mt)
+			p.discardLocked(mt, DiscardByLimbo)
+		}
+		return nil, nil
+	}
+
+	delete(p.limbo.invalidTxsMap, idHash)
+	p.discardReasonsLRU.Remove(string(hash[:]))
+
+	// the unwind of the limbo batch will hand the transaction back to the pool on its own
+	if inPool || (pendingDiscard && handled == 0) || limboTx == nil {
+		return nil, nil
+	}
+
+	return common.CopyBytes(limboTx.Rlp), nil
+}
+
+// should be called from within a locked context from the pool
+func (p *TxPool) isTxParkedInLimbo(hash common.Hash) bool {
+	for _, slot := range p.limbo.limboSlots.Txs {
+		if slot.IDHash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// should be called from within a locked context from the pool
+func (p *TxPool) removeFromSubPoolLocked(mt *metaTx) {
+	switch mt.currentSubPool {
+	case PendingSubPool:
+		p.pending.Remove(mt)
+	case BaseFeeSubPool:
+		p.baseFee.Remove(mt)
+	case QueuedSubPool:
+		p.queued.Remove(mt)
+	default:
+		//already removed
+	}
+}
+
+// removeInvalidTx removes the transaction from the invalid limbo blocks, dropping any block left empty
+func (_this *Limbo) removeInvalidTx(hash common.Hash) *LimboBlockTransactionDetails {
+	for i, limboBlock := range _this.invalidLimboBlocks {
+		limboTx, j := limboBlock.getTxDetailsByHash(&hash)
+		if limboTx == nil {
+			continue
+		}
+
+		limboBlock.Transactions = append(limboBlock.Transactions[:j], limboBlock.Transactions[j+1:]...)
+		if len(limboBlock.Transactions) == 0 {
+			_this.invalidLimboBlocks = append(_this.invalidLimboBlocks[:i], _this.invalidLimboBlocks[i+1:]...)
+		}
+		return limboTx
+	}
+
+	return nil
+}
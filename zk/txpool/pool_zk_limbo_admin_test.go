@@ -0,0 +1,158 @@
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/u256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal/temporaltest"
+	"github.com/ledgerwatch/erigon-lib/txpool/txpoolcfg"
+	"github.com/ledgerwatch/erigon-lib/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/status-im/keycard-go/hexutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimboDetailsAndResolve(t *testing.T) {
+	ctx := context.Background()
+	sender := [20]byte{1}
+
+	_, coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	t.Cleanup(coreDB.Close)
+	aclsDB := newTestACLDB(t, t.TempDir())
+	t.Cleanup(aclsDB.Close)
+
+	ethCfg := ethconfig.Defaults
+	ethCfg.Zk = &ethconfig.Zk{}
+	pool, err := New(make(chan types.Announcements, 100), coreDB, txpoolcfg.DefaultConfig, &ethCfg, kvcache.New(kvcache.DefaultCoherentConfig), *u256.N1, nil, nil, aclsDB)
+	require.NoError(t, err)
+
+	tx, err := memdb.NewTestPoolDB(t).BeginRw(ctx)
+	require.NoError(t, err)
+	t.Cleanup(tx.Rollback)
+
+	balance := *uint256.NewInt(18 * common.Ether)
+	v := make([]byte, types.EncodeSenderLengthForStorage(0, balance))
+	types.EncodeSender(0, balance, v)
+	change := &remote.StateChangeBatch{
+		PendingBlockBaseFee: 200000,
+		BlockGasLimit:       1000000,
+		ChangeBatch: []*remote.StateChange{{
+			BlockHeight: 0,
+			BlockHash:   gointerfaces.ConvertHashToH256([32]byte{}),
+			Changes:     []*remote.AccountChange{{Action: remote.Action_UPSERT, Address: gointerfaces.ConvertAddressToH160(sender), Data: v}},
+		}},
+	}
+	require.NoError(t, pool.OnNewBlock(ctx, change, types.TxSlots{}, types.TxSlots{}, tx))
+
+	// the valid transaction of the invalid batch went back to the pool with the unwind
+	var slots types.TxSlots
+	inPool := &types.TxSlot{Tip: *uint256.NewInt(300000), FeeCap: *uint256.NewInt(300000), Gas: 100000, Rlp: []byte{1}}
+	inPool.IDHash[0] = 1
+	slots.Append(inPool, sender[:], true)
+	reasons, err := pool.AddLocalTxs(ctx, slots, tx)
+	require.NoError(t, err)
+	require.Equal(t, Success, reasons[0], reasons[0].String())
+
+	validHash := common.Hash{1}
+	invalidHash := common.Hash{2}
+	recoveringHash := common.Hash{3}
+	parkedHash := common.Hash{4}
+
+	invalidBlock := NewLimboBlockDetails()
+	invalidBlock.BatchNumber = 4
+	invalidBlock.BlockNumber = 10
+	invalidBlock.Witness = []byte{0xaa, 0xbb}
+	invalidBlock.AppendTransaction([]byte{0xc1}, []byte{0x01}, validHash, sender)
+	invalidBlock.AppendTransaction([]byte{0xc2}, []byte{0x02}, invalidHash, sender)
+
+	uncheckedBlock1 := NewLimboBlockDetails()
+	uncheckedBlock1.BatchNumber = 5
+	uncheckedBlock1.BlockNumber = 11
+	uncheckedBlock1.AppendTransaction([]byte{0xc3}, []byte{0x03}, recoveringHash, sender)
+	uncheckedBlock1.Transactions[0].Root = common.Hash{0xff}
+	uncheckedBlock2 := NewLimboBlockDetails()
+	uncheckedBlock2.BatchNumber = 5
+	uncheckedBlock2.BlockNumber = 12
+
+	pool.lock.Lock()
+	pool.limbo.invalidLimboBlocks = append(pool.limbo.invalidLimboBlocks, invalidBlock)
+	pool.limbo.uncheckedLimboBlocks = append(pool.limbo.uncheckedLimboBlocks, uncheckedBlock1, uncheckedBlock2)
+	pool.limbo.invalidTxsMap[hexutils.BytesToHex(invalidHash[:])] = 0
+	pool.limbo.limboSlots.Append(&types.TxSlot{IDHash: parkedHash}, sender[:], true)
+	pool.lock.Unlock()
+
+	details := pool.GetLimboDetails()
+	require.Len(t, details.Batches, 2)
+	require.Equal(t, uint64(5), details.Batches[0].BatchNumber)
+	require.Equal(t, LimboBatchUnchecked, details.Batches[0].State)
+	require.Len(t, details.Batches[0].Blocks, 2)
+	require.Equal(t, LimboTxAwaitingVerification, details.Batches[0].Blocks[0].Transactions[0].State)
+	require.Equal(t, common.Hash{0xff}, *details.Batches[0].Blocks[0].Transactions[0].Root)
+	require.Equal(t, uint64(4), details.Batches[1].BatchNumber)
+	require.Equal(t, LimboBatchInvalid, details.Batches[1].State)
+	require.True(t, details.Batches[1].Blocks[0].HasWitness)
+	require.Equal(t, 2, details.Batches[1].Blocks[0].WitnessSize)
+	require.Equal(t, []LimboTxDetails{
+		{Hash: validHash, Sender: sender, StreamBytes: []byte{0x01}, State: LimboTxInvalid},
+		{Hash: invalidHash, Sender: sender, StreamBytes: []byte{0x02}, State: LimboTxInvalid},
+	}, details.Batches[1].Blocks[0].Transactions)
+	require.Equal(t, []common.Hash{parkedHash}, details.ParkedTxs)
+	require.Equal(t, []common.Hash{invalidHash}, details.PendingDiscardTxs)
+
+	_, err = pool.ResolveLimboTx(validHash, "forget")
+	require.Error(t, err)
+	_, err = pool.ResolveLimboTx(recoveringHash, LimboResolutionDrop)
+	require.ErrorIs(t, err, ErrLimboTxRecovering)
+	_, err = pool.ResolveLimboTx(parkedHash, LimboResolutionRequeue)
+	require.ErrorIs(t, err, ErrLimboTxRecovering)
+	_, err = pool.ResolveLimboTx(common.Hash{9}, LimboResolutionDrop)
+	require.ErrorIs(t, err, ErrLimboTxNotFound)
+
+	// dropping takes the transaction out of the pool as well
+	rlp, err := pool.ResolveLimboTx(validHash, LimboResolutionDrop)
+	require.NoError(t, err)
+	require.Nil(t, rlp)
+	pool.lock.Lock()
+	_, ok := pool.byHash[string(validHash[:])]
+	require.False(t, ok)
+	reason, ok := pool.discardReasonsLRU.Get(string(validHash[:]))
+	require.True(t, ok)
+	require.Equal(t, DiscardByLimbo, reason)
+	pool.lock.Unlock()
+
+	// the unwind has not returned the invalid transaction yet, re-queueing stops it being discarded when it does
+	rlp, err = pool.ResolveLimboTx(invalidHash, LimboResolutionRequeue)
+	require.NoError(t, err)
+	require.Nil(t, rlp)
+	details = pool.GetLimboDetails()
+	require.Len(t, details.Batches, 1)
+	require.Empty(t, details.PendingDiscardTxs)
+
+	_, err = pool.ResolveLimboTx(invalidHash, LimboResolutionRequeue)
+	require.ErrorIs(t, err, ErrLimboTxNotFound)
+
+	// once discarded the transaction has to be added back by the caller
+	discardedBlock := NewLimboBlockDetails()
+	discardedBlock.BatchNumber = 6
+	discardedBlock.AppendTransaction([]byte{0xc5}, nil, common.Hash{5}, sender)
+	pool.lock.Lock()
+	pool.limbo.invalidLimboBlocks = append(pool.limbo.invalidLimboBlocks, discardedBlock)
+	pool.discardReasonsLRU.Add(string(common.Hash{5}.Bytes()), DiscardByLimbo)
+	pool.lock.Unlock()
+
+	rlp, err = pool.ResolveLimboTx(common.Hash{5}, LimboResolutionRequeue)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xc5}, rlp)
+	pool.lock.Lock()
+	_, ok = pool.discardReasonsLRU.Get(string(common.Hash{5}.Bytes()))
+	require.False(t, ok)
+	pool.lock.Unlock()
+}
@@ -0,0 +1,159 @@
+package txpool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/metrics"
+)
+
+type LimboTxState string
+
+const (
+	// LimboTxAwaitingRoot is a transaction from a limbo batch that has not been re-executed on its own yet
+	LimboTxAwaitingRoot LimboTxState = "awaiting_root"
+	// LimboTxAwaitingVerification has been re-executed and is waiting for the limbo processor to verify it
+	LimboTxAwaitingVerification LimboTxState = "awaiting_verification"
+	// LimboTxInvalid failed verification by the executor
+	LimboTxInvalid LimboTxState = "invalid"
+	// LimboTxParked was unwound with a limbo batch and is held back from being yielded by the pool
+	LimboTxParked LimboTxState = "parked"
+)
+
+var limboTxStates = []LimboTxState{LimboTxAwaitingRoot, LimboTxAwaitingVerification, LimboTxInvalid, LimboTxParked}
+
+var (
+	limboUncheckedBatchesGauge = metrics.GetOrCreateGauge(`zkevm_limbo_batches{state="unchecked"}`)
+	limboInvalidBatchesGauge   = metrics.GetOrCreateGauge(`zkevm_limbo_batches{state="invalid"}`)
+	limboOldestEntryAgeGauge   = metrics.GetOrCreateGauge(`zkevm_limbo_oldest_entry_age_seconds`)
+	// per sender counts are only exposed over rpc, as metric labels they would be unbounded
+	limboSendersGauge           = metrics.GetOrCreateGauge(`zkevm_limbo_senders`)
+	limboMaxTxsPerSenderGauge   = metrics.GetOrCreateGauge(`zkevm_limbo_max_txs_per_sender`)
+	limboBatchesEnteredCounter  = metrics.GetOrCreateCounter(`zkevm_limbo_batches_entered_total`)
+	limboRecoveryValidCounter   = metrics.GetOrCreateCounter(`zkevm_limbo_recovery_total{outcome="valid"}`)
+	limboRecoveryInvalidCounter = metrics.GetOrCreateCounter(`zkevm_limbo_recovery_total{outcome="invalid"}`)
+
+	limboTxsGauges = map[LimboTxState]metrics.Gauge{}
+)
+
+func init() {
+	for _, state := range limboTxStates {
+		limboTxsGauges[state] = metrics.GetOrCreateGauge(`zkevm_limbo_txs{state="` + string(state) + `"}`)
+	}
+}
+
+type LimboSenderCount struct {
+	Sender common.Address `json:"sender"`
+	Count  int            `json:"count"`
+}
+
+type LimboRecoveryStats struct {
+	Valid   uint64 `json:"valid"`
+	Invalid uint64 `json:"invalid"`
+}
+
+type LimboStats struct {
+	UncheckedBatches      int                  `json:"uncheckedBatches"`
+	InvalidBatches        int                  `json:"invalidBatches"`
+	TxsByState            map[LimboTxState]int `json:"txsByState"`
+	OldestEntryAgeSeconds uint64               `json:"oldestEntryAgeSeconds"`
+	Senders               []LimboSenderCount   `json:"senders"`
+	BatchesEntered        uint64               `json:"batchesEntered"`
+	Recovery              LimboRecoveryStats   `json:"recovery"`
+}
+
+// should be called from within a locked context from the pool
+func (p *TxPool) limboStatsLocked(now time.Time) *LimboStats {
+	stats := &LimboStats{
+		UncheckedBatches: len(p.limbo.uncheckedLimboBlocks),
+		InvalidBatches:   len(p.limbo.invalidLimboBlocks),
+		TxsByState:       make(map[LimboTxState]int, len(limboTxStates)),
+		BatchesEntered:   limboBatchesEnteredCounter.GetValueUint64(),
+		Recovery: LimboRecoveryStats{
+			Valid:   limboRecoveryValidCounter.GetValueUint64(),
+			Invalid: limboRecoveryInvalidCounter.GetValueUint64(),
+		},
+	}
+	for _, state := range limboTxStates {
+		stats.TxsByState[state] = 0
+	}
+
+	senders := make(map[common.Address]int)
+	var oldest uint64
+	countBlock := func(limboBlock *LimboBlockDetails, invalid bool) {
+		if limboBlock.BlockTimestamp != 0 && (oldest == 0 || limboBlock.BlockTimestamp < oldest) {
+			oldest = limboBlock.BlockTimestamp
+		}
+		for _, limboTx := range limboBlock.Transactions {
+			switch {
+			case invalid:
+				stats.TxsByState[LimboTxInvalid]++
+			case limboTx.hasRoot():
+				stats.TxsByState[LimboTxAwaitingVerification]++
+			default:
+				stats.TxsByState[LimboTxAwaitingRoot]++
+			}
+			senders[limboTx.Sender]++
+		}
+	}
+	for _, limboBlock := range p.limbo.uncheckedLimboBlocks {
+		countBlock(limboBlock, false)
+	}
+	for _, limboBlock := range p.limbo.invalidLimboBlocks {
+		countBlock(limboBlock, true)
+	}
+	stats.TxsByState[LimboTxParked] = len(p.limbo.limboSlots.Txs)
+
+	// the block timestamp of a limbo block is when it was sealed, moments before the executor rejected it
+	if oldest != 0 && uint64(now.Unix()) > oldest {
+		stats.OldestEntryAgeSeconds = uint64(now.Unix()) - oldest
+	}
+
+	stats.Senders = make([]LimboSenderCount, 0, len(senders))
+	for sender, count := range senders {
+		stats.Senders = append(stats.Senders, LimboSenderCount{Sender: sender, Count: count})
+	}
+	sort.Slice(stats.Senders, func(i, j int) bool {
+		if stats.Senders[i].Count != stats.Senders[j].Count {
+			return stats.Senders[i].Count > stats.Senders[j].Count
+		}
+		return stats.Senders[i].Sender.Hex() < stats.Senders[j].Sender.Hex()
+	})
+
+	return stats
+}
+
+// GetLimboStats returns a summary of the transactions and batches currently in limbo
+func (p *TxPool) GetLimboStats() *LimboStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.limboStatsLocked(time.Now())
+}
+
+// should be called from within a locked context from the pool
+func (p *TxPool) updateLimboMetricsLocked() {
+	if !p.ethCfg.Limbo {
+		return
+	}
+
+	stats := p.limboStatsLocked(time.Now())
+	limboUncheckedBatchesGauge.SetInt(stats.UncheckedBatches)
+	limboInvalidBatchesGauge.SetInt(stats.InvalidBatches)
+	for state, count := range stats.TxsByState {
+		limboTxsGauges[state].SetInt(count)
+	}
+	limboOldestEntryAgeGauge.SetUint64(stats.OldestEntryAgeSeconds)
+	limboSendersGauge.SetInt(len(stats.Senders))
+	maxPerSender := 0
+	if len(stats.Senders) > 0 {
+		maxPerSender = stats.Senders[0].Count
+	}
+	limboMaxTxsPerSenderGauge.SetInt(maxPerSender)
+}
+
+func (p *TxPool) updateLimboMetrics() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.updateLimboMetricsLocked()
+}
@@ -0,0 +1,43 @@
+package txpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimboStats(t *testing.T) {
+	p := &TxPool{limbo: newLimbo()}
+	senderA := common.HexToAddress("0xa")
+	senderB := common.HexToAddress("0xb")
+
+	unchecked := NewLimboBlockDetails()
+	unchecked.BlockTimestamp = 1000
+	unchecked.AppendTransaction(nil, nil, common.HexToHash("0x1"), senderA)
+	unchecked.AppendTransaction(nil, nil, common.HexToHash("0x2"), senderA)
+	unchecked.Transactions[0].Root = common.HexToHash("0xff")
+
+	invalid := NewLimboBlockDetails()
+	invalid.BlockTimestamp = 900
+	invalid.AppendTransaction(nil, nil, common.HexToHash("0x3"), senderB)
+
+	p.limbo.uncheckedLimboBlocks = append(p.limbo.uncheckedLimboBlocks, unchecked)
+	p.limbo.invalidLimboBlocks = append(p.limbo.invalidLimboBlocks, invalid)
+	p.limbo.limboSlots.Append(&types.TxSlot{}, senderA[:], true)
+
+	stats := p.limboStatsLocked(time.Unix(1000, 0))
+
+	require.Equal(t, 1, stats.UncheckedBatches)
+	require.Equal(t, 1, stats.InvalidBatches)
+	require.Equal(t, map[LimboTxState]int{
+		LimboTxAwaitingRoot:         1,
+		LimboTxAwaitingVerification: 1,
+		LimboTxInvalid:              1,
+		LimboTxParked:               1,
+	}, stats.TxsByState)
+	require.Equal(t, uint64(100), stats.OldestEntryAgeSeconds)
+	require.Equal(t, []LimboSenderCount{{Sender: senderA, Count: 2}, {Sender: senderB, Count: 1}}, stats.Senders)
+}
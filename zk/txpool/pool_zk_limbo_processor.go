@@ -102,10 +102,12 @@ func (_this *LimboSubPoolProcessor) run() {
 					invalidBlocksIndices = append(invalidBlocksIndices, i)
 					lastAddedInvalidBlockIndex = i
 				}
+				limboRecoveryInvalidCounter.Inc()
 				log.Info("[Limbo pool processor]", "invalid tx", limboTx.Hash, "err", err)
 				continue
 			}
 
+			limboRecoveryValidCounter.Inc()
 			processedTransactions++
 			log.Info("[Limbo pool processor]", "valid tx", limboTx.Hash, "progress", fmt.Sprintf("transactions: %d of %d, blocks: %d of %d", processedTransactions, totalTransactions, i+1, len(limboBlocksDetails)))
 		}
@@ -0,0 +1,172 @@
+package txpool
+
+import (
+	"fmt"
+	"sort"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// TxOrdering is the strategy used to decide the order in which pending transactions are yielded to the sequencer
+type TxOrdering string
+
+const (
+	// OrderingPriorityFee yields transactions by their effective tip, this is the pool's natural ordering
+	OrderingPriorityFee TxOrdering = "priority-fee"
+	// OrderingFifo yields transactions in the order they arrived in the pool
+	OrderingFifo TxOrdering = "fifo"
+	// OrderingSenderFair round-robins across senders so a single busy sender cannot fill a batch
+	OrderingSenderFair TxOrdering = "sender-fair"
+	// OrderingCounterAware yields transactions with the smallest known virtual counter usage first so that
+	// as many transactions as possible are packed into each batch
+	OrderingCounterAware TxOrdering = "counter-aware"
+)
+
+const txCounterUsageCacheSize = 100_000
+
+func ParseTxOrdering(s string) (TxOrdering, error) {
+	switch o := TxOrdering(s); o {
+	case "":
+		return OrderingPriorityFee, nil
+	case OrderingPriorityFee, OrderingFifo, OrderingSenderFair, OrderingCounterAware:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown transaction ordering %q", s)
+	}
+}
+
+// txOrderer holds the ordering configuration of the pool along with the virtual counter usage of transactions
+// the sequencer has already executed.  The configuration never changes and the usage cache has its own lock so
+// that recording usage from the sequencer doesn't contend for the pool lock.  The per sender counts of the batch
+// being built are only touched under the pool lock.
+type txOrderer struct {
+	ordering        TxOrdering
+	maxTxsPerSender int
+	counterUsage    *lru.Cache[[32]byte, float64]
+
+	batchNo          uint64
+	yieldedPerSender map[uint64]int
+}
+
+func newTxOrderer(ordering TxOrdering, maxTxsPerSender int) (*txOrderer, error) {
+	counterUsage, err := lru.New[[32]byte, float64](txCounterUsageCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &txOrderer{
+		ordering:        ordering,
+		maxTxsPerSender: maxTxsPerSender,
+		counterUsage:    counterUsage,
+	}, nil
+}
+
+// order returns the pending transactions in the order they should be yielded.  The given slice is the pending
+// pool's best slice which must not be re-ordered in place as it maintains the bestIndex of each transaction.
+func (o *txOrderer) order(best []*metaTx) []*metaTx {
+	switch o.ordering {
+	case OrderingFifo:
+		ordered := copyMetaTxs(best)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].arrival < ordered[j].arrival
+		})
+		preserveSenderNonceOrder(ordered)
+		return ordered
+	case OrderingSenderFair:
+		return roundRobinSenders(best)
+	case OrderingCounterAware:
+		ordered := copyMetaTxs(best)
+		// transactions we have not executed yet have no usage recorded and go first so that we learn their cost
+		sort.SliceStable(ordered, func(i, j int) bool {
+			iUsage, _ := o.counterUsage.Peek(ordered[i].Tx.IDHash)
+			jUsage, _ := o.counterUsage.Peek(ordered[j].Tx.IDHash)
+			return iUsage < jUsage
+		})
+		preserveSenderNonceOrder(ordered)
+		return ordered
+	default:
+		return best
+	}
+}
+
+// batchSenderCounts returns the number of transactions yielded per sender for the batch, the sequencer asks for
+// transactions many times while building a batch so the counts are kept until it moves on to another batch
+func (o *txOrderer) batchSenderCounts(batchNo uint64) map[uint64]int {
+	if o.yieldedPerSender == nil || o.batchNo != batchNo {
+		o.batchNo = batchNo
+		o.yieldedPerSender = make(map[uint64]int)
+	}
+	return o.yieldedPerSender
+}
+
+// senderCapReached reports whether the sender has already had the maximum number of transactions yielded
+func (o *txOrderer) senderCapReached(yielded map[uint64]int, senderID uint64) bool {
+	return o.maxTxsPerSender > 0 && yielded[senderID] >= o.maxTxsPerSender
+}
+
+func copyMetaTxs(ms []*metaTx) []*metaTx {
+	res := make([]*metaTx, len(ms))
+	copy(res, ms)
+	return res
+}
+
+// preserveSenderNonceOrder keeps the positions each sender occupies in the slice but re-assigns the sender's
+// transactions to those positions in nonce order, as a higher nonce can never be executed before a lower one
+func preserveSenderNonceOrder(ms []*metaTx) {
+	positions := make(map[uint64][]int)
+	for i, mt := range ms {
+		positions[mt.Tx.SenderID] = append(positions[mt.Tx.SenderID], i)
+	}
+	for _, idx := range positions {
+		if len(idx) < 2 {
+			continue
+		}
+		senderTxs := make([]*metaTx, len(idx))
+		for i, pos := range idx {
+			senderTxs[i] = ms[pos]
+		}
+		sort.Slice(senderTxs, func(i, j int) bool {
+			return senderTxs[i].Tx.Nonce < senderTxs[j].Tx.Nonce
+		})
+		for i, pos := range idx {
+			ms[pos] = senderTxs[i]
+		}
+	}
+}
+
+// roundRobinSenders takes one transaction per sender in turn, senders are visited in the order their best
+// transaction appears in the pool and each sender's transactions are yielded in nonce order
+func roundRobinSenders(best []*metaTx) []*metaTx {
+	var senders []uint64
+	bySender := make(map[uint64][]*metaTx)
+	for _, mt := range best {
+		if _, ok := bySender[mt.Tx.SenderID]; !ok {
+			senders = append(senders, mt.Tx.SenderID)
+		}
+		bySender[mt.Tx.SenderID] = append(bySender[mt.Tx.SenderID], mt)
+	}
+	for _, txs := range bySender {
+		sort.SliceStable(txs, func(i, j int) bool {
+			return txs[i].Tx.Nonce < txs[j].Tx.Nonce
+		})
+	}
+
+	res := make([]*metaTx, 0, len(best))
+	for round := 0; len(res) < len(best); round++ {
+		for _, sender := range senders {
+			if txs := bySender[sender]; round < len(txs) {
+				res = append(res, txs[round])
+			}
+		}
+	}
+	return res
+}
+
+// UpdateTxCounterUsage records the virtual counter usage of a transaction after the sequencer has executed it,
+// expressed as the highest fraction of any single counter's batch limit.  Only the counter-aware ordering uses
+// it so nothing is recorded for the other orderings.
+func (p *TxPool) UpdateTxCounterUsage(txHash [32]byte, usage float64) {
+	if p.orderer.ordering != OrderingCounterAware {
+		return
+	}
+	p.orderer.counterUsage.Add(txHash, usage)
+}
@@ -0,0 +1,244 @@
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/common/u256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon-lib/kv/temporal/temporaltest"
+	"github.com/ledgerwatch/erigon-lib/txpool/txpoolcfg"
+	"github.com/ledgerwatch/erigon-lib/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func orderingTestTx(sender uint64, nonce uint64, id byte) *metaTx {
+	mt := newMetaTx(&types.TxSlot{SenderID: sender, Nonce: nonce}, false, 0)
+	mt.Tx.IDHash[0] = id
+	return mt
+}
+
+func orderedIDs(ms []*metaTx) []byte {
+	ids := make([]byte, len(ms))
+	for i, mt := range ms {
+		ids[i] = mt.Tx.IDHash[0]
+	}
+	return ids
+}
+
+func TestParseTxOrdering(t *testing.T) {
+	o, err := ParseTxOrdering("")
+	require.NoError(t, err)
+	require.Equal(t, OrderingPriorityFee, o)
+
+	o, err = ParseTxOrdering("sender-fair")
+	require.NoError(t, err)
+	require.Equal(t, OrderingSenderFair, o)
+
+	_, err = ParseTxOrdering("random")
+	require.Error(t, err)
+}
+
+func TestTxOrderingFifo(t *testing.T) {
+	orderer, err := newTxOrderer(OrderingFifo, 0)
+	require.NoError(t, err)
+
+	// created in the order they arrive in the pool, sender 1's higher nonce arrives first but must still be
+	// yielded after its lower nonce
+	highNonce := orderingTestTx(1, 2, 3)
+	sender3 := orderingTestTx(3, 0, 4)
+	sender2 := orderingTestTx(2, 0, 1)
+	lowNonce := orderingTestTx(1, 1, 2)
+
+	best := []*metaTx{sender2, lowNonce, highNonce, sender3}
+	ordered := orderer.order(best)
+	require.Equal(t, []byte{2, 4, 1, 3}, orderedIDs(ordered))
+
+	// the pool's best slice must be left untouched
+	require.Equal(t, []byte{1, 2, 3, 4}, orderedIDs(best))
+}
+
+func TestTxOrderingSenderFair(t *testing.T) {
+	orderer, err := newTxOrderer(OrderingSenderFair, 0)
+	require.NoError(t, err)
+
+	best := []*metaTx{
+		orderingTestTx(1, 0, 1),
+		orderingTestTx(1, 1, 2),
+		orderingTestTx(1, 2, 3),
+		orderingTestTx(2, 0, 4),
+		orderingTestTx(3, 0, 5),
+		orderingTestTx(3, 1, 6),
+	}
+	require.Equal(t, []byte{1, 4, 5, 2, 6, 3}, orderedIDs(orderer.order(best)))
+}
+
+func TestTxOrderingCounterAware(t *testing.T) {
+	orderer, err := newTxOrderer(OrderingCounterAware, 0)
+	require.NoError(t, err)
+
+	heavy := orderingTestTx(1, 0, 1)
+	light := orderingTestTx(2, 0, 2)
+	unknown := orderingTestTx(3, 0, 3)
+	orderer.counterUsage.Add(heavy.Tx.IDHash, 0.5)
+	orderer.counterUsage.Add(light.Tx.IDHash, 0.01)
+
+	require.Equal(t, []byte{3, 2, 1}, orderedIDs(orderer.order([]*metaTx{heavy, light, unknown})))
+}
+
+func TestTxOrderingSenderCap(t *testing.T) {
+	orderer, err := newTxOrderer(OrderingPriorityFee, 2)
+	require.NoError(t, err)
+
+	yielded := map[uint64]int{1: 1}
+	require.False(t, orderer.senderCapReached(yielded, 1))
+	yielded[1]++
+	require.True(t, orderer.senderCapReached(yielded, 1))
+	require.False(t, orderer.senderCapReached(yielded, 2))
+
+	unlimited, err := newTxOrderer(OrderingPriorityFee, 0)
+	require.NoError(t, err)
+	require.False(t, unlimited.senderCapReached(map[uint64]int{1: 1000}, 1))
+}
+
+func TestTxOrderingBatchSenderCounts(t *testing.T) {
+	orderer, err := newTxOrderer(OrderingPriorityFee, 2)
+	require.NoError(t, err)
+
+	orderer.batchSenderCounts(5)[1] += 2
+	require.True(t, orderer.senderCapReached(orderer.batchSenderCounts(5), 1))
+
+	// the counts start again once the sequencer moves on to the next batch
+	require.False(t, orderer.senderCapReached(orderer.batchSenderCounts(6), 1))
+	require.Empty(t, orderer.batchSenderCounts(6))
+}
+
+func TestYieldBestOrderingAndSenderCap(t *testing.T) {
+	ctx := context.Background()
+	busySender := [20]byte{1}
+	quietSender := [20]byte{2}
+
+	newPool := func(t *testing.T, ordering TxOrdering, maxTxsPerSender int) (*TxPool, kv.RwTx) {
+		_, coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+		t.Cleanup(coreDB.Close)
+		aclsDB := newTestACLDB(t, t.TempDir())
+		t.Cleanup(aclsDB.Close)
+
+		ethCfg := ethconfig.Defaults
+		ethCfg.Zk = &ethconfig.Zk{SequencerTxOrdering: string(ordering), SequencerMaxTxsPerSender: maxTxsPerSender}
+		pool, err := New(make(chan types.Announcements, 100), coreDB, txpoolcfg.DefaultConfig, &ethCfg, kvcache.New(kvcache.DefaultCoherentConfig), *u256.N1, nil, nil, aclsDB)
+		require.NoError(t, err)
+
+		tx, err := memdb.NewTestPoolDB(t).BeginRw(ctx)
+		require.NoError(t, err)
+		t.Cleanup(tx.Rollback)
+
+		change := &remote.StateChangeBatch{
+			PendingBlockBaseFee: 200000,
+			BlockGasLimit:       1000000,
+			ChangeBatch:         []*remote.StateChange{{BlockHeight: 0, BlockHash: gointerfaces.ConvertHashToH256([32]byte{})}},
+		}
+		balance := *uint256.NewInt(18 * common.Ether)
+		for _, sender := range [][20]byte{busySender, quietSender} {
+			v := make([]byte, types.EncodeSenderLengthForStorage(0, balance))
+			types.EncodeSender(0, balance, v)
+			change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+				Action:  remote.Action_UPSERT,
+				Address: gointerfaces.ConvertAddressToH160(sender),
+				Data:    v,
+			})
+		}
+		require.NoError(t, pool.OnNewBlock(ctx, change, types.TxSlots{}, types.TxSlots{}, tx))
+
+		add := func(sender [20]byte, nonce uint64, tip uint64, id byte) {
+			var slots types.TxSlots
+			slot := &types.TxSlot{Tip: *uint256.NewInt(tip), FeeCap: *uint256.NewInt(tip), Gas: 100000, Nonce: nonce, Rlp: []byte{id}}
+			slot.IDHash[0] = id
+			slots.Append(slot, sender[:], true)
+			reasons, err := pool.AddLocalTxs(ctx, slots, tx)
+			require.NoError(t, err)
+			require.Equal(t, Success, reasons[0], reasons[0].String())
+		}
+		// the quiet sender's transaction arrives first but pays the lowest tip
+		add(quietSender, 0, 250000, 1)
+		add(busySender, 0, 300000, 2)
+		add(busySender, 1, 300000, 3)
+		add(busySender, 2, 300000, 4)
+
+		return pool, tx
+	}
+
+	yield := func(t *testing.T, pool *TxPool, tx kv.Tx) []byte {
+		var txs types.TxsRlp
+		_, count, err := pool.YieldBest(10, &txs, tx, 0, 30_000_000, 0, mapset.NewSet[[32]byte]())
+		require.NoError(t, err)
+		ids := make([]byte, count)
+		for i := 0; i < count; i++ {
+			ids[i] = txs.TxIds[i][0]
+		}
+		return ids
+	}
+
+	t.Run("priority fee", func(t *testing.T) {
+		pool, tx := newPool(t, OrderingPriorityFee, 0)
+		ids := yield(t, pool, tx)
+		require.ElementsMatch(t, []byte{1, 2, 3, 4}, ids)
+		require.Equal(t, byte(2), ids[0])
+	})
+
+	t.Run("fifo", func(t *testing.T) {
+		pool, tx := newPool(t, OrderingFifo, 0)
+		require.Equal(t, []byte{1, 2, 3, 4}, yield(t, pool, tx))
+	})
+
+	t.Run("fifo with sender cap", func(t *testing.T) {
+		pool, tx := newPool(t, OrderingFifo, 2)
+		require.Equal(t, []byte{1, 2, 3}, yield(t, pool, tx))
+	})
+
+	t.Run("sender fair with sender cap", func(t *testing.T) {
+		pool, tx := newPool(t, OrderingSenderFair, 1)
+		require.ElementsMatch(t, []byte{1, 2}, yield(t, pool, tx))
+	})
+
+	t.Run("sender cap across yields of one batch", func(t *testing.T) {
+		pool, tx := newPool(t, OrderingFifo, 2)
+		yielded := mapset.NewSet[[32]byte]()
+		yieldForBatch := func(batchNo uint64, n uint16) []byte {
+			var txs types.TxsRlp
+			_, count, err := pool.YieldBestForBatch(batchNo, n, &txs, tx, 0, 30_000_000, 0, yielded)
+			require.NoError(t, err)
+			ids := make([]byte, count)
+			for i := 0; i < count; i++ {
+				ids[i] = txs.TxIds[i][0]
+			}
+			return ids
+		}
+
+		require.Equal(t, []byte{1, 2}, yieldForBatch(1, 2))
+		require.Equal(t, []byte{3}, yieldForBatch(1, 10))
+		// the busy sender has had two transactions in batch 1, however many times the sequencer asks
+		require.Empty(t, yieldForBatch(1, 10))
+		require.Equal(t, []byte{4}, yieldForBatch(2, 10))
+	})
+}
+
+func TestUpdateTxCounterUsageOnlyForCounterAware(t *testing.T) {
+	for _, ordering := range []TxOrdering{OrderingPriorityFee, OrderingFifo, OrderingSenderFair, OrderingCounterAware} {
+		orderer, err := newTxOrderer(ordering, 0)
+		require.NoError(t, err)
+		pool := &TxPool{orderer: orderer}
+
+		pool.UpdateTxCounterUsage([32]byte{1}, 0.5)
+		require.Equal(t, ordering == OrderingCounterAware, orderer.counterUsage.Contains([32]byte{1}), ordering)
+	}
+}
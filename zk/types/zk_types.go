@@ -11,6 +11,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon/cl/utils"
@@ -168,3 +169,146 @@ type ForkInterval struct {
 	ToBatchNumber   uint64
 	BlockNumber     uint64
 }
+
+// BridgeDeposit is a BridgeEvent emitted by the bridge contract on the L2, each one adds a leaf to the local exit tree
+type BridgeDeposit struct {
+	LeafType           uint8
+	OriginNetwork      uint32
+	OriginAddress      common.Address
+	DestinationNetwork uint32
+	DestinationAddress common.Address
+	Amount             *big.Int
+	Metadata           []byte
+	DepositCount       uint32
+	BlockNumber        uint64
+	TxHash             common.Hash
+}
+
+const bridgeDepositFixedLength = 1 + 4 + 20 + 4 + 20 + 32 + 4 + 8 + 32
+
+func (d *BridgeDeposit) Marshall() []byte {
+	result := make([]byte, bridgeDepositFixedLength, bridgeDepositFixedLength+len(d.Metadata))
+	result[0] = d.LeafType
+	binary.LittleEndian.PutUint32(result[1:5], d.OriginNetwork)
+	copy(result[5:25], d.OriginAddress[:])
+	binary.LittleEndian.PutUint32(result[25:29], d.DestinationNetwork)
+	copy(result[29:49], d.DestinationAddress[:])
+	if d.Amount != nil {
+		d.Amount.FillBytes(result[49:81])
+	}
+	binary.LittleEndian.PutUint32(result[81:85], d.DepositCount)
+	copy(result[85:93], utils.Uint64ToLE(d.BlockNumber))
+	copy(result[93:125], d.TxHash[:])
+	return append(result, d.Metadata...)
+}
+
+func (d *BridgeDeposit) Unmarshall(input []byte) error {
+	if len(input) < bridgeDepositFixedLength {
+		return fmt.Errorf("unmarshall error, input is too short")
+	}
+	d.LeafType = input[0]
+	d.OriginNetwork = binary.LittleEndian.Uint32(input[1:5])
+	copy(d.OriginAddress[:], input[5:25])
+	d.DestinationNetwork = binary.LittleEndian.Uint32(input[25:29])
+	copy(d.DestinationAddress[:], input[29:49])
+	d.Amount = new(big.Int).SetBytes(input[49:81])
+	d.DepositCount = binary.LittleEndian.Uint32(input[81:85])
+	d.BlockNumber = binary.LittleEndian.Uint64(input[85:93])
+	copy(d.TxHash[:], input[93:125])
+	d.Metadata = append([]byte{}, input[bridgeDepositFixedLength:]...)
+	return nil
+}
+
+// BridgeClaim is a ClaimEvent emitted by the bridge contract on the L2 when a deposit from another network is claimed
+type BridgeClaim struct {
+	GlobalIndex        *big.Int
+	OriginNetwork      uint32
+	OriginAddress      common.Address
+	DestinationAddress common.Address
+	Amount             *big.Int
+	BlockNumber        uint64
+	TxHash             common.Hash
+}
+
+const bridgeClaimLength = 32 + 4 + 20 + 20 + 32 + 8 + 32
+
+func (c *BridgeClaim) Marshall() []byte {
+	result := make([]byte, bridgeClaimLength)
+	if c.GlobalIndex != nil {
+		c.GlobalIndex.FillBytes(result[0:32])
+	}
+	binary.LittleEndian.PutUint32(result[32:36], c.OriginNetwork)
+	copy(result[36:56], c.OriginAddress[:])
+	copy(result[56:76], c.DestinationAddress[:])
+	if c.Amount != nil {
+		c.Amount.FillBytes(result[76:108])
+	}
+	copy(result[108:116], utils.Uint64ToLE(c.BlockNumber))
+	copy(result[116:148], c.TxHash[:])
+	return result
+}
+
+func (c *BridgeClaim) Unmarshall(input []byte) error {
+	if len(input) < bridgeClaimLength {
+		return fmt.Errorf("unmarshall error, input is too short")
+	}
+	c.GlobalIndex = new(big.Int).SetBytes(input[0:32])
+	c.OriginNetwork = binary.LittleEndian.Uint32(input[32:36])
+	copy(c.OriginAddress[:], input[36:56])
+	copy(c.DestinationAddress[:], input[56:76])
+	c.Amount = new(big.Int).SetBytes(input[76:108])
+	c.BlockNumber = binary.LittleEndian.Uint64(input[108:116])
+	copy(c.TxHash[:], input[116:148])
+	return nil
+}
+
+type BatchEventType uint8
+
+const (
+	BatchClosed BatchEventType = iota + 1
+	BatchVirtualized
+	BatchVerified
+)
+
+// BatchEvent is a step of the lifecycle of a batch, sent to the rpc subscribers of the batch topics. Fields the node
+// does not know when the event is raised, like the blocks of a batch sequenced on the L1 before it was synced, are zero
+type BatchEvent struct {
+	Type          BatchEventType
+	BatchNumber   uint64
+	FromBlock     uint64
+	ToBlock       uint64
+	L1TxHash      common.Hash
+	L1BlockNumber uint64
+	StateRoot     common.Hash
+	AccInputHash  common.Hash
+}
+
+const batchEventLength = 1 + 8 + 8 + 8 + 32 + 8 + 32 + 32
+
+func (e *BatchEvent) Marshall() []byte {
+	result := make([]byte, batchEventLength)
+	result[0] = byte(e.Type)
+	copy(result[1:9], utils.Uint64ToLE(e.BatchNumber))
+	copy(result[9:17], utils.Uint64ToLE(e.FromBlock))
+	copy(result[17:25], utils.Uint64ToLE(e.ToBlock))
+	copy(result[25:57], e.L1TxHash[:])
+	copy(result[57:65], utils.Uint64ToLE(e.L1BlockNumber))
+	copy(result[65:97], e.StateRoot[:])
+	copy(result[97:129], e.AccInputHash[:])
+	return result
+}
+
+func (e *BatchEvent) Unmarshall(input []byte) error {
+	if len(input) < batchEventLength {
+		return fmt.Errorf("unmarshall error, input is too short")
+	}
+	e.Type = BatchEventType(input[0])
+	e.BatchNumber = binary.LittleEndian.Uint64(input[1:9])
+	e.FromBlock = binary.LittleEndian.Uint64(input[9:17])
+	e.ToBlock = binary.LittleEndian.Uint64(input[17:25])
+	copy(e.L1TxHash[:], input[25:57])
+	e.L1BlockNumber = binary.LittleEndian.Uint64(input[57:65])
+	copy(e.StateRoot[:], input[65:97])
+	copy(e.AccInputHash[:], input[97:129])
+	return nil
+}
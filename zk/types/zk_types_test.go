@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"strings"
 	"testing"
 
@@ -117,3 +118,63 @@ func mustDecodeHexString(t *testing.T, hexStr string) []byte {
 	require.NoError(t, err)
 	return bytes
 }
+
+func Test_BridgeDepositMarshallUnmarshall(t *testing.T) {
+	for _, metadata := range [][]byte{{}, {1, 2, 3}} {
+		input := &BridgeDeposit{
+			LeafType:           1,
+			OriginNetwork:      2,
+			OriginAddress:      libcommon.HexToAddress("0x3"),
+			DestinationNetwork: 4,
+			DestinationAddress: libcommon.HexToAddress("0x5"),
+			Amount:             big.NewInt(6),
+			Metadata:           metadata,
+			DepositCount:       7,
+			BlockNumber:        8,
+			TxHash:             libcommon.HexToHash("0x9"),
+		}
+
+		result := &BridgeDeposit{}
+		require.NoError(t, result.Unmarshall(input.Marshall()))
+		require.Equal(t, input, result)
+	}
+
+	require.Error(t, (&BridgeDeposit{}).Unmarshall([]byte{1}))
+}
+
+func Test_BridgeClaimMarshallUnmarshall(t *testing.T) {
+	input := &BridgeClaim{
+		GlobalIndex:        new(big.Int).Lsh(big.NewInt(1), 64),
+		OriginNetwork:      1,
+		OriginAddress:      libcommon.HexToAddress("0x2"),
+		DestinationAddress: libcommon.HexToAddress("0x3"),
+		Amount:             big.NewInt(4),
+		BlockNumber:        5,
+		TxHash:             libcommon.HexToHash("0x6"),
+	}
+
+	result := &BridgeClaim{}
+	require.NoError(t, result.Unmarshall(input.Marshall()))
+	require.Equal(t, input, result)
+
+	require.Error(t, (&BridgeClaim{}).Unmarshall([]byte{1}))
+}
+
+func Test_BatchEventMarshallUnmarshall(t *testing.T) {
+	input := &BatchEvent{
+		Type:          BatchVerified,
+		BatchNumber:   1,
+		FromBlock:     2,
+		ToBlock:       3,
+		L1TxHash:      libcommon.HexToHash("0x4"),
+		L1BlockNumber: 5,
+		StateRoot:     libcommon.HexToHash("0x6"),
+		AccInputHash:  libcommon.HexToHash("0x7"),
+	}
+
+	result := &BatchEvent{}
+	require.NoError(t, result.Unmarshall(input.Marshall()))
+	require.Equal(t, input, result)
+
+	require.Error(t, (&BatchEvent{}).Unmarshall([]byte{1}))
+}
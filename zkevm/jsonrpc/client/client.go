@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,6 +36,11 @@ func (e *HTTPError) Error() string {
 // the provided method and parameters, which is compatible with the Ethereum
 // JSON RPC Server.
 func JSONRPCCall(url, method string, parameters ...interface{}) (types.Response, error) {
+	return JSONRPCCallWithContext(context.Background(), url, method, parameters...)
+}
+
+// JSONRPCCallWithContext is JSONRPCCall with the request bound to ctx, it gives up as soon as ctx is done
+func JSONRPCCallWithContext(ctx context.Context, url, method string, parameters ...interface{}) (types.Response, error) {
 	const jsonRPCVersion = "2.0"
 
 	params := []byte{}
@@ -59,7 +65,7 @@ func JSONRPCCall(url, method string, parameters ...interface{}) (types.Response,
 	}
 
 	reqBodyReader := bytes.NewReader(reqBody)
-	httpReq, err := http.NewRequest(http.MethodPost, url, reqBodyReader)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, reqBodyReader)
 	if err != nil {
 		return types.Response{}, err
 	}
@@ -70,6 +76,7 @@ func JSONRPCCall(url, method string, parameters ...interface{}) (types.Response,
 	if err != nil {
 		return types.Response{}, err
 	}
+	defer httpRes.Body.Close()
 
 	if httpRes.StatusCode != http.StatusOK {
 		return types.Response{}, &HTTPError{StatusCode: httpRes.StatusCode}
@@ -79,7 +86,6 @@ func JSONRPCCall(url, method string, parameters ...interface{}) (types.Response,
 	if err != nil {
 		return types.Response{}, err
 	}
-	defer httpRes.Body.Close()
 
 	var res types.Response
 	err = json.Unmarshal(resBody, &res)